@@ -0,0 +1,178 @@
+// Package client — типизированный клиент API сервера для интеграционных тестов и
+// утилит. Типы и методы (client_gen.go) генерируются по спецификации OpenAPI
+// командой go generate ./router; здесь — транспорт и то, что генератор не покрывает.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client вызывает API по BaseURL (http://localhost:8080). Авторизация — APIKey
+// (X-API-Key, устройства и скрипты) или Token (access-токен сессии сотрудника);
+// если заданы оба, отправляется сессия.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	APIKey     string
+	Token      string
+}
+
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// APIError — ответ сервера с кодом не 2xx. Error и Code — поля ErrorResponse,
+// если тело было JSON; иначе Error — начало тела.
+type APIError struct {
+	Status  int
+	Message string `json:"error"`
+	Code    string `json:"code"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("api: %d %s (%s)", e.Status, msg, e.Code)
+	}
+	return fmt.Sprintf("api: %d %s", e.Status, msg)
+}
+
+// StatusCode — HTTP-статус ошибки API (0 — ошибка не от сервера).
+func StatusCode(err error) int {
+	if apiErr, ok := err.(*APIError); ok {
+		return apiErr.Status
+	}
+	return 0
+}
+
+// do отправляет body как JSON и разбирает успешный ответ в out (если тело есть).
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) (int, error) {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("кодирование запроса: %w", err)
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	status, data, err := c.send(ctx, method, path, query, reader, contentType)
+	if err != nil {
+		return status, err
+	}
+	if out != nil && len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return status, fmt.Errorf("разбор ответа %s %s: %w", method, path, err)
+		}
+	}
+	return status, nil
+}
+
+// doRaw возвращает тело успешного ответа как есть (PNG, CSV).
+func (c *Client) doRaw(ctx context.Context, method, path string, query url.Values) ([]byte, error) {
+	_, data, err := c.send(ctx, method, path, query, nil, "")
+	return data, err
+}
+
+// Upload отправляет готовое тело с заданным Content-Type (multipart-загрузки, которые
+// генератор не описывает) и разбирает JSON-ответ в out.
+func (c *Client) Upload(ctx context.Context, method, path string, body io.Reader, contentType string, out any) error {
+	_, data, err := c.send(ctx, method, path, nil, body, contentType)
+	if err != nil {
+		return err
+	}
+	if out != nil && len(bytes.TrimSpace(data)) > 0 {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) (int, []byte, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case c.APIKey != "":
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{Status: resp.StatusCode}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+			if len(apiErr.Message) > 200 {
+				apiErr.Message = apiErr.Message[:200]
+			}
+		}
+		return resp.StatusCode, nil, apiErr
+	}
+	return resp.StatusCode, data, nil
+}
+
+func pathParam(v any) string {
+	return url.PathEscape(fmt.Sprint(v))
+}
+
+// setQuery добавляет параметр, если значение не нулевое.
+func setQuery(q url.Values, name string, v any) {
+	switch v := v.(type) {
+	case string:
+		if v != "" {
+			q.Set(name, v)
+		}
+	case int:
+		if v != 0 {
+			q.Set(name, strconv.Itoa(v))
+		}
+	case int64:
+		if v != 0 {
+			q.Set(name, strconv.FormatInt(v, 10))
+		}
+	case bool:
+		if v {
+			q.Set(name, "true")
+		}
+	case float64:
+		if v != 0 {
+			q.Set(name, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	default:
+		panic(fmt.Sprintf("client: query-параметр %s типа %T", name, v))
+	}
+}
@@ -0,0 +1,2409 @@
+// Code generated by cmd/openapi. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type APIKey struct {
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Fingerprint  string     `json:"fingerprint"`
+	ID           int        `json:"id"`
+	Label        string     `json:"label"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP   string     `json:"last_used_ip"`
+	Primary      bool       `json:"primary"`
+	ReplacedByID *int       `json:"replaced_by_id,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	UserID       int        `json:"user_id"`
+}
+
+type APIKeyCreatedResponse struct {
+	APIKey string  `json:"api_key"`
+	Key    *APIKey `json:"key,omitempty"`
+}
+
+type APIKeyInput struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Label     string     `json:"label"`
+}
+
+type Alert struct {
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *int       `json:"acknowledged_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DedupKey       string     `json:"dedup_key"`
+	FirstSeenAt    time.Time  `json:"first_seen_at"`
+	ID             int        `json:"id"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	Message        string     `json:"message"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy     *int       `json:"resolved_by,omitempty"`
+	RuleID         int        `json:"rule_id"`
+	Severity       string     `json:"severity"`
+	Status         string     `json:"status"`
+	Type           string     `json:"type"`
+	UserID         int        `json:"user_id"`
+}
+
+type AlertEvaluation struct {
+	Opened   int `json:"opened"`
+	Resolved int `json:"resolved"`
+	Updated  int `json:"updated"`
+}
+
+type AlertListResponse struct {
+	Alerts []Alert `json:"alerts,omitempty"`
+	Total  int64   `json:"total"`
+}
+
+type AlertRule struct {
+	BatteryPercent   *float64  `json:"battery_percent,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	Enabled          bool      `json:"enabled"`
+	ID               int       `json:"id"`
+	Issue            string    `json:"issue"`
+	MinAppVersion    string    `json:"min_app_version"`
+	Name             string    `json:"name"`
+	OrganizationID   int       `json:"organization_id"`
+	Severity         string    `json:"severity"`
+	ThresholdMinutes *int      `json:"threshold_minutes,omitempty"`
+	Type             string    `json:"type"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	UserID           *int      `json:"user_id,omitempty"`
+	WorkDaysOnly     bool      `json:"work_days_only"`
+	WorkEndHour      *int      `json:"work_end_hour,omitempty"`
+	WorkStartHour    *int      `json:"work_start_hour,omitempty"`
+}
+
+type AppRelease struct {
+	Changelog         string     `json:"changelog"`
+	Channel           string     `json:"channel"`
+	CreatedAt         time.Time  `json:"created_at"`
+	Filename          string     `json:"filename"`
+	Force             bool       `json:"force"`
+	ID                int        `json:"id"`
+	MaxFailurePercent int        `json:"max_failure_percent"`
+	MinFailureSamples int        `json:"min_failure_samples"`
+	OrganizationID    int        `json:"organization_id"`
+	PackageName       string     `json:"package_name"`
+	PauseReason       string     `json:"pause_reason"`
+	PausedAt          *time.Time `json:"paused_at,omitempty"`
+	RolloutPercent    int        `json:"rollout_percent"`
+	SHA256            string     `json:"sha256"`
+	Status            string     `json:"status"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	VersionCode       int64      `json:"version_code"`
+	VersionName       string     `json:"version_name"`
+}
+
+type AppReleaseChannel struct {
+	MinVersion     string    `json:"min_version"`
+	Name           string    `json:"name"`
+	OrganizationID int       `json:"organization_id"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type AppReleaseInput struct {
+	Changelog         string `json:"changelog"`
+	Channel           string `json:"channel"`
+	Filename          string `json:"filename"`
+	Force             bool   `json:"force"`
+	MaxFailurePercent *int   `json:"max_failure_percent,omitempty"`
+	MinFailureSamples *int   `json:"min_failure_samples,omitempty"`
+	RolloutPercent    *int   `json:"rollout_percent,omitempty"`
+}
+
+type AppReleaseMember struct {
+	Channel   string    `json:"channel"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    int       `json:"user_id"`
+}
+
+type AppReleaseStats struct {
+	Completed      int64      `json:"completed"`
+	Failed         int64      `json:"failed"`
+	FailurePercent float64    `json:"failure_percent"`
+	Release        AppRelease `json:"release"`
+}
+
+type AppReleaseUpdateInput struct {
+	Changelog         *string `json:"changelog,omitempty"`
+	Force             *bool   `json:"force,omitempty"`
+	MaxFailurePercent *int    `json:"max_failure_percent,omitempty"`
+	MinFailureSamples *int    `json:"min_failure_samples,omitempty"`
+	RolloutPercent    *int    `json:"rollout_percent,omitempty"`
+}
+
+type AuditEntry struct {
+	Action         string    `json:"action"`
+	ActorID        *int      `json:"actor_id,omitempty"`
+	ActorName      string    `json:"actor_name"`
+	ActorRole      string    `json:"actor_role"`
+	At             time.Time `json:"at"`
+	Error          string    `json:"error"`
+	ID             int64     `json:"id"`
+	IP             string    `json:"ip"`
+	OrganizationID int       `json:"organization_id"`
+	Params         any       `json:"params,omitempty"`
+	Result         string    `json:"result"`
+	SessionID      string    `json:"session_id"`
+	Status         int       `json:"status"`
+	TargetID       string    `json:"target_id"`
+	TargetType     string    `json:"target_type"`
+}
+
+type AuditListResponse struct {
+	Entries []AuditEntry `json:"entries,omitempty"`
+	Total   int64        `json:"total"`
+}
+
+type BackfillCapturedAtResult struct {
+	Bursts         int `json:"bursts"`
+	Skipped        int `json:"skipped"`
+	Updated        int `json:"updated"`
+	UsersProcessed int `json:"users_processed"`
+}
+
+type Checkpoint struct {
+	CreatedAt      time.Time `json:"created_at"`
+	ID             int       `json:"id"`
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	Name           string    `json:"name"`
+	OrganizationID int       `json:"organization_id"`
+	Radius         float64   `json:"radius"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type CheckpointRequest struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name"`
+	Radius    float64 `json:"radius"`
+}
+
+type CommandAckRequest struct {
+	CommandID string `json:"command_id"`
+	Message   string `json:"message"`
+	Status    string `json:"status"`
+}
+
+type CommandAckResponse struct {
+	Legacy bool `json:"legacy"`
+	Ok     bool `json:"ok"`
+}
+
+type ConfigDriftSummaryResponse struct {
+	Devices []DeviceConfigDriftStatus `json:"devices,omitempty"`
+	Drifted int                       `json:"drifted"`
+	Total   int                       `json:"total"`
+}
+
+type CreateUserRequest struct {
+	GroupID *int   `json:"group_id,omitempty"`
+	IsAdmin bool   `json:"is_admin"`
+	Name    string `json:"name"`
+	Role    string `json:"role"`
+}
+
+type CredentialsInput struct {
+	Password  string `json:"password"`
+	ResetTOTP bool   `json:"reset_totp"`
+	Username  string `json:"username"`
+}
+
+type CurrentUserResponse struct {
+	GroupID        *int     `json:"group_id,omitempty"`
+	ID             int      `json:"id"`
+	IsAdmin        bool     `json:"is_admin"`
+	Name           string   `json:"name"`
+	OrganizationID int      `json:"organization_id"`
+	Permissions    []string `json:"permissions,omitempty"`
+	QRCode         string   `json:"qr_code"`
+	Role           string   `json:"role"`
+	Timezone       string   `json:"timezone"`
+	TOTPEnabled    bool     `json:"totp_enabled"`
+	Username       *string  `json:"username,omitempty"`
+}
+
+type DesiredConfigResponse struct {
+	Effective    DeviceConfigFields `json:"effective"`
+	LastPushedAt *time.Time         `json:"last_pushed_at,omitempty"`
+	Overrides    DeviceConfigFields `json:"overrides"`
+	ProfileID    *int               `json:"profile_id,omitempty"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	UserID       int                `json:"user_id"`
+}
+
+type DeviceCommand struct {
+	AckMessage     string     `json:"ack_message"`
+	AckStatus      string     `json:"ack_status"`
+	AckedAt        *time.Time `json:"acked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	ID             string     `json:"id"`
+	OrganizationID int        `json:"organization_id"`
+	Payload        any        `json:"payload,omitempty"`
+	Status         string     `json:"status"`
+	Type           string     `json:"type"`
+	UserID         int        `json:"user_id"`
+}
+
+type DeviceCommandResponse struct {
+	CommandID string         `json:"command_id"`
+	Payload   map[string]any `json:"payload,omitempty"`
+	Status    string         `json:"status"`
+	Type      string         `json:"type"`
+	UserID    int            `json:"user_id"`
+}
+
+type DeviceConfigDriftStatus struct {
+	Desired      DeviceConfigFields                `json:"desired"`
+	Drift        map[string]DeviceConfigFieldDrift `json:"drift,omitempty"`
+	InSync       bool                              `json:"in_sync"`
+	LastPushedAt *time.Time                        `json:"last_pushed_at,omitempty"`
+	ProfileID    *int                              `json:"profile_id,omitempty"`
+	Reported     bool                              `json:"reported"`
+	ReportedAt   *time.Time                        `json:"reported_at,omitempty"`
+	UserID       int                               `json:"user_id"`
+}
+
+type DeviceConfigFieldDrift struct {
+	Desired  any `json:"desired,omitempty"`
+	Reported any `json:"reported,omitempty"`
+}
+
+type DeviceConfigFields struct {
+	AdminPin                    *string `json:"admin_pin,omitempty"`
+	APIBaseURL                  *string `json:"api_base_url,omitempty"`
+	HealthReportIntervalSeconds *int64  `json:"health_report_interval_seconds,omitempty"`
+	HiddenFromLauncher          *bool   `json:"hidden_from_launcher,omitempty"`
+	LocationIntervalSeconds     *int64  `json:"location_interval_seconds,omitempty"`
+	PollIntervalSeconds         *int64  `json:"poll_interval_seconds,omitempty"`
+	TrackingPaused              *bool   `json:"tracking_paused,omitempty"`
+}
+
+type DeviceConfigProfile struct {
+	AdminPin                    *string   `json:"admin_pin,omitempty"`
+	APIBaseURL                  *string   `json:"api_base_url,omitempty"`
+	CreatedAt                   time.Time `json:"created_at"`
+	HealthReportIntervalSeconds *int64    `json:"health_report_interval_seconds,omitempty"`
+	HiddenFromLauncher          *bool     `json:"hidden_from_launcher,omitempty"`
+	ID                          int       `json:"id"`
+	LocationIntervalSeconds     *int64    `json:"location_interval_seconds,omitempty"`
+	Name                        string    `json:"name"`
+	OrganizationID              int       `json:"organization_id"`
+	PollIntervalSeconds         *int64    `json:"poll_interval_seconds,omitempty"`
+	TrackingPaused              *bool     `json:"tracking_paused,omitempty"`
+	UpdatedAt                   time.Time `json:"updated_at"`
+}
+
+type DeviceConfigProfileRequest struct {
+	AdminPin                    *string `json:"admin_pin,omitempty"`
+	APIBaseURL                  *string `json:"api_base_url,omitempty"`
+	HealthReportIntervalSeconds *int64  `json:"health_report_interval_seconds,omitempty"`
+	HiddenFromLauncher          *bool   `json:"hidden_from_launcher,omitempty"`
+	LocationIntervalSeconds     *int64  `json:"location_interval_seconds,omitempty"`
+	Name                        string  `json:"name"`
+	PollIntervalSeconds         *int64  `json:"poll_interval_seconds,omitempty"`
+	TrackingPaused              *bool   `json:"tracking_paused,omitempty"`
+}
+
+type DeviceConfigUpdateInput struct {
+	AdminPin                    *string `json:"admin_pin,omitempty"`
+	APIBaseURL                  *string `json:"api_base_url,omitempty"`
+	APIKey                      *string `json:"api_key,omitempty"`
+	EnableLocation              *bool   `json:"enable_location,omitempty"`
+	HealthReportIntervalSeconds *int64  `json:"health_report_interval_seconds,omitempty"`
+	HiddenFromLauncher          *bool   `json:"hidden_from_launcher,omitempty"`
+	LocationIntervalSeconds     *int64  `json:"location_interval_seconds,omitempty"`
+	PollIntervalSeconds         *int64  `json:"poll_interval_seconds,omitempty"`
+	TrackingPaused              *bool   `json:"tracking_paused,omitempty"`
+	WakeDevice                  *bool   `json:"wake_device,omitempty"`
+}
+
+type DeviceDesiredConfigRequest struct {
+	AdminPin                    *string `json:"admin_pin,omitempty"`
+	APIBaseURL                  *string `json:"api_base_url,omitempty"`
+	HealthReportIntervalSeconds *int64  `json:"health_report_interval_seconds,omitempty"`
+	HiddenFromLauncher          *bool   `json:"hidden_from_launcher,omitempty"`
+	LocationIntervalSeconds     *int64  `json:"location_interval_seconds,omitempty"`
+	PollIntervalSeconds         *int64  `json:"poll_interval_seconds,omitempty"`
+	ProfileID                   *int    `json:"profile_id,omitempty"`
+	TrackingPaused              *bool   `json:"tracking_paused,omitempty"`
+}
+
+type DeviceIssueEpisode struct {
+	FirstSeenAt time.Time  `json:"first_seen_at"`
+	Issue       string     `json:"issue"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	Ongoing     bool       `json:"ongoing"`
+	Reports     int        `json:"reports"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
+
+type DevicePollResponse struct {
+	Command *PolledCommand `json:"command,omitempty"`
+	Pending *bool          `json:"pending,omitempty"`
+}
+
+type DeviceReportBattery struct {
+	Charging      *bool    `json:"charging,omitempty"`
+	LevelPercent  *float64 `json:"level_percent,omitempty"`
+	PowerSaveMode *bool    `json:"power_save_mode,omitempty"`
+}
+
+type DeviceReportItem struct {
+	AppVersion string               `json:"app_version"`
+	CreatedAt  time.Time            `json:"created_at"`
+	ID         int                  `json:"id"`
+	Issues     []string             `json:"issues,omitempty"`
+	Platform   string               `json:"platform"`
+	Report     *DeviceReportPayload `json:"report,omitempty"`
+}
+
+type DeviceReportLocation struct {
+	ForegroundServiceRunning *bool   `json:"foreground_service_running,omitempty"`
+	LastPostAt               *string `json:"last_post_at,omitempty"`
+	Permission               string  `json:"permission"`
+	SystemEnabled            *bool   `json:"system_enabled,omitempty"`
+}
+
+type DeviceReportNetwork struct {
+	Type string `json:"type"`
+}
+
+type DeviceReportPage struct {
+	From   time.Time          `json:"from"`
+	Items  []DeviceReportItem `json:"items,omitempty"`
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+	To     time.Time          `json:"to"`
+	Total  int64              `json:"total"`
+}
+
+// DeviceReportPayload: сервер принимает и присылает и другие ключи, здесь — только известные.
+type DeviceReportPayload struct {
+	AppVersion           string                `json:"app_version"`
+	BackgroundRestricted *bool                 `json:"background_restricted,omitempty"`
+	Battery              *DeviceReportBattery  `json:"battery,omitempty"`
+	Issues               []string              `json:"issues,omitempty"`
+	Location             *DeviceReportLocation `json:"location,omitempty"`
+	Network              *DeviceReportNetwork  `json:"network,omitempty"`
+	Platform             string                `json:"platform"`
+	Storage              *DeviceReportStorage  `json:"storage,omitempty"`
+}
+
+type DeviceReportResponse struct {
+	ConfigCommandID string    `json:"config_command_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	ID              int       `json:"id"`
+	UserID          int       `json:"user_id"`
+}
+
+type DeviceReportStorage struct {
+	FreeBytes  *int64 `json:"free_bytes,omitempty"`
+	TotalBytes *int64 `json:"total_bytes,omitempty"`
+}
+
+type DeviceReportTrendPoint struct {
+	AppVersion               string    `json:"app_version"`
+	At                       time.Time `json:"at"`
+	BackgroundRestricted     *bool     `json:"background_restricted,omitempty"`
+	BatteryPercent           *float64  `json:"battery_percent,omitempty"`
+	Charging                 *bool     `json:"charging,omitempty"`
+	ForegroundServiceRunning *bool     `json:"foreground_service_running,omitempty"`
+	IssueCount               int       `json:"issue_count"`
+	LocationPermission       string    `json:"location_permission"`
+	NetworkType              string    `json:"network_type"`
+	PowerSaveMode            *bool     `json:"power_save_mode,omitempty"`
+	StorageFreeBytes         *int64    `json:"storage_free_bytes,omitempty"`
+}
+
+type DeviceWakeResponse struct {
+	ConfigCommandID string `json:"config_command_id"`
+	Note            string `json:"note"`
+	UserID          int    `json:"user_id"`
+}
+
+type DevicesStatusResponse struct {
+	Users map[string]UserDeviceStatusSummary `json:"users,omitempty"`
+}
+
+type ErrorResponse struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+type Group struct {
+	CreatedAt      time.Time `json:"created_at"`
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	OrganizationID int       `json:"organization_id"`
+}
+
+type GroupMemberRequest struct {
+	UserID int `json:"user_id"`
+}
+
+type GroupRequest struct {
+	Name string `json:"name"`
+}
+
+type IssueTimelineResponse struct {
+	Episodes []DeviceIssueEpisode `json:"episodes,omitempty"`
+	UserID   int                  `json:"user_id"`
+}
+
+type Location struct {
+	CapturedAt  *time.Time `json:"captured_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Downsampled bool       `json:"downsampled"`
+	ID          int        `json:"id"`
+	Latitude    float64    `json:"latitude"`
+	Longitude   float64    `json:"longitude"`
+	Masked      bool       `json:"masked"`
+	RequestID   string     `json:"request_id"`
+	Source      string     `json:"source"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	UserID      int        `json:"user_id"`
+}
+
+type LocationEvent struct {
+	CheckpointID   int       `json:"checkpoint_id"`
+	Latitude       float64   `json:"latitude"`
+	LocationID     int       `json:"location_id"`
+	Longitude      float64   `json:"longitude"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	OrganizationID int       `json:"organization_id"`
+	Source         string    `json:"source"`
+	UserID         int       `json:"user_id"`
+}
+
+type LocationRequestPollResponse struct {
+	Pending   bool   `json:"pending"`
+	RequestID string `json:"request_id"`
+}
+
+type LocationRequestQueuedResponse struct {
+	CommandID string `json:"command_id"`
+	RequestID string `json:"request_id"`
+	Status    string `json:"status"`
+	UserID    int    `json:"user_id"`
+}
+
+type LocationRequestStatusResponse struct {
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RequestID   string     `json:"request_id"`
+	Status      string     `json:"status"`
+	UserID      int        `json:"user_id"`
+}
+
+type LocationSnapshot struct {
+	AgeSeconds int64      `json:"age_seconds"`
+	CapturedAt *time.Time `json:"captured_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ID         int        `json:"id"`
+	Latitude   float64    `json:"latitude"`
+	Longitude  float64    `json:"longitude"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UserID     int        `json:"user_id"`
+}
+
+type LocationUploadRequest struct {
+	Accuracy   float64 `json:"accuracy"`
+	CapturedAt string  `json:"captured_at"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	RequestID  string  `json:"request_id"`
+	Source     string  `json:"source"`
+	Timestamp  int64   `json:"timestamp"`
+	UserID     int     `json:"user_id"`
+}
+
+type LocationUploadResponse struct {
+	CapturedAt  *time.Time `json:"captured_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Downsampled bool       `json:"downsampled"`
+	ID          int        `json:"id"`
+	Latitude    float64    `json:"latitude"`
+	Longitude   float64    `json:"longitude"`
+	Masked      bool       `json:"masked"`
+	Reason      string     `json:"reason"`
+	RequestID   string     `json:"request_id"`
+	Skipped     bool       `json:"skipped"`
+	Source      string     `json:"source"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	UserID      int        `json:"user_id"`
+}
+
+type LoginLinkRequest struct {
+	Token    string `json:"token"`
+	TOTPCode string `json:"totp_code"`
+}
+
+type LoginLinkResponse struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Path      string    `json:"path"`
+	Token     string    `json:"token"`
+}
+
+type LoginRequest struct {
+	Password string `json:"password"`
+	TOTPCode string `json:"totp_code"`
+	Username string `json:"username"`
+}
+
+type MatchedRouteResponse struct {
+	Coordinates [][]float64   `json:"coordinates,omitempty"`
+	Segments    [][][]float64 `json:"segments,omitempty"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+type NotificationDelivery struct {
+	Channel        string    `json:"channel"`
+	CreatedAt      time.Time `json:"created_at"`
+	Error          string    `json:"error"`
+	Event          string    `json:"event"`
+	ID             int       `json:"id"`
+	Status         string    `json:"status"`
+	SubjectUserID  int       `json:"subject_user_id"`
+	SubscriptionID int       `json:"subscription_id"`
+	UserID         int       `json:"user_id"`
+}
+
+type NotificationSubscription struct {
+	Channel        string    `json:"channel"`
+	CreatedAt      time.Time `json:"created_at"`
+	Enabled        bool      `json:"enabled"`
+	Events         any       `json:"events,omitempty"`
+	ID             int       `json:"id"`
+	Locale         string    `json:"locale"`
+	MinSeverity    string    `json:"min_severity"`
+	QuietEndHour   *int      `json:"quiet_end_hour,omitempty"`
+	QuietStartHour *int      `json:"quiet_start_hour,omitempty"`
+	SubjectUserID  *int      `json:"subject_user_id,omitempty"`
+	Target         string    `json:"target"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	UserID         int       `json:"user_id"`
+}
+
+type NotificationSubscriptionInput struct {
+	Channel        string   `json:"channel"`
+	Enabled        *bool    `json:"enabled,omitempty"`
+	Events         []string `json:"events,omitempty"`
+	Locale         string   `json:"locale"`
+	MinSeverity    string   `json:"min_severity"`
+	QuietEndHour   *int     `json:"quiet_end_hour,omitempty"`
+	QuietStartHour *int     `json:"quiet_start_hour,omitempty"`
+	SubjectUserID  *int     `json:"subject_user_id,omitempty"`
+	Target         string   `json:"target"`
+	UserID         *int     `json:"user_id,omitempty"`
+}
+
+type OKResponse struct {
+	Ok bool `json:"ok"`
+}
+
+type OnDemandLocationRequest struct {
+	UserID int `json:"user_id"`
+}
+
+type Organization struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Timezone  string    `json:"timezone"`
+}
+
+type OrganizationCreatedResponse struct {
+	Admin        *User         `json:"admin,omitempty"`
+	APIKey       string        `json:"api_key"`
+	Organization *Organization `json:"organization,omitempty"`
+}
+
+type OrganizationRequest struct {
+	AdminName string `json:"admin_name"`
+	Name      string `json:"name"`
+	Timezone  string `json:"timezone"`
+}
+
+type OrganizationTimezoneRequest struct {
+	Timezone string `json:"timezone"`
+}
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type PolledCommand struct {
+	ID      string         `json:"id"`
+	Payload map[string]any `json:"payload,omitempty"`
+	Type    string         `json:"type"`
+}
+
+type QRCodeResponse struct {
+	QRCode string `json:"qr_code"`
+	UserID int    `json:"user_id"`
+}
+
+type RateLimitsResponse struct {
+	Counters map[string]int64 `json:"counters,omitempty"`
+	Enabled  bool             `json:"enabled"`
+}
+
+type ReadinessResponse struct {
+	Checks map[string]string `json:"checks,omitempty"`
+	Status string            `json:"status"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RegenerateQRRequest struct {
+	APIKey       string `json:"api_key"`
+	PushToDevice *bool  `json:"push_to_device,omitempty"`
+}
+
+type RegenerateQRResponse struct {
+	APIBaseURL      string `json:"api_base_url"`
+	APIKey          string `json:"api_key"`
+	ConfigCommandID string `json:"config_command_id"`
+	ID              int    `json:"id"`
+	IsAdmin         bool   `json:"is_admin"`
+	Name            string `json:"name"`
+	QRCode          string `json:"qr_code"`
+}
+
+type ReleaseChannelRequest struct {
+	MinVersion string `json:"min_version"`
+}
+
+type ReleaseStatusRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ReportTrendsResponse struct {
+	Points []DeviceReportTrendPoint `json:"points,omitempty"`
+	UserID int                      `json:"user_id"`
+}
+
+type RetentionPolicy struct {
+	Archive           bool      `json:"archive"`
+	DownsampleMinutes int       `json:"downsample_minutes"`
+	Enabled           bool      `json:"enabled"`
+	OrganizationID    int       `json:"organization_id"`
+	PurgeDays         int       `json:"purge_days"`
+	RawDays           int       `json:"raw_days"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type RetentionPolicyInput struct {
+	Archive           bool  `json:"archive"`
+	DownsampleMinutes int   `json:"downsample_minutes"`
+	Enabled           *bool `json:"enabled,omitempty"`
+	PurgeDays         int   `json:"purge_days"`
+	RawDays           int   `json:"raw_days"`
+}
+
+type RetentionPolicyResponse struct {
+	Custom bool             `json:"custom"`
+	Policy *RetentionPolicy `json:"policy,omitempty"`
+}
+
+type RetentionRun struct {
+	ArchiveFiles   string     `json:"archive_files"`
+	Archived       int64      `json:"archived"`
+	Downsampled    int64      `json:"downsampled"`
+	DryRun         bool       `json:"dry_run"`
+	Error          string     `json:"error"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	ID             int64      `json:"id"`
+	OrganizationID *int       `json:"organization_id,omitempty"`
+	Purged         int64      `json:"purged"`
+	Scanned        int64      `json:"scanned"`
+	StartedAt      time.Time  `json:"started_at"`
+	Status         string     `json:"status"`
+	UsersDone      int        `json:"users_done"`
+	UsersTotal     int        `json:"users_total"`
+}
+
+type RetentionRunRequest struct {
+	DryRun         bool `json:"dry_run"`
+	OrganizationID *int `json:"organization_id,omitempty"`
+}
+
+type RetentionRunsResponse struct {
+	Runs []RetentionRun `json:"runs,omitempty"`
+}
+
+type SessionInfo struct {
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ID         string    `json:"id"`
+	IP         string    `json:"ip"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+}
+
+type SessionTokens struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	SessionID    string `json:"session_id"`
+	TokenType    string `json:"token_type"`
+	User         *User  `json:"user,omitempty"`
+}
+
+type StaleLocationResponse struct {
+	AgeSeconds    int64            `json:"age_seconds"`
+	Error         string           `json:"error"`
+	Location      LocationSnapshot `json:"location"`
+	MaxAgeSeconds int64            `json:"max_age_seconds"`
+}
+
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+type SyncManifestRequest struct {
+	Changelog string `json:"changelog"`
+	Filename  string `json:"filename"`
+	Force     *bool  `json:"force,omitempty"`
+}
+
+type SyncManifestResponse struct {
+	Manifest map[string]any `json:"manifest,omitempty"`
+	Message  string         `json:"message"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TOTPSetup struct {
+	OtpauthUri string `json:"otpauth_uri"`
+	Secret     string `json:"secret"`
+}
+
+type TrackingConsentRequest struct {
+	By    string `json:"by"`
+	Given bool   `json:"given"`
+}
+
+type TrackingSchedule struct {
+	ConsentBy         string     `json:"consent_by"`
+	ConsentGivenAt    *time.Time `json:"consent_given_at,omitempty"`
+	ConsentRecordedBy *int       `json:"consent_recorded_by,omitempty"`
+	ConsentRequired   bool       `json:"consent_required"`
+	ConsentRevokedAt  *time.Time `json:"consent_revoked_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	Enabled           bool       `json:"enabled"`
+	Holidays          any        `json:"holidays,omitempty"`
+	LastPushedAt      *time.Time `json:"last_pushed_at,omitempty"`
+	LastPushedPaused  *bool      `json:"last_pushed_paused,omitempty"`
+	OrganizationID    int        `json:"organization_id"`
+	OutsideAction     string     `json:"outside_action"`
+	Timezone          string     `json:"timezone"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	UserID            int        `json:"user_id"`
+	Windows           any        `json:"windows,omitempty"`
+}
+
+type TrackingScheduleInput struct {
+	ConsentRequired bool             `json:"consent_required"`
+	Enabled         *bool            `json:"enabled,omitempty"`
+	Holidays        []string         `json:"holidays,omitempty"`
+	OutsideAction   string           `json:"outside_action"`
+	Timezone        string           `json:"timezone"`
+	Windows         []TrackingWindow `json:"windows,omitempty"`
+}
+
+type TrackingScheduleResponse struct {
+	Schedule *TrackingSchedule       `json:"schedule,omitempty"`
+	Status   *TrackingScheduleStatus `json:"status,omitempty"`
+}
+
+type TrackingScheduleStatus struct {
+	Paused   bool       `json:"paused"`
+	Reason   string     `json:"reason"`
+	Since    *time.Time `json:"since,omitempty"`
+	Timezone string     `json:"timezone"`
+	Until    *time.Time `json:"until,omitempty"`
+}
+
+type TrackingWindow struct {
+	Days  []int  `json:"days,omitempty"`
+	End   string `json:"end"`
+	Start string `json:"start"`
+}
+
+type UpdateUserRequest struct {
+	Name     string  `json:"name"`
+	Timezone *string `json:"timezone,omitempty"`
+}
+
+type User struct {
+	CreatedAt      time.Time `json:"created_at"`
+	GroupID        *int      `json:"group_id,omitempty"`
+	ID             int       `json:"id"`
+	IsAdmin        bool      `json:"is_admin"`
+	Name           string    `json:"name"`
+	OrganizationID int       `json:"organization_id"`
+	QRCode         string    `json:"qr_code"`
+	Role           string    `json:"role"`
+	Timezone       string    `json:"timezone"`
+	TOTPEnabled    bool      `json:"totp_enabled"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Username       *string   `json:"username,omitempty"`
+}
+
+type UserAccessRequest struct {
+	GroupID *int   `json:"group_id,omitempty"`
+	Role    string `json:"role"`
+}
+
+type UserCommandRequest struct {
+	Payload map[string]any `json:"payload,omitempty"`
+	Type    string         `json:"type"`
+}
+
+type UserDeviceStatusSummary struct {
+	AgeSeconds   *int64   `json:"age_seconds,omitempty"`
+	AppVersion   string   `json:"app_version"`
+	GPS          string   `json:"gps"`
+	Healthy      *bool    `json:"healthy,omitempty"`
+	Issues       []string `json:"issues,omitempty"`
+	LastReportAt *string  `json:"last_report_at,omitempty"`
+	Platform     string   `json:"platform"`
+}
+
+type UserHealthResponse struct {
+	AppVersion   string         `json:"app_version"`
+	Healthy      bool           `json:"healthy"`
+	IssueCount   int            `json:"issue_count"`
+	Issues       []string       `json:"issues,omitempty"`
+	LastReportAt time.Time      `json:"last_report_at"`
+	Platform     string         `json:"platform"`
+	Report       map[string]any `json:"report,omitempty"`
+	UserID       int            `json:"user_id"`
+}
+
+type UserReleaseChannelRequest struct {
+	Channel string `json:"channel"`
+}
+
+type UserReleaseChannelResponse struct {
+	Channel string `json:"channel"`
+	UserID  int    `json:"user_id"`
+}
+
+type Visit struct {
+	CheckpointID int        `json:"checkpoint_id"`
+	Duration     int        `json:"duration"`
+	EndAt        *time.Time `json:"end_at,omitempty"`
+	ID           int64      `json:"id"`
+	Kind         string     `json:"kind"`
+	StartAt      time.Time  `json:"start_at"`
+	UserID       int        `json:"user_id"`
+}
+
+// AckDeviceCommand — POST /api/device/command/ack: Подтверждение команды.
+func (c *Client) AckDeviceCommand(ctx context.Context, body CommandAckRequest) (*CommandAckResponse, error) {
+	var out CommandAckResponse
+	if _, err := c.do(ctx, "POST", "/api/device/command/ack", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AcknowledgeAlert — POST /api/alerts/{id}/ack: Алерт принят в работу.
+func (c *Client) AcknowledgeAlert(ctx context.Context, id int) (*Alert, error) {
+	var out Alert
+	if _, err := c.do(ctx, "POST", "/api/alerts/"+pathParam(id)+"/ack", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AddGroupMember — POST /api/admin/groups/{id}/members: Перевести пользователя в группу.
+func (c *Client) AddGroupMember(ctx context.Context, id int, body GroupMemberRequest) (*User, error) {
+	var out User
+	if _, err := c.do(ctx, "POST", "/api/admin/groups/"+pathParam(id)+"/members", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ArchiveRelease — POST /api/admin/releases/{id}/archive: Архивировать релиз.
+func (c *Client) ArchiveRelease(ctx context.Context, id int, body ReleaseStatusRequest) (*AppRelease, error) {
+	var out AppRelease
+	if _, err := c.do(ctx, "POST", "/api/admin/releases/"+pathParam(id)+"/archive", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// BackfillCapturedAtParams — query-параметры BackfillCapturedAt. Нулевые значения не отправляются.
+type BackfillCapturedAtParams struct {
+	// UserID — только этот пользователь.
+	UserID int
+	// IntervalSeconds — шаг между точками очереди (по умолчанию 300).
+	IntervalSeconds int
+	// BurstGapSeconds — разрыв, начинающий новую пачку (по умолчанию 30).
+	BurstGapSeconds int
+	// DryRun — только подсчёт.
+	DryRun bool
+}
+
+func (p *BackfillCapturedAtParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "user_id", p.UserID)
+	setQuery(q, "interval_seconds", p.IntervalSeconds)
+	setQuery(q, "burst_gap_seconds", p.BurstGapSeconds)
+	setQuery(q, "dry_run", p.DryRun)
+	return q
+}
+
+// BackfillCapturedAt — POST /api/admin/locations/backfill-captured-at: Восстановить captured_at старых точек.
+func (c *Client) BackfillCapturedAt(ctx context.Context, params *BackfillCapturedAtParams) (*BackfillCapturedAtResult, error) {
+	var out BackfillCapturedAtResult
+	if _, err := c.do(ctx, "POST", "/api/admin/locations/backfill-captured-at", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ChangePassword — PUT /api/auth/password: Смена пароля; остальные сессии закрываются.
+func (c *Client) ChangePassword(ctx context.Context, body PasswordChangeRequest) (*StatusResponse, error) {
+	var out StatusResponse
+	if _, err := c.do(ctx, "PUT", "/api/auth/password", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CheckUserInCheckpointParams — query-параметры CheckUserInCheckpoint. Нулевые значения не отправляются.
+type CheckUserInCheckpointParams struct {
+	// UserID — пользователь (обязательно).
+	UserID int
+	// CheckpointID — чекпоинт (обязательно).
+	CheckpointID int
+}
+
+func (p *CheckUserInCheckpointParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "user_id", p.UserID)
+	setQuery(q, "checkpoint_id", p.CheckpointID)
+	return q
+}
+
+// CheckUserInCheckpoint — GET /api/checkpoint/check: Проверить последнюю точку пользователя в чекпоинте (асинхронно).
+func (c *Client) CheckUserInCheckpoint(ctx context.Context, params *CheckUserInCheckpointParams) (*MessageResponse, error) {
+	var out MessageResponse
+	if _, err := c.do(ctx, "GET", "/api/checkpoint/check", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateAlertRule — POST /api/admin/alert-rules: Новое правило.
+func (c *Client) CreateAlertRule(ctx context.Context, body AlertRule) (*AlertRule, error) {
+	var out AlertRule
+	if _, err := c.do(ctx, "POST", "/api/admin/alert-rules", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateCheckpoint — POST /api/checkpoint/: Новый чекпоинт.
+func (c *Client) CreateCheckpoint(ctx context.Context, body CheckpointRequest) (*Checkpoint, error) {
+	var out Checkpoint
+	if _, err := c.do(ctx, "POST", "/api/checkpoint/", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateDeviceConfigProfile — POST /api/admin/device-config/profiles: Новый профиль конфигурации.
+func (c *Client) CreateDeviceConfigProfile(ctx context.Context, body DeviceConfigProfileRequest) (*DeviceConfigProfile, error) {
+	var out DeviceConfigProfile
+	if _, err := c.do(ctx, "POST", "/api/admin/device-config/profiles", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateGroup — POST /api/admin/groups: Новая группа.
+func (c *Client) CreateGroup(ctx context.Context, body GroupRequest) (*Group, error) {
+	var out Group
+	if _, err := c.do(ctx, "POST", "/api/admin/groups", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateNotificationSubscription — POST /api/notifications/subscriptions: Новая подписка.
+func (c *Client) CreateNotificationSubscription(ctx context.Context, body NotificationSubscriptionInput) (*NotificationSubscription, error) {
+	var out NotificationSubscription
+	if _, err := c.do(ctx, "POST", "/api/notifications/subscriptions", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateOrganization — POST /api/admin/organizations: Организация и её первый super_admin.
+func (c *Client) CreateOrganization(ctx context.Context, body OrganizationRequest) (*OrganizationCreatedResponse, error) {
+	var out OrganizationCreatedResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/organizations", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateRelease — POST /api/admin/releases: Новый релиз.
+func (c *Client) CreateRelease(ctx context.Context, body AppReleaseInput) (*AppRelease, error) {
+	var out AppRelease
+	if _, err := c.do(ctx, "POST", "/api/admin/releases", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateUser — POST /api/users/: Новый пользователь с API-ключом и QR-кодом.
+func (c *Client) CreateUser(ctx context.Context, body CreateUserRequest) (*User, error) {
+	var out User
+	if _, err := c.do(ctx, "POST", "/api/users/", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateUserAPIKey — POST /api/admin/users/{id}/api-keys: Дополнительный API-ключ (показывается один раз).
+func (c *Client) CreateUserAPIKey(ctx context.Context, id int, body APIKeyInput) (*APIKeyCreatedResponse, error) {
+	var out APIKeyCreatedResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/users/"+pathParam(id)+"/api-keys", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateUserLoginLink — POST /api/admin/users/{id}/login-link: Одноразовая ссылка входа.
+func (c *Client) CreateUserLoginLink(ctx context.Context, id int) (*LoginLinkResponse, error) {
+	var out LoginLinkResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/users/"+pathParam(id)+"/login-link", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAlertRule — DELETE /api/admin/alert-rules/{id}: Удаление правила.
+func (c *Client) DeleteAlertRule(ctx context.Context, id int) (*OKResponse, error) {
+	var out OKResponse
+	if _, err := c.do(ctx, "DELETE", "/api/admin/alert-rules/"+pathParam(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteNotificationSubscription — DELETE /api/notifications/subscriptions/{id}: Удаление подписки.
+func (c *Client) DeleteNotificationSubscription(ctx context.Context, id int) (*OKResponse, error) {
+	var out OKResponse
+	if _, err := c.do(ctx, "DELETE", "/api/notifications/subscriptions/"+pathParam(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteRetentionPolicyParams — query-параметры DeleteRetentionPolicy. Нулевые значения не отправляются.
+type DeleteRetentionPolicyParams struct {
+	// OrganizationID — организация (по умолчанию — своя).
+	OrganizationID int
+}
+
+func (p *DeleteRetentionPolicyParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "organization_id", p.OrganizationID)
+	return q
+}
+
+// DeleteRetentionPolicy — DELETE /api/admin/retention/policy: Вернуть политику по умолчанию.
+func (c *Client) DeleteRetentionPolicy(ctx context.Context, params *DeleteRetentionPolicyParams) error {
+	_, err := c.do(ctx, "DELETE", "/api/admin/retention/policy", params.values(), nil, nil)
+	return err
+}
+
+// DeleteSession — DELETE /api/auth/sessions/{id}: Выход на другом устройстве.
+func (c *Client) DeleteSession(ctx context.Context, id string) (*StatusResponse, error) {
+	var out StatusResponse
+	if _, err := c.do(ctx, "DELETE", "/api/auth/sessions/"+pathParam(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteTrackingSchedule — DELETE /api/admin/users/{id}/tracking-schedule: Снять ограничения отслеживания.
+func (c *Client) DeleteTrackingSchedule(ctx context.Context, id int) (*StatusResponse, error) {
+	var out StatusResponse
+	if _, err := c.do(ctx, "DELETE", "/api/admin/users/"+pathParam(id)+"/tracking-schedule", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DisableTOTP — POST /api/auth/totp/disable: Выключить второй фактор.
+func (c *Client) DisableTOTP(ctx context.Context, body TOTPCodeRequest) (*StatusResponse, error) {
+	var out StatusResponse
+	if _, err := c.do(ctx, "POST", "/api/auth/totp/disable", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EnableDeviceLocation — POST /api/admin/users/{id}/enable-location: Включить разрешения и геолокацию (Device Owner).
+func (c *Client) EnableDeviceLocation(ctx context.Context, id int) (*DeviceWakeResponse, error) {
+	var out DeviceWakeResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/users/"+pathParam(id)+"/enable-location", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EnableTOTP — POST /api/auth/totp/enable: Включить второй фактор.
+func (c *Client) EnableTOTP(ctx context.Context, body TOTPCodeRequest) (*StatusResponse, error) {
+	var out StatusResponse
+	if _, err := c.do(ctx, "POST", "/api/auth/totp/enable", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EvaluateAlerts — POST /api/admin/alerts/evaluate: Внеочередная проверка правил.
+func (c *Client) EvaluateAlerts(ctx context.Context) (*AlertEvaluation, error) {
+	var out AlertEvaluation
+	if _, err := c.do(ctx, "POST", "/api/admin/alerts/evaluate", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ExportAuditParams — query-параметры ExportAudit. Нулевые значения не отправляются.
+type ExportAuditParams struct {
+	OrganizationID int
+	ActorID        int
+	// Action — начало имени действия.
+	Action     string
+	TargetType string
+	TargetID   string
+	Result     string
+	// From — RFC3339.
+	From string
+	// To — RFC3339.
+	To string
+	// Format — csv (по умолчанию) или jsonl.
+	Format string
+}
+
+func (p *ExportAuditParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "organization_id", p.OrganizationID)
+	setQuery(q, "actor_id", p.ActorID)
+	setQuery(q, "action", p.Action)
+	setQuery(q, "target_type", p.TargetType)
+	setQuery(q, "target_id", p.TargetID)
+	setQuery(q, "result", p.Result)
+	setQuery(q, "from", p.From)
+	setQuery(q, "to", p.To)
+	setQuery(q, "format", p.Format)
+	return q
+}
+
+// ExportAudit — GET /api/admin/audit/export: Выгрузка журнала аудита файлом.
+func (c *Client) ExportAudit(ctx context.Context, params *ExportAuditParams) ([]byte, error) {
+	return c.doRaw(ctx, "GET", "/api/admin/audit/export", params.values())
+}
+
+// GetConfigDrift — GET /api/admin/users/{id}/device/config-drift: Дрейф конфигурации устройства.
+func (c *Client) GetConfigDrift(ctx context.Context, id int) (*DeviceConfigDriftStatus, error) {
+	var out DeviceConfigDriftStatus
+	if _, err := c.do(ctx, "GET", "/api/admin/users/"+pathParam(id)+"/device/config-drift", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCurrentUser — GET /api/users/me: Текущий пользователь.
+func (c *Client) GetCurrentUser(ctx context.Context) (*CurrentUserResponse, error) {
+	var out CurrentUserResponse
+	if _, err := c.do(ctx, "GET", "/api/users/me", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDesiredConfig — GET /api/admin/users/{id}/device/desired-config: Желаемая конфигурация устройства.
+func (c *Client) GetDesiredConfig(ctx context.Context, id int) (*DesiredConfigResponse, error) {
+	var out DesiredConfigResponse
+	if _, err := c.do(ctx, "GET", "/api/admin/users/"+pathParam(id)+"/device/desired-config", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDeviceCommand — GET /api/admin/users/{id}/commands/{command_id}: Статус команды и ack устройства.
+func (c *Client) GetDeviceCommand(ctx context.Context, id int, commandID string) (*DeviceCommand, error) {
+	var out DeviceCommand
+	if _, err := c.do(ctx, "GET", "/api/admin/users/"+pathParam(id)+"/commands/"+pathParam(commandID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDevicesStatus — GET /api/admin/devices/status: Сводка GPS и health по всем пользователям.
+func (c *Client) GetDevicesStatus(ctx context.Context) (*DevicesStatusResponse, error) {
+	var out DevicesStatusResponse
+	if _, err := c.do(ctx, "GET", "/api/admin/devices/status", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetHealthz — GET /healthz: Процесс жив (прежнее имя /livez).
+func (c *Client) GetHealthz(ctx context.Context) (*StatusResponse, error) {
+	var out StatusResponse
+	if _, err := c.do(ctx, "GET", "/healthz", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLatestReleaseParams — query-параметры GetLatestRelease. Нулевые значения не отправляются.
+type GetLatestReleaseParams struct {
+	// Channel — канал (по умолчанию stable).
+	Channel string
+	// OrganizationID — организация (по умолчанию 1).
+	OrganizationID int
+}
+
+func (p *GetLatestReleaseParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "channel", p.Channel)
+	setQuery(q, "organization_id", p.OrganizationID)
+	return q
+}
+
+// GetLatestRelease — GET /api/app/release/latest: Манифест последнего релиза для OTA.
+func (c *Client) GetLatestRelease(ctx context.Context, params *GetLatestReleaseParams) (map[string]any, error) {
+	var out map[string]any
+	if _, err := c.do(ctx, "GET", "/api/app/release/latest", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetLivez — GET /livez: Процесс жив.
+func (c *Client) GetLivez(ctx context.Context) (*StatusResponse, error) {
+	var out StatusResponse
+	if _, err := c.do(ctx, "GET", "/livez", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLocationCurrentParams — query-параметры GetLocationCurrent. Нулевые значения не отправляются.
+type GetLocationCurrentParams struct {
+	// UserID — пользователь (по умолчанию — текущий).
+	UserID int
+	// MaxAgeSeconds — точка старше — 404 с location.
+	MaxAgeSeconds int
+}
+
+func (p *GetLocationCurrentParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "user_id", p.UserID)
+	setQuery(q, "max_age_seconds", p.MaxAgeSeconds)
+	return q
+}
+
+// GetLocationCurrent — GET /api/location/current: Последняя точка пользователя.
+func (c *Client) GetLocationCurrent(ctx context.Context, params *GetLocationCurrentParams) (*LocationSnapshot, error) {
+	var out LocationSnapshot
+	if _, err := c.do(ctx, "GET", "/api/location/current", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLocationRequest — GET /api/location/request/{request_id}: Состояние on-demand запроса.
+func (c *Client) GetLocationRequest(ctx context.Context, requestID string) (*LocationRequestStatusResponse, error) {
+	var out LocationRequestStatusResponse
+	if _, err := c.do(ctx, "GET", "/api/location/request/"+pathParam(requestID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLocationSingleParams — query-параметры GetLocationSingle. Нулевые значения не отправляются.
+type GetLocationSingleParams struct {
+	// UserID — пользователь (по умолчанию — текущий).
+	UserID int
+	// MaxAgeSeconds — точка старше — 404 с location.
+	MaxAgeSeconds int
+}
+
+func (p *GetLocationSingleParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "user_id", p.UserID)
+	setQuery(q, "max_age_seconds", p.MaxAgeSeconds)
+	return q
+}
+
+// GetLocationSingle — GET /api/location/single: Последняя точка пользователя (прежнее имя /location/current).
+func (c *Client) GetLocationSingle(ctx context.Context, params *GetLocationSingleParams) (*LocationSnapshot, error) {
+	var out LocationSnapshot
+	if _, err := c.do(ctx, "GET", "/api/location/single", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMatchedRouteParams — query-параметры GetMatchedRoute. Нулевые значения не отправляются.
+type GetMatchedRouteParams struct {
+	// UserID — пользователь (обязательно).
+	UserID int
+	// From — начало интервала: RFC3339 или YYYY-MM-DDTHH:mm в поясе пользователя.
+	From string
+	// To — конец интервала, как from.
+	To string
+	// Tz — IANA-пояс для from/to и времени в ответе.
+	Tz string
+}
+
+func (p *GetMatchedRouteParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "user_id", p.UserID)
+	setQuery(q, "from", p.From)
+	setQuery(q, "to", p.To)
+	setQuery(q, "tz", p.Tz)
+	return q
+}
+
+// GetMatchedRoute — GET /api/location/match-route: Трек, привязанный к дорогам (OSRM match).
+func (c *Client) GetMatchedRoute(ctx context.Context, params *GetMatchedRouteParams) (*MatchedRouteResponse, error) {
+	var out MatchedRouteResponse
+	if _, err := c.do(ctx, "GET", "/api/location/match-route", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOpenAPI — GET /api/openapi.json: Эта спецификация.
+func (c *Client) GetOpenAPI(ctx context.Context) (map[string]any, error) {
+	var out map[string]any
+	if _, err := c.do(ctx, "GET", "/api/openapi.json", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOwnQRCode — GET /api/users/qr-code: Свой QR-код.
+func (c *Client) GetOwnQRCode(ctx context.Context) (*QRCodeResponse, error) {
+	var out QRCodeResponse
+	if _, err := c.do(ctx, "GET", "/api/users/qr-code", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOwnQRCodeFile — GET /api/users/qr-code-file: Свой QR-код картинкой.
+func (c *Client) GetOwnQRCodeFile(ctx context.Context) ([]byte, error) {
+	return c.doRaw(ctx, "GET", "/api/users/qr-code-file", nil)
+}
+
+// GetRateLimits — GET /api/admin/rate-limits: Лимиты частоты: счётчики отказов.
+func (c *Client) GetRateLimits(ctx context.Context) (*RateLimitsResponse, error) {
+	var out RateLimitsResponse
+	if _, err := c.do(ctx, "GET", "/api/admin/rate-limits", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetReadyz — GET /readyz: Готовность: БД, брокер, миграции.
+func (c *Client) GetReadyz(ctx context.Context) (*ReadinessResponse, error) {
+	var out ReadinessResponse
+	if _, err := c.do(ctx, "GET", "/readyz", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetReleaseStats — GET /api/admin/releases/{id}/stats: Итоги app_update по релизу.
+func (c *Client) GetReleaseStats(ctx context.Context, id int) (*AppReleaseStats, error) {
+	var out AppReleaseStats
+	if _, err := c.do(ctx, "GET", "/api/admin/releases/"+pathParam(id)+"/stats", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetRetentionPolicyParams — query-параметры GetRetentionPolicy. Нулевые значения не отправляются.
+type GetRetentionPolicyParams struct {
+	// OrganizationID — организация (по умолчанию — своя).
+	OrganizationID int
+}
+
+func (p *GetRetentionPolicyParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "organization_id", p.OrganizationID)
+	return q
+}
+
+// GetRetentionPolicy — GET /api/admin/retention/policy: Политика хранения организации.
+func (c *Client) GetRetentionPolicy(ctx context.Context, params *GetRetentionPolicyParams) (*RetentionPolicyResponse, error) {
+	var out RetentionPolicyResponse
+	if _, err := c.do(ctx, "GET", "/api/admin/retention/policy", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetRetentionRun — GET /api/admin/retention/runs/{id}: Состояние прохода хранения.
+func (c *Client) GetRetentionRun(ctx context.Context, id int) (*RetentionRun, error) {
+	var out RetentionRun
+	if _, err := c.do(ctx, "GET", "/api/admin/retention/runs/"+pathParam(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTrackingSchedule — GET /api/admin/users/{id}/tracking-schedule: График отслеживания и текущее состояние.
+func (c *Client) GetTrackingSchedule(ctx context.Context, id int) (*TrackingScheduleResponse, error) {
+	var out TrackingScheduleResponse
+	if _, err := c.do(ctx, "GET", "/api/admin/users/"+pathParam(id)+"/tracking-schedule", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUser — GET /api/users/{id}: Пользователь.
+func (c *Client) GetUser(ctx context.Context, id int) (*User, error) {
+	var out User
+	if _, err := c.do(ctx, "GET", "/api/users/"+pathParam(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUserHealth — GET /api/users/{id}/health: Последний отчёт устройства и проблемы.
+func (c *Client) GetUserHealth(ctx context.Context, id int) (*UserHealthResponse, error) {
+	var out UserHealthResponse
+	if _, err := c.do(ctx, "GET", "/api/users/"+pathParam(id)+"/health", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUserIssueTimelineParams — query-параметры GetUserIssueTimeline. Нулевые значения не отправляются.
+type GetUserIssueTimelineParams struct {
+	// From — начало интервала: RFC3339 или YYYY-MM-DDTHH:mm в поясе пользователя.
+	From string
+	// To — конец интервала, как from.
+	To string
+	// Tz — IANA-пояс для from/to и времени в ответе.
+	Tz string
+}
+
+func (p *GetUserIssueTimelineParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "from", p.From)
+	setQuery(q, "to", p.To)
+	setQuery(q, "tz", p.Tz)
+	return q
+}
+
+// GetUserIssueTimeline — GET /api/users/{id}/reports/issues-timeline: Когда появлялись и уходили проблемы устройства.
+func (c *Client) GetUserIssueTimeline(ctx context.Context, id int, params *GetUserIssueTimelineParams) (*IssueTimelineResponse, error) {
+	var out IssueTimelineResponse
+	if _, err := c.do(ctx, "GET", "/api/users/"+pathParam(id)+"/reports/issues-timeline", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUserQRCode — GET /api/users/{id}/qr-code: QR-код пользователя.
+func (c *Client) GetUserQRCode(ctx context.Context, id int) (*QRCodeResponse, error) {
+	var out QRCodeResponse
+	if _, err := c.do(ctx, "GET", "/api/users/"+pathParam(id)+"/qr-code", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUserQRCodeFile — GET /api/users/{id}/qr-code-file: QR-код пользователя картинкой.
+func (c *Client) GetUserQRCodeFile(ctx context.Context, id int) ([]byte, error) {
+	return c.doRaw(ctx, "GET", "/api/users/"+pathParam(id)+"/qr-code-file", nil)
+}
+
+// GetUserReportTrendsParams — query-параметры GetUserReportTrends. Нулевые значения не отправляются.
+type GetUserReportTrendsParams struct {
+	// From — начало интервала: RFC3339 или YYYY-MM-DDTHH:mm в поясе пользователя.
+	From string
+	// To — конец интервала, как from.
+	To string
+	// Tz — IANA-пояс для from/to и времени в ответе.
+	Tz string
+}
+
+func (p *GetUserReportTrendsParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "from", p.From)
+	setQuery(q, "to", p.To)
+	setQuery(q, "tz", p.Tz)
+	return q
+}
+
+// GetUserReportTrends — GET /api/users/{id}/reports/trends: Батарея, сеть и фон по времени.
+func (c *Client) GetUserReportTrends(ctx context.Context, id int, params *GetUserReportTrendsParams) (*ReportTrendsResponse, error) {
+	var out ReportTrendsResponse
+	if _, err := c.do(ctx, "GET", "/api/users/"+pathParam(id)+"/reports/trends", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAlertRules — GET /api/admin/alert-rules: Правила алертинга.
+func (c *Client) ListAlertRules(ctx context.Context) ([]AlertRule, error) {
+	var out []AlertRule
+	if _, err := c.do(ctx, "GET", "/api/admin/alert-rules", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListAlertsParams — query-параметры ListAlerts. Нулевые значения не отправляются.
+type ListAlertsParams struct {
+	Status string
+	Type   string
+	UserID int
+	RuleID int
+	Limit  int
+	Offset int
+}
+
+func (p *ListAlertsParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "status", p.Status)
+	setQuery(q, "type", p.Type)
+	setQuery(q, "user_id", p.UserID)
+	setQuery(q, "rule_id", p.RuleID)
+	setQuery(q, "limit", p.Limit)
+	setQuery(q, "offset", p.Offset)
+	return q
+}
+
+// ListAlerts — GET /api/alerts: Алерты.
+func (c *Client) ListAlerts(ctx context.Context, params *ListAlertsParams) (*AlertListResponse, error) {
+	var out AlertListResponse
+	if _, err := c.do(ctx, "GET", "/api/alerts", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAuditParams — query-параметры ListAudit. Нулевые значения не отправляются.
+type ListAuditParams struct {
+	OrganizationID int
+	ActorID        int
+	// Action — начало имени действия.
+	Action     string
+	TargetType string
+	TargetID   string
+	Result     string
+	// From — RFC3339.
+	From string
+	// To — RFC3339.
+	To     string
+	Limit  int
+	Offset int
+}
+
+func (p *ListAuditParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "organization_id", p.OrganizationID)
+	setQuery(q, "actor_id", p.ActorID)
+	setQuery(q, "action", p.Action)
+	setQuery(q, "target_type", p.TargetType)
+	setQuery(q, "target_id", p.TargetID)
+	setQuery(q, "result", p.Result)
+	setQuery(q, "from", p.From)
+	setQuery(q, "to", p.To)
+	setQuery(q, "limit", p.Limit)
+	setQuery(q, "offset", p.Offset)
+	return q
+}
+
+// ListAudit — GET /api/admin/audit: Журнал аудита.
+func (c *Client) ListAudit(ctx context.Context, params *ListAuditParams) (*AuditListResponse, error) {
+	var out AuditListResponse
+	if _, err := c.do(ctx, "GET", "/api/admin/audit", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListCheckpoints — GET /api/checkpoint/: Чекпоинты организации.
+func (c *Client) ListCheckpoints(ctx context.Context) ([]Checkpoint, error) {
+	var out []Checkpoint
+	if _, err := c.do(ctx, "GET", "/api/checkpoint/", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListConfigDrift — GET /api/admin/devices/config-drift: Дрейф конфигурации по всем устройствам.
+func (c *Client) ListConfigDrift(ctx context.Context) (*ConfigDriftSummaryResponse, error) {
+	var out ConfigDriftSummaryResponse
+	if _, err := c.do(ctx, "GET", "/api/admin/devices/config-drift", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListDeviceConfigProfiles — GET /api/admin/device-config/profiles: Профили конфигурации.
+func (c *Client) ListDeviceConfigProfiles(ctx context.Context) ([]DeviceConfigProfile, error) {
+	var out []DeviceConfigProfile
+	if _, err := c.do(ctx, "GET", "/api/admin/device-config/profiles", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListGroupMembers — GET /api/admin/groups/{id}/members: Состав группы.
+func (c *Client) ListGroupMembers(ctx context.Context, id int) ([]User, error) {
+	var out []User
+	if _, err := c.do(ctx, "GET", "/api/admin/groups/"+pathParam(id)+"/members", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListGroups — GET /api/admin/groups: Группы.
+func (c *Client) ListGroups(ctx context.Context) ([]Group, error) {
+	var out []Group
+	if _, err := c.do(ctx, "GET", "/api/admin/groups", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLocationsParams — query-параметры ListLocations. Нулевые значения не отправляются.
+type ListLocationsParams struct {
+	// From — начало интервала: RFC3339 или YYYY-MM-DDTHH:mm в поясе пользователя.
+	From string
+	// To — конец интервала, как from.
+	To string
+	// Tz — IANA-пояс для from/to и времени в ответе.
+	Tz string
+	// Raw — все точки без фильтра значимых.
+	Raw bool
+}
+
+func (p *ListLocationsParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "from", p.From)
+	setQuery(q, "to", p.To)
+	setQuery(q, "tz", p.Tz)
+	setQuery(q, "raw", p.Raw)
+	return q
+}
+
+// ListLocations — GET /api/location/: Точки (по умолчанию — значимые).
+func (c *Client) ListLocations(ctx context.Context, params *ListLocationsParams) ([]Location, error) {
+	var out []Location
+	if _, err := c.do(ctx, "GET", "/api/location/", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListNotificationDeliveries — GET /api/notifications/deliveries: Последние попытки доставки.
+func (c *Client) ListNotificationDeliveries(ctx context.Context) ([]NotificationDelivery, error) {
+	var out []NotificationDelivery
+	if _, err := c.do(ctx, "GET", "/api/notifications/deliveries", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListNotificationSubscriptionsParams — query-параметры ListNotificationSubscriptions. Нулевые значения не отправляются.
+type ListNotificationSubscriptionsParams struct {
+	// UserID — чужие подписки (только администратор).
+	UserID int
+}
+
+func (p *ListNotificationSubscriptionsParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "user_id", p.UserID)
+	return q
+}
+
+// ListNotificationSubscriptions — GET /api/notifications/subscriptions: Подписки на уведомления.
+func (c *Client) ListNotificationSubscriptions(ctx context.Context, params *ListNotificationSubscriptionsParams) ([]NotificationSubscription, error) {
+	var out []NotificationSubscription
+	if _, err := c.do(ctx, "GET", "/api/notifications/subscriptions", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListOrganizations — GET /api/admin/organizations: Организации (администратор платформы).
+func (c *Client) ListOrganizations(ctx context.Context) ([]Organization, error) {
+	var out []Organization
+	if _, err := c.do(ctx, "GET", "/api/admin/organizations", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListReleaseChannelMembers — GET /api/admin/release-channels/members: Пользователи не-stable каналов.
+func (c *Client) ListReleaseChannelMembers(ctx context.Context) ([]AppReleaseMember, error) {
+	var out []AppReleaseMember
+	if _, err := c.do(ctx, "GET", "/api/admin/release-channels/members", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListReleaseChannels — GET /api/admin/release-channels: Каналы релизов.
+func (c *Client) ListReleaseChannels(ctx context.Context) ([]AppReleaseChannel, error) {
+	var out []AppReleaseChannel
+	if _, err := c.do(ctx, "GET", "/api/admin/release-channels", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListReleasesParams — query-параметры ListReleases. Нулевые значения не отправляются.
+type ListReleasesParams struct {
+	// Channel — только этот канал.
+	Channel string
+}
+
+func (p *ListReleasesParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "channel", p.Channel)
+	return q
+}
+
+// ListReleases — GET /api/admin/releases: История релизов.
+func (c *Client) ListReleases(ctx context.Context, params *ListReleasesParams) ([]AppRelease, error) {
+	var out []AppRelease
+	if _, err := c.do(ctx, "GET", "/api/admin/releases", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListRetentionRuns — GET /api/admin/retention/runs: Последние проходы хранения.
+func (c *Client) ListRetentionRuns(ctx context.Context) (*RetentionRunsResponse, error) {
+	var out RetentionRunsResponse
+	if _, err := c.do(ctx, "GET", "/api/admin/retention/runs", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSessions — GET /api/auth/sessions: Действующие сессии.
+func (c *Client) ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	var out []SessionInfo
+	if _, err := c.do(ctx, "GET", "/api/auth/sessions", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListUserAPIKeys — GET /api/admin/users/{id}/api-keys: API-ключи пользователя без секретов.
+func (c *Client) ListUserAPIKeys(ctx context.Context, id int) ([]APIKey, error) {
+	var out []APIKey
+	if _, err := c.do(ctx, "GET", "/api/admin/users/"+pathParam(id)+"/api-keys", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListUserReportsParams — query-параметры ListUserReports. Нулевые значения не отправляются.
+type ListUserReportsParams struct {
+	// From — начало интервала: RFC3339 или YYYY-MM-DDTHH:mm в поясе пользователя.
+	From string
+	// To — конец интервала, как from.
+	To string
+	// Tz — IANA-пояс для from/to и времени в ответе.
+	Tz     string
+	Limit  int
+	Offset int
+}
+
+func (p *ListUserReportsParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "from", p.From)
+	setQuery(q, "to", p.To)
+	setQuery(q, "tz", p.Tz)
+	setQuery(q, "limit", p.Limit)
+	setQuery(q, "offset", p.Offset)
+	return q
+}
+
+// ListUserReports — GET /api/users/{id}/reports: История отчётов (по умолчанию — 7 суток).
+func (c *Client) ListUserReports(ctx context.Context, id int, params *ListUserReportsParams) (*DeviceReportPage, error) {
+	var out DeviceReportPage
+	if _, err := c.do(ctx, "GET", "/api/users/"+pathParam(id)+"/reports", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListUsers — GET /api/users/: Пользователи организации.
+func (c *Client) ListUsers(ctx context.Context) ([]User, error) {
+	var out []User
+	if _, err := c.do(ctx, "GET", "/api/users/", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListVisitsParams — query-параметры ListVisits. Нулевые значения не отправляются.
+type ListVisitsParams struct {
+	ID           int
+	UserID       int
+	CheckpointID int
+	// Active — только открытые.
+	Active bool
+	// From — начало интервала: RFC3339 или YYYY-MM-DDTHH:mm в поясе пользователя.
+	From string
+	// To — конец интервала, как from.
+	To string
+	// Tz — IANA-пояс для from/to и времени в ответе.
+	Tz string
+	// IncludeOutside — вместе с визитами вне окна отслеживания.
+	IncludeOutside bool
+}
+
+func (p *ListVisitsParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "id", p.ID)
+	setQuery(q, "user_id", p.UserID)
+	setQuery(q, "checkpoint_id", p.CheckpointID)
+	setQuery(q, "active", p.Active)
+	setQuery(q, "from", p.From)
+	setQuery(q, "to", p.To)
+	setQuery(q, "tz", p.Tz)
+	setQuery(q, "include_outside", p.IncludeOutside)
+	return q
+}
+
+// ListVisits — GET /api/visits/: Визиты по фильтрам.
+func (c *Client) ListVisits(ctx context.Context, params *ListVisitsParams) ([]Visit, error) {
+	var out []Visit
+	if _, err := c.do(ctx, "GET", "/api/visits/", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Login — POST /api/auth/login: Вход по логину и паролю.
+func (c *Client) Login(ctx context.Context, body LoginRequest) (*SessionTokens, error) {
+	var out SessionTokens
+	if _, err := c.do(ctx, "POST", "/api/auth/login", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// LoginWithLink — POST /api/auth/login-link: Вход по одноразовой ссылке.
+func (c *Client) LoginWithLink(ctx context.Context, body LoginLinkRequest) (*SessionTokens, error) {
+	var out SessionTokens
+	if _, err := c.do(ctx, "POST", "/api/auth/login-link", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Logout — POST /api/auth/logout: Выход: закрыть текущую сессию.
+func (c *Client) Logout(ctx context.Context) (*StatusResponse, error) {
+	var out StatusResponse
+	if _, err := c.do(ctx, "POST", "/api/auth/logout", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PauseRelease — POST /api/admin/releases/{id}/pause: Пауза раскатки.
+func (c *Client) PauseRelease(ctx context.Context, id int, body ReleaseStatusRequest) (*AppRelease, error) {
+	var out AppRelease
+	if _, err := c.do(ctx, "POST", "/api/admin/releases/"+pathParam(id)+"/pause", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PollDeviceParams — query-параметры PollDevice. Нулевые значения не отправляются.
+type PollDeviceParams struct {
+	// JSON — вместо 204 — 200 {"pending": false}.
+	JSON bool
+}
+
+func (p *PollDeviceParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "json", p.JSON)
+	return q
+}
+
+// PollDevice — GET /api/device/poll: Опрос команд устройством.
+// Ответ без тела (204) — нулевое значение без ошибки.
+func (c *Client) PollDevice(ctx context.Context, params *PollDeviceParams) (*DevicePollResponse, error) {
+	var out DevicePollResponse
+	status, err := c.do(ctx, "GET", "/api/device/poll", params.values(), nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNoContent {
+		return nil, nil
+	}
+	return &out, nil
+}
+
+// PollLocationRequestParams — query-параметры PollLocationRequest. Нулевые значения не отправляются.
+type PollLocationRequestParams struct {
+	// JSON — вместо 204 — 200 {"pending": false}.
+	JSON bool
+}
+
+func (p *PollLocationRequestParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "json", p.JSON)
+	return q
+}
+
+// PollLocationRequest — GET /api/location/request: Legacy-опрос on-demand запроса.
+// Ответ без тела (204) — нулевое значение без ошибки.
+func (c *Client) PollLocationRequest(ctx context.Context, params *PollLocationRequestParams) (*LocationRequestPollResponse, error) {
+	var out LocationRequestPollResponse
+	status, err := c.do(ctx, "GET", "/api/location/request", params.values(), nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNoContent {
+		return nil, nil
+	}
+	return &out, nil
+}
+
+// PostDeviceReport — POST /api/device/report: Диагностический отчёт телефона.
+func (c *Client) PostDeviceReport(ctx context.Context, body DeviceReportPayload) (*DeviceReportResponse, error) {
+	var out DeviceReportResponse
+	if _, err := c.do(ctx, "POST", "/api/device/report", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostLocation — POST /api/location: Новая точка.
+func (c *Client) PostLocation(ctx context.Context, body LocationUploadRequest) (*LocationUploadResponse, error) {
+	var out LocationUploadResponse
+	if _, err := c.do(ctx, "POST", "/api/location", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PublishAppUpdateParams — query-параметры PublishAppUpdate. Нулевые значения не отправляются.
+type PublishAppUpdateParams struct {
+	// ReleaseID — релиз из БД (по умолчанию — manifest.json).
+	ReleaseID int
+}
+
+func (p *PublishAppUpdateParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "release_id", p.ReleaseID)
+	return q
+}
+
+// PublishAppUpdate — POST /api/admin/releases/publish-update/{user_id}: Команда app_update на устройство.
+func (c *Client) PublishAppUpdate(ctx context.Context, userID int, params *PublishAppUpdateParams) (*DeviceCommandResponse, error) {
+	var out DeviceCommandResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/releases/publish-update/"+pathParam(userID), params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PublishEvent — POST /api/event/publish: Событие точки в очередь визитов.
+func (c *Client) PublishEvent(ctx context.Context, body LocationEvent) (*MessageResponse, error) {
+	var out MessageResponse
+	if _, err := c.do(ctx, "POST", "/api/event/publish", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RecordTrackingConsent — POST /api/admin/users/{id}/tracking-consent: Согласие на отслеживание получено или отозвано.
+func (c *Client) RecordTrackingConsent(ctx context.Context, id int, body TrackingConsentRequest) (*TrackingScheduleResponse, error) {
+	var out TrackingScheduleResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/users/"+pathParam(id)+"/tracking-consent", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RefreshSession — POST /api/auth/refresh: Обновление access-токена.
+func (c *Client) RefreshSession(ctx context.Context, body RefreshRequest) (*SessionTokens, error) {
+	var out SessionTokens
+	if _, err := c.do(ctx, "POST", "/api/auth/refresh", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RegenerateUserQR — POST /api/admin/users/{id}/regenerate-qr: Новый API-ключ и QR-код.
+func (c *Client) RegenerateUserQR(ctx context.Context, id int, body RegenerateQRRequest) (*RegenerateQRResponse, error) {
+	var out RegenerateQRResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/users/"+pathParam(id)+"/regenerate-qr", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveGroupMember — DELETE /api/admin/groups/{id}/members/{user_id}: Убрать пользователя из группы.
+func (c *Client) RemoveGroupMember(ctx context.Context, id int, userID int) (*User, error) {
+	var out User
+	if _, err := c.do(ctx, "DELETE", "/api/admin/groups/"+pathParam(id)+"/members/"+pathParam(userID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RequestLocation — POST /api/location/request: On-demand запрос точки с телефона.
+func (c *Client) RequestLocation(ctx context.Context, body OnDemandLocationRequest) (*LocationRequestQueuedResponse, error) {
+	var out LocationRequestQueuedResponse
+	if _, err := c.do(ctx, "POST", "/api/location/request", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResolveAlert — POST /api/alerts/{id}/resolve: Закрыть алерт.
+func (c *Client) ResolveAlert(ctx context.Context, id int) (*Alert, error) {
+	var out Alert
+	if _, err := c.do(ctx, "POST", "/api/alerts/"+pathParam(id)+"/resolve", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResumeRelease — POST /api/admin/releases/{id}/resume: Возобновить раскатку.
+func (c *Client) ResumeRelease(ctx context.Context, id int, body ReleaseStatusRequest) (*AppRelease, error) {
+	var out AppRelease
+	if _, err := c.do(ctx, "POST", "/api/admin/releases/"+pathParam(id)+"/resume", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RevokeUserAPIKey — DELETE /api/admin/users/{id}/api-keys/{key_id}: Отзыв API-ключа.
+func (c *Client) RevokeUserAPIKey(ctx context.Context, id int, keyID int) (*APIKey, error) {
+	var out APIKey
+	if _, err := c.do(ctx, "DELETE", "/api/admin/users/"+pathParam(id)+"/api-keys/"+pathParam(keyID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RevokeUserSessions — POST /api/admin/users/{id}/sessions/revoke: Закрыть все сессии сотрудника.
+func (c *Client) RevokeUserSessions(ctx context.Context, id int) (*StatusResponse, error) {
+	var out StatusResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/users/"+pathParam(id)+"/sessions/revoke", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SendDeviceCommand — POST /api/admin/users/{id}/commands: Команда устройству.
+func (c *Client) SendDeviceCommand(ctx context.Context, id int, body UserCommandRequest) (*DeviceCommandResponse, error) {
+	var out DeviceCommandResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/users/"+pathParam(id)+"/commands", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SendDeviceConfig — POST /api/admin/users/{id}/device/config: Валидированный config_update.
+func (c *Client) SendDeviceConfig(ctx context.Context, id int, body DeviceConfigUpdateInput) (*DeviceCommandResponse, error) {
+	var out DeviceCommandResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/users/"+pathParam(id)+"/device/config", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetDesiredConfig — PUT /api/admin/users/{id}/device/desired-config: Заменить желаемую конфигурацию.
+func (c *Client) SetDesiredConfig(ctx context.Context, id int, body DeviceDesiredConfigRequest) (*DesiredConfigResponse, error) {
+	var out DesiredConfigResponse
+	if _, err := c.do(ctx, "PUT", "/api/admin/users/"+pathParam(id)+"/device/desired-config", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetOrganizationTimezone — PUT /api/admin/organizations/{id}/timezone: Часовой пояс организации.
+func (c *Client) SetOrganizationTimezone(ctx context.Context, id int, body OrganizationTimezoneRequest) (*Organization, error) {
+	var out Organization
+	if _, err := c.do(ctx, "PUT", "/api/admin/organizations/"+pathParam(id)+"/timezone", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetReleaseChannel — PUT /api/admin/release-channels/{name}: Минимальная версия канала.
+func (c *Client) SetReleaseChannel(ctx context.Context, name string, body ReleaseChannelRequest) (*AppReleaseChannel, error) {
+	var out AppReleaseChannel
+	if _, err := c.do(ctx, "PUT", "/api/admin/release-channels/"+pathParam(name), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetRetentionPolicyParams — query-параметры SetRetentionPolicy. Нулевые значения не отправляются.
+type SetRetentionPolicyParams struct {
+	// OrganizationID — организация (по умолчанию — своя).
+	OrganizationID int
+}
+
+func (p *SetRetentionPolicyParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	setQuery(q, "organization_id", p.OrganizationID)
+	return q
+}
+
+// SetRetentionPolicy — PUT /api/admin/retention/policy: Изменение политики хранения.
+func (c *Client) SetRetentionPolicy(ctx context.Context, params *SetRetentionPolicyParams, body RetentionPolicyInput) (*RetentionPolicyResponse, error) {
+	var out RetentionPolicyResponse
+	if _, err := c.do(ctx, "PUT", "/api/admin/retention/policy", params.values(), body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetTrackingSchedule — PUT /api/admin/users/{id}/tracking-schedule: Заменить график отслеживания.
+func (c *Client) SetTrackingSchedule(ctx context.Context, id int, body TrackingScheduleInput) (*TrackingScheduleResponse, error) {
+	var out TrackingScheduleResponse
+	if _, err := c.do(ctx, "PUT", "/api/admin/users/"+pathParam(id)+"/tracking-schedule", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetUserAccess — PUT /api/admin/users/{id}/access: Роль и группа пользователя.
+func (c *Client) SetUserAccess(ctx context.Context, id int, body UserAccessRequest) (*User, error) {
+	var out User
+	if _, err := c.do(ctx, "PUT", "/api/admin/users/"+pathParam(id)+"/access", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetUserCredentials — PUT /api/admin/users/{id}/credentials: Логин и пароль сотрудника.
+func (c *Client) SetUserCredentials(ctx context.Context, id int, body CredentialsInput) (*User, error) {
+	var out User
+	if _, err := c.do(ctx, "PUT", "/api/admin/users/"+pathParam(id)+"/credentials", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetUserReleaseChannel — PUT /api/admin/users/{id}/release-channel: Канал релизов пользователя.
+func (c *Client) SetUserReleaseChannel(ctx context.Context, id int, body UserReleaseChannelRequest) (*UserReleaseChannelResponse, error) {
+	var out UserReleaseChannelResponse
+	if _, err := c.do(ctx, "PUT", "/api/admin/users/"+pathParam(id)+"/release-channel", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetupTOTP — POST /api/auth/totp/setup: Новый секрет TOTP (включается через /totp/enable).
+func (c *Client) SetupTOTP(ctx context.Context) (*TOTPSetup, error) {
+	var out TOTPSetup
+	if _, err := c.do(ctx, "POST", "/api/auth/totp/setup", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StartRetentionRun — POST /api/admin/retention/runs: Запустить проход хранения в фоне.
+func (c *Client) StartRetentionRun(ctx context.Context, body RetentionRunRequest) (*RetentionRun, error) {
+	var out RetentionRun
+	if _, err := c.do(ctx, "POST", "/api/admin/retention/runs", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SyncReleaseManifest — POST /api/admin/releases/sync-manifest: Пересчитать manifest.json из APK.
+func (c *Client) SyncReleaseManifest(ctx context.Context, body SyncManifestRequest) (*SyncManifestResponse, error) {
+	var out SyncManifestResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/releases/sync-manifest", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// TestNotificationSubscription — POST /api/notifications/subscriptions/{id}/test: Тестовое сообщение в канал подписки.
+func (c *Client) TestNotificationSubscription(ctx context.Context, id int) (*NotificationDelivery, error) {
+	var out NotificationDelivery
+	if _, err := c.do(ctx, "POST", "/api/notifications/subscriptions/"+pathParam(id)+"/test", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateAlertRule — PUT /api/admin/alert-rules/{id}: Изменение правила.
+func (c *Client) UpdateAlertRule(ctx context.Context, id int, body AlertRule) (*AlertRule, error) {
+	var out AlertRule
+	if _, err := c.do(ctx, "PUT", "/api/admin/alert-rules/"+pathParam(id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateCheckpoint — PUT /api/checkpoint/{id}: Изменение чекпоинта.
+func (c *Client) UpdateCheckpoint(ctx context.Context, id int, body CheckpointRequest) (*Checkpoint, error) {
+	var out Checkpoint
+	if _, err := c.do(ctx, "PUT", "/api/checkpoint/"+pathParam(id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateDeviceConfigProfile — PUT /api/admin/device-config/profiles/{id}: Изменение профиля конфигурации.
+func (c *Client) UpdateDeviceConfigProfile(ctx context.Context, id int, body DeviceConfigProfileRequest) (*DeviceConfigProfile, error) {
+	var out DeviceConfigProfile
+	if _, err := c.do(ctx, "PUT", "/api/admin/device-config/profiles/"+pathParam(id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateNotificationSubscription — PUT /api/notifications/subscriptions/{id}: Изменение подписки.
+func (c *Client) UpdateNotificationSubscription(ctx context.Context, id int, body NotificationSubscriptionInput) (*NotificationSubscription, error) {
+	var out NotificationSubscription
+	if _, err := c.do(ctx, "PUT", "/api/notifications/subscriptions/"+pathParam(id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateRelease — PUT /api/admin/releases/{id}: Раскатка, порог автопаузы, force, changelog.
+func (c *Client) UpdateRelease(ctx context.Context, id int, body AppReleaseUpdateInput) (*AppRelease, error) {
+	var out AppRelease
+	if _, err := c.do(ctx, "PUT", "/api/admin/releases/"+pathParam(id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateUser — PUT /api/users/{id}: Имя и часовой пояс пользователя.
+func (c *Client) UpdateUser(ctx context.Context, id int, body UpdateUserRequest) (*User, error) {
+	var out User
+	if _, err := c.do(ctx, "PUT", "/api/users/"+pathParam(id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// WakeDevice — POST /api/admin/users/{id}/wake: Пробудить трекинг на телефоне.
+func (c *Client) WakeDevice(ctx context.Context, id int) (*DeviceWakeResponse, error) {
+	var out DeviceWakeResponse
+	if _, err := c.do(ctx, "POST", "/api/admin/users/"+pathParam(id)+"/wake", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
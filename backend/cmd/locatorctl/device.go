@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"locator/client"
+	"locator/models"
+)
+
+// commandPollInterval — как часто опрашивается статус команды при -wait.
+var commandPollInterval = 2 * time.Second
+
+func cmdCommandSend(ctx context.Context, a *app, args []string) error {
+	fs := flags("command send")
+	userID := fs.Int("user", 0, "ID пользователя")
+	typ := fs.String("type", "", "тип: location_request, health_check, config_update, app_update")
+	payload := fs.String("payload", "", "payload команды (JSON-объект)")
+	wait := fs.Duration("wait", 0, "ждать ack от телефона не дольше")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "user", "type"); err != nil {
+		return err
+	}
+	req := client.UserCommandRequest{Type: *typ}
+	if *payload != "" {
+		if err := json.Unmarshal([]byte(*payload), &req.Payload); err != nil {
+			return usageError("command send: -payload не JSON-объект: " + err.Error())
+		}
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+
+	resp, err := a.api.SendDeviceCommand(ctx, *userID, req)
+	if err != nil {
+		return err
+	}
+	if *wait <= 0 {
+		return a.print(resp, func(w io.Writer) {
+			fmt.Fprintf(w, "Команда %s (%s) поставлена в очередь: %s\n", resp.CommandID, resp.Type, resp.Status)
+		})
+	}
+	if !a.json {
+		fmt.Fprintf(a.out, "Команда %s (%s) поставлена в очередь, ждём ack до %s\n", resp.CommandID, resp.Type, *wait)
+	}
+	return a.watchCommand(ctx, resp.UserID, resp.CommandID, *wait)
+}
+
+func cmdCommandStatus(ctx context.Context, a *app, args []string) error {
+	fs := flags("command status")
+	userID := fs.Int("user", 0, "ID пользователя")
+	id := fs.String("id", "", "ID команды")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "user", "id"); err != nil {
+		return err
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+	cmd, err := a.api.GetDeviceCommand(ctx, *userID, *id)
+	if err != nil {
+		return err
+	}
+	return a.print(cmd, func(w io.Writer) {
+		fmt.Fprintf(w, "%s %s: %s\n", cmd.ID, cmd.Type, describeCommand(cmd))
+		fmt.Fprintf(w, "создана %s, доставлена %s, ack %s\n",
+			formatTime(&cmd.CreatedAt), formatTime(cmd.DeliveredAt), formatTime(cmd.AckedAt))
+	})
+}
+
+// watchCommand опрашивает команду и печатает каждое изменение статуса или ack
+// (app_update присылает прогресс: downloading → installing → installed). Ошибка —
+// команда завершилась неудачей, истекла или не дождались за timeout.
+func (a *app) watchCommand(ctx context.Context, userID int, commandID string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(commandPollInterval)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		cmd, err := a.api.GetDeviceCommand(ctx, userID, commandID)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if cmd != nil {
+			if state := cmd.Status + "/" + cmd.AckStatus; state != last {
+				last = state
+				line := fmt.Sprintf("%s %s: %s", time.Now().Format("15:04:05"), cmd.ID, describeCommand(cmd))
+				if err := a.printLine(cmd, line); err != nil {
+					return err
+				}
+			}
+			switch cmd.Status {
+			case models.DeviceCommandStatusAcked:
+				return nil
+			case models.DeviceCommandStatusFailed, models.DeviceCommandStatusExpired:
+				return fmt.Errorf("команда %s: %s", cmd.ID, describeCommand(cmd))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("команда %s: нет ack за %s", commandID, timeout)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func describeCommand(cmd *client.DeviceCommand) string {
+	parts := []string{cmd.Status}
+	if cmd.AckStatus != "" {
+		parts = append(parts, cmd.AckStatus)
+	}
+	if cmd.AckMessage != "" {
+		parts = append(parts, "«"+cmd.AckMessage+"»")
+	}
+	return strings.Join(parts, " ")
+}
+
+// healthDump — всё, что сервер знает о телефоне: отчёт, дрейф конфигурации, последняя точка.
+type healthDump struct {
+	UserID   int                             `json:"user_id"`
+	Health   *client.UserHealthResponse      `json:"health,omitempty"`
+	Config   *client.DeviceConfigDriftStatus `json:"config,omitempty"`
+	Location *client.LocationSnapshot        `json:"location,omitempty"`
+}
+
+func cmdHealth(ctx context.Context, a *app, args []string) error {
+	fs := flags("health")
+	userID := fs.Int("user", 0, "ID пользователя")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "user"); err != nil {
+		return err
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+
+	// 404 — телефон ещё не присылал отчёт, конфигурацию или точку: это часть ответа.
+	dump := healthDump{UserID: *userID}
+	var err error
+	if dump.Health, err = a.api.GetUserHealth(ctx, *userID); err != nil && !isNotFound(err) {
+		return err
+	}
+	if dump.Config, err = a.api.GetConfigDrift(ctx, *userID); err != nil && !isNotFound(err) {
+		return err
+	}
+	if dump.Location, err = a.api.GetLocationCurrent(ctx, &client.GetLocationCurrentParams{UserID: *userID}); err != nil && !isNotFound(err) {
+		return err
+	}
+	return a.print(dump, func(w io.Writer) { printHealth(w, dump) })
+}
+
+func printHealth(w io.Writer, d healthDump) {
+	fmt.Fprintf(w, "Пользователь %d\n", d.UserID)
+	if h := d.Health; h != nil {
+		state := "в порядке"
+		if !h.Healthy {
+			state = fmt.Sprintf("проблем: %d", h.IssueCount)
+		}
+		fmt.Fprintf(w, "Устройство:   %s %s, отчёт %s — %s\n", h.Platform, h.AppVersion, formatTime(&h.LastReportAt), state)
+		for _, issue := range h.Issues {
+			fmt.Fprintf(w, "  ! %s\n", issue)
+		}
+	} else {
+		fmt.Fprintln(w, "Устройство:   отчётов не было")
+	}
+
+	if c := d.Config; c != nil {
+		switch {
+		case !c.Reported:
+			fmt.Fprintln(w, "Конфигурация: телефон не сообщал применённые настройки")
+		case c.InSync:
+			fmt.Fprintf(w, "Конфигурация: совпадает с профилем (отчёт %s)\n", formatTime(c.ReportedAt))
+		default:
+			fmt.Fprintf(w, "Конфигурация: расходится с профилем (отчёт %s)\n", formatTime(c.ReportedAt))
+			for _, field := range sortedKeys(c.Drift) {
+				fmt.Fprintf(w, "  %s: нужно %v, на телефоне %v\n", field, c.Drift[field].Desired, c.Drift[field].Reported)
+			}
+		}
+	}
+
+	if l := d.Location; l != nil {
+		fmt.Fprintf(w, "Точка:        %.6f, %.6f — %s назад (id %d)\n",
+			l.Latitude, l.Longitude, time.Duration(l.AgeSeconds)*time.Second, l.ID)
+	} else {
+		fmt.Fprintln(w, "Точка:        нет")
+	}
+
+	if d.Health != nil && len(d.Health.Report) > 0 {
+		report, _ := json.MarshalIndent(d.Health.Report, "  ", "  ")
+		fmt.Fprintf(w, "Отчёт:\n  %s\n", report)
+	}
+}
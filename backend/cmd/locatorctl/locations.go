@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"locator/client"
+)
+
+func cmdLocationsTail(ctx context.Context, a *app, args []string) error {
+	fs := flags("locations tail")
+	users := fs.String("user", "", "ID пользователей через запятую (по умолчанию — все)")
+	interval := fs.Duration("interval", 5*time.Second, "период опроса")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return usageError("locations tail: -interval должен быть положительным")
+	}
+	userIDs, err := parseIDs(*users)
+	if err != nil {
+		return usageError("locations tail: -user: " + err.Error())
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		list, err := a.api.ListUsers(ctx)
+		if err != nil {
+			return err
+		}
+		for _, u := range list {
+			userIDs = append(userIDs, u.ID)
+		}
+	}
+	if !a.json {
+		fmt.Fprintf(a.errOut, "Следим за %d пользователями, опрос каждые %s (Ctrl+C — выход)\n", len(userIDs), *interval)
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	// Последняя показанная точка: новая — другой ID (сервер хранит текущую точку на пользователя).
+	seen := make(map[int]int)
+	for {
+		for _, id := range userIDs {
+			loc, err := a.api.GetLocationCurrent(ctx, &client.GetLocationCurrentParams{UserID: id})
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				if !isNotFound(err) {
+					fmt.Fprintf(a.errOut, "пользователь %d: %v\n", id, err)
+				}
+				continue
+			}
+			if last, ok := seen[id]; ok && last == loc.ID && loc.ID != 0 {
+				continue
+			}
+			seen[id] = loc.ID
+			if err := a.printLine(loc, formatLocation(loc)); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func formatLocation(l *client.LocationSnapshot) string {
+	at := l.CreatedAt
+	if l.CapturedAt != nil {
+		at = *l.CapturedAt
+	}
+	return fmt.Sprintf("%s  user %-5d %.6f, %.6f  (%s назад)",
+		at.Local().Format("2006-01-02 15:04:05"), l.UserID, l.Latitude, l.Longitude, time.Duration(l.AgeSeconds)*time.Second)
+}
+
+// parseIDs разбирает "1,2,3"; пустая строка — пустой список.
+func parseIDs(s string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("неверный ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,245 @@
+// locatorctl — консоль оператора: пользователи и ключи, команды устройствам, живые
+// точки, релизы и обслуживание данных. Работает через REST API (пакет client);
+// пересборка визитов — напрямую с БД, как cmd/migrate.
+//
+// Адрес и вход — флаги или переменные окружения, как у scripts/admin_session.sh:
+// LOCATOR_URL (или BASE_URL), LOCATOR_TOKEN — готовый access-токен, иначе вход по
+// LOCATOR_ADMIN_USERNAME / LOCATOR_ADMIN_PASSWORD / LOCATOR_ADMIN_TOTP.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"locator/client"
+)
+
+const usage = `usage: locatorctl [-url URL] [-token TOKEN] [-json] <команда> [флаги]
+
+  login                        войти и напечатать access-токен (export LOCATOR_TOKEN=...)
+  users list                   пользователи организации
+  users create -name N         новый пользователь, QR-код привязки в терминал
+  keys rotate -user ID         новый API-ключ и QR (по умолчанию ключ уходит на телефон)
+  keys list -user ID           API-ключи пользователя
+  keys revoke -user ID -key K  отозвать ключ
+  command send -user ID -type T [-payload JSON] [-wait 2m]
+                               команда устройству и ожидание ack
+  command status -user ID -id C
+  locations tail [-user ID,ID] живые точки (по умолчанию — всех пользователей)
+  health -user ID              диагностика устройства: отчёт, проблемы, дрейф, точка
+  backfill captured-at [-user ID] [-dry-run]
+                               восстановить captured_at старых точек
+  visits rebuild -user ID -from T -to T [-dry-run]
+                               пересобрать визиты по точкам (БД, как cmd/migrate)
+  releases list [-channel C]
+  releases publish -apk FILE [-channel C] [-rollout N] [-changelog S] [-force]
+  releases pause|resume|archive -id N [-reason S]
+  releases stats -id N
+
+-json — вывод в JSON (для tail и command send -wait — JSON Lines).`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	var usageErr usageError
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+	case errors.As(err, &usageErr):
+		if usageErr != "" {
+			fmt.Fprintln(os.Stderr, "locatorctl:", string(usageErr))
+		}
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, "locatorctl:", err)
+		os.Exit(1)
+	}
+}
+
+// usageError — неверный вызов: печатается usage, код выхода 2.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+type app struct {
+	api    *client.Client
+	json   bool
+	out    io.Writer
+	errOut io.Writer
+	env    func(string) string
+}
+
+type command func(ctx context.Context, a *app, args []string) error
+
+var commands = map[string]command{
+	"login":                cmdLogin,
+	"users list":           cmdUsersList,
+	"users create":         cmdUsersCreate,
+	"keys rotate":          cmdKeysRotate,
+	"keys list":            cmdKeysList,
+	"keys revoke":          cmdKeysRevoke,
+	"command send":         cmdCommandSend,
+	"command status":       cmdCommandStatus,
+	"locations tail":       cmdLocationsTail,
+	"health":               cmdHealth,
+	"backfill captured-at": cmdBackfillCapturedAt,
+	"visits rebuild":       cmdVisitsRebuild,
+	"releases list":        cmdReleasesList,
+	"releases publish":     cmdReleasesPublish,
+	"releases pause":       cmdReleasesStatus("pause"),
+	"releases resume":      cmdReleasesStatus("resume"),
+	"releases archive":     cmdReleasesStatus("archive"),
+	"releases stats":       cmdReleasesStats,
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	return runWithEnv(ctx, args, stdout, stderr, os.Getenv)
+}
+
+func runWithEnv(ctx context.Context, args []string, stdout, stderr io.Writer, env func(string) string) error {
+	fs := flag.NewFlagSet("locatorctl", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	baseURL := fs.String("url", firstNonEmpty(env("LOCATOR_URL"), env("BASE_URL"), "http://localhost:8080"), "адрес сервера")
+	token := fs.String("token", env("LOCATOR_TOKEN"), "access-токен сессии")
+	asJSON := fs.Bool("json", false, "вывод в JSON")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	rest := fs.Args()
+	if len(rest) == 0 {
+		return usageError("")
+	}
+
+	// Команда — одно или два слова: "health", "users create".
+	name := rest[0]
+	cmd, ok := commands[name]
+	if !ok && len(rest) > 1 {
+		name = rest[0] + " " + rest[1]
+		cmd, ok = commands[name]
+	}
+	if !ok {
+		return usageError("неизвестная команда: " + strings.Join(rest[:min(2, len(rest))], " "))
+	}
+	cmdArgs := rest[len(strings.Fields(name)):]
+
+	api := client.New(*baseURL)
+	api.Token = *token
+	a := &app{api: api, json: *asJSON, out: stdout, errOut: stderr, env: env}
+	return cmd(ctx, a, cmdArgs)
+}
+
+// flags — набор флагов подкоманды; ошибки разбора — usageError.
+func flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return usageError(fs.Name() + ": " + err.Error())
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs.Name() + ": лишние аргументы: " + strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+func required(fs *flag.FlagSet, names ...string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, name := range names {
+		if !set[name] {
+			return usageError(fmt.Sprintf("%s: нужен флаг -%s", fs.Name(), name))
+		}
+	}
+	return nil
+}
+
+// session обеспечивает access-токен: административные маршруты не принимают API-ключ.
+func (a *app) session(ctx context.Context) error {
+	if a.api.Token != "" {
+		return nil
+	}
+	password := a.env("LOCATOR_ADMIN_PASSWORD")
+	if password == "" {
+		return errors.New("нужна сессия: задайте LOCATOR_TOKEN или LOCATOR_ADMIN_PASSWORD (см. locatorctl login)")
+	}
+	tokens, err := a.api.Login(ctx, client.LoginRequest{
+		Username: firstNonEmpty(a.env("LOCATOR_ADMIN_USERNAME"), "admin"),
+		Password: password,
+		TOTPCode: a.env("LOCATOR_ADMIN_TOTP"),
+	})
+	if err != nil {
+		return fmt.Errorf("вход: %w", err)
+	}
+	a.api.Token = tokens.AccessToken
+	return nil
+}
+
+// print выводит v как JSON (-json) или человекочитаемо через human.
+func (a *app) print(v any, human func(w io.Writer)) error {
+	if a.json {
+		enc := json.NewEncoder(a.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	human(a.out)
+	return nil
+}
+
+// printLine — одна запись потока: JSON Lines при -json.
+func (a *app) printLine(v any, human string) error {
+	if a.json {
+		return json.NewEncoder(a.out).Encode(v)
+	}
+	_, err := fmt.Fprintln(a.out, human)
+	return err
+}
+
+func cmdLogin(ctx context.Context, a *app, args []string) error {
+	if err := parse(flags("login"), args); err != nil {
+		return err
+	}
+	a.api.Token = ""
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+	return a.print(map[string]string{"access_token": a.api.Token}, func(w io.Writer) {
+		fmt.Fprintln(w, a.api.Token)
+	})
+}
+
+// isNotFound — 404 от API: для необязательных частей вывода (нет отчёта, нет точки).
+func isNotFound(err error) bool {
+	return client.StatusCode(err) == 404
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm/logger"
+	"locator/client"
+	"locator/config"
+	"locator/config/bootstrap"
+	"locator/dao"
+	"locator/service"
+)
+
+func cmdBackfillCapturedAt(ctx context.Context, a *app, args []string) error {
+	fs := flags("backfill captured-at")
+	userID := fs.Int("user", 0, "только этот пользователь")
+	interval := fs.Int("interval-seconds", 0, "шаг между точками очереди (по умолчанию — серверный, 300)")
+	burstGap := fs.Int("burst-gap-seconds", 0, "разрыв, начинающий новую пачку (по умолчанию — серверный, 30)")
+	dryRun := fs.Bool("dry-run", false, "только подсчёт, без записи")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+	result, err := a.api.BackfillCapturedAt(ctx, &client.BackfillCapturedAtParams{
+		UserID:          *userID,
+		IntervalSeconds: *interval,
+		BurstGapSeconds: *burstGap,
+		DryRun:          *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	return a.print(result, func(w io.Writer) {
+		verb := "обновлено"
+		if *dryRun {
+			verb = "будет обновлено (dry-run)"
+		}
+		fmt.Fprintf(w, "Пользователей: %d, пачек: %d, точек %s: %d, пропущено: %d\n",
+			result.UsersProcessed, result.Bursts, verb, result.Updated, result.Skipped)
+	})
+}
+
+// cmdVisitsRebuild работает с БД напрямую (конфигурация как у сервера и cmd/migrate):
+// API пересборки нет — операция долгая и нужна только при разборе инцидентов.
+func cmdVisitsRebuild(ctx context.Context, a *app, args []string) error {
+	fs := flags("visits rebuild")
+	users := fs.String("user", "", "ID пользователей через запятую")
+	fromStr := fs.String("from", "", "начало: RFC3339 или YYYY-MM-DD (полночь в поясе пользователя, иначе его организации)")
+	toStr := fs.String("to", "", "конец: RFC3339 или YYYY-MM-DD (полночь в том же поясе, день не включается)")
+	dryRun := fs.Bool("dry-run", false, "только показать результат, без записи")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "user", "from", "to"); err != nil {
+		return err
+	}
+	userIDs, err := parseIDs(*users)
+	if err != nil || len(userIDs) == 0 {
+		return usageError("visits rebuild: -user: нужен хотя бы один ID")
+	}
+	// Формат проверяется до подключения к БД; даты разбираются в поясе каждого пользователя.
+	if _, err := parseTime(*fromStr, time.UTC); err != nil {
+		return usageError("visits rebuild: -from: " + err.Error())
+	}
+	if _, err := parseTime(*toStr, time.UTC); err != nil {
+		return usageError("visits rebuild: -to: " + err.Error())
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	// Журнал сервиса (вход и выход из геозон) — только предупреждения; stdout — для результата.
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	bootstrap.ApplyThresholds(cfg.Thresholds)
+	db := config.InitDB(cfg.Database, logger.Default.LogMode(logger.Warn))
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	userDAO := dao.NewUserDAO(db)
+	timezones := service.NewTimezoneService(userDAO, dao.NewOrganizationDAO(db))
+	processor := service.NewVisitEventProcessor(
+		service.NewCheckpointService(dao.NewCheckpointDAO(db)),
+		service.NewVisitService(dao.NewVisitDAO(db), nil),
+		dao.NewLocationDAO(db),
+	)
+
+	results := make([]*service.VisitRebuildResult, 0, len(userIDs))
+	for _, id := range userIDs {
+		user, err := userDAO.GetByID(id)
+		if err != nil {
+			return fmt.Errorf("пользователь %d: %w", id, err)
+		}
+		loc := timezones.ForUser(user)
+		from, _ := parseTime(*fromStr, loc)
+		to, _ := parseTime(*toStr, loc)
+		result, err := processor.RebuildVisits(ctx, user.OrganizationID, id, from, to, *dryRun)
+		if err != nil {
+			return fmt.Errorf("пользователь %d: %w", id, err)
+		}
+		results = append(results, result)
+		if !a.json {
+			printRebuild(a.out, result, loc)
+		}
+	}
+	if a.json {
+		return a.print(results, nil)
+	}
+	return nil
+}
+
+func printRebuild(w io.Writer, r *service.VisitRebuildResult, loc *time.Location) {
+	mode := ""
+	if r.DryRun {
+		mode = " (dry-run, БД не изменена)"
+	}
+	fmt.Fprintf(w, "Пользователь %d, %s — %s (%s)%s: точек %d, визитов удалено %d, создано %d\n",
+		r.UserID, r.From.In(loc).Format("2006-01-02 15:04"), r.To.In(loc).Format("2006-01-02 15:04"), loc,
+		mode, r.Points, r.Deleted, r.Created)
+	if len(r.Visits) == 0 {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ЧЕКПОИНТ\tНАЧАЛО\tКОНЕЦ")
+	for _, v := range r.Visits {
+		end := "-"
+		if v.EndAt != nil {
+			end = v.EndAt.In(loc).Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "  %d\t%s\t%s\n", v.CheckpointID, v.StartAt.In(loc).Format("2006-01-02 15:04:05"), end)
+	}
+	tw.Flush()
+}
+
+// parseTime принимает RFC3339 или дату YYYY-MM-DD (полночь в поясе loc).
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ожидается RFC3339 или YYYY-MM-DD: %q", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"locator/client"
+)
+
+func cmdReleasesList(ctx context.Context, a *app, args []string) error {
+	fs := flags("releases list")
+	channel := fs.String("channel", "", "только этот канал")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+	releases, err := a.api.ListReleases(ctx, &client.ListReleasesParams{Channel: *channel})
+	if err != nil {
+		return err
+	}
+	return a.print(releases, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tВЕРСИЯ\tКОД\tКАНАЛ\tСТАТУС\tРАСКАТКА\tСОЗДАН")
+		for _, r := range releases {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\t%d%%\t%s\n",
+				r.ID, r.VersionName, r.VersionCode, r.Channel, releaseStatus(r), r.RolloutPercent, formatTime(&r.CreatedAt))
+		}
+		tw.Flush()
+	})
+}
+
+func releaseStatus(r client.AppRelease) string {
+	if r.PauseReason != "" {
+		return r.Status + " (" + r.PauseReason + ")"
+	}
+	return r.Status
+}
+
+// releaseFormFields — флаги publish и поля multipart, которые их получают. Отправляются
+// только заданные флаги: остальное сервер берёт из настроек канала.
+var releaseFormFields = map[string]string{
+	"channel":     "channel",
+	"changelog":   "changelog",
+	"force":       "force",
+	"rollout":     "rollout_percent",
+	"max-failure": "max_failure_percent",
+	"min-samples": "min_failure_samples",
+}
+
+func cmdReleasesPublish(ctx context.Context, a *app, args []string) error {
+	fs := flags("releases publish")
+	apk := fs.String("apk", "", "путь к подписанному APK")
+	fs.String("channel", "", "канал (по умолчанию — серверный)")
+	fs.String("changelog", "", "что нового")
+	fs.Bool("force", false, "обязательное обновление")
+	fs.Int("rollout", 0, "доля раскатки, %")
+	fs.Int("max-failure", 0, "порог автопаузы, % неудачных установок")
+	fs.Int("min-samples", 0, "минимум установок до автопаузы")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "apk"); err != nil {
+		return err
+	}
+	file, err := os.Open(*apk)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+
+	// Сервер читает multipart потоково: поля должны идти до файла.
+	fields := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if name, ok := releaseFormFields[f.Name]; ok {
+			fields[name] = f.Value.String()
+		}
+	})
+	body, contentType := multipartAPK(fields, filepath.Base(*apk), file)
+
+	var release client.AppRelease
+	if err := a.api.Upload(ctx, "POST", "/api/admin/releases", body, contentType, &release); err != nil {
+		return err
+	}
+	return a.print(release, func(w io.Writer) {
+		fmt.Fprintf(w, "Релиз %d: %s (%d), канал %s, %s, раскатка %d%%\nsha256 %s\n",
+			release.ID, release.VersionName, release.VersionCode, release.Channel,
+			release.Status, release.RolloutPercent, release.SHA256)
+	})
+}
+
+// multipartAPK собирает тело загрузки без чтения APK в память.
+func multipartAPK(fields map[string]string, filename string, apk io.Reader) (io.Reader, string) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		for _, name := range sortedKeys(fields) {
+			if err := mw.WriteField(name, fields[name]); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		part, err := mw.CreateFormFile("apk", filename)
+		if err == nil {
+			_, err = io.Copy(part, apk)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, mw.FormDataContentType()
+}
+
+// cmdReleasesStatus — pause, resume и archive: один флаг -id и причина для журнала.
+func cmdReleasesStatus(action string) command {
+	return func(ctx context.Context, a *app, args []string) error {
+		fs := flags("releases " + action)
+		id := fs.Int("id", 0, "ID релиза")
+		reason := fs.String("reason", "", "причина (в журнал аудита)")
+		if err := parse(fs, args); err != nil {
+			return err
+		}
+		if err := required(fs, "id"); err != nil {
+			return err
+		}
+		if err := a.session(ctx); err != nil {
+			return err
+		}
+		req := client.ReleaseStatusRequest{Reason: *reason}
+		var release *client.AppRelease
+		var err error
+		switch action {
+		case "pause":
+			release, err = a.api.PauseRelease(ctx, *id, req)
+		case "resume":
+			release, err = a.api.ResumeRelease(ctx, *id, req)
+		default:
+			release, err = a.api.ArchiveRelease(ctx, *id, req)
+		}
+		if err != nil {
+			return err
+		}
+		return a.print(release, func(w io.Writer) {
+			fmt.Fprintf(w, "Релиз %d (%s): %s\n", release.ID, release.VersionName, releaseStatus(*release))
+		})
+	}
+}
+
+func cmdReleasesStats(ctx context.Context, a *app, args []string) error {
+	fs := flags("releases stats")
+	id := fs.Int("id", 0, "ID релиза")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "id"); err != nil {
+		return err
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+	stats, err := a.api.GetReleaseStats(ctx, *id)
+	if err != nil {
+		return err
+	}
+	return a.print(stats, func(w io.Writer) {
+		r := stats.Release
+		fmt.Fprintf(w, "Релиз %d (%s), %s, раскатка %d%%\n", r.ID, r.VersionName, releaseStatus(r), r.RolloutPercent)
+		fmt.Fprintf(w, "Установлено: %d, неудачно: %d (%.1f%%, автопауза при %d%% после %d установок)\n",
+			stats.Completed, stats.Failed, stats.FailurePercent, r.MaxFailurePercent, r.MinFailureSamples)
+	})
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"locator/client"
+	"locator/service"
+)
+
+func cmdUsersList(ctx context.Context, a *app, args []string) error {
+	if err := parse(flags("users list"), args); err != nil {
+		return err
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+	users, err := a.api.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	return a.print(users, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tИМЯ\tРОЛЬ\tГРУППА\tАДМИН")
+		for _, u := range users {
+			group := "-"
+			if u.GroupID != nil {
+				group = fmt.Sprint(*u.GroupID)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%t\n", u.ID, u.Name, u.Role, group, u.IsAdmin)
+		}
+		tw.Flush()
+	})
+}
+
+// provisioning — данные привязки телефона: то же содержимое, что в QR-коде сервера.
+type provisioning struct {
+	User            *client.User `json:"user,omitempty"`
+	UserID          int          `json:"user_id"`
+	APIKey          string       `json:"api_key"`
+	APIBaseURL      string       `json:"api_base_url"`
+	QRContent       string       `json:"qr_content"`
+	ConfigCommandID string       `json:"config_command_id,omitempty"`
+}
+
+func newProvisioning(resp *client.RegenerateQRResponse) provisioning {
+	return provisioning{
+		UserID:          resp.ID,
+		APIKey:          resp.APIKey,
+		APIBaseURL:      resp.APIBaseURL,
+		QRContent:       service.UserQRContent(resp.ID, resp.APIKey, resp.APIBaseURL),
+		ConfigCommandID: resp.ConfigCommandID,
+	}
+}
+
+// printQR рисует QR-код символами — сканируется камерой телефона прямо с терминала.
+func printQR(w io.Writer, content string) error {
+	qr, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("QR-код: %w", err)
+	}
+	_, err = fmt.Fprint(w, qr.ToSmallString(false))
+	return err
+}
+
+func (a *app) printProvisioning(p provisioning, title string) error {
+	if a.json {
+		return a.print(p, nil)
+	}
+	fmt.Fprintln(a.out, title)
+	if err := printQR(a.out, p.QRContent); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "user_id:      %d\napi_key:      %s\napi_base_url: %s\n", p.UserID, p.APIKey, p.APIBaseURL)
+	fmt.Fprintln(a.out, "Ключ показывается один раз.")
+	return nil
+}
+
+func cmdUsersCreate(ctx context.Context, a *app, args []string) error {
+	fs := flags("users create")
+	name := fs.String("name", "", "имя сотрудника")
+	role := fs.String("role", "", "роль (по умолчанию — как у сервера)")
+	group := fs.Int("group", 0, "ID группы")
+	admin := fs.Bool("admin", false, "администратор")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "name"); err != nil {
+		return err
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+
+	req := client.CreateUserRequest{Name: *name, Role: *role, IsAdmin: *admin}
+	if *group > 0 {
+		req.GroupID = group
+	}
+	user, err := a.api.CreateUser(ctx, req)
+	if err != nil {
+		return err
+	}
+	// Ключ нового пользователя сервер не возвращает — выпускаем свой; телефона ещё нет.
+	push := false
+	resp, err := a.api.RegenerateUserQR(ctx, user.ID, client.RegenerateQRRequest{PushToDevice: &push})
+	if err != nil {
+		return fmt.Errorf("пользователь %d создан, но ключ не выпущен: %w", user.ID, err)
+	}
+	p := newProvisioning(resp)
+	p.User = user
+	return a.printProvisioning(p, fmt.Sprintf("Пользователь %d «%s» создан. Отсканируйте QR-код в приложении:", user.ID, user.Name))
+}
+
+func cmdKeysRotate(ctx context.Context, a *app, args []string) error {
+	fs := flags("keys rotate")
+	userID := fs.Int("user", 0, "ID пользователя")
+	push := fs.Bool("push", true, "отправить новый ключ на телефон (config_update)")
+	wait := fs.Duration("wait", 0, "ждать подтверждения config_update от телефона")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "user"); err != nil {
+		return err
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+
+	resp, err := a.api.RegenerateUserQR(ctx, *userID, client.RegenerateQRRequest{PushToDevice: push})
+	if err != nil {
+		return err
+	}
+	title := fmt.Sprintf("Новый ключ пользователя %d. Старый больше не действует.", resp.ID)
+	if resp.ConfigCommandID != "" {
+		title += " Ключ отправлен на телефон командой " + resp.ConfigCommandID + "."
+	}
+	if err := a.printProvisioning(newProvisioning(resp), title); err != nil {
+		return err
+	}
+	if *wait > 0 && resp.ConfigCommandID != "" {
+		return a.watchCommand(ctx, resp.ID, resp.ConfigCommandID, *wait)
+	}
+	return nil
+}
+
+func cmdKeysList(ctx context.Context, a *app, args []string) error {
+	fs := flags("keys list")
+	userID := fs.Int("user", 0, "ID пользователя")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "user"); err != nil {
+		return err
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+	keys, err := a.api.ListUserAPIKeys(ctx, *userID)
+	if err != nil {
+		return err
+	}
+	return a.print(keys, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tОТПЕЧАТОК\tМЕТКА\tОСНОВНОЙ\tИСПОЛЬЗОВАН\tСОСТОЯНИЕ")
+		for _, k := range keys {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%t\t%s\t%s\n", k.ID, k.Fingerprint, k.Label, k.Primary, formatTime(k.LastUsedAt), keyState(k))
+		}
+		tw.Flush()
+	})
+}
+
+func keyState(k client.APIKey) string {
+	switch {
+	case k.RevokedAt != nil:
+		return "отозван " + formatTime(k.RevokedAt)
+	case k.ExpiresAt != nil && k.ExpiresAt.Before(time.Now()):
+		return "истёк " + formatTime(k.ExpiresAt)
+	case k.ExpiresAt != nil:
+		return "до " + formatTime(k.ExpiresAt)
+	default:
+		return "действует"
+	}
+}
+
+func cmdKeysRevoke(ctx context.Context, a *app, args []string) error {
+	fs := flags("keys revoke")
+	userID := fs.Int("user", 0, "ID пользователя")
+	keyID := fs.Int("key", 0, "ID ключа")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "user", "key"); err != nil {
+		return err
+	}
+	if err := a.session(ctx); err != nil {
+		return err
+	}
+	key, err := a.api.RevokeUserAPIKey(ctx, *userID, *keyID)
+	if err != nil {
+		return err
+	}
+	return a.print(key, func(w io.Writer) {
+		fmt.Fprintf(w, "Ключ %d (%s) пользователя %d отозван.\n", key.ID, key.Fingerprint, key.UserID)
+	})
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
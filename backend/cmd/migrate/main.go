@@ -0,0 +1,83 @@
+// migrate применяет версионные миграции схемы (backend/migrations, встроены в бинарник).
+// Подключение — раздел database конфигурации сервера (CONFIG_FILE, DATABASE_URL или
+// DB_HOST/DB_PORT/DB_USER/DB_PASSWORD/DB_NAME/DB_SSLMODE).
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"locator/config"
+	"locator/migrations"
+
+	"gorm.io/gorm/logger"
+)
+
+const usage = `usage: migrate <command>
+  up      применить все неприменённые миграции
+  down    откатить последнюю применённую миграцию
+  status  список миграций и время их применения`
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail(err)
+	}
+	db := config.InitDB(cfg.Database, logger.Default.LogMode(logger.Warn))
+	sqlDB, err := db.DB()
+	if err != nil {
+		fail(err)
+	}
+	defer sqlDB.Close()
+	runner, err := migrations.New(sqlDB)
+	if err != nil {
+		fail(err)
+	}
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := runner.Up(ctx)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Printf("применено миграций: %d\n", applied)
+	case "down":
+		m, err := runner.Down(ctx)
+		if errors.Is(err, migrations.ErrNoMigration) {
+			fmt.Println("нет применённых миграций")
+			return
+		}
+		if err != nil {
+			fail(err)
+		}
+		fmt.Printf("откачена %d_%s\n", m.Version, m.Name)
+	case "status":
+		list, err := runner.Status(ctx)
+		if err != nil {
+			fail(err)
+		}
+		for _, s := range list {
+			applied := "не применена"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%d  %-45s  %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
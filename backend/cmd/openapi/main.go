@@ -0,0 +1,41 @@
+// openapi записывает спецификацию API (api/openapi.json) и сгенерированный по ней
+// клиент (client/client_gen.go). Запускается из router: go generate ./router.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"locator/internal/openapi/clientgen"
+	"locator/router"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "корень модуля backend")
+	flag.Parse()
+
+	if err := write(filepath.Join(*dir, "api", "openapi.json"), router.SpecJSON()); err != nil {
+		fail(err)
+	}
+	src, err := clientgen.Generate(router.Spec(), "client")
+	if err != nil {
+		fail(err)
+	}
+	if err := write(filepath.Join(*dir, "client", "client_gen.go"), src); err != nil {
+		fail(err)
+	}
+}
+
+func write(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "openapi:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,441 @@
+// simulator — N виртуальных телефонов для нагрузочных прогонов и проверки визитов.
+// Каждое устройство — пользователь sim-NNN с собственным API-ключом — идёт по маршруту
+// (GPX или прогулка между чекпоинтами организации) и говорит с сервером по протоколу
+// приложения: точки с шумом GPS и выбросами, офлайн-очередь с пачкой после связи,
+// закэшированные fix, poll и ack команд, отчёты. В конце — пропускная способность,
+// задержки и сверка визитов сервера с эталонными по истинному маршруту.
+//
+// Вход — как у locatorctl: LOCATOR_URL (или BASE_URL), LOCATOR_TOKEN или
+// LOCATOR_ADMIN_USERNAME / LOCATOR_ADMIN_PASSWORD / LOCATOR_ADMIN_TOTP.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"locator/client"
+	"locator/internal/simulator"
+)
+
+type options struct {
+	url, token string
+	devices    int
+	prefix     string
+	duration   time.Duration
+	speed      float64
+	gpx        string
+	stagger    time.Duration
+	walkSpeed  float64
+	dwellMin   time.Duration
+	dwellMax   time.Duration
+	behavior   simulator.Behavior
+	seed       int64
+	settle     time.Duration
+	tolerance  time.Duration
+	minVisit   time.Duration
+	json       bool
+}
+
+func main() {
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "simulator:", err)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := run(ctx, opts, os.Stderr)
+	if err != nil {
+		fail(err)
+	}
+	if opts.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fail(err)
+		}
+		return
+	}
+	printReport(os.Stdout, report)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "simulator:", err)
+	os.Exit(1)
+}
+
+func parseFlags(args []string) (options, error) {
+	var o options
+	fs := flag.NewFlagSet("simulator", flag.ContinueOnError)
+	fs.StringVar(&o.url, "url", firstNonEmpty(os.Getenv("LOCATOR_URL"), os.Getenv("BASE_URL"), "http://localhost:8080"), "адрес сервера")
+	fs.StringVar(&o.token, "token", os.Getenv("LOCATOR_TOKEN"), "access-токен администратора")
+	fs.IntVar(&o.devices, "devices", 10, "число устройств")
+	fs.StringVar(&o.prefix, "prefix", "sim-", "префикс имён пользователей устройств (существующие переиспользуются)")
+	fs.DurationVar(&o.duration, "duration", time.Hour, "длительность прогулки между чекпоинтами (время симуляции)")
+	fs.Float64Var(&o.speed, "speed", 1, "ускорение времени: 60 — час маршрута за минуту (captured_at — в прошлом)")
+	fs.StringVar(&o.gpx, "gpx", "", "GPX-маршрут для всех устройств вместо прогулки")
+	fs.DurationVar(&o.stagger, "stagger", time.Minute, "сдвиг старта устройств по GPX")
+	fs.Float64Var(&o.walkSpeed, "walk-speed", 1.4, "скорость перехода и GPX без времени, м/с")
+	fs.DurationVar(&o.dwellMin, "dwell-min", 10*time.Minute, "стоянка в чекпоинте не меньше")
+	fs.DurationVar(&o.dwellMax, "dwell-max", 40*time.Minute, "стоянка в чекпоинте не больше")
+	fs.DurationVar(&o.behavior.GPSInterval, "gps", 30*time.Second, "интервал плановых точек")
+	fs.DurationVar(&o.behavior.PollInterval, "poll", 30*time.Second, "интервал poll команд")
+	fs.DurationVar(&o.behavior.ReportInterval, "report", 15*time.Minute, "интервал отчётов устройства")
+	fs.Float64Var(&o.behavior.AccuracyM, "accuracy", 15, "точность GPS, м")
+	fs.Float64Var(&o.behavior.OutlierRate, "outliers", 0.02, "доля выбросов")
+	fs.Float64Var(&o.behavior.OutlierM, "outlier-m", 800, "дальность выброса, м")
+	fs.Float64Var(&o.behavior.OfflineRate, "offline", 0.1, "доля времени без сети")
+	fs.DurationVar(&o.behavior.OfflineMax, "offline-max", 15*time.Minute, "самый длинный эпизод без сети")
+	fs.Float64Var(&o.behavior.StaleRate, "stale", 0.02, "доля точек с закэшированным fix (старый captured_at)")
+	fs.Int64Var(&o.seed, "seed", time.Now().UnixNano(), "seed маршрутов и шума (для повтора прогона)")
+	fs.DurationVar(&o.settle, "settle", 15*time.Second, "пауза перед сверкой визитов (очередь RabbitMQ)")
+	fs.DurationVar(&o.tolerance, "tolerance", 3*time.Minute, "допуск границ визита при сверке")
+	fs.DurationVar(&o.minVisit, "min-visit", time.Minute, "минимальный эталонный визит (GEOFENCE_MIN_VISIT_SECONDS)")
+	fs.BoolVar(&o.json, "json", false, "итог в JSON")
+	if err := fs.Parse(args); err != nil {
+		return o, err
+	}
+	switch {
+	case fs.NArg() > 0:
+		return o, fmt.Errorf("лишние аргументы: %v", fs.Args())
+	case o.devices <= 0:
+		return o, errors.New("-devices должен быть положительным")
+	case o.speed < 1:
+		return o, errors.New("-speed не меньше 1: точки не могут приходить из будущего")
+	case o.behavior.GPSInterval <= 0 || o.behavior.PollInterval <= 0 || o.behavior.ReportInterval <= 0:
+		return o, errors.New("интервалы -gps, -poll, -report должны быть положительными")
+	}
+	return o, nil
+}
+
+// device — устройство прогона вместе с маршрутом (для эталона).
+type device struct {
+	*simulator.Device
+	user  client.User
+	track simulator.Track
+}
+
+// Report — итог прогона.
+type Report struct {
+	Devices      int                      `json:"devices"`
+	Seed         int64                    `json:"seed"`
+	Speed        float64                  `json:"speed"`
+	SimFrom      time.Time                `json:"sim_from"`
+	SimTo        time.Time                `json:"sim_to"`
+	WallSeconds  float64                  `json:"wall_seconds"`
+	Operations   []simulator.OpSummary    `json:"operations"`
+	Points       map[string]int           `json:"points"`
+	DeviceTotals []simulator.DeviceResult `json:"device_results"`
+	Visits       []DeviceVisits           `json:"visits"`
+	VisitTotals  VisitTotals              `json:"visit_totals"`
+}
+
+// DeviceVisits — сверка визитов одного устройства.
+type DeviceVisits struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	simulator.Comparison
+}
+
+// VisitTotals — сверка по всем устройствам.
+type VisitTotals struct {
+	Expected int `json:"expected"`
+	Actual   int `json:"actual"`
+	Matched  int `json:"matched"`
+	Accurate int `json:"accurate"`
+	Missed   int `json:"missed"`
+	Extra    int `json:"extra"`
+}
+
+func run(ctx context.Context, o options, logw io.Writer) (*Report, error) {
+	api := client.New(o.url)
+	api.Token = o.token
+	if err := login(ctx, api); err != nil {
+		return nil, err
+	}
+
+	list, err := api.ListCheckpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("чекпоинты: %w", err)
+	}
+	checkpoints := make([]simulator.Checkpoint, 0, len(list))
+	for _, cp := range list {
+		checkpoints = append(checkpoints, simulator.Checkpoint{
+			ID: cp.ID, Name: cp.Name, Center: simulator.Point{Lat: cp.Latitude, Lon: cp.Longitude}, Radius: cp.Radius,
+		})
+	}
+	var gpxTrack simulator.Track
+	if o.gpx != "" {
+		f, err := os.Open(o.gpx)
+		if err != nil {
+			return nil, err
+		}
+		gpxTrack, err = simulator.ParseGPX(f, o.walkSpeed)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if len(checkpoints) == 0 {
+		return nil, errors.New("в организации нет чекпоинтов: создайте их или задайте -gpx")
+	}
+
+	users, err := provisionUsers(ctx, api, o.prefix, o.devices)
+	if err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(o.seed))
+	tracks := make([]simulator.Track, len(users))
+	var longest time.Duration
+	for i := range users {
+		if gpxTrack != nil {
+			tracks[i] = gpxTrack.Shift(time.Duration(i) * o.stagger)
+		} else if tracks[i], err = simulator.RandomWalk(rand.New(rand.NewSource(rng.Int63())), checkpoints, simulator.WalkOptions{
+			Duration: o.duration, SpeedMps: o.walkSpeed, DwellMin: o.dwellMin, DwellMax: o.dwellMax,
+		}); err != nil {
+			return nil, err
+		}
+		longest = max(longest, tracks[i].Duration())
+	}
+
+	// Прогон заканчивается «сейчас»: при ускорении маршрут начинается в прошлом и
+	// captured_at никогда не опережает часы сервера.
+	wallDuration := time.Duration(float64(longest) / o.speed)
+	origin := time.Now().Add(wallDuration - longest)
+	clock := simulator.NewClock(origin, o.speed)
+	stats := simulator.NewStats()
+
+	devices := make([]device, len(users))
+	for i, u := range users {
+		deviceAPI := client.New(o.url)
+		deviceAPI.APIKey = u.key
+		devices[i] = device{
+			Device: simulator.NewDevice(u.user.ID, deviceAPI, tracks[i], clock, o.behavior, stats, rng.Int63()),
+			user:   u.user,
+			track:  tracks[i],
+		}
+	}
+
+	if !o.json {
+		fmt.Fprintf(logw, "%d устройств, маршрут %s, ускорение ×%g — около %s; seed %d\n",
+			len(devices), longest.Round(time.Second), o.speed, wallDuration.Round(time.Second), o.seed)
+	}
+	started := time.Now()
+	var wg sync.WaitGroup
+	for _, d := range devices {
+		wg.Add(1)
+		go func(d device) {
+			defer wg.Done()
+			_ = d.Run(ctx)
+		}(d)
+	}
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	progress := time.NewTicker(10 * time.Second)
+	defer progress.Stop()
+wait:
+	for {
+		select {
+		case <-done:
+			break wait
+		case <-progress.C:
+			if !o.json {
+				ops, points := stats.Summary(time.Since(started))
+				fmt.Fprintf(logw, "%s  время симуляции %s, точек сохранено %d, запросов %d\n",
+					time.Since(started).Round(time.Second), clock.Now().Sub(origin).Round(time.Second), points["saved"], totalRequests(ops))
+			}
+		}
+	}
+	wall := time.Since(started)
+
+	report := &Report{
+		Devices: len(devices), Seed: o.seed, Speed: o.speed,
+		SimFrom: origin, SimTo: origin.Add(longest), WallSeconds: wall.Seconds(),
+	}
+	report.Operations, report.Points = stats.Summary(wall)
+	for _, d := range devices {
+		report.DeviceTotals = append(report.DeviceTotals, d.Result())
+	}
+
+	// Визиты считаются асинхронно (RabbitMQ): даём очереди дойти.
+	if ctx.Err() == nil && o.settle > 0 {
+		if !o.json {
+			fmt.Fprintf(logw, "Ждём %s перед сверкой визитов\n", o.settle)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(o.settle):
+		}
+	}
+	compareCtx := context.WithoutCancel(ctx)
+	for _, d := range devices {
+		actual, err := serverVisits(compareCtx, api, d.user.ID, origin, origin.Add(longest))
+		if err != nil {
+			return report, fmt.Errorf("визиты пользователя %d: %w", d.user.ID, err)
+		}
+		expected := simulator.ExpectedVisits(d.track, origin, checkpoints, 5*time.Second, o.minVisit)
+		cmp := simulator.CompareVisits(expected, actual, o.tolerance, time.Now())
+		report.Visits = append(report.Visits, DeviceVisits{UserID: d.user.ID, Name: d.user.Name, Comparison: cmp})
+		t := &report.VisitTotals
+		t.Expected += cmp.Expected
+		t.Actual += cmp.Actual
+		t.Matched += len(cmp.Matched)
+		t.Accurate += cmp.Accurate()
+		t.Missed += len(cmp.Missed)
+		t.Extra += len(cmp.Extra)
+	}
+	return report, nil
+}
+
+func login(ctx context.Context, api *client.Client) error {
+	if api.Token != "" {
+		return nil
+	}
+	password := os.Getenv("LOCATOR_ADMIN_PASSWORD")
+	if password == "" {
+		return errors.New("нужна сессия администратора: LOCATOR_TOKEN или LOCATOR_ADMIN_PASSWORD")
+	}
+	tokens, err := api.Login(ctx, client.LoginRequest{
+		Username: firstNonEmpty(os.Getenv("LOCATOR_ADMIN_USERNAME"), "admin"),
+		Password: password,
+		TOTPCode: os.Getenv("LOCATOR_ADMIN_TOTP"),
+	})
+	if err != nil {
+		return fmt.Errorf("вход: %w", err)
+	}
+	api.Token = tokens.AccessToken
+	return nil
+}
+
+type provisioned struct {
+	user client.User
+	key  string
+}
+
+// provisionUsers находит или создаёт пользователей prefix001… и выпускает каждому
+// новый ключ (без отправки на телефон — телефон здесь симулятор).
+func provisionUsers(ctx context.Context, api *client.Client, prefix string, n int) ([]provisioned, error) {
+	existing, err := api.ListUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("пользователи: %w", err)
+	}
+	byName := make(map[string]client.User, len(existing))
+	for _, u := range existing {
+		byName[u.Name] = u
+	}
+	push := false
+	out := make([]provisioned, 0, n)
+	for i := 1; i <= n; i++ {
+		name := fmt.Sprintf("%s%03d", prefix, i)
+		user, ok := byName[name]
+		if !ok {
+			created, err := api.CreateUser(ctx, client.CreateUserRequest{Name: name})
+			if err != nil {
+				return nil, fmt.Errorf("создание %s: %w", name, err)
+			}
+			user = *created
+		}
+		resp, err := api.RegenerateUserQR(ctx, user.ID, client.RegenerateQRRequest{PushToDevice: &push})
+		if err != nil {
+			return nil, fmt.Errorf("ключ %s: %w", name, err)
+		}
+		out = append(out, provisioned{user: user, key: resp.APIKey})
+	}
+	return out, nil
+}
+
+func serverVisits(ctx context.Context, api *client.Client, userID int, from, to time.Time) ([]simulator.Visit, error) {
+	list, err := api.ListVisits(ctx, &client.ListVisitsParams{
+		UserID:         userID,
+		From:           from.UTC().Format(time.RFC3339),
+		To:             to.UTC().Format(time.RFC3339),
+		IncludeOutside: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]simulator.Visit, 0, len(list))
+	for _, v := range list {
+		out = append(out, simulator.Visit{CheckpointID: v.CheckpointID, Start: v.StartAt, End: v.EndAt})
+	}
+	return out, nil
+}
+
+func printReport(w io.Writer, r *Report) {
+	fmt.Fprintf(w, "Прогон: устройств %d, %s — %s (×%g), %.0f с, seed %d\n\n",
+		r.Devices, r.SimFrom.Local().Format("2006-01-02 15:04"), r.SimTo.Local().Format("15:04"), r.Speed, r.WallSeconds, r.Seed)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "операция\tзапросов\tошибок\tв секунду\tp50, мс\tp95, мс\tp99, мс\tmax, мс\t")
+	for _, op := range r.Operations {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.0f\t%.0f\t%.0f\t%.0f\t\n",
+			op.Op, op.Requests, op.Errors, op.PerSecond, op.P50Ms, op.P95Ms, op.P99Ms, op.MaxMs)
+	}
+	tw.Flush()
+
+	fmt.Fprint(w, "\nТочки:")
+	for _, k := range sortedKeys(r.Points) {
+		fmt.Fprintf(w, " %s=%d", k, r.Points[k])
+	}
+	bursts, maxQueue := 0, 0
+	for _, d := range r.DeviceTotals {
+		bursts += d.Bursts
+		maxQueue = max(maxQueue, d.MaxQueue)
+	}
+	fmt.Fprintf(w, "\nОфлайн-пачек: %d, самая длинная очередь: %d\n\n", bursts, maxQueue)
+
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "УСТРОЙСТВО\tЭТАЛОН\tСЕРВЕР\tСОВПАЛО\tТОЧНО\tПРОПУЩЕНО\tЛИШНИХ")
+	for _, v := range r.Visits {
+		fmt.Fprintf(tw, "%s (%d)\t%d\t%d\t%d\t%d\t%d\t%d\n",
+			v.Name, v.UserID, v.Expected, v.Actual, len(v.Matched), v.Accurate(), len(v.Missed), len(v.Extra))
+	}
+	t := r.VisitTotals
+	fmt.Fprintf(tw, "ВСЕГО\t%d\t%d\t%d\t%d\t%d\t%d\n", t.Expected, t.Actual, t.Matched, t.Accurate, t.Missed, t.Extra)
+	tw.Flush()
+	if t.Expected > 0 && t.Actual > 0 {
+		fmt.Fprintf(w, "\nПолнота %.0f%%, точность %.0f%% (совпавшие визиты к эталону и к визитам сервера)\n",
+			100*float64(t.Matched)/float64(t.Expected), 100*float64(t.Matched)/float64(t.Actual))
+	}
+}
+
+func totalRequests(ops []simulator.OpSummary) int {
+	n := 0
+	for _, op := range ops {
+		n += op.Requests
+	}
+	return n
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,80 +1,96 @@
 package bootstrap
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"sync"
 	"time"
 
 	"locator/config"
 	"locator/config/messaging"
 	"locator/controllers"
 	"locator/dao"
+	"locator/internal/metrics"
+	"locator/internal/ratelimit"
+	"locator/migrations"
 	"locator/models"
 	"locator/router"
 	"locator/seed"
 	"locator/service"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
 // App содержит зависимости приложения.
 type App struct {
 	Router    *gin.Engine
-	DB        interface{}
+	DB        *gorm.DB
 	RMQClient *messaging.RabbitMQClient
+	Health    *controllers.HealthController
+	// VisitConsumer — обработчик очереди location_events; Shutdown дожидается его.
+	VisitConsumer *messaging.Consumer
+	// stopJobs останавливает фоновые проходы (алерты, окна отслеживания, хранение),
+	// jobs — ожидание их завершения.
+	stopJobs context.CancelFunc
+	jobs     *sync.WaitGroup
 }
 
 // InitializeApp собирает все зависимости приложения и возвращает готовый инстанс App.
-// Функция принимает параметр dbLogger для настройки уровня логирования SQL-запросов.
-func InitializeApp(dbLogger logger.Interface) (*App, error) {
-	// 1. Инициализация подключения к БД с указанным логгером.
-	dbConn := config.InitDB(dbLogger)
+// cfg — проверенная конфигурация (config.Load), dbLogger — уровень логирования SQL-запросов.
+func InitializeApp(cfg *config.Config, dbLogger logger.Interface) (*App, error) {
+	ApplyThresholds(cfg.Thresholds)
 
-	if err := dbConn.AutoMigrate(
-		&models.User{},
-		&models.Location{},
-		&models.LocationRequest{},
-		&models.DeviceCommand{},
-		&models.DeviceReport{},
-		&models.Checkpoint{},
-		&models.Visit{},
-	); err != nil {
-		return nil, fmt.Errorf("auto migrate failed: %w", err)
+	// 1. Инициализация подключения к БД с указанным логгером.
+	dbConn := config.InitDB(cfg.Database, dbLogger)
+	sqlDB, err := dbConn.DB()
+	if err != nil {
+		return nil, err
 	}
-
-	seed.DefaultAdmin(dbConn)
-
-	// 2. Настройка параметров подключения к RabbitMQ из окружения.
-	user := os.Getenv("RABBITMQ_USER")
-	if user == "" {
-		user = "guest"
+	migrationRunner, err := migrations.New(sqlDB)
+	if err != nil {
+		return nil, fmt.Errorf("migrations: %w", err)
 	}
-	pass := os.Getenv("RABBITMQ_PASS")
-	if pass == "" {
-		pass = "guest"
+
+	// Версионные миграции (migrations/*.sql). Обычно их применяет cmd/migrate перед
+	// стартом; MIGRATE_ON_START=true — применить здесь, под блокировкой от других реплик.
+	if cfg.Database.MigrateOnStart {
+		applied, err := migrationRunner.Up(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("migrate failed: %w", err)
+		}
+		slog.Info("Миграции применены", "applied", applied)
 	}
-	host := os.Getenv("RABBITMQ_HOST")
-	if host == "" {
-		host = "rabbitmq"
+
+	// Схему ведут только SQL-миграции: запуск на базе с неприменёнными миграциями —
+	// ошибка конфигурации деплоя, а не повод досоздать таблицы по моделям.
+	pending, err := migrationRunner.Pending(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("migrations: %w", err)
 	}
-	port := os.Getenv("RABBITMQ_PORT")
-	if port == "" {
-		port = "5672"
+	if len(pending) > 0 {
+		return nil, fmt.Errorf("не применено миграций: %d (первая %d_%s); выполните cmd/migrate up или MIGRATE_ON_START=true",
+			len(pending), pending[0].Version, pending[0].Name)
 	}
 
-	// 3. Ждём, когда RabbitMQ станет доступен, и создаём клиент.
+	seed.DefaultOrganization(dbConn)
+	seed.DefaultAdmin(dbConn, cfg.Admin, cfg.BaseURL)
+
+	// 2–3. Ждём, когда RabbitMQ станет доступен, и создаём клиент.
+	host, port := cfg.RabbitMQ.Host, cfg.RabbitMQ.Port
 	var rmqClient *messaging.RabbitMQClient
-	var err error
 	for i := 0; i < 15; i++ {
-		rmqURL := fmt.Sprintf("amqp://%s:%s@%s:%s/", user, pass, host, port)
-		rmqClient, err = messaging.NewRabbitMQClient(rmqURL)
+		rmqClient, err = messaging.NewRabbitMQClient(cfg.RabbitMQ.URL())
 		if err == nil {
-			log.Printf("Подключились к RabbitMQ по %s", rmqURL)
+			slog.Info("Подключение к RabbitMQ установлено", "host", host, "port", port)
 			break
 		}
-		log.Printf("⏳ waiting for RabbitMQ at %s:%s … (%v)", host, port, err)
+		slog.Warn("Ожидание RabbitMQ", "host", host, "port", port, "error", err)
 		time.Sleep(2 * time.Second)
 	}
 	if err != nil {
@@ -94,30 +110,61 @@ func InitializeApp(dbLogger logger.Interface) (*App, error) {
 		nil,   // args
 	)
 	if err != nil {
-		log.Printf("Ошибка объявления очереди: %v", err)
+		slog.Error("Ошибка объявления очереди", "error", err)
 	} else {
-		log.Printf("Очередь '%s' объявлена успешно", queue.Name)
+		slog.Info("Очередь объявлена", "queue", queue.Name)
 	}
 
 	// 4. Инициализация DAO, сервисов и контроллеров
 
+	// Фоновые проходы останавливаются через App.Shutdown.
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobs := &sync.WaitGroup{}
+	runJob := func(run func(context.Context, time.Duration), interval time.Duration) {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			run(jobsCtx, interval)
+		}()
+	}
+
 	// Location
 	locationDAO := dao.NewLocationDAO(dbConn)
 	locationService := service.NewLocationService(locationDAO)
 	locationRequestDAO := dao.NewLocationRequestDAO(dbConn)
 	locationRequestService := service.NewLocationRequestService(locationRequestDAO)
-	routingBase := os.Getenv("ROUTING_BASE_URL")
 	deviceCommandDAO := dao.NewDeviceCommandDAO(dbConn)
 	deviceReportDAO := dao.NewDeviceReportDAO(dbConn)
 	deviceCommandService := service.NewDeviceCommandService(deviceCommandDAO, locationRequestService)
 	deviceReportService := service.NewDeviceReportService(deviceReportDAO)
 	deviceStatusService := service.NewDeviceStatusService(locationDAO, deviceReportDAO)
-	baseURL := os.Getenv("BASE_URL")
-	if baseURL == "" {
-		baseURL = "http://localhost:8080"
-	}
-	appReleaseController := controllers.NewAppReleaseController("static/releases/manifest.json", "static/releases", baseURL)
-	deviceController := controllers.NewDeviceController(deviceCommandService, deviceReportService, deviceStatusService, locationRequestService, appReleaseController)
+	deviceConfigDAO := dao.NewDeviceConfigDAO(dbConn)
+	deviceConfigService := service.NewDeviceConfigService(deviceConfigDAO, deviceReportDAO, deviceCommandService)
+	userDAO := dao.NewUserDAO(dbConn)
+	organizationDAO := dao.NewOrganizationDAO(dbConn)
+	// Часовые пояса: пользователя, иначе организации, иначе Europe/Minsk
+	timezoneService := service.NewTimezoneService(userDAO, organizationDAO)
+	locationService.Timezones = timezoneService
+	notificationService := service.NewNotificationService(dao.NewNotificationDAO(dbConn), userDAO, notificationChannels(cfg.Notify)...)
+	notificationService.RateLimitPerHour = cfg.Notify.RateLimitPerHour
+	notificationService.Timezones = timezoneService
+	alertService := service.NewAlertService(dao.NewAlertDAO(dbConn), userDAO, locationDAO, deviceReportDAO, locationRequestDAO)
+	alertService.Notifier = notificationService
+	alertService.Timezones = timezoneService
+	alertInterval := cfg.Jobs.AlertInterval
+	runJob(alertService.Run, alertInterval)
+	slog.Info("Движок алертов запущен", "interval", alertInterval)
+	baseURL := cfg.BaseURL
+	appReleaseService := service.NewAppReleaseService(dao.NewAppReleaseDAO(dbConn), deviceCommandDAO, deviceCommandService, deviceReportDAO, "static/releases", baseURL)
+	deviceCommandService.AppUpdates = appReleaseService
+	configureReleaseVerification(appReleaseService, cfg.Releases)
+	appReleaseController := controllers.NewAppReleaseController("static/releases/manifest.json", "static/releases", baseURL, appReleaseService)
+	deviceController := controllers.NewDeviceController(deviceCommandService, deviceReportService, deviceStatusService, locationRequestService, appReleaseController, deviceConfigService, alertService)
+	deviceController.Timezones = timezoneService
+	deviceController.BaseURL = baseURL
+	deviceConfigController := controllers.NewDeviceConfigController(deviceConfigService)
+	alertController := controllers.NewAlertController(alertService)
+	notificationController := controllers.NewNotificationController(notificationService)
 	locationRequestController := controllers.NewLocationRequestController(locationRequestService, deviceCommandService)
 
 	// Checkpoint и Visit
@@ -126,43 +173,285 @@ func InitializeApp(dbLogger logger.Interface) (*App, error) {
 	visitDAO := dao.NewVisitDAO(dbConn)
 	travelSegmentService := service.NewTravelSegmentService(locationDAO, checkpointService)
 	visitService := service.NewVisitService(visitDAO, travelSegmentService)
-	locationController := controllers.NewLocationController(locationService, locationRequestService, deviceCommandService, publisher, routingBase)
+	locationController := controllers.NewLocationController(locationService, locationRequestService, deviceCommandService, publisher, cfg.Routing.BaseURL)
+	locationController.Timezones = timezoneService
 	checkpointController := controllers.NewCheckpointController(
 		checkpointService, locationService, visitService, publisher,
 	)
 	visitController := controllers.NewVisitController(visitService)
+	visitController.Timezones = timezoneService
+
+	// Окна отслеживания и согласие
+	trackingScheduleService := service.NewTrackingScheduleService(dao.NewTrackingScheduleDAO(dbConn), userDAO, deviceCommandService)
+	trackingScheduleService.Visits = visitService
+	trackingScheduleService.Timezones = timezoneService
+	locationService.Privacy = trackingScheduleService
+	travelSegmentService.Privacy = trackingScheduleService
+	deviceConfigService.Schedules = trackingScheduleService
+	alertService.Privacy = trackingScheduleService
+	trackingInterval := cfg.Jobs.TrackingScheduleInterval
+	runJob(trackingScheduleService.Run, trackingInterval)
+	slog.Info("Планировщик окон отслеживания запущен", "interval", trackingInterval)
+	trackingScheduleController := controllers.NewTrackingScheduleController(trackingScheduleService)
+
+	// Сроки хранения точек: прореживание, удаление и архив
+	retentionService := service.NewRetentionService(dao.NewRetentionDAO(dbConn), locationDAO, userDAO)
+	retentionService.Organizations = organizationDAO
+	retentionService.Checkpoints = checkpointService
+	retentionService.Partitions = locationDAO
+	retentionService.Defaults = retentionDefaults(cfg.Retention)
+	retentionService.ArchiveDir = cfg.Retention.ArchiveDir
+	retentionInterval := cfg.Retention.Interval
+	runJob(retentionService.Run, retentionInterval)
+	slog.Info("Хранение точек запущено", "raw_days", retentionService.Defaults.RawDays,
+		"downsample_minutes", retentionService.Defaults.DownsampleMinutes, "purge_days", retentionService.Defaults.PurgeDays,
+		"archive", retentionService.Defaults.Archive, "interval", retentionInterval)
+	retentionController := controllers.NewRetentionController(retentionService)
 
 	visitEventProcessor := service.NewVisitEventProcessor(checkpointService, visitService, locationDAO)
+	visitEventProcessor.Notifier = notificationService
 	visitEventConsumer := messaging.NewConsumer(rmqClient, "location_events")
+	visitEventConsumer.Prefetch = cfg.RabbitMQ.Prefetch
 	if err := visitEventConsumer.Consume(visitEventProcessor.ProcessEvent); err != nil {
+		stopJobs()
 		return nil, fmt.Errorf("visit event consumer: %w", err)
 	}
-	log.Println("Обработчик визитов запущен (очередь location_events)")
+	slog.Info("Обработчик визитов запущен", "queue", "location_events", "prefetch", visitEventConsumer.Prefetch)
+	metrics.NewGaugeFunc("locator_rabbitmq_queue_depth", "Сообщений в очереди RabbitMQ.",
+		func() (float64, bool) {
+			depth, err := rmqClient.QueueDepth("location_events")
+			return float64(depth), err == nil
+		}, "queue", "location_events")
 
 	eventController := controllers.NewEventController(publisher)
 
 	// User
-	userDAO := dao.NewUserDAO(dbConn)
-	userService := service.NewUserService(userDAO)
+	userService := service.NewUserService(userDAO, dao.NewAPIKeyDAO(dbConn))
+	userService.Groups = dao.NewGroupDAO(dbConn)
+	userService.Organizations = organizationDAO
+	userService.KeyRotationGrace = cfg.Auth.APIKeyRotationGrace
+	userService.BaseURL = baseURL
 	userController := controllers.NewUserController(userService, deviceCommandService)
+	userController.Timezones = timezoneService
+	sessionService := service.NewSessionService(userDAO, dao.NewSessionDAO(dbConn), sessionSecret(cfg.Auth.SessionSecret))
+	authController := controllers.NewAuthController(sessionService)
+	auditService := service.NewAuditService(dao.NewAuditDAO(dbConn))
+	auditController := controllers.NewAuditController(auditService)
+
+	healthController := controllers.NewHealthController(map[string]controllers.HealthCheck{
+		"database": sqlDB.PingContext,
+		"rabbitmq": func(context.Context) error {
+			if rmqClient.Conn.IsClosed() {
+				return errors.New("соединение закрыто")
+			}
+			return nil
+		},
+		"migrations": func(ctx context.Context) error {
+			pending, err := migrationRunner.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("не применено миграций: %d (первая %d_%s)", len(pending), pending[0].Version, pending[0].Name)
+			}
+			return nil
+		},
+	})
 
 	// 5. Инициализация роутера
 	routerEngine := router.InitRoutes(
 		locationController,
 		locationRequestController,
 		deviceController,
+		deviceConfigController,
+		alertController,
+		notificationController,
 		appReleaseController,
 		checkpointController,
 		visitController,
 		eventController,
 		userController,
+		authController,
+		auditController,
+		trackingScheduleController,
+		retentionController,
+		healthController,
 		userService,
+		sessionService,
+		auditService,
+		rateLimiter(cfg.RateLimit, cfg.Auth),
 	)
 
 	app := &App{
-		Router:    routerEngine,
-		DB:        dbConn,
-		RMQClient: rmqClient,
+		Router:        routerEngine,
+		DB:            dbConn,
+		RMQClient:     rmqClient,
+		Health:        healthController,
+		VisitConsumer: visitEventConsumer,
+		stopJobs:      stopJobs,
+		jobs:          jobs,
 	}
 	return app, nil
 }
+
+// Shutdown вызывается после остановки HTTP-сервера: останавливает фоновые проходы,
+// дожидается подтверждения сообщений, уже взятых обработчиком визитов, и окончания
+// текущих проходов, затем закрывает брокер и БД. ctx ограничивает ожидание.
+// Если обработчик или проход не уложился в срок, канал брокера всё равно
+// закрывается (неподтверждённые сообщения RabbitMQ вернёт в очередь), а БД —
+// нет: незавершённая обработка не обрывается на полпути закрытием соединений.
+func (app *App) Shutdown(ctx context.Context) {
+	app.stopJobs()
+	idle := true
+	if err := app.VisitConsumer.Stop(ctx); err != nil {
+		slog.Error("Обработчик визитов не остановлен", "error", err)
+		idle = false
+	}
+	app.RMQClient.Close()
+	if !waitJobs(ctx, app.jobs) {
+		slog.Error("Фоновые проходы не завершены к сроку", "error", ctx.Err())
+		idle = false
+	}
+	if !idle {
+		slog.Warn("БД не закрывается: обработка ещё идёт, соединения закроются при выходе")
+		return
+	}
+	if sqlDB, err := app.DB.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Error("Ошибка закрытия БД", "error", err)
+		}
+	}
+}
+
+// waitJobs ждёт окончания фоновых проходов; false — не дождались до ctx.
+func waitJobs(ctx context.Context, jobs *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// ApplyThresholds делает пороги из конфигурации действующими (при старте и по SIGHUP).
+func ApplyThresholds(t config.ThresholdsConfig) {
+	service.SetThresholds(service.Thresholds{
+		GeofenceExitBufferMeters:  t.GeofenceExitBufferMeters,
+		GeofenceExitGraceSeconds:  t.GeofenceExitGraceSeconds,
+		GeofenceEnterGraceSeconds: t.GeofenceEnterGraceSeconds,
+		GeofenceMinVisitSeconds:   t.GeofenceMinVisitSeconds,
+		GeofenceFarExitMeters:     t.GeofenceFarExitMeters,
+		GeofenceStaleGapSeconds:   t.GeofenceStaleGapSeconds,
+		MaxPeriodicAccuracyM:      t.MaxPeriodicAccuracyM,
+		MaxOnDemandAccuracyM:      t.MaxOnDemandAccuracyM,
+		RouteMatchChunkSize:       t.RouteMatchChunkSize,
+		RouteMatchRadiusMeters:    t.RouteMatchRadiusMeters,
+	})
+}
+
+// sessionSecret — ключ подписи access-токенов (SESSION_SECRET). В release конфиг
+// без него не проходит проверку; в разработке ключ генерируется при старте, и после
+// перезапуска сотрудникам придётся обновить сессию.
+func sessionSecret(secret string) []byte {
+	if secret != "" {
+		if len(secret) < 32 {
+			slog.Warn("SESSION_SECRET короче 32 символов")
+		}
+		return []byte(secret)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		slog.Error("Не удалось сгенерировать ключ сессий", "error", err)
+		os.Exit(1)
+	}
+	slog.Warn("SESSION_SECRET не задан, используется случайный ключ до перезапуска")
+	return key
+}
+
+// retentionDefaults — политика хранения организаций без своей записи. По умолчанию
+// точки хранятся бессрочно и без прореживания.
+func retentionDefaults(cfg config.RetentionConfig) models.RetentionPolicy {
+	policy := models.RetentionPolicy{
+		Enabled:           true,
+		RawDays:           cfg.RawDays,
+		DownsampleMinutes: cfg.DownsampleMinutes,
+		PurgeDays:         cfg.PurgeDays,
+		Archive:           cfg.Archive,
+	}
+	if policy.PurgeDays > 0 && policy.PurgeDays <= policy.RawDays {
+		slog.Warn("RETENTION_PURGE_DAYS не больше RETENTION_RAW_DAYS, прореживание не успеет сработать",
+			"purge_days", policy.PurgeDays, "raw_days", policy.RawDays)
+	}
+	return policy
+}
+
+// rateLimiter — лимиты частоты запросов по классам маршрутов и блокировка IP после
+// неудачных входов; nil, если ограничения выключены. Состояние хранится в памяти
+// процесса: при нескольких репликах лимиты считаются на каждой отдельно.
+func rateLimiter(cfg config.RateLimitConfig, auth config.AuthConfig) *ratelimit.Limiter {
+	if !cfg.Enabled {
+		slog.Warn("RATE_LIMIT_ENABLED=false: ограничение частоты запросов отключено")
+		return nil
+	}
+	classes := map[string]ratelimit.Limit{
+		ratelimit.ClassAuth:   ratelimit.PerMinute(cfg.AuthPerMinute, cfg.AuthBurst),
+		ratelimit.ClassIngest: ratelimit.PerMinute(cfg.IngestPerMinute, cfg.IngestBurst),
+		ratelimit.ClassDevice: ratelimit.PerMinute(cfg.DevicePerMinute, cfg.DeviceBurst),
+		ratelimit.ClassAdmin:  ratelimit.PerMinute(cfg.AdminPerMinute, cfg.AdminBurst),
+	}
+	lockout := ratelimit.DefaultLockout
+	lockout.Free = auth.LockoutFreeAttempts
+	lockout.Max = auth.LockoutMax
+	return ratelimit.New(ratelimit.NewMemoryStore(), classes, lockout)
+}
+
+// configureReleaseVerification — проверки загружаемых APK: package приложения,
+// закреплённые отпечатки сертификата подписи и лимит размера.
+func configureReleaseVerification(svc *service.AppReleaseService, cfg config.ReleasesConfig) {
+	svc.ExpectedPackage = cfg.PackageName
+	for _, fp := range cfg.CertSHA256 {
+		if fp = service.NormalizeCertFingerprint(fp); fp != "" {
+			svc.CertFingerprints = append(svc.CertFingerprints, fp)
+		}
+	}
+	svc.AllowUnpinnedCert = cfg.AllowUnpinnedCert
+	switch {
+	case len(svc.CertFingerprints) > 0:
+	case cfg.AllowUnpinnedCert:
+		slog.Warn("RELEASE_ALLOW_UNPINNED_CERT: сертификат подписи APK не сверяется с доверенным")
+	default:
+		slog.Warn("RELEASE_CERT_SHA256 не задан: загрузка APK отклоняется, пока не закреплён сертификат подписи")
+	}
+	svc.MaxAPKBytes = int64(cfg.MaxUploadMB) << 20
+}
+
+// notificationChannels — каналы доставки уведомлений; email и Telegram
+// включаются только при заданных SMTP_ADDR / TELEGRAM_BOT_TOKEN.
+func notificationChannels(cfg config.NotifyConfig) []service.NotificationChannel {
+	channels := []service.NotificationChannel{&service.WebhookChannel{AllowPrivateNetworks: cfg.WebhookAllowPrivate}}
+	if cfg.WebhookAllowPrivate {
+		slog.Warn("Уведомления: вебхуки во внутреннюю сеть разрешены (NOTIFY_WEBHOOK_ALLOW_PRIVATE)")
+	}
+	if cfg.SMTPAddr != "" {
+		channels = append(channels, &service.SMTPChannel{
+			Addr:     cfg.SMTPAddr,
+			From:     cfg.SMTPFrom,
+			Username: cfg.SMTPUser,
+			Password: cfg.SMTPPassword,
+		})
+		slog.Info("Уведомления: email включён", "smtp_addr", cfg.SMTPAddr)
+	}
+	if cfg.TelegramBotToken != "" {
+		channels = append(channels, &service.TelegramChannel{
+			BaseURL: cfg.TelegramAPIBase,
+			Token:   cfg.TelegramBotToken,
+		})
+		slog.Info("Уведомления: Telegram включён")
+	}
+	return channels
+}
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted — чем в журнале заменяются заданные секреты.
+const redacted = "***"
+
+// Load собирает конфигурацию процесса: подгружает .env (если есть), затем читает
+// YAML-файл из CONFIG_FILE (если задан) и переменные окружения. Любое нераспознанное
+// или недопустимое значение — ошибка со списком всех проблем, а не молчаливый дефолт.
+func Load() (*Config, error) {
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Не удалось загрузить файл .env", "error", err)
+	}
+	return LoadFrom(os.Getenv("CONFIG_FILE"), os.LookupEnv)
+}
+
+// LoadFrom — Load с явными источниками: path — YAML-файл ("" — без файла), lookup —
+// переменные окружения.
+func LoadFrom(path string, lookup func(key string) (string, bool)) (*Config, error) {
+	cfg := &Config{}
+	var errs []error
+	var file map[string]any
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("файл конфигурации: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("файл конфигурации %s: %w", path, err)
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, f := range settingFields(cfg) {
+		if f.def != "" {
+			if err := f.set(f.def); err != nil {
+				errs = append(errs, fmt.Errorf("%s: значение по умолчанию %q: %w", f.path, f.def, err))
+			}
+		}
+		if raw, ok := lookupPath(file, f.path); ok {
+			used[f.path] = true
+			if err := f.set(raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s (файл %s): %w", f.path, path, err))
+			}
+		}
+		if f.env == "" {
+			continue
+		}
+		if raw, ok := lookup(f.env); ok && strings.TrimSpace(raw) != "" {
+			if err := f.set(raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s (%s=%q): %w", f.path, f.env, raw, err))
+			}
+		}
+	}
+	for _, key := range unknownPaths(file, "", used) {
+		errs = append(errs, fmt.Errorf("%s (файл %s): неизвестный параметр", key, path))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LogValue выводит конфигурацию группами разделов; заданные секреты — "***".
+func (c *Config) LogValue() slog.Value {
+	groups := make(map[string][]slog.Attr)
+	var order []string
+	var top []slog.Attr
+	for _, f := range settingFields(c) {
+		value := f.String()
+		if f.secret && value != "" {
+			value = redacted
+		}
+		section, key, nested := strings.Cut(f.path, ".")
+		if !nested {
+			top = append(top, slog.String(section, value))
+			continue
+		}
+		if _, ok := groups[section]; !ok {
+			order = append(order, section)
+		}
+		groups[section] = append(groups[section], slog.String(key, value))
+	}
+	for _, section := range order {
+		top = append(top, slog.Attr{Key: section, Value: slog.GroupValue(groups[section]...)})
+	}
+	return slog.GroupValue(top...)
+}
+
+// settingField — одно поле Config с его тегами.
+type settingField struct {
+	path   string // путь в YAML: "database.port"
+	env    string
+	def    string
+	unit   string
+	secret bool
+	value  reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func settingFields(cfg *Config) []settingField {
+	var out []settingField
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			key := sf.Tag.Get("yaml")
+			if key == "" || key == "-" {
+				continue
+			}
+			path := prefix + key
+			if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+				walk(v.Field(i), path+".")
+				continue
+			}
+			out = append(out, settingField{
+				path:   path,
+				env:    sf.Tag.Get("env"),
+				def:    sf.Tag.Get("default"),
+				unit:   sf.Tag.Get("unit"),
+				secret: sf.Tag.Get("secret") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return out
+}
+
+// set разбирает строковое значение в тип поля.
+func (f settingField) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if f.value.Type() == durationType {
+		d, err := parseDuration(raw, f.unit)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(d))
+		return nil
+	}
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается целое число, получено %q", raw)
+		}
+		f.value.SetInt(int64(n))
+	case reflect.Float64:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("ожидается число, получено %q", raw)
+		}
+		f.value.SetFloat(v)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается true или false, получено %q", raw)
+		}
+		f.value.SetBool(v)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("неподдерживаемый тип %s", f.value.Type())
+	}
+	return nil
+}
+
+// String — значение поля в том виде, в каком его можно задать обратно.
+func (f settingField) String() string {
+	if f.value.Type() == durationType {
+		return time.Duration(f.value.Int()).String()
+	}
+	if f.value.Kind() == reflect.Slice {
+		return strings.Join(f.value.Interface().([]string), ",")
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+// parseDuration — длительность Go (15m, 72h); число без единицы — в единицах unit.
+func parseDuration(raw, unit string) (time.Duration, error) {
+	if n, err := strconv.Atoi(raw); err == nil && unit != "" {
+		d, err := time.ParseDuration("1" + unit)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * d, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("ожидается длительность (90s, 15m, 72h), получено %q", raw)
+	}
+	return d, nil
+}
+
+// lookupPath ищет значение по пути вида "database.port" во вложенных разделах YAML.
+func lookupPath(file map[string]any, path string) (string, bool) {
+	node := any(file)
+	for _, key := range strings.Split(path, ".") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if node, ok = m[key]; !ok {
+			return "", false
+		}
+	}
+	switch v := node.(type) {
+	case nil:
+		return "", false
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ","), true
+	case map[string]any:
+		return "", false
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// unknownPaths — ключи файла, не соответствующие ни одному полю (опечатки).
+func unknownPaths(node map[string]any, prefix string, used map[string]bool) []string {
+	var out []string
+	for key, v := range node {
+		path := prefix + key
+		if used[path] {
+			continue
+		}
+		if m, ok := v.(map[string]any); ok {
+			out = append(out, unknownPaths(m, path+".", used)...)
+			continue
+		}
+		if v != nil {
+			out = append(out, path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envMap(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+var minimalEnv = map[string]string{
+	"DB_NAME": "locator_db",
+	"DB_USER": "locator_user",
+	// GIN_MODE=release по умолчанию требует постоянный ключ сессий.
+	"SESSION_SECRET": "0123456789abcdef0123456789abcdef",
+}
+
+func TestLoadFrom_defaults(t *testing.T) {
+	cfg, err := LoadFrom("", envMap(minimalEnv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BaseURL != "http://localhost:8080" || cfg.HTTP.Addr != ":8080" {
+		t.Fatalf("base_url=%q addr=%q", cfg.BaseURL, cfg.HTTP.Addr)
+	}
+	if cfg.HTTP.ReadTimeout != 300*time.Second || cfg.Retention.Interval != 24*time.Hour {
+		t.Fatalf("read_timeout=%s retention.interval=%s", cfg.HTTP.ReadTimeout, cfg.Retention.Interval)
+	}
+	if cfg.HTTP.DrainDelay != 5*time.Second || cfg.RabbitMQ.ShutdownTimeout != 30*time.Second || cfg.RabbitMQ.Prefetch != 10 {
+		t.Fatalf("drain_delay=%s rabbitmq=%+v", cfg.HTTP.DrainDelay, cfg.RabbitMQ)
+	}
+	if !cfg.RateLimit.Enabled || cfg.RateLimit.AuthBurst != 10 {
+		t.Fatalf("rate_limit=%+v", cfg.RateLimit)
+	}
+	if cfg.Thresholds.GeofenceExitGraceSeconds != 90 || cfg.Thresholds.RouteMatchChunkSize != 80 {
+		t.Fatalf("thresholds=%+v", cfg.Thresholds)
+	}
+	if cfg.Database.DSN() != "host=localhost port=5432 user=locator_user password= dbname=locator_db sslmode=disable" {
+		t.Fatalf("dsn=%q", cfg.Database.DSN())
+	}
+}
+
+func TestLoadFrom_fileThenEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locator.yaml")
+	data := `
+base_url: https://locator.example.com
+http:
+  read_timeout: 2m
+  trusted_proxies: [10.0.0.1, 10.0.0.2]
+database:
+  name: from_file
+  user: from_file
+auth:
+  session_secret: 0123456789abcdef0123456789abcdef
+thresholds:
+  geofence_exit_grace_seconds: 45
+  max_on_demand_accuracy_m: 120
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFrom(path, envMap(map[string]string{
+		"DB_NAME":                     "from_env",
+		"HTTP_WRITE_TIMEOUT_SECONDS":  "90",
+		"GEOFENCE_EXIT_GRACE_SECONDS": "",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BaseURL != "https://locator.example.com" {
+		t.Fatalf("base_url=%q", cfg.BaseURL)
+	}
+	if cfg.Database.Name != "from_env" || cfg.Database.User != "from_file" {
+		t.Fatalf("env must override file: %+v", cfg.Database)
+	}
+	if cfg.HTTP.ReadTimeout != 2*time.Minute || cfg.HTTP.WriteTimeout != 90*time.Second {
+		t.Fatalf("read=%s write=%s", cfg.HTTP.ReadTimeout, cfg.HTTP.WriteTimeout)
+	}
+	if strings.Join(cfg.HTTP.TrustedProxies, ",") != "10.0.0.1,10.0.0.2" {
+		t.Fatalf("trusted_proxies=%v", cfg.HTTP.TrustedProxies)
+	}
+	if cfg.Thresholds.GeofenceExitGraceSeconds != 45 || cfg.Thresholds.MaxOnDemandAccuracyM != 120 {
+		t.Fatalf("thresholds=%+v", cfg.Thresholds)
+	}
+}
+
+func TestLoadFrom_reportsAllErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locator.yaml")
+	if err := os.WriteFile(path, []byte("database:\n  nmae: typo\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"DB_PORT":                  "five",
+		"RATE_LIMIT_ENABLED":       "yes please",
+		"BASE_URL":                 "87.232.65.52:8080",
+		"ROUTING_MATCH_CHUNK_SIZE": "500",
+		"RABBITMQ_PREFETCH":        "0",
+		// Исключение для разработки недопустимо при GIN_MODE=release (по умолчанию).
+		"RELEASE_ALLOW_UNPINNED_CERT": "true",
+	}
+	for k, v := range minimalEnv {
+		env[k] = v
+	}
+	_, err := LoadFrom(path, envMap(env))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"DB_PORT", "RATE_LIMIT_ENABLED", "database.nmae"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error must mention %s: %v", want, err)
+		}
+	}
+
+	// Ошибки разбора не доходят до Validate; проверки диапазонов — отдельным проходом.
+	delete(env, "DB_PORT")
+	delete(env, "RATE_LIMIT_ENABLED")
+	delete(env, "SESSION_SECRET")
+	_, err = LoadFrom("", envMap(env))
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"BASE_URL", "ROUTING_MATCH_CHUNK_SIZE", "RABBITMQ_PREFETCH", "RELEASE_ALLOW_UNPINNED_CERT", "SESSION_SECRET"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error must mention %s: %v", want, err)
+		}
+	}
+}
+
+func TestConfig_LogValueRedactsSecrets(t *testing.T) {
+	env := map[string]string{
+		"DB_PASSWORD": "hunter2",
+	}
+	for k, v := range minimalEnv {
+		env[k] = v
+	}
+	cfg, err := LoadFrom("", envMap(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("config", "config", cfg)
+	out := buf.String()
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "0123456789abcdef") {
+		t.Fatalf("secret leaked: %s", out)
+	}
+	if !strings.Contains(out, `"password":"***"`) || !strings.Contains(out, `"token":""`) {
+		t.Fatalf("set secrets must be ***, unset empty: %s", out)
+	}
+	if !strings.Contains(out, `"user":"locator_user"`) {
+		t.Fatalf("plain values must be printed: %s", out)
+	}
+}
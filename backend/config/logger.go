@@ -1,9 +1,14 @@
 package config
 
 import (
+	"io"
 	"log"
+	"log/slog"
+	"os"
 	"time"
 
+	"locator/internal/logging"
+
 	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
 	"gorm.io/gorm/logger"
 )
@@ -12,7 +17,10 @@ import (
 // Логи сохраняются в файлах:
 // - активный лог: logs/app.log
 // - архивы: logs/app.log.YYYYMMDD (за предыдущие 3 дня, всего 4 файла)
-func InitLogger(logPath string) {
+//
+// Записи — строки slog (формат, уровень и сэмплирование — раздел log конфигурации);
+// атрибуты, для которых isSecret возвращает true, маскируются.
+func InitLogger(logPath string, cfg LogConfig, isSecret func(key string) bool) {
 	writer, err := rotatelogs.New(
 		// Например: "logs/app.log.20250613"
 		logPath+".%Y%m%d",
@@ -20,13 +28,14 @@ func InitLogger(logPath string) {
 		rotatelogs.WithRotationCount(4),           // сохранять максимум 4 файла (активный + 3 архива)
 	)
 	if err != nil {
-		log.Fatalf("Не удалось настроить ротацию логов: %v", err)
+		slog.Error("Не удалось настроить ротацию логов", "error", err)
+		os.Exit(1)
 	}
 
-	// Настраиваем стандартный логгер
-	log.SetOutput(writer)
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-	log.Println("Логгер инициализирован, логи пишутся в:", logPath)
+	// Стандартный логгер (log.Printf) пишет через тот же обработчик slog; копия — в stdout
+	// для docker logs и сборщика журналов.
+	logging.Setup(io.MultiWriter(writer, os.Stdout), cfg.loggingConfig(isSecret))
+	slog.Info("Логгер инициализирован", "path", logPath)
 }
 
 // InitDBQueryLogger инициализирует логгер для логирования SQL-запросов к БД с ротацией логов.
@@ -39,7 +48,8 @@ func InitDBQueryLogger(logPath string) logger.Interface {
 		rotatelogs.WithRotationCount(4),           // сохранять максимум 4 файла (активный + 3 архива)
 	)
 	if err != nil {
-		log.Fatalf("Не удалось настроить ротацию логов для DB запросов: %v", err)
+		slog.Error("Не удалось настроить ротацию логов для DB запросов", "error", err)
+		os.Exit(1)
 	}
 
 	dbLogger := logger.New(
@@ -53,3 +63,15 @@ func InitDBQueryLogger(logPath string) logger.Interface {
 	)
 	return dbLogger
 }
+
+// loggingConfig переводит раздел log в параметры internal/logging; значения уже проверены Validate.
+func (c LogConfig) loggingConfig(isSecret func(key string) bool) logging.Config {
+	cfg := logging.Config{
+		JSON:             c.Format != "text",
+		SampleInitial:    c.SampleInitial,
+		SampleThereafter: c.SampleThereafter,
+		IsSecret:         isSecret,
+	}
+	_ = cfg.Level.UnmarshalText([]byte(c.Level))
+	return cfg
+}
@@ -1,10 +1,24 @@
 // Package messaging messaging/consumer.go
 package messaging
 
+import (
+	"context"
+	"fmt"
+	"locator/internal/logging"
+	"locator/internal/metrics"
+	"os"
+	"time"
+)
+
 // Consumer отвечает за получение и обработку сообщений из указанной очереди.
+// Prefetch ограничивает число неподтверждённых сообщений у обработчика: при
+// остановке дорабатываются только они, а не вся очередь, выданная брокером заранее.
 type Consumer struct {
 	Client    *RabbitMQClient
 	QueueName string
+	Prefetch  int
+	tag       string
+	done      chan struct{}
 }
 
 // NewConsumer создаёт нового Consumer для указанной очереди.
@@ -16,10 +30,17 @@ func NewConsumer(client *RabbitMQClient, queueName string) *Consumer {
 }
 
 // Consume начинает прослушивание очереди и вызывает handler для каждого полученного сообщения.
-func (c *Consumer) Consume(handler func([]byte) error) error {
+// Контекст handler несёт ID запроса из заголовка RequestIDHeader (если он есть).
+func (c *Consumer) Consume(handler func(ctx context.Context, body []byte) error) error {
+	c.tag = fmt.Sprintf("%s-%d", c.QueueName, os.Getpid())
+	if c.Prefetch > 0 {
+		if err := c.Client.Channel.Qos(c.Prefetch, 0, false); err != nil {
+			return fmt.Errorf("qos: %w", err)
+		}
+	}
 	msgs, err := c.Client.Channel.Consume(
 		c.QueueName, // название очереди
-		"",          // consumer tag
+		c.tag,       // consumer tag (нужен для отмены в Stop)
 		false,       // auto-ack (false позволит нам вручную подтверждать сообщение)
 		false,       // exclusive
 		false,       // no-local
@@ -30,17 +51,44 @@ func (c *Consumer) Consume(handler func([]byte) error) error {
 		return err
 	}
 
+	c.done = make(chan struct{})
 	go func() {
+		defer close(c.done)
 		for msg := range msgs {
-			if err := handler(msg.Body); err != nil {
+			if !msg.Timestamp.IsZero() {
+				metrics.RabbitConsumerLag.Observe(time.Since(msg.Timestamp).Seconds(), c.QueueName)
+			}
+			requestID, _ := msg.Headers[RequestIDHeader].(string)
+			ctx := logging.WithRequestID(context.Background(), requestID)
+			if err := handler(ctx, msg.Body); err != nil {
 				// Если обработка не удалась, отправляем nack, чтобы сообщение повторно доставлялось
 				msg.Nack(false, true)
+				metrics.RabbitConsumed.Inc(c.QueueName, "nack")
 			} else {
 				// Если всё хорошо, подтверждаем обработку сообщения
 				msg.Ack(false)
+				metrics.RabbitConsumed.Inc(c.QueueName, "ack")
 			}
 		}
 	}()
 
 	return nil
 }
+
+// Stop отменяет подписку: брокер больше не присылает сообщения, уже полученные
+// обрабатываются и подтверждаются. Неподтверждённые после закрытия канала брокер
+// доставит повторно. Возвращается после последнего ack/nack или по ctx.
+func (c *Consumer) Stop(ctx context.Context) error {
+	if c.done == nil {
+		return nil
+	}
+	if err := c.Client.Channel.Cancel(c.tag, false); err != nil {
+		return err
+	}
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
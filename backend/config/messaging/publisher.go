@@ -1,7 +1,12 @@
 package messaging
 
 import (
+	"context"
 	"encoding/json"
+	"locator/internal/logging"
+	"locator/internal/metrics"
+	"time"
+
 	"github.com/rabbitmq/amqp091-go"
 )
 
@@ -21,29 +26,54 @@ func NewPublisher(client *RabbitMQClient, exchange, routingKey string) *Publishe
 	}
 }
 
+// RequestIDHeader — заголовок сообщения с ID HTTP-запроса, породившего событие.
+const RequestIDHeader = "request_id"
+
 // Publish отправляет message (например, JSON-сериализованное событие) в очередь.
 func (p *Publisher) Publish(message []byte) error {
+	return p.PublishContext(context.Background(), message)
+}
+
+// PublishContext — Publish с ID запроса из ctx в заголовке RequestIDHeader:
+// по нему обработку события у потребителя можно связать с исходным запросом.
+func (p *Publisher) PublishContext(ctx context.Context, message []byte) error {
 	if p == nil || p.Client == nil || p.Client.Channel == nil {
 		// Tests and optional messaging: Publisher{} is a deliberate no-op.
 		return nil
 	}
-	return p.Client.Channel.Publish(
+	var headers amqp091.Table
+	if id := logging.RequestID(ctx); id != "" {
+		headers = amqp091.Table{RequestIDHeader: id}
+	}
+	err := p.Client.Channel.Publish(
 		p.Exchange,   // обмен
 		p.RoutingKey, // ключ маршрутизации
 		false,        // mandatory
 		false,        // immediate
 		amqp091.Publishing{
 			ContentType: "application/json",
-			Body:        message,
+			Headers:     headers,
+			// Timestamp — для метрики задержки потребителя.
+			Timestamp: time.Now(),
+			Body:      message,
 		},
 	)
+	if err != nil {
+		metrics.RabbitPublishFailures.Inc(p.RoutingKey)
+	}
+	return err
 }
 
 // PublishJSON сериализует объект в JSON и публикует его.
 func (p *Publisher) PublishJSON(v interface{}) error {
+	return p.PublishJSONContext(context.Background(), v)
+}
+
+// PublishJSONContext — PublishJSON с ID запроса из ctx (см. PublishContext).
+func (p *Publisher) PublishJSONContext(ctx context.Context, v interface{}) error {
 	message, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
-	return p.Publish(message)
+	return p.PublishContext(ctx, message)
 }
@@ -42,3 +42,18 @@ func (c *RabbitMQClient) Close() {
 		}
 	}
 }
+
+// QueueDepth — число сообщений в очереди. Пассивное объявление выполняется на
+// отдельном канале: при ошибке брокер закрывает канал, и основной не должен пострадать.
+func (c *RabbitMQClient) QueueDepth(name string) (int, error) {
+	ch, err := c.Conn.Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+	q, err := ch.QueueDeclarePassive(name, true, false, false, false, nil)
+	if err != nil {
+		return 0, err
+	}
+	return q.Messages, nil
+}
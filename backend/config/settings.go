@@ -0,0 +1,207 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Config — все настройки сервера в одном месте. Источники по возрастанию приоритета:
+// значение по умолчанию (тег default), YAML-файл из CONFIG_FILE (тег yaml), переменная
+// окружения (тег env). Поля с тегом secret в журнал выводятся как "***".
+//
+// Длительности задаются строкой Go (90s, 15m, 72h); число без единицы — в единицах
+// тега unit (так переменные вида *_SECONDS читаются по-прежнему).
+type Config struct {
+	// BaseURL — внешний адрес API: уходит в QR-коды и config_update телефонам.
+	BaseURL string `yaml:"base_url" env:"BASE_URL" default:"http://localhost:8080"`
+
+	HTTP       HTTPConfig       `yaml:"http"`
+	Database   DatabaseConfig   `yaml:"database"`
+	RabbitMQ   RabbitMQConfig   `yaml:"rabbitmq"`
+	Log        LogConfig        `yaml:"log"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Routing    RoutingConfig    `yaml:"routing"`
+	Jobs       JobsConfig       `yaml:"jobs"`
+	Retention  RetentionConfig  `yaml:"retention"`
+	Auth       AuthConfig       `yaml:"auth"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	Releases   ReleasesConfig   `yaml:"releases"`
+	Notify     NotifyConfig     `yaml:"notify"`
+	Admin      AdminConfig      `yaml:"admin"`
+	Thresholds ThresholdsConfig `yaml:"thresholds"`
+}
+
+// HTTPConfig — HTTP-сервер API.
+type HTTPConfig struct {
+	Addr    string `yaml:"addr" env:"HTTP_ADDR" default:":8080"`
+	GinMode string `yaml:"gin_mode" env:"GIN_MODE" default:"release"`
+	// TrustedProxies — прокси, которым доверяются X-Forwarded-*; пусто — никому.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	// ReadTimeout и WriteTimeout — с запасом на загрузку APK и выгрузки.
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT_SECONDS" default:"300s" unit:"s"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT_SECONDS" default:"300s" unit:"s"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT_SECONDS" default:"120s" unit:"s"`
+	// ShutdownTimeout — сколько при остановке ждать текущие HTTP-запросы.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT_SECONDS" default:"30s" unit:"s"`
+	// DrainDelay — пауза между переходом /readyz в 503 и закрытием приёма соединений:
+	// балансировщик успевает увидеть 503 и снять экземпляр с трафика.
+	DrainDelay time.Duration `yaml:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY_SECONDS" default:"5s" unit:"s"`
+}
+
+// DatabaseConfig — подключение к Postgres; URL, если задан, важнее остальных полей.
+type DatabaseConfig struct {
+	URL          string `yaml:"url" env:"DATABASE_URL" secret:"true"`
+	Host         string `yaml:"host" env:"DB_HOST" default:"localhost"`
+	Port         int    `yaml:"port" env:"DB_PORT" default:"5432"`
+	User         string `yaml:"user" env:"DB_USER"`
+	Password     string `yaml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name         string `yaml:"name" env:"DB_NAME"`
+	SSLMode      string `yaml:"sslmode" env:"DB_SSLMODE" default:"disable"`
+	MaxOpenConns int    `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" default:"15"`
+	MaxIdleConns int    `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" default:"5"`
+	// MigrateOnStart — применять версионные миграции при старте приложения.
+	MigrateOnStart bool `yaml:"migrate_on_start" env:"MIGRATE_ON_START"`
+}
+
+// DSN — строка подключения для драйвера Postgres.
+func (c DatabaseConfig) DSN() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode)
+}
+
+// RabbitMQConfig — брокер событий локаций.
+type RabbitMQConfig struct {
+	Host     string `yaml:"host" env:"RABBITMQ_HOST" default:"rabbitmq"`
+	Port     int    `yaml:"port" env:"RABBITMQ_PORT" default:"5672"`
+	User     string `yaml:"user" env:"RABBITMQ_USER" default:"guest"`
+	Password string `yaml:"password" env:"RABBITMQ_PASS" default:"guest" secret:"true"`
+	// Prefetch — сколько неподтверждённых сообщений брокер выдаёт обработчику (basic.qos).
+	Prefetch int `yaml:"prefetch" env:"RABBITMQ_PREFETCH" default:"10"`
+	// ShutdownTimeout — сколько при остановке ждать обработчик очереди и фоновые проходы.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"RABBITMQ_SHUTDOWN_TIMEOUT_SECONDS" default:"30s" unit:"s"`
+}
+
+// URL — адрес amqp:// с учётными данными (в журнал не выводить).
+func (c RabbitMQConfig) URL() string {
+	u := url.URL{
+		Scheme: "amqp",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:   "/",
+	}
+	return u.String()
+}
+
+// LogConfig — журнал приложения (см. internal/logging).
+type LogConfig struct {
+	Level            string `yaml:"level" env:"LOG_LEVEL" default:"info"`
+	Format           string `yaml:"format" env:"LOG_FORMAT" default:"json"`
+	SampleInitial    int    `yaml:"sample_initial" env:"LOG_SAMPLE_INITIAL"`
+	SampleThereafter int    `yaml:"sample_thereafter" env:"LOG_SAMPLE_THEREAFTER"`
+}
+
+// MetricsConfig — /metrics на внутреннем порту; Addr "off" — выключено.
+type MetricsConfig struct {
+	Addr  string `yaml:"addr" env:"METRICS_ADDR" default:":9100"`
+	Token string `yaml:"token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+// RoutingConfig — OSRM для «Пути по дорогам»; пустой BaseURL — эндпоинт недоступен.
+type RoutingConfig struct {
+	BaseURL string `yaml:"base_url" env:"ROUTING_BASE_URL"`
+}
+
+// JobsConfig — интервалы фоновых проходов.
+type JobsConfig struct {
+	AlertInterval            time.Duration `yaml:"alert_interval" env:"ALERT_EVAL_INTERVAL_SECONDS" default:"60s" unit:"s"`
+	TrackingScheduleInterval time.Duration `yaml:"tracking_schedule_interval" env:"TRACKING_SCHEDULE_INTERVAL_SECONDS" default:"60s" unit:"s"`
+}
+
+// RetentionConfig — политика хранения точек для организаций без своей записи.
+type RetentionConfig struct {
+	RawDays           int           `yaml:"raw_days" env:"RETENTION_RAW_DAYS"`
+	DownsampleMinutes int           `yaml:"downsample_minutes" env:"RETENTION_DOWNSAMPLE_MINUTES" default:"5"`
+	PurgeDays         int           `yaml:"purge_days" env:"RETENTION_PURGE_DAYS"`
+	Archive           bool          `yaml:"archive" env:"RETENTION_ARCHIVE"`
+	ArchiveDir        string        `yaml:"archive_dir" env:"RETENTION_ARCHIVE_DIR" default:"archive/locations"`
+	Interval          time.Duration `yaml:"interval" env:"RETENTION_INTERVAL_HOURS" default:"24h" unit:"h"`
+}
+
+// AuthConfig — сессии веб-интерфейса, ключи устройств и блокировка входа.
+type AuthConfig struct {
+	// SessionSecret — ключ подписи access-токенов; обязателен при GIN_MODE=release,
+	// в разработке пусто — случайный до перезапуска.
+	SessionSecret       string        `yaml:"session_secret" env:"SESSION_SECRET" secret:"true"`
+	APIKeyRotationGrace time.Duration `yaml:"api_key_rotation_grace" env:"API_KEY_ROTATION_GRACE" default:"72h"`
+	LockoutFreeAttempts int           `yaml:"lockout_free_attempts" env:"AUTH_LOCKOUT_FREE_ATTEMPTS" default:"5"`
+	LockoutMax          time.Duration `yaml:"lockout_max" env:"AUTH_LOCKOUT_MAX" default:"15m"`
+}
+
+// RateLimitConfig — лимиты запросов в минуту и пачки по классам маршрутов.
+type RateLimitConfig struct {
+	Enabled         bool    `yaml:"enabled" env:"RATE_LIMIT_ENABLED" default:"true"`
+	AuthPerMinute   float64 `yaml:"auth_per_minute" env:"RATE_LIMIT_AUTH_PER_MINUTE" default:"20"`
+	AuthBurst       int     `yaml:"auth_burst" env:"RATE_LIMIT_AUTH_BURST" default:"10"`
+	IngestPerMinute float64 `yaml:"ingest_per_minute" env:"RATE_LIMIT_INGEST_PER_MINUTE" default:"120"`
+	IngestBurst     int     `yaml:"ingest_burst" env:"RATE_LIMIT_INGEST_BURST" default:"120"`
+	DevicePerMinute float64 `yaml:"device_per_minute" env:"RATE_LIMIT_DEVICE_PER_MINUTE" default:"120"`
+	DeviceBurst     int     `yaml:"device_burst" env:"RATE_LIMIT_DEVICE_BURST" default:"60"`
+	AdminPerMinute  float64 `yaml:"admin_per_minute" env:"RATE_LIMIT_ADMIN_PER_MINUTE" default:"600"`
+	AdminBurst      int     `yaml:"admin_burst" env:"RATE_LIMIT_ADMIN_BURST" default:"200"`
+}
+
+// ReleasesConfig — проверки загружаемых APK.
+type ReleasesConfig struct {
+	PackageName string `yaml:"package_name" env:"RELEASE_PACKAGE_NAME" default:"com.example.lctr_app"`
+	// CertSHA256 — закреплённые отпечатки сертификата подписи (через запятую в env);
+	// без них APK не публикуются.
+	CertSHA256 []string `yaml:"cert_sha256" env:"RELEASE_CERT_SHA256"`
+	// AllowUnpinnedCert — публиковать APK с любым сертификатом подписи. Только для
+	// разработки: при GIN_MODE=release не допускается.
+	AllowUnpinnedCert bool `yaml:"allow_unpinned_cert" env:"RELEASE_ALLOW_UNPINNED_CERT" default:"false"`
+	MaxUploadMB       int  `yaml:"max_upload_mb" env:"RELEASE_MAX_UPLOAD_MB" default:"200"`
+}
+
+// NotifyConfig — каналы уведомлений; email и Telegram включаются заданными
+// SMTPAddr / TelegramBotToken.
+type NotifyConfig struct {
+	RateLimitPerHour int    `yaml:"rate_limit_per_hour" env:"NOTIFY_RATE_LIMIT_PER_HOUR" default:"20"`
+	SMTPAddr         string `yaml:"smtp_addr" env:"SMTP_ADDR"`
+	SMTPFrom         string `yaml:"smtp_from" env:"SMTP_FROM"`
+	SMTPUser         string `yaml:"smtp_user" env:"SMTP_USER"`
+	SMTPPassword     string `yaml:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+	TelegramBotToken string `yaml:"telegram_bot_token" env:"TELEGRAM_BOT_TOKEN" secret:"true"`
+	TelegramAPIBase  string `yaml:"telegram_api_base" env:"TELEGRAM_API_BASE"`
+	// WebhookAllowPrivate разрешает вебхуки во внутреннюю сеть (loopback, частные
+	// и link-local адреса) — только для закрытых инсталляций.
+	WebhookAllowPrivate bool `yaml:"webhook_allow_private" env:"NOTIFY_WEBHOOK_ALLOW_PRIVATE"`
+}
+
+// AdminConfig — дефолтный администратор, создаваемый при старте.
+type AdminConfig struct {
+	Name     string `yaml:"name" env:"DEFAULT_ADMIN_NAME"`
+	APIKey   string `yaml:"api_key" env:"DEFAULT_ADMIN_API_KEY" secret:"true"`
+	Username string `yaml:"username" env:"DEFAULT_ADMIN_USERNAME"`
+	Password string `yaml:"password" env:"DEFAULT_ADMIN_PASSWORD" secret:"true"`
+}
+
+// ThresholdsConfig — пороги геозон, качества точек и привязки к дорогам. Единственный
+// раздел, который перечитывается без перезапуска (SIGHUP).
+type ThresholdsConfig struct {
+	GeofenceExitBufferMeters  float64 `yaml:"geofence_exit_buffer_meters" env:"GEOFENCE_EXIT_BUFFER_METERS" default:"40"`
+	GeofenceExitGraceSeconds  int     `yaml:"geofence_exit_grace_seconds" env:"GEOFENCE_EXIT_GRACE_SECONDS" default:"90"`
+	GeofenceEnterGraceSeconds int     `yaml:"geofence_enter_grace_seconds" env:"GEOFENCE_ENTER_GRACE_SECONDS" default:"30"`
+	GeofenceMinVisitSeconds   int     `yaml:"geofence_min_visit_seconds" env:"GEOFENCE_MIN_VISIT_SECONDS" default:"60"`
+	GeofenceFarExitMeters     float64 `yaml:"geofence_far_exit_meters" env:"GEOFENCE_FAR_EXIT_METERS" default:"300"`
+	GeofenceStaleGapSeconds   int     `yaml:"geofence_stale_gap_seconds" env:"GEOFENCE_STALE_GAP_SECONDS" default:"600"`
+	MaxPeriodicAccuracyM      float64 `yaml:"max_periodic_accuracy_m" env:"LOCATION_MAX_PERIODIC_ACCURACY_M" default:"150"`
+	MaxOnDemandAccuracyM      float64 `yaml:"max_on_demand_accuracy_m" env:"LOCATION_MAX_ON_DEMAND_ACCURACY_M" default:"200"`
+	// RouteMatchChunkSize — точек в одном запросе к OSRM match (публичный сервер — не больше 10).
+	RouteMatchChunkSize int `yaml:"route_match_chunk_size" env:"ROUTING_MATCH_CHUNK_SIZE" default:"80"`
+	// RouteMatchRadiusMeters — радиус поиска дороги на точку; 0 — не передаётся.
+	RouteMatchRadiusMeters int `yaml:"route_match_radius_meters" env:"ROUTING_MATCH_RADIUS"`
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Validate проверяет значения, которые разбор типов пропускает: диапазоны, адреса,
+// перечисления. Возвращает все ошибки сразу.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if err := checkHTTPURL(c.BaseURL); err != nil {
+		fail("base_url (BASE_URL)", "%v", err)
+	}
+
+	if c.HTTP.Addr == "" {
+		fail("http.addr (HTTP_ADDR)", "не задан")
+	}
+	switch c.HTTP.GinMode {
+	case "debug", "release", "test":
+	default:
+		fail("http.gin_mode (GIN_MODE)", "ожидается debug, release или test, получено %q", c.HTTP.GinMode)
+	}
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"http.read_timeout (HTTP_READ_TIMEOUT_SECONDS)", c.HTTP.ReadTimeout},
+		{"http.write_timeout (HTTP_WRITE_TIMEOUT_SECONDS)", c.HTTP.WriteTimeout},
+		{"http.idle_timeout (HTTP_IDLE_TIMEOUT_SECONDS)", c.HTTP.IdleTimeout},
+		{"http.shutdown_timeout (SHUTDOWN_TIMEOUT_SECONDS)", c.HTTP.ShutdownTimeout},
+		{"rabbitmq.shutdown_timeout (RABBITMQ_SHUTDOWN_TIMEOUT_SECONDS)", c.RabbitMQ.ShutdownTimeout},
+		{"jobs.alert_interval (ALERT_EVAL_INTERVAL_SECONDS)", c.Jobs.AlertInterval},
+		{"jobs.tracking_schedule_interval (TRACKING_SCHEDULE_INTERVAL_SECONDS)", c.Jobs.TrackingScheduleInterval},
+		{"retention.interval (RETENTION_INTERVAL_HOURS)", c.Retention.Interval},
+		{"auth.lockout_max (AUTH_LOCKOUT_MAX)", c.Auth.LockoutMax},
+	} {
+		if d.value <= 0 {
+			fail(d.key, "должно быть больше нуля")
+		}
+	}
+
+	if c.Database.URL == "" {
+		if c.Database.Host == "" {
+			fail("database.host (DB_HOST)", "не задан (или задайте DATABASE_URL)")
+		}
+		if c.Database.Name == "" {
+			fail("database.name (DB_NAME)", "не задано (или задайте DATABASE_URL)")
+		}
+		if c.Database.User == "" {
+			fail("database.user (DB_USER)", "не задан (или задайте DATABASE_URL)")
+		}
+		checkPort(fail, "database.port (DB_PORT)", c.Database.Port)
+	}
+	if c.Database.MaxOpenConns <= 0 {
+		fail("database.max_open_conns (DB_MAX_OPEN_CONNS)", "должно быть больше нуля")
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		fail("database.max_idle_conns (DB_MAX_IDLE_CONNS)", "должно быть от 0 до max_open_conns (%d)", c.Database.MaxOpenConns)
+	}
+
+	if c.RabbitMQ.Host == "" {
+		fail("rabbitmq.host (RABBITMQ_HOST)", "не задан")
+	}
+	checkPort(fail, "rabbitmq.port (RABBITMQ_PORT)", c.RabbitMQ.Port)
+	if c.RabbitMQ.Prefetch <= 0 {
+		fail("rabbitmq.prefetch (RABBITMQ_PREFETCH)", "должно быть больше нуля")
+	}
+	if c.HTTP.DrainDelay < 0 {
+		fail("http.drain_delay (SHUTDOWN_DRAIN_DELAY_SECONDS)", "не может быть отрицательной")
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		fail("log.level (LOG_LEVEL)", "ожидается debug, info, warn или error, получено %q", c.Log.Level)
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		fail("log.format (LOG_FORMAT)", "ожидается json или text, получено %q", c.Log.Format)
+	}
+	if c.Log.SampleInitial < 0 || c.Log.SampleThereafter < 0 {
+		fail("log.sample_* (LOG_SAMPLE_*)", "не может быть отрицательным")
+	}
+
+	if c.Routing.BaseURL != "" {
+		if err := checkHTTPURL(c.Routing.BaseURL); err != nil {
+			fail("routing.base_url (ROUTING_BASE_URL)", "%v", err)
+		}
+	}
+
+	if c.Retention.RawDays < 0 || c.Retention.DownsampleMinutes < 0 || c.Retention.PurgeDays < 0 {
+		fail("retention (RETENTION_*_DAYS, RETENTION_DOWNSAMPLE_MINUTES)", "не может быть отрицательным")
+	}
+	if c.Retention.Archive && c.Retention.ArchiveDir == "" {
+		fail("retention.archive_dir (RETENTION_ARCHIVE_DIR)", "нужен при включённом архиве")
+	}
+
+	if c.Auth.APIKeyRotationGrace < 0 {
+		fail("auth.api_key_rotation_grace (API_KEY_ROTATION_GRACE)", "не может быть отрицательным")
+	}
+	if c.Auth.LockoutFreeAttempts < 0 {
+		fail("auth.lockout_free_attempts (AUTH_LOCKOUT_FREE_ATTEMPTS)", "не может быть отрицательным")
+	}
+
+	for _, class := range []struct {
+		name      string
+		perMinute float64
+		burst     int
+	}{
+		{"auth", c.RateLimit.AuthPerMinute, c.RateLimit.AuthBurst},
+		{"ingest", c.RateLimit.IngestPerMinute, c.RateLimit.IngestBurst},
+		{"device", c.RateLimit.DevicePerMinute, c.RateLimit.DeviceBurst},
+		{"admin", c.RateLimit.AdminPerMinute, c.RateLimit.AdminBurst},
+	} {
+		env := "RATE_LIMIT_" + strings.ToUpper(class.name)
+		if class.perMinute < 0 {
+			fail("rate_limit."+class.name+"_per_minute ("+env+"_PER_MINUTE)", "не может быть отрицательным")
+		}
+		if class.burst <= 0 {
+			fail("rate_limit."+class.name+"_burst ("+env+"_BURST)", "должно быть больше нуля")
+		}
+	}
+
+	if c.Auth.SessionSecret == "" && c.HTTP.GinMode == "release" {
+		fail("auth.session_secret (SESSION_SECRET)", "обязателен при GIN_MODE=release: случайный ключ сбрасывает сессии при перезапуске и различается между репликами")
+	}
+
+	if c.Releases.PackageName == "" {
+		fail("releases.package_name (RELEASE_PACKAGE_NAME)", "не задан")
+	}
+	if c.Releases.AllowUnpinnedCert && c.HTTP.GinMode == "release" {
+		fail("releases.allow_unpinned_cert (RELEASE_ALLOW_UNPINNED_CERT)", "только для разработки, при GIN_MODE=release недопустимо")
+	}
+	if c.Releases.MaxUploadMB <= 0 {
+		fail("releases.max_upload_mb (RELEASE_MAX_UPLOAD_MB)", "должно быть больше нуля")
+	}
+
+	if c.Notify.RateLimitPerHour < 0 {
+		fail("notify.rate_limit_per_hour (NOTIFY_RATE_LIMIT_PER_HOUR)", "не может быть отрицательным (0 — без лимита)")
+	}
+
+	if c.Admin.APIKey != "" && c.Admin.Name == "" {
+		fail("admin.name (DEFAULT_ADMIN_NAME)", "нужно вместе с DEFAULT_ADMIN_API_KEY")
+	}
+
+	errs = append(errs, c.Thresholds.validate()...)
+	return errors.Join(errs...)
+}
+
+// Validate проверяет только пороги — для перечитывания по SIGHUP.
+func (t ThresholdsConfig) Validate() error {
+	return errors.Join(t.validate()...)
+}
+
+func (t ThresholdsConfig) validate() []error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("thresholds.%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+	for _, v := range []struct {
+		key   string
+		value float64
+	}{
+		{"geofence_exit_buffer_meters (GEOFENCE_EXIT_BUFFER_METERS)", t.GeofenceExitBufferMeters},
+		{"geofence_far_exit_meters (GEOFENCE_FAR_EXIT_METERS)", t.GeofenceFarExitMeters},
+		{"geofence_exit_grace_seconds (GEOFENCE_EXIT_GRACE_SECONDS)", float64(t.GeofenceExitGraceSeconds)},
+		{"geofence_enter_grace_seconds (GEOFENCE_ENTER_GRACE_SECONDS)", float64(t.GeofenceEnterGraceSeconds)},
+		{"geofence_min_visit_seconds (GEOFENCE_MIN_VISIT_SECONDS)", float64(t.GeofenceMinVisitSeconds)},
+		{"geofence_stale_gap_seconds (GEOFENCE_STALE_GAP_SECONDS)", float64(t.GeofenceStaleGapSeconds)},
+	} {
+		if v.value < 0 {
+			fail(v.key, "не может быть отрицательным")
+		}
+	}
+	if t.MaxPeriodicAccuracyM <= 0 {
+		fail("max_periodic_accuracy_m (LOCATION_MAX_PERIODIC_ACCURACY_M)", "должно быть больше нуля")
+	}
+	if t.MaxOnDemandAccuracyM <= 0 {
+		fail("max_on_demand_accuracy_m (LOCATION_MAX_ON_DEMAND_ACCURACY_M)", "должно быть больше нуля")
+	}
+	if t.RouteMatchChunkSize < 2 || t.RouteMatchChunkSize > 100 {
+		fail("route_match_chunk_size (ROUTING_MATCH_CHUNK_SIZE)", "ожидается от 2 до 100, получено %d", t.RouteMatchChunkSize)
+	}
+	if t.RouteMatchRadiusMeters < 0 || t.RouteMatchRadiusMeters > 50 {
+		fail("route_match_radius_meters (ROUTING_MATCH_RADIUS)", "ожидается от 0 до 50, получено %d", t.RouteMatchRadiusMeters)
+	}
+	return errs
+}
+
+func checkPort(fail func(key, format string, args ...any), key string, port int) {
+	if port < 1 || port > 65535 {
+		fail(key, "ожидается порт 1–65535, получено %d", port)
+	}
+}
+
+func checkHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("ожидается адрес вида http(s)://host[:port], получено %q", raw)
+	}
+	return nil
+}
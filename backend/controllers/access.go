@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"locator/models"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// userScopeFromContext — область видимости сотрудника, установленная middleware
+// (nil — без ограничений).
+func userScopeFromContext(ctx *gin.Context) *service.UserScope {
+	v, ok := ctx.Get("scope")
+	if !ok {
+		return nil
+	}
+	scope, _ := v.(*service.UserScope)
+	return scope
+}
+
+// canAccessUser — чтение: свои данные доступны всегда, чужие — сотруднику в пределах его группы.
+func canAccessUser(ctx *gin.Context, currentUser *models.User, userID int) bool {
+	if userID == currentUser.ID {
+		return true
+	}
+	return currentUser.IsStaff() && userScopeFromContext(ctx).Allows(userID)
+}
+
+// canWriteUser — запись от имени пользователя: свои данные — всегда, чужие — только
+// с правом perm и в пределах группы (права на чтение недостаточно).
+func canWriteUser(ctx *gin.Context, currentUser *models.User, userID int, perm models.Permission) bool {
+	if userID == currentUser.ID {
+		return true
+	}
+	return currentUser.HasPermission(perm) && userScopeFromContext(ctx).Allows(userID)
+}
+
+// logAccessDenied пишет отказ в доступе: кто, какой запрос и почему.
+func logAccessDenied(ctx *gin.Context, currentUser *models.User, reason string, args ...any) {
+	args = append([]any{
+		"user_id", currentUser.ID, "name", currentUser.Name, "role", currentUser.EffectiveRole(),
+		"method", ctx.Request.Method, "path", ctx.Request.URL.Path, "reason", reason,
+	}, args...)
+	slog.WarnContext(ctx.Request.Context(), "Доступ запрещён", args...)
+}
+
+// denyUserAccess логирует отказ и отвечает 403.
+func denyUserAccess(ctx *gin.Context, currentUser *models.User, userID int, message string) {
+	logAccessDenied(ctx, currentUser, "пользователь вне области видимости", "target_user_id", userID)
+	ctx.JSON(http.StatusForbidden, gin.H{"error": message})
+}
+
+// writeUserAccessError переводит ошибки назначения ролей, групп и организаций в HTTP-ответ.
+func writeUserAccessError(ctx *gin.Context, currentUser *models.User, err error) {
+	switch {
+	case errors.Is(err, service.ErrAccessDenied):
+		logAccessDenied(ctx, currentUser, err.Error())
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав для назначения роли или группы"})
+	case errors.Is(err, service.ErrInvalidRole):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестная роль"})
+	case errors.Is(err, service.ErrGroupNotFound):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Группа не найдена"})
+	case errors.Is(err, service.ErrGroupNameEmpty):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите имя группы"})
+	case errors.Is(err, service.ErrGroupMemberNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не состоит в группе"})
+	case errors.Is(err, service.ErrOrganizationNameEmpty):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите имя организации"})
+	case errors.Is(err, service.ErrOrganizationNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Организация не найдена"})
+	case errors.Is(err, service.ErrTimezoneInvalid):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный часовой пояс"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка изменения прав пользователя"})
+	}
+}
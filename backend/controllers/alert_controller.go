@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"locator/models"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AlertController — алерты по устройствам и трекингу, правила алертинга (admin).
+type AlertController struct {
+	AlertService *service.AlertService
+}
+
+func NewAlertController(alertService *service.AlertService) *AlertController {
+	return &AlertController{AlertService: alertService}
+}
+
+// AlertListResponse — страница алертов и общее число подходящих под фильтр.
+type AlertListResponse struct {
+	Alerts []models.Alert `json:"alerts"`
+	Total  int64          `json:"total"`
+}
+
+func writeAlertError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAlertNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Алерт не найден"})
+	case errors.Is(err, service.ErrAlertAlreadyResolved):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Алерт уже закрыт"})
+	case errors.Is(err, service.ErrAlertRuleNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Правило не найдено"})
+	case errors.Is(err, service.ErrAlertRuleInvalid):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Некорректное правило: %v", err)})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка алертинга"})
+	}
+}
+
+// GetAlerts — GET /api/alerts?status=&user_id=&rule_id=&type=&limit=&offset=
+func (ac *AlertController) GetAlerts(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	if status := ctx.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	if alertType := ctx.Query("type"); alertType != "" {
+		filters["type"] = alertType
+	}
+	for _, key := range []string{"user_id", "rule_id"} {
+		raw := ctx.Query(key)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": key + " должен быть числом"})
+			return
+		}
+		filters[key] = id
+	}
+	if scope := userScopeFromContext(ctx); !scope.All() {
+		if id, ok := filters["user_id"].(int); ok && !scope.Allows(id) {
+			denyUserAccess(ctx, currentUser, id, "Нет доступа к пользователю")
+			return
+		}
+		if _, ok := filters["user_id"]; !ok {
+			filters["user_id"] = scope.UserIDs()
+		}
+	}
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+	offset, _ := strconv.Atoi(ctx.Query("offset"))
+
+	alerts, total, err := ac.AlertService.ListAlerts(filters, limit, offset)
+	if err != nil {
+		writeAlertError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, AlertListResponse{Alerts: alerts, Total: total})
+}
+
+// PostAcknowledge — POST /api/alerts/:id/ack
+func (ac *AlertController) PostAcknowledge(ctx *gin.Context) {
+	ac.changeAlertStatus(ctx, ac.AlertService.Acknowledge)
+}
+
+// PostResolve — POST /api/alerts/:id/resolve
+func (ac *AlertController) PostResolve(ctx *gin.Context) {
+	ac.changeAlertStatus(ctx, ac.AlertService.Resolve)
+}
+
+func (ac *AlertController) changeAlertStatus(ctx *gin.Context, apply func(id, byUserID int) (*models.Alert, error)) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID алерта"})
+		return
+	}
+	if scope := userScopeFromContext(ctx); !scope.All() {
+		alert, err := ac.AlertService.GetAlert(id)
+		if err != nil {
+			writeAlertError(ctx, err)
+			return
+		}
+		if !scope.Allows(alert.UserID) {
+			denyUserAccess(ctx, currentUser, alert.UserID, "Нет доступа к пользователю")
+			return
+		}
+	}
+
+	alert, err := apply(id, currentUser.ID)
+	if err != nil {
+		writeAlertError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, alert)
+}
+
+// PostEvaluate — POST /api/admin/alerts/evaluate — внеочередная проверка правил.
+func (ac *AlertController) PostEvaluate(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	result, err := ac.AlertService.Evaluate()
+	if err != nil {
+		writeAlertError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// GetRules — GET /api/admin/alert-rules
+func (ac *AlertController) GetRules(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	rules, err := ac.AlertService.ListRules(currentUser.OrganizationID)
+	if err != nil {
+		writeAlertError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, rules)
+}
+
+// PostRule — POST /api/admin/alert-rules
+func (ac *AlertController) PostRule(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	var rule models.AlertRule
+	if err := ctx.ShouldBindJSON(&rule); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+	if err := ac.AlertService.CreateRule(currentUser.OrganizationID, &rule); err != nil {
+		writeAlertError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, rule)
+}
+
+// PutRule — PUT /api/admin/alert-rules/:id
+func (ac *AlertController) PutRule(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID правила"})
+		return
+	}
+	var rule models.AlertRule
+	if err := ctx.ShouldBindJSON(&rule); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+	if err := ac.AlertService.UpdateRule(currentUser.OrganizationID, id, &rule); err != nil {
+		writeAlertError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule — DELETE /api/admin/alert-rules/:id
+func (ac *AlertController) DeleteRule(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID правила"})
+		return
+	}
+	if err := ac.AlertService.DeleteRule(currentUser.OrganizationID, id); err != nil {
+		writeAlertError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, OKResponse{OK: true})
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"locator/models"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// managedUserID — ID пользователя из :id, которым текущий сотрудник вправе управлять.
+func (uc *UserController) managedUserID(ctx *gin.Context) (int, bool) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return 0, false
+	}
+	if !uc.canManageUser(ctx, currentUser, id) {
+		return 0, false
+	}
+	return id, true
+}
+
+// APIKeyCreatedResponse — выпущенный ключ; api_key показывается один раз.
+type APIKeyCreatedResponse struct {
+	Key    *models.APIKey `json:"key"`
+	APIKey string         `json:"api_key"`
+}
+
+// GetUserAPIKeys — GET /api/admin/users/:id/api-keys: ключи пользователя без секретов.
+func (uc *UserController) GetUserAPIKeys(ctx *gin.Context) {
+	id, ok := uc.managedUserID(ctx)
+	if !ok {
+		return
+	}
+	keys, err := uc.Service.ListAPIKeys(id)
+	if err != nil {
+		slog.ErrorContext(ctx.Request.Context(), "Ошибка получения API-ключей", "user_id", id, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения API-ключей"})
+		return
+	}
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// PostUserAPIKey — POST /api/admin/users/:id/api-keys {"label":"скрипт","expires_at":"2027-01-01T00:00:00Z"}
+// Выпускает дополнительный ключ; сам ключ показывается один раз.
+func (uc *UserController) PostUserAPIKey(ctx *gin.Context) {
+	id, ok := uc.managedUserID(ctx)
+	if !ok {
+		return
+	}
+	var body service.APIKeyInput
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные запроса"})
+		return
+	}
+	key, plainKey, err := uc.Service.CreateAPIKey(id, body)
+	switch {
+	case errors.Is(err, service.ErrAPIKeyLabelTooLong), errors.Is(err, service.ErrAPIKeyExpired):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка выпуска API-ключа"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, APIKeyCreatedResponse{Key: key, APIKey: plainKey})
+}
+
+// DeleteUserAPIKey — DELETE /api/admin/users/:id/api-keys/:key_id: немедленный отзыв ключа.
+func (uc *UserController) DeleteUserAPIKey(ctx *gin.Context) {
+	id, ok := uc.managedUserID(ctx)
+	if !ok {
+		return
+	}
+	keyID, err := strconv.Atoi(ctx.Param("key_id"))
+	if err != nil || keyID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID ключа"})
+		return
+	}
+	key, err := uc.Service.RevokeAPIKey(id, keyID)
+	switch {
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка отзыва API-ключа"})
+		return
+	}
+	ctx.JSON(http.StatusOK, key)
+}
@@ -2,28 +2,37 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
+	"locator/models"
 	"locator/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AppReleaseController — публикация APK для OTA (app_update).
+// AppReleaseController — публикация APK для OTA (app_update): legacy manifest.json,
+// релизы по каналам с поэтапной раскаткой и минимальной версией.
 type AppReleaseController struct {
 	ManifestPath string
 	ReleasesDir  string
 	BaseURL      string
+	Releases     *service.AppReleaseService
 }
 
-func NewAppReleaseController(manifestPath, releasesDir, baseURL string) *AppReleaseController {
+func NewAppReleaseController(manifestPath, releasesDir, baseURL string, releases *service.AppReleaseService) *AppReleaseController {
 	return &AppReleaseController{
 		ManifestPath: manifestPath,
 		ReleasesDir:  releasesDir,
 		BaseURL:      baseURL,
+		Releases:     releases,
 	}
 }
 
@@ -43,8 +52,34 @@ func (rc *AppReleaseController) loadManifest() (map[string]interface{}, error) {
 	return m, nil
 }
 
-// GetLatestRelease — GET /api/app/release/latest (без авторизации).
+// GetLatestRelease — GET /api/app/release/latest[?channel=][&organization_id=] (без авторизации).
+// Самый новый релиз канала организации (по умолчанию — 1) на 100% раскатки;
+// если релизов в БД нет — manifest.json.
 func (rc *AppReleaseController) GetLatestRelease(ctx *gin.Context) {
+	if rc.Releases != nil {
+		channel, err := service.NormalizeAppReleaseChannel(ctx.Query("channel"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный канал"})
+			return
+		}
+		organizationID := models.DefaultOrganizationID
+		if v := ctx.Query("organization_id"); v != "" {
+			if organizationID, err = strconv.Atoi(v); err != nil || organizationID <= 0 {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный organization_id"})
+				return
+			}
+		}
+		m, err := rc.Releases.LatestManifest(organizationID, channel)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка чтения релизов"})
+			return
+		}
+		if m != nil {
+			ctx.JSON(http.StatusOK, m)
+			return
+		}
+	}
+
 	m, err := rc.loadManifest()
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -68,7 +103,11 @@ func (rc *AppReleaseController) SyncManifestFromReleaseAPK() (map[string]interfa
 		return nil, fmt.Errorf("manifest: filename пуст")
 	}
 	apkPath := filepath.Join(rc.ReleasesDir, filename)
-	return service.WriteReleaseManifest(apkPath, rc.ManifestPath, rc.BaseURL, "")
+	force, ok := m["force"].(bool)
+	if !ok {
+		force = true
+	}
+	return service.WriteReleaseManifest(apkPath, rc.ManifestPath, rc.BaseURL, "", force)
 }
 
 // ManifestForAppUpdate возвращает payload для команды app_update.
@@ -88,8 +127,44 @@ func (rc *AppReleaseController) ManifestForAppUpdate() (map[string]interface{},
 	}, nil
 }
 
+// SyncManifestRequest — тело POST /api/admin/releases/sync-manifest; без filename —
+// APK из текущего manifest.json, без force — true.
+type SyncManifestRequest struct {
+	Filename  string `json:"filename"`
+	Changelog string `json:"changelog"`
+	Force     *bool  `json:"force"`
+}
+
+// SyncManifestResponse — manifest.json после пересчёта.
+type SyncManifestResponse struct {
+	Message  string                 `json:"message"`
+	Manifest map[string]interface{} `json:"manifest"`
+}
+
+// ReleaseStatusRequest — необязательная причина паузы, возобновления или архивации.
+type ReleaseStatusRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ReleaseChannelRequest — тело PUT /api/admin/release-channels/:name.
+type ReleaseChannelRequest struct {
+	MinVersion string `json:"min_version"`
+}
+
+// UserReleaseChannelRequest — тело PUT /api/admin/users/:id/release-channel.
+type UserReleaseChannelRequest struct {
+	Channel string `json:"channel"`
+}
+
+// UserReleaseChannelResponse — канал, назначенный пользователю.
+type UserReleaseChannelResponse struct {
+	UserID  int    `json:"user_id"`
+	Channel string `json:"channel"`
+}
+
 // PostSyncReleaseManifest — POST /api/admin/releases/sync-manifest
-// Перечитывает versionCode/versionName из APK в releases и обновляет manifest.json.
+// Перечитывает versionCode/versionName из APK в releases и обновляет manifest.json
+// (force по умолчанию true, как раньше).
 func (rc *AppReleaseController) PostSyncReleaseManifest(ctx *gin.Context) {
 	currentUser, ok := getCurrentUserFromContext(ctx)
 	if !ok {
@@ -100,10 +175,7 @@ func (rc *AppReleaseController) PostSyncReleaseManifest(ctx *gin.Context) {
 		return
 	}
 
-	var body struct {
-		Filename  string `json:"filename"`
-		Changelog string `json:"changelog"`
-	}
+	var body SyncManifestRequest
 	_ = ctx.ShouldBindJSON(&body)
 
 	apkPath := filepath.Join(rc.ReleasesDir, body.Filename)
@@ -122,14 +194,322 @@ func (rc *AppReleaseController) PostSyncReleaseManifest(ctx *gin.Context) {
 		return
 	}
 
-	manifest, err := service.WriteReleaseManifest(apkPath, rc.ManifestPath, rc.BaseURL, body.Changelog)
+	force := true
+	if body.Force != nil {
+		force = *body.Force
+	}
+	manifest, err := service.WriteReleaseManifest(apkPath, rc.ManifestPath, rc.BaseURL, body.Changelog, force)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"message":  "manifest обновлён из APK",
-		"manifest": manifest,
+	ctx.JSON(http.StatusOK, SyncManifestResponse{
+		Message:  "manifest обновлён из APK",
+		Manifest: manifest,
 	})
 }
+
+func writeAppReleaseError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAppReleaseNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Релиз не найден"})
+	case errors.Is(err, service.ErrAppReleaseExists), errors.Is(err, service.ErrAppReleaseVersionNotNewer):
+		ctx.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Релиз не принят: %v", err)})
+	case errors.Is(err, service.ErrAppReleaseInvalid):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Некорректный релиз: %v", err)})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка релизов"})
+	}
+}
+
+// requireReleaseAdmin — текущий пользователь-админ и настроенный сервис релизов.
+// Релизы и каналы берутся из организации возвращённого пользователя.
+func (rc *AppReleaseController) requireReleaseAdmin(ctx *gin.Context) (*models.User, bool) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return nil, false
+	}
+	if rc.Releases == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Релизы не настроены"})
+		return nil, false
+	}
+	return currentUser, true
+}
+
+func releaseIDParam(ctx *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID релиза"})
+		return 0, false
+	}
+	return id, true
+}
+
+// GetReleases — GET /api/admin/releases[?channel=] — история релизов.
+func (rc *AppReleaseController) GetReleases(ctx *gin.Context) {
+	currentUser, ok := rc.requireReleaseAdmin(ctx)
+	if !ok {
+		return
+	}
+	releases, err := rc.Releases.ListReleases(currentUser.OrganizationID, ctx.Query("channel"))
+	if err != nil {
+		writeAppReleaseError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, releases)
+}
+
+// PostRelease — POST /api/admin/releases
+// multipart/form-data: файл "apk" (поля channel, changelog, force, rollout_percent,
+// max_failure_percent, min_failure_samples — до файла или в query) загружается потоково,
+// проверяется (package, подпись, сертификат, version_code) и регистрируется как релиз.
+// JSON {"filename": ...} регистрирует APK, уже лежащий в static/releases.
+func (rc *AppReleaseController) PostRelease(ctx *gin.Context) {
+	currentUser, ok := rc.requireReleaseAdmin(ctx)
+	if !ok {
+		return
+	}
+	if ctx.ContentType() == "multipart/form-data" {
+		rc.uploadRelease(ctx, currentUser.OrganizationID)
+		return
+	}
+
+	var body service.AppReleaseInput
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+	body.OrganizationID = currentUser.OrganizationID
+	release, err := rc.Releases.CreateRelease(body)
+	if err != nil {
+		writeAppReleaseError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, release)
+}
+
+func (rc *AppReleaseController) uploadRelease(ctx *gin.Context, organizationID int) {
+	reader, err := ctx.Request.MultipartReader()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный multipart"})
+		return
+	}
+	fields := ctx.Request.URL.Query()
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Нет файла apk"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный multipart"})
+			return
+		}
+		if part.FormName() != "apk" {
+			value, err := io.ReadAll(io.LimitReader(part, 64<<10))
+			_ = part.Close()
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный multipart"})
+				return
+			}
+			fields.Set(part.FormName(), string(value))
+			continue
+		}
+
+		input, err := appReleaseInputFromForm(fields)
+		if err != nil {
+			_ = part.Close()
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		input.OrganizationID = organizationID
+		release, err := rc.Releases.UploadRelease(part, input)
+		_ = part.Close()
+		if err != nil {
+			writeAppReleaseError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, release)
+		return
+	}
+}
+
+func appReleaseInputFromForm(fields url.Values) (service.AppReleaseInput, error) {
+	input := service.AppReleaseInput{
+		Channel:   fields.Get("channel"),
+		Changelog: fields.Get("changelog"),
+	}
+	if v := fields.Get("force"); v != "" {
+		force, err := strconv.ParseBool(v)
+		if err != nil {
+			return input, fmt.Errorf("Некорректное значение force")
+		}
+		input.Force = force
+	}
+	for name, dst := range map[string]**int{
+		"rollout_percent":     &input.RolloutPercent,
+		"max_failure_percent": &input.MaxFailurePercent,
+		"min_failure_samples": &input.MinFailureSamples,
+	} {
+		v := fields.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return input, fmt.Errorf("Некорректное значение %s", name)
+		}
+		*dst = &n
+	}
+	return input, nil
+}
+
+// PutRelease — PUT /api/admin/releases/:id — доля раскатки, порог автопаузы, force, changelog.
+func (rc *AppReleaseController) PutRelease(ctx *gin.Context) {
+	currentUser, ok := rc.requireReleaseAdmin(ctx)
+	if !ok {
+		return
+	}
+	id, ok := releaseIDParam(ctx)
+	if !ok {
+		return
+	}
+	var body service.AppReleaseUpdateInput
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+	release, err := rc.Releases.UpdateRelease(currentUser.OrganizationID, id, body)
+	if err != nil {
+		writeAppReleaseError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, release)
+}
+
+func (rc *AppReleaseController) setReleaseStatus(ctx *gin.Context, status string) {
+	currentUser, ok := rc.requireReleaseAdmin(ctx)
+	if !ok {
+		return
+	}
+	id, ok := releaseIDParam(ctx)
+	if !ok {
+		return
+	}
+	var body ReleaseStatusRequest
+	_ = ctx.ShouldBindJSON(&body)
+	release, err := rc.Releases.SetReleaseStatus(currentUser.OrganizationID, id, status, body.Reason, time.Now())
+	if err != nil {
+		writeAppReleaseError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, release)
+}
+
+// PostPauseRelease — POST /api/admin/releases/:id/pause
+func (rc *AppReleaseController) PostPauseRelease(ctx *gin.Context) {
+	rc.setReleaseStatus(ctx, models.AppReleaseStatusPaused)
+}
+
+// PostResumeRelease — POST /api/admin/releases/:id/resume
+func (rc *AppReleaseController) PostResumeRelease(ctx *gin.Context) {
+	rc.setReleaseStatus(ctx, models.AppReleaseStatusActive)
+}
+
+// PostArchiveRelease — POST /api/admin/releases/:id/archive — релиз больше не раздаётся.
+func (rc *AppReleaseController) PostArchiveRelease(ctx *gin.Context) {
+	rc.setReleaseStatus(ctx, models.AppReleaseStatusArchived)
+}
+
+// GetReleaseStats — GET /api/admin/releases/:id/stats — итоги app_update по релизу.
+func (rc *AppReleaseController) GetReleaseStats(ctx *gin.Context) {
+	currentUser, ok := rc.requireReleaseAdmin(ctx)
+	if !ok {
+		return
+	}
+	id, ok := releaseIDParam(ctx)
+	if !ok {
+		return
+	}
+	stats, err := rc.Releases.ReleaseStats(currentUser.OrganizationID, id)
+	if err != nil {
+		writeAppReleaseError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
+}
+
+// GetReleaseChannels — GET /api/admin/release-channels
+func (rc *AppReleaseController) GetReleaseChannels(ctx *gin.Context) {
+	currentUser, ok := rc.requireReleaseAdmin(ctx)
+	if !ok {
+		return
+	}
+	channels, err := rc.Releases.ListChannels(currentUser.OrganizationID)
+	if err != nil {
+		writeAppReleaseError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, channels)
+}
+
+// PutReleaseChannel — PUT /api/admin/release-channels/:name — {"min_version": "1.4.0"}.
+func (rc *AppReleaseController) PutReleaseChannel(ctx *gin.Context) {
+	currentUser, ok := rc.requireReleaseAdmin(ctx)
+	if !ok {
+		return
+	}
+	var body ReleaseChannelRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+	ch, err := rc.Releases.SetChannelMinVersion(currentUser.OrganizationID, ctx.Param("name"), body.MinVersion)
+	if err != nil {
+		writeAppReleaseError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, ch)
+}
+
+// GetReleaseChannelMembers — GET /api/admin/release-channels/members — назначения не-stable каналов.
+func (rc *AppReleaseController) GetReleaseChannelMembers(ctx *gin.Context) {
+	currentUser, ok := rc.requireReleaseAdmin(ctx)
+	if !ok {
+		return
+	}
+	members, err := rc.Releases.ListMembers(currentUser.OrganizationID)
+	if err != nil {
+		writeAppReleaseError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, members)
+}
+
+// PutUserReleaseChannel — PUT /api/admin/users/:id/release-channel — {"channel": "beta"}.
+func (rc *AppReleaseController) PutUserReleaseChannel(ctx *gin.Context) {
+	if _, ok := rc.requireReleaseAdmin(ctx); !ok {
+		return
+	}
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || userID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+	var body UserReleaseChannelRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+	channel, err := rc.Releases.SetUserChannel(userID, body.Channel)
+	if err != nil {
+		writeAppReleaseError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, UserReleaseChannelResponse{UserID: userID, Channel: channel})
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"locator/models"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditController — просмотр и выгрузка журнала аудита административных действий.
+type AuditController struct {
+	Service *service.AuditService
+}
+
+func NewAuditController(auditService *service.AuditService) *AuditController {
+	return &AuditController{Service: auditService}
+}
+
+// AuditListResponse — страница журнала аудита и общее число записей под фильтром.
+type AuditListResponse struct {
+	Entries []models.AuditEntry `json:"entries"`
+	Total   int64               `json:"total"`
+}
+
+// auditFilterFromQuery — фильтры из query: organization_id, actor_id, action (начало
+// строки), target_type, target_id, result, from/to (RFC3339). При ошибке запрос прерван.
+func auditFilterFromQuery(ctx *gin.Context) (service.AuditFilter, bool) {
+	filter := service.AuditFilter{
+		Action:     ctx.Query("action"),
+		TargetType: ctx.Query("target_type"),
+		TargetID:   ctx.Query("target_id"),
+		Result:     ctx.Query("result"),
+	}
+	for key, dst := range map[string]*int{"organization_id": &filter.OrganizationID, "actor_id": &filter.ActorID} {
+		raw := ctx.Query(key)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": key + " должен быть положительным числом"})
+			return filter, false
+		}
+		*dst = id
+	}
+	for key, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		raw := ctx.Query(key)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": key + " должен быть в формате RFC3339"})
+			return filter, false
+		}
+		*dst = &t
+	}
+	return filter, true
+}
+
+func writeAuditError(ctx *gin.Context, currentUser *models.User, err error) {
+	if errors.Is(err, service.ErrAccessDenied) {
+		logAccessDenied(ctx, currentUser, "журнал аудита вне области видимости")
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Нет доступа к журналу аудита этой организации или сотрудника"})
+		return
+	}
+	slog.ErrorContext(ctx.Request.Context(), "Ошибка чтения журнала аудита", "error", err)
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка чтения журнала аудита"})
+}
+
+// GetAudit — GET /api/admin/audit?actor_id=&action=&target_type=&target_id=&result=&from=&to=&limit=&offset=
+func (ac *AuditController) GetAudit(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	filter, ok := auditFilterFromQuery(ctx)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+	offset, _ := strconv.Atoi(ctx.Query("offset"))
+
+	entries, total, err := ac.Service.List(currentUser, userScopeFromContext(ctx), filter, limit, offset)
+	if err != nil {
+		writeAuditError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, AuditListResponse{Entries: entries, Total: total})
+}
+
+// auditCSVHeader — столбцы выгрузки в CSV.
+var auditCSVHeader = []string{
+	"id", "at", "organization_id", "actor_id", "actor_name", "actor_role", "session_id",
+	"action", "target_type", "target_id", "result", "status", "error", "ip", "params",
+}
+
+// csvCell защищает от формул при открытии выгрузки в табличном редакторе:
+// значение, начинающееся с = + - @, получает ведущий апостроф.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// GetAuditExport — GET /api/admin/audit/export?format=csv|jsonl&<фильтры как у /audit>
+// Выгрузка файлом (не больше service.AuditExportMaxRows записей, новые первыми).
+func (ac *AuditController) GetAuditExport(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	filter, ok := auditFilterFromQuery(ctx)
+	if !ok {
+		return
+	}
+	format := ctx.DefaultQuery("format", "csv")
+	if format != "csv" && format != "jsonl" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format должен быть csv или jsonl"})
+		return
+	}
+
+	// Права проверяются до начала ответа: после первых строк код ответа уже не изменить.
+	if _, _, err := ac.Service.List(currentUser, userScopeFromContext(ctx), filter, 1, 0); err != nil {
+		writeAuditError(ctx, currentUser, err)
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	ctx.Header("Cache-Control", "no-store")
+
+	var emit func(*models.AuditEntry) error
+	var flush func() error
+	if format == "csv" {
+		ctx.Header("Content-Type", "text/csv; charset=utf-8")
+		w := csv.NewWriter(ctx.Writer)
+		if err := w.Write(auditCSVHeader); err != nil {
+			return
+		}
+		emit = func(e *models.AuditEntry) error {
+			actorID := ""
+			if e.ActorID != nil {
+				actorID = strconv.Itoa(*e.ActorID)
+			}
+			return w.Write([]string{
+				strconv.FormatInt(e.ID, 10), e.At.UTC().Format(time.RFC3339), strconv.Itoa(e.OrganizationID),
+				actorID, csvCell(e.ActorName), e.ActorRole, e.SessionID,
+				e.Action, e.TargetType, csvCell(e.TargetID), e.Result, strconv.Itoa(e.Status), csvCell(e.Error), e.IP, string(e.Params),
+			})
+		}
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+	} else {
+		ctx.Header("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(ctx.Writer)
+		emit = func(e *models.AuditEntry) error { return enc.Encode(e) }
+		flush = func() error { return nil }
+	}
+	ctx.Status(http.StatusOK)
+
+	err := ac.Service.Export(currentUser, userScopeFromContext(ctx), filter, emit)
+	if ferr := flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		// Заголовки уже отправлены — остаётся только прервать выгрузку и записать в лог.
+		slog.ErrorContext(ctx.Request.Context(), "Выгрузка журнала аудита прервана", "user_id", currentUser.ID, "error", err)
+	}
+}
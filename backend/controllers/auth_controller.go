@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"locator/internal/metrics"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AuthController — вход сотрудников в веб-интерфейс: логин/пароль или одноразовая
+// ссылка, TOTP, обновление и отзыв сессий.
+type AuthController struct {
+	Sessions *service.SessionService
+}
+
+func NewAuthController(sessions *service.SessionService) *AuthController {
+	return &AuthController{Sessions: sessions}
+}
+
+func sessionMeta(ctx *gin.Context) service.SessionMeta {
+	return service.SessionMeta{UserAgent: ctx.Request.UserAgent(), IP: ctx.ClientIP()}
+}
+
+// writeAuthError переводит ошибки входа и сессий в HTTP-ответ. code — машинно-читаемая
+// причина для фронтенда (например, totp_required — показать поле кода).
+func writeAuthError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
+		metrics.AuthFailures.Inc("invalid_credentials")
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный логин или пароль", "code": "invalid_credentials"})
+	case errors.Is(err, service.ErrTOTPRequired):
+		metrics.AuthFailures.Inc("totp_required")
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Введите код из приложения-аутентификатора", "code": "totp_required"})
+	case errors.Is(err, service.ErrInvalidTOTP):
+		metrics.AuthFailures.Inc("totp_invalid")
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный код двухфакторной аутентификации", "code": "totp_invalid"})
+	case errors.Is(err, service.ErrCredentialsNotAllowed):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Вход по паролю доступен только сотрудникам"})
+	case errors.Is(err, service.ErrSessionInvalid):
+		metrics.AuthFailures.Inc("session_invalid")
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Сессия истекла или недействительна", "code": "session_invalid"})
+	case errors.Is(err, service.ErrSessionNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Сессия не найдена"})
+	case errors.Is(err, service.ErrLoginLinkInvalid):
+		metrics.AuthFailures.Inc("login_link_invalid")
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Ссылка для входа недействительна или истекла", "code": "login_link_invalid"})
+	case errors.Is(err, service.ErrTOTPAlreadyEnabled):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Двухфакторная аутентификация уже включена"})
+	case errors.Is(err, service.ErrTOTPNotEnabled):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Двухфакторная аутентификация не включена"})
+	case errors.Is(err, service.ErrUsernameEmpty):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите логин"})
+	case errors.Is(err, service.ErrUsernameTaken):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Логин уже занят"})
+	case errors.Is(err, service.ErrPasswordTooShort):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Пароль должен быть не короче 8 символов"})
+	case errors.Is(err, service.ErrAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+	default:
+		slog.ErrorContext(ctx.Request.Context(), "Ошибка авторизации", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка авторизации"})
+	}
+}
+
+// LoginRequest — тело POST /api/auth/login; totp_code — при включённом втором факторе.
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	TOTPCode string `json:"totp_code"`
+}
+
+// LoginLinkRequest — тело POST /api/auth/login-link.
+type LoginLinkRequest struct {
+	Token    string `json:"token" binding:"required"`
+	TOTPCode string `json:"totp_code"`
+}
+
+// RefreshRequest — тело POST /api/auth/refresh.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// PasswordChangeRequest — тело PUT /api/auth/password.
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// TOTPCodeRequest — код из приложения-аутентификатора для /totp/enable и /totp/disable.
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// SessionInfo — элемент GET /api/auth/sessions; current — сессия этого запроса.
+type SessionInfo struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// LoginLinkResponse — одноразовая ссылка входа; токен показывается один раз.
+type LoginLinkResponse struct {
+	Token     string    `json:"token"`
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// currentSessionID — сессия текущего запроса (устанавливается SessionAuthMiddleware).
+func currentSessionID(ctx *gin.Context) string {
+	return ctx.GetString("session_id")
+}
+
+// PostLogin — POST /api/auth/login {"username","password","totp_code"}
+func (ac *AuthController) PostLogin(ctx *gin.Context) {
+	var body LoginRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите логин и пароль"})
+		return
+	}
+	tokens, err := ac.Sessions.Login(body.Username, body.Password, body.TOTPCode, sessionMeta(ctx))
+	if err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// PostLoginLink — POST /api/auth/login-link {"token","totp_code"}
+func (ac *AuthController) PostLoginLink(ctx *gin.Context) {
+	var body LoginLinkRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите token"})
+		return
+	}
+	tokens, err := ac.Sessions.LoginWithLink(body.Token, body.TOTPCode, sessionMeta(ctx))
+	if err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// PostRefresh — POST /api/auth/refresh {"refresh_token"}; старый refresh-токен перестаёт действовать.
+func (ac *AuthController) PostRefresh(ctx *gin.Context) {
+	var body RefreshRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите refresh_token"})
+		return
+	}
+	tokens, err := ac.Sessions.Refresh(body.RefreshToken, sessionMeta(ctx))
+	if err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// PostLogout — POST /api/auth/logout — закрывает текущую сессию.
+func (ac *AuthController) PostLogout(ctx *gin.Context) {
+	if err := ac.Sessions.Logout(currentSessionID(ctx)); err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, StatusResponse{Status: "ok"})
+}
+
+// GetSessions — GET /api/auth/sessions — действующие сессии текущего сотрудника.
+func (ac *AuthController) GetSessions(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	sessions, err := ac.Sessions.ListSessions(currentUser.ID)
+	if err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	current := currentSessionID(ctx)
+	out := make([]SessionInfo, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, SessionInfo{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.ID == current,
+		})
+	}
+	ctx.JSON(http.StatusOK, out)
+}
+
+// DeleteSession — DELETE /api/auth/sessions/:id — выход на другом устройстве.
+func (ac *AuthController) DeleteSession(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if err := ac.Sessions.RevokeSession(currentUser.ID, ctx.Param("id")); err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, StatusResponse{Status: "ok"})
+}
+
+// PutPassword — PUT /api/auth/password {"current_password","new_password"};
+// остальные сессии сотрудника закрываются.
+func (ac *AuthController) PutPassword(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	var body PasswordChangeRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите new_password"})
+		return
+	}
+	if err := ac.Sessions.ChangePassword(currentUser.ID, currentSessionID(ctx), body.CurrentPassword, body.NewPassword); err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, StatusResponse{Status: "ok"})
+}
+
+// PostTOTPSetup — POST /api/auth/totp/setup — новый секрет; включается после /totp/enable.
+func (ac *AuthController) PostTOTPSetup(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	setup, err := ac.Sessions.SetupTOTP(currentUser.ID)
+	if err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, setup)
+}
+
+// PostTOTPEnable — POST /api/auth/totp/enable {"code"}
+func (ac *AuthController) PostTOTPEnable(ctx *gin.Context) {
+	ac.changeTOTP(ctx, ac.Sessions.EnableTOTP)
+}
+
+// PostTOTPDisable — POST /api/auth/totp/disable {"code"}
+func (ac *AuthController) PostTOTPDisable(ctx *gin.Context) {
+	ac.changeTOTP(ctx, ac.Sessions.DisableTOTP)
+}
+
+func (ac *AuthController) changeTOTP(ctx *gin.Context, apply func(userID int, code string) error) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	var body TOTPCodeRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите code"})
+		return
+	}
+	if err := apply(currentUser.ID, body.Code); err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, StatusResponse{Status: "ok"})
+}
+
+// PutUserCredentials — PUT /api/admin/users/:id/credentials {"username","password","reset_totp"}
+func (ac *AuthController) PutUserCredentials(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+	var body service.CredentialsInput
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные запроса"})
+		return
+	}
+	user, err := ac.Sessions.SetCredentials(currentUser, userScopeFromContext(ctx), id, body)
+	if err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, user)
+}
+
+// PostUserLoginLink — POST /api/admin/users/:id/login-link — одноразовая ссылка входа.
+// Токен показывается один раз; path — путь страницы входа веб-интерфейса.
+func (ac *AuthController) PostUserLoginLink(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+	token, link, err := ac.Sessions.CreateLoginLink(currentUser, userScopeFromContext(ctx), id)
+	if err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, LoginLinkResponse{
+		Token:     token,
+		Path:      "/login?login_token=" + url.QueryEscape(token),
+		ExpiresAt: link.ExpiresAt,
+	})
+}
+
+// PostRevokeUserSessions — POST /api/admin/users/:id/sessions/revoke — выход сотрудника везде.
+func (ac *AuthController) PostRevokeUserSessions(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+	if err := ac.Sessions.RevokeUserSessions(currentUser, userScopeFromContext(ctx), id); err != nil {
+		writeAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, StatusResponse{Status: "ok"})
+}
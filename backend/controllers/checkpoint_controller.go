@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"locator/config/messaging"
 	"locator/models"
 	"locator/service"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // CheckpointController отвечает за обработку запросов, связанных с чекпоинтами.
@@ -35,19 +37,26 @@ func NewCheckpointController(
 	}
 }
 
+// CheckpointRequest — тело создания и изменения чекпоинта; radius — в метрах.
+type CheckpointRequest struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Radius    float64 `json:"radius"`
+}
+
 // PostCheckpoint обрабатывает POST-запрос для создания нового чекпоинта.
 func (cc *CheckpointController) PostCheckpoint(ctx *gin.Context) {
-	var req struct {
-		Name      string  `json:"name"`
-		Latitude  float64 `json:"latitude"`
-		Longitude float64 `json:"longitude"`
-		Radius    float64 `json:"radius"`
-	}
+	var req CheckpointRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
 		return
 	}
-	cp, err := cc.Service.CreateCheckpoint(req.Name, req.Latitude, req.Longitude, req.Radius)
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	cp, err := cc.Service.CreateCheckpoint(currentUser.OrganizationID, req.Name, req.Latitude, req.Longitude, req.Radius)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания чекпоинта"})
 		return
@@ -55,9 +64,13 @@ func (cc *CheckpointController) PostCheckpoint(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, cp)
 }
 
-// GetCheckpoints обрабатывает GET-запрос для получения всех чекпоинтов.
+// GetCheckpoints обрабатывает GET-запрос для получения всех чекпоинтов организации.
 func (cc *CheckpointController) GetCheckpoints(ctx *gin.Context) {
-	checkpoints, err := cc.Service.GetCheckpoints()
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	checkpoints, err := cc.Service.GetCheckpoints(currentUser.OrganizationID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения чекпоинтов"})
 		return
@@ -75,18 +88,21 @@ func (cc *CheckpointController) UpdateCheckpoint(ctx *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Name      string  `json:"name"`
-		Latitude  float64 `json:"latitude"`
-		Longitude float64 `json:"longitude"`
-		Radius    float64 `json:"radius"`
-	}
+	var req CheckpointRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
 		return
 	}
 
-	cp, err := cc.Service.UpdateCheckpoint(id, req.Name, req.Latitude, req.Longitude, req.Radius)
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	cp, err := cc.Service.UpdateCheckpoint(currentUser.OrganizationID, id, req.Name, req.Latitude, req.Longitude, req.Radius)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Чекпоинт не найден"})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления чекпоинта"})
 		return
@@ -116,6 +132,14 @@ func (cc *CheckpointController) CheckUserInCheckpoint(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "checkpoint_id должен быть числом"})
 		return
 	}
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !userScopeFromContext(ctx).Allows(userID) {
+		denyUserAccess(ctx, currentUser, userID, "Нет доступа к пользователю")
+		return
+	}
 
 	// Получаем локацию пользователя через LocationService.
 	loc, err := cc.LocationService.GetLocation(userID)
@@ -125,7 +149,7 @@ func (cc *CheckpointController) CheckUserInCheckpoint(ctx *gin.Context) {
 	}
 
 	// Получаем чекпоинт по его ID.
-	cp, err := cc.Service.GetCheckpointByID(checkpointID)
+	cp, err := cc.Service.GetCheckpointByID(currentUser.OrganizationID, checkpointID)
 	if err != nil || cp == nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Чекпоинт не найден"})
 		return
@@ -134,18 +158,19 @@ func (cc *CheckpointController) CheckUserInCheckpoint(ctx *gin.Context) {
 	// Формируем событие на основе полученных данных.
 	// Модель LocationEvent должна содержать поля: UserID, CheckpointID, Latitude, Longitude, OccurredAt.
 	event := models.LocationEvent{
-		UserID:       userID,
-		CheckpointID: checkpointID,
-		Latitude:     loc.Latitude,
-		Longitude:    loc.Longitude,
-		OccurredAt:   time.Now(),
+		UserID:         userID,
+		OrganizationID: currentUser.OrganizationID,
+		CheckpointID:   checkpointID,
+		Latitude:       loc.Latitude,
+		Longitude:      loc.Longitude,
+		OccurredAt:     time.Now(),
 	}
 
 	// Публикуем событие в RabbitMQ для дальнейшей асинхронной обработки (например, создания или завершения визита).
-	if err := cc.Publisher.PublishJSON(event); err != nil {
+	if err := cc.Publisher.PublishJSONContext(ctx.Request.Context(), event); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка публикации события"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Событие отправлено на обработку"})
+	ctx.JSON(http.StatusOK, MessageResponse{Message: "Событие отправлено на обработку"})
 }
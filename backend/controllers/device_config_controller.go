@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"locator/models"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceConfigController — desired-state конфигурация устройств и дрейф (admin).
+type DeviceConfigController struct {
+	ConfigService *service.DeviceConfigService
+}
+
+func NewDeviceConfigController(configService *service.DeviceConfigService) *DeviceConfigController {
+	return &DeviceConfigController{ConfigService: configService}
+}
+
+// DeviceConfigProfileRequest — тело POST/PUT /api/admin/device-config/profiles.
+type DeviceConfigProfileRequest struct {
+	Name string `json:"name"`
+	models.DeviceConfigFields
+}
+
+// DeviceDesiredConfigRequest — тело PUT /api/admin/users/:id/device/desired-config:
+// профиль и поля поверх него.
+type DeviceDesiredConfigRequest struct {
+	ProfileID *int `json:"profile_id"`
+	models.DeviceConfigFields
+}
+
+// DesiredConfigResponse — desired-конфигурация пользователя: свои поля (overrides)
+// и итог с профилем (effective); PIN и ключи замаскированы.
+type DesiredConfigResponse struct {
+	UserID       int                       `json:"user_id"`
+	ProfileID    *int                      `json:"profile_id"`
+	Overrides    models.DeviceConfigFields `json:"overrides"`
+	Effective    models.DeviceConfigFields `json:"effective"`
+	LastPushedAt *time.Time                `json:"last_pushed_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+}
+
+// ConfigDriftSummaryResponse — дрейф по всем устройствам; drifted — отчитавшиеся не в синхроне.
+type ConfigDriftSummaryResponse struct {
+	Devices []service.DeviceConfigDriftStatus `json:"devices"`
+	Total   int                               `json:"total"`
+	Drifted int                               `json:"drifted"`
+}
+
+func desiredConfigResponse(cfg *models.DeviceDesiredConfig, effective models.DeviceConfigFields) DesiredConfigResponse {
+	return DesiredConfigResponse{
+		UserID:       cfg.UserID,
+		ProfileID:    cfg.ProfileID,
+		Overrides:    service.MaskDeviceConfigFields(cfg.DeviceConfigFields),
+		Effective:    service.MaskDeviceConfigFields(effective),
+		LastPushedAt: cfg.LastPushedAt,
+		UpdatedAt:    cfg.UpdatedAt,
+	}
+}
+
+func writeDeviceConfigError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrDeviceConfigUpdateInvalid):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные параметры (интервалы, PIN или URL)"})
+	case errors.Is(err, service.ErrDeviceConfigProfileNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Профиль конфигурации не найден"})
+	case errors.Is(err, service.ErrDeviceDesiredConfigNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Желаемая конфигурация не задана"})
+	case errors.Is(err, service.ErrAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Нет доступа к пользователю"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка конфигурации устройства"})
+	}
+}
+
+// GetProfiles — GET /api/admin/device-config/profiles
+func (dcc *DeviceConfigController) GetProfiles(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	profiles, err := dcc.ConfigService.ListProfiles(currentUser.OrganizationID)
+	if err != nil {
+		writeDeviceConfigError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, profiles)
+}
+
+// PostProfile — POST /api/admin/device-config/profiles
+func (dcc *DeviceConfigController) PostProfile(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	var body DeviceConfigProfileRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil || body.Name == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите name профиля"})
+		return
+	}
+
+	profile, err := dcc.ConfigService.CreateProfile(currentUser.OrganizationID, body.Name, body.DeviceConfigFields)
+	if err != nil {
+		writeDeviceConfigError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, profile)
+}
+
+// PutProfile — PUT /api/admin/device-config/profiles/:id
+func (dcc *DeviceConfigController) PutProfile(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID профиля"})
+		return
+	}
+
+	var body DeviceConfigProfileRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+
+	profile, err := dcc.ConfigService.UpdateProfile(currentUser.OrganizationID, id, body.Name, body.DeviceConfigFields)
+	if err != nil {
+		writeDeviceConfigError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, profile)
+}
+
+// GetUserDesiredConfig — GET /api/admin/users/:id/device/desired-config
+func (dcc *DeviceConfigController) GetUserDesiredConfig(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || userID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	cfg, effective, err := dcc.ConfigService.GetDesired(userID)
+	if err != nil {
+		writeDeviceConfigError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, desiredConfigResponse(cfg, effective))
+}
+
+// PutUserDesiredConfig — PUT /api/admin/users/:id/device/desired-config
+// Полностью заменяет desired-конфигурацию; дрейф устраняется при следующем отчёте телефона.
+func (dcc *DeviceConfigController) PutUserDesiredConfig(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || userID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	var body DeviceDesiredConfigRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+
+	cfg, err := dcc.ConfigService.SetDesired(currentUser.OrganizationID, userScopeFromContext(ctx), userID, body.ProfileID, body.DeviceConfigFields)
+	if err != nil {
+		writeDeviceConfigError(ctx, err)
+		return
+	}
+	_, effective, err := dcc.ConfigService.GetDesired(userID)
+	if err != nil {
+		writeDeviceConfigError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, desiredConfigResponse(cfg, effective))
+}
+
+// GetUserConfigDrift — GET /api/admin/users/:id/device/config-drift
+func (dcc *DeviceConfigController) GetUserConfigDrift(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || userID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	status, err := dcc.ConfigService.DriftForUser(userID)
+	if err != nil {
+		writeDeviceConfigError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, status)
+}
+
+// GetDevicesConfigDrift — GET /api/admin/devices/config-drift
+// Сводка дрейфа конфигурации по всем устройствам с заданной desired-конфигурацией.
+func (dcc *DeviceConfigController) GetDevicesConfigDrift(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return
+	}
+
+	items, err := dcc.ConfigService.DriftForAll(currentUser.OrganizationID)
+	if err != nil {
+		writeDeviceConfigError(ctx, err)
+		return
+	}
+	if scope := userScopeFromContext(ctx); !scope.All() {
+		visible := make([]service.DeviceConfigDriftStatus, 0, len(items))
+		for _, item := range items {
+			if scope.Allows(item.UserID) {
+				visible = append(visible, item)
+			}
+		}
+		items = visible
+	}
+	drifted := 0
+	for _, item := range items {
+		if item.Reported && !item.InSync {
+			drifted++
+		}
+	}
+	ctx.JSON(http.StatusOK, ConfigDriftSummaryResponse{
+		Devices: items,
+		Total:   len(items),
+		Drifted: drifted,
+	})
+}
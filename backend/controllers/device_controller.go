@@ -2,11 +2,13 @@ package controllers
 
 import (
 	"errors"
+	"fmt"
 	"locator/models"
 	"locator/service"
+	"log/slog"
 	"net/http"
-	"os"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,6 +20,11 @@ type DeviceController struct {
 	StatusService     *service.DeviceStatusService
 	RequestService    *service.LocationRequestService
 	ReleaseController *AppReleaseController
+	ConfigService     *service.DeviceConfigService
+	AlertService      *service.AlertService
+	Timezones         *service.TimezoneService
+	// BaseURL — внешний адрес API для config_update ("" — service.DefaultAPIBaseURL).
+	BaseURL string
 }
 
 func NewDeviceController(
@@ -26,6 +33,8 @@ func NewDeviceController(
 	statusService *service.DeviceStatusService,
 	requestService *service.LocationRequestService,
 	releaseController *AppReleaseController,
+	configService *service.DeviceConfigService,
+	alertService *service.AlertService,
 ) *DeviceController {
 	return &DeviceController{
 		CommandService:    commandService,
@@ -33,9 +42,32 @@ func NewDeviceController(
 		StatusService:     statusService,
 		RequestService:    requestService,
 		ReleaseController: releaseController,
+		ConfigService:     configService,
+		AlertService:      alertService,
 	}
 }
 
+func (dc *DeviceController) apiBaseURL() string {
+	if dc.BaseURL == "" {
+		return service.DefaultAPIBaseURL
+	}
+	return dc.BaseURL
+}
+
+// PolledCommand — команда, выданная устройству при опросе.
+type PolledCommand struct {
+	ID      string                 `json:"id"`
+	Type    string                 `json:"type"`
+	Payload map[string]interface{} `json:"payload"`
+}
+
+// DevicePollResponse — ответ GET /api/device/poll: команда либо, с ?json=1,
+// {"pending": false} вместо 204.
+type DevicePollResponse struct {
+	Command *PolledCommand `json:"command,omitempty"`
+	Pending *bool          `json:"pending,omitempty"`
+}
+
 // PollDevice — GET /api/device/poll
 func (dc *DeviceController) PollDevice(ctx *gin.Context) {
 	currentUser, ok := getCurrentUserFromContext(ctx)
@@ -43,6 +75,13 @@ func (dc *DeviceController) PollDevice(ctx *gin.Context) {
 		return
 	}
 
+	// Раскатка релизов и минимальная версия: app_update ставится в очередь до выдачи команды.
+	if dc.ReleaseController != nil && dc.ReleaseController.Releases != nil {
+		if _, err := dc.ReleaseController.Releases.CheckDevice(currentUser.ID, currentUser.OrganizationID, time.Now()); err != nil {
+			slog.ErrorContext(ctx.Request.Context(), "Ошибка проверки обновления", "user_id", currentUser.ID, "error", err)
+		}
+	}
+
 	cmd, err := dc.CommandService.Poll(currentUser.ID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка опроса команд"})
@@ -56,11 +95,11 @@ func (dc *DeviceController) PollDevice(ctx *gin.Context) {
 			return
 		}
 		if locReq != nil {
-			ctx.JSON(http.StatusOK, gin.H{
-				"command": gin.H{
-					"id":   locReq.ID,
-					"type": models.DeviceCommandTypeLocationRequest,
-					"payload": gin.H{
+			ctx.JSON(http.StatusOK, DevicePollResponse{
+				Command: &PolledCommand{
+					ID:   locReq.ID,
+					Type: models.DeviceCommandTypeLocationRequest,
+					Payload: map[string]interface{}{
 						"request_id": locReq.ID,
 					},
 				},
@@ -71,7 +110,7 @@ func (dc *DeviceController) PollDevice(ctx *gin.Context) {
 
 	if cmd == nil {
 		if ctx.Query("json") == "1" || ctx.Query("json") == "true" {
-			ctx.JSON(http.StatusOK, gin.H{"pending": false})
+			ctx.JSON(http.StatusOK, DevicePollResponse{Pending: new(bool)})
 			return
 		}
 		ctx.Status(http.StatusNoContent)
@@ -84,16 +123,26 @@ func (dc *DeviceController) PollDevice(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"command": gin.H{
-			"id":      cmd.ID,
-			"type":    cmd.Type,
-			"payload": payload,
+	ctx.JSON(http.StatusOK, DevicePollResponse{
+		Command: &PolledCommand{
+			ID:      cmd.ID,
+			Type:    cmd.Type,
+			Payload: payload,
 		},
 	})
 }
 
+// DeviceReportResponse — сохранённый отчёт; config_command_id — config_update,
+// поставленный из-за дрейфа конфигурации.
+type DeviceReportResponse struct {
+	ID              int       `json:"id"`
+	UserID          int       `json:"user_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	ConfigCommandID string    `json:"config_command_id,omitempty"`
+}
+
 // PostDeviceReport — POST /api/device/report
+// Тело — models.DeviceReportPayload; неизвестные ключи сохраняются как есть.
 func (dc *DeviceController) PostDeviceReport(ctx *gin.Context) {
 	currentUser, ok := getCurrentUserFromContext(ctx)
 	if !ok {
@@ -107,16 +156,48 @@ func (dc *DeviceController) PostDeviceReport(ctx *gin.Context) {
 	}
 
 	report, err := dc.ReportService.SaveReport(currentUser.ID, body)
+	if errors.Is(err, service.ErrDeviceReportInvalid) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело отчёта: " + err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить отчёт"})
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{
-		"id":         report.ID,
-		"user_id":    report.UserID,
-		"created_at": report.CreatedAt,
-	})
+	resp := DeviceReportResponse{
+		ID:        report.ID,
+		UserID:    report.UserID,
+		CreatedAt: report.CreatedAt,
+	}
+
+	// Reported config → сравнение с desired; дрейф уходит config_update только с расходящимися полями.
+	if reported, ok := body["config"].(map[string]interface{}); ok && dc.ConfigService != nil {
+		cmd, err := dc.ConfigService.ReconcileReported(currentUser.ID, reported)
+		if err != nil {
+			slog.ErrorContext(ctx.Request.Context(), "Ошибка сверки конфигурации", "user_id", currentUser.ID, "error", err)
+		} else if cmd != nil {
+			resp.ConfigCommandID = cmd.ID
+		}
+	}
+
+	// Новый отчёт может открыть или закрыть алерты (issues, батарея, версия).
+	dc.AlertService.Trigger()
+
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// CommandAckRequest — тело POST /api/device/command/ack.
+type CommandAckRequest struct {
+	CommandID string `json:"command_id" binding:"required"`
+	Status    string `json:"status" binding:"required"`
+	Message   string `json:"message"`
+}
+
+// CommandAckResponse — legacy=true: подтверждён старый location request, а не команда.
+type CommandAckResponse struct {
+	OK     bool `json:"ok"`
+	Legacy bool `json:"legacy,omitempty"`
 }
 
 // PostCommandAck — POST /api/device/command/ack
@@ -126,11 +207,7 @@ func (dc *DeviceController) PostCommandAck(ctx *gin.Context) {
 		return
 	}
 
-	var body struct {
-		CommandID string `json:"command_id" binding:"required"`
-		Status    string `json:"status" binding:"required"`
-		Message   string `json:"message"`
-	}
+	var body CommandAckRequest
 	if err := ctx.ShouldBindJSON(&body); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите command_id и status"})
 		return
@@ -140,7 +217,7 @@ func (dc *DeviceController) PostCommandAck(ctx *gin.Context) {
 		if errors.Is(err, service.ErrDeviceCommandNotFound) && dc.RequestService != nil &&
 			(body.Status == "ok" || body.Status == "success") {
 			if completeErr := dc.RequestService.Complete(body.CommandID, currentUser.ID); completeErr == nil {
-				ctx.JSON(http.StatusOK, gin.H{"ok": true, "legacy": true})
+				ctx.JSON(http.StatusOK, CommandAckResponse{OK: true, Legacy: true})
 				return
 			}
 		}
@@ -155,7 +232,19 @@ func (dc *DeviceController) PostCommandAck(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"ok": true})
+	ctx.JSON(http.StatusOK, CommandAckResponse{OK: true})
+}
+
+// UserHealthResponse — последний отчёт устройства и его проблемы.
+type UserHealthResponse struct {
+	UserID       int                    `json:"user_id"`
+	LastReportAt time.Time              `json:"last_report_at"`
+	AppVersion   string                 `json:"app_version"`
+	Platform     string                 `json:"platform"`
+	Issues       []string               `json:"issues"`
+	IssueCount   int                    `json:"issue_count"`
+	Healthy      bool                   `json:"healthy"`
+	Report       map[string]interface{} `json:"report"`
 }
 
 // GetUserHealth — GET /api/users/:id/health (admin)
@@ -188,18 +277,121 @@ func (dc *DeviceController) GetUserHealth(ctx *gin.Context) {
 	reportMap, _ := service.ReportAsMap(report)
 	issues, _ := service.IssuesSlice(report)
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"user_id":        userID,
-		"last_report_at": report.CreatedAt,
-		"app_version":    report.AppVersion,
-		"platform":       report.Platform,
-		"issues":         issues,
-		"issue_count":    len(issues),
-		"healthy":        len(issues) == 0,
-		"report":         reportMap,
+	ctx.JSON(http.StatusOK, UserHealthResponse{
+		UserID:       userID,
+		LastReportAt: report.CreatedAt,
+		AppVersion:   report.AppVersion,
+		Platform:     report.Platform,
+		Issues:       issues,
+		IssueCount:   len(issues),
+		Healthy:      len(issues) == 0,
+		Report:       reportMap,
 	})
 }
 
+// GetUserReports — GET /api/users/:id/reports?from=&to=&limit=&offset= (admin)
+// История диагностических отчётов; без from/to — последние 7 суток.
+func (dc *DeviceController) GetUserReports(ctx *gin.Context) {
+	userID, ok := dc.adminReportUserID(ctx)
+	if !ok {
+		return
+	}
+
+	loc, ok := requestLocation(ctx, dc.Timezones, userID)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+	offset, _ := strconv.Atoi(ctx.Query("offset"))
+	page, err := dc.ReportService.ListReports(userID, ctx.Query("from"), ctx.Query("to"), loc, limit, offset)
+	if err != nil {
+		writeDeviceReportError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, page)
+}
+
+// IssueTimelineResponse — эпизоды проблем устройства.
+type IssueTimelineResponse struct {
+	UserID   int                          `json:"user_id"`
+	Episodes []service.DeviceIssueEpisode `json:"episodes"`
+}
+
+// ReportTrendsResponse — показатели отчётов по времени.
+type ReportTrendsResponse struct {
+	UserID int                              `json:"user_id"`
+	Points []service.DeviceReportTrendPoint `json:"points"`
+}
+
+// GetUserIssueTimeline — GET /api/users/:id/reports/issues-timeline?from=&to= (admin)
+// Когда каждая проблема появилась и когда ушла (например, «каждый вечер стоп трекинга»).
+func (dc *DeviceController) GetUserIssueTimeline(ctx *gin.Context) {
+	userID, ok := dc.adminReportUserID(ctx)
+	if !ok {
+		return
+	}
+
+	loc, ok := requestLocation(ctx, dc.Timezones, userID)
+	if !ok {
+		return
+	}
+	episodes, err := dc.ReportService.IssueTimeline(userID, ctx.Query("from"), ctx.Query("to"), loc)
+	if err != nil {
+		writeDeviceReportError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, IssueTimelineResponse{UserID: userID, Episodes: episodes})
+}
+
+// GetUserReportTrends — GET /api/users/:id/reports/trends?from=&to= (admin)
+func (dc *DeviceController) GetUserReportTrends(ctx *gin.Context) {
+	userID, ok := dc.adminReportUserID(ctx)
+	if !ok {
+		return
+	}
+
+	loc, ok := requestLocation(ctx, dc.Timezones, userID)
+	if !ok {
+		return
+	}
+	points, err := dc.ReportService.Trends(userID, ctx.Query("from"), ctx.Query("to"), loc)
+	if err != nil {
+		writeDeviceReportError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, ReportTrendsResponse{UserID: userID, Points: points})
+}
+
+func (dc *DeviceController) adminReportUserID(ctx *gin.Context) (int, bool) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	if !currentUser.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Требуются права администратора"})
+		return 0, false
+	}
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return 0, false
+	}
+	return userID, true
+}
+
+func writeDeviceReportError(ctx *gin.Context, err error) {
+	if errors.Is(err, service.ErrDeviceReportRangeInvalid) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Интервал: %v", err)})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения отчётов"})
+}
+
+// DevicesStatusResponse — сводка по пользователям; ключ — ID пользователя.
+type DevicesStatusResponse struct {
+	Users map[string]service.UserDeviceStatusSummary `json:"users"`
+}
+
 // GetAdminDevicesStatus — GET /api/admin/devices/status
 // Пакетная сводка GPS + health для всех пользователей (вместо N×2 запросов из админки).
 func (dc *DeviceController) GetAdminDevicesStatus(ctx *gin.Context) {
@@ -216,17 +408,28 @@ func (dc *DeviceController) GetAdminDevicesStatus(ctx *gin.Context) {
 		return
 	}
 
-	summary, err := dc.StatusService.AllUsersSummary()
+	summary, err := dc.StatusService.AllUsersSummary(currentUser.OrganizationID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения статусов"})
 		return
 	}
 
+	scope := userScopeFromContext(ctx)
 	users := make(map[string]service.UserDeviceStatusSummary, len(summary))
 	for id, s := range summary {
+		if !scope.Allows(id) {
+			continue
+		}
 		users[strconv.Itoa(id)] = s
 	}
-	ctx.JSON(http.StatusOK, gin.H{"users": users})
+	ctx.JSON(http.StatusOK, DevicesStatusResponse{Users: users})
+}
+
+// DeviceWakeResponse — config_update пробуждения или включения GPS в очереди.
+type DeviceWakeResponse struct {
+	UserID          int    `json:"user_id"`
+	ConfigCommandID string `json:"config_command_id"`
+	Note            string `json:"note"`
 }
 
 // PostAdminWakeDevice — POST /api/admin/users/:id/wake
@@ -249,10 +452,7 @@ func (dc *DeviceController) PostAdminWakeDevice(ctx *gin.Context) {
 
 	wake := true
 	enableLoc := true
-	apiBase := os.Getenv("BASE_URL")
-	if apiBase == "" {
-		apiBase = "http://87.232.65.52:8080"
-	}
+	apiBase := dc.apiBaseURL()
 	payload, err := service.BuildConfigUpdatePayload(userID, service.DeviceConfigUpdateInput{
 		WakeDevice:     &wake,
 		EnableLocation: &enableLoc,
@@ -269,10 +469,10 @@ func (dc *DeviceController) PostAdminWakeDevice(ctx *gin.Context) {
 		return
 	}
 
-	resp := gin.H{
-		"user_id":           userID,
-		"config_command_id": configCmd.ID,
-		"note":              "Команда пробуждения в очереди. Сработает при следующем опросе телефона (~15 с).",
+	resp := DeviceWakeResponse{
+		UserID:          userID,
+		ConfigCommandID: configCmd.ID,
+		Note:            "Команда пробуждения в очереди. Сработает при следующем опросе телефона (~15 с).",
 	}
 	ctx.JSON(http.StatusAccepted, resp)
 }
@@ -297,10 +497,7 @@ func (dc *DeviceController) PostAdminEnableLocation(ctx *gin.Context) {
 
 	enableLoc := true
 	wake := true
-	apiBase := os.Getenv("BASE_URL")
-	if apiBase == "" {
-		apiBase = "http://87.232.65.52:8080"
-	}
+	apiBase := dc.apiBaseURL()
 	payload, err := service.BuildConfigUpdatePayload(userID, service.DeviceConfigUpdateInput{
 		EnableLocation: &enableLoc,
 		WakeDevice:     &wake,
@@ -317,14 +514,29 @@ func (dc *DeviceController) PostAdminEnableLocation(ctx *gin.Context) {
 		return
 	}
 
-	resp := gin.H{
-		"user_id":           userID,
-		"config_command_id": configCmd.ID,
-		"note":              "Команда включения GPS в очереди. Телефон получит её при следующем опросе (~15 с).",
+	resp := DeviceWakeResponse{
+		UserID:          userID,
+		ConfigCommandID: configCmd.ID,
+		Note:            "Команда включения GPS в очереди. Телефон получит её при следующем опросе (~15 с).",
 	}
 	ctx.JSON(http.StatusAccepted, resp)
 }
 
+// UserCommandRequest — тело POST /api/admin/users/:id/commands.
+type UserCommandRequest struct {
+	Type    string                 `json:"type" binding:"required"`
+	Payload map[string]interface{} `json:"payload"`
+}
+
+// DeviceCommandResponse — команда, поставленная в очередь устройства.
+type DeviceCommandResponse struct {
+	CommandID string                 `json:"command_id"`
+	Type      string                 `json:"type"`
+	Status    string                 `json:"status,omitempty"`
+	UserID    int                    `json:"user_id"`
+	Payload   map[string]interface{} `json:"payload"`
+}
+
 // PostAdminUserCommand — POST /api/admin/users/:id/commands
 func (dc *DeviceController) PostAdminUserCommand(ctx *gin.Context) {
 	currentUser, ok := getCurrentUserFromContext(ctx)
@@ -342,10 +554,7 @@ func (dc *DeviceController) PostAdminUserCommand(ctx *gin.Context) {
 		return
 	}
 
-	var body struct {
-		Type    string                 `json:"type" binding:"required"`
-		Payload map[string]interface{} `json:"payload"`
-	}
+	var body UserCommandRequest
 	if err := ctx.ShouldBindJSON(&body); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите type"})
 		return
@@ -362,15 +571,36 @@ func (dc *DeviceController) PostAdminUserCommand(ctx *gin.Context) {
 	}
 
 	payload, _ := service.CommandPayloadMap(cmd)
-	ctx.JSON(http.StatusAccepted, gin.H{
-		"command_id": cmd.ID,
-		"type":       cmd.Type,
-		"status":     cmd.Status,
-		"user_id":    cmd.UserID,
-		"payload":    payload,
+	ctx.JSON(http.StatusAccepted, DeviceCommandResponse{
+		CommandID: cmd.ID,
+		Type:      cmd.Type,
+		Status:    cmd.Status,
+		UserID:    cmd.UserID,
+		Payload:   payload,
 	})
 }
 
+// GetAdminUserCommand — GET /api/admin/users/:id/commands/:command_id
+// Статус команды: pending → delivered → acked / failed / expired, ack_status и ack_message телефона.
+func (dc *DeviceController) GetAdminUserCommand(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	cmd, err := dc.CommandService.GetCommand(userID, ctx.Param("command_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrDeviceCommandNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Команда не найдена"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить команду"})
+		return
+	}
+	ctx.JSON(http.StatusOK, cmd)
+}
+
 // PostAdminUserDeviceConfig — POST /api/admin/users/:id/device/config
 // Валидированный config_update (ключ, интервалы, PIN, пауза трекинга, скрытие из лаунчера).
 func (dc *DeviceController) PostAdminUserDeviceConfig(ctx *gin.Context) {
@@ -415,17 +645,17 @@ func (dc *DeviceController) PostAdminUserDeviceConfig(ctx *gin.Context) {
 	}
 
 	cmdPayload, _ := service.CommandPayloadMap(cmd)
-	ctx.JSON(http.StatusAccepted, gin.H{
-		"command_id": cmd.ID,
-		"type":       cmd.Type,
-		"status":     cmd.Status,
-		"user_id":    cmd.UserID,
-		"payload":    cmdPayload,
+	ctx.JSON(http.StatusAccepted, DeviceCommandResponse{
+		CommandID: cmd.ID,
+		Type:      cmd.Type,
+		Status:    cmd.Status,
+		UserID:    cmd.UserID,
+		Payload:   cmdPayload,
 	})
 }
 
-// PostPublishAppUpdate — POST /api/admin/releases/publish-update/:user_id
-// Команда app_update из manifest.json на устройство.
+// PostPublishAppUpdate — POST /api/admin/releases/publish-update/:user_id[?release_id=]
+// Команда app_update из manifest.json (или релиза из БД по release_id) на устройство.
 func (dc *DeviceController) PostPublishAppUpdate(ctx *gin.Context) {
 	currentUser, ok := getCurrentUserFromContext(ctx)
 	if !ok {
@@ -446,10 +676,25 @@ func (dc *DeviceController) PostPublishAppUpdate(ctx *gin.Context) {
 		return
 	}
 
-	payload, err := dc.ReleaseController.ManifestForAppUpdate()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Манифест релиза недоступен"})
-		return
+	var payload map[string]interface{}
+	if raw := ctx.Query("release_id"); raw != "" && dc.ReleaseController.Releases != nil {
+		releaseID, err := strconv.Atoi(raw)
+		if err != nil || releaseID <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный release_id"})
+			return
+		}
+		release, err := dc.ReleaseController.Releases.GetRelease(currentUser.OrganizationID, releaseID)
+		if err != nil {
+			writeAppReleaseError(ctx, err)
+			return
+		}
+		payload = dc.ReleaseController.Releases.AppUpdatePayload(release, false)
+	} else {
+		payload, err = dc.ReleaseController.ManifestForAppUpdate()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Манифест релиза недоступен"})
+			return
+		}
 	}
 
 	cmd, err := dc.CommandService.EnqueueCommand(userID, models.DeviceCommandTypeAppUpdate, payload)
@@ -458,10 +703,10 @@ func (dc *DeviceController) PostPublishAppUpdate(ctx *gin.Context) {
 		return
 	}
 	cmdPayload, _ := service.CommandPayloadMap(cmd)
-	ctx.JSON(http.StatusAccepted, gin.H{
-		"command_id": cmd.ID,
-		"type":       cmd.Type,
-		"user_id":    userID,
-		"payload":    cmdPayload,
+	ctx.JSON(http.StatusAccepted, DeviceCommandResponse{
+		CommandID: cmd.ID,
+		Type:      cmd.Type,
+		UserID:    userID,
+		Payload:   cmdPayload,
 	})
 }
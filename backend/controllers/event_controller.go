@@ -33,14 +33,19 @@ func (ec *EventController) PublishEvent(c *gin.Context) {
 		return
 	}
 
+	// Событие относится к организации отправителя — чекпоинты других организаций не затрагиваются.
+	if currentUser, ok := getCurrentUserFromContext(c); ok {
+		event.OrganizationID = currentUser.OrganizationID
+	}
+
 	// При необходимости можно обновить время события:
 	// event.OccurredAt = time.Now()
 
 	// Публикуем событие в RabbitMQ через Publisher.
-	if err := ec.Publisher.PublishJSON(event); err != nil {
+	if err := ec.Publisher.PublishJSONContext(c.Request.Context(), event); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка публикации события"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Событие успешно опубликовано"})
+	c.JSON(http.StatusOK, MessageResponse{Message: "Событие успешно опубликовано"})
 }
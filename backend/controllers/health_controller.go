@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthCheckTimeout — сколько ждать одну проверку готовности.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthCheck проверяет зависимость для /readyz; nil — в порядке.
+type HealthCheck func(ctx context.Context) error
+
+// ReadinessResponse — ответ /readyz: общий статус (ok, unavailable, draining) и
+// результат каждой проверки ("ok" или "unavailable"). /readyz открыт без
+// аутентификации, поэтому текст ошибки (хосты, порты, пользователь БД) только в журнале.
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// HealthController — пробы процесса: /livez (процесс жив) и /readyz (готов принимать
+// запросы: БД, брокер и миграции в порядке, остановка не начата).
+type HealthController struct {
+	Checks   map[string]HealthCheck
+	draining atomic.Bool
+}
+
+func NewHealthController(checks map[string]HealthCheck) *HealthController {
+	return &HealthController{Checks: checks}
+}
+
+// SetDraining переводит /readyz в 503: балансировщик перестаёт слать новые запросы,
+// пока текущие дорабатывают.
+func (hc *HealthController) SetDraining() {
+	hc.draining.Store(true)
+}
+
+// Livez отвечает 200, пока процесс обслуживает HTTP; зависимости не проверяются,
+// чтобы сбой БД не приводил к перезапуску контейнера.
+func (hc *HealthController) Livez(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, StatusResponse{Status: "ok"})
+}
+
+// Readyz выполняет проверки параллельно; ответ — статус каждой и общий код 200 или 503.
+func (hc *HealthController) Readyz(ctx *gin.Context) {
+	if hc.draining.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, ReadinessResponse{Status: "draining"})
+		return
+	}
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(hc.Checks))
+	for name, check := range hc.Checks {
+		go func() {
+			checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), healthCheckTimeout)
+			defer cancel()
+			results <- result{name, check(checkCtx)}
+		}()
+	}
+
+	status, code := "ok", http.StatusOK
+	checks := make(map[string]string, len(hc.Checks))
+	for range hc.Checks {
+		r := <-results
+		checks[r.name] = "ok"
+		if r.err != nil {
+			slog.WarnContext(ctx.Request.Context(), "Проверка готовности не прошла", "check", r.name, "error", r.err)
+			checks[r.name] = "unavailable"
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+	ctx.JSON(code, ReadinessResponse{Status: status, Checks: checks})
+}
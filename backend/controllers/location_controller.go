@@ -1,11 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"locator/config/messaging"
 	"locator/models"
 	"locator/service"
-	"log"
+	"log/slog"
 	"net/http"
 	"sort"
 	"strconv"
@@ -31,8 +32,8 @@ func getCurrentUserFromContext(ctx *gin.Context) (*models.User, bool) {
 	return currentUser, true
 }
 
-// locationSnapshot — последняя известная позиция пользователя (для GET /api/location/single|current).
-type locationSnapshot struct {
+// LocationSnapshot — последняя известная позиция пользователя (для GET /api/location/single|current).
+type LocationSnapshot struct {
 	ID         int        `json:"id"`
 	UserID     int        `json:"user_id"`
 	Latitude   float64    `json:"latitude"`
@@ -43,13 +44,48 @@ type locationSnapshot struct {
 	AgeSeconds int64      `json:"age_seconds"`
 }
 
-func newLocationSnapshot(loc *models.Location) locationSnapshot {
+// StaleLocationResponse — 404 при max_age_seconds: последняя точка старше лимита.
+type StaleLocationResponse struct {
+	Error         string           `json:"error"`
+	AgeSeconds    int64            `json:"age_seconds"`
+	MaxAgeSeconds int64            `json:"max_age_seconds"`
+	Location      LocationSnapshot `json:"location"`
+}
+
+// LocationUploadRequest — тело POST /api/location. captured_at (RFC3339) или timestamp
+// (мс) — время фиксации на телефоне; request_id — ответ на on-demand запрос.
+type LocationUploadRequest struct {
+	UserID     int     `json:"user_id"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	RequestID  string  `json:"request_id"`
+	Source     string  `json:"source"`
+	CapturedAt string  `json:"captured_at"`
+	Timestamp  int64   `json:"timestamp"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// LocationUploadResponse — сохранённая точка либо {"skipped": true, "reason": ...},
+// если точка отброшена фильтром качества.
+type LocationUploadResponse struct {
+	*models.Location
+	Skipped bool   `json:"skipped,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// MatchedRouteResponse — линия, привязанная к дорогам; segments — участки между разрывами.
+type MatchedRouteResponse struct {
+	Coordinates [][]float64   `json:"coordinates"`
+	Segments    [][][]float64 `json:"segments,omitempty"`
+}
+
+func newLocationSnapshot(loc *models.Location) LocationSnapshot {
 	effective := loc.EffectiveAt()
 	age := int64(time.Since(effective).Seconds())
 	if age < 0 {
 		age = 0
 	}
-	return locationSnapshot{
+	return LocationSnapshot{
 		ID:         loc.ID,
 		UserID:     loc.UserID,
 		Latitude:   loc.Latitude,
@@ -69,6 +105,7 @@ type LocationController struct {
 	Publisher       *messaging.Publisher
 	RoutingBaseURL  string // OSRM/совместимый инстанс, без завершающего /; пусто — эндпоинт match недоступен
 	HTTPRouting     *http.Client
+	Timezones       *service.TimezoneService // пояс для from/to и времени в ответах (nil — пояс по умолчанию)
 }
 
 // NewLocationController создаёт новый экземпляр контроллера для работы с локациями.
@@ -108,8 +145,8 @@ func (lc *LocationController) GetLocation(ctx *gin.Context) {
 		requestedUserID = userID
 	}
 
-	if !currentUser.IsAdmin && requestedUserID != currentUser.ID {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав для просмотра чужой локации"})
+	if !canAccessUser(ctx, currentUser, requestedUserID) {
+		denyUserAccess(ctx, currentUser, requestedUserID, "Недостаточно прав для просмотра чужой локации")
 		return
 	}
 
@@ -127,11 +164,11 @@ func (lc *LocationController) GetLocation(ctx *gin.Context) {
 			return
 		}
 		if snapshot.AgeSeconds > maxAge {
-			ctx.JSON(http.StatusNotFound, gin.H{
-				"error":           "Локация устарела",
-				"age_seconds":     snapshot.AgeSeconds,
-				"max_age_seconds": maxAge,
-				"location":        snapshot,
+			ctx.JSON(http.StatusNotFound, StaleLocationResponse{
+				Error:         "Локация устарела",
+				AgeSeconds:    snapshot.AgeSeconds,
+				MaxAgeSeconds: maxAge,
+				Location:      snapshot,
 			})
 			return
 		}
@@ -147,16 +184,7 @@ func (lc *LocationController) PostLocation(ctx *gin.Context) {
 		return
 	}
 
-	var req struct {
-		UserID     int     `json:"user_id"`
-		Latitude   float64 `json:"latitude"`
-		Longitude  float64 `json:"longitude"`
-		RequestID  string  `json:"request_id"`
-		Source     string  `json:"source"`
-		CapturedAt string  `json:"captured_at"`
-		Timestamp  int64   `json:"timestamp"`
-		Accuracy   float64 `json:"accuracy"`
-	}
+	var req LocationUploadRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
 		return
@@ -167,8 +195,9 @@ func (lc *LocationController) PostLocation(ctx *gin.Context) {
 		targetUserID = currentUser.ID
 	}
 
-	if !currentUser.IsAdmin && targetUserID != currentUser.ID {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав для обновления чужой локации"})
+	// Чужие точки пишет только служебный оператор (импорт, отладка); устройство — свои.
+	if !canWriteUser(ctx, currentUser, targetUserID, models.PermSystem) {
+		denyUserAccess(ctx, currentUser, targetUserID, "Недостаточно прав для обновления чужой локации")
 		return
 	}
 
@@ -176,7 +205,7 @@ func (lc *LocationController) PostLocation(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки пользователя"})
 		return
 	} else if !exists {
-		log.Printf("[PostLocation] user_id=%d не найден, используем текущего user_id=%d", targetUserID, currentUser.ID)
+		slog.WarnContext(ctx.Request.Context(), "Пользователь точки не найден, используется текущий", "target_user_id", targetUserID, "user_id", currentUser.ID)
 		targetUserID = currentUser.ID
 	}
 
@@ -198,7 +227,7 @@ func (lc *LocationController) PostLocation(ctx *gin.Context) {
 		}
 	}
 
-	capturedAt, err := lc.Service.ResolveCapturedAt(req.CapturedAt, req.Timestamp)
+	capturedAt, err := lc.Service.ResolveCapturedAt(targetUserID, req.CapturedAt, req.Timestamp)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -211,28 +240,40 @@ func (lc *LocationController) PostLocation(ctx *gin.Context) {
 
 	// Создаём новую запись о локации вместо обновления существующей.
 	location, skipReason, err := lc.Service.CreateLocation(
-		targetUserID, req.Latitude, req.Longitude, requestID, source, capturedAt, accuracy,
+		ctx.Request.Context(), targetUserID, req.Latitude, req.Longitude, requestID, source, capturedAt, accuracy,
 	)
+	if errors.Is(err, service.ErrTrackingCheckFailed) {
+		ctx.Header("Retry-After", "30")
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Окно отслеживания не проверено, повторите отправку"})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания записи"})
 		return
 	}
 	if skipReason != "" {
-		ctx.JSON(http.StatusOK, gin.H{"skipped": true, "reason": skipReason})
+		ctx.JSON(http.StatusOK, LocationUploadResponse{Skipped: true, Reason: skipReason})
+		return
+	}
+	if location.Masked {
+		// Вне окна отслеживания: точка только подтверждает связь — без визитов и ответа на запрос.
+		ctx.JSON(http.StatusOK, LocationUploadResponse{Location: location})
 		return
 	}
 
 	// Сначала публикуем событие для визитов — даже если завершение request_id не удалось.
 	event := models.LocationEvent{
-		UserID:     targetUserID,
-		Latitude:   req.Latitude,
-		Longitude:  req.Longitude,
-		OccurredAt: location.EffectiveAt(),
-		Source:     source,
+		UserID:         targetUserID,
+		OrganizationID: currentUser.OrganizationID,
+		Latitude:       req.Latitude,
+		Longitude:      req.Longitude,
+		OccurredAt:     location.EffectiveAt(),
+		Source:         source,
+		LocationID:     location.ID,
 	}
 	if lc.Publisher != nil {
-		if err := lc.Publisher.PublishJSON(event); err != nil {
-			log.Printf("[PostLocation] Ошибка публикации события userID=%d: %v", targetUserID, err)
+		if err := lc.Publisher.PublishJSONContext(ctx.Request.Context(), event); err != nil {
+			slog.ErrorContext(ctx.Request.Context(), "Ошибка публикации события", "user_id", targetUserID, "error", err)
 		}
 	}
 
@@ -244,16 +285,17 @@ func (lc *LocationController) PostLocation(ctx *gin.Context) {
 			completeErr = lc.RequestService.Complete(requestID, targetUserID)
 		}
 		if completeErr != nil {
-			log.Printf("[PostLocation] request_id=%s не завершён (локация сохранена): %v", requestID, completeErr)
+			slog.ErrorContext(ctx.Request.Context(), "Запрос местоположения не завершён (локация сохранена)", "request", requestID, "error", completeErr)
 		}
 	}
 
-	ctx.JSON(http.StatusOK, location)
+	ctx.JSON(http.StatusOK, LocationUploadResponse{Location: location})
 }
 
 // GetLocations обрабатывает GET-запрос для получения локаций.
 // Query raw=true|1 — все точки из БД без фильтра «значимых» (по умолчанию — значимые, как раньше).
-// Параметры from и to — интервал времени (RFC3339 или YYYY-MM-DDTHH:mm в Europe/Minsk).
+// Параметры from и to — интервал времени (RFC3339 или YYYY-MM-DDTHH:mm в поясе пользователя;
+// ?tz= — явный IANA-пояс). Времена в ответе — со смещением этого пояса.
 func (lc *LocationController) GetLocations(ctx *gin.Context) {
 	from := ctx.Query("from")
 	to := ctx.Query("to")
@@ -264,10 +306,14 @@ func (lc *LocationController) GetLocations(ctx *gin.Context) {
 	var err error
 
 	if from != "" && to != "" {
+		loc, ok := requestLocation(ctx, lc.Timezones, 0)
+		if !ok {
+			return
+		}
 		if useRaw {
-			locations, err = lc.Service.GetLocationsBetweenRaw(from, to)
+			locations, err = lc.Service.GetLocationsBetweenRaw(from, to, loc)
 		} else {
-			locations, err = lc.Service.GetLocationsBetween(from, to)
+			locations, err = lc.Service.GetLocationsBetween(from, to, loc)
 		}
 		if err != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Ошибка фильтрации по интервалу: %v", err)})
@@ -284,6 +330,15 @@ func (lc *LocationController) GetLocations(ctx *gin.Context) {
 			return
 		}
 	}
+	if scope := userScopeFromContext(ctx); !scope.All() {
+		visible := make([]models.Location, 0, len(locations))
+		for _, l := range locations {
+			if scope.Allows(l.UserID) {
+				visible = append(visible, l)
+			}
+		}
+		locations = visible
+	}
 	ctx.JSON(http.StatusOK, locations)
 }
 
@@ -311,12 +366,16 @@ func (lc *LocationController) GetMatchedRoute(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "user_id должен быть числом"})
 		return
 	}
-	if !currentUser.IsAdmin && userID != currentUser.ID {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+	if !canAccessUser(ctx, currentUser, userID) {
+		denyUserAccess(ctx, currentUser, userID, "Недостаточно прав")
 		return
 	}
 
-	all, err := lc.Service.GetLocationsBetweenRaw(from, to)
+	loc, ok := requestLocation(ctx, lc.Timezones, userID)
+	if !ok {
+		return
+	}
+	all, err := lc.Service.GetLocationsBetweenRaw(from, to, loc)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Интервал: %v", err)})
 		return
@@ -332,7 +391,7 @@ func (lc *LocationController) GetMatchedRoute(ctx *gin.Context) {
 		return forUser[i].EffectiveAt().Before(forUser[j].EffectiveAt())
 	})
 	if len(forUser) < 2 {
-		ctx.JSON(http.StatusOK, gin.H{"coordinates": [][]float64{}})
+		ctx.JSON(http.StatusOK, MatchedRouteResponse{Coordinates: [][]float64{}})
 		return
 	}
 
@@ -341,9 +400,9 @@ func (lc *LocationController) GetMatchedRoute(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"coordinates": coords,
-		"segments":    segments,
+	ctx.JSON(http.StatusOK, MatchedRouteResponse{
+		Coordinates: coords,
+		Segments:    segments,
 	})
 }
 
@@ -5,6 +5,7 @@ import (
 	"locator/models"
 	"locator/service"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,6 +23,35 @@ func NewLocationRequestController(requestService *service.LocationRequestService
 	}
 }
 
+// OnDemandLocationRequest — тело POST /api/location/request.
+type OnDemandLocationRequest struct {
+	UserID int `json:"user_id" binding:"required"`
+}
+
+// LocationRequestQueuedResponse — запрос принят; command_id — команда location_request
+// ("" — legacy-очередь без команд).
+type LocationRequestQueuedResponse struct {
+	RequestID string `json:"request_id"`
+	CommandID string `json:"command_id"`
+	Status    string `json:"status"`
+	UserID    int    `json:"user_id"`
+}
+
+// LocationRequestPollResponse — ответ legacy-опроса GET /api/location/request.
+type LocationRequestPollResponse struct {
+	RequestID string `json:"request_id,omitempty"`
+	Pending   bool   `json:"pending"`
+}
+
+// LocationRequestStatusResponse — состояние on-demand запроса.
+type LocationRequestStatusResponse struct {
+	RequestID   string     `json:"request_id"`
+	UserID      int        `json:"user_id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
 // PostLocationRequest создаёт pending-запрос для user_id (admin).
 // POST /api/location/request  {"user_id": 5}
 func (rc *LocationRequestController) PostLocationRequest(ctx *gin.Context) {
@@ -34,13 +64,15 @@ func (rc *LocationRequestController) PostLocationRequest(ctx *gin.Context) {
 		return
 	}
 
-	var body struct {
-		UserID int `json:"user_id" binding:"required"`
-	}
+	var body OnDemandLocationRequest
 	if err := ctx.ShouldBindJSON(&body); err != nil || body.UserID <= 0 {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите user_id"})
 		return
 	}
+	if !canAccessUser(ctx, currentUser, body.UserID) {
+		denyUserAccess(ctx, currentUser, body.UserID, "Нет доступа к пользователю")
+		return
+	}
 
 	var cmdID string
 	if rc.CommandService != nil {
@@ -51,11 +83,12 @@ func (rc *LocationRequestController) PostLocationRequest(ctx *gin.Context) {
 		}
 		cmdID = cmd.ID
 		payload, _ := service.CommandPayloadMap(cmd)
-		ctx.JSON(http.StatusAccepted, gin.H{
-			"request_id": payload["request_id"],
-			"command_id": cmd.ID,
-			"status":     cmd.Status,
-			"user_id":    body.UserID,
+		requestID, _ := payload["request_id"].(string)
+		ctx.JSON(http.StatusAccepted, LocationRequestQueuedResponse{
+			RequestID: requestID,
+			CommandID: cmd.ID,
+			Status:    cmd.Status,
+			UserID:    body.UserID,
 		})
 		return
 	}
@@ -66,11 +99,11 @@ func (rc *LocationRequestController) PostLocationRequest(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusAccepted, gin.H{
-		"request_id": req.ID,
-		"command_id": cmdID,
-		"status":     req.Status,
-		"user_id":    req.UserID,
+	ctx.JSON(http.StatusAccepted, LocationRequestQueuedResponse{
+		RequestID: req.ID,
+		CommandID: cmdID,
+		Status:    req.Status,
+		UserID:    req.UserID,
 	})
 }
 
@@ -89,16 +122,16 @@ func (rc *LocationRequestController) PollLocationRequest(ctx *gin.Context) {
 	}
 	if req == nil {
 		if ctx.Query("json") == "1" || ctx.Query("json") == "true" {
-			ctx.JSON(http.StatusOK, gin.H{"pending": false})
+			ctx.JSON(http.StatusOK, LocationRequestPollResponse{})
 			return
 		}
 		ctx.Status(http.StatusNoContent)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"request_id": req.ID,
-		"pending":    true,
+	ctx.JSON(http.StatusOK, LocationRequestPollResponse{
+		RequestID: req.ID,
+		Pending:   true,
 	})
 }
 
@@ -129,12 +162,16 @@ func (rc *LocationRequestController) GetLocationRequestStatus(ctx *gin.Context)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения запроса"})
 		return
 	}
+	if !canAccessUser(ctx, currentUser, req.UserID) {
+		denyUserAccess(ctx, currentUser, req.UserID, "Нет доступа к пользователю")
+		return
+	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"request_id":   req.ID,
-		"user_id":      req.UserID,
-		"status":       req.Status,
-		"created_at":   req.CreatedAt,
-		"completed_at": req.CompletedAt,
+	ctx.JSON(http.StatusOK, LocationRequestStatusResponse{
+		RequestID:   req.ID,
+		UserID:      req.UserID,
+		Status:      req.Status,
+		CreatedAt:   req.CreatedAt,
+		CompletedAt: req.CompletedAt,
 	})
 }
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"locator/models"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationController — подписки на уведомления (свои; с правом alerts.manage —
+// пользователей своей области видимости) и журнал доставки.
+type NotificationController struct {
+	NotificationService *service.NotificationService
+}
+
+func NewNotificationController(notificationService *service.NotificationService) *NotificationController {
+	return &NotificationController{NotificationService: notificationService}
+}
+
+func writeNotificationError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotificationSubscriptionNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Подписка не найдена"})
+	case errors.Is(err, service.ErrNotificationForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+	case errors.Is(err, service.ErrNotificationSubscriptionInvalid):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Некорректная подписка: %v", err)})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка уведомлений"})
+	}
+}
+
+// webhookAllowed — вебхук (запрос сервера на произвольный URL) настраивает только
+// сотрудник, вошедший по сессии; устройству по API-ключу он недоступен.
+func webhookAllowed(ctx *gin.Context, currentUser *models.User, channel string) bool {
+	return channel != models.NotificationChannelWebhook ||
+		(currentUser.IsStaff() && ctx.GetString("session_id") != "")
+}
+
+// GetSubscriptions — GET /api/notifications/subscriptions[?user_id=] (user_id — с правом alerts.manage)
+func (nc *NotificationController) GetSubscriptions(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	userID := currentUser.ID
+	if currentUser.HasPermission(models.PermAlertsManage) {
+		userID, _ = strconv.Atoi(ctx.Query("user_id"))
+	}
+	subs, err := nc.NotificationService.ListSubscriptions(currentUser, userScopeFromContext(ctx), userID)
+	if err != nil {
+		writeNotificationError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, subs)
+}
+
+// PostSubscription — POST /api/notifications/subscriptions
+func (nc *NotificationController) PostSubscription(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	var body service.NotificationSubscriptionInput
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+	if !webhookAllowed(ctx, currentUser, body.Channel) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Вебхуки настраивает только сотрудник после входа"})
+		return
+	}
+	sub, err := nc.NotificationService.CreateSubscription(currentUser, userScopeFromContext(ctx), body)
+	if err != nil {
+		writeNotificationError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, sub)
+}
+
+// PutSubscription — PUT /api/notifications/subscriptions/:id
+func (nc *NotificationController) PutSubscription(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID подписки"})
+		return
+	}
+	var body service.NotificationSubscriptionInput
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+	if !webhookAllowed(ctx, currentUser, body.Channel) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Вебхуки настраивает только сотрудник после входа"})
+		return
+	}
+	sub, err := nc.NotificationService.UpdateSubscription(id, currentUser, userScopeFromContext(ctx), body)
+	if err != nil {
+		writeNotificationError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, sub)
+}
+
+// DeleteSubscription — DELETE /api/notifications/subscriptions/:id
+func (nc *NotificationController) DeleteSubscription(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID подписки"})
+		return
+	}
+	if err := nc.NotificationService.DeleteSubscription(id, currentUser, userScopeFromContext(ctx)); err != nil {
+		writeNotificationError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, OKResponse{OK: true})
+}
+
+// PostTestSubscription — POST /api/notifications/subscriptions/:id/test
+// Тестовое сообщение в канал подписки (без тихих часов и лимита частоты).
+func (nc *NotificationController) PostTestSubscription(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID подписки"})
+		return
+	}
+	delivery, err := nc.NotificationService.SendTest(ctx.Request.Context(), id, currentUser, userScopeFromContext(ctx))
+	if err != nil {
+		writeNotificationError(ctx, err)
+		return
+	}
+	status := http.StatusOK
+	if delivery.Error != "" {
+		status = http.StatusBadGateway
+	}
+	ctx.JSON(status, delivery)
+}
+
+// GetDeliveries — GET /api/notifications/deliveries — последние попытки доставки.
+func (nc *NotificationController) GetDeliveries(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	items, err := nc.NotificationService.ListDeliveries(currentUser, userScopeFromContext(ctx))
+	if err != nil {
+		writeNotificationError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, items)
+}
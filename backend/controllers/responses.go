@@ -0,0 +1,26 @@
+package controllers
+
+// Общие тела ответов. Типы запросов и ответов отдельных маршрутов объявлены рядом
+// с обработчиками; по ним же собирается спецификация OpenAPI (router.Spec).
+
+// ErrorResponse — тело любого ответа об ошибке (gin.H{"error": ...}). Code —
+// машинно-читаемая причина отказа во входе (см. writeAuthError).
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+}
+
+// StatusResponse — {"status": "ok"} и подобные подтверждения.
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// OKResponse — {"ok": true}.
+type OKResponse struct {
+	OK bool `json:"ok"`
+}
+
+// MessageResponse — подтверждение с сообщением для человека.
+type MessageResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"locator/models"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RetentionController — сроки хранения точек: политики организаций и проходы
+// прореживания/удаления.
+type RetentionController struct {
+	Service *service.RetentionService
+}
+
+func NewRetentionController(retentionService *service.RetentionService) *RetentionController {
+	return &RetentionController{Service: retentionService}
+}
+
+// RetentionRunRequest — тело POST /api/admin/retention/runs; без organization_id —
+// по всем организациям.
+type RetentionRunRequest struct {
+	OrganizationID *int `json:"organization_id"`
+	DryRun         bool `json:"dry_run"`
+}
+
+// RetentionPolicyResponse — действующая политика; custom=false — значения по умолчанию.
+type RetentionPolicyResponse struct {
+	Policy *models.RetentionPolicy `json:"policy"`
+	Custom bool                    `json:"custom"`
+}
+
+// RetentionRunsResponse — последние проходы, новые первыми.
+type RetentionRunsResponse struct {
+	Runs []models.RetentionRun `json:"runs"`
+}
+
+// retentionOrganizationID — организация из ?organization_id= (по умолчанию — своя).
+func retentionOrganizationID(ctx *gin.Context, currentUser *models.User) (int, bool) {
+	raw := ctx.Query("organization_id")
+	if raw == "" {
+		return currentUser.OrganizationID, true
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "organization_id должен быть положительным числом"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeRetentionError(ctx *gin.Context, currentUser *models.User, err error) {
+	switch {
+	case errors.Is(err, service.ErrRetentionPolicyInvalid):
+		detail := strings.TrimPrefix(err.Error(), service.ErrRetentionPolicyInvalid.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректная политика хранения" + detail})
+	case errors.Is(err, service.ErrRetentionRunning):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Проход хранения уже выполняется"})
+	case errors.Is(err, service.ErrRetentionRunNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Проход хранения не найден"})
+	case errors.Is(err, service.ErrAccessDenied):
+		logAccessDenied(ctx, currentUser, "политика хранения чужой организации")
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Нет доступа к политике хранения этой организации"})
+	default:
+		slog.ErrorContext(ctx.Request.Context(), "Ошибка хранения точек", "method", ctx.Request.Method, "path", ctx.Request.URL.Path, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка хранения точек"})
+	}
+}
+
+// GetPolicy — GET /api/admin/retention/policy?organization_id=
+// Действующая политика и признак custom (false — значения по умолчанию).
+func (rc *RetentionController) GetPolicy(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	orgID, ok := retentionOrganizationID(ctx, currentUser)
+	if !ok {
+		return
+	}
+	policy, custom, err := rc.Service.ViewPolicy(currentUser, orgID)
+	if err != nil {
+		writeRetentionError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, RetentionPolicyResponse{Policy: policy, Custom: custom})
+}
+
+// PutPolicy — PUT /api/admin/retention/policy?organization_id=
+// {"raw_days":30,"downsample_minutes":5,"purge_days":365,"archive":true,"enabled":true}
+func (rc *RetentionController) PutPolicy(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	orgID, ok := retentionOrganizationID(ctx, currentUser)
+	if !ok {
+		return
+	}
+	var body service.RetentionPolicyInput
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные запроса"})
+		return
+	}
+	policy, err := rc.Service.SavePolicy(currentUser, orgID, body)
+	if err != nil {
+		writeRetentionError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, RetentionPolicyResponse{Policy: policy, Custom: true})
+}
+
+// DeletePolicy — DELETE /api/admin/retention/policy?organization_id=
+// Возвращает организации политику по умолчанию.
+func (rc *RetentionController) DeletePolicy(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	orgID, ok := retentionOrganizationID(ctx, currentUser)
+	if !ok {
+		return
+	}
+	if err := rc.Service.DeletePolicy(currentUser, orgID); err != nil {
+		writeRetentionError(ctx, currentUser, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// PostRun — POST /api/admin/retention/runs {"dry_run":true,"organization_id":2}
+// Запускает проход в фоне (без organization_id — по всем организациям); прогресс —
+// GET /api/admin/retention/runs/:id.
+func (rc *RetentionController) PostRun(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	var body RetentionRunRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные запроса"})
+			return
+		}
+	}
+	if body.OrganizationID != nil && *body.OrganizationID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "organization_id должен быть положительным числом"})
+		return
+	}
+	run, err := rc.Service.Start(body.OrganizationID, body.DryRun)
+	if err != nil {
+		writeRetentionError(ctx, currentUser, err)
+		return
+	}
+	slog.InfoContext(ctx.Request.Context(), "Проход хранения запущен вручную", "run_id", run.ID, "dry_run", run.DryRun,
+		"user_id", currentUser.ID, "name", currentUser.Name)
+	ctx.JSON(http.StatusAccepted, run)
+}
+
+// GetRuns — GET /api/admin/retention/runs — последние проходы, новые первыми.
+func (rc *RetentionController) GetRuns(ctx *gin.Context) {
+	runs, err := rc.Service.ListRuns()
+	if err != nil {
+		slog.ErrorContext(ctx.Request.Context(), "Ошибка чтения проходов хранения", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка чтения проходов хранения"})
+		return
+	}
+	ctx.JSON(http.StatusOK, RetentionRunsResponse{Runs: runs})
+}
+
+// GetRun — GET /api/admin/retention/runs/:id — состояние и прогресс прохода.
+func (rc *RetentionController) GetRun(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID прохода"})
+		return
+	}
+	run, err := rc.Service.GetRun(id)
+	if err != nil {
+		writeRetentionError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, run)
+}
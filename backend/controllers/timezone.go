@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"locator/models"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestLocation — пояс, в котором толкуются from/to запроса и отдаются времена ответа:
+// query ?tz= (IANA), иначе пояс пользователя subjectID (если он задан), иначе — текущего
+// пользователя. Неизвестный tz — 400.
+func requestLocation(ctx *gin.Context, timezones *service.TimezoneService, subjectID int) (*time.Location, bool) {
+	if tz := ctx.Query("tz"); tz != "" {
+		loc, err := service.LoadTimezone(tz)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный часовой пояс"})
+			return nil, false
+		}
+		return loc, true
+	}
+	if subjectID > 0 {
+		return timezones.UserLocation(subjectID), true
+	}
+	var currentUser *models.User
+	if v, ok := ctx.Get("user"); ok {
+		currentUser, _ = v.(*models.User)
+	}
+	return timezones.ForUser(currentUser), true
+}
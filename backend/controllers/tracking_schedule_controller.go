@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"locator/models"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrackingScheduleController — окна отслеживания и согласие пользователя (admin).
+type TrackingScheduleController struct {
+	Service *service.TrackingScheduleService
+}
+
+func NewTrackingScheduleController(scheduleService *service.TrackingScheduleService) *TrackingScheduleController {
+	return &TrackingScheduleController{Service: scheduleService}
+}
+
+// TrackingConsentRequest — тело POST /api/admin/users/:id/tracking-consent;
+// by — кто дал согласие (обязательно при given=true).
+type TrackingConsentRequest struct {
+	Given bool   `json:"given"`
+	By    string `json:"by"`
+}
+
+// TrackingScheduleResponse — график и состояние отслеживания на текущий момент.
+type TrackingScheduleResponse struct {
+	Schedule *models.TrackingSchedule        `json:"schedule"`
+	Status   *service.TrackingScheduleStatus `json:"status"`
+}
+
+func trackingScheduleUserID(ctx *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || userID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return 0, false
+	}
+	return userID, true
+}
+
+func writeTrackingScheduleError(ctx *gin.Context, userID int, err error) {
+	switch {
+	case errors.Is(err, service.ErrTrackingScheduleInvalid):
+		detail := strings.TrimPrefix(err.Error(), service.ErrTrackingScheduleInvalid.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный график отслеживания" + detail})
+	case errors.Is(err, service.ErrTrackingScheduleNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "График отслеживания не задан"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+	default:
+		slog.ErrorContext(ctx.Request.Context(), "Ошибка графика отслеживания", "user_id", userID, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка графика отслеживания"})
+	}
+}
+
+// writeTrackingSchedule отвечает графиком и состоянием отслеживания на текущий момент.
+func (tsc *TrackingScheduleController) writeTrackingSchedule(ctx *gin.Context, userID int) {
+	schedule, status, err := tsc.Service.Get(userID)
+	if err != nil {
+		writeTrackingScheduleError(ctx, userID, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, TrackingScheduleResponse{Schedule: schedule, Status: status})
+}
+
+// GetUserTrackingSchedule — GET /api/admin/users/:id/tracking-schedule
+func (tsc *TrackingScheduleController) GetUserTrackingSchedule(ctx *gin.Context) {
+	userID, ok := trackingScheduleUserID(ctx)
+	if !ok {
+		return
+	}
+	tsc.writeTrackingSchedule(ctx, userID)
+}
+
+// PutUserTrackingSchedule — PUT /api/admin/users/:id/tracking-schedule
+// Полностью заменяет окна, праздники и режим точек вне окна; телефон получает
+// график и текущее состояние паузы сразу, дальше — на каждой границе окна.
+func (tsc *TrackingScheduleController) PutUserTrackingSchedule(ctx *gin.Context) {
+	userID, ok := trackingScheduleUserID(ctx)
+	if !ok {
+		return
+	}
+	var body service.TrackingScheduleInput
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+	if _, err := tsc.Service.Save(userID, body); err != nil {
+		writeTrackingScheduleError(ctx, userID, err)
+		return
+	}
+	tsc.writeTrackingSchedule(ctx, userID)
+}
+
+// DeleteUserTrackingSchedule — DELETE /api/admin/users/:id/tracking-schedule
+// Снимает ограничения: телефон возобновляет отслеживание.
+func (tsc *TrackingScheduleController) DeleteUserTrackingSchedule(ctx *gin.Context) {
+	userID, ok := trackingScheduleUserID(ctx)
+	if !ok {
+		return
+	}
+	if err := tsc.Service.Delete(userID); err != nil {
+		writeTrackingScheduleError(ctx, userID, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, StatusResponse{Status: "deleted"})
+}
+
+// PostUserTrackingConsent — POST /api/admin/users/:id/tracking-consent
+// {"given": true, "by": "Иванов И. И."} — согласие получено; {"given": false} — отозвано.
+func (tsc *TrackingScheduleController) PostUserTrackingConsent(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	userID, ok := trackingScheduleUserID(ctx)
+	if !ok {
+		return
+	}
+	var body TrackingConsentRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса"})
+		return
+	}
+	if body.Given && strings.TrimSpace(body.By) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите, кто дал согласие (by)"})
+		return
+	}
+	if _, err := tsc.Service.RecordConsent(userID, currentUser.ID, body.Given, body.By); err != nil {
+		writeTrackingScheduleError(ctx, userID, err)
+		return
+	}
+	slog.InfoContext(ctx.Request.Context(), "Согласие на отслеживание отмечено", "user_id", userID, "given", body.Given, "actor_id", currentUser.ID)
+	tsc.writeTrackingSchedule(ctx, userID)
+}
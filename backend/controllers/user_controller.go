@@ -1,10 +1,10 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 
@@ -26,6 +26,7 @@ func serveQRCodePNG(ctx *gin.Context, userID int) {
 type UserController struct {
 	Service        *service.UserService
 	CommandService *service.DeviceCommandService
+	Timezones      *service.TimezoneService
 }
 
 // NewUserController создаёт новый экземпляр UserController.
@@ -33,6 +34,97 @@ func NewUserController(svc *service.UserService, cmdSvc *service.DeviceCommandSe
 	return &UserController{Service: svc, CommandService: cmdSvc}
 }
 
+// CreateUserRequest — тело POST /api/users/. Без role: is_admin=true — super_admin,
+// иначе — устройство.
+type CreateUserRequest struct {
+	Name    string `json:"name" binding:"required"`
+	IsAdmin bool   `json:"is_admin"`
+	Role    string `json:"role"`
+	GroupID *int   `json:"group_id"`
+}
+
+// UpdateUserRequest — тело PUT /api/users/:id. Timezone — IANA-пояс пользователя;
+// "" — пояс организации, без поля — не меняется.
+type UpdateUserRequest struct {
+	Name     string  `json:"name"`
+	Timezone *string `json:"timezone"`
+}
+
+// QRCodeResponse — данные QR-кода пользователя (JSON с user_id и api_key).
+type QRCodeResponse struct {
+	UserID int    `json:"user_id"`
+	QRCode string `json:"qr_code"`
+}
+
+// RegenerateQRRequest — тело POST /api/admin/users/:id/regenerate-qr. Без push_to_device
+// новый ключ отправляется на телефон; api_key — задать ключ явно.
+type RegenerateQRRequest struct {
+	PushToDevice *bool  `json:"push_to_device"`
+	APIKey       string `json:"api_key"`
+}
+
+// RegenerateQRResponse — новый ключ (показывается один раз) и config_update для телефона.
+type RegenerateQRResponse struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	IsAdmin         bool   `json:"is_admin"`
+	QRCode          string `json:"qr_code"`
+	APIKey          string `json:"api_key"`
+	APIBaseURL      string `json:"api_base_url"`
+	ConfigCommandID string `json:"config_command_id,omitempty"`
+}
+
+// CurrentUserResponse — GET /api/users/me; timezone — действующий пояс (свой, иначе организации).
+type CurrentUserResponse struct {
+	ID             int                 `json:"id"`
+	Name           string              `json:"name"`
+	IsAdmin        bool                `json:"is_admin"`
+	Role           string              `json:"role"`
+	OrganizationID int                 `json:"organization_id"`
+	GroupID        *int                `json:"group_id"`
+	Permissions    []models.Permission `json:"permissions"`
+	QRCode         string              `json:"qr_code"`
+	Username       *string             `json:"username"`
+	TOTPEnabled    bool                `json:"totp_enabled"`
+	Timezone       string              `json:"timezone"`
+}
+
+// UserAccessRequest — тело PUT /api/admin/users/:id/access.
+type UserAccessRequest struct {
+	Role    string `json:"role" binding:"required"`
+	GroupID *int   `json:"group_id"`
+}
+
+// GroupRequest — тело POST /api/admin/groups.
+type GroupRequest struct {
+	Name string `json:"name"`
+}
+
+// GroupMemberRequest — тело POST /api/admin/groups/:id/members.
+type GroupMemberRequest struct {
+	UserID int `json:"user_id" binding:"required"`
+}
+
+// OrganizationRequest — тело POST /api/admin/organizations.
+type OrganizationRequest struct {
+	Name      string `json:"name"`
+	AdminName string `json:"admin_name"`
+	Timezone  string `json:"timezone"`
+}
+
+// OrganizationCreatedResponse — новая организация и её первый super_admin;
+// API-ключ показывается один раз.
+type OrganizationCreatedResponse struct {
+	Organization *models.Organization `json:"organization"`
+	Admin        *models.User         `json:"admin"`
+	APIKey       string               `json:"api_key"`
+}
+
+// OrganizationTimezoneRequest — тело PUT /api/admin/organizations/:id/timezone.
+type OrganizationTimezoneRequest struct {
+	Timezone string `json:"timezone"`
+}
+
 func (uc *UserController) CreateUser(ctx *gin.Context) {
 	// Извлекаем текущего пользователя из контекста (например, через middleware авторизации)
 	currentUserInterface, exists := ctx.Get("user")
@@ -46,26 +138,27 @@ func (uc *UserController) CreateUser(ctx *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Name    string `json:"name" binding:"required"`
-		IsAdmin bool   `json:"is_admin"`
-	}
+	var req CreateUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные запроса"})
 		return
 	}
 
-	// Если запрошено создание администратора, проверяем, что запрос исходит от администратора.
-	if req.IsAdmin && !currentUser.IsAdmin {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "Нет прав для создания администратора"})
-		return
+	// Без явной роли: is_admin=true — super_admin (как раньше), иначе — устройство.
+	// Выдать роль выше собственной нельзя.
+	role := req.Role
+	if role == "" {
+		role = models.RoleDevice
+		if req.IsAdmin {
+			role = models.RoleSuperAdmin
+		}
 	}
 
 	// Создаем пользователя через UserService.
 	// После создания пользователю сгенерируется QR‑код с данными (JSON: user_id и api_key).
-	user, _, err := uc.Service.CreateUser(req.Name, req.IsAdmin)
+	user, _, err := uc.Service.CreateUserWithAccess(currentUser, userScopeFromContext(ctx), req.Name, role, req.GroupID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания пользователя"})
+		writeUserAccessError(ctx, currentUser, err)
 		return
 	}
 	// Поле API‑ключа не выводится в JSON благодаря тегу json:"-" в модели.
@@ -90,44 +183,92 @@ func (uc *UserController) GetUser(ctx *gin.Context) {
 
 // UpdateUser обрабатывает PUT-запрос для изменения имени пользователя.
 func (uc *UserController) UpdateUser(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+
 	idStr := ctx.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
 		return
 	}
-
-	var req struct {
-		Name string `json:"name" binding:"required"`
+	if !uc.canManageUser(ctx, currentUser, id) {
+		return
 	}
+
+	var req UpdateUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные запроса"})
 		return
 	}
 
 	name := strings.TrimSpace(req.Name)
-	if name == "" {
+	if name == "" && req.Timezone == nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Имя не может быть пустым"})
 		return
 	}
 
-	user, err := uc.Service.UpdateUserName(id, name)
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
-		return
+	var user *models.User
+	if req.Timezone != nil {
+		user, err = uc.Service.UpdateUserTimezone(id, *req.Timezone)
+		if errors.Is(err, service.ErrTimezoneInvalid) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный часовой пояс"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+			return
+		}
+	}
+	if name != "" {
+		user, err = uc.Service.UpdateUserName(id, name)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+			return
+		}
 	}
 	ctx.JSON(http.StatusOK, user)
 }
 
 func (uc *UserController) GetAllUsers(ctx *gin.Context) {
-	users, err := uc.Service.GetAllUsers()
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	users, err := uc.Service.GetAllUsers(currentUser.OrganizationID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения пользователей"})
 		return
 	}
+	if scope := userScopeFromContext(ctx); !scope.All() {
+		visible := make([]models.User, 0, len(users))
+		for _, u := range users {
+			if scope.Allows(u.ID) {
+				visible = append(visible, u)
+			}
+		}
+		users = visible
+	}
 	ctx.JSON(http.StatusOK, users)
 }
 
+// canManageUser проверяет право управлять пользователем id (ключ, QR, имя);
+// при отказе сам пишет ответ.
+func (uc *UserController) canManageUser(ctx *gin.Context, currentUser *models.User, id int) bool {
+	target, err := uc.Service.GetUserByID(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return false
+	}
+	if err := service.CheckUserManagement(currentUser, userScopeFromContext(ctx), target); err != nil {
+		denyUserAccess(ctx, currentUser, id, "Нет прав на управление пользователем")
+		return false
+	}
+	return true
+}
+
 // GetQRCode возвращает JSON с данными QR‑кода для текущего пользователя.
 func (uc *UserController) GetQRCode(ctx *gin.Context) {
 	// Извлекаем текущего пользователя (например, через middleware авторизации).
@@ -142,9 +283,9 @@ func (uc *UserController) GetQRCode(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"user_id": currentUser.ID,
-		"qr_code": currentUser.QRCode,
+	ctx.JSON(http.StatusOK, QRCodeResponse{
+		UserID: currentUser.ID,
+		QRCode: currentUser.QRCode,
 	})
 }
 
@@ -201,10 +342,14 @@ func (uc *UserController) GetUserQRCode(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
 		return
 	}
+	if err := service.CheckUserManagement(currentUser, userScopeFromContext(ctx), targetUser); err != nil {
+		denyUserAccess(ctx, currentUser, id, "Нет прав на управление пользователем")
+		return
+	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"user_id": targetUser.ID,
-		"qr_code": targetUser.QRCode,
+	ctx.JSON(http.StatusOK, QRCodeResponse{
+		UserID: targetUser.ID,
+		QRCode: targetUser.QRCode,
 	})
 
 }
@@ -244,6 +389,10 @@ func (uc *UserController) GetUserQRCodeFile(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
 		return
 	}
+	if err := service.CheckUserManagement(currentUser, userScopeFromContext(ctx), targetUser); err != nil {
+		denyUserAccess(ctx, currentUser, id, "Нет прав на управление пользователем")
+		return
+	}
 
 	serveQRCodePNG(ctx, targetUser.ID)
 }
@@ -287,11 +436,11 @@ func (uc *UserController) PostRegenerateUserQR(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
 		return
 	}
-
-	var body struct {
-		PushToDevice *bool  `json:"push_to_device"`
-		APIKey       string `json:"api_key"`
+	if !uc.canManageUser(ctx, currentUser, id) {
+		return
 	}
+
+	var body RegenerateQRRequest
 	_ = ctx.ShouldBindJSON(&body)
 	pushToDevice := body.PushToDevice == nil || *body.PushToDevice
 
@@ -307,29 +456,26 @@ func (uc *UserController) PostRegenerateUserQR(ctx *gin.Context) {
 		return
 	}
 
-	response := gin.H{
-		"id":       user.ID,
-		"name":     user.Name,
-		"is_admin": user.IsAdmin,
-		"qr_code":  user.QRCode,
-		"api_key":  plainKey,
+	response := RegenerateQRResponse{
+		ID:         user.ID,
+		Name:       user.Name,
+		IsAdmin:    user.IsAdmin,
+		QRCode:     user.QRCode,
+		APIKey:     plainKey,
+		APIBaseURL: uc.Service.APIBaseURL(),
 	}
 
 	if pushToDevice && uc.CommandService != nil {
-		apiBase := os.Getenv("BASE_URL")
-		if apiBase == "" {
-			apiBase = "http://localhost:8080"
-		}
 		payload := map[string]interface{}{
-			"api_base_url": apiBase,
+			"api_base_url": uc.Service.APIBaseURL(),
 			"api_key":      plainKey,
 			"user_id":      user.ID,
 		}
 		cmd, err := uc.CommandService.EnqueueCommand(user.ID, models.DeviceCommandTypeConfigUpdate, payload)
 		if err != nil {
-			log.Printf("[PostRegenerateUserQR] config_update не поставлен в очередь: %v", err)
+			slog.ErrorContext(ctx.Request.Context(), "config_update не поставлен в очередь", "user_id", user.ID, "error", err)
 		} else {
-			response["config_command_id"] = cmd.ID
+			response.ConfigCommandID = cmd.ID
 		}
 	}
 
@@ -351,11 +497,221 @@ func (uc *UserController) GetCurrentUser(c *gin.Context) {
 		return
 	}
 
-	// Возвращаем информацию о пользователе
-	c.JSON(http.StatusOK, gin.H{
-		"id":       user.ID,
-		"name":     user.Name,
-		"is_admin": user.IsAdmin,
-		"qr_code":  user.QRCode,
+	// Возвращаем информацию о пользователе; timezone — действующий пояс (свой, иначе организации)
+	c.JSON(http.StatusOK, CurrentUserResponse{
+		ID:             user.ID,
+		Name:           user.Name,
+		IsAdmin:        user.IsAdmin,
+		Role:           user.EffectiveRole(),
+		OrganizationID: user.OrganizationID,
+		GroupID:        user.GroupID,
+		Permissions:    models.RolePermissions(user.EffectiveRole()),
+		QRCode:         user.QRCode,
+		Username:       user.Username,
+		TOTPEnabled:    user.TOTPEnabled,
+		Timezone:       uc.Timezones.ForUser(user).String(),
+	})
+}
+
+// PutUserAccess — PUT /api/admin/users/:id/access {"role":"dispatcher","group_id":3}
+// Назначает роль и группу. Нельзя выдать роль выше собственной, менять свою роль
+// и пользователей вне своей группы.
+func (uc *UserController) PutUserAccess(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	var body UserAccessRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите role"})
+		return
+	}
+
+	user, err := uc.Service.SetUserAccess(currentUser, userScopeFromContext(ctx), id, body.Role, body.GroupID)
+	if err != nil {
+		if !errors.Is(err, service.ErrAccessDenied) && !errors.Is(err, service.ErrInvalidRole) &&
+			!errors.Is(err, service.ErrGroupNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+			return
+		}
+		writeUserAccessError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, user)
+}
+
+// GetGroups — GET /api/admin/groups
+func (uc *UserController) GetGroups(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	groups, err := uc.Service.ListGroups(currentUser)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения групп"})
+		return
+	}
+	ctx.JSON(http.StatusOK, groups)
+}
+
+// PostGroup — POST /api/admin/groups {"name":"Бригада 1"}
+func (uc *UserController) PostGroup(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	var body GroupRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные запроса"})
+		return
+	}
+	group, err := uc.Service.CreateGroup(currentUser, body.Name)
+	if err != nil {
+		writeUserAccessError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, group)
+}
+
+func groupIDParam(ctx *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID группы"})
+		return 0, false
+	}
+	return id, true
+}
+
+// GetGroupMembers — GET /api/admin/groups/:id/members
+func (uc *UserController) GetGroupMembers(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	groupID, ok := groupIDParam(ctx)
+	if !ok {
+		return
+	}
+	members, err := uc.Service.ListGroupMembers(currentUser, groupID)
+	if err != nil {
+		writeUserAccessError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, members)
+}
+
+// PostGroupMember — POST /api/admin/groups/:id/members {"user_id":12}
+// Переводит пользователя в группу (из прежней группы он выходит).
+func (uc *UserController) PostGroupMember(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	groupID, ok := groupIDParam(ctx)
+	if !ok {
+		return
+	}
+	var body GroupMemberRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Укажите user_id"})
+		return
+	}
+	user, err := uc.Service.AddGroupMember(currentUser, userScopeFromContext(ctx), groupID, body.UserID)
+	if err != nil {
+		writeUserAccessError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, user)
+}
+
+// DeleteGroupMember — DELETE /api/admin/groups/:id/members/:user_id
+func (uc *UserController) DeleteGroupMember(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	groupID, ok := groupIDParam(ctx)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil || userID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+	user, err := uc.Service.RemoveGroupMember(currentUser, userScopeFromContext(ctx), groupID, userID)
+	if err != nil {
+		writeUserAccessError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, user)
+}
+
+// GetOrganizations — GET /api/admin/organizations (только администратор платформы).
+func (uc *UserController) GetOrganizations(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	orgs, err := uc.Service.ListOrganizations(currentUser)
+	if err != nil {
+		writeUserAccessError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, orgs)
+}
+
+// PostOrganization — POST /api/admin/organizations {"name":"Acme","admin_name":"Иван","timezone":"Europe/Warsaw"}
+// Создаёт организацию и её первого super_admin; API-ключ показывается один раз.
+func (uc *UserController) PostOrganization(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	var body OrganizationRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные запроса"})
+		return
+	}
+	org, admin, apiKey, err := uc.Service.CreateOrganization(currentUser, body.Name, body.AdminName, body.Timezone)
+	if err != nil {
+		writeUserAccessError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, OrganizationCreatedResponse{
+		Organization: org,
+		Admin:        admin,
+		APIKey:       apiKey,
 	})
 }
+
+// PutOrganizationTimezone — PUT /api/admin/organizations/:id/timezone {"timezone":"Asia/Almaty"}
+// Пояс, в котором толкуется локальное время пользователей организации без собственного пояса.
+func (uc *UserController) PutOrganizationTimezone(ctx *gin.Context) {
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID организации"})
+		return
+	}
+	var body OrganizationTimezoneRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные запроса"})
+		return
+	}
+	org, err := uc.Service.SetOrganizationTimezone(currentUser, id, body.Timezone)
+	if err != nil {
+		writeUserAccessError(ctx, currentUser, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, org)
+}
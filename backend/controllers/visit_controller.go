@@ -2,13 +2,16 @@ package controllers
 
 import (
 	"github.com/gin-gonic/gin"
+	"locator/models"
 	"locator/service"
 	"net/http"
+	"strconv"
 )
 
 // VisitController отвечает за обработку запросов, связанных с визитами (посещениями чекпоинтов).
 type VisitController struct {
 	VisitService *service.VisitService
+	Timezones    *service.TimezoneService
 }
 
 // NewVisitController создаёт новый экземпляр VisitController.
@@ -19,10 +22,29 @@ func NewVisitController(visitService *service.VisitService) *VisitController {
 }
 
 func (vc *VisitController) GetVisitsByFilters(ctx *gin.Context) {
-	visits, err := vc.VisitService.GetVisitsByFilters(ctx.Request.URL.Query())
+	currentUser, ok := getCurrentUserFromContext(ctx)
+	if !ok {
+		return
+	}
+	// from/to — в поясе пользователя user_id (если задан), иначе текущего; ?tz= важнее.
+	subjectID, _ := strconv.Atoi(ctx.Query("user_id"))
+	loc, ok := requestLocation(ctx, vc.Timezones, subjectID)
+	if !ok {
+		return
+	}
+	visits, err := vc.VisitService.GetVisitsByFilters(currentUser.OrganizationID, ctx.Request.URL.Query(), loc)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if scope := userScopeFromContext(ctx); !scope.All() {
+		visible := make([]models.Visit, 0, len(visits))
+		for _, v := range visits {
+			if scope.Allows(v.UserID) {
+				visible = append(visible, v)
+			}
+		}
+		visits = visible
+	}
 	ctx.JSON(http.StatusOK, visits)
 }
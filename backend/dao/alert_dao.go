@@ -0,0 +1,101 @@
+package dao
+
+import (
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+// AlertDAO — правила алертинга и история алертов.
+type AlertDAO struct {
+	DB *gorm.DB
+}
+
+func NewAlertDAO(db *gorm.DB) *AlertDAO {
+	return &AlertDAO{DB: db}
+}
+
+func (dao *AlertDAO) CreateRule(rule *models.AlertRule) error {
+	return dao.DB.Create(rule).Error
+}
+
+func (dao *AlertDAO) UpdateRule(rule *models.AlertRule) error {
+	return dao.DB.Save(rule).Error
+}
+
+func (dao *AlertDAO) DeleteRule(id int) error {
+	return dao.DB.Delete(&models.AlertRule{}, id).Error
+}
+
+func (dao *AlertDAO) GetRuleByID(id int) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	if err := dao.DB.First(&rule, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetAllRules — правила всех организаций (движок алертов).
+func (dao *AlertDAO) GetAllRules() ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	if err := dao.DB.Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetRulesByOrganization — правила организации.
+func (dao *AlertDAO) GetRulesByOrganization(organizationID int) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	if err := dao.DB.Where("organization_id = ?", organizationID).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (dao *AlertDAO) CreateAlert(alert *models.Alert) error {
+	return dao.DB.Create(alert).Error
+}
+
+func (dao *AlertDAO) UpdateAlert(alert *models.Alert) error {
+	return dao.DB.Save(alert).Error
+}
+
+func (dao *AlertDAO) GetAlertByID(id int) (*models.Alert, error) {
+	var alert models.Alert
+	if err := dao.DB.First(&alert, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// GetActiveAlerts — все незакрытые алерты (open и acknowledged).
+func (dao *AlertDAO) GetActiveAlerts() ([]models.Alert, error) {
+	var alerts []models.Alert
+	err := dao.DB.Where("status <> ?", models.AlertStatusResolved).Order("id ASC").Find(&alerts).Error
+	return alerts, err
+}
+
+// ListAlerts возвращает страницу алертов (новые первыми) и общее число.
+// Параметр filters может содержать ключи: "status", "user_id", "rule_id", "type".
+func (dao *AlertDAO) ListAlerts(filters map[string]interface{}, limit, offset int) ([]models.Alert, int64, error) {
+	query := dao.DB.Model(&models.Alert{})
+	for key, value := range filters {
+		if values, ok := value.([]int); ok {
+			query = query.Where(key+" IN ?", values)
+			continue
+		}
+		query = query.Where(key+" = ?", value)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var alerts []models.Alert
+	if err := query.Order("last_seen_at DESC, id DESC").Limit(limit).Offset(offset).Find(&alerts).Error; err != nil {
+		return nil, 0, err
+	}
+	return alerts, total, nil
+}
@@ -0,0 +1,65 @@
+package dao
+
+import (
+	"time"
+
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyDAO — API-ключи пользователей.
+type APIKeyDAO struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyDAO(db *gorm.DB) *APIKeyDAO {
+	return &APIKeyDAO{DB: db}
+}
+
+func (dao *APIKeyDAO) CreateAPIKey(key *models.APIKey) error {
+	return dao.DB.Create(key).Error
+}
+
+func (dao *APIKeyDAO) UpdateAPIKey(key *models.APIKey) error {
+	return dao.DB.Save(key).Error
+}
+
+func (dao *APIKeyDAO) GetAPIKeyByID(id int) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := dao.DB.First(&key, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKeysByUser — ключи пользователя, новые первыми.
+func (dao *APIKeyDAO) GetAPIKeysByUser(userID int) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := dao.DB.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// GetAPIKeyCandidates — неотозванные ключи с данным отпечатком.
+func (dao *APIKeyDAO) GetAPIKeyCandidates(fingerprint string) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := dao.DB.Where("revoked_at IS NULL AND fingerprint = ?", fingerprint).
+		Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// GetLegacyAPIKeys — не больше limit действующих в now ключей без отпечатка
+// (перенесённых из users.api_key и ещё ни разу не проверенных), новые первыми.
+func (dao *APIKeyDAO) GetLegacyAPIKeys(now time.Time, limit int) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := dao.DB.Where("revoked_at IS NULL AND fingerprint = '' AND (expires_at IS NULL OR expires_at > ?)", now).
+		Order("id DESC").Limit(limit).Find(&keys).Error
+	return keys, err
+}
+
+// RevokeReplacedAPIKeys отзывает ключи, заменённые ключом replacedByID.
+func (dao *APIKeyDAO) RevokeReplacedAPIKeys(replacedByID int, at time.Time) error {
+	return dao.DB.Model(&models.APIKey{}).
+		Where("replaced_by_id = ? AND revoked_at IS NULL", replacedByID).
+		Update("revoked_at", at).Error
+}
@@ -0,0 +1,99 @@
+package dao
+
+import (
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+// AppReleaseDAO — релизы приложения, каналы обновлений и участники каналов.
+type AppReleaseDAO struct {
+	DB *gorm.DB
+}
+
+func NewAppReleaseDAO(db *gorm.DB) *AppReleaseDAO {
+	return &AppReleaseDAO{DB: db}
+}
+
+func (dao *AppReleaseDAO) CreateRelease(release *models.AppRelease) error {
+	return dao.DB.Create(release).Error
+}
+
+func (dao *AppReleaseDAO) UpdateRelease(release *models.AppRelease) error {
+	return dao.DB.Save(release).Error
+}
+
+func (dao *AppReleaseDAO) GetReleaseByID(id int) (*models.AppRelease, error) {
+	var release models.AppRelease
+	if err := dao.DB.First(&release, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// ListReleases — история релизов организации (новые версии первыми); пустой channel — все каналы.
+func (dao *AppReleaseDAO) ListReleases(organizationID int, channel string) ([]models.AppRelease, error) {
+	query := dao.DB.Model(&models.AppRelease{}).Where("organization_id = ?", organizationID)
+	if channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+	var releases []models.AppRelease
+	err := query.Order("version_code DESC, id DESC").Find(&releases).Error
+	return releases, err
+}
+
+// GetActiveReleases — активные релизы указанных каналов организации, новые версии первыми.
+func (dao *AppReleaseDAO) GetActiveReleases(organizationID int, channels []string) ([]models.AppRelease, error) {
+	var releases []models.AppRelease
+	err := dao.DB.
+		Where("organization_id = ? AND channel IN ? AND status = ?", organizationID, channels, models.AppReleaseStatusActive).
+		Order("version_code DESC, id DESC").
+		Find(&releases).Error
+	return releases, err
+}
+
+func (dao *AppReleaseDAO) GetChannel(organizationID int, name string) (*models.AppReleaseChannel, error) {
+	var ch models.AppReleaseChannel
+	if err := dao.DB.First(&ch, "organization_id = ? AND name = ?", organizationID, name).Error; err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+func (dao *AppReleaseDAO) GetAllChannels(organizationID int) ([]models.AppReleaseChannel, error) {
+	var channels []models.AppReleaseChannel
+	err := dao.DB.Where("organization_id = ?", organizationID).Order("name ASC").Find(&channels).Error
+	return channels, err
+}
+
+// SaveChannel создаёт или перезаписывает настройки канала.
+func (dao *AppReleaseDAO) SaveChannel(ch *models.AppReleaseChannel) error {
+	return dao.DB.Save(ch).Error
+}
+
+func (dao *AppReleaseDAO) GetMember(userID int) (*models.AppReleaseMember, error) {
+	var m models.AppReleaseMember
+	if err := dao.DB.First(&m, "user_id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetAllMembers — участники каналов среди пользователей организации.
+func (dao *AppReleaseDAO) GetAllMembers(organizationID int) ([]models.AppReleaseMember, error) {
+	var members []models.AppReleaseMember
+	err := dao.DB.
+		Where("user_id IN (SELECT id FROM users WHERE organization_id = ?)", organizationID).
+		Order("user_id ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// SaveMember назначает пользователю канал обновлений.
+func (dao *AppReleaseDAO) SaveMember(m *models.AppReleaseMember) error {
+	return dao.DB.Save(m).Error
+}
+
+func (dao *AppReleaseDAO) DeleteMember(userID int) error {
+	return dao.DB.Delete(&models.AppReleaseMember{}, "user_id = ?", userID).Error
+}
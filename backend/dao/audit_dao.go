@@ -0,0 +1,69 @@
+package dao
+
+import (
+	"strings"
+	"time"
+
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditDAO — журнал аудита. Записи только добавляются.
+type AuditDAO struct {
+	DB *gorm.DB
+}
+
+func NewAuditDAO(db *gorm.DB) *AuditDAO {
+	return &AuditDAO{DB: db}
+}
+
+func (dao *AuditDAO) CreateAuditEntry(entry *models.AuditEntry) error {
+	return dao.DB.Create(entry).Error
+}
+
+// ListAuditEntries возвращает страницу записей (новые первыми) и общее число.
+// Параметр filters может содержать ключи: "organization_id", "actor_id", "target_type",
+// "target_id", "result" (равенство; []int — список) и "action" (начало строки).
+// from/to (nil — без ограничения) задают период [from, to).
+func (dao *AuditDAO) ListAuditEntries(filters map[string]interface{}, from, to *time.Time, limit, offset int) ([]models.AuditEntry, int64, error) {
+	query := dao.DB.Model(&models.AuditEntry{})
+	for key, value := range filters {
+		switch v := value.(type) {
+		case []int:
+			query = query.Where(key+" IN ?", v)
+		case string:
+			if key == "action" {
+				query = query.Where("action LIKE ?", escapeLike(v)+"%")
+				continue
+			}
+			query = query.Where(key+" = ?", v)
+		default:
+			query = query.Where(key+" = ?", v)
+		}
+	}
+	if from != nil {
+		query = query.Where("at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("at < ?", *to)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.AuditEntry
+	if err := query.Order("at DESC, id DESC").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike экранирует спецсимволы шаблона LIKE.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -21,19 +21,19 @@ func (dao *CheckpointDAO) Create(cp *models.Checkpoint) error {
 	return dao.DB.Create(cp).Error
 }
 
-// GetAll возвращает список всех чекпоинтов.
-func (dao *CheckpointDAO) GetAll() ([]models.Checkpoint, error) {
+// GetAll возвращает список всех чекпоинтов организации.
+func (dao *CheckpointDAO) GetAll(organizationID int) ([]models.Checkpoint, error) {
 	var checkpoints []models.Checkpoint
-	if err := dao.DB.Find(&checkpoints).Error; err != nil {
+	if err := dao.DB.Where("organization_id = ?", organizationID).Find(&checkpoints).Error; err != nil {
 		return nil, err
 	}
 	return checkpoints, nil
 }
 
-// GetByID возвращает чекпоинт по его ID.
-func (dao *CheckpointDAO) GetByID(id int) (*models.Checkpoint, error) {
+// GetByID возвращает чекпоинт организации по его ID.
+func (dao *CheckpointDAO) GetByID(organizationID, id int) (*models.Checkpoint, error) {
 	var cp models.Checkpoint
-	if err := dao.DB.First(&cp, "id = ?", id).Error; err != nil {
+	if err := dao.DB.First(&cp, "id = ? AND organization_id = ?", id, organizationID).Error; err != nil {
 		return nil, err
 	}
 	return &cp, nil
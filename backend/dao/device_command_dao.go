@@ -2,9 +2,11 @@ package dao
 
 import (
 	"locator/models"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DeviceCommandDAO struct {
@@ -15,7 +17,17 @@ func NewDeviceCommandDAO(db *gorm.DB) *DeviceCommandDAO {
 	return &DeviceCommandDAO{DB: db}
 }
 
+// Create сохраняет команду; организация берётся у пользователя-получателя.
 func (dao *DeviceCommandDAO) Create(cmd *models.DeviceCommand) error {
+	if cmd.OrganizationID == 0 {
+		var orgIDs []int
+		if err := dao.DB.Model(&models.User{}).Where("id = ?", cmd.UserID).Pluck("organization_id", &orgIDs).Error; err != nil {
+			return err
+		}
+		if len(orgIDs) > 0 {
+			cmd.OrganizationID = orgIDs[0]
+		}
+	}
 	return dao.DB.Create(cmd).Error
 }
 
@@ -73,12 +85,16 @@ func (dao *DeviceCommandDAO) MarkProgress(id, ackStatus, ackMessage string, at t
 	}).Error
 }
 
-func (dao *DeviceCommandDAO) ExpirePendingOlderThan(cutoff time.Time) error {
+func (dao *DeviceCommandDAO) ExpirePendingOlderThan(cutoff time.Time) ([]string, error) {
 	return dao.ExpirePendingOlderThanExceptType(cutoff, "")
 }
 
-func (dao *DeviceCommandDAO) ExpirePendingOlderThanExceptType(cutoff time.Time, exceptType string) error {
-	q := dao.DB.Model(&models.DeviceCommand{}).
+// ExpirePendingOlderThanExceptType помечает expired недоставленные и неподтверждённые
+// команды старше cutoff (кроме типа exceptType) и возвращает их типы.
+func (dao *DeviceCommandDAO) ExpirePendingOlderThanExceptType(cutoff time.Time, exceptType string) ([]string, error) {
+	var expired []models.DeviceCommand
+	q := dao.DB.Model(&expired).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "type"}}}).
 		Where("status IN ? AND created_at < ?", []string{
 			models.DeviceCommandStatusPending,
 			models.DeviceCommandStatusDelivered,
@@ -86,16 +102,34 @@ func (dao *DeviceCommandDAO) ExpirePendingOlderThanExceptType(cutoff time.Time,
 	if exceptType != "" {
 		q = q.Where("type <> ?", exceptType)
 	}
-	return q.Update("status", models.DeviceCommandStatusExpired).Error
+	if err := q.Update("status", models.DeviceCommandStatusExpired).Error; err != nil {
+		return nil, err
+	}
+	return commandTypes(expired), nil
 }
 
-func (dao *DeviceCommandDAO) ExpirePendingOlderThanType(cutoff time.Time, cmdType string) error {
-	return dao.DB.Model(&models.DeviceCommand{}).
+// ExpirePendingOlderThanType — то же для команд одного типа.
+func (dao *DeviceCommandDAO) ExpirePendingOlderThanType(cutoff time.Time, cmdType string) ([]string, error) {
+	var expired []models.DeviceCommand
+	err := dao.DB.Model(&expired).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "type"}}}).
 		Where("status IN ? AND created_at < ? AND type = ?", []string{
 			models.DeviceCommandStatusPending,
 			models.DeviceCommandStatusDelivered,
 		}, cutoff, cmdType).
 		Update("status", models.DeviceCommandStatusExpired).Error
+	if err != nil {
+		return nil, err
+	}
+	return commandTypes(expired), nil
+}
+
+func commandTypes(cmds []models.DeviceCommand) []string {
+	types := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		types[i] = cmd.Type
+	}
+	return types
 }
 
 func (dao *DeviceCommandDAO) CancelPendingForUser(userID int, cmdType, excludeID string) error {
@@ -106,3 +140,33 @@ func (dao *DeviceCommandDAO) CancelPendingForUser(userID int, cmdType, excludeID
 	}
 	return q.Update("status", models.DeviceCommandStatusExpired).Error
 }
+
+// GetLatestByType — последняя команда указанного типа для пользователя.
+func (dao *DeviceCommandDAO) GetLatestByType(userID int, cmdType string) (*models.DeviceCommand, error) {
+	var cmd models.DeviceCommand
+	err := dao.DB.
+		Where("user_id = ? AND type = ?", userID, cmdType).
+		Order("created_at DESC").
+		First(&cmd).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+// CountAppUpdateResults — число завершённых (acked/failed) и неуспешных команд app_update релиза.
+func (dao *DeviceCommandDAO) CountAppUpdateResults(releaseID int) (total, failed int64, err error) {
+	var row struct {
+		Total  int64
+		Failed int64
+	}
+	err = dao.DB.Model(&models.DeviceCommand{}).
+		Select("COUNT(*) AS total, COUNT(*) FILTER (WHERE status = ?) AS failed", models.DeviceCommandStatusFailed).
+		Where("type = ? AND payload->>'release_id' = ? AND status IN ?",
+			models.DeviceCommandTypeAppUpdate,
+			strconv.Itoa(releaseID),
+			[]string{models.DeviceCommandStatusAcked, models.DeviceCommandStatusFailed},
+		).
+		Scan(&row).Error
+	return row.Total, row.Failed, err
+}
@@ -0,0 +1,74 @@
+package dao
+
+import (
+	"locator/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeviceConfigDAO — профили и желаемая конфигурация устройств.
+type DeviceConfigDAO struct {
+	DB *gorm.DB
+}
+
+func NewDeviceConfigDAO(db *gorm.DB) *DeviceConfigDAO {
+	return &DeviceConfigDAO{DB: db}
+}
+
+func (dao *DeviceConfigDAO) CreateProfile(p *models.DeviceConfigProfile) error {
+	return dao.DB.Create(p).Error
+}
+
+// UpdateProfile перезаписывает профиль в пределах его организации.
+func (dao *DeviceConfigDAO) UpdateProfile(p *models.DeviceConfigProfile) error {
+	return dao.DB.Model(p).Where("organization_id = ?", p.OrganizationID).Select("*").Updates(p).Error
+}
+
+// GetProfileByID возвращает профиль организации; профиль другой организации не находится.
+func (dao *DeviceConfigDAO) GetProfileByID(organizationID, id int) (*models.DeviceConfigProfile, error) {
+	var p models.DeviceConfigProfile
+	if err := dao.DB.First(&p, "id = ? AND organization_id = ?", id, organizationID).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetAllProfiles возвращает профили организации.
+func (dao *DeviceConfigDAO) GetAllProfiles(organizationID int) ([]models.DeviceConfigProfile, error) {
+	var profiles []models.DeviceConfigProfile
+	if err := dao.DB.Where("organization_id = ?", organizationID).Order("id ASC").Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+func (dao *DeviceConfigDAO) GetDesired(userID int) (*models.DeviceDesiredConfig, error) {
+	var cfg models.DeviceDesiredConfig
+	if err := dao.DB.First(&cfg, "user_id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// SaveDesired создаёт или полностью перезаписывает желаемую конфигурацию пользователя.
+func (dao *DeviceConfigDAO) SaveDesired(cfg *models.DeviceDesiredConfig) error {
+	return dao.DB.Save(cfg).Error
+}
+
+// GetAllDesired возвращает desired-конфигурации устройств организации.
+func (dao *DeviceConfigDAO) GetAllDesired(organizationID int) ([]models.DeviceDesiredConfig, error) {
+	var items []models.DeviceDesiredConfig
+	if err := dao.DB.Where("organization_id = ?", organizationID).Order("user_id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// MarkPushed запоминает хэш последнего автоматического config_update.
+func (dao *DeviceConfigDAO) MarkPushed(userID int, hash string, at time.Time) error {
+	return dao.DB.Model(&models.DeviceDesiredConfig{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"last_pushed_hash": hash,
+		"last_pushed_at":   at,
+	}).Error
+}
@@ -2,6 +2,7 @@ package dao
 
 import (
 	"locator/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -29,3 +30,32 @@ func (dao *DeviceReportDAO) GetLatestByUserID(userID int) (*models.DeviceReport,
 	}
 	return &report, nil
 }
+
+// ListByUserBetween — страница отчётов пользователя за интервал (новые первыми) и общее число.
+func (dao *DeviceReportDAO) ListByUserBetween(userID int, from, to time.Time, limit, offset int) ([]models.DeviceReport, int64, error) {
+	q := dao.DB.Model(&models.DeviceReport{}).
+		Where("user_id = ? AND created_at >= ? AND created_at <= ?", userID, from, to)
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reports []models.DeviceReport
+	err := q.Order("created_at DESC").Limit(limit).Offset(offset).Find(&reports).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return reports, total, nil
+}
+
+// GetByUserBetweenAsc — все отчёты пользователя за интервал в хронологическом порядке
+// (для таймлайна проблем и трендов).
+func (dao *DeviceReportDAO) GetByUserBetweenAsc(userID int, from, to time.Time) ([]models.DeviceReport, error) {
+	var reports []models.DeviceReport
+	err := dao.DB.
+		Where("user_id = ? AND created_at >= ? AND created_at <= ?", userID, from, to).
+		Order("created_at ASC").
+		Find(&reports).Error
+	return reports, err
+}
@@ -4,20 +4,63 @@ import "time"
 
 // LatestDeviceReportRow — краткая сводка последнего отчёта устройства.
 type LatestDeviceReportRow struct {
-	UserID     int
-	AppVersion string
-	Platform   string
-	Issues     []byte
-	CreatedAt  time.Time
+	UserID         int
+	AppVersion     string
+	Platform       string
+	Issues         []byte
+	BatteryPercent *float64
+	CreatedAt      time.Time
 }
 
 // GetLatestPerUser возвращает последний отчёт для каждого пользователя (один SQL).
-func (dao *DeviceReportDAO) GetLatestPerUser() ([]LatestDeviceReportRow, error) {
+// organizationID = 0 — по всем организациям (движок алертов).
+func (dao *DeviceReportDAO) GetLatestPerUser(organizationID int) ([]LatestDeviceReportRow, error) {
 	var rows []LatestDeviceReportRow
 	err := dao.DB.Raw(`
-		SELECT DISTINCT ON (user_id) user_id, app_version, platform, issues, created_at
+		SELECT DISTINCT ON (user_id) user_id, app_version, platform, issues,
+			CASE WHEN jsonb_typeof(report->'battery'->'level_percent') = 'number'
+				THEN (report->'battery'->>'level_percent')::float8 END AS battery_percent,
+			created_at
 		FROM device_reports
+		WHERE ? = 0 OR user_id IN (SELECT id FROM users WHERE organization_id = ?)
 		ORDER BY user_id, created_at DESC
-	`).Scan(&rows).Error
+	`, organizationID, organizationID).Scan(&rows).Error
 	return rows, err
 }
+
+// LatestReportedConfigRow — блок config из последнего отчёта устройства, где он был.
+type LatestReportedConfigRow struct {
+	UserID    int
+	Config    []byte
+	CreatedAt time.Time
+}
+
+// GetLatestConfigPerUser возвращает reported config последнего отчёта каждого
+// пользователя организации (один SQL).
+func (dao *DeviceReportDAO) GetLatestConfigPerUser(organizationID int) ([]LatestReportedConfigRow, error) {
+	var rows []LatestReportedConfigRow
+	err := dao.DB.Raw(`
+		SELECT DISTINCT ON (user_id) user_id, report->'config' AS config, created_at
+		FROM device_reports
+		WHERE report->'config' IS NOT NULL
+			AND user_id IN (SELECT id FROM users WHERE organization_id = ?)
+		ORDER BY user_id, created_at DESC
+	`, organizationID).Scan(&rows).Error
+	return rows, err
+}
+
+// GetLatestConfigByUserID — reported config последнего отчёта пользователя, где он был.
+func (dao *DeviceReportDAO) GetLatestConfigByUserID(userID int) (*LatestReportedConfigRow, error) {
+	var rows []LatestReportedConfigRow
+	err := dao.DB.Raw(`
+		SELECT user_id, report->'config' AS config, created_at
+		FROM device_reports
+		WHERE user_id = ? AND report->'config' IS NOT NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, userID).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return &rows[0], nil
+}
@@ -0,0 +1,35 @@
+package dao
+
+import (
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+// GroupDAO — группы пользователей внутри организации (ограничение видимости сотрудников).
+type GroupDAO struct {
+	DB *gorm.DB
+}
+
+func NewGroupDAO(db *gorm.DB) *GroupDAO {
+	return &GroupDAO{DB: db}
+}
+
+func (dao *GroupDAO) CreateGroup(group *models.Group) error {
+	return dao.DB.Create(group).Error
+}
+
+func (dao *GroupDAO) GetGroupByID(id int) (*models.Group, error) {
+	var group models.Group
+	if err := dao.DB.First(&group, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetAllGroups — группы организации.
+func (dao *GroupDAO) GetAllGroups(organizationID int) ([]models.Group, error) {
+	var groups []models.Group
+	err := dao.DB.Where("organization_id = ?", organizationID).Order("name ASC").Find(&groups).Error
+	return groups, err
+}
@@ -18,9 +18,10 @@ func NewLocationDAO(db *gorm.DB) *LocationDAO {
 }
 
 // GetByUserID возвращает последнюю запись по времени фиксации (captured_at или created_at).
+// Здесь и ниже маскированные точки (вне окна отслеживания) в выборку не попадают.
 func (dao *LocationDAO) GetByUserID(userID int) (*models.Location, error) {
 	var loc models.Location
-	if err := dao.DB.Where("user_id = ?", userID).
+	if err := dao.DB.Where("user_id = ? AND NOT masked", userID).
 		Order("COALESCE(captured_at, created_at) DESC").
 		First(&loc).Error; err != nil {
 		return nil, err
@@ -32,7 +33,7 @@ func (dao *LocationDAO) GetByUserID(userID int) (*models.Location, error) {
 func (dao *LocationDAO) GetPreviousByEffectiveTime(userID int, before time.Time) (*models.Location, error) {
 	var loc models.Location
 	err := dao.DB.Where(
-		"user_id = ? AND COALESCE(captured_at, created_at) < ?",
+		"user_id = ? AND NOT masked AND COALESCE(captured_at, created_at) < ?",
 		userID, before,
 	).Order("COALESCE(captured_at, created_at) DESC").First(&loc).Error
 	if err != nil {
@@ -61,7 +62,7 @@ func (dao *LocationDAO) Update(loc *models.Location) error {
 // GetAll возвращает все записи о местоположениях.
 func (dao *LocationDAO) GetAll() ([]models.Location, error) {
 	var locations []models.Location
-	if err := dao.DB.Find(&locations).Error; err != nil {
+	if err := dao.DB.Where("NOT masked").Find(&locations).Error; err != nil {
 		return nil, err
 	}
 	return locations, nil
@@ -97,7 +98,7 @@ func (dao *LocationDAO) UpdateCapturedAt(id int, capturedAt time.Time) error {
 func (dao *LocationDAO) GetLocationsBetween(from, to time.Time) ([]models.Location, error) {
 	var locations []models.Location
 	err := dao.DB.
-		Where("COALESCE(captured_at, created_at) BETWEEN ? AND ?", from, to).
+		Where("NOT masked AND COALESCE(captured_at, created_at) BETWEEN ? AND ?", from, to).
 		Order("COALESCE(captured_at, created_at) ASC").
 		Find(&locations).Error
 	if err != nil {
@@ -111,7 +112,7 @@ func (dao *LocationDAO) GetLocationsByUserBetween(userID int, from, to time.Time
 	var locations []models.Location
 	err := dao.DB.
 		Where(
-			"user_id = ? AND COALESCE(captured_at, created_at) BETWEEN ? AND ?",
+			"user_id = ? AND NOT masked AND COALESCE(captured_at, created_at) BETWEEN ? AND ?",
 			userID, from, to,
 		).
 		Order("COALESCE(captured_at, created_at) ASC").
@@ -121,3 +122,48 @@ func (dao *LocationDAO) GetLocationsByUserBetween(userID int, from, to time.Time
 	}
 	return locations, nil
 }
+
+// GetForDownsampling — ещё не прореженные точки пользователя (включая маскированные)
+// с временем фиксации в [from, to), после курсора (afterAt, afterID), по возрастанию времени.
+func (dao *LocationDAO) GetForDownsampling(userID int, from, to, afterAt time.Time, afterID, limit int) ([]models.Location, error) {
+	var locations []models.Location
+	err := dao.DB.
+		Where(
+			"user_id = ? AND NOT downsampled AND COALESCE(captured_at, created_at) >= ? AND COALESCE(captured_at, created_at) < ?"+
+				" AND (COALESCE(captured_at, created_at), id) > (?, ?)",
+			userID, from, to, afterAt, afterID,
+		).
+		Order("COALESCE(captured_at, created_at) ASC, id ASC").
+		Limit(limit).
+		Find(&locations).Error
+	return locations, err
+}
+
+// GetOlderThan — точки пользователя (включая маскированные) с временем фиксации раньше
+// before, с id больше afterID, по возрастанию id.
+func (dao *LocationDAO) GetOlderThan(userID int, before time.Time, afterID, limit int) ([]models.Location, error) {
+	var locations []models.Location
+	err := dao.DB.
+		Where("user_id = ? AND COALESCE(captured_at, created_at) < ? AND id > ?", userID, before, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&locations).Error
+	return locations, err
+}
+
+// DeleteByIDs удаляет точки по id и возвращает число удалённых.
+func (dao *LocationDAO) DeleteByIDs(ids []int) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	res := dao.DB.Where("id IN ?", ids).Delete(&models.Location{})
+	return res.RowsAffected, res.Error
+}
+
+// MarkDownsampled помечает оставшиеся после прореживания точки.
+func (dao *LocationDAO) MarkDownsampled(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return dao.DB.Model(&models.Location{}).Where("id IN ?", ids).Update("downsampled", true).Error
+}
@@ -7,13 +7,16 @@ type LatestLocationAge struct {
 }
 
 // GetLatestAgePerUser возвращает age_seconds последней точки для каждого user_id (один SQL).
-func (dao *LocationDAO) GetLatestAgePerUser() ([]LatestLocationAge, error) {
+// organizationID = 0 — по всем организациям (движок алертов). Маскированные точки
+// учитываются: они подтверждают, что устройство на связи.
+func (dao *LocationDAO) GetLatestAgePerUser(organizationID int) ([]LatestLocationAge, error) {
 	var rows []LatestLocationAge
 	err := dao.DB.Raw(`
 		SELECT DISTINCT ON (user_id) user_id,
 			GREATEST(0, EXTRACT(EPOCH FROM (NOW() - COALESCE(captured_at, created_at))))::bigint AS age_seconds
 		FROM locations
+		WHERE ? = 0 OR user_id IN (SELECT id FROM users WHERE organization_id = ?)
 		ORDER BY user_id, COALESCE(captured_at, created_at) DESC
-	`).Scan(&rows).Error
+	`, organizationID, organizationID).Scan(&rows).Error
 	return rows, err
 }
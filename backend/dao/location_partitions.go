@@ -0,0 +1,120 @@
+package dao
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+// Месячные секции locations (миграция 20261019220000_partition_locations): locations_pYYYYMM
+// по COALESCE(captured_at, created_at), остальное — в locations_default. Если таблица не
+// секционирована (база тестов, поднятая AutoMigrate), методы ничего не делают.
+
+// IsPartitioned — секционирована ли таблица locations.
+func (dao *LocationDAO) IsPartitioned() (bool, error) {
+	var kind string
+	err := dao.DB.Raw("SELECT relkind::text FROM pg_class WHERE oid = to_regclass('locations')").Scan(&kind).Error
+	return kind == "p", err
+}
+
+// EnsurePartitions создаёт недостающие месячные секции с from по to включительно и
+// возвращает их число.
+func (dao *LocationDAO) EnsurePartitions(from, to time.Time) (int, error) {
+	partitioned, err := dao.IsPartitioned()
+	if err != nil || !partitioned {
+		return 0, err
+	}
+	var created int
+	err = dao.DB.Raw("SELECT locations_ensure_partitions(?::date, ?::date)",
+		from.UTC().Format("2006-01-02"), to.UTC().Format("2006-01-02")).Scan(&created).Error
+	return created, err
+}
+
+// ExpiredPartitions — месячные секции, целиком закончившиеся не позже before, в том
+// числе уже отсоединённые прерванным проходом хранения.
+func (dao *LocationDAO) ExpiredPartitions(before time.Time) ([]string, error) {
+	partitioned, err := dao.IsPartitioned()
+	if err != nil || !partitioned {
+		return nil, err
+	}
+	var names []string
+	err = dao.DB.Raw(`
+		SELECT relname FROM pg_class
+		WHERE relkind = 'r' AND relname ~ '^locations_p[0-9]{6}$' AND pg_table_is_visible(oid)
+		ORDER BY relname
+	`).Scan(&names).Error
+	if err != nil {
+		return nil, err
+	}
+	var expired []string
+	for _, name := range names {
+		month, err := time.Parse("200601", strings.TrimPrefix(name, "locations_p"))
+		if err != nil || month.AddDate(0, 1, 0).After(before) {
+			continue
+		}
+		expired = append(expired, name)
+	}
+	return expired, nil
+}
+
+// DetachPartition отсоединяет секцию от locations: её точки пропадают из выборок, но
+// остаются в отдельной таблице до DropPartition. Уже отсоединённая секция не ошибка.
+func (dao *LocationDAO) DetachPartition(name string) error {
+	var attached bool
+	err := dao.DB.Raw(
+		"SELECT EXISTS (SELECT 1 FROM pg_inherits WHERE inhrelid = to_regclass(?) AND inhparent = 'locations'::regclass)",
+		name).Scan(&attached).Error
+	if err != nil || !attached {
+		return err
+	}
+	return dao.DB.Exec(fmt.Sprintf("ALTER TABLE locations DETACH PARTITION %q", name)).Error
+}
+
+// LatestInPartition — точки отсоединённой секции, которые остаются последними
+// (немаскированными) точками своих пользователей: их удалять нельзя.
+func (dao *LocationDAO) LatestInPartition(name string) ([]int, error) {
+	var ids []int
+	err := dao.DB.Raw(fmt.Sprintf(`
+		SELECT DISTINCT ON (p.user_id) p.id
+		FROM %q p
+		WHERE NOT p.masked AND NOT EXISTS (
+			SELECT 1 FROM locations l
+			WHERE l.user_id = p.user_id AND NOT l.masked
+				AND COALESCE(l.captured_at, l.created_at) > COALESCE(p.captured_at, p.created_at)
+		)
+		ORDER BY p.user_id, COALESCE(p.captured_at, p.created_at) DESC, p.id DESC
+	`, name)).Scan(&ids).Error
+	return ids, err
+}
+
+// PartitionLocations — точки отсоединённой секции по возрастанию id, не больше limit
+// после afterID (для архивирования).
+func (dao *LocationDAO) PartitionLocations(name string, afterID, limit int) ([]models.Location, error) {
+	var points []models.Location
+	err := dao.DB.Table(name).Where("id > ?", afterID).Order("id").Limit(limit).Find(&points).Error
+	return points, err
+}
+
+// DropPartition удаляет отсоединённую секцию; точки keep в той же транзакции
+// возвращаются в locations (их месяц уже без секции — они попадут в locations_default).
+func (dao *LocationDAO) DropPartition(name string, keep []int) error {
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		if len(keep) > 0 {
+			const columns = "id, user_id, latitude, longitude, request_id, source, captured_at, masked, downsampled, created_at, updated_at"
+			err := tx.Exec(fmt.Sprintf("INSERT INTO locations (%s) SELECT %s FROM %q WHERE id IN ?", columns, columns, name), keep).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec(fmt.Sprintf("DROP TABLE %q", name)).Error; err != nil {
+			return err
+		}
+		slog.Info("Удалена секция locations", "partition", name, "kept", len(keep))
+		return nil
+	})
+}
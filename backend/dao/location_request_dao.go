@@ -63,3 +63,13 @@ func (dao *LocationRequestDAO) CancelPendingForUser(userID int, excludeID string
 	}
 	return q.Update("status", models.LocationRequestStatusExpired).Error
 }
+
+// GetExpiredSince — просроченные (без ответа) запросы, созданные не раньше since.
+func (dao *LocationRequestDAO) GetExpiredSince(since time.Time) ([]models.LocationRequest, error) {
+	var reqs []models.LocationRequest
+	err := dao.DB.
+		Where("status = ? AND completed_at IS NULL AND created_at >= ?", models.LocationRequestStatusExpired, since).
+		Order("created_at ASC").
+		Find(&reqs).Error
+	return reqs, err
+}
@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+// NotificationDAO — подписки на уведомления и журнал доставки.
+type NotificationDAO struct {
+	DB *gorm.DB
+}
+
+func NewNotificationDAO(db *gorm.DB) *NotificationDAO {
+	return &NotificationDAO{DB: db}
+}
+
+func (dao *NotificationDAO) CreateSubscription(sub *models.NotificationSubscription) error {
+	return dao.DB.Create(sub).Error
+}
+
+func (dao *NotificationDAO) UpdateSubscription(sub *models.NotificationSubscription) error {
+	return dao.DB.Save(sub).Error
+}
+
+func (dao *NotificationDAO) DeleteSubscription(id int) error {
+	return dao.DB.Delete(&models.NotificationSubscription{}, id).Error
+}
+
+func (dao *NotificationDAO) GetSubscriptionByID(id int) (*models.NotificationSubscription, error) {
+	var sub models.NotificationSubscription
+	if err := dao.DB.First(&sub, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// GetSubscriptionsByUser — подписки пользователя; userID = 0 — все подписки.
+func (dao *NotificationDAO) GetSubscriptionsByUser(userID int) ([]models.NotificationSubscription, error) {
+	var subs []models.NotificationSubscription
+	q := dao.DB.Order("id ASC")
+	if userID != 0 {
+		q = q.Where("user_id = ?", userID)
+	}
+	err := q.Find(&subs).Error
+	return subs, err
+}
+
+func (dao *NotificationDAO) GetEnabledSubscriptions() ([]models.NotificationSubscription, error) {
+	var subs []models.NotificationSubscription
+	err := dao.DB.Where("enabled = ?", true).Order("id ASC").Find(&subs).Error
+	return subs, err
+}
+
+func (dao *NotificationDAO) CreateDelivery(d *models.NotificationDelivery) error {
+	return dao.DB.Create(d).Error
+}
+
+// GetDeliveries — последние записи журнала; userID = 0 — по всем пользователям.
+func (dao *NotificationDAO) GetDeliveries(userID, limit int) ([]models.NotificationDelivery, error) {
+	var items []models.NotificationDelivery
+	q := dao.DB.Order("created_at DESC, id DESC").Limit(limit)
+	if userID != 0 {
+		q = q.Where("user_id = ?", userID)
+	}
+	err := q.Find(&items).Error
+	return items, err
+}
@@ -0,0 +1,38 @@
+package dao
+
+import (
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+// OrganizationDAO — клиентские организации (тенанты).
+type OrganizationDAO struct {
+	DB *gorm.DB
+}
+
+func NewOrganizationDAO(db *gorm.DB) *OrganizationDAO {
+	return &OrganizationDAO{DB: db}
+}
+
+func (dao *OrganizationDAO) CreateOrganization(org *models.Organization) error {
+	return dao.DB.Create(org).Error
+}
+
+func (dao *OrganizationDAO) GetOrganizationByID(id int) (*models.Organization, error) {
+	var org models.Organization
+	if err := dao.DB.First(&org, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (dao *OrganizationDAO) UpdateOrganization(org *models.Organization) error {
+	return dao.DB.Save(org).Error
+}
+
+func (dao *OrganizationDAO) GetAllOrganizations() ([]models.Organization, error) {
+	var orgs []models.Organization
+	err := dao.DB.Order("id ASC").Find(&orgs).Error
+	return orgs, err
+}
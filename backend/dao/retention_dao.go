@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+// RetentionDAO — сроки хранения точек по организациям и проходы хранения.
+type RetentionDAO struct {
+	DB *gorm.DB
+}
+
+func NewRetentionDAO(db *gorm.DB) *RetentionDAO {
+	return &RetentionDAO{DB: db}
+}
+
+func (dao *RetentionDAO) GetPolicy(organizationID int) (*models.RetentionPolicy, error) {
+	var p models.RetentionPolicy
+	if err := dao.DB.First(&p, "organization_id = ?", organizationID).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SavePolicy создаёт или перезаписывает политику организации.
+func (dao *RetentionDAO) SavePolicy(p *models.RetentionPolicy) error {
+	return dao.DB.Save(p).Error
+}
+
+func (dao *RetentionDAO) DeletePolicy(organizationID int) error {
+	return dao.DB.Delete(&models.RetentionPolicy{}, "organization_id = ?", organizationID).Error
+}
+
+func (dao *RetentionDAO) CreateRun(run *models.RetentionRun) error {
+	return dao.DB.Create(run).Error
+}
+
+func (dao *RetentionDAO) UpdateRun(run *models.RetentionRun) error {
+	return dao.DB.Save(run).Error
+}
+
+func (dao *RetentionDAO) GetRun(id int64) (*models.RetentionRun, error) {
+	var run models.RetentionRun
+	if err := dao.DB.First(&run, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns — последние проходы, новые первыми.
+func (dao *RetentionDAO) ListRuns(limit int) ([]models.RetentionRun, error) {
+	var runs []models.RetentionRun
+	err := dao.DB.Order("id DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
@@ -0,0 +1,91 @@
+package dao
+
+import (
+	"time"
+
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+// SessionDAO — сессии входа сотрудников и одноразовые ссылки для входа.
+type SessionDAO struct {
+	DB *gorm.DB
+}
+
+func NewSessionDAO(db *gorm.DB) *SessionDAO {
+	return &SessionDAO{DB: db}
+}
+
+func (dao *SessionDAO) CreateSession(session *models.UserSession) error {
+	return dao.DB.Create(session).Error
+}
+
+func (dao *SessionDAO) UpdateSession(session *models.UserSession) error {
+	return dao.DB.Save(session).Error
+}
+
+// RotateRefreshToken заменяет refresh-токен сессии, только если в БД всё ещё
+// previousHash и сессия не отозвана; false — токен уже заменил параллельный запрос.
+func (dao *SessionDAO) RotateRefreshToken(session *models.UserSession, previousHash string) (bool, error) {
+	res := dao.DB.Model(&models.UserSession{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, previousHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": session.RefreshTokenHash,
+			"expires_at":         session.ExpiresAt,
+			"last_used_at":       session.LastUsedAt,
+			"user_agent":         session.UserAgent,
+			"ip":                 session.IP,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+// RevokeSession отзывает сессию, не перезаписывая остальные поля.
+func (dao *SessionDAO) RevokeSession(id string, at time.Time) error {
+	return dao.DB.Model(&models.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+func (dao *SessionDAO) GetSessionByID(id string) (*models.UserSession, error) {
+	var session models.UserSession
+	if err := dao.DB.First(&session, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetSessionsByUser — сессии пользователя, новые первыми.
+func (dao *SessionDAO) GetSessionsByUser(userID int) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	err := dao.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeUserSessions отзывает все ещё не отозванные сессии пользователя.
+func (dao *SessionDAO) RevokeUserSessions(userID int, at time.Time) error {
+	return dao.DB.Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+func (dao *SessionDAO) CreateLoginLink(link *models.LoginLink) error {
+	return dao.DB.Create(link).Error
+}
+
+// ConsumeLoginLink помечает ссылку использованной одним условным UPDATE; false —
+// ссылку уже израсходовал параллельный вход или она истекла.
+func (dao *SessionDAO) ConsumeLoginLink(id int, at time.Time) (bool, error) {
+	res := dao.DB.Model(&models.LoginLink{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", id, at).
+		Update("used_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (dao *SessionDAO) GetLoginLinkByHash(tokenHash string) (*models.LoginLink, error) {
+	var link models.LoginLink
+	if err := dao.DB.First(&link, "token_hash = ?", tokenHash).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
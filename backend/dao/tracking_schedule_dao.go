@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"locator/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TrackingScheduleDAO — графики приватности (окна отслеживания и согласие).
+type TrackingScheduleDAO struct {
+	DB *gorm.DB
+}
+
+func NewTrackingScheduleDAO(db *gorm.DB) *TrackingScheduleDAO {
+	return &TrackingScheduleDAO{DB: db}
+}
+
+func (dao *TrackingScheduleDAO) GetSchedule(userID int) (*models.TrackingSchedule, error) {
+	var s models.TrackingSchedule
+	if err := dao.DB.First(&s, "user_id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SaveSchedule создаёт или полностью перезаписывает график пользователя.
+func (dao *TrackingScheduleDAO) SaveSchedule(s *models.TrackingSchedule) error {
+	return dao.DB.Save(s).Error
+}
+
+func (dao *TrackingScheduleDAO) DeleteSchedule(userID int) error {
+	return dao.DB.Delete(&models.TrackingSchedule{}, "user_id = ?", userID).Error
+}
+
+// GetEnabledSchedules — включённые графики всех организаций (фоновый цикл).
+func (dao *TrackingScheduleDAO) GetEnabledSchedules() ([]models.TrackingSchedule, error) {
+	var items []models.TrackingSchedule
+	if err := dao.DB.Where("enabled").Order("user_id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// MarkPushed запоминает состояние паузы, отправленное телефону.
+func (dao *TrackingScheduleDAO) MarkPushed(userID int, paused bool, at time.Time) error {
+	return dao.DB.Model(&models.TrackingSchedule{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"last_pushed_paused": paused,
+		"last_pushed_at":     at,
+	}).Error
+}
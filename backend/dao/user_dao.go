@@ -33,7 +33,48 @@ func (dao *UserDAO) GetByID(id int) (*models.User, error) {
 	return &user, nil
 }
 
-// GetAll возвращает список всех пользователей.
+// GetByUsername возвращает сотрудника по логину для входа в веб-интерфейс.
+func (dao *UserDAO) GetByUsername(username string) (*models.User, error) {
+	var user models.User
+	if err := dao.DB.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UseTOTPStep атомарно запоминает принятый шаг TOTP; false — этот или более
+// поздний шаг уже использован (повтор кода). Колонка только для чтения в модели,
+// поэтому запрос пишется напрямую.
+func (dao *UserDAO) UseTOTPStep(userID int, step int64) (bool, error) {
+	res := dao.DB.Exec("UPDATE users SET totp_last_step = ?, updated_at = ? WHERE id = ? AND totp_last_step < ?",
+		step, dao.DB.NowFunc(), userID, step)
+	return res.RowsAffected == 1, res.Error
+}
+
+// GetAllByOrganization возвращает пользователей организации.
+func (dao *UserDAO) GetAllByOrganization(organizationID int) ([]models.User, error) {
+	var users []models.User
+	if err := dao.DB.Where("organization_id = ?", organizationID).Order("id ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetIDsByOrganization возвращает ID пользователей организации; groupID != nil —
+// только участников группы.
+func (dao *UserDAO) GetIDsByOrganization(organizationID int, groupID *int) ([]int, error) {
+	query := dao.DB.Model(&models.User{}).Where("organization_id = ?", organizationID)
+	if groupID != nil {
+		query = query.Where("group_id = ?", *groupID)
+	}
+	var ids []int
+	if err := query.Order("id ASC").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetAll возвращает список всех пользователей (всех организаций — для аутентификации и фоновых задач).
 func (dao *UserDAO) GetAll() ([]models.User, error) {
 	var users []models.User
 	if err := dao.DB.Order("id ASC").Find(&users).Error; err != nil {
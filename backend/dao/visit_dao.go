@@ -12,6 +12,31 @@ type VisitDAO struct {
 	DB *gorm.DB
 }
 
+// VisitStore — операции с визитами, доступные внутри InUserLock.
+type VisitStore interface {
+	Create(visit *models.Visit) error
+	Update(visit *models.Visit) error
+	Delete(id int64) error
+	GetActiveVisit(userID int, checkpointID int) (*models.Visit, error)
+	GetVisits(organizationID int, filters map[string]interface{}, activeOnly bool, rangeFrom, rangeTo *time.Time) ([]models.Visit, error)
+}
+
+// visitLockSpace — первый ключ advisory-блокировки визитов пользователя (второй — ID
+// пользователя), чтобы не пересекаться с блокировкой миграций.
+const visitLockSpace = 0x76697369
+
+// InUserLock выполняет fn в одной транзакции под advisory-блокировкой визитов
+// пользователя: обработчик событий и пересборка визитов не меняют визиты одного
+// пользователя одновременно. Ошибка fn откатывает все изменения.
+func (dao *VisitDAO) InUserLock(userID int, fn func(tx VisitStore) error) error {
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", visitLockSpace, userID).Error; err != nil {
+			return err
+		}
+		return fn(&VisitDAO{DB: tx})
+	})
+}
+
 func NewVisitDAO(db *gorm.DB) *VisitDAO {
 	return &VisitDAO{DB: db}
 }
@@ -53,16 +78,17 @@ func (dao *VisitDAO) GetVisitsByUser(userID int) ([]models.Visit, error) {
 	return visits, nil
 }
 
-// GetVisits возвращает список визитов с применением фильтров.
+// GetVisits возвращает список визитов пользователей организации с применением фильтров.
 // Параметр filters может содержать ключи: "id", "user_id", "checkpoint_id" и т.д.
 // При activeOnly выбираются только незавершённые визиты (end_at IS NULL).
 func (dao *VisitDAO) GetVisits(
+	organizationID int,
 	filters map[string]interface{},
 	activeOnly bool,
 	rangeFrom, rangeTo *time.Time,
 ) ([]models.Visit, error) {
 	var visits []models.Visit
-	query := dao.DB
+	query := dao.DB.Where("user_id IN (SELECT id FROM users WHERE organization_id = ?)", organizationID)
 	for key, value := range filters {
 		query = query.Where(key+" = ?", value)
 	}
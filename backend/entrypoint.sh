@@ -6,5 +6,5 @@ until psql "$DATABASE_URL" -c '\q'; do
   sleep 2
 done
 
-goose -dir /migrations postgres "$DATABASE_URL" up
+/app/migrate up
 exec su-exec postgres /app/locator
@@ -2,11 +2,17 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"locator/client"
 )
 
 func TestHealthz(t *testing.T) {
@@ -19,6 +25,38 @@ func TestHealthz(t *testing.T) {
 	}
 }
 
+func TestReadyz_checksAndDraining(t *testing.T) {
+	env := setupEnv(t)
+	w := httptest.NewRecorder()
+	env.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"database":"ok"`) {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	// Текст ошибки проверки в ответ не попадает.
+	env.Health.Checks["broker"] = func(context.Context) error {
+		return errors.New("dial tcp rabbitmq.internal:5672: connection refused")
+	}
+	w = httptest.NewRecorder()
+	env.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"broker":"unavailable"`) ||
+		strings.Contains(w.Body.String(), "rabbitmq.internal") {
+		t.Fatalf("failed check: status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	env.Health.SetDraining()
+	w = httptest.NewRecorder()
+	env.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("draining: status=%d body=%s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	env.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("livez while draining: status=%d", w.Code)
+	}
+}
+
 func TestAuth_usersMe(t *testing.T) {
 	env := setupEnv(t)
 
@@ -38,14 +76,324 @@ func TestAuth_usersMe(t *testing.T) {
 	}
 }
 
-func TestAuth_deviceForbiddenOnAdminRoute(t *testing.T) {
+func TestAuth_apiKeyRejectedOnAdminRoute(t *testing.T) {
 	env := setupEnv(t)
-	w := httptest.NewRecorder()
-	req := httptest.NewRequest(http.MethodGet, "/api/checkpoint/", nil)
-	req.Header.Set("X-API-Key", env.DeviceKey)
-	env.Router.ServeHTTP(w, req)
-	if w.Code != http.StatusForbidden {
-		t.Fatalf("expected 403, got %d body=%s", w.Code, w.Body.String())
+	// API-ключи (и устройства, и администратора) на административных маршрутах не принимаются.
+	for _, key := range []string{env.DeviceKey, env.AdminKey} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/checkpoint/", nil)
+		req.Header.Set("X-API-Key", key)
+		env.Router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d body=%s", w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAuth_sessionLoginRefreshLogout(t *testing.T) {
+	env := setupEnv(t)
+
+	post := func(path, token string, body interface{}) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+	get := func(path, token string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		env.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if w := post("/api/auth/login", "", map[string]string{"username": "it-admin", "password": "wrong-password"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: %d %s", w.Code, w.Body.String())
+	}
+
+	w := post("/api/auth/login", "", map[string]string{"username": "IT-Admin", "password": env.AdminPassword})
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", w.Code, w.Body.String())
+	}
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil {
+		t.Fatal(err)
+	}
+	if code := get("/api/checkpoint/", tokens.AccessToken); code != http.StatusOK {
+		t.Fatalf("checkpoint with session: %d", code)
+	}
+
+	w = post("/api/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken})
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh: %d %s", w.Code, w.Body.String())
+	}
+	var refreshed struct {
+		AccessToken string `json:"access_token"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &refreshed)
+
+	if w := post("/api/auth/logout", refreshed.AccessToken, nil); w.Code != http.StatusOK {
+		t.Fatalf("logout: %d %s", w.Code, w.Body.String())
+	}
+	if code := get("/api/checkpoint/", refreshed.AccessToken); code != http.StatusUnauthorized {
+		t.Fatalf("after logout expected 401, got %d", code)
+	}
+	// Сессия администратора из харнесса не затронута.
+	if code := get("/api/checkpoint/", env.AdminToken); code != http.StatusOK {
+		t.Fatalf("other session: %d", code)
+	}
+}
+
+func TestAPIKeys_issueUseRevoke(t *testing.T) {
+	env := setupEnv(t)
+
+	do := func(method, path string, header map[string]string, body interface{}) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+	admin := map[string]string{"Authorization": "Bearer " + env.AdminToken}
+	keysPath := "/api/admin/users/" + itoa(env.Device.ID) + "/api-keys"
+
+	w := do(http.MethodPost, keysPath, admin, map[string]string{"label": "script"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Key struct {
+			ID int `json:"id"`
+		} `json:"key"`
+		APIKey string `json:"api_key"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+
+	// Оба ключа устройства действуют одновременно.
+	for _, key := range []string{env.DeviceKey, created.APIKey} {
+		if w := do(http.MethodGet, "/api/users/me", map[string]string{"X-API-Key": key}, nil); w.Code != http.StatusOK {
+			t.Fatalf("users/me: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	w = do(http.MethodGet, keysPath, admin, nil)
+	var keys []struct {
+		ID         int     `json:"id"`
+		LastUsedAt *string `json:"last_used_at"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &keys); err != nil || len(keys) != 2 {
+		t.Fatalf("list: %d %s", w.Code, w.Body.String())
+	}
+	for _, k := range keys {
+		if k.LastUsedAt == nil {
+			t.Fatalf("last use not recorded for key %d", k.ID)
+		}
+	}
+
+	if w := do(http.MethodDelete, keysPath+"/"+itoa(created.Key.ID), admin, nil); w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/api/users/me", map[string]string{"X-API-Key": created.APIKey}, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked key: expected 401, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/users/me", map[string]string{"X-API-Key": env.DeviceKey}, nil); w.Code != http.StatusOK {
+		t.Fatalf("device key after revoking another: %d", w.Code)
+	}
+}
+
+func TestClient_generatedClientAgainstRouter(t *testing.T) {
+	env := setupEnv(t)
+	ctx := context.Background()
+
+	anon := env.Client(t)
+	if _, err := anon.GetCurrentUser(ctx); client.StatusCode(err) != http.StatusUnauthorized {
+		t.Fatalf("anonymous users/me: %v", err)
+	}
+	tokens, err := anon.Login(ctx, client.LoginRequest{Username: "it-admin", Password: env.AdminPassword})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	admin := env.Client(t)
+	admin.Token = tokens.AccessToken
+	created, err := admin.CreateUserAPIKey(ctx, env.Device.ID, client.APIKeyInput{Label: "client"})
+	if err != nil || created.APIKey == "" || created.Key == nil {
+		t.Fatalf("create key: %+v %v", created, err)
+	}
+
+	device := env.Client(t)
+	device.APIKey = created.APIKey
+	me, err := device.GetCurrentUser(ctx)
+	if err != nil || me.ID != env.Device.ID {
+		t.Fatalf("users/me: %+v %v", me, err)
+	}
+
+	keys, err := admin.ListUserAPIKeys(ctx, env.Device.ID)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("list keys: %+v %v", keys, err)
+	}
+}
+
+func TestAudit_adminActionRecordedWithoutSecrets(t *testing.T) {
+	env := setupEnv(t)
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+env.AdminToken)
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	configPath := "/api/admin/users/" + itoa(env.Device.ID) + "/device/desired-config"
+	w := do(http.MethodPut, configPath, map[string]interface{}{
+		"admin_pin": "4321", "api_key": "secret-device-key", "poll_interval_seconds": 60,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("desired-config: %d %s", w.Code, w.Body.String())
+	}
+
+	w = do(http.MethodGet, "/api/admin/audit?target_type=user&target_id="+itoa(env.Device.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("audit: %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "4321") || strings.Contains(w.Body.String(), "secret-device-key") {
+		t.Fatalf("secrets leaked into audit log: %s", w.Body.String())
+	}
+	var page struct {
+		Entries []struct {
+			Action  string `json:"action"`
+			ActorID *int   `json:"actor_id"`
+			Status  int    `json:"status"`
+		} `json:"entries"`
+		Total int `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || page.Total != 1 {
+		t.Fatalf("audit page: %s", w.Body.String())
+	}
+	got := page.Entries[0]
+	if got.Action != "PUT /api/admin/users/:id/device/desired-config" || got.ActorID == nil || *got.ActorID != env.Admin.ID {
+		t.Fatalf("unexpected entry: %+v", got)
+	}
+
+	w = do(http.MethodGet, "/api/admin/audit/export?format=csv&action=PUT", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "desired-config") {
+		t.Fatalf("export: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTrackingSchedule_consentGatesLocations(t *testing.T) {
+	env := setupEnv(t)
+
+	do := func(method, path, authHeader, token string, body interface{}) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(authHeader, token)
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+	admin := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		return do(method, path, "Authorization", "Bearer "+env.AdminToken, body)
+	}
+	postLocation := func() map[string]interface{} {
+		w := do(http.MethodPost, "/api/location", "X-API-Key", env.DeviceKey, map[string]interface{}{
+			"latitude": 53.9, "longitude": 27.5, "source": "periodic",
+		})
+		if w.Code != http.StatusOK && w.Code != http.StatusCreated {
+			t.Fatalf("POST location status=%d body=%s", w.Code, w.Body.String())
+		}
+		var out map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return out
+	}
+
+	schedulePath := "/api/admin/users/" + itoa(env.Device.ID) + "/tracking-schedule"
+	w := admin(http.MethodPut, schedulePath, map[string]interface{}{"consent_required": true})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"reason":"consent"`) {
+		t.Fatalf("put schedule: %d %s", w.Code, w.Body.String())
+	}
+	if got := postLocation(); got["reason"] != "privacy_window" {
+		t.Fatalf("expected privacy_window skip, got %v", got)
+	}
+
+	w = admin(http.MethodPost, "/api/admin/users/"+itoa(env.Device.ID)+"/tracking-consent", map[string]interface{}{
+		"given": true, "by": "Иванов И. И.",
+	})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"paused":false`) {
+		t.Fatalf("consent: %d %s", w.Code, w.Body.String())
+	}
+	if got := postLocation(); got["skipped"] != nil {
+		t.Fatalf("location skipped after consent: %v", got)
+	}
+
+	w = admin(http.MethodDelete, schedulePath, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete schedule: %d %s", w.Code, w.Body.String())
+	}
+	if w = admin(http.MethodGet, schedulePath, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("get after delete: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTimezone_userZoneAndQueryOverride(t *testing.T) {
+	env := setupEnv(t)
+
+	do := func(method, path, key string, body interface{}) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPut, "/api/users/"+itoa(env.Admin.ID), env.AdminKey, map[string]interface{}{"timezone": "Asia/Tokyo"})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"timezone":"Asia/Tokyo"`) {
+		t.Fatalf("set user timezone: %d %s", w.Code, w.Body.String())
+	}
+	if w = do(http.MethodPut, "/api/users/"+itoa(env.Admin.ID), env.AdminKey, map[string]interface{}{"timezone": "Mars/Olympus"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown timezone: %d %s", w.Code, w.Body.String())
+	}
+	if w = do(http.MethodGet, "/api/users/me", env.AdminKey, nil); !strings.Contains(w.Body.String(), `"timezone":"Asia/Tokyo"`) {
+		t.Fatalf("users/me: %s", w.Body.String())
+	}
+
+	w = do(http.MethodPost, "/api/location", env.DeviceKey, map[string]interface{}{
+		"latitude": 53.9, "longitude": 27.5, "source": "periodic",
+	})
+	if w.Code != http.StatusOK && w.Code != http.StatusCreated {
+		t.Fatalf("POST location: %d %s", w.Code, w.Body.String())
+	}
+
+	now := time.Now().UTC()
+	window := "from=" + now.AddDate(0, 0, -1).Format("2006-01-02") + "&to=" + now.AddDate(0, 0, 1).Format("2006-01-02")
+	w = do(http.MethodGet, "/api/location/?raw=1&"+window, env.AdminKey, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "+09:00") {
+		t.Fatalf("locations in user zone: %d %s", w.Code, w.Body.String())
+	}
+	w = do(http.MethodGet, "/api/location/?raw=1&tz=America/Sao_Paulo&"+window, env.AdminKey, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "-03:00") {
+		t.Fatalf("locations with tz override: %d %s", w.Code, w.Body.String())
+	}
+	if w = do(http.MethodGet, "/api/location/?tz=Nowhere&"+window, env.AdminKey, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown tz: %d %s", w.Code, w.Body.String())
 	}
 }
 
@@ -91,7 +439,7 @@ func TestCheckpoint_CRUD(t *testing.T) {
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/api/checkpoint/", bytes.NewReader(raw))
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", env.AdminKey)
+	req.Header.Set("Authorization", "Bearer "+env.AdminToken)
 	env.Router.ServeHTTP(w, req)
 	if w.Code != http.StatusOK && w.Code != http.StatusCreated {
 		t.Fatalf("create checkpoint status=%d body=%s", w.Code, w.Body.String())
@@ -99,7 +447,7 @@ func TestCheckpoint_CRUD(t *testing.T) {
 
 	w2 := httptest.NewRecorder()
 	req2 := httptest.NewRequest(http.MethodGet, "/api/checkpoint/", nil)
-	req2.Header.Set("X-API-Key", env.AdminKey)
+	req2.Header.Set("Authorization", "Bearer "+env.AdminToken)
 	env.Router.ServeHTTP(w2, req2)
 	if w2.Code != http.StatusOK {
 		t.Fatalf("list checkpoints status=%d", w2.Code)
@@ -117,7 +465,7 @@ func TestVisits_listEmpty(t *testing.T) {
 	env := setupEnv(t)
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/api/visits/?user_id="+itoa(env.Device.ID), nil)
-	req.Header.Set("X-API-Key", env.AdminKey)
+	req.Header.Set("Authorization", "Bearer "+env.AdminToken)
 	env.Router.ServeHTTP(w, req)
 	if w.Code != http.StatusOK {
 		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
@@ -140,7 +488,7 @@ func TestDevice_pollEmptyThenEnqueueAndPoll(t *testing.T) {
 	w2 := httptest.NewRecorder()
 	req2 := httptest.NewRequest(http.MethodPost, "/api/admin/users/"+itoa(env.Device.ID)+"/commands", bytes.NewReader(raw))
 	req2.Header.Set("Content-Type", "application/json")
-	req2.Header.Set("X-API-Key", env.AdminKey)
+	req2.Header.Set("Authorization", "Bearer "+env.AdminToken)
 	env.Router.ServeHTTP(w2, req2)
 	if w2.Code != http.StatusAccepted && w2.Code != http.StatusOK {
 		t.Fatalf("enqueue status=%d body=%s", w2.Code, w2.Body.String())
@@ -204,7 +552,7 @@ func TestVisitEventProcessor_enterExit(t *testing.T) {
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/api/checkpoint/", bytes.NewReader(raw))
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", env.AdminKey)
+	req.Header.Set("Authorization", "Bearer "+env.AdminToken)
 	env.Router.ServeHTTP(w, req)
 	if w.Code != http.StatusOK && w.Code != http.StatusCreated {
 		t.Fatalf("checkpoint: %s", w.Body.String())
@@ -214,7 +562,7 @@ func TestVisitEventProcessor_enterExit(t *testing.T) {
 	// here we verify visits endpoint still works after DB seed.
 	w2 := httptest.NewRecorder()
 	req2 := httptest.NewRequest(http.MethodGet, "/api/visits/?user_id="+itoa(env.Device.ID)+"&active=true", nil)
-	req2.Header.Set("X-API-Key", env.AdminKey)
+	req2.Header.Set("Authorization", "Bearer "+env.AdminToken)
 	env.Router.ServeHTTP(w2, req2)
 	if w2.Code != http.StatusOK {
 		t.Fatalf("visits: %d %s", w2.Code, w2.Body.String())
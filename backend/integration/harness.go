@@ -1,16 +1,20 @@
 package integration
 
 import (
+	"context"
 	"fmt"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"locator/client"
 	"locator/config/messaging"
 	"locator/controllers"
 	"locator/dao"
 	"locator/models"
 	"locator/router"
+	"locator/seed"
 	"locator/service"
 
 	"github.com/gin-gonic/gin"
@@ -28,6 +32,19 @@ type TestEnv struct {
 	Device    models.User
 	AdminKey  string
 	DeviceKey string
+	// AdminToken — access-токен сессии администратора для административных маршрутов.
+	AdminToken string
+	// AdminPassword — пароль администратора (логин it-admin).
+	AdminPassword string
+	Health        *controllers.HealthController
+}
+
+// Client returns a generated API client talking to env.Router over a local HTTP server.
+func (e *TestEnv) Client(t *testing.T) *client.Client {
+	t.Helper()
+	srv := httptest.NewServer(e.Router)
+	t.Cleanup(srv.Close)
+	return client.New(srv.URL)
 }
 
 func requireIntegration(t *testing.T) {
@@ -77,11 +94,30 @@ func setupEnv(t *testing.T) *TestEnv {
 
 	db := openTestDB(t)
 	if err := db.AutoMigrate(
+		&models.Organization{},
+		&models.Group{},
 		&models.User{},
+		&models.APIKey{},
+		&models.UserSession{},
+		&models.LoginLink{},
+		&models.AuditEntry{},
+		&models.TrackingSchedule{},
+		&models.RetentionPolicy{},
+		&models.RetentionRun{},
+		// В тестах locations — обычная таблица без секций: DAO работает с обеими.
 		&models.Location{},
 		&models.LocationRequest{},
 		&models.DeviceCommand{},
 		&models.DeviceReport{},
+		&models.DeviceConfigProfile{},
+		&models.DeviceDesiredConfig{},
+		&models.AlertRule{},
+		&models.Alert{},
+		&models.NotificationSubscription{},
+		&models.NotificationDelivery{},
+		&models.AppRelease{},
+		&models.AppReleaseChannel{},
+		&models.AppReleaseMember{},
 		&models.Checkpoint{},
 		&models.Visit{},
 	); err != nil {
@@ -90,10 +126,14 @@ func setupEnv(t *testing.T) *TestEnv {
 
 	// Isolate each test run: wipe domain tables (keep schema).
 	for _, table := range []string{
-		"visits", "locations", "location_requests", "device_commands", "device_reports", "checkpoints", "users",
+		"visits", "locations", "location_requests", "device_commands", "device_reports",
+		"device_desired_configs", "device_config_profiles", "alerts", "alert_rules", "notification_deliveries", "notification_subscriptions",
+		"app_releases", "app_release_channels", "app_release_members", "checkpoints", "user_sessions", "login_links", "api_keys", "audit_log", "tracking_schedules", "retention_policies", "retention_runs", "users", "groups",
+		"organizations",
 	} {
 		_ = db.Exec("TRUNCATE TABLE " + table + " RESTART IDENTITY CASCADE").Error
 	}
+	seed.DefaultOrganization(db)
 
 	wd, _ := os.Getwd()
 	// Ensure QR dir exists relative to backend or repo root when tests run from integration/
@@ -112,14 +152,28 @@ func setupEnv(t *testing.T) *TestEnv {
 		t.Fatal(err)
 	}
 
-	admin := models.User{Name: "it-admin", ApiKey: string(adminHash), IsAdmin: true}
-	device := models.User{Name: "it-device", ApiKey: string(deviceHash), IsAdmin: false}
+	adminPassword := "integration-admin-password"
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(adminPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminUsername := "it-admin"
+	admin := models.User{Name: "it-admin", IsAdmin: true, Username: &adminUsername, PasswordHash: string(passwordHash)}
+	device := models.User{Name: "it-device", IsAdmin: false}
 	if err := db.Create(&admin).Error; err != nil {
 		t.Fatal(err)
 	}
 	if err := db.Create(&device).Error; err != nil {
 		t.Fatal(err)
 	}
+	for _, key := range []models.APIKey{
+		{UserID: admin.ID, Label: "QR", KeyHash: string(adminHash), Primary: true},
+		{UserID: device.ID, Label: "QR", KeyHash: string(deviceHash), Primary: true},
+	} {
+		if err := db.Create(&key).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
 
 	noopPub := &messaging.Publisher{} // nil channel → no-op publish
 
@@ -132,16 +186,36 @@ func setupEnv(t *testing.T) *TestEnv {
 	deviceCommandService := service.NewDeviceCommandService(deviceCommandDAO, locationRequestService)
 	deviceReportService := service.NewDeviceReportService(deviceReportDAO)
 	deviceStatusService := service.NewDeviceStatusService(locationDAO, deviceReportDAO)
+	deviceConfigService := service.NewDeviceConfigService(dao.NewDeviceConfigDAO(db), deviceReportDAO, deviceCommandService)
+	userDAO := dao.NewUserDAO(db)
+	organizationDAO := dao.NewOrganizationDAO(db)
+	timezoneService := service.NewTimezoneService(userDAO, organizationDAO)
+	locationService.Timezones = timezoneService
+	notificationService := service.NewNotificationService(dao.NewNotificationDAO(db), userDAO, &service.WebhookChannel{})
+	notificationService.Timezones = timezoneService
+	alertService := service.NewAlertService(dao.NewAlertDAO(db), userDAO, locationDAO, deviceReportDAO, locationRequestDAO)
+	alertService.Notifier = notificationService
+	alertService.Timezones = timezoneService
 
 	baseURL := "http://localhost:8080"
+	appReleaseService := service.NewAppReleaseService(
+		dao.NewAppReleaseDAO(db), deviceCommandDAO, deviceCommandService, deviceReportDAO,
+		filepath.Join(wd, "..", "static", "releases"), baseURL,
+	)
+	deviceCommandService.AppUpdates = appReleaseService
 	appReleaseController := controllers.NewAppReleaseController(
 		filepath.Join(wd, "..", "static", "releases", "manifest.json"),
 		filepath.Join(wd, "..", "static", "releases"),
 		baseURL,
+		appReleaseService,
 	)
 	deviceController := controllers.NewDeviceController(
-		deviceCommandService, deviceReportService, deviceStatusService, locationRequestService, appReleaseController,
+		deviceCommandService, deviceReportService, deviceStatusService, locationRequestService, appReleaseController, deviceConfigService, alertService,
 	)
+	deviceController.Timezones = timezoneService
+	deviceConfigController := controllers.NewDeviceConfigController(deviceConfigService)
+	alertController := controllers.NewAlertController(alertService)
+	notificationController := controllers.NewNotificationController(notificationService)
 	locationRequestController := controllers.NewLocationRequestController(locationRequestService, deviceCommandService)
 
 	checkpointDAO := dao.NewCheckpointDAO(db)
@@ -149,37 +223,86 @@ func setupEnv(t *testing.T) *TestEnv {
 	visitDAO := dao.NewVisitDAO(db)
 	travelSegmentService := service.NewTravelSegmentService(locationDAO, checkpointService)
 	visitService := service.NewVisitService(visitDAO, travelSegmentService)
+	trackingScheduleService := service.NewTrackingScheduleService(dao.NewTrackingScheduleDAO(db), userDAO, deviceCommandService)
+	trackingScheduleService.Visits = visitService
+	trackingScheduleService.Timezones = timezoneService
+	locationService.Privacy = trackingScheduleService
+	travelSegmentService.Privacy = trackingScheduleService
+	deviceConfigService.Schedules = trackingScheduleService
+	alertService.Privacy = trackingScheduleService
+	trackingScheduleController := controllers.NewTrackingScheduleController(trackingScheduleService)
+	retentionService := service.NewRetentionService(dao.NewRetentionDAO(db), locationDAO, userDAO)
+	retentionService.Organizations = organizationDAO
+	retentionService.Checkpoints = checkpointService
+	retentionService.Partitions = locationDAO
+	retentionService.ArchiveDir = t.TempDir()
+	retentionController := controllers.NewRetentionController(retentionService)
 	locationController := controllers.NewLocationController(
 		locationService, locationRequestService, deviceCommandService, noopPub, "",
 	)
+	locationController.Timezones = timezoneService
 	checkpointController := controllers.NewCheckpointController(
 		checkpointService, locationService, visitService, noopPub,
 	)
 	visitController := controllers.NewVisitController(visitService)
+	visitController.Timezones = timezoneService
 	eventController := controllers.NewEventController(noopPub)
 
-	userDAO := dao.NewUserDAO(db)
-	userService := service.NewUserService(userDAO)
+	userService := service.NewUserService(userDAO, dao.NewAPIKeyDAO(db))
+	userService.Groups = dao.NewGroupDAO(db)
+	userService.Organizations = organizationDAO
 	userController := controllers.NewUserController(userService, deviceCommandService)
+	userController.Timezones = timezoneService
+	sessionService := service.NewSessionService(userDAO, dao.NewSessionDAO(db), []byte("integration-session-secret-0000000000"))
+	authController := controllers.NewAuthController(sessionService)
+	auditService := service.NewAuditService(dao.NewAuditDAO(db))
+	auditController := controllers.NewAuditController(auditService)
+	adminSession, err := sessionService.Login(adminUsername, adminPassword, "", service.SessionMeta{UserAgent: "integration"})
+	if err != nil {
+		t.Fatalf("admin login: %v", err)
+	}
 
+	health := controllers.NewHealthController(map[string]controllers.HealthCheck{
+		"database": func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	})
 	r := router.InitRoutes(
 		locationController,
 		locationRequestController,
 		deviceController,
+		deviceConfigController,
+		alertController,
+		notificationController,
 		appReleaseController,
 		checkpointController,
 		visitController,
 		eventController,
 		userController,
+		authController,
+		auditController,
+		trackingScheduleController,
+		retentionController,
+		health,
 		userService,
+		sessionService,
+		auditService,
+		nil, // без лимитов частоты: тесты шлют запросы пачками
 	)
 
 	return &TestEnv{
-		DB:        db,
-		Router:    r,
-		Admin:     admin,
-		Device:    device,
-		AdminKey:  adminKey,
-		DeviceKey: deviceKey,
+		DB:            db,
+		Router:        r,
+		Admin:         admin,
+		Device:        device,
+		AdminKey:      adminKey,
+		DeviceKey:     deviceKey,
+		AdminToken:    adminSession.AccessToken,
+		AdminPassword: adminPassword,
+		Health:        health,
 	}
 }
@@ -0,0 +1,117 @@
+// Package logging — структурированный журнал на log/slog: JSON или текст, уровень,
+// сэмплирование частых сообщений, ID запроса из контекста и маскирование секретов.
+// Setup делает логгер стандартным, поэтому строки log.Printf тоже проходят через него
+// (уровень info, без сэмплирования).
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log"
+	"log/slog"
+)
+
+// RedactedValue — чем заменяются значения секретных атрибутов.
+const RedactedValue = "***"
+
+// Config — параметры журнала.
+type Config struct {
+	Level slog.Level
+	// JSON — вывод JSON-строками; иначе key=value.
+	JSON bool
+	// SampleInitial и SampleThereafter — из записей ниже warn с одним ключом за секунду
+	// пишутся первые SampleInitial, затем каждая SampleThereafter-я (0 — остальные
+	// отбрасываются). SampleInitial == 0 — без сэмплирования.
+	SampleInitial    int
+	SampleThereafter int
+	// IsSecret — атрибуты с такими ключами пишутся как RedactedValue.
+	IsSecret func(key string) bool
+}
+
+// New собирает логгер с выводом в w.
+func New(w io.Writer, cfg Config) *slog.Logger {
+	h, _ := newHandlers(w, cfg)
+	return slog.New(h)
+}
+
+// Setup делает логгер с выводом в w стандартным для slog и пакета log. Строки
+// log.Printf не сэмплируются: уровня у них нет, и среди них могут быть ошибки.
+func Setup(w io.Writer, cfg Config) *slog.Logger {
+	h, plain := newHandlers(w, cfg)
+	logger := slog.New(h)
+	slog.SetDefault(logger)
+	log.SetOutput(slog.NewLogLogger(plain, slog.LevelInfo).Writer())
+	return logger
+}
+
+// newHandlers — обработчик с сэмплированием (если оно включено) и тот же вывод без него.
+func newHandlers(w io.Writer, cfg Config) (h, plain slog.Handler) {
+	opts := &slog.HandlerOptions{
+		Level: cfg.Level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if cfg.IsSecret != nil && cfg.IsSecret(a.Key) {
+				return slog.String(a.Key, RedactedValue)
+			}
+			return a
+		},
+	}
+	var base slog.Handler
+	if cfg.JSON {
+		base = slog.NewJSONHandler(w, opts)
+	} else {
+		base = slog.NewTextHandler(w, opts)
+	}
+	plain = contextHandler{base}
+	if cfg.SampleInitial > 0 {
+		return newSamplingHandler(plain, cfg.SampleInitial, cfg.SampleThereafter), plain
+	}
+	return plain, plain
+}
+
+type requestIDKey struct{}
+
+// WithRequestID сохраняет ID запроса в контексте; записи slog.*Context получат атрибут request_id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID — ID запроса из контекста ("" — нет).
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID — случайный ID запроса, 16 hex-символов.
+func NewRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// contextHandler добавляет к записи request_id из контекста.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
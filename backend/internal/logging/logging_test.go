@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("not JSON: %q", line)
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func TestNew_requestIDAndRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Config{
+		Level:    slog.LevelInfo,
+		JSON:     true,
+		IsSecret: func(key string) bool { return key == "api_key" },
+	})
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "Точка сохранена", "user_id", 7, "api_key", "plain")
+	logger.Debug("не пишется")
+
+	recs := records(t, &buf)
+	if len(recs) != 1 {
+		t.Fatalf("records %v", recs)
+	}
+	rec := recs[0]
+	if rec["request_id"] != "req-1" || rec["api_key"] != RedactedValue || rec["user_id"] != float64(7) {
+		t.Fatalf("record %v", rec)
+	}
+	if rec["level"] != "INFO" || rec["msg"] != "Точка сохранена" {
+		t.Fatalf("record %v", rec)
+	}
+}
+
+func TestSampling_perKeyPerSecond(t *testing.T) {
+	var buf bytes.Buffer
+	h := newSamplingHandler(slog.NewJSONHandler(&buf, nil), 2, 3)
+	now := time.Unix(1000, 0)
+	h.state.now = func() time.Time { return now }
+	logger := slog.New(h)
+
+	for i := 0; i < 8; i++ {
+		logger.Info("[CreateLocation] Создание записи: userID=1")
+	}
+	logger.Info("[Other] x")
+	logger.Warn("[CreateLocation] предупреждение")
+	// первые 2, затем 5-я и 8-я; другой ключ и warn — всегда.
+	if n := len(records(t, &buf)); n != 6 {
+		t.Fatalf("kept %d records:\n%s", n, buf.String())
+	}
+
+	buf.Reset()
+	now = now.Add(time.Second)
+	logger.Info("[CreateLocation] новая секунда")
+	if n := len(records(t, &buf)); n != 1 {
+		t.Fatalf("counter not reset, kept %d", n)
+	}
+}
+
+func TestSetup_bridgedLogIsNotSampled(t *testing.T) {
+	prevSlog, prevOut, prevFlags := slog.Default(), log.Writer(), log.Flags()
+	t.Cleanup(func() {
+		slog.SetDefault(prevSlog)
+		log.SetOutput(prevOut)
+		log.SetFlags(prevFlags)
+	})
+
+	var buf bytes.Buffer
+	Setup(&buf, Config{Level: slog.LevelInfo, JSON: true, SampleInitial: 1})
+	for i := 0; i < 3; i++ {
+		slog.Info("[Notify] повтор")
+		log.Printf("[Notify] Ошибка доставки %d", i)
+	}
+	// slog.Info прореживается до одной записи, строки log.Printf пишутся все.
+	if n := len(records(t, &buf)); n != 4 {
+		t.Fatalf("kept %d records:\n%s", n, buf.String())
+	}
+}
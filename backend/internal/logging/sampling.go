@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// samplingHandler прореживает частые записи ниже warn. Ключ — сообщение; для сообщений
+// вида "[Префикс] текст" — только префикс. Строки log.Printf сюда не попадают (см. Setup).
+type samplingHandler struct {
+	slog.Handler
+	state *samplingState
+}
+
+type samplingState struct {
+	initial    int
+	thereafter int
+	now        func() time.Time
+
+	mu     sync.Mutex
+	second int64
+	counts map[string]int
+}
+
+func newSamplingHandler(next slog.Handler, initial, thereafter int) *samplingHandler {
+	return &samplingHandler{Handler: next, state: &samplingState{
+		initial:    initial,
+		thereafter: thereafter,
+		now:        time.Now,
+		counts:     make(map[string]int),
+	}}
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && !h.state.keep(sampleKey(r.Message)) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), state: h.state}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), state: h.state}
+}
+
+func (s *samplingState) keep(key string) bool {
+	sec := s.now().Unix()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sec != s.second {
+		s.second = sec
+		clear(s.counts)
+	}
+	s.counts[key]++
+	n := s.counts[key]
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
+
+func sampleKey(msg string) string {
+	if strings.HasPrefix(msg, "[") {
+		if end := strings.IndexByte(msg, ']'); end > 0 {
+			return msg[:end+1]
+		}
+	}
+	return msg
+}
@@ -0,0 +1,49 @@
+package metrics
+
+// Метрики приложения. Имена — locator_*, метки — с ограниченным набором значений
+// (маршрут — шаблон gin, а не URL; без ID пользователей).
+var (
+	HTTPRequests = NewCounterVec("locator_http_requests_total",
+		"HTTP-запросы по маршруту и коду ответа.", "method", "route", "status")
+	HTTPDuration = NewHistogramVec("locator_http_request_duration_seconds",
+		"Длительность обработки HTTP-запросов.", nil, "method", "route")
+
+	// LocationsIngested — точки с телефонов: result — saved, masked, skipped или error;
+	// reason — причина пропуска (poor_accuracy, gps_outlier, stale_gps_outlier, ...).
+	LocationsIngested = NewCounterVec("locator_locations_ingested_total",
+		"Принятые и отброшенные точки по источнику и причине пропуска.", "source", "result", "reason")
+
+	// Visits — event: start, end, abandon.
+	Visits = NewCounterVec("locator_visits_total",
+		"Начатые, завершённые и отменённые (короткие) визиты.", "event")
+
+	RabbitPublishFailures = NewCounterVec("locator_rabbitmq_publish_failures_total",
+		"Ошибки публикации в RabbitMQ.", "routing_key")
+	RabbitConsumed = NewCounterVec("locator_rabbitmq_consumed_total",
+		"Обработанные сообщения RabbitMQ: result — ack или nack.", "queue", "result")
+	RabbitConsumerLag = NewHistogramVec("locator_rabbitmq_consumer_lag_seconds",
+		"Задержка от публикации сообщения до начала его обработки.",
+		[]float64{0.01, 0.05, 0.1, 0.5, 1, 5, 15, 60, 300, 900}, "queue")
+
+	// DeviceCommands — status: pending (поставлена), delivered, acked, failed,
+	// progress (промежуточный статус OTA), expired.
+	DeviceCommands = NewCounterVec("locator_device_commands_total",
+		"Переходы команд устройствам по типу и статусу.", "type", "status")
+
+	OSRMDuration = NewHistogramVec("locator_osrm_request_duration_seconds",
+		"Длительность запросов к OSRM.", nil, "service")
+	OSRMErrors = NewCounterVec("locator_osrm_errors_total",
+		"Ошибки запросов к OSRM.", "service")
+
+	// AuthFailures — reason: api_key_missing, api_key_invalid, session_missing,
+	// session_invalid, invalid_credentials, totp_required, totp_invalid, login_link_invalid.
+	AuthFailures = NewCounterVec("locator_auth_failures_total",
+		"Неудачные попытки аутентификации.", "reason")
+
+	// RateLimitRequests — решения ограничителя по классу: result — allowed или limited.
+	RateLimitRequests = NewCounterVec("locator_rate_limit_requests_total",
+		"Запросы, пропущенные и отклонённые ограничителем, по классу.", "class", "result")
+	// RateLimitEvents — event: auth_failure, lockout, locked_rejection.
+	RateLimitEvents = NewCounterVec("locator_rate_limit_events_total",
+		"Неудачные входы, блокировки IP и запросы, отклонённые из-за блокировки.", "event")
+)
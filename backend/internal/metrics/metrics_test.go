@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, token string) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	Default.Handler(token).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+func TestCounterVec_textFormat(t *testing.T) {
+	c := NewCounterVec("test_counter_total", "Тестовый\nсчётчик.", "route", "status")
+	c.Inc("/api/users/:id", "200")
+	c.Add(2, "/api/users/:id", "200")
+	c.Inc(`a"b\c`, "500")
+	c.Add(-1, "/api/users/:id", "200")
+
+	out := scrape(t, "")
+	for _, want := range []string{
+		"# HELP test_counter_total Тестовый\\nсчётчик.\n# TYPE test_counter_total counter\n",
+		`test_counter_total{route="/api/users/:id",status="200"} 3` + "\n",
+		`test_counter_total{route="a\"b\\c",status="500"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if v := c.Value("/api/users/:id", "200"); v != 3 {
+		t.Fatalf("value %v", v)
+	}
+}
+
+func TestHistogramVec_cumulativeBuckets(t *testing.T) {
+	h := NewHistogramVec("test_duration_seconds", "Тест.", []float64{0.1, 1}, "service")
+	h.Observe(0.05, "match")
+	h.Observe(0.5, "match")
+	h.Observe(3, "match")
+
+	out := scrape(t, "")
+	for _, want := range []string{
+		`test_duration_seconds_bucket{service="match",le="0.1"} 1`,
+		`test_duration_seconds_bucket{service="match",le="1"} 2`,
+		`test_duration_seconds_bucket{service="match",le="+Inf"} 3`,
+		`test_duration_seconds_sum{service="match"} 3.55`,
+		`test_duration_seconds_count{service="match"} 3`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if n := h.Count("match"); n != 3 {
+		t.Fatalf("count %d", n)
+	}
+}
+
+func TestGaugeFunc_skipsUnavailableValue(t *testing.T) {
+	available := false
+	NewGaugeFunc("test_queue_depth", "Тест.", func() (float64, bool) { return 7, available }, "queue", "q")
+
+	if out := scrape(t, ""); strings.Contains(out, "test_queue_depth{") {
+		t.Fatalf("unavailable gauge exported:\n%s", out)
+	}
+	available = true
+	if out := scrape(t, ""); !strings.Contains(out, `test_queue_depth{queue="q"} 7`+"\n") {
+		t.Fatalf("gauge missing:\n%s", out)
+	}
+}
+
+func TestHandler_token(t *testing.T) {
+	h := Default.Handler("secret")
+	for header, want := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"Bearer secret": http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Authorization %q: status %d, want %d", header, rec.Code, want)
+		}
+	}
+}
+
+func TestRegister_duplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("duplicate metric must panic")
+		}
+	}()
+	NewCounterVec("locator_visits_total", "", "event")
+}
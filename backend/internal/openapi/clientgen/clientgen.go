@@ -0,0 +1,448 @@
+// Package clientgen генерирует Go-клиент по документу OpenAPI: структуры для схем
+// components и по методу на операцию. Транспорт (Client.do, Client.doRaw, APIError)
+// пишется руками в пакете клиента — генератор только вызывает его.
+package clientgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"locator/internal/openapi"
+)
+
+// Generate возвращает отформатированный исходник пакета pkg.
+func Generate(doc *openapi.Document, pkg string) ([]byte, error) {
+	g := &generator{doc: doc}
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := g.typeDecl(name, doc.Components.Schemas[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	ops := g.operations()
+	for _, op := range ops {
+		if err := g.method(op); err != nil {
+			return nil, err
+		}
+	}
+
+	body := g.buf.String()
+	var out strings.Builder
+	out.WriteString("// Code generated by cmd/openapi. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", pkg)
+	for _, imp := range []string{"context", "net/http", "net/url", "time"} {
+		name := imp[strings.LastIndex(imp, "/")+1:]
+		if regexp.MustCompile(`\b` + name + `\.`).MatchString(body) {
+			fmt.Fprintf(&out, "%q\n", imp)
+		}
+	}
+	out.WriteString(")\n\n")
+	out.WriteString(body)
+
+	src, err := format.Source([]byte(out.String()))
+	if err != nil {
+		return nil, fmt.Errorf("форматирование клиента: %w", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	doc *openapi.Document
+	buf bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+type operation struct {
+	method, path string
+	*openapi.Operation
+}
+
+// operations — операции документа в порядке operationId.
+func (g *generator) operations() []operation {
+	var ops []operation
+	for path, item := range g.doc.Paths {
+		for method, op := range *item {
+			ops = append(ops, operation{method: strings.ToUpper(method), path: path, Operation: op})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].OperationID < ops[j].OperationID })
+	return ops
+}
+
+func (g *generator) typeDecl(name string, s *openapi.Schema) error {
+	if s.Description != "" {
+		g.printf("// %s — %s\n", name, s.Description)
+	}
+	if s.Type == "object" && s.AdditionalProperties != nil && len(s.Properties) > 0 {
+		g.printf("// %s: сервер принимает и присылает и другие ключи, здесь — только известные.\n", name)
+	}
+	if s.Type != "object" || s.AdditionalProperties != nil && len(s.Properties) == 0 {
+		typ, err := goType(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		g.printf("type %s %s\n\n", name, typ)
+		return nil
+	}
+	body, err := structType(s)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	g.printf("type %s %s\n\n", name, body)
+	return nil
+}
+
+func structType(s *openapi.Schema) (string, error) {
+	props := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		props = append(props, name)
+	}
+	sort.Strings(props)
+
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	seen := make(map[string]string)
+	for _, prop := range props {
+		field := fieldName(prop)
+		if other, dup := seen[field]; dup {
+			return "", fmt.Errorf("поля %q и %q дают одно имя %s", other, prop, field)
+		}
+		seen[field] = prop
+		ps := s.Properties[prop]
+		typ, err := goType(ps)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", prop, err)
+		}
+		tag := prop
+		if omittable(ps) {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "%s %s `json:%q`\n", field, typ, tag)
+	}
+	b.WriteString("}")
+	return b.String(), nil
+}
+
+// omittable — поле, которое на сервере — указатель, срез или карта: nil не отправляется.
+func omittable(s *openapi.Schema) bool {
+	return s.Nullable || s.Type == "array" || s.Type == "object" && len(s.Properties) == 0 ||
+		s.Ref == "" && s.Type == "" && len(s.AllOf) == 0
+}
+
+// goType — Go-тип схемы: nullable-скаляры и ссылки — указатели.
+func goType(s *openapi.Schema) (string, error) {
+	if name := s.RefName(); name != "" {
+		return name, nil
+	}
+	if len(s.AllOf) == 1 {
+		inner, err := goType(s.AllOf[0])
+		if err != nil {
+			return "", err
+		}
+		if s.Nullable {
+			return "*" + inner, nil
+		}
+		return inner, nil
+	}
+	if len(s.AllOf) > 1 {
+		return "", fmt.Errorf("allOf из %d схем не поддерживается", len(s.AllOf))
+	}
+
+	var typ string
+	switch s.Type {
+	case "":
+		return "any", nil
+	case "boolean":
+		typ = "bool"
+	case "integer":
+		switch s.Format {
+		case "int64":
+			typ = "int64"
+		case "int32":
+			typ = "int32"
+		default:
+			typ = "int"
+		}
+	case "number":
+		if s.Format == "float" {
+			typ = "float32"
+		} else {
+			typ = "float64"
+		}
+	case "string":
+		switch s.Format {
+		case "date-time":
+			typ = "time.Time"
+		case "byte", "binary":
+			return "[]byte", nil
+		default:
+			typ = "string"
+		}
+	case "array":
+		if s.Items == nil {
+			return "[]any", nil
+		}
+		elem, err := goType(s.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case "object":
+		if len(s.Properties) > 0 {
+			body, err := structType(s)
+			if err != nil {
+				return "", err
+			}
+			if s.Nullable {
+				return "*" + body, nil
+			}
+			return body, nil
+		}
+		if s.AdditionalProperties == nil {
+			return "map[string]any", nil
+		}
+		elem, err := goType(s.AdditionalProperties)
+		if err != nil {
+			return "", err
+		}
+		return "map[string]" + elem, nil
+	default:
+		return "", fmt.Errorf("тип %q не поддерживается", s.Type)
+	}
+	if s.Nullable {
+		return "*" + typ, nil
+	}
+	return typ, nil
+}
+
+func (g *generator) method(op operation) error {
+	name := exportName(op.OperationID)
+	args := []string{"ctx context.Context"}
+
+	// Путь: "/api/users/" + pathParam(id).
+	var path []string
+	rest := op.path
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest, '}')
+		param := rest[start+1 : end]
+		if start > 0 {
+			path = append(path, strconv.Quote(rest[:start]))
+		}
+		arg := paramName(param)
+		typ, err := g.paramType(op, "path", param)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op.OperationID, err)
+		}
+		args = append(args, arg+" "+typ)
+		path = append(path, "pathParam("+arg+")")
+		rest = rest[end+1:]
+	}
+	if rest != "" || len(path) == 0 {
+		path = append(path, strconv.Quote(rest))
+	}
+
+	query := "nil"
+	var queryParams []openapi.Parameter
+	for _, p := range op.Parameters {
+		if p.In == "query" {
+			queryParams = append(queryParams, p)
+		}
+	}
+	paramsType := name + "Params"
+	if len(queryParams) > 0 {
+		if err := g.paramsStruct(paramsType, op, queryParams); err != nil {
+			return err
+		}
+		args = append(args, "params *"+paramsType)
+		query = "params.values()"
+	}
+
+	body := "nil"
+	if op.RequestBody != nil {
+		if mt, ok := op.RequestBody.Content["application/json"]; ok {
+			typ, err := goType(mt.Schema)
+			if err != nil {
+				return fmt.Errorf("%s: тело: %w", op.OperationID, err)
+			}
+			args = append(args, "body "+typ)
+			body = "body"
+		}
+	}
+
+	result, raw, noContent, err := g.result(op)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op.OperationID, err)
+	}
+
+	g.printf("// %s — %s %s", name, op.method, op.path)
+	if op.Summary != "" {
+		g.printf(": %s", op.Summary)
+	}
+	g.printf(".\n")
+	if noContent && result != "" {
+		g.printf("// Ответ без тела (%d) — нулевое значение без ошибки.\n", http.StatusNoContent)
+	}
+	call := fmt.Sprintf("%q, %s, %s", op.method, strings.Join(path, " + "), query)
+	switch {
+	case raw:
+		g.printf("func (c *Client) %s(%s) ([]byte, error) {\n", name, strings.Join(args, ", "))
+		g.printf("return c.doRaw(ctx, %s)\n}\n\n", call)
+	case result == "":
+		g.printf("func (c *Client) %s(%s) error {\n", name, strings.Join(args, ", "))
+		g.printf("_, err := c.do(ctx, %s, %s, nil)\nreturn err\n}\n\n", call, body)
+	case strings.HasPrefix(result, "*"):
+		g.printf("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), result)
+		g.printf("var out %s\n", result[1:])
+		if noContent {
+			g.printf("status, err := c.do(ctx, %s, %s, &out)\n", call, body)
+			g.printf("if err != nil {\nreturn nil, err\n}\n")
+			g.printf("if status == http.StatusNoContent {\nreturn nil, nil\n}\n")
+		} else {
+			g.printf("if _, err := c.do(ctx, %s, %s, &out); err != nil {\nreturn nil, err\n}\n", call, body)
+		}
+		g.printf("return &out, nil\n}\n\n")
+	default:
+		g.printf("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), result)
+		g.printf("var out %s\n", result)
+		g.printf("if _, err := c.do(ctx, %s, %s, &out); err != nil {\nreturn nil, err\n}\n", call, body)
+		g.printf("return out, nil\n}\n\n")
+	}
+	return nil
+}
+
+// result — тип первого успешного JSON-ответа. raw — ответ файлом, noContent — есть 204.
+func (g *generator) result(op operation) (typ string, raw, noContent bool, err error) {
+	statuses := make([]string, 0, len(op.Responses))
+	for status := range op.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		if status[0] != '2' {
+			continue
+		}
+		if status == strconv.Itoa(http.StatusNoContent) {
+			noContent = true
+			continue
+		}
+		if typ != "" || raw {
+			continue
+		}
+		resp := op.Responses[status]
+		if mt, ok := resp.Content["application/json"]; ok {
+			typ, err = goType(mt.Schema)
+			if err != nil {
+				return "", false, false, err
+			}
+			if !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "any" {
+				typ = "*" + strings.TrimPrefix(typ, "*")
+			}
+		} else if len(resp.Content) > 0 {
+			raw = true
+		}
+	}
+	return typ, raw, noContent, nil
+}
+
+func (g *generator) paramType(op operation, in, name string) (string, error) {
+	for _, p := range op.Parameters {
+		if p.In == in && p.Name == name {
+			return goType(p.Schema)
+		}
+	}
+	return "", fmt.Errorf("параметр %s %q не описан", in, name)
+}
+
+// paramsStruct — структура query-параметров операции; нулевые значения не отправляются.
+func (g *generator) paramsStruct(typeName string, op operation, params []openapi.Parameter) error {
+	g.printf("// %s — query-параметры %s. Нулевые значения не отправляются.\n", typeName, exportName(op.OperationID))
+	g.printf("type %s struct {\n", typeName)
+	for _, p := range params {
+		typ, err := goType(p.Schema)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", op.OperationID, p.Name, err)
+		}
+		if p.Description != "" {
+			g.printf("// %s — %s.\n", fieldName(p.Name), p.Description)
+		}
+		g.printf("%s %s\n", fieldName(p.Name), typ)
+	}
+	g.printf("}\n\n")
+
+	g.printf("func (p *%s) values() url.Values {\n", typeName)
+	g.printf("if p == nil {\nreturn nil\n}\nq := url.Values{}\n")
+	for _, p := range params {
+		g.printf("setQuery(q, %q, p.%s)\n", p.Name, fieldName(p.Name))
+	}
+	g.printf("return q\n}\n\n")
+	return nil
+}
+
+// initialisms — части имён, которые в Go пишутся заглавными целиком.
+var initialisms = map[string]bool{
+	"api": true, "apk": true, "gps": true, "http": true, "id": true, "ip": true,
+	"json": true, "qr": true, "sha256": true, "sms": true, "totp": true, "url": true, "uuid": true,
+}
+
+// fieldName — имя Go-поля по ключу JSON: app_version → AppVersion, user_id → UserID.
+func fieldName(key string) string {
+	parts := strings.FieldsFunc(key, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.' || r == ' '
+	})
+	var b strings.Builder
+	for _, part := range parts {
+		if initialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(exportName(part))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// paramName — имя аргумента метода по параметру пути: request_id → requestID.
+func paramName(key string) string {
+	name := fieldName(key)
+	r := []rune(name)
+	n := 1
+	// Ведущая аббревиатура целиком в нижний регистр: ID → id, APIKey → apiKey.
+	for n < len(r) && unicode.IsUpper(r[n]) && (n+1 == len(r) || unicode.IsUpper(r[n+1])) {
+		n++
+	}
+	for i := 0; i < n; i++ {
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
+func exportName(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,120 @@
+package clientgen
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"locator/internal/openapi"
+)
+
+func testDoc() *openapi.Document {
+	ref := func(name string) *openapi.Schema { return &openapi.Schema{Ref: openapi.RefPrefix + name} }
+	json := func(s *openapi.Schema) map[string]openapi.MediaType {
+		return map[string]openapi.MediaType{"application/json": {Schema: s}}
+	}
+	return &openapi.Document{
+		Paths: map[string]*openapi.PathItem{
+			"/api/users/{id}": {
+				"get": {
+					OperationID: "getUser",
+					Summary:     "Пользователь",
+					Parameters: []openapi.Parameter{
+						{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "integer"}},
+						{Name: "tz", In: "query", Schema: &openapi.Schema{Type: "string"}},
+					},
+					Responses: map[string]*openapi.Response{
+						"200": {Content: json(ref("User"))},
+						"404": {Content: json(ref("ErrorResponse"))},
+					},
+				},
+			},
+			"/api/device/poll": {
+				"get": {
+					OperationID: "pollDevice",
+					Responses: map[string]*openapi.Response{
+						"200": {Content: json(ref("User"))},
+						"204": {},
+					},
+				},
+			},
+			"/api/users/": {
+				"post": {
+					OperationID: "createUser",
+					RequestBody: &openapi.RequestBody{Content: json(ref("User"))},
+					Responses:   map[string]*openapi.Response{"200": {Content: json(&openapi.Schema{Type: "array", Items: ref("User")})}},
+				},
+			},
+			"/api/users/qr-code-file": {
+				"get": {
+					OperationID: "getQRCodeFile",
+					Responses: map[string]*openapi.Response{
+						"200": {Content: map[string]openapi.MediaType{"image/png": {Schema: &openapi.Schema{Type: "string", Format: "binary"}}}},
+					},
+				},
+			},
+		},
+		Components: openapi.Components{Schemas: map[string]*openapi.Schema{
+			"User": {Type: "object", Properties: map[string]*openapi.Schema{
+				"id":         {Type: "integer"},
+				"qr_code":    {Type: "string"},
+				"group_id":   {Type: "integer", Nullable: true},
+				"created_at": {Type: "string", Format: "date-time"},
+				"tags":       {Type: "array", Items: &openapi.Schema{Type: "string"}},
+				"owner":      {AllOf: []*openapi.Schema{ref("User")}, Nullable: true},
+			}},
+			"ErrorResponse": {Type: "object", Properties: map[string]*openapi.Schema{"error": {Type: "string"}}},
+		}},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate(testDoc(), "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "client_gen.go", src, 0); err != nil {
+		t.Fatalf("parse: %v\n%s", err, src)
+	}
+	out := string(src)
+	for _, want := range []string{
+		"CreatedAt time.Time `json:\"created_at\"`",
+		"GroupID   *int      `json:\"group_id,omitempty\"`",
+		"Owner     *User     `json:\"owner,omitempty\"`",
+		"QRCode    string    `json:\"qr_code\"`",
+		"Tags      []string  `json:\"tags,omitempty\"`",
+		"func (c *Client) GetUser(ctx context.Context, id int, params *GetUserParams) (*User, error)",
+		`"/api/users/"+pathParam(id)`,
+		`setQuery(q, "tz", p.Tz)`,
+		"func (c *Client) CreateUser(ctx context.Context, body User) ([]User, error)",
+		"func (c *Client) GetQRCodeFile(ctx context.Context) ([]byte, error)",
+		"if status == http.StatusNoContent {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("нет %q в\n%s", want, out)
+		}
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	for key, want := range map[string]string{
+		"user_id":        "UserID",
+		"api_key":        "APIKey",
+		"totp_enabled":   "TOTPEnabled",
+		"apk_sha256":     "APKSHA256",
+		"app_version":    "AppVersion",
+		"config-command": "ConfigCommand",
+		"2fa":            "X2fa",
+		"ShortURL":       "ShortURL",
+	} {
+		if got := fieldName(key); got != want {
+			t.Errorf("fieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+	for key, want := range map[string]string{"id": "id", "request_id": "requestID", "api_key": "apiKey", "key_id": "keyID"} {
+		if got := paramName(key); got != want {
+			t.Errorf("paramName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Schemas собирает схемы components из Go-типов по правилам encoding/json:
+// имена полей — из тега json, встроенные структуры раскрываются, указатели —
+// nullable. Обязательными считаются поля с binding:"required" — те, без которых
+// обработчик отвечает 400.
+type Schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func NewSchemas() *Schemas {
+	return &Schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// Components — накопленные именованные схемы.
+func (s *Schemas) Components() map[string]*Schema {
+	return s.components
+}
+
+// For — схема значения v (обычно нулевого значения типа: controllers.LoginRequest{}).
+// Именованная структура попадает в components, возвращается ссылка на неё.
+func (s *Schemas) For(v any) *Schema {
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *Schemas) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Pointer {
+		inner := s.schema(t.Elem())
+		if inner.Ref != "" {
+			return &Schema{AllOf: []*Schema{inner}, Nullable: true}
+		}
+		inner.Nullable = true
+		return inner
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "наносекунды"}
+	case t.Kind() == reflect.Struct && t.Name() != "" && t.PkgPath() != "":
+		return s.ref(t)
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// Свой MarshalJSON у не-структуры (datatypes.JSON, json.RawMessage) — форма неизвестна.
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		return s.object(t)
+	default:
+		// interface{} и всё, что не сериализуется однозначно.
+		return &Schema{}
+	}
+}
+
+// ref регистрирует именованную структуру в components. Одноимённые типы разных
+// пакетов различаются префиксом пакета (ModelsLocation).
+func (s *Schemas) ref(t reflect.Type) *Schema {
+	name, ok := s.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := s.components[name]; taken {
+			pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+			name = exportName(pkg) + name
+		}
+		s.names[t] = name
+		// Заглушка до обхода полей — для рекурсивных типов.
+		s.components[name] = &Schema{}
+		obj := s.object(t)
+		if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+			// Свой MarshalJSON поверх структуры (DeviceReportPayload) — известные поля плюс прочие ключи.
+			obj.AdditionalProperties = &Schema{}
+		}
+		*s.components[name] = *obj
+	}
+	return &Schema{Ref: RefPrefix + name}
+}
+
+func (s *Schemas) object(t reflect.Type) *Schema {
+	obj := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.addFields(obj, t)
+	if len(obj.Properties) == 0 {
+		obj.Properties = nil
+	}
+	return obj
+}
+
+func (s *Schemas) addFields(obj *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.addFields(obj, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		var prop *Schema
+		if hasOption(opts, "string") {
+			prop = &Schema{Type: "string"}
+		} else {
+			prop = s.schema(ft)
+		}
+		obj.Properties[name] = prop
+		if strings.Contains(f.Tag.Get("binding"), "required") {
+			obj.Required = append(obj.Required, name)
+		}
+	}
+}
+
+func hasOption(opts, name string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+func exportName(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
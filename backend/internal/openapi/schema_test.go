@@ -0,0 +1,114 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testChild struct {
+	Name string `json:"name"`
+}
+
+type testItem struct {
+	testBase
+	Title    string           `json:"title" binding:"required"`
+	Note     *string          `json:"note,omitempty"`
+	Child    *testChild       `json:"child"`
+	Children []testChild      `json:"children"`
+	Tags     map[string]int64 `json:"tags"`
+	Count    int64            `json:"count,string"`
+	Extra    any              `json:"extra"`
+	Raw      json.RawMessage  `json:"raw"`
+	Hidden   string           `json:"-"`
+	secret   string
+	Self     *testItem         `json:"self,omitempty"`
+	Labels   map[string]string `json:"labels"`
+}
+
+func TestSchemas_struct(t *testing.T) {
+	s := NewSchemas()
+	ref := s.For(testItem{})
+	if ref.RefName() != "testItem" {
+		t.Fatalf("ref = %+v", ref)
+	}
+	item := s.Components()["testItem"]
+	if item == nil || item.Type != "object" {
+		t.Fatalf("component = %+v", item)
+	}
+
+	props := item.Properties
+	for _, name := range []string{"id", "created_at", "title", "note", "child", "children", "tags", "count", "extra", "raw", "self"} {
+		if props[name] == nil {
+			t.Errorf("нет свойства %s", name)
+		}
+	}
+	for _, name := range []string{"Hidden", "-", "secret", "testBase"} {
+		if props[name] != nil {
+			t.Errorf("лишнее свойство %s", name)
+		}
+	}
+	if !reflect.DeepEqual(item.Required, []string{"title"}) {
+		t.Errorf("required = %v", item.Required)
+	}
+
+	checks := map[string]Schema{
+		"created_at": {Type: "string", Format: "date-time"},
+		"note":       {Type: "string", Nullable: true},
+		"count":      {Type: "string"},
+		"extra":      {},
+		"raw":        {},
+	}
+	for name, want := range checks {
+		if got := *props[name]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %+v, want %+v", name, got, want)
+		}
+	}
+	if child := props["child"]; !child.Nullable || len(child.AllOf) != 1 || child.AllOf[0].RefName() != "testChild" {
+		t.Errorf("child = %+v", child)
+	}
+	if children := props["children"]; children.Type != "array" || children.Items.RefName() != "testChild" {
+		t.Errorf("children = %+v", children)
+	}
+	if tags := props["tags"]; tags.Type != "object" || tags.AdditionalProperties.Format != "int64" {
+		t.Errorf("tags = %+v", tags)
+	}
+	if self := props["self"]; len(self.AllOf) != 1 || self.AllOf[0].RefName() != "testItem" {
+		t.Errorf("self = %+v", self)
+	}
+}
+
+type testPayload struct {
+	Known string `json:"known"`
+}
+
+func (p testPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"known": p.Known})
+}
+
+func TestSchemas_marshalerStructAllowsExtraKeys(t *testing.T) {
+	s := NewSchemas()
+	s.For(testPayload{})
+	payload := s.Components()["testPayload"]
+	if payload.Properties["known"] == nil || payload.AdditionalProperties == nil {
+		t.Fatalf("payload = %+v", payload)
+	}
+}
+
+func TestSchemas_nameClashGetsPackagePrefix(t *testing.T) {
+	s := NewSchemas()
+	s.components["testChild"] = &Schema{Type: "string"}
+	if name := s.For(testChild{}).RefName(); name != "OpenapitestChild" {
+		t.Fatalf("name = %q", name)
+	}
+	// Повторный вызов — то же имя, без нового компонента.
+	if name := s.For(testChild{}).RefName(); name != "OpenapitestChild" || len(s.Components()) != 2 {
+		t.Fatalf("name = %q, components = %d", name, len(s.Components()))
+	}
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval — как часто MemoryStore удаляет полные корзины и забытые ошибки.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	// fullAt — когда корзина наполнится; после этого запись можно удалить.
+	fullAt time.Time
+}
+
+type failureState struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+	window      time.Duration
+}
+
+// MemoryStore — Store в памяти одного процесса.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	failures  map[string]*failureState
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]*failureState),
+	}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, at: now}
+		s.buckets[key] = b
+	} else if elapsed := now.Sub(b.at).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*limit.Rate)
+		b.at = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.fullAt = now.Add(seconds((burst - b.tokens) / limit.Rate))
+	if allowed {
+		return true, 0
+	}
+	return false, seconds((1 - b.tokens) / limit.Rate)
+}
+
+func (s *MemoryStore) RecordFailure(key string, policy Lockout, now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	st, ok := s.failures[key]
+	if !ok || now.Sub(st.last) > policy.Window {
+		st = &failureState{}
+		s.failures[key] = st
+	}
+	st.count++
+	st.last = now
+	st.window = policy.Window
+	if d := policy.Duration(st.count); d > 0 {
+		st.lockedUntil = now.Add(d)
+	}
+	return st.lockedUntil
+}
+
+func (s *MemoryStore) LockedUntil(key string, now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.failures[key]; ok && st.lockedUntil.After(now) {
+		return st.lockedUntil
+	}
+	return time.Time{}
+}
+
+// sweep удаляет записи, не влияющие на решения: полные корзины и ошибки за
+// пределами окна без действующей блокировки. Вызывается под s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, st := range s.failures {
+		if now.Sub(st.last) > st.window && !st.lockedUntil.After(now) {
+			delete(s.failures, key)
+		}
+	}
+}
+
+func seconds(v float64) time.Duration {
+	return time.Duration(v * float64(time.Second))
+}
@@ -0,0 +1,204 @@
+// Package ratelimit — ограничение частоты запросов (token bucket) и блокировка
+// IP после серии неудачных попыток аутентификации с экспоненциальной задержкой.
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
+	"sync"
+	"time"
+
+	"locator/internal/metrics"
+)
+
+// Классы маршрутов: у каждого свой лимит.
+const (
+	// ClassAuth — вход, ссылка входа, обновление сессии (по IP: учётных данных ещё нет).
+	ClassAuth = "auth"
+	// ClassIngest — приём данных с устройства: координаты, отчёты, ack команд.
+	ClassIngest = "ingest"
+	// ClassDevice — остальные маршруты устройства и профиль (poll, /users/me, подписки).
+	ClassDevice = "device"
+	// ClassAdmin — административные маршруты (по сессии сотрудника).
+	ClassAdmin = "admin"
+)
+
+// Limit — параметры корзины: Rate токенов в секунду, не больше Burst подряд.
+// Нулевой Rate означает «без ограничения».
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute — лимит n запросов в минуту с допустимой пачкой burst.
+func PerMinute(n float64, burst int) Limit {
+	return Limit{Rate: n / 60, Burst: burst}
+}
+
+// Lockout — блокировка после неудачных попыток: первые Free ошибок в окне Window
+// бесплатны, далее каждая ошибка блокирует на Base, 2·Base, 4·Base… но не дольше Max.
+type Lockout struct {
+	Free   int
+	Base   time.Duration
+	Max    time.Duration
+	Window time.Duration
+}
+
+// DefaultLockout — 5 бесплатных ошибок, затем 1 с, 2 с, 4 с… до 15 минут.
+var DefaultLockout = Lockout{Free: 5, Base: time.Second, Max: 15 * time.Minute, Window: 15 * time.Minute}
+
+// Duration — длительность блокировки после failures ошибок подряд.
+func (p Lockout) Duration(failures int) time.Duration {
+	if failures <= p.Free || p.Base <= 0 {
+		return 0
+	}
+	d := p.Base
+	for i := p.Free + 1; i < failures; i++ {
+		d *= 2
+		if d >= p.Max {
+			return p.Max
+		}
+	}
+	if d > p.Max {
+		return p.Max
+	}
+	return d
+}
+
+// Store — состояние корзин и счётчиков ошибок. MemoryStore хранит его в памяти
+// процесса; при нескольких репликах нужна общая реализация (например, Redis).
+// Все методы должны быть атомарны относительно ключа.
+type Store interface {
+	// Take списывает токен из корзины key; false — токенов нет, retryAfter — когда появится.
+	Take(key string, limit Limit, now time.Time) (ok bool, retryAfter time.Duration)
+	// RecordFailure учитывает неудачную попытку key и возвращает, до какого момента key заблокирован.
+	RecordFailure(key string, policy Lockout, now time.Time) (lockedUntil time.Time)
+	// LockedUntil — момент окончания блокировки key (нулевое время — не заблокирован).
+	LockedUntil(key string, now time.Time) time.Time
+}
+
+// counters — счётчики (expvar "ratelimit"), отдаются GET /api/admin/rate-limits:
+// allowed.<класс>, limited.<класс>, auth_failures, lockouts, locked_rejections.
+// Те же события считаются в /metrics (locator_rate_limit_*).
+var counters = expvar.NewMap("ratelimit")
+
+// trustedTTL — сколько учётные данные, успешно прошедшие проверку, пропускаются
+// через блокировку IP (телефоны за общим NAT не страдают из-за соседа).
+const trustedTTL = 10 * time.Minute
+
+// Limiter применяет лимиты классов и блокировку IP поверх Store.
+type Limiter struct {
+	Store   Store
+	Classes map[string]Limit
+	Lockout Lockout
+
+	mu           sync.Mutex
+	trusted      map[string]time.Time
+	trustedSwept time.Time
+	now          func() time.Time
+}
+
+// New создаёт ограничитель; класс без записи в classes не ограничивается.
+func New(store Store, classes map[string]Limit, lockout Lockout) *Limiter {
+	return &Limiter{
+		Store:   store,
+		Classes: classes,
+		Lockout: lockout,
+		trusted: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Allow списывает запрос identity из корзины класса class.
+func (l *Limiter) Allow(class, identity string) (bool, time.Duration) {
+	limit, ok := l.Classes[class]
+	if !ok || limit.Rate <= 0 {
+		return true, 0
+	}
+	allowed, retryAfter := l.Store.Take(class+"|"+identity, limit, l.now())
+	result := "allowed"
+	if !allowed {
+		result = "limited"
+	}
+	counters.Add(result+"."+class, 1)
+	metrics.RateLimitRequests.Inc(class, result)
+	return allowed, retryAfter
+}
+
+// Locked — сколько ещё заблокирован ip. Учётные данные credential, недавно
+// успешно прошедшие проверку, блокировку обходят.
+func (l *Limiter) Locked(ip, credential string) time.Duration {
+	now := l.now()
+	until := l.Store.LockedUntil("ip|"+ip, now)
+	if !until.After(now) || l.isTrusted(credential, now) {
+		return 0
+	}
+	countEvent("locked_rejections", "locked_rejection")
+	return until.Sub(now)
+}
+
+// AuthFailed учитывает неудачную аутентификацию с ip; возвращает длительность
+// блокировки (0 — ещё в пределах бесплатных попыток).
+func (l *Limiter) AuthFailed(ip string) time.Duration {
+	now := l.now()
+	countEvent("auth_failures", "auth_failure")
+	until := l.Store.RecordFailure("ip|"+ip, l.Lockout, now)
+	if !until.After(now) {
+		return 0
+	}
+	countEvent("lockouts", "lockout")
+	return until.Sub(now)
+}
+
+// Trust запоминает credential как успешно прошедшие проверку. Счётчик ошибок IP
+// успехом не сбрасывается: иначе владелец любой учётной записи мог бы обнулять
+// его между попытками подбора; ошибки забываются по истечении Lockout.Window.
+func (l *Limiter) Trust(credential string) {
+	if credential == "" {
+		return
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.trustedSwept) >= sweepInterval {
+		l.trustedSwept = now
+		for k, until := range l.trusted {
+			if !until.After(now) {
+				delete(l.trusted, k)
+			}
+		}
+	}
+	l.trusted[credentialHash(credential)] = now.Add(trustedTTL)
+}
+
+func (l *Limiter) isTrusted(credential string, now time.Time) bool {
+	if credential == "" {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.trusted[credentialHash(credential)].After(now)
+}
+
+func credentialHash(credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return hex.EncodeToString(sum[:])
+}
+
+// countEvent учитывает событие блокировки в expvar (key) и в /metrics (event).
+func countEvent(key, event string) {
+	counters.Add(key, 1)
+	metrics.RateLimitEvents.Inc(event)
+}
+
+// Snapshot — текущие значения счётчиков.
+func Snapshot() map[string]int64 {
+	out := make(map[string]int64)
+	counters.Do(func(kv expvar.KeyValue) {
+		if v, ok := kv.Value.(*expvar.Int); ok {
+			out[kv.Key] = v.Value()
+		}
+	})
+	return out
+}
@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"locator/internal/metrics"
+)
+
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time { return c.t }
+
+func newTestLimiter(classes map[string]Limit, lockout Lockout) (*Limiter, *testClock) {
+	clock := &testClock{t: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	l := New(NewMemoryStore(), classes, lockout)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestAllow_burstThenRefill(t *testing.T) {
+	l, clock := newTestLimiter(map[string]Limit{ClassIngest: PerMinute(60, 3)}, DefaultLockout)
+	allowedBefore := metrics.RateLimitRequests.Value(ClassIngest, "allowed")
+	limitedBefore := metrics.RateLimitRequests.Value(ClassIngest, "limited")
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow(ClassIngest, "key:1"); !ok {
+			t.Fatalf("request %d within burst rejected", i+1)
+		}
+	}
+	ok, retryAfter := l.Allow(ClassIngest, "key:1")
+	if ok {
+		t.Fatal("request beyond burst allowed")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Fatalf("retryAfter=%s, want (0, 1s]", retryAfter)
+	}
+
+	// Другой ключ — своя корзина
+	if ok, _ := l.Allow(ClassIngest, "key:2"); !ok {
+		t.Fatal("other key limited by key:1")
+	}
+
+	clock.t = clock.t.Add(time.Second)
+	if ok, _ := l.Allow(ClassIngest, "key:1"); !ok {
+		t.Fatal("token not refilled after 1s at 1 rps")
+	}
+	if ok, _ := l.Allow(ClassIngest, "key:1"); ok {
+		t.Fatal("only one token should have been refilled")
+	}
+
+	// Те же решения видны в /metrics.
+	allowed := metrics.RateLimitRequests.Value(ClassIngest, "allowed") - allowedBefore
+	limited := metrics.RateLimitRequests.Value(ClassIngest, "limited") - limitedBefore
+	if allowed != 5 || limited != 2 {
+		t.Fatalf("locator_rate_limit_requests_total allowed=%v limited=%v, want 5 and 2", allowed, limited)
+	}
+}
+
+func TestAllow_unconfiguredClassUnlimited(t *testing.T) {
+	l, _ := newTestLimiter(map[string]Limit{ClassAdmin: {Rate: 0, Burst: 1}}, DefaultLockout)
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow(ClassAdmin, "session:a"); !ok {
+			t.Fatal("zero rate must mean unlimited")
+		}
+		if ok, _ := l.Allow(ClassDevice, "key:1"); !ok {
+			t.Fatal("class without limit must be unlimited")
+		}
+	}
+}
+
+func TestLockoutDuration_exponentialAndCapped(t *testing.T) {
+	p := Lockout{Free: 2, Base: time.Second, Max: 10 * time.Second, Window: time.Minute}
+	cases := map[int]time.Duration{
+		1:  0,
+		2:  0,
+		3:  time.Second,
+		4:  2 * time.Second,
+		5:  4 * time.Second,
+		6:  8 * time.Second,
+		7:  10 * time.Second,
+		50: 10 * time.Second,
+	}
+	for failures, want := range cases {
+		if got := p.Duration(failures); got != want {
+			t.Errorf("Duration(%d)=%s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestAuthFailed_locksIPAndTrustedCredentialBypasses(t *testing.T) {
+	l, clock := newTestLimiter(nil, Lockout{Free: 2, Base: time.Minute, Max: time.Hour, Window: time.Hour})
+	l.Trust("device-key")
+
+	for i := 0; i < 2; i++ {
+		if lock := l.AuthFailed("203.0.113.7"); lock != 0 {
+			t.Fatalf("free attempt %d locked for %s", i+1, lock)
+		}
+	}
+	if l.Locked("203.0.113.7", "") != 0 {
+		t.Fatal("locked within free attempts")
+	}
+	if lock := l.AuthFailed("203.0.113.7"); lock != time.Minute {
+		t.Fatalf("third failure lock=%s, want 1m", lock)
+	}
+
+	if wait := l.Locked("203.0.113.7", "guess"); wait != time.Minute {
+		t.Fatalf("untrusted credential wait=%s, want 1m", wait)
+	}
+	if wait := l.Locked("203.0.113.7", "device-key"); wait != 0 {
+		t.Fatalf("trusted credential blocked for %s", wait)
+	}
+	if wait := l.Locked("198.51.100.1", "guess"); wait != 0 {
+		t.Fatalf("other IP blocked for %s", wait)
+	}
+
+	clock.t = clock.t.Add(time.Minute)
+	if wait := l.Locked("203.0.113.7", "guess"); wait != 0 {
+		t.Fatalf("lock not lifted after its duration: %s", wait)
+	}
+	// Следующая ошибка в том же окне — блокировка вдвое длиннее
+	if lock := l.AuthFailed("203.0.113.7"); lock != 2*time.Minute {
+		t.Fatalf("fourth failure lock=%s, want 2m", lock)
+	}
+
+	// Доверие к учётным данным истекает
+	clock.t = clock.t.Add(trustedTTL)
+	l.AuthFailed("203.0.113.7")
+	if wait := l.Locked("203.0.113.7", "device-key"); wait == 0 {
+		t.Fatal("trust must expire after trustedTTL")
+	}
+}
+
+func TestMemoryStore_failuresForgottenAfterWindow(t *testing.T) {
+	s := NewMemoryStore()
+	p := Lockout{Free: 1, Base: time.Second, Max: time.Minute, Window: 10 * time.Minute}
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	s.RecordFailure("ip|a", p, now)
+	now = now.Add(11 * time.Minute)
+	if until := s.RecordFailure("ip|a", p, now); !until.IsZero() {
+		t.Fatalf("failure after window counted as repeat: locked until %s", until)
+	}
+}
+
+func TestMemoryStore_sweepDropsIdleEntries(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	limit := PerMinute(60, 5)
+	p := Lockout{Free: 0, Base: time.Second, Max: time.Second, Window: time.Minute}
+
+	s.Take("ingest|key:1", limit, now)
+	s.RecordFailure("ip|a", p, now)
+	if len(s.buckets) != 1 || len(s.failures) != 1 {
+		t.Fatalf("buckets=%d failures=%d, want 1/1", len(s.buckets), len(s.failures))
+	}
+
+	// Через 2 минуты корзина снова полна, окно ошибок прошло — обе записи удаляются
+	s.Take("ingest|key:2", limit, now.Add(2*time.Minute))
+	if _, ok := s.buckets["ingest|key:1"]; ok {
+		t.Fatal("full bucket not swept")
+	}
+	if len(s.failures) != 0 {
+		t.Fatalf("stale failures not swept: %d", len(s.failures))
+	}
+}
@@ -0,0 +1,371 @@
+package simulator
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"locator/client"
+	"locator/models"
+)
+
+// Clock — время симуляции: Speed секунд симуляции за секунду реального времени,
+// от Origin в момент NewClock. Одни часы на все устройства прогона.
+type Clock struct {
+	Origin time.Time
+	Speed  float64
+	start  time.Time
+}
+
+func NewClock(origin time.Time, speed float64) *Clock {
+	if speed <= 0 {
+		speed = 1
+	}
+	return &Clock{Origin: origin, Speed: speed, start: time.Now()}
+}
+
+// Elapsed — прошедшее время симуляции.
+func (c *Clock) Elapsed() time.Duration {
+	return time.Duration(float64(time.Since(c.start)) * c.Speed)
+}
+
+func (c *Clock) Now() time.Time {
+	return c.Origin.Add(c.Elapsed())
+}
+
+// Sleep ждёт d времени симуляции или отмены ctx.
+func (c *Clock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(time.Duration(float64(d) / c.Speed))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Behavior — поведение телефона. Интервалы — во времени симуляции.
+type Behavior struct {
+	GPSInterval    time.Duration
+	PollInterval   time.Duration
+	ReportInterval time.Duration
+
+	// AccuracyM — заявленная точность fix; фактический шум — нормальный, σ ≈ AccuracyM/2.
+	AccuracyM float64
+	// OutlierRate — доля выбросов: fix в OutlierM от истинного положения.
+	OutlierRate float64
+	OutlierM    float64
+
+	// OfflineRate — примерная доля времени без сети; эпизод — до OfflineMax. Точки
+	// копятся в очереди и уходят пачкой после восстановления связи.
+	OfflineRate float64
+	OfflineMax  time.Duration
+
+	// StaleRate — доля точек с закэшированным fix: прежние координаты и captured_at.
+	StaleRate float64
+}
+
+// DeviceResult — итог одного устройства.
+type DeviceResult struct {
+	UserID      int `json:"user_id"`
+	Fixes       int `json:"fixes"`
+	Sent        int `json:"sent"`
+	Saved       int `json:"saved"`
+	Skipped     int `json:"skipped"`
+	Rejected    int `json:"rejected"`
+	Bursts      int `json:"bursts"`
+	MaxQueue    int `json:"max_queue"`
+	OfflineTime int `json:"offline_seconds"`
+	Commands    int `json:"commands"`
+	Reports     int `json:"reports"`
+}
+
+// Device — виртуальный телефон: ходит по Track и говорит с сервером как приложение —
+// POST /api/location, GET /api/device/poll, ack команд, POST /api/device/report.
+type Device struct {
+	UserID   int
+	API      *client.Client
+	Track    Track
+	Clock    *Clock
+	Behavior Behavior
+	Stats    *Stats
+
+	rng          *rand.Rand
+	queue        []client.LocationUploadRequest
+	offlineUntil time.Time
+	offlineFrom  time.Time
+	lastFix      *client.LocationUploadRequest
+	battery      float64
+	result       DeviceResult
+	mu           sync.Mutex
+}
+
+func NewDevice(userID int, api *client.Client, track Track, clock *Clock, behavior Behavior, stats *Stats, seed int64) *Device {
+	return &Device{
+		UserID: userID, API: api, Track: track, Clock: clock, Behavior: behavior, Stats: stats,
+		rng:     rand.New(rand.NewSource(seed)),
+		battery: 60 + float64(seed%40),
+	}
+}
+
+// Result — итог устройства (после Run или во время прогона).
+func (d *Device) Result() DeviceResult {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := d.result
+	r.UserID = d.UserID
+	return r
+}
+
+// Run проходит маршрут до конца (или до отмены ctx). В конце телефон выходит на связь
+// и отправляет накопленную очередь.
+func (d *Device) Run(ctx context.Context) error {
+	b := d.Behavior
+	now := d.Clock.Now()
+	end := d.Clock.Origin.Add(d.Track.Duration())
+	nextGPS := now
+	// Разнести poll и отчёты устройств, стартующих одновременно.
+	nextPoll := now.Add(time.Duration(d.rng.Int63n(int64(b.PollInterval) + 1)))
+	nextReport := now.Add(time.Duration(d.rng.Int63n(int64(b.ReportInterval) + 1)))
+
+	for {
+		now = d.Clock.Now()
+		if now.After(end) || ctx.Err() != nil {
+			d.goOnline(now)
+			d.flush(context.WithoutCancel(ctx))
+			return ctx.Err()
+		}
+		if !now.Before(nextGPS) {
+			d.tickGPS(ctx, now)
+			nextGPS = nextGPS.Add(b.GPSInterval)
+		}
+		if d.online(now) {
+			if len(d.queue) > 0 {
+				d.flush(ctx)
+			}
+			if !now.Before(nextPoll) {
+				d.poll(ctx, now)
+				nextPoll = now.Add(b.PollInterval)
+			}
+			if !now.Before(nextReport) {
+				d.report(ctx, now)
+				nextReport = now.Add(b.ReportInterval)
+			}
+		}
+
+		wake := nextGPS
+		for _, t := range []time.Time{nextPoll, nextReport, d.offlineUntil, end} {
+			if t.After(now) && t.Before(wake) {
+				wake = t
+			}
+		}
+		_ = d.Clock.Sleep(ctx, wake.Sub(d.Clock.Now()))
+	}
+}
+
+func (d *Device) online(now time.Time) bool {
+	if d.offlineUntil.IsZero() {
+		return true
+	}
+	if now.Before(d.offlineUntil) {
+		return false
+	}
+	d.goOnline(now)
+	return true
+}
+
+func (d *Device) goOnline(now time.Time) {
+	if d.offlineUntil.IsZero() {
+		return
+	}
+	d.mu.Lock()
+	d.result.OfflineTime += int(now.Sub(d.offlineFrom).Seconds())
+	d.mu.Unlock()
+	d.offlineUntil = time.Time{}
+}
+
+// maybeGoOffline начинает эпизод без сети с вероятностью, дающей в среднем OfflineRate
+// времени офлайн (средний эпизод — 5/8 OfflineMax).
+func (d *Device) maybeGoOffline(now time.Time) {
+	b := d.Behavior
+	if b.OfflineRate <= 0 || b.OfflineMax <= 0 || !d.offlineUntil.IsZero() || b.OfflineRate >= 1 {
+		return
+	}
+	mean := float64(b.OfflineMax) * 5 / 8
+	p := b.OfflineRate * float64(b.GPSInterval) / (mean * (1 - b.OfflineRate))
+	if d.rng.Float64() >= p {
+		return
+	}
+	length := b.OfflineMax/4 + time.Duration(d.rng.Int63n(int64(b.OfflineMax*3/4)+1))
+	d.offlineFrom = now
+	d.offlineUntil = now.Add(length)
+}
+
+// fix — показание GPS в момент now: истинное положение плюс шум, изредка выброс.
+func (d *Device) fix(now time.Time) client.LocationUploadRequest {
+	b := d.Behavior
+	truth := d.Track.Position(now.Sub(d.Clock.Origin))
+	accuracy := b.AccuracyM * (0.7 + 0.6*d.rng.Float64())
+	var pos Point
+	if b.OutlierRate > 0 && d.rng.Float64() < b.OutlierRate {
+		pos = Offset(truth, b.OutlierM*(0.8+0.4*d.rng.Float64()), d.rng.Float64()*2*math.Pi)
+	} else {
+		sigma := b.AccuracyM / 2
+		pos = Offset(truth, math.Abs(d.rng.NormFloat64()*sigma), d.rng.Float64()*2*math.Pi)
+	}
+	return client.LocationUploadRequest{
+		Latitude:   pos.Lat,
+		Longitude:  pos.Lon,
+		Accuracy:   math.Round(accuracy*10) / 10,
+		CapturedAt: now.UTC().Format(time.RFC3339),
+		Source:     models.LocationSourcePeriodic,
+	}
+}
+
+// currentFix — свежий fix или, с вероятностью StaleRate, закэшированный прежний.
+func (d *Device) currentFix(now time.Time) client.LocationUploadRequest {
+	if d.lastFix != nil && d.Behavior.StaleRate > 0 && d.rng.Float64() < d.Behavior.StaleRate {
+		return *d.lastFix
+	}
+	f := d.fix(now)
+	d.lastFix = &f
+	return f
+}
+
+func (d *Device) tickGPS(ctx context.Context, now time.Time) {
+	req := d.currentFix(now)
+	req.Source = models.LocationSourcePeriodic
+	d.mu.Lock()
+	d.result.Fixes++
+	d.mu.Unlock()
+
+	// Точка встаёт в очередь за неотправленными — сервер получает их по порядку.
+	d.maybeGoOffline(now)
+	d.queue = append(d.queue, req)
+	if !d.online(now) {
+		d.mu.Lock()
+		d.result.MaxQueue = max(d.result.MaxQueue, len(d.queue))
+		d.mu.Unlock()
+		return
+	}
+	d.flush(ctx)
+}
+
+// flush отправляет офлайн-очередь по порядку; сетевая ошибка — остаток ждёт следующей попытки.
+func (d *Device) flush(ctx context.Context) {
+	if len(d.queue) == 0 {
+		return
+	}
+	if len(d.queue) > 1 {
+		d.mu.Lock()
+		d.result.Bursts++
+		d.mu.Unlock()
+	}
+	for len(d.queue) > 0 {
+		if !d.send(ctx, d.queue[0]) {
+			return
+		}
+		d.queue = d.queue[1:]
+	}
+}
+
+// send — POST /api/location. false — повторить позже (сеть, 5xx, 429); отказ 4xx не повторяется.
+func (d *Device) send(ctx context.Context, req client.LocationUploadRequest) bool {
+	start := time.Now()
+	resp, err := d.API.PostLocation(ctx, req)
+	d.Stats.Observe(OpLocation, time.Since(start), err)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.result.Sent++
+	status := client.StatusCode(err)
+	switch {
+	case err == nil && resp.Skipped:
+		d.result.Skipped++
+		d.Stats.Point(resp.Reason)
+	case err == nil:
+		d.result.Saved++
+		d.Stats.Point("saved")
+	case status >= 400 && status < 500 && status != 429:
+		d.result.Rejected++
+		d.Stats.Point("rejected")
+	default:
+		return false
+	}
+	return true
+}
+
+// poll забирает команды (сервер отдаёт по одной) и отвечает на каждую.
+func (d *Device) poll(ctx context.Context, now time.Time) {
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		resp, err := d.API.PollDevice(ctx, &client.PollDeviceParams{JSON: true})
+		d.Stats.Observe(OpPoll, time.Since(start), err)
+		if err != nil || resp == nil || resp.Command == nil {
+			return
+		}
+		d.handle(ctx, now, resp.Command)
+	}
+}
+
+func (d *Device) handle(ctx context.Context, now time.Time, cmd *client.PolledCommand) {
+	d.mu.Lock()
+	d.result.Commands++
+	d.mu.Unlock()
+
+	status, message := "ok", ""
+	switch cmd.Type {
+	case models.DeviceCommandTypeLocationRequest:
+		req := d.currentFix(now)
+		req.Source = models.LocationSourceOnDemand
+		req.RequestID = cmd.ID
+		if rid, ok := cmd.Payload["request_id"].(string); ok && rid != "" {
+			req.RequestID = rid
+		}
+		if !d.send(ctx, req) {
+			status, message = "error", "точка не отправлена"
+		}
+	case models.DeviceCommandTypeHealthCheck:
+		d.report(ctx, now)
+	case models.DeviceCommandTypeConfigUpdate:
+		// Новый ключ из config_update — как приложение после перегенерации QR.
+		if key, ok := cmd.Payload["api_key"].(string); ok && key != "" {
+			d.API.APIKey = key
+		}
+	case models.DeviceCommandTypeAppUpdate:
+		status, message = "failed", "симулятор не устанавливает обновления"
+	default:
+		status, message = "unsupported", "неизвестная команда "+cmd.Type
+	}
+
+	start := time.Now()
+	_, err := d.API.AckDeviceCommand(ctx, client.CommandAckRequest{CommandID: cmd.ID, Status: status, Message: message})
+	d.Stats.Observe(OpAck, time.Since(start), err)
+}
+
+func (d *Device) report(ctx context.Context, now time.Time) {
+	d.battery = math.Max(5, d.battery-0.2)
+	level := math.Round(d.battery)
+	charging := false
+	running := true
+	lastPost := now.UTC().Format(time.RFC3339)
+	payload := client.DeviceReportPayload{
+		AppVersion: "simulator",
+		Platform:   "android",
+		Battery:    &client.DeviceReportBattery{LevelPercent: &level, Charging: &charging},
+		Location:   &client.DeviceReportLocation{Permission: "always", ForegroundServiceRunning: &running, LastPostAt: &lastPost},
+		Network:    &client.DeviceReportNetwork{Type: "cellular"},
+	}
+	start := time.Now()
+	_, err := d.API.PostDeviceReport(ctx, payload)
+	d.Stats.Observe(OpReport, time.Since(start), err)
+	d.mu.Lock()
+	d.result.Reports++
+	d.mu.Unlock()
+}
@@ -0,0 +1,150 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"locator/client"
+)
+
+// fakeServer — протокол устройства: точки, одна команда в poll, ack и отчёты.
+type fakeServer struct {
+	mu        sync.Mutex
+	points    []client.LocationUploadRequest
+	acks      []client.CommandAckRequest
+	reports   int
+	commands  []client.PolledCommand
+	apiKeys   []string
+	offlineOK bool
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.apiKeys = append(f.apiKeys, r.Header.Get("X-API-Key"))
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/api/location":
+		var req client.LocationUploadRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.points = append(f.points, req)
+		_ = json.NewEncoder(w).Encode(client.LocationUploadResponse{ID: len(f.points), Source: req.Source})
+	case "/api/device/poll":
+		resp := client.DevicePollResponse{}
+		if len(f.commands) > 0 {
+			resp.Command = &f.commands[0]
+			f.commands = f.commands[1:]
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	case "/api/device/command/ack":
+		var req client.CommandAckRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.acks = append(f.acks, req)
+		_ = json.NewEncoder(w).Encode(client.CommandAckResponse{Ok: true})
+	case "/api/device/report":
+		f.reports++
+		_ = json.NewEncoder(w).Encode(client.DeviceReportResponse{ID: f.reports})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func runDevice(t *testing.T, f *fakeServer, behavior Behavior, duration time.Duration) *Device {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	api := client.New(srv.URL)
+	api.APIKey = "key-1"
+
+	origin := time.Date(2026, 7, 1, 7, 0, 0, 0, time.UTC)
+	track := Track{{At: 0, Point: minsk}, {At: duration, Point: minsk}}
+	// 1 час симуляции за ~0.1 с.
+	device := NewDevice(5, api, track, NewClock(origin, 36000), behavior, NewStats(), 42)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := device.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return device
+}
+
+func TestDevice_offlineQueueReplaysInOrder(t *testing.T) {
+	f := &fakeServer{}
+	behavior := Behavior{
+		GPSInterval: 30 * time.Second, PollInterval: time.Hour, ReportInterval: time.Hour,
+		AccuracyM: 10, OfflineRate: 0.4, OfflineMax: 10 * time.Minute,
+	}
+	device := runDevice(t, f, behavior, time.Hour)
+	res := device.Result()
+
+	if res.Sent != res.Fixes || len(f.points) != res.Fixes {
+		t.Fatalf("fixes %d, sent %d, server got %d — queue lost points", res.Fixes, res.Sent, len(f.points))
+	}
+	if res.Bursts == 0 || res.MaxQueue < 2 || res.OfflineTime == 0 {
+		t.Fatalf("expected offline bursts, got %+v", res)
+	}
+	var prev time.Time
+	for i, p := range f.points {
+		at, err := time.Parse(time.RFC3339, p.CapturedAt)
+		if err != nil {
+			t.Fatalf("point %d: captured_at %q: %v", i, p.CapturedAt, err)
+		}
+		if at.Before(prev) {
+			t.Fatalf("point %d captured at %s before previous %s", i, at, prev)
+		}
+		prev = at
+		if d := DistanceM(Point{p.Latitude, p.Longitude}, minsk); d > 60 {
+			t.Fatalf("point %d is %.0f m from the truth with 10 m accuracy", i, d)
+		}
+	}
+}
+
+func TestDevice_answersCommands(t *testing.T) {
+	f := &fakeServer{commands: []client.PolledCommand{
+		{ID: "c1", Type: "location_request", Payload: map[string]any{"request_id": "r1"}},
+		{ID: "c2", Type: "config_update", Payload: map[string]any{"api_key": "key-2"}},
+		{ID: "c3", Type: "health_check"},
+		{ID: "c4", Type: "app_update"},
+	}}
+	behavior := Behavior{GPSInterval: 10 * time.Minute, PollInterval: 5 * time.Minute, ReportInterval: time.Hour, AccuracyM: 10}
+	device := runDevice(t, f, behavior, 30*time.Minute)
+
+	want := map[string]string{"c1": "ok", "c2": "ok", "c3": "ok", "c4": "failed"}
+	if len(f.acks) != len(want) {
+		t.Fatalf("acks = %+v", f.acks)
+	}
+	for _, ack := range f.acks {
+		if want[ack.CommandID] != ack.Status {
+			t.Fatalf("ack %s = %q, want %q", ack.CommandID, ack.Status, want[ack.CommandID])
+		}
+	}
+	onDemand := 0
+	for _, p := range f.points {
+		if p.Source == "on_demand" {
+			onDemand++
+			if p.RequestID != "r1" {
+				t.Fatalf("on-demand point request_id = %q, want r1", p.RequestID)
+			}
+		}
+	}
+	if onDemand != 1 {
+		t.Fatalf("on-demand points = %d, want 1", onDemand)
+	}
+	if device.API.APIKey != "key-2" || f.apiKeys[len(f.apiKeys)-1] != "key-2" {
+		t.Fatalf("config_update key not applied: %q", device.API.APIKey)
+	}
+	// health_check + плановый отчёт в начале прогона.
+	if f.reports < 1 || device.Result().Commands != 4 {
+		t.Fatalf("reports %d, result %+v", f.reports, device.Result())
+	}
+
+	ops, points := device.Stats.Summary(time.Second)
+	if len(ops) != 4 || points["saved"] != len(f.points) {
+		t.Fatalf("stats = %+v %+v", ops, points)
+	}
+}
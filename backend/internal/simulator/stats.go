@@ -0,0 +1,114 @@
+package simulator
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Операции протокола устройства в статистике.
+const (
+	OpLocation = "location"
+	OpPoll     = "poll"
+	OpAck      = "ack"
+	OpReport   = "report"
+)
+
+// Stats — задержки и ошибки запросов всех устройств прогона; безопасна для горутин.
+type Stats struct {
+	mu      sync.Mutex
+	ops     map[string]*opStats
+	reasons map[string]int
+}
+
+type opStats struct {
+	latencies []time.Duration
+	errors    int
+}
+
+func NewStats() *Stats {
+	return &Stats{ops: make(map[string]*opStats), reasons: make(map[string]int)}
+}
+
+// Observe учитывает запрос op длительностью d; err != nil — ошибка (сеть или не 2xx).
+func (s *Stats) Observe(op string, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.ops[op]
+	if st == nil {
+		st = &opStats{}
+		s.ops[op] = st
+	}
+	st.latencies = append(st.latencies, d)
+	if err != nil {
+		st.errors++
+	}
+}
+
+// Point учитывает исход отправки точки: "saved" или причина пропуска сервером.
+func (s *Stats) Point(outcome string) {
+	s.mu.Lock()
+	s.reasons[outcome]++
+	s.mu.Unlock()
+}
+
+// OpSummary — итог по операции; задержки в миллисекундах.
+type OpSummary struct {
+	Op        string  `json:"op"`
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	PerSecond float64 `json:"per_second"`
+	P50Ms     float64 `json:"p50_ms"`
+	P95Ms     float64 `json:"p95_ms"`
+	P99Ms     float64 `json:"p99_ms"`
+	MaxMs     float64 `json:"max_ms"`
+}
+
+// Summary — итог по операциям (по имени) и исходы точек за прогон длительностью elapsed.
+func (s *Stats) Summary(elapsed time.Duration) ([]OpSummary, map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]OpSummary, 0, len(s.ops))
+	for op, st := range s.ops {
+		lat := append([]time.Duration(nil), st.latencies...)
+		sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+		sum := OpSummary{
+			Op:       op,
+			Requests: len(lat),
+			Errors:   st.errors,
+			P50Ms:    ms(percentile(lat, 0.50)),
+			P95Ms:    ms(percentile(lat, 0.95)),
+			P99Ms:    ms(percentile(lat, 0.99)),
+			MaxMs:    ms(percentile(lat, 1)),
+		}
+		if elapsed > 0 {
+			sum.PerSecond = float64(len(lat)) / elapsed.Seconds()
+		}
+		out = append(out, sum)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Op < out[j].Op })
+	reasons := make(map[string]int, len(s.reasons))
+	for k, v := range s.reasons {
+		reasons[k] = v
+	}
+	return out, reasons
+}
+
+// percentile — значение ранга q (0..1) отсортированной выборки, nearest-rank.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*q+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,211 @@
+// Package simulator — виртуальные телефоны для нагрузочных прогонов и демонстраций:
+// маршруты (GPX или прогулка между чекпоинтами), шум GPS, офлайн-очередь и протокол
+// устройства (точки, poll, ack, отчёты), а также эталонные визиты для сверки с сервером.
+package simulator
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const earthRadiusM = 6371000.0
+
+// Point — координаты в градусах.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// DistanceM — расстояние по большому кругу в метрах.
+func DistanceM(a, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusM * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Offset — точка в distM метрах от p по азимуту bearing (радианы, 0 — север).
+// Плоское приближение: смещения симулятора — сотни метров.
+func Offset(p Point, distM, bearing float64) Point {
+	dLat := distM * math.Cos(bearing) / earthRadiusM
+	dLon := distM * math.Sin(bearing) / (earthRadiusM * math.Cos(p.Lat*math.Pi/180))
+	return Point{Lat: p.Lat + dLat*180/math.Pi, Lon: p.Lon + dLon*180/math.Pi}
+}
+
+// Checkpoint — геозона для прогулки и эталонных визитов.
+type Checkpoint struct {
+	ID     int
+	Name   string
+	Center Point
+	Radius float64
+}
+
+// TrackPoint — истинное положение через At от начала маршрута.
+type TrackPoint struct {
+	At time.Duration
+	Point
+}
+
+// Track — маршрут устройства: положения по возрастанию At, между ними — линейно.
+type Track []TrackPoint
+
+// Duration — длительность маршрута.
+func (t Track) Duration() time.Duration {
+	if len(t) == 0 {
+		return 0
+	}
+	return t[len(t)-1].At
+}
+
+// Position — истинное положение через d от начала; за пределами маршрута — крайняя точка.
+func (t Track) Position(d time.Duration) Point {
+	if len(t) == 0 {
+		return Point{}
+	}
+	i := sort.Search(len(t), func(i int) bool { return t[i].At > d })
+	if i == 0 {
+		return t[0].Point
+	}
+	if i == len(t) {
+		return t[len(t)-1].Point
+	}
+	a, b := t[i-1], t[i]
+	if b.At == a.At {
+		return b.Point
+	}
+	f := float64(d-a.At) / float64(b.At-a.At)
+	return Point{Lat: a.Lat + (b.Lat-a.Lat)*f, Lon: a.Lon + (b.Lon-a.Lon)*f}
+}
+
+// Shift — тот же маршрут, начатый на d позже (для нескольких устройств по одному GPX).
+func (t Track) Shift(d time.Duration) Track {
+	out := make(Track, 0, len(t)+1)
+	if d > 0 && len(t) > 0 {
+		out = append(out, TrackPoint{At: 0, Point: t[0].Point})
+	}
+	for _, p := range t {
+		p.At += d
+		out = append(out, p)
+	}
+	return out
+}
+
+type gpxFile struct {
+	Points []gpxPoint `xml:"trk>trkseg>trkpt"`
+	Route  []gpxPoint `xml:"rte>rtept"`
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time"`
+}
+
+// ParseGPX читает трек (trkpt) или маршрут (rtept) GPX. Время точек задаёт темп;
+// без времени точки проходятся со скоростью speedMps.
+func ParseGPX(r io.Reader, speedMps float64) (Track, error) {
+	var doc gpxFile
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("GPX: %w", err)
+	}
+	points := doc.Points
+	if len(points) == 0 {
+		points = doc.Route
+	}
+	if len(points) < 2 {
+		return nil, errors.New("GPX: в треке меньше двух точек")
+	}
+
+	track := make(Track, 0, len(points))
+	var first time.Time
+	timed := true
+	for _, p := range points {
+		if p.Time == "" {
+			timed = false
+			break
+		}
+	}
+	for i, p := range points {
+		tp := TrackPoint{Point: Point{Lat: p.Lat, Lon: p.Lon}}
+		switch {
+		case timed:
+			at, err := time.Parse(time.RFC3339, p.Time)
+			if err != nil {
+				return nil, fmt.Errorf("GPX: время точки %d: %w", i, err)
+			}
+			if i == 0 {
+				first = at
+			}
+			tp.At = at.Sub(first)
+			if i > 0 && tp.At < track[i-1].At {
+				return nil, fmt.Errorf("GPX: время точки %d раньше предыдущей", i)
+			}
+		case i > 0:
+			if speedMps <= 0 {
+				return nil, errors.New("GPX без времени: нужна скорость")
+			}
+			prev := track[i-1]
+			tp.At = prev.At + time.Duration(DistanceM(prev.Point, tp.Point)/speedMps*float64(time.Second))
+		}
+		track = append(track, tp)
+	}
+	return track, nil
+}
+
+// WalkOptions — прогулка между чекпоинтами: переход со скоростью SpeedMps, стоянка
+// внутри геозоны от DwellMin до DwellMax.
+type WalkOptions struct {
+	Duration time.Duration
+	SpeedMps float64
+	DwellMin time.Duration
+	DwellMax time.Duration
+}
+
+// RandomWalk строит маршрут: стоянка в случайном чекпоинте, переход к другому и так
+// до Duration. Место стоянки — случайное в пределах половины радиуса от центра, чтобы
+// шум GPS не выводил точку за границу.
+func RandomWalk(rng *rand.Rand, checkpoints []Checkpoint, opts WalkOptions) (Track, error) {
+	if len(checkpoints) == 0 {
+		return nil, errors.New("нет чекпоинтов для маршрута")
+	}
+	if opts.SpeedMps <= 0 || opts.DwellMax < opts.DwellMin || opts.Duration <= 0 {
+		return nil, errors.New("некорректные параметры маршрута")
+	}
+	spot := func(cp Checkpoint) Point {
+		return Offset(cp.Center, rng.Float64()*cp.Radius/2, rng.Float64()*2*math.Pi)
+	}
+
+	current := rng.Intn(len(checkpoints))
+	pos := spot(checkpoints[current])
+	track := Track{{At: 0, Point: pos}}
+	at := time.Duration(0)
+	for at < opts.Duration {
+		dwell := opts.DwellMin
+		if opts.DwellMax > opts.DwellMin {
+			dwell += time.Duration(rng.Int63n(int64(opts.DwellMax - opts.DwellMin)))
+		}
+		at += dwell
+		track = append(track, TrackPoint{At: at, Point: pos})
+		if len(checkpoints) == 1 {
+			continue
+		}
+
+		next := rng.Intn(len(checkpoints) - 1)
+		if next >= current {
+			next++
+		}
+		current = next
+		target := spot(checkpoints[current])
+		at += time.Duration(DistanceM(pos, target) / opts.SpeedMps * float64(time.Second))
+		pos = target
+		track = append(track, TrackPoint{At: at, Point: pos})
+	}
+	return track, nil
+}
@@ -0,0 +1,114 @@
+package simulator
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+var minsk = Point{Lat: 53.9006, Lon: 27.5590}
+
+func TestOffset_distance(t *testing.T) {
+	for _, bearing := range []float64{0, math.Pi / 3, math.Pi, 1.5 * math.Pi} {
+		got := DistanceM(minsk, Offset(minsk, 250, bearing))
+		if math.Abs(got-250) > 1 {
+			t.Fatalf("bearing %.2f: distance = %.2f, want 250", bearing, got)
+		}
+	}
+}
+
+func TestTrack_positionInterpolates(t *testing.T) {
+	b := Offset(minsk, 1000, math.Pi/2)
+	track := Track{{At: 0, Point: minsk}, {At: 10 * time.Minute, Point: b}}
+
+	if got := DistanceM(track.Position(5*time.Minute), minsk); math.Abs(got-500) > 2 {
+		t.Fatalf("midpoint at %.1f m, want 500", got)
+	}
+	if track.Position(-time.Minute) != minsk || track.Position(time.Hour) != b {
+		t.Fatal("positions outside the track must clamp to its ends")
+	}
+	if shifted := track.Shift(time.Minute); shifted.Duration() != 11*time.Minute || shifted.Position(30*time.Second) != minsk {
+		t.Fatalf("shifted track: duration %s, position before start %+v", shifted.Duration(), shifted.Position(30*time.Second))
+	}
+}
+
+func TestParseGPX_timed(t *testing.T) {
+	gpx := `<?xml version="1.0"?>
+<gpx version="1.1"><trk><trkseg>
+  <trkpt lat="53.9000" lon="27.5500"><time>2026-07-01T07:00:00Z</time></trkpt>
+  <trkpt lat="53.9010" lon="27.5500"><time>2026-07-01T07:02:00Z</time></trkpt>
+  <trkpt lat="53.9020" lon="27.5500"><time>2026-07-01T07:10:00Z</time></trkpt>
+</trkseg></trk></gpx>`
+	track, err := ParseGPX(strings.NewReader(gpx), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(track) != 3 || track[1].At != 2*time.Minute || track.Duration() != 10*time.Minute {
+		t.Fatalf("track = %+v", track)
+	}
+}
+
+func TestParseGPX_untimedUsesSpeed(t *testing.T) {
+	gpx := `<gpx><rte>
+  <rtept lat="53.9000" lon="27.5500"/>
+  <rtept lat="53.9090" lon="27.5500"/>
+</rte></gpx>`
+	track, err := ParseGPX(strings.NewReader(gpx), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ~1000 м со скоростью 10 м/с.
+	if d := track.Duration(); d < 99*time.Second || d > 101*time.Second {
+		t.Fatalf("duration = %s, want ~100s", d)
+	}
+	if _, err := ParseGPX(strings.NewReader(gpx), 0); err == nil {
+		t.Fatal("untimed GPX without speed must fail")
+	}
+	if _, err := ParseGPX(strings.NewReader(`<gpx><trk><trkseg><trkpt lat="1" lon="1"/></trkseg></trk></gpx>`), 1); err == nil {
+		t.Fatal("single-point GPX must fail")
+	}
+}
+
+func TestRandomWalk_dwellsInsideCheckpoints(t *testing.T) {
+	checkpoints := []Checkpoint{
+		{ID: 1, Center: minsk, Radius: 100},
+		{ID: 2, Center: Offset(minsk, 2000, 0), Radius: 80},
+	}
+	opts := WalkOptions{Duration: 3 * time.Hour, SpeedMps: 1.4, DwellMin: 10 * time.Minute, DwellMax: 30 * time.Minute}
+	track, err := RandomWalk(rand.New(rand.NewSource(7)), checkpoints, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if track.Duration() < opts.Duration {
+		t.Fatalf("track ends at %s, before %s", track.Duration(), opts.Duration)
+	}
+	// Стоянки — пары точек с одинаковыми координатами, внутри половины радиуса.
+	dwells := 0
+	for i := 1; i < len(track); i++ {
+		if track[i].Point != track[i-1].Point {
+			continue
+		}
+		dwells++
+		inside := false
+		for _, cp := range checkpoints {
+			if DistanceM(track[i].Point, cp.Center) <= cp.Radius/2+0.5 {
+				inside = true
+			}
+		}
+		if !inside {
+			t.Fatalf("dwell at %+v is outside every checkpoint", track[i].Point)
+		}
+		if d := track[i].At - track[i-1].At; d < opts.DwellMin || d > opts.DwellMax {
+			t.Fatalf("dwell length %s outside [%s, %s]", d, opts.DwellMin, opts.DwellMax)
+		}
+	}
+	if dwells < 3 {
+		t.Fatalf("dwells = %d, want several", dwells)
+	}
+
+	if _, err := RandomWalk(rand.New(rand.NewSource(1)), nil, opts); err == nil {
+		t.Fatal("walk without checkpoints must fail")
+	}
+}
@@ -0,0 +1,129 @@
+package simulator
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Visit — визит в чекпоинт: эталонный (по истинному маршруту) или созданный сервером.
+// End == nil — визит не закрыт.
+type Visit struct {
+	CheckpointID int        `json:"checkpoint_id"`
+	Start        time.Time  `json:"start"`
+	End          *time.Time `json:"end,omitempty"`
+}
+
+// ExpectedVisits — эталон: интервалы, когда истинное положение было внутри радиуса
+// чекпоинта не меньше minDwell (короче — сервер удаляет). Маршрут проходится с шагом
+// step от origin; визит, продолжающийся в конце маршрута, остаётся открытым.
+func ExpectedVisits(track Track, origin time.Time, checkpoints []Checkpoint, step, minDwell time.Duration) []Visit {
+	var out []Visit
+	end := track.Duration()
+	for _, cp := range checkpoints {
+		var enteredAt time.Duration = -1
+		closeVisit := func(at time.Duration, open bool) {
+			if enteredAt >= 0 && at-enteredAt >= minDwell {
+				v := Visit{CheckpointID: cp.ID, Start: origin.Add(enteredAt)}
+				if !open {
+					t := origin.Add(at)
+					v.End = &t
+				}
+				out = append(out, v)
+			}
+			enteredAt = -1
+		}
+		for at := time.Duration(0); at <= end; at += step {
+			inside := DistanceM(track.Position(at), cp.Center) <= cp.Radius
+			switch {
+			case inside && enteredAt < 0:
+				enteredAt = at
+			case !inside && enteredAt >= 0:
+				closeVisit(at, false)
+			}
+		}
+		closeVisit(end, true)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+// VisitMatch — эталонный визит и найденный для него серверный; ошибки границ в секундах
+// (сервер позже эталона — положительные).
+type VisitMatch struct {
+	Expected     Visit   `json:"expected"`
+	Actual       Visit   `json:"actual"`
+	StartErrorS  float64 `json:"start_error_s"`
+	EndErrorS    float64 `json:"end_error_s,omitempty"`
+	WithinBounds bool    `json:"within_tolerance"`
+}
+
+// Comparison — сверка визитов сервера с эталоном.
+type Comparison struct {
+	Expected int          `json:"expected"`
+	Actual   int          `json:"actual"`
+	Matched  []VisitMatch `json:"matched"`
+	Missed   []Visit      `json:"missed"`
+	Extra    []Visit      `json:"extra"`
+}
+
+// Accurate — визиты, совпавшие с эталоном в пределах допуска.
+func (c Comparison) Accurate() int {
+	n := 0
+	for _, m := range c.Matched {
+		if m.WithinBounds {
+			n++
+		}
+	}
+	return n
+}
+
+// CompareVisits сопоставляет визиты одного устройства: пара — тот же чекпоинт и
+// пересечение интервалов (с допуском tolerance на каждой границе); из нескольких
+// кандидатов берётся ближайший по началу. Открытый визит сервера — до now.
+func CompareVisits(expected, actual []Visit, tolerance time.Duration, now time.Time) Comparison {
+	c := Comparison{Expected: len(expected), Actual: len(actual)}
+	used := make([]bool, len(actual))
+	end := func(v Visit) time.Time {
+		if v.End != nil {
+			return *v.End
+		}
+		return now
+	}
+	for _, exp := range expected {
+		best, bestDiff := -1, math.MaxFloat64
+		for i, act := range actual {
+			if used[i] || act.CheckpointID != exp.CheckpointID {
+				continue
+			}
+			if act.Start.After(end(exp).Add(tolerance)) || end(act).Add(tolerance).Before(exp.Start) {
+				continue
+			}
+			if diff := math.Abs(act.Start.Sub(exp.Start).Seconds()); diff < bestDiff {
+				best, bestDiff = i, diff
+			}
+		}
+		if best < 0 {
+			c.Missed = append(c.Missed, exp)
+			continue
+		}
+		used[best] = true
+		act := actual[best]
+		m := VisitMatch{Expected: exp, Actual: act, StartErrorS: act.Start.Sub(exp.Start).Seconds()}
+		m.WithinBounds = math.Abs(m.StartErrorS) <= tolerance.Seconds()
+		switch {
+		case exp.End != nil && act.End != nil:
+			m.EndErrorS = act.End.Sub(*exp.End).Seconds()
+			m.WithinBounds = m.WithinBounds && math.Abs(m.EndErrorS) <= tolerance.Seconds()
+		case (exp.End == nil) != (act.End == nil):
+			m.WithinBounds = false
+		}
+		c.Matched = append(c.Matched, m)
+	}
+	for i, act := range actual {
+		if !used[i] {
+			c.Extra = append(c.Extra, act)
+		}
+	}
+	return c
+}
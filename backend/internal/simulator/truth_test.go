@@ -0,0 +1,84 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExpectedVisits_dwellAndMinDuration(t *testing.T) {
+	cp := Checkpoint{ID: 3, Center: minsk, Radius: 100}
+	far := Offset(minsk, 1000, math.Pi)
+	origin := time.Date(2026, 7, 1, 7, 0, 0, 0, time.UTC)
+	track := Track{
+		{At: 0, Point: far},
+		{At: 10 * time.Minute, Point: minsk}, // вход около 9:00
+		{At: 30 * time.Minute, Point: minsk},
+		{At: 40 * time.Minute, Point: far}, // выход около 31:00
+		{At: 50 * time.Minute, Point: far},
+		{At: 60 * time.Minute, Point: minsk}, // вход около 59:00, до конца
+		{At: 65 * time.Minute, Point: minsk},
+	}
+
+	visits := ExpectedVisits(track, origin, []Checkpoint{cp}, 10*time.Second, time.Minute)
+	if len(visits) != 2 {
+		t.Fatalf("visits = %+v, want 2", visits)
+	}
+	first := visits[0]
+	if first.CheckpointID != 3 || first.End == nil {
+		t.Fatalf("first visit = %+v", first)
+	}
+	if d := first.Start.Sub(origin.Add(9 * time.Minute)); d < -20*time.Second || d > 20*time.Second {
+		t.Fatalf("first start off by %s", d)
+	}
+	if d := first.End.Sub(origin.Add(31 * time.Minute)); d < -20*time.Second || d > 20*time.Second {
+		t.Fatalf("first end off by %s", d)
+	}
+	if visits[1].End != nil {
+		t.Fatalf("visit still in progress must stay open: %+v", visits[1])
+	}
+
+	// Проход насквозь короче minDwell — не визит.
+	pass := Track{{At: 0, Point: far}, {At: 2 * time.Minute, Point: Offset(minsk, 1000, 0)}}
+	if v := ExpectedVisits(pass, origin, []Checkpoint{cp}, time.Second, 5*time.Minute); len(v) != 0 {
+		t.Fatalf("pass-through produced visits: %+v", v)
+	}
+}
+
+func TestCompareVisits(t *testing.T) {
+	at := func(min int) time.Time { return time.Date(2026, 7, 1, 7, min, 0, 0, time.UTC) }
+	ptr := func(t time.Time) *time.Time { return &t }
+	expected := []Visit{
+		{CheckpointID: 1, Start: at(0), End: ptr(at(20))},
+		{CheckpointID: 2, Start: at(30), End: ptr(at(40))},
+		{CheckpointID: 1, Start: at(50)},
+	}
+	actual := []Visit{
+		{CheckpointID: 1, Start: at(1), End: ptr(at(21))},  // в допуске
+		{CheckpointID: 1, Start: at(52)},                   // открыт, начало в допуске
+		{CheckpointID: 3, Start: at(10), End: ptr(at(15))}, // лишний
+	}
+
+	c := CompareVisits(expected, actual, 3*time.Minute, at(60))
+	if c.Expected != 3 || c.Actual != 3 {
+		t.Fatalf("counts = %d/%d", c.Expected, c.Actual)
+	}
+	if len(c.Matched) != 2 || c.Accurate() != 2 {
+		t.Fatalf("matched = %+v", c.Matched)
+	}
+	if c.Matched[0].StartErrorS != 60 || c.Matched[0].EndErrorS != 60 {
+		t.Fatalf("errors = %+v", c.Matched[0])
+	}
+	if len(c.Missed) != 1 || c.Missed[0].CheckpointID != 2 {
+		t.Fatalf("missed = %+v", c.Missed)
+	}
+	if len(c.Extra) != 1 || c.Extra[0].CheckpointID != 3 {
+		t.Fatalf("extra = %+v", c.Extra)
+	}
+
+	// Совпадение с ошибкой больше допуска учитывается, но не точное.
+	late := CompareVisits(expected[:1], []Visit{{CheckpointID: 1, Start: at(10), End: ptr(at(20))}}, 3*time.Minute, at(60))
+	if len(late.Matched) != 1 || late.Accurate() != 0 {
+		t.Fatalf("late = %+v", late)
+	}
+}
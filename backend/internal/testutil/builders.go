@@ -43,18 +43,26 @@ func Checkpoint(id int, name string, lat, lon, radius float64) models.Checkpoint
 	}
 }
 
-// UserWithAPIKey hashes plainKey with bcrypt and returns a user ready for auth tests.
-func UserWithAPIKey(id int, name, plainKey string, isAdmin bool) (models.User, error) {
+// UserWithAPIKey returns a user and its primary API key (plainKey hashed with bcrypt)
+// ready for auth tests.
+func UserWithAPIKey(id int, name, plainKey string, isAdmin bool) (models.User, models.APIKey, error) {
 	hashed, err := bcrypt.GenerateFromPassword([]byte(plainKey), bcrypt.MinCost)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, models.APIKey{}, err
 	}
-	return models.User{
+	user := models.User{
 		ID:      id,
 		Name:    name,
-		ApiKey:  string(hashed),
 		IsAdmin: isAdmin,
-	}, nil
+	}
+	key := models.APIKey{
+		ID:      id,
+		UserID:  id,
+		Label:   "QR",
+		KeyHash: string(hashed),
+		Primary: true,
+	}
+	return user, key, nil
 }
 
 // VisitActive builds an open visit.
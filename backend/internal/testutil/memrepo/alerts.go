@@ -0,0 +1,119 @@
+package memrepo
+
+import "locator/models"
+
+// Alerts mirrors dao.AlertDAO (rules and alerts).
+type Alerts struct {
+	s      *Store
+	rules  table[int, models.AlertRule]
+	alerts table[int, models.Alert]
+}
+
+func alertRuleKey(rule *models.AlertRule) int { return rule.ID }
+
+func alertKey(alert *models.Alert) int { return alert.ID }
+
+func byRuleID(a, b *models.AlertRule) bool { return a.ID < b.ID }
+
+func (r *Alerts) CreateRule(rule *models.AlertRule) error {
+	return r.rules.insert(r.s, rule, alertRuleKey)
+}
+
+func (r *Alerts) UpdateRule(rule *models.AlertRule) error {
+	return r.rules.save(r.s, rule, alertRuleKey)
+}
+
+func (r *Alerts) DeleteRule(id int) error {
+	r.rules.delete(func(rule *models.AlertRule) bool { return rule.ID == id })
+	return nil
+}
+
+func (r *Alerts) GetRuleByID(id int) (*models.AlertRule, error) {
+	return r.rules.first(func(rule *models.AlertRule) bool { return rule.ID == id }, nil)
+}
+
+func (r *Alerts) GetAllRules() ([]models.AlertRule, error) {
+	return r.rules.find(nil, byRuleID), nil
+}
+
+func (r *Alerts) GetRulesByOrganization(organizationID int) ([]models.AlertRule, error) {
+	return r.rules.find(func(rule *models.AlertRule) bool { return rule.OrganizationID == organizationID }, byRuleID), nil
+}
+
+func (r *Alerts) CreateAlert(alert *models.Alert) error { return r.alerts.insert(r.s, alert, alertKey) }
+
+func (r *Alerts) UpdateAlert(alert *models.Alert) error { return r.alerts.save(r.s, alert, alertKey) }
+
+func (r *Alerts) GetAlertByID(id int) (*models.Alert, error) {
+	return r.alerts.first(func(alert *models.Alert) bool { return alert.ID == id }, nil)
+}
+
+func (r *Alerts) GetActiveAlerts() ([]models.Alert, error) {
+	return r.alerts.find(func(alert *models.Alert) bool { return alert.Status != models.AlertStatusResolved },
+		func(a, b *models.Alert) bool { return a.ID < b.ID }), nil
+}
+
+func (r *Alerts) ListAlerts(filters map[string]interface{}, limit, offset int) ([]models.Alert, int64, error) {
+	alerts := r.alerts.find(func(alert *models.Alert) bool { return matchFilters(alert, filters) },
+		func(a, b *models.Alert) bool {
+			if !a.LastSeenAt.Equal(b.LastSeenAt) {
+				return a.LastSeenAt.After(b.LastSeenAt)
+			}
+			return a.ID > b.ID
+		})
+	return page(alerts, limit, offset), int64(len(alerts)), nil
+}
+
+// Notifications mirrors dao.NotificationDAO (subscriptions and the delivery log).
+type Notifications struct {
+	s          *Store
+	subs       table[int, models.NotificationSubscription]
+	deliveries table[int, models.NotificationDelivery]
+}
+
+func subscriptionKey(sub *models.NotificationSubscription) int { return sub.ID }
+
+func bySubscriptionID(a, b *models.NotificationSubscription) bool { return a.ID < b.ID }
+
+func (r *Notifications) CreateSubscription(sub *models.NotificationSubscription) error {
+	return r.subs.insert(r.s, sub, subscriptionKey)
+}
+
+func (r *Notifications) UpdateSubscription(sub *models.NotificationSubscription) error {
+	return r.subs.save(r.s, sub, subscriptionKey)
+}
+
+func (r *Notifications) DeleteSubscription(id int) error {
+	r.subs.delete(func(sub *models.NotificationSubscription) bool { return sub.ID == id })
+	return nil
+}
+
+func (r *Notifications) GetSubscriptionByID(id int) (*models.NotificationSubscription, error) {
+	return r.subs.first(func(sub *models.NotificationSubscription) bool { return sub.ID == id }, nil)
+}
+
+func (r *Notifications) GetSubscriptionsByUser(userID int) ([]models.NotificationSubscription, error) {
+	return r.subs.find(func(sub *models.NotificationSubscription) bool {
+		return userID == 0 || sub.UserID == userID
+	}, bySubscriptionID), nil
+}
+
+func (r *Notifications) GetEnabledSubscriptions() ([]models.NotificationSubscription, error) {
+	return r.subs.find(func(sub *models.NotificationSubscription) bool { return sub.Enabled }, bySubscriptionID), nil
+}
+
+func (r *Notifications) CreateDelivery(d *models.NotificationDelivery) error {
+	return r.deliveries.insert(r.s, d, func(d *models.NotificationDelivery) int { return d.ID })
+}
+
+func (r *Notifications) GetDeliveries(userID, limit int) ([]models.NotificationDelivery, error) {
+	items := r.deliveries.find(func(d *models.NotificationDelivery) bool {
+		return userID == 0 || d.UserID == userID
+	}, func(a, b *models.NotificationDelivery) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	return page(items, limit, 0), nil
+}
@@ -0,0 +1,475 @@
+package memrepo
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"locator/dao"
+	"locator/models"
+)
+
+// DeviceConfigs mirrors dao.DeviceConfigDAO (profiles and desired configs).
+type DeviceConfigs struct {
+	s        *Store
+	profiles table[int, models.DeviceConfigProfile]
+	desired  table[int, models.DeviceDesiredConfig]
+}
+
+func profileKey(p *models.DeviceConfigProfile) int { return p.ID }
+
+func desiredKey(cfg *models.DeviceDesiredConfig) int { return cfg.UserID }
+
+func (r *DeviceConfigs) CreateProfile(p *models.DeviceConfigProfile) error {
+	return r.profiles.insert(r.s, p, profileKey)
+}
+
+// UpdateProfile, like the DAO's scoped UPDATE, leaves a profile of another
+// organisation untouched.
+func (r *DeviceConfigs) UpdateProfile(p *models.DeviceConfigProfile) error {
+	if _, err := r.GetProfileByID(p.OrganizationID, p.ID); err != nil {
+		return nil
+	}
+	return r.profiles.save(r.s, p, profileKey)
+}
+
+func (r *DeviceConfigs) GetProfileByID(organizationID, id int) (*models.DeviceConfigProfile, error) {
+	return r.profiles.first(func(p *models.DeviceConfigProfile) bool {
+		return p.ID == id && p.OrganizationID == organizationID
+	}, nil)
+}
+
+func (r *DeviceConfigs) GetAllProfiles(organizationID int) ([]models.DeviceConfigProfile, error) {
+	return r.profiles.find(func(p *models.DeviceConfigProfile) bool { return p.OrganizationID == organizationID },
+		func(a, b *models.DeviceConfigProfile) bool { return a.ID < b.ID }), nil
+}
+
+func (r *DeviceConfigs) GetDesired(userID int) (*models.DeviceDesiredConfig, error) {
+	return r.desired.first(func(cfg *models.DeviceDesiredConfig) bool { return cfg.UserID == userID }, nil)
+}
+
+func (r *DeviceConfigs) SaveDesired(cfg *models.DeviceDesiredConfig) error {
+	return r.desired.save(r.s, cfg, desiredKey)
+}
+
+func (r *DeviceConfigs) GetAllDesired(organizationID int) ([]models.DeviceDesiredConfig, error) {
+	return r.desired.find(func(cfg *models.DeviceDesiredConfig) bool { return cfg.OrganizationID == organizationID }, func(a, b *models.DeviceDesiredConfig) bool { return a.UserID < b.UserID }), nil
+}
+
+func (r *DeviceConfigs) MarkPushed(userID int, hash string, at time.Time) error {
+	r.desired.update(r.s, func(cfg *models.DeviceDesiredConfig) bool { return cfg.UserID == userID },
+		func(cfg *models.DeviceDesiredConfig) {
+			cfg.LastPushedHash = hash
+			cfg.LastPushedAt = &at
+		})
+	return nil
+}
+
+// TrackingSchedules mirrors dao.TrackingScheduleDAO.
+type TrackingSchedules struct {
+	s *Store
+	t table[int, models.TrackingSchedule]
+}
+
+func (r *TrackingSchedules) GetSchedule(userID int) (*models.TrackingSchedule, error) {
+	return r.t.first(func(s *models.TrackingSchedule) bool { return s.UserID == userID }, nil)
+}
+
+func (r *TrackingSchedules) SaveSchedule(s *models.TrackingSchedule) error {
+	return r.t.save(r.s, s, func(s *models.TrackingSchedule) int { return s.UserID })
+}
+
+func (r *TrackingSchedules) DeleteSchedule(userID int) error {
+	r.t.delete(func(s *models.TrackingSchedule) bool { return s.UserID == userID })
+	return nil
+}
+
+func (r *TrackingSchedules) GetEnabledSchedules() ([]models.TrackingSchedule, error) {
+	return r.t.find(func(s *models.TrackingSchedule) bool { return s.Enabled },
+		func(a, b *models.TrackingSchedule) bool { return a.UserID < b.UserID }), nil
+}
+
+func (r *TrackingSchedules) MarkPushed(userID int, paused bool, at time.Time) error {
+	r.t.update(r.s, func(s *models.TrackingSchedule) bool { return s.UserID == userID },
+		func(s *models.TrackingSchedule) {
+			s.LastPushedPaused = &paused
+			s.LastPushedAt = &at
+		})
+	return nil
+}
+
+// DeviceReports mirrors dao.DeviceReportDAO, including the batch queries.
+type DeviceReports struct {
+	s *Store
+	t table[int, models.DeviceReport]
+}
+
+func byReportCreatedDesc(a, b *models.DeviceReport) bool { return a.CreatedAt.After(b.CreatedAt) }
+
+func (r *DeviceReports) Create(report *models.DeviceReport) error {
+	return r.t.insert(r.s, report, func(rep *models.DeviceReport) int { return rep.ID })
+}
+
+func (r *DeviceReports) GetLatestByUserID(userID int) (*models.DeviceReport, error) {
+	return r.t.first(func(rep *models.DeviceReport) bool { return rep.UserID == userID }, byReportCreatedDesc)
+}
+
+func (r *DeviceReports) ListByUserBetween(userID int, from, to time.Time, limit, offset int) ([]models.DeviceReport, int64, error) {
+	reports := r.t.find(func(rep *models.DeviceReport) bool {
+		return rep.UserID == userID && between(rep.CreatedAt, from, to)
+	}, byReportCreatedDesc)
+	return page(reports, limit, offset), int64(len(reports)), nil
+}
+
+func (r *DeviceReports) GetByUserBetweenAsc(userID int, from, to time.Time) ([]models.DeviceReport, error) {
+	return r.t.find(func(rep *models.DeviceReport) bool {
+		return rep.UserID == userID && between(rep.CreatedAt, from, to)
+	}, func(a, b *models.DeviceReport) bool { return a.CreatedAt.Before(b.CreatedAt) }), nil
+}
+
+// latestPerUser — the newest matching report of each user, ordered by user_id.
+func (r *DeviceReports) latestPerUser(match func(*models.DeviceReport) bool) []models.DeviceReport {
+	var out []models.DeviceReport
+	seen := make(map[int]bool)
+	for _, rep := range r.t.find(match, byReportCreatedDesc) {
+		if !seen[rep.UserID] {
+			seen[rep.UserID] = true
+			out = append(out, rep)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].UserID < out[j].UserID })
+	return out
+}
+
+func (r *DeviceReports) GetLatestPerUser(organizationID int) ([]dao.LatestDeviceReportRow, error) {
+	inOrg := r.s.Users.inOrganizationOrAll(organizationID)
+	reports := r.latestPerUser(func(rep *models.DeviceReport) bool { return inOrg(rep.UserID) })
+	rows := make([]dao.LatestDeviceReportRow, 0, len(reports))
+	for _, rep := range reports {
+		rows = append(rows, dao.LatestDeviceReportRow{
+			UserID:         rep.UserID,
+			AppVersion:     rep.AppVersion,
+			Platform:       rep.Platform,
+			Issues:         rep.Issues,
+			BatteryPercent: batteryPercent(rep.Report),
+			CreatedAt:      rep.CreatedAt,
+		})
+	}
+	return rows, nil
+}
+
+// batteryPercent is report->'battery'->'level_percent' when it is a number.
+func batteryPercent(report []byte) *float64 {
+	var body struct {
+		Battery struct {
+			LevelPercent any `json:"level_percent"`
+		} `json:"battery"`
+	}
+	if json.Unmarshal(report, &body) != nil {
+		return nil
+	}
+	if v, ok := body.Battery.LevelPercent.(float64); ok {
+		return &v
+	}
+	return nil
+}
+
+// reportedConfig is report->'config' (nil when the key is absent).
+func reportedConfig(report []byte) []byte {
+	var body map[string]json.RawMessage
+	if json.Unmarshal(report, &body) != nil {
+		return nil
+	}
+	return body["config"]
+}
+
+func (r *DeviceReports) GetLatestConfigPerUser(organizationID int) ([]dao.LatestReportedConfigRow, error) {
+	inOrg := r.s.Users.inOrganization(organizationID)
+	reports := r.latestPerUser(func(rep *models.DeviceReport) bool {
+		return inOrg(rep.UserID) && reportedConfig(rep.Report) != nil
+	})
+	rows := make([]dao.LatestReportedConfigRow, 0, len(reports))
+	for _, rep := range reports {
+		rows = append(rows, dao.LatestReportedConfigRow{UserID: rep.UserID, Config: reportedConfig(rep.Report), CreatedAt: rep.CreatedAt})
+	}
+	return rows, nil
+}
+
+func (r *DeviceReports) GetLatestConfigByUserID(userID int) (*dao.LatestReportedConfigRow, error) {
+	rep, err := r.t.first(func(rep *models.DeviceReport) bool {
+		return rep.UserID == userID && reportedConfig(rep.Report) != nil
+	}, byReportCreatedDesc)
+	if err != nil {
+		return nil, nil
+	}
+	return &dao.LatestReportedConfigRow{UserID: rep.UserID, Config: reportedConfig(rep.Report), CreatedAt: rep.CreatedAt}, nil
+}
+
+// DeviceCommands mirrors dao.DeviceCommandDAO.
+type DeviceCommands struct {
+	s *Store
+	t table[string, models.DeviceCommand]
+}
+
+// Create takes the organisation from the recipient, as the DAO does.
+func (r *DeviceCommands) Create(cmd *models.DeviceCommand) error {
+	if cmd.OrganizationID == 0 {
+		if u, err := r.s.Users.GetByID(cmd.UserID); err == nil {
+			cmd.OrganizationID = u.OrganizationID
+		}
+	}
+	return r.t.insert(r.s, cmd, func(c *models.DeviceCommand) string { return c.ID })
+}
+
+func (r *DeviceCommands) GetByID(id string) (*models.DeviceCommand, error) {
+	return r.t.first(func(c *models.DeviceCommand) bool { return c.ID == id }, nil)
+}
+
+func (r *DeviceCommands) GetNextPending(userID int) (*models.DeviceCommand, error) {
+	return r.t.first(func(c *models.DeviceCommand) bool {
+		return c.UserID == userID && c.Status == models.DeviceCommandStatusPending
+	}, func(a, b *models.DeviceCommand) bool { return a.CreatedAt.Before(b.CreatedAt) })
+}
+
+func (r *DeviceCommands) set(id string, fn func(*models.DeviceCommand)) error {
+	r.t.update(r.s, func(c *models.DeviceCommand) bool { return c.ID == id }, fn)
+	return nil
+}
+
+func (r *DeviceCommands) MarkDelivered(id string, at time.Time) error {
+	return r.set(id, func(c *models.DeviceCommand) {
+		c.Status = models.DeviceCommandStatusDelivered
+		c.DeliveredAt = &at
+	})
+}
+
+func (r *DeviceCommands) MarkAcked(id, ackStatus, ackMessage string, at time.Time) error {
+	return r.set(id, func(c *models.DeviceCommand) {
+		c.Status = models.DeviceCommandStatusAcked
+		c.AckStatus, c.AckMessage, c.AckedAt = ackStatus, ackMessage, &at
+	})
+}
+
+func (r *DeviceCommands) MarkFailed(id, ackStatus, ackMessage string, at time.Time) error {
+	return r.set(id, func(c *models.DeviceCommand) {
+		c.Status = models.DeviceCommandStatusFailed
+		c.AckStatus, c.AckMessage, c.AckedAt = ackStatus, ackMessage, &at
+	})
+}
+
+func (r *DeviceCommands) MarkProgress(id, ackStatus, ackMessage string, at time.Time) error {
+	return r.set(id, func(c *models.DeviceCommand) {
+		c.AckStatus, c.AckMessage, c.AckedAt = ackStatus, ackMessage, &at
+	})
+}
+
+// expire marks undelivered or unacknowledged commands created before cutoff
+// expired and returns their types.
+func (r *DeviceCommands) expire(cutoff time.Time, match func(*models.DeviceCommand) bool) []string {
+	types := []string{}
+	r.t.update(r.s, func(c *models.DeviceCommand) bool {
+		return (c.Status == models.DeviceCommandStatusPending || c.Status == models.DeviceCommandStatusDelivered) &&
+			c.CreatedAt.Before(cutoff) && match(c)
+	}, func(c *models.DeviceCommand) {
+		c.Status = models.DeviceCommandStatusExpired
+		types = append(types, c.Type)
+	})
+	return types
+}
+
+func (r *DeviceCommands) ExpirePendingOlderThan(cutoff time.Time) ([]string, error) {
+	return r.ExpirePendingOlderThanExceptType(cutoff, "")
+}
+
+func (r *DeviceCommands) ExpirePendingOlderThanExceptType(cutoff time.Time, exceptType string) ([]string, error) {
+	return r.expire(cutoff, func(c *models.DeviceCommand) bool { return exceptType == "" || c.Type != exceptType }), nil
+}
+
+func (r *DeviceCommands) ExpirePendingOlderThanType(cutoff time.Time, cmdType string) ([]string, error) {
+	return r.expire(cutoff, func(c *models.DeviceCommand) bool { return c.Type == cmdType }), nil
+}
+
+func (r *DeviceCommands) CancelPendingForUser(userID int, cmdType, excludeID string) error {
+	r.t.update(r.s, func(c *models.DeviceCommand) bool {
+		return c.UserID == userID && c.Status == models.DeviceCommandStatusPending && c.Type == cmdType &&
+			(excludeID == "" || c.ID != excludeID)
+	}, func(c *models.DeviceCommand) { c.Status = models.DeviceCommandStatusExpired })
+	return nil
+}
+
+func (r *DeviceCommands) GetLatestByType(userID int, cmdType string) (*models.DeviceCommand, error) {
+	return r.t.first(func(c *models.DeviceCommand) bool { return c.UserID == userID && c.Type == cmdType },
+		func(a, b *models.DeviceCommand) bool { return a.CreatedAt.After(b.CreatedAt) })
+}
+
+func (r *DeviceCommands) CountAppUpdateResults(releaseID int) (total, failed int64, err error) {
+	want := strconv.Itoa(releaseID)
+	for _, c := range r.t.find(func(c *models.DeviceCommand) bool {
+		return c.Type == models.DeviceCommandTypeAppUpdate &&
+			(c.Status == models.DeviceCommandStatusAcked || c.Status == models.DeviceCommandStatusFailed) &&
+			payloadText(c.Payload, "release_id") == want
+	}, nil) {
+		total++
+		if c.Status == models.DeviceCommandStatusFailed {
+			failed++
+		}
+	}
+	return total, failed, nil
+}
+
+// payloadText is payload->>key.
+func payloadText(payload []byte, key string) string {
+	var body map[string]any
+	if json.Unmarshal(payload, &body) != nil {
+		return ""
+	}
+	switch v := body[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// LocationRequests mirrors dao.LocationRequestDAO.
+type LocationRequests struct {
+	s *Store
+	t table[string, models.LocationRequest]
+}
+
+func (r *LocationRequests) Create(req *models.LocationRequest) error {
+	return r.t.insert(r.s, req, func(q *models.LocationRequest) string { return q.ID })
+}
+
+func (r *LocationRequests) GetByID(id string) (*models.LocationRequest, error) {
+	return r.t.first(func(q *models.LocationRequest) bool { return q.ID == id }, nil)
+}
+
+func (r *LocationRequests) GetPendingByUserID(userID int) (*models.LocationRequest, error) {
+	return r.t.first(func(q *models.LocationRequest) bool {
+		return q.UserID == userID && q.Status == models.LocationRequestStatusPending
+	}, func(a, b *models.LocationRequest) bool { return a.CreatedAt.After(b.CreatedAt) })
+}
+
+func (r *LocationRequests) UpdateStatus(id, status string, completedAt *time.Time) error {
+	r.t.update(r.s, func(q *models.LocationRequest) bool { return q.ID == id }, func(q *models.LocationRequest) {
+		q.Status = status
+		if completedAt != nil {
+			at := *completedAt
+			q.CompletedAt = &at
+		}
+	})
+	return nil
+}
+
+func (r *LocationRequests) ExpirePendingOlderThan(cutoff time.Time) error {
+	r.t.update(r.s, func(q *models.LocationRequest) bool {
+		return q.Status == models.LocationRequestStatusPending && q.CreatedAt.Before(cutoff)
+	}, func(q *models.LocationRequest) { q.Status = models.LocationRequestStatusExpired })
+	return nil
+}
+
+func (r *LocationRequests) CancelPendingForUser(userID int, excludeID string) error {
+	r.t.update(r.s, func(q *models.LocationRequest) bool {
+		return q.UserID == userID && q.Status == models.LocationRequestStatusPending && (excludeID == "" || q.ID != excludeID)
+	}, func(q *models.LocationRequest) { q.Status = models.LocationRequestStatusExpired })
+	return nil
+}
+
+func (r *LocationRequests) GetExpiredSince(since time.Time) ([]models.LocationRequest, error) {
+	return r.t.find(func(q *models.LocationRequest) bool {
+		return q.Status == models.LocationRequestStatusExpired && q.CompletedAt == nil && !q.CreatedAt.Before(since)
+	}, func(a, b *models.LocationRequest) bool { return a.CreatedAt.Before(b.CreatedAt) }), nil
+}
+
+// AppReleases mirrors dao.AppReleaseDAO (releases, channels and members).
+type AppReleases struct {
+	s        *Store
+	releases table[int, models.AppRelease]
+	channels table[channelKey, models.AppReleaseChannel]
+	members  table[int, models.AppReleaseMember]
+}
+
+type channelKey struct {
+	organizationID int
+	name           string
+}
+
+func releaseKey(rel *models.AppRelease) int { return rel.ID }
+
+func byVersionDesc(a, b *models.AppRelease) bool {
+	if a.VersionCode != b.VersionCode {
+		return a.VersionCode > b.VersionCode
+	}
+	return a.ID > b.ID
+}
+
+func (r *AppReleases) CreateRelease(release *models.AppRelease) error {
+	return r.releases.insert(r.s, release, releaseKey)
+}
+
+func (r *AppReleases) UpdateRelease(release *models.AppRelease) error {
+	return r.releases.save(r.s, release, releaseKey)
+}
+
+func (r *AppReleases) GetReleaseByID(id int) (*models.AppRelease, error) {
+	return r.releases.first(func(rel *models.AppRelease) bool { return rel.ID == id }, nil)
+}
+
+func (r *AppReleases) ListReleases(organizationID int, channel string) ([]models.AppRelease, error) {
+	return r.releases.find(func(rel *models.AppRelease) bool {
+		return rel.OrganizationID == organizationID && (channel == "" || rel.Channel == channel)
+	}, byVersionDesc), nil
+}
+
+func (r *AppReleases) GetActiveReleases(organizationID int, channels []string) ([]models.AppRelease, error) {
+	in := make(map[string]bool, len(channels))
+	for _, ch := range channels {
+		in[ch] = true
+	}
+	return r.releases.find(func(rel *models.AppRelease) bool {
+		return rel.OrganizationID == organizationID && in[rel.Channel] && rel.Status == models.AppReleaseStatusActive
+	}, byVersionDesc), nil
+}
+
+func (r *AppReleases) GetChannel(organizationID int, name string) (*models.AppReleaseChannel, error) {
+	return r.channels.first(func(ch *models.AppReleaseChannel) bool {
+		return ch.OrganizationID == organizationID && ch.Name == name
+	}, nil)
+}
+
+func (r *AppReleases) GetAllChannels(organizationID int) ([]models.AppReleaseChannel, error) {
+	return r.channels.find(func(ch *models.AppReleaseChannel) bool { return ch.OrganizationID == organizationID },
+		func(a, b *models.AppReleaseChannel) bool { return a.Name < b.Name }), nil
+}
+
+func (r *AppReleases) SaveChannel(ch *models.AppReleaseChannel) error {
+	return r.channels.save(r.s, ch, func(ch *models.AppReleaseChannel) channelKey {
+		return channelKey{organizationID: ch.OrganizationID, name: ch.Name}
+	})
+}
+
+func (r *AppReleases) GetMember(userID int) (*models.AppReleaseMember, error) {
+	return r.members.first(func(m *models.AppReleaseMember) bool { return m.UserID == userID }, nil)
+}
+
+func (r *AppReleases) GetAllMembers(organizationID int) ([]models.AppReleaseMember, error) {
+	inOrg := r.s.Users.inOrganization(organizationID)
+	return r.members.find(func(m *models.AppReleaseMember) bool { return inOrg(m.UserID) },
+		func(a, b *models.AppReleaseMember) bool { return a.UserID < b.UserID }), nil
+}
+
+func (r *AppReleases) SaveMember(m *models.AppReleaseMember) error {
+	return r.members.save(r.s, m, func(m *models.AppReleaseMember) int { return m.UserID })
+}
+
+func (r *AppReleases) DeleteMember(userID int) error {
+	r.members.delete(func(m *models.AppReleaseMember) bool { return m.UserID == userID })
+	return nil
+}
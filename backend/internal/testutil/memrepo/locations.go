@@ -0,0 +1,298 @@
+package memrepo
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"locator/dao"
+	"locator/models"
+)
+
+// Locations mirrors dao.LocationDAO. The table is never partitioned:
+// the partition methods do nothing.
+type Locations struct {
+	s *Store
+	t table[int, models.Location]
+}
+
+func locationKey(l *models.Location) int { return l.ID }
+
+// effectiveAt is COALESCE(captured_at, created_at).
+func effectiveAt(l *models.Location) time.Time {
+	if l.CapturedAt != nil {
+		return *l.CapturedAt
+	}
+	return l.CreatedAt
+}
+
+func byEffectiveAsc(a, b *models.Location) bool { return effectiveAt(a).Before(effectiveAt(b)) }
+
+func byEffectiveDesc(a, b *models.Location) bool { return effectiveAt(a).After(effectiveAt(b)) }
+
+func (r *Locations) GetByUserID(userID int) (*models.Location, error) {
+	return r.t.first(func(l *models.Location) bool { return l.UserID == userID && !l.Masked }, byEffectiveDesc)
+}
+
+func (r *Locations) GetPreviousByEffectiveTime(userID int, before time.Time) (*models.Location, error) {
+	return r.t.first(func(l *models.Location) bool {
+		return l.UserID == userID && !l.Masked && effectiveAt(l).Before(before)
+	}, byEffectiveDesc)
+}
+
+func (r *Locations) UserExists(userID int) (bool, error) {
+	_, err := r.s.Users.GetByID(userID)
+	return err == nil, nil
+}
+
+func (r *Locations) Create(loc *models.Location) error { return r.t.insert(r.s, loc, locationKey) }
+
+func (r *Locations) Update(loc *models.Location) error { return r.t.save(r.s, loc, locationKey) }
+
+func (r *Locations) GetAll() ([]models.Location, error) {
+	return r.t.find(func(l *models.Location) bool { return !l.Masked }, nil), nil
+}
+
+func (r *Locations) ListUserIDsWithoutCapturedAt() ([]int, error) {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, l := range r.t.find(func(l *models.Location) bool { return l.CapturedAt == nil }, nil) {
+		if !seen[l.UserID] {
+			seen[l.UserID] = true
+			ids = append(ids, l.UserID)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+func (r *Locations) GetWithoutCapturedAtByUser(userID int) ([]models.Location, error) {
+	return r.t.find(func(l *models.Location) bool { return l.UserID == userID && l.CapturedAt == nil },
+		func(a, b *models.Location) bool {
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+			return a.ID < b.ID
+		}), nil
+}
+
+func (r *Locations) UpdateCapturedAt(id int, capturedAt time.Time) error {
+	at := capturedAt.UTC()
+	r.t.update(r.s, func(l *models.Location) bool { return l.ID == id }, func(l *models.Location) { l.CapturedAt = &at })
+	return nil
+}
+
+func (r *Locations) GetLocationsBetween(from, to time.Time) ([]models.Location, error) {
+	return r.t.find(func(l *models.Location) bool {
+		return !l.Masked && between(effectiveAt(l), from, to)
+	}, byEffectiveAsc), nil
+}
+
+func (r *Locations) GetLocationsByUserBetween(userID int, from, to time.Time) ([]models.Location, error) {
+	return r.t.find(func(l *models.Location) bool {
+		return l.UserID == userID && !l.Masked && between(effectiveAt(l), from, to)
+	}, byEffectiveAsc), nil
+}
+
+// between is SQL BETWEEN (both ends inclusive).
+func between(at, from, to time.Time) bool { return !at.Before(from) && !at.After(to) }
+
+func (r *Locations) GetForDownsampling(userID int, from, to, afterAt time.Time, afterID, limit int) ([]models.Location, error) {
+	locs := r.t.find(func(l *models.Location) bool {
+		at := effectiveAt(l)
+		return l.UserID == userID && !l.Downsampled && !at.Before(from) && at.Before(to) &&
+			(at.After(afterAt) || at.Equal(afterAt) && l.ID > afterID)
+	}, func(a, b *models.Location) bool {
+		if !effectiveAt(a).Equal(effectiveAt(b)) {
+			return byEffectiveAsc(a, b)
+		}
+		return a.ID < b.ID
+	})
+	return page(locs, limit, 0), nil
+}
+
+func (r *Locations) GetOlderThan(userID int, before time.Time, afterID, limit int) ([]models.Location, error) {
+	locs := r.t.find(func(l *models.Location) bool {
+		return l.UserID == userID && effectiveAt(l).Before(before) && l.ID > afterID
+	}, func(a, b *models.Location) bool { return a.ID < b.ID })
+	return page(locs, limit, 0), nil
+}
+
+func (r *Locations) DeleteByIDs(ids []int) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	set := intSet(ids)
+	return r.t.delete(func(l *models.Location) bool { return set[l.ID] }), nil
+}
+
+func (r *Locations) MarkDownsampled(ids []int) error {
+	set := intSet(ids)
+	r.t.update(r.s, func(l *models.Location) bool { return set[l.ID] }, func(l *models.Location) { l.Downsampled = true })
+	return nil
+}
+
+func intSet(ids []int) map[int]bool {
+	set := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// GetLatestAgePerUser — age of each user's latest point (masked ones included,
+// as in SQL) against the store clock.
+func (r *Locations) GetLatestAgePerUser(organizationID int) ([]dao.LatestLocationAge, error) {
+	inOrg := r.s.Users.inOrganizationOrAll(organizationID)
+	latest := make(map[int]time.Time)
+	for _, l := range r.t.find(nil, nil) {
+		if at := effectiveAt(&l); inOrg(l.UserID) && at.After(latest[l.UserID]) {
+			latest[l.UserID] = at
+		}
+	}
+	now := r.s.Now()
+	rows := make([]dao.LatestLocationAge, 0, len(latest))
+	for userID, at := range latest {
+		age := int64(now.Sub(at) / time.Second)
+		if age < 0 {
+			age = 0
+		}
+		rows = append(rows, dao.LatestLocationAge{UserID: userID, AgeSeconds: age})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].UserID < rows[j].UserID })
+	return rows, nil
+}
+
+func (r *Locations) IsPartitioned() (bool, error) { return false, nil }
+
+func (r *Locations) EnsurePartitions(from, to time.Time) (int, error) { return 0, nil }
+
+func (r *Locations) ExpiredPartitions(before time.Time) ([]string, error) { return nil, nil }
+
+func (r *Locations) DetachPartition(name string) error { return nil }
+
+func (r *Locations) LatestInPartition(name string) ([]int, error) { return nil, nil }
+
+func (r *Locations) PartitionLocations(name string, afterID, limit int) ([]models.Location, error) {
+	return nil, nil
+}
+
+func (r *Locations) DropPartition(name string, keep []int) error { return nil }
+
+// Retention mirrors dao.RetentionDAO.
+type Retention struct {
+	s        *Store
+	policies table[int, models.RetentionPolicy]
+	runs     table[int64, models.RetentionRun]
+}
+
+func retentionPolicyKey(p *models.RetentionPolicy) int { return p.OrganizationID }
+
+func retentionRunKey(run *models.RetentionRun) int64 { return run.ID }
+
+func (r *Retention) GetPolicy(organizationID int) (*models.RetentionPolicy, error) {
+	return r.policies.first(func(p *models.RetentionPolicy) bool { return p.OrganizationID == organizationID }, nil)
+}
+
+func (r *Retention) SavePolicy(p *models.RetentionPolicy) error {
+	return r.policies.save(r.s, p, retentionPolicyKey)
+}
+
+func (r *Retention) DeletePolicy(organizationID int) error {
+	r.policies.delete(func(p *models.RetentionPolicy) bool { return p.OrganizationID == organizationID })
+	return nil
+}
+
+func (r *Retention) CreateRun(run *models.RetentionRun) error {
+	return r.runs.insert(r.s, run, retentionRunKey)
+}
+
+func (r *Retention) UpdateRun(run *models.RetentionRun) error {
+	return r.runs.save(r.s, run, retentionRunKey)
+}
+
+func (r *Retention) GetRun(id int64) (*models.RetentionRun, error) {
+	return r.runs.first(func(run *models.RetentionRun) bool { return run.ID == id }, nil)
+}
+
+func (r *Retention) ListRuns(limit int) ([]models.RetentionRun, error) {
+	runs := r.runs.find(nil, func(a, b *models.RetentionRun) bool { return a.ID > b.ID })
+	return page(runs, limit, 0), nil
+}
+
+// Visits mirrors dao.VisitDAO. InUserLock serialises all callers (not only those
+// of one user) and rolls the table back when fn fails.
+type Visits struct {
+	s    *Store
+	t    table[int64, models.Visit]
+	lock sync.Mutex
+}
+
+func visitKey(v *models.Visit) int64 { return v.ID }
+
+func (r *Visits) Create(visit *models.Visit) error { return r.t.insert(r.s, visit, visitKey) }
+
+func (r *Visits) Update(visit *models.Visit) error { return r.t.save(r.s, visit, visitKey) }
+
+func (r *Visits) Delete(id int64) error {
+	r.t.delete(func(v *models.Visit) bool { return v.ID == id })
+	return nil
+}
+
+func (r *Visits) GetActiveVisit(userID int, checkpointID int) (*models.Visit, error) {
+	return r.t.first(func(v *models.Visit) bool {
+		return v.UserID == userID && v.CheckpointID == checkpointID && v.EndAt == nil
+	}, nil)
+}
+
+func (r *Visits) InUserLock(userID int, fn func(tx dao.VisitStore) error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	st := r.t.snapshot()
+	if err := fn(r); err != nil {
+		r.t.restore(st)
+		return err
+	}
+	return nil
+}
+
+func (r *Visits) GetVisitsByUser(userID int) ([]models.Visit, error) {
+	return r.t.find(func(v *models.Visit) bool { return v.UserID == userID }, nil), nil
+}
+
+func (r *Visits) GetVisits(
+	organizationID int,
+	filters map[string]interface{},
+	activeOnly bool,
+	rangeFrom, rangeTo *time.Time,
+) ([]models.Visit, error) {
+	inOrg := r.s.Users.inOrganization(organizationID)
+	return r.t.find(func(v *models.Visit) bool {
+		return inOrg(v.UserID) && matchFilters(v, filters) &&
+			(!activeOnly || v.EndAt == nil) &&
+			(rangeFrom == nil || v.EndAt == nil || !v.EndAt.Before(*rangeFrom)) &&
+			(rangeTo == nil || !v.StartAt.After(*rangeTo))
+	}, func(a, b *models.Visit) bool { return a.StartAt.After(b.StartAt) }), nil
+}
+
+// Checkpoints mirrors dao.CheckpointDAO.
+type Checkpoints struct {
+	s *Store
+	t table[int, models.Checkpoint]
+}
+
+func checkpointKey(cp *models.Checkpoint) int { return cp.ID }
+
+func (r *Checkpoints) Create(cp *models.Checkpoint) error { return r.t.insert(r.s, cp, checkpointKey) }
+
+func (r *Checkpoints) GetAll(organizationID int) ([]models.Checkpoint, error) {
+	return r.t.find(func(cp *models.Checkpoint) bool { return cp.OrganizationID == organizationID }, nil), nil
+}
+
+func (r *Checkpoints) GetByID(organizationID, id int) (*models.Checkpoint, error) {
+	return r.t.first(func(cp *models.Checkpoint) bool {
+		return cp.ID == id && cp.OrganizationID == organizationID
+	}, nil)
+}
+
+func (r *Checkpoints) Update(cp *models.Checkpoint) error { return r.t.save(r.s, cp, checkpointKey) }
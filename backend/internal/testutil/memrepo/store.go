@@ -0,0 +1,372 @@
+// Package memrepo provides in-memory implementations of every DAO in locator/dao,
+// so services can be tested without Postgres.
+//
+// The repositories mirror the SQL they replace: the same filters and ordering,
+// gorm.ErrRecordNotFound from lookups, auto-increment IDs, autoCreateTime /
+// autoUpdateTime and column defaults applied to zero fields on insert. Rows are
+// stored by value, so callers only change the store through repository methods.
+//
+//	store := memrepo.New(testutil.NewClock(start))
+//	svc := service.NewLocationRequestService(store.LocationRequests)
+package memrepo
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Clock is the store's time source for timestamps and "now" in queries
+// (testutil.Clock satisfies it). Nil means time.Now.
+type Clock interface {
+	Now() time.Time
+}
+
+// Store holds one repository per DAO. Repositories that join other tables in
+// SQL (organisation filters, user existence) read them from the same store.
+type Store struct {
+	Users             *Users
+	APIKeys           *APIKeys
+	Audit             *Audit
+	Groups            *Groups
+	Organizations     *Organizations
+	Sessions          *Sessions
+	Locations         *Locations
+	Retention         *Retention
+	Visits            *Visits
+	Checkpoints       *Checkpoints
+	DeviceConfigs     *DeviceConfigs
+	TrackingSchedules *TrackingSchedules
+	DeviceReports     *DeviceReports
+	Alerts            *Alerts
+	Notifications     *Notifications
+	AppReleases       *AppReleases
+	DeviceCommands    *DeviceCommands
+	LocationRequests  *LocationRequests
+
+	clock Clock
+}
+
+// New returns an empty store driven by clock (nil — wall clock).
+func New(clock Clock) *Store {
+	s := &Store{clock: clock}
+	s.Users = &Users{s: s}
+	s.APIKeys = &APIKeys{s: s}
+	s.Audit = &Audit{s: s}
+	s.Groups = &Groups{s: s}
+	s.Organizations = &Organizations{s: s}
+	s.Sessions = &Sessions{s: s}
+	s.Locations = &Locations{s: s}
+	s.Retention = &Retention{s: s}
+	s.Visits = &Visits{s: s}
+	s.Checkpoints = &Checkpoints{s: s}
+	s.DeviceConfigs = &DeviceConfigs{s: s}
+	s.TrackingSchedules = &TrackingSchedules{s: s}
+	s.DeviceReports = &DeviceReports{s: s}
+	s.Alerts = &Alerts{s: s}
+	s.Notifications = &Notifications{s: s}
+	s.AppReleases = &AppReleases{s: s}
+	s.DeviceCommands = &DeviceCommands{s: s}
+	s.LocationRequests = &LocationRequests{s: s}
+	return s
+}
+
+// Now is the store's current time.
+func (s *Store) Now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+// table is one SQL table: rows in insertion order, looked up by primary key.
+type table[K comparable, T any] struct {
+	mu   sync.Mutex
+	rows []*T
+	seq  int64
+}
+
+// insert mirrors gorm Create: assigns the auto-increment ID, applies defaults
+// and timestamps, rejects a duplicate primary key and writes the result back to row.
+func (t *table[K, T]) insert(s *Store, row *T, key func(*T) K) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.insertLocked(s, row, key)
+}
+
+func (t *table[K, T]) insertLocked(s *Store, row *T, key func(*T) K) error {
+	beforeCreate(row, s.Now(), &t.seq)
+	k := key(row)
+	for _, r := range t.rows {
+		if key(r) == k {
+			return fmt.Errorf("memrepo: duplicate key %v", k)
+		}
+	}
+	stored := *row
+	t.rows = append(t.rows, &stored)
+	return nil
+}
+
+// save mirrors gorm Save: updates the row with the same key, inserts otherwise.
+func (t *table[K, T]) save(s *Store, row *T, key func(*T) K) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	k := key(row)
+	for i, r := range t.rows {
+		if key(r) == k {
+			beforeUpdate(row, s.Now())
+			stored := *row
+			t.rows[i] = &stored
+			return nil
+		}
+	}
+	return t.insertLocked(s, row, key)
+}
+
+// first returns a copy of the first row by less (insertion order when nil)
+// among those matching, or gorm.ErrRecordNotFound.
+func (t *table[K, T]) first(match func(*T) bool, less func(a, b *T) bool) (*T, error) {
+	rows := t.find(match, less)
+	if len(rows) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rows[0], nil
+}
+
+// find returns copies of matching rows ordered by less (insertion order when nil).
+func (t *table[K, T]) find(match func(*T) bool, less func(a, b *T) bool) []T {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []T
+	for _, r := range t.rows {
+		if match == nil || match(r) {
+			out = append(out, *r)
+		}
+	}
+	if less != nil {
+		sort.SliceStable(out, func(i, j int) bool { return less(&out[i], &out[j]) })
+	}
+	return out
+}
+
+// update applies fn to matching rows and bumps autoUpdateTime like
+// Model(...).Update(s); returns the number of rows changed.
+func (t *table[K, T]) update(s *Store, match func(*T) bool, fn func(*T)) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var n int64
+	for _, r := range t.rows {
+		if match(r) {
+			fn(r)
+			beforeUpdate(r, s.Now())
+			n++
+		}
+	}
+	return n
+}
+
+// delete removes matching rows and returns their number.
+func (t *table[K, T]) delete(match func(*T) bool) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	kept := t.rows[:0]
+	var n int64
+	for _, r := range t.rows {
+		if match(r) {
+			n++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	t.rows = kept
+	return n
+}
+
+// tableState is a copy of a table taken by snapshot.
+type tableState[T any] struct {
+	rows []T
+	seq  int64
+}
+
+// snapshot copies the rows so that restore can emulate a rolled-back transaction.
+func (t *table[K, T]) snapshot() tableState[T] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := tableState[T]{rows: make([]T, len(t.rows)), seq: t.seq}
+	for i, r := range t.rows {
+		st.rows[i] = *r
+	}
+	return st
+}
+
+func (t *table[K, T]) restore(st tableState[T]) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows = make([]*T, len(st.rows))
+	for i := range st.rows {
+		row := st.rows[i]
+		t.rows[i] = &row
+	}
+	t.seq = st.seq
+}
+
+// page applies LIMIT/OFFSET (limit <= 0 — no limit).
+func page[T any](rows []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(rows) {
+			return nil
+		}
+		rows = rows[offset:]
+	}
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// gormTag parses `gorm:"primaryKey;default:1"` into settings.
+func gormTag(f reflect.StructField) map[string]string {
+	settings := make(map[string]string)
+	for _, part := range strings.Split(f.Tag.Get("gorm"), ";") {
+		if part == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(part, ":")
+		settings[strings.ToLower(name)] = value
+	}
+	return settings
+}
+
+// beforeCreate fills what gorm and Postgres fill on insert: the auto-increment
+// primary key, autoCreateTime/autoUpdateTime and column defaults of zero fields
+// (a false bool with default:true becomes true, as with gorm).
+func beforeCreate(row any, now time.Time, seq *int64) {
+	v := reflect.ValueOf(row).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		tag := gormTag(field)
+		_, pk := tag["primarykey"]
+		autoInc, hasAutoInc := tag["autoincrement"]
+		switch {
+		case pk && hasAutoInc && autoInc != "false" && value.CanInt():
+			if value.Int() == 0 {
+				*seq++
+				value.SetInt(*seq)
+			} else if value.Int() > *seq {
+				*seq = value.Int()
+			}
+		case hasKey(tag, "autocreatetime", "autoupdatetime") && value.Type() == reflect.TypeOf(time.Time{}):
+			if value.Interface().(time.Time).IsZero() {
+				value.Set(reflect.ValueOf(now))
+			}
+		}
+		if def, ok := tag["default"]; ok && value.IsZero() {
+			setDefault(value, strings.Trim(def, "'"))
+		}
+	}
+}
+
+// beforeUpdate sets autoUpdateTime fields to now.
+func beforeUpdate(row any, now time.Time) {
+	v := reflect.ValueOf(row).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if hasKey(gormTag(v.Type().Field(i)), "autoupdatetime") && v.Field(i).Type() == reflect.TypeOf(time.Time{}) {
+			v.Field(i).Set(reflect.ValueOf(now))
+		}
+	}
+}
+
+func hasKey(tag map[string]string, keys ...string) bool {
+	for _, k := range keys {
+		if _, ok := tag[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func setDefault(value reflect.Value, def string) {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(def)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(def)
+		if err == nil {
+			value.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(def, 10, 64)
+		if err == nil {
+			value.SetInt(n)
+		}
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(def, 64)
+		if err == nil {
+			value.SetFloat(f)
+		}
+	}
+}
+
+var naming = schema.NamingStrategy{}
+
+// column returns the value of the field stored in SQL column name (pointers
+// dereferenced; ok is false for a nil pointer or an unknown column).
+func column(row any, name string) (any, bool) {
+	v := reflect.ValueOf(row).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		col := gormTag(field)["column"]
+		if col == "" {
+			col = naming.ColumnName("", field.Name)
+		}
+		if col != name {
+			continue
+		}
+		value := v.Field(i)
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return nil, false
+			}
+			value = value.Elem()
+		}
+		return value.Interface(), true
+	}
+	return nil, false
+}
+
+// matchFilters mirrors the DAOs' Where(key+" = ?", value) loops; a []int value
+// means IN.
+func matchFilters(row any, filters map[string]interface{}) bool {
+	for key, want := range filters {
+		got, ok := column(row, key)
+		if !ok {
+			return false
+		}
+		if values, isList := want.([]int); isList {
+			if !containsValue(values, got) {
+				return false
+			}
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsValue(values []int, got any) bool {
+	for _, v := range values {
+		if fmt.Sprint(v) == fmt.Sprint(got) {
+			return true
+		}
+	}
+	return false
+}
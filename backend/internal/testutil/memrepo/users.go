@@ -0,0 +1,268 @@
+package memrepo
+
+import (
+	"strings"
+	"time"
+
+	"locator/models"
+)
+
+// Users mirrors dao.UserDAO.
+type Users struct {
+	s *Store
+	t table[int, models.User]
+}
+
+func userKey(u *models.User) int { return u.ID }
+
+func (r *Users) Create(user *models.User) error { return r.t.insert(r.s, user, userKey) }
+
+// Update keeps totp_last_step: the column is read-only for gorm Save.
+func (r *Users) Update(user *models.User) error {
+	if current, err := r.GetByID(user.ID); err == nil {
+		user.TOTPLastStep = current.TOTPLastStep
+	}
+	return r.t.save(r.s, user, userKey)
+}
+
+func (r *Users) GetByID(id int) (*models.User, error) {
+	return r.t.first(func(u *models.User) bool { return u.ID == id }, nil)
+}
+
+func (r *Users) GetByUsername(username string) (*models.User, error) {
+	return r.t.first(func(u *models.User) bool { return u.Username != nil && *u.Username == username }, nil)
+}
+
+func (r *Users) UseTOTPStep(userID int, step int64) (bool, error) {
+	n := r.t.update(r.s, func(u *models.User) bool { return u.ID == userID && u.TOTPLastStep < step },
+		func(u *models.User) { u.TOTPLastStep = step })
+	return n == 1, nil
+}
+
+func (r *Users) GetAllByOrganization(organizationID int) ([]models.User, error) {
+	return r.t.find(func(u *models.User) bool { return u.OrganizationID == organizationID }, byUserID), nil
+}
+
+func (r *Users) GetIDsByOrganization(organizationID int, groupID *int) ([]int, error) {
+	var ids []int
+	for _, u := range r.t.find(func(u *models.User) bool {
+		return u.OrganizationID == organizationID && (groupID == nil || u.GroupID != nil && *u.GroupID == *groupID)
+	}, byUserID) {
+		ids = append(ids, u.ID)
+	}
+	return ids, nil
+}
+
+func (r *Users) GetAll() ([]models.User, error) {
+	return r.t.find(nil, byUserID), nil
+}
+
+func byUserID(a, b *models.User) bool { return a.ID < b.ID }
+
+// inOrganization is the SQL subquery "user_id IN (SELECT id FROM users WHERE
+// organization_id = ?)".
+func (r *Users) inOrganization(organizationID int) func(userID int) bool {
+	ids := make(map[int]bool)
+	for _, u := range r.t.find(func(u *models.User) bool { return u.OrganizationID == organizationID }, nil) {
+		ids[u.ID] = true
+	}
+	return func(userID int) bool { return ids[userID] }
+}
+
+// inOrganizationOrAll is "? = 0 OR user_id IN (...)" of the batch queries.
+func (r *Users) inOrganizationOrAll(organizationID int) func(userID int) bool {
+	if organizationID == 0 {
+		return func(int) bool { return true }
+	}
+	return r.inOrganization(organizationID)
+}
+
+// APIKeys mirrors dao.APIKeyDAO.
+type APIKeys struct {
+	s *Store
+	t table[int, models.APIKey]
+}
+
+func apiKeyKey(k *models.APIKey) int { return k.ID }
+
+func (r *APIKeys) CreateAPIKey(key *models.APIKey) error { return r.t.insert(r.s, key, apiKeyKey) }
+
+func (r *APIKeys) UpdateAPIKey(key *models.APIKey) error { return r.t.save(r.s, key, apiKeyKey) }
+
+func (r *APIKeys) GetAPIKeyByID(id int) (*models.APIKey, error) {
+	return r.t.first(func(k *models.APIKey) bool { return k.ID == id }, nil)
+}
+
+func (r *APIKeys) GetAPIKeysByUser(userID int) ([]models.APIKey, error) {
+	return r.t.find(func(k *models.APIKey) bool { return k.UserID == userID }, func(a, b *models.APIKey) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	}), nil
+}
+
+func (r *APIKeys) GetAPIKeyCandidates(fingerprint string) ([]models.APIKey, error) {
+	return r.t.find(func(k *models.APIKey) bool {
+		return k.RevokedAt == nil && k.Fingerprint == fingerprint
+	}, func(a, b *models.APIKey) bool { return a.ID > b.ID }), nil
+}
+
+func (r *APIKeys) GetLegacyAPIKeys(now time.Time, limit int) ([]models.APIKey, error) {
+	keys := r.t.find(func(k *models.APIKey) bool {
+		return k.RevokedAt == nil && k.Fingerprint == "" && (k.ExpiresAt == nil || k.ExpiresAt.After(now))
+	}, func(a, b *models.APIKey) bool { return a.ID > b.ID })
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, nil
+}
+
+func (r *APIKeys) RevokeReplacedAPIKeys(replacedByID int, at time.Time) error {
+	r.t.update(r.s, func(k *models.APIKey) bool {
+		return k.ReplacedByID != nil && *k.ReplacedByID == replacedByID && k.RevokedAt == nil
+	}, func(k *models.APIKey) { k.RevokedAt = &at })
+	return nil
+}
+
+// Audit mirrors dao.AuditDAO.
+type Audit struct {
+	s *Store
+	t table[int64, models.AuditEntry]
+}
+
+func (r *Audit) CreateAuditEntry(entry *models.AuditEntry) error {
+	return r.t.insert(r.s, entry, func(e *models.AuditEntry) int64 { return e.ID })
+}
+
+func (r *Audit) ListAuditEntries(filters map[string]interface{}, from, to *time.Time, limit, offset int) ([]models.AuditEntry, int64, error) {
+	prefix, hasPrefix := filters["action"].(string)
+	rest := make(map[string]interface{}, len(filters))
+	for k, v := range filters {
+		if k != "action" || !hasPrefix {
+			rest[k] = v
+		}
+	}
+	entries := r.t.find(func(e *models.AuditEntry) bool {
+		return matchFilters(e, rest) &&
+			(!hasPrefix || strings.HasPrefix(e.Action, prefix)) &&
+			(from == nil || !e.At.Before(*from)) &&
+			(to == nil || e.At.Before(*to))
+	}, func(a, b *models.AuditEntry) bool {
+		if !a.At.Equal(b.At) {
+			return a.At.After(b.At)
+		}
+		return a.ID > b.ID
+	})
+	return page(entries, limit, offset), int64(len(entries)), nil
+}
+
+// Groups mirrors dao.GroupDAO.
+type Groups struct {
+	s *Store
+	t table[int, models.Group]
+}
+
+func (r *Groups) CreateGroup(group *models.Group) error {
+	return r.t.insert(r.s, group, func(g *models.Group) int { return g.ID })
+}
+
+func (r *Groups) GetGroupByID(id int) (*models.Group, error) {
+	return r.t.first(func(g *models.Group) bool { return g.ID == id }, nil)
+}
+
+func (r *Groups) GetAllGroups(organizationID int) ([]models.Group, error) {
+	return r.t.find(func(g *models.Group) bool { return g.OrganizationID == organizationID },
+		func(a, b *models.Group) bool { return a.Name < b.Name }), nil
+}
+
+// Organizations mirrors dao.OrganizationDAO.
+type Organizations struct {
+	s *Store
+	t table[int, models.Organization]
+}
+
+func organizationKey(o *models.Organization) int { return o.ID }
+
+func (r *Organizations) CreateOrganization(org *models.Organization) error {
+	return r.t.insert(r.s, org, organizationKey)
+}
+
+func (r *Organizations) GetOrganizationByID(id int) (*models.Organization, error) {
+	return r.t.first(func(o *models.Organization) bool { return o.ID == id }, nil)
+}
+
+func (r *Organizations) UpdateOrganization(org *models.Organization) error {
+	return r.t.save(r.s, org, organizationKey)
+}
+
+func (r *Organizations) GetAllOrganizations() ([]models.Organization, error) {
+	return r.t.find(nil, func(a, b *models.Organization) bool { return a.ID < b.ID }), nil
+}
+
+// Sessions mirrors dao.SessionDAO (sessions and login links).
+type Sessions struct {
+	s     *Store
+	t     table[string, models.UserSession]
+	links table[int, models.LoginLink]
+}
+
+func sessionKey(s *models.UserSession) string { return s.ID }
+
+func loginLinkKey(l *models.LoginLink) int { return l.ID }
+
+func (r *Sessions) CreateSession(session *models.UserSession) error {
+	return r.t.insert(r.s, session, sessionKey)
+}
+
+func (r *Sessions) UpdateSession(session *models.UserSession) error {
+	return r.t.save(r.s, session, sessionKey)
+}
+
+func (r *Sessions) GetSessionByID(id string) (*models.UserSession, error) {
+	return r.t.first(func(s *models.UserSession) bool { return s.ID == id }, nil)
+}
+
+func (r *Sessions) GetSessionsByUser(userID int) ([]models.UserSession, error) {
+	return r.t.find(func(s *models.UserSession) bool { return s.UserID == userID },
+		func(a, b *models.UserSession) bool { return a.CreatedAt.After(b.CreatedAt) }), nil
+}
+
+func (r *Sessions) RotateRefreshToken(session *models.UserSession, previousHash string) (bool, error) {
+	n := r.t.update(r.s, func(s *models.UserSession) bool {
+		return s.ID == session.ID && s.RefreshTokenHash == previousHash && s.RevokedAt == nil
+	}, func(s *models.UserSession) {
+		s.RefreshTokenHash = session.RefreshTokenHash
+		s.ExpiresAt = session.ExpiresAt
+		s.LastUsedAt = session.LastUsedAt
+		s.UserAgent = session.UserAgent
+		s.IP = session.IP
+	})
+	return n == 1, nil
+}
+
+func (r *Sessions) RevokeSession(id string, at time.Time) error {
+	r.t.update(r.s, func(s *models.UserSession) bool { return s.ID == id && s.RevokedAt == nil },
+		func(s *models.UserSession) { s.RevokedAt = &at })
+	return nil
+}
+
+func (r *Sessions) RevokeUserSessions(userID int, at time.Time) error {
+	r.t.update(r.s, func(s *models.UserSession) bool { return s.UserID == userID && s.RevokedAt == nil },
+		func(s *models.UserSession) { s.RevokedAt = &at })
+	return nil
+}
+
+func (r *Sessions) CreateLoginLink(link *models.LoginLink) error {
+	return r.links.insert(r.s, link, loginLinkKey)
+}
+
+func (r *Sessions) ConsumeLoginLink(id int, at time.Time) (bool, error) {
+	n := r.links.update(r.s, func(l *models.LoginLink) bool { return l.ID == id && l.UsedAt == nil && l.ExpiresAt.After(at) },
+		func(l *models.LoginLink) { l.UsedAt = &at })
+	return n == 1, nil
+}
+
+func (r *Sessions) GetLoginLinkByHash(tokenHash string) (*models.LoginLink, error) {
+	return r.links.first(func(l *models.LoginLink) bool { return l.TokenHash == tokenHash }, nil)
+}
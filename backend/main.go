@@ -1,63 +1,152 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"locator/config"
 	"locator/config/bootstrap"
-	"log"
+	"locator/internal/metrics"
+	"locator/service"
+	"log/slog"
+	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	if err := os.MkdirAll("logs", 0755); err != nil {
-		log.Fatalf("Ошибка создания директории логов: %v", err)
+		fatal("Ошибка создания директории логов", err)
 	}
 	if err := os.MkdirAll("static/qrcode", 0755); err != nil {
-		log.Fatalf("Ошибка создания директории QR-кодов: %v", err)
+		fatal("Ошибка создания директории QR-кодов", err)
 	}
 
-	config.InitLogger("logs/app.log")
+	// Конфигурация проверяется до запуска чего-либо: при ошибке процесс завершается
+	// со списком всех неверных параметров.
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("Ошибка конфигурации", err)
+	}
+
+	config.InitLogger("logs/app.log", cfg.Log, service.IsSecretParam)
+	slog.Info("Конфигурация загружена", "config", cfg)
 
 	dbLogger := config.InitDBQueryLogger("logs/db.log")
 
-	ginMode := strings.TrimSpace(os.Getenv("GIN_MODE"))
-	if ginMode == "" {
-		ginMode = gin.ReleaseMode
-	}
-	gin.SetMode(ginMode)
+	gin.SetMode(cfg.HTTP.GinMode)
 
 	// Инициализируем приложение и передаём логгер для работы с БД.
-	app, err := bootstrap.InitializeApp(dbLogger)
+	app, err := bootstrap.InitializeApp(cfg, dbLogger)
 	if err != nil {
-		log.Fatalf("Ошибка инициализации приложения: %v", err)
+		fatal("Ошибка инициализации приложения", err)
 	}
 
 	// По умолчанию не доверяем прокси, чтобы не принимать X-Forwarded-* от любого источника.
-	trustedProxiesRaw := strings.TrimSpace(os.Getenv("TRUSTED_PROXIES"))
-	if trustedProxiesRaw == "" {
-		if err := app.Router.SetTrustedProxies(nil); err != nil {
-			log.Fatalf("Ошибка настройки trusted proxies: %v", err)
-		}
-	} else {
-		rawList := strings.Split(trustedProxiesRaw, ",")
-		trustedProxies := make([]string, 0, len(rawList))
-		for _, proxy := range rawList {
-			proxy = strings.TrimSpace(proxy)
-			if proxy != "" {
-				trustedProxies = append(trustedProxies, proxy)
-			}
+	if err := app.Router.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		fatal("Ошибка настройки trusted proxies", err)
+	}
+
+	// SIGTERM (docker stop, деплой) и Ctrl+C — плавная остановка.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go reloadThresholdsOnHUP(ctx)
+
+	metricsServer := startMetricsServer(cfg.Metrics)
+	srv := &http.Server{
+		Addr:    cfg.HTTP.Addr,
+		Handler: app.Router,
+		// Чтение и запись — с запасом на загрузку APK (RELEASE_MAX_UPLOAD_MB) и выгрузки.
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Сервер запущен", "addr", cfg.HTTP.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		fatal("Ошибка запуска сервера", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Порядок: /readyz → 503 и пауза, чтобы балансировщик снял экземпляр с трафика;
+	// затем приём новых соединений прекращён и текущие запросы дорабатывают; затем
+	// фоновые проходы и обработчик очереди, затем брокер и БД. У HTTP и обработчика
+	// очереди свои сроки.
+	app.Health.SetDraining()
+	if delay := cfg.HTTP.DrainDelay; delay > 0 {
+		slog.Info("Получен сигнал остановки, /readyz отвечает 503", "drain_delay", delay)
+		time.Sleep(delay)
+	}
+	slog.Info("Завершение HTTP", "timeout", cfg.HTTP.ShutdownTimeout)
+	httpCtx, cancelHTTP := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	if err := srv.Shutdown(httpCtx); err != nil {
+		slog.Error("HTTP-запросы не завершены к сроку", "error", err)
+	}
+	if metricsServer != nil {
+		_ = metricsServer.Shutdown(httpCtx)
+	}
+	cancelHTTP()
+	slog.Info("Завершение обработчика очереди и фоновых проходов", "timeout", cfg.RabbitMQ.ShutdownTimeout)
+	consumerCtx, cancelConsumer := context.WithTimeout(context.Background(), cfg.RabbitMQ.ShutdownTimeout)
+	defer cancelConsumer()
+	app.Shutdown(consumerCtx)
+	slog.Info("Сервер остановлен")
+}
+
+// fatal пишет ошибку запуска и завершает процесс.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// reloadThresholdsOnHUP по SIGHUP перечитывает конфигурацию (файл CONFIG_FILE) и
+// применяет раздел thresholds; остальные разделы меняются только перезапуском. При
+// ошибке проверки действующие пороги сохраняются.
+func reloadThresholdsOnHUP(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
 		}
-		if err := app.Router.SetTrustedProxies(trustedProxies); err != nil {
-			log.Fatalf("Ошибка настройки trusted proxies: %v", err)
+		cfg, err := config.Load()
+		if err != nil {
+			slog.Error("Конфигурация не перечитана, пороги не изменены", "error", err)
+			continue
 		}
+		bootstrap.ApplyThresholds(cfg.Thresholds)
+		slog.Info("Пороги перечитаны", "thresholds", fmt.Sprintf("%+v", cfg.Thresholds))
 	}
+}
 
-	defer app.RMQClient.Close()
-	log.Println("Сервер запущен на порту 8080")
-
-	if err := app.Router.Run(":8080"); err != nil {
-		log.Fatalf("Ошибка запуска сервера: %v", err)
+// startMetricsServer отдаёт /metrics на отдельном внутреннем порту (Addr "off" —
+// выключено). Token — необязательный Bearer-токен.
+func startMetricsServer(cfg config.MetricsConfig) *http.Server {
+	if cfg.Addr == "off" {
+		return nil
 	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default.Handler(cfg.Token))
+	srv := &http.Server{Addr: cfg.Addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		slog.Info("Метрики Prometheus запущены", "addr", cfg.Addr, "path", "/metrics")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Ошибка сервера метрик", "error", err)
+		}
+	}()
+	return srv
 }
@@ -0,0 +1,261 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"locator/models"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// auditBodyLimit — тело запроса больше лимита в журнал не пишется (только размер).
+	auditBodyLimit = 64 << 10
+	// auditResponseLimit — сколько ответа читается, чтобы достать error и id созданного объекта.
+	auditResponseLimit = 4 << 10
+)
+
+// Audit записывает в журнал аудита изменяющие запросы (POST, PUT, PATCH, DELETE):
+// сотрудника, действие (метод и шаблон маршрута), объект, параметры без секретов,
+// код ответа и IP. Ставится после аутентификации, но до проверки прав — отказы
+// тоже попадают в журнал. При audit == nil ничего не делает.
+func Audit(audit *service.AuditService) gin.HandlerFunc {
+	return auditHandler(audit, false)
+}
+
+// AuditAccess — то же для чтения секретов (QR-код с API-ключом): пишется любой метод.
+func AuditAccess(audit *service.AuditService) gin.HandlerFunc {
+	return auditHandler(audit, true)
+}
+
+func auditHandler(audit *service.AuditService, anyMethod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if audit == nil || (!anyMethod && !isMutatingMethod(c.Request.Method)) {
+			c.Next()
+			return
+		}
+
+		body := auditRequestBody(c)
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		status := writer.Status()
+		entry := &models.AuditEntry{
+			SessionID: c.GetString("session_id"),
+			Action:    c.Request.Method + " " + c.FullPath(),
+			Status:    status,
+			Result:    auditResult(status),
+			IP:        c.ClientIP(),
+		}
+		if user := contextUser(c); user != nil {
+			id := user.ID
+			entry.ActorID = &id
+			entry.ActorName = user.Name
+			entry.ActorRole = user.EffectiveRole()
+			entry.OrganizationID = user.OrganizationID
+		}
+		response := writer.jsonResponse()
+		entry.TargetType, entry.TargetID = auditTarget(c, response)
+		if status >= http.StatusBadRequest {
+			if msg, ok := response["error"].(string); ok {
+				entry.Error = msg
+			}
+		}
+		if params := auditParams(c, body); len(params) > 0 {
+			if data, err := json.Marshal(params); err == nil {
+				entry.Params = data
+			}
+		}
+		audit.Record(entry)
+	}
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func auditResult(status int) string {
+	switch {
+	case status < http.StatusBadRequest:
+		return models.AuditResultSuccess
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return models.AuditResultDenied
+	default:
+		return models.AuditResultError
+	}
+}
+
+// auditRequestBody читает тело запроса (кроме multipart — там файлы) и возвращает
+// его обработчику нетронутым. nil — тела нет или оно не читалось.
+func auditRequestBody(c *gin.Context) interface{} {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody ||
+		strings.HasPrefix(c.ContentType(), "multipart/") {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, auditBodyLimit+1))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), c.Request.Body), c.Request.Body}
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	if len(data) > auditBodyLimit {
+		return map[string]interface{}{"truncated": true, "limit": auditBodyLimit}
+	}
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return map[string]interface{}{"content_type": c.ContentType(), "size": len(data)}
+	}
+	return service.RedactRouteSecrets(c.FullPath(), parsed)
+}
+
+// auditParams — параметры пути, запроса и тело (multipart — поля формы и имена файлов).
+func auditParams(c *gin.Context, body interface{}) map[string]interface{} {
+	params := make(map[string]interface{})
+	if len(c.Params) > 0 {
+		path := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			path[p.Key] = p.Value
+		}
+		params["path"] = path
+	}
+	route := c.FullPath()
+	if query := redactValues(route, c.Request.URL.Query()); len(query) > 0 {
+		params["query"] = query
+	}
+	if form := c.Request.MultipartForm; form != nil {
+		multipart := map[string]interface{}{"fields": redactValues(route, form.Value)}
+		files := make(map[string]interface{}, len(form.File))
+		for field, headers := range form.File {
+			list := make([]map[string]interface{}, 0, len(headers))
+			for _, h := range headers {
+				list = append(list, map[string]interface{}{"filename": h.Filename, "size": h.Size})
+			}
+			files[field] = list
+		}
+		multipart["files"] = files
+		params["body"] = multipart
+	} else if body != nil {
+		params["body"] = body
+	}
+	return params
+}
+
+// redactValues — значения формы или query-строки маршрута route без секретов.
+func redactValues(route string, values map[string][]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for key, list := range values {
+		switch {
+		case service.IsRouteSecretParam(route, key):
+			out[key] = service.RedactedValue
+		case len(list) == 1:
+			out[key] = list[0]
+		default:
+			out[key] = list
+		}
+	}
+	return out
+}
+
+// auditTarget — объект действия: параметр :id (тип — сегмент пути перед ним),
+// иначе первый :<тип>_id; для создания без параметров — id из ответа.
+func auditTarget(c *gin.Context, response map[string]interface{}) (string, string) {
+	segments := strings.Split(strings.Trim(c.FullPath(), "/"), "/")
+	param := -1
+	for i, seg := range segments {
+		if seg == ":id" {
+			param = i
+			break
+		}
+		if param < 0 && strings.HasPrefix(seg, ":") {
+			param = i
+		}
+	}
+	if param >= 0 {
+		name := segments[param][1:]
+		if name != "id" && strings.HasSuffix(name, "_id") {
+			return strings.TrimSuffix(name, "_id"), c.Param(name)
+		}
+		if param == 0 {
+			return name, c.Param(name)
+		}
+		return auditTargetType(segments[param-1]), c.Param(name)
+	}
+
+	if c.Writer.Status() >= http.StatusBadRequest || len(segments) == 0 {
+		return "", ""
+	}
+	id, ok := response["id"]
+	if !ok {
+		return "", ""
+	}
+	var value string
+	switch v := id.(type) {
+	case float64:
+		value = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		value = v
+	default:
+		return "", ""
+	}
+	return auditTargetType(segments[len(segments)-1]), value
+}
+
+// auditTargetType — "alert-rules" → "alert_rule", "checkpoint" → "checkpoint".
+func auditTargetType(segment string) string {
+	t := strings.ReplaceAll(segment, "-", "_")
+	if strings.HasSuffix(t, "s") && !strings.HasSuffix(t, "ss") {
+		t = strings.TrimSuffix(t, "s")
+	}
+	return t
+}
+
+// auditResponseWriter запоминает начало ответа, чтобы достать текст ошибки и id.
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *auditResponseWriter) capture(b []byte) {
+	if room := auditResponseLimit - w.body.Len(); room > 0 {
+		if len(b) > room {
+			b = b[:room]
+		}
+		w.body.Write(b)
+	}
+}
+
+// jsonResponse — ответ как JSON-объект (nil, если это не JSON или он длиннее лимита).
+func (w *auditResponseWriter) jsonResponse() map[string]interface{} {
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return nil
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(w.body.Bytes(), &out); err != nil {
+		return nil
+	}
+	return out
+}
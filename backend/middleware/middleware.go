@@ -1,9 +1,13 @@
 package middleware
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"locator/internal/metrics"
 	"locator/models"
 	"locator/service"
 
@@ -11,97 +15,209 @@ import (
 )
 
 // BasicAuthMiddleware проверяет только наличие API-ключа и аутентифицирует пользователя
-// без проверки на права администратора
+// без проверки на права администратора. API-ключ — учётные данные устройства:
+// он принимается только на маршрутах устройства, но не на административных.
 func BasicAuthMiddleware(userService *service.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Ожидаем, что API ключ передается в заголовке "X-API-Key"
-		apiKey := c.GetHeader("X-API-Key")
-		if apiKey == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Отсутствует API ключ"})
+		user, ok := authenticateAPIKey(c, userService)
+		if !ok {
 			return
 		}
+		if setContextUser(c, userService, user, "BasicAuthMiddleware") {
+			c.Next()
+		}
+	}
+}
 
-		// Пытаемся аутентифицировать пользователя на основе предоставленного API ключа
-		user, err := userService.AuthenticateUser(apiKey)
-		if err != nil {
-			log.Printf("[Middleware] Ошибка аутентификации: %v", err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Неверный API ключ"})
+// UserAuthMiddleware — общие маршруты (профиль, подписки, точки): сотрудник входит
+// сессией ("Authorization: Bearer"), устройство — API-ключом.
+func UserAuthMiddleware(sessions *service.SessionService, userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user *models.User
+		var ok bool
+		if token := bearerToken(c); token != "" {
+			user, ok = authenticateSession(c, sessions, token)
+		} else {
+			user, ok = authenticateAPIKey(c, userService)
+		}
+		if !ok {
 			return
 		}
+		if setContextUser(c, userService, user, "UserAuthMiddleware") {
+			c.Next()
+		}
+	}
+}
 
-		if user == nil {
-			log.Printf("[Middleware] Критическая ошибка: AuthenticateUser вернул nil без ошибки")
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Внутренняя ошибка сервера"})
+// SessionAuthMiddleware — административные маршруты: только сессия сотрудника,
+// полученная входом по логину и паролю. API-ключи здесь не принимаются.
+func SessionAuthMiddleware(sessions *service.SessionService, userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" {
+			msg := "Требуется вход в систему"
+			if c.GetHeader("X-API-Key") != "" {
+				msg = "API-ключ устройства не даёт доступа к административным маршрутам, выполните вход"
+			}
+			metrics.AuthFailures.Inc("session_missing")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": msg})
+			return
+		}
+		user, ok := authenticateSession(c, sessions, token)
+		if !ok {
+			return
+		}
+
+		// Отслеживаемые устройства (роль device) к административным маршрутам не допускаются
+		if !user.IsStaff() {
+			logAccessDenied(c, user, "роль device")
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен: требуются права администратора"})
 			return
 		}
 
-		// Создаем копию пользователя, чтобы избежать проблем с указателями
-		userCopy := models.User{
-			ID:      user.ID,
-			Name:    user.Name,
-			ApiKey:  user.ApiKey,
-			IsAdmin: user.IsAdmin,
-			QRCode:  user.QRCode,
-			// Добавьте другие поля, если они есть в модели
+		if setContextUser(c, userService, user, "SessionAuthMiddleware") {
+			c.Next()
 		}
+	}
+}
+
+// bearerToken — токен из заголовка "Authorization: Bearer <token>".
+func bearerToken(c *gin.Context) string {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
 
-		log.Printf("[BasicAuthMiddleware] Пользователь аутентифицирован: ID=%d, Name=%s, IsAdmin=%t",
-			userCopy.ID, userCopy.Name, userCopy.IsAdmin)
+// authenticateAPIKey проверяет заголовок "X-API-Key"; при ошибке запрос прерван.
+// ID ключа сохраняется в контексте ("api_key_id") для лимитов по ключу.
+func authenticateAPIKey(c *gin.Context, userService *service.UserService) (*models.User, bool) {
+	apiKey := c.GetHeader("X-API-Key")
+	if apiKey == "" {
+		metrics.AuthFailures.Inc("api_key_missing")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Отсутствует API ключ"})
+		return nil, false
+	}
 
-		// Сохраняем КОПИЮ пользователя в контексте запроса
-		c.Set("user", &userCopy)
-		c.Next()
+	// Пытаемся аутентифицировать пользователя на основе предоставленного API ключа
+	user, key, err := userService.AuthenticateAPIKey(apiKey, c.ClientIP())
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "Ошибка аутентификации по API-ключу", "ip", c.ClientIP(), "error", err)
+		metrics.AuthFailures.Inc("api_key_invalid")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Неверный API ключ"})
+		return nil, false
 	}
+
+	if user == nil {
+		slog.ErrorContext(c.Request.Context(), "AuthenticateAPIKey вернул nil без ошибки")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Внутренняя ошибка сервера"})
+		return nil, false
+	}
+	c.Set("api_key_id", key.ID)
+	return user, true
 }
 
-// APIKeyAuthMiddleware проверяет наличие API-ключа в заголовке и аутентифицирует пользователя,
-// а также проверяет права администратора
-func APIKeyAuthMiddleware(userService *service.UserService) gin.HandlerFunc {
+// authenticateSession проверяет access-токен сессии; ID сессии сохраняется
+// в контексте ("session_id") для выхода и списка сессий.
+func authenticateSession(c *gin.Context, sessions *service.SessionService, token string) (*models.User, bool) {
+	user, session, err := sessions.Authenticate(token)
+	if err != nil {
+		metrics.AuthFailures.Inc("session_invalid")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Сессия истекла или недействительна", "code": "session_invalid"})
+		return nil, false
+	}
+	c.Set("session_id", session.ID)
+	return user, true
+}
+
+// setContextUser сохраняет в контексте копию пользователя и его область видимости.
+// Возвращает false, если запрос прерван.
+func setContextUser(c *gin.Context, userService *service.UserService, user *models.User, source string) bool {
+	// Создаем копию пользователя, чтобы избежать проблем с указателями
+	userCopy := models.User{
+		ID:             user.ID,
+		OrganizationID: user.OrganizationID,
+		Name:           user.Name,
+		IsAdmin:        user.IsAdmin,
+		Role:           user.EffectiveRole(),
+		GroupID:        user.GroupID,
+		QRCode:         user.QRCode,
+		Username:       user.Username,
+		TOTPEnabled:    user.TOTPEnabled,
+	}
+
+	slog.DebugContext(c.Request.Context(), "Пользователь аутентифицирован",
+		"middleware", source, "user_id", userCopy.ID, "role", userCopy.Role)
+
+	// Область видимости: сотрудник с группой видит только пользователей своей группы
+	scope, err := userService.ScopeFor(&userCopy)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Ошибка построения области видимости", "user_id", userCopy.ID, "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Внутренняя ошибка сервера"})
+		return false
+	}
+
+	// Сохраняем КОПИЮ пользователя в контексте запроса
+	c.Set("user", &userCopy)
+	c.Set("scope", scope)
+	return true
+}
+
+// RequirePermission пропускает запрос, только если роль текущего пользователя
+// даёт право perm. Ставится после SessionAuthMiddleware при объявлении маршрута.
+func RequirePermission(perm models.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Ожидаем, что API ключ передается в заголовке "X-API-Key"
-		apiKey := c.GetHeader("X-API-Key")
-		if apiKey == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Отсутствует API ключ"})
+		user := contextUser(c)
+		if user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Необходима авторизация"})
 			return
 		}
-
-		// Пытаемся аутентифицировать пользователя на основе предоставленного API ключа
-		user, err := userService.AuthenticateUser(apiKey)
-		if err != nil {
-			log.Printf("[Middleware] Ошибка аутентификации: %v", err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Неверный API ключ"})
+		if !user.HasPermission(perm) {
+			logAccessDenied(c, user, "нет права "+string(perm))
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":      "Недостаточно прав",
+				"permission": perm,
+			})
 			return
 		}
+		c.Next()
+	}
+}
 
-		if user == nil {
-			log.Printf("[Middleware] Критическая ошибка: AuthenticateUser вернул nil без ошибки")
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Внутренняя ошибка сервера"})
+// RequireUserInScope проверяет, что пользователь из параметра пути param
+// входит в область видимости текущего сотрудника (его группу).
+// Некорректный ID пропускается — его отклонит обработчик.
+func RequireUserInScope(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.Atoi(c.Param(param))
+		if err != nil {
+			c.Next()
 			return
 		}
-
-		// Проверяем, является ли пользователь администратором
-		if !user.IsAdmin {
-			log.Printf("[Middleware] Отказано в доступе: пользователь не администратор: ID=%d, Name=%s",
-				user.ID, user.Name)
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен: требуются права администратора"})
+		scope, _ := c.Get("scope")
+		if s, ok := scope.(*service.UserScope); ok && !s.Allows(userID) {
+			logAccessDenied(c, contextUser(c), fmt.Sprintf("пользователь %d вне группы", userID))
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Нет доступа к пользователю"})
 			return
 		}
+		c.Next()
+	}
+}
 
-		// Создаем копию пользователя, чтобы избежать проблем с указателями
-		userCopy := models.User{
-			ID:      user.ID,
-			Name:    user.Name,
-			ApiKey:  user.ApiKey,
-			IsAdmin: user.IsAdmin,
-			QRCode:  user.QRCode,
-			// Добавьте другие поля, если они есть в модели
-		}
-
-		log.Printf("[AdminAuthMiddleware] Пользователь аутентифицирован: ID=%d, Name=%s, IsAdmin=%t",
-			userCopy.ID, userCopy.Name, userCopy.IsAdmin)
+func contextUser(c *gin.Context) *models.User {
+	v, ok := c.Get("user")
+	if !ok {
+		return nil
+	}
+	user, _ := v.(*models.User)
+	return user
+}
 
-		// Сохраняем КОПИЮ пользователя в контексте запроса
-		c.Set("user", &userCopy)
-		c.Next()
+func logAccessDenied(c *gin.Context, user *models.User, reason string) {
+	args := []any{"method", c.Request.Method, "path", c.Request.URL.Path, "reason", reason}
+	if user != nil {
+		args = append(args, "user_id", user.ID, "name", user.Name, "role", user.EffectiveRole())
 	}
+	slog.WarnContext(c.Request.Context(), "Доступ запрещён", args...)
 }
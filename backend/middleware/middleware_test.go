@@ -1,17 +1,24 @@
 package middleware_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"locator/internal/logging"
+	"locator/internal/ratelimit"
 	"locator/internal/testutil"
 	"locator/middleware"
 	"locator/models"
 	"locator/service"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type fakeUserRepo struct {
@@ -34,6 +41,16 @@ func (f *fakeUserRepo) GetByID(id int) (*models.User, error) {
 	cp := u
 	return &cp, nil
 }
+func (f *fakeUserRepo) UseTOTPStep(int, int64) (bool, error) { return false, nil }
+func (f *fakeUserRepo) GetByUsername(username string) (*models.User, error) {
+	for _, u := range f.users {
+		if u.Username != nil && *u.Username == username {
+			cp := u
+			return &cp, nil
+		}
+	}
+	return nil, http.ErrNoCookie
+}
 func (f *fakeUserRepo) GetAll() ([]models.User, error) {
 	out := make([]models.User, 0, len(f.users))
 	for _, u := range f.users {
@@ -41,14 +58,94 @@ func (f *fakeUserRepo) GetAll() ([]models.User, error) {
 	}
 	return out, nil
 }
+func (f *fakeUserRepo) GetAllByOrganization(organizationID int) ([]models.User, error) {
+	out := make([]models.User, 0, len(f.users))
+	for _, u := range f.users {
+		if u.OrganizationID == organizationID || (u.OrganizationID == 0 && organizationID == models.DefaultOrganizationID) {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeUserRepo) GetIDsByOrganization(organizationID int, groupID *int) ([]int, error) {
+	users, _ := f.GetAllByOrganization(organizationID)
+	ids := make([]int, 0, len(users))
+	for _, u := range users {
+		if groupID == nil || (u.GroupID != nil && *u.GroupID == *groupID) {
+			ids = append(ids, u.ID)
+		}
+	}
+	return ids, nil
+}
+
+type fakeAPIKeyRepo struct {
+	keys map[int]models.APIKey
+}
+
+func (f *fakeAPIKeyRepo) CreateAPIKey(key *models.APIKey) error {
+	key.ID = len(f.keys) + 1
+	f.keys[key.ID] = *key
+	return nil
+}
+func (f *fakeAPIKeyRepo) UpdateAPIKey(key *models.APIKey) error {
+	f.keys[key.ID] = *key
+	return nil
+}
+func (f *fakeAPIKeyRepo) GetAPIKeyByID(id int) (*models.APIKey, error) {
+	k, ok := f.keys[id]
+	if !ok {
+		return nil, http.ErrNoCookie
+	}
+	return &k, nil
+}
+func (f *fakeAPIKeyRepo) GetAPIKeysByUser(userID int) ([]models.APIKey, error) {
+	var out []models.APIKey
+	for _, k := range f.keys {
+		if k.UserID == userID {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+func (f *fakeAPIKeyRepo) GetAPIKeyCandidates(fingerprint string) ([]models.APIKey, error) {
+	var out []models.APIKey
+	for _, k := range f.keys {
+		if k.RevokedAt == nil && k.Fingerprint == fingerprint {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+func (f *fakeAPIKeyRepo) GetLegacyAPIKeys(now time.Time, limit int) ([]models.APIKey, error) {
+	var out []models.APIKey
+	for _, k := range f.keys {
+		if k.RevokedAt == nil && k.Fingerprint == "" && k.Active(now) && len(out) < limit {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+func (f *fakeAPIKeyRepo) RevokeReplacedAPIKeys(replacedByID int, at time.Time) error {
+	for id, k := range f.keys {
+		if k.ReplacedByID != nil && *k.ReplacedByID == replacedByID && k.RevokedAt == nil {
+			k.RevokedAt = &at
+			f.keys[id] = k
+		}
+	}
+	return nil
+}
 
 func newTestUserService(t *testing.T, plain string, isAdmin bool) *service.UserService {
 	t.Helper()
-	u, err := testutil.UserWithAPIKey(1, "test", plain, isAdmin)
+	u, key, err := testutil.UserWithAPIKey(1, "test", plain, isAdmin)
 	if err != nil {
 		t.Fatal(err)
 	}
-	svc := &service.UserService{DAO: &fakeUserRepo{users: map[int]models.User{1: u}}}
+	svc := &service.UserService{
+		DAO:  &fakeUserRepo{users: map[int]models.User{1: u}},
+		Keys: &fakeAPIKeyRepo{keys: map[int]models.APIKey{key.ID: key}},
+	}
 	return svc
 }
 
@@ -111,12 +208,108 @@ func TestBasicAuthMiddleware_success(t *testing.T) {
 	}
 }
 
-func TestAPIKeyAuthMiddleware_nonAdminForbidden(t *testing.T) {
+const testPassword = "operator-password-1"
+
+type fakeSessionRepo struct {
+	sessions map[string]models.UserSession
+}
+
+func (f *fakeSessionRepo) CreateSession(s *models.UserSession) error {
+	f.sessions[s.ID] = *s
+	return nil
+}
+func (f *fakeSessionRepo) UpdateSession(s *models.UserSession) error {
+	f.sessions[s.ID] = *s
+	return nil
+}
+func (f *fakeSessionRepo) GetSessionByID(id string) (*models.UserSession, error) {
+	s, ok := f.sessions[id]
+	if !ok {
+		return nil, http.ErrNoCookie
+	}
+	return &s, nil
+}
+func (f *fakeSessionRepo) GetSessionsByUser(userID int) ([]models.UserSession, error) {
+	var out []models.UserSession
+	for _, s := range f.sessions {
+		if s.UserID == userID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+func (f *fakeSessionRepo) RevokeUserSessions(userID int, at time.Time) error {
+	for id, s := range f.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt = &at
+			f.sessions[id] = s
+		}
+	}
+	return nil
+}
+func (f *fakeSessionRepo) RotateRefreshToken(*models.UserSession, string) (bool, error) {
+	return false, nil
+}
+func (f *fakeSessionRepo) RevokeSession(string, time.Time) error         { return nil }
+func (f *fakeSessionRepo) CreateLoginLink(*models.LoginLink) error       { return nil }
+func (f *fakeSessionRepo) ConsumeLoginLink(int, time.Time) (bool, error) { return false, nil }
+func (f *fakeSessionRepo) GetLoginLinkByHash(string) (*models.LoginLink, error) {
+	return nil, http.ErrNoCookie
+}
+
+// userFixture — пользователь и его API-ключ.
+type userFixture struct {
+	user models.User
+	key  models.APIKey
+}
+
+// newSessionEnv — сервисы пользователей и сессий поверх общих фейков.
+func newSessionEnv(t *testing.T, fixtures ...userFixture) (*service.UserService, *service.SessionService, *fakeUserRepo) {
+	t.Helper()
+	repo := &fakeUserRepo{users: map[int]models.User{}}
+	keys := &fakeAPIKeyRepo{keys: map[int]models.APIKey{}}
+	for _, f := range fixtures {
+		repo.users[f.user.ID] = f.user
+		keys.keys[f.key.ID] = f.key
+	}
+	sessions := service.NewSessionService(repo, &fakeSessionRepo{sessions: map[string]models.UserSession{}}, []byte("test-session-secret"))
+	return service.NewUserService(repo, keys), sessions, repo
+}
+
+// userWithRole — пользователь с API-ключом, логином user<ID> и паролем testPassword.
+func userWithRole(t *testing.T, id int, plainKey, role string, groupID *int) userFixture {
+	t.Helper()
+	u, key, err := testutil.UserWithAPIKey(id, "user", plainKey, role != models.RoleDevice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Role = role
+	u.GroupID = groupID
+	username := fmt.Sprintf("user%d", id)
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Username = &username
+	u.PasswordHash = string(hash)
+	return userFixture{user: u, key: key}
+}
+
+func sessionToken(t *testing.T, sessions *service.SessionService, userID int) string {
+	t.Helper()
+	tokens, err := sessions.Login(fmt.Sprintf("user%d", userID), testPassword, "", service.SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens.AccessToken
+}
+
+func TestSessionAuthMiddleware_apiKeyRejected(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	const key = "device-key-abcdefgh"
-	svc := newTestUserService(t, key, false)
+	const key = "admin-key-abcdefgh"
+	users, sessions, _ := newSessionEnv(t, userWithRole(t, 1, key, models.RoleSuperAdmin, nil))
 	r := gin.New()
-	r.GET("/admin", middleware.APIKeyAuthMiddleware(svc), func(c *gin.Context) {
+	r.GET("/admin", middleware.SessionAuthMiddleware(sessions, users), func(c *gin.Context) {
 		c.JSON(200, gin.H{"ok": true})
 	})
 
@@ -124,25 +317,352 @@ func TestAPIKeyAuthMiddleware_nonAdminForbidden(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
 	req.Header.Set("X-API-Key", key)
 	r.ServeHTTP(w, req)
-	if w.Code != http.StatusForbidden {
+	if w.Code != http.StatusUnauthorized {
 		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
 	}
 }
 
-func TestAPIKeyAuthMiddleware_adminOK(t *testing.T) {
+func TestSessionAuthMiddleware_adminOK(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	const key = "admin-key-abcdefgh"
-	svc := newTestUserService(t, key, true)
+	users, sessions, _ := newSessionEnv(t, userWithRole(t, 1, "admin-key-abcdefgh", models.RoleSuperAdmin, nil))
 	r := gin.New()
-	r.GET("/admin", middleware.APIKeyAuthMiddleware(svc), func(c *gin.Context) {
-		c.JSON(200, gin.H{"ok": true})
+	r.GET("/admin", middleware.SessionAuthMiddleware(sessions, users), func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true, "session": c.GetString("session_id") != ""})
 	})
 
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
-	req.Header.Set("X-API-Key", key)
+	req.Header.Set("Authorization", "Bearer "+sessionToken(t, sessions, 1))
 	r.ServeHTTP(w, req)
 	if w.Code != http.StatusOK {
 		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
 	}
 }
+
+func TestSessionAuthMiddleware_revokedAndTamperedRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users, sessions, _ := newSessionEnv(t, userWithRole(t, 1, "admin-key-abcdefgh", models.RoleSuperAdmin, nil))
+	r := gin.New()
+	r.GET("/admin", middleware.SessionAuthMiddleware(sessions, users), func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true})
+	})
+	call := func(token string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	token := sessionToken(t, sessions, 1)
+	if code := call(token + "x"); code != http.StatusUnauthorized {
+		t.Fatalf("tampered: status=%d", code)
+	}
+	_, session, err := sessions.Authenticate(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.Logout(session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if code := call(token); code != http.StatusUnauthorized {
+		t.Fatalf("revoked: status=%d", code)
+	}
+}
+
+func TestRequirePermission_roleChecked(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users, sessions, _ := newSessionEnv(t, userWithRole(t, 1, "viewer-key-abcdefgh", models.RoleViewer, nil))
+	token := sessionToken(t, sessions, 1)
+	r := gin.New()
+	r.Use(middleware.SessionAuthMiddleware(sessions, users))
+	ok := func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) }
+	r.GET("/read", middleware.RequirePermission(models.PermTrackingRead), ok)
+	r.POST("/command", middleware.RequirePermission(models.PermDevicesCommand), ok)
+
+	for path, want := range map[string]int{"/read": http.StatusOK, "/command": http.StatusForbidden} {
+		method := http.MethodGet
+		if path == "/command" {
+			method = http.MethodPost
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("%s: status=%d want %d body=%s", path, w.Code, want, w.Body.String())
+		}
+	}
+}
+
+func TestRequireUserInScope_groupLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	group, other := 7, 8
+	users, sessions, _ := newSessionEnv(t,
+		userWithRole(t, 1, "manager-key-abcdefgh", models.RoleFleetAdmin, &group),
+		userWithRole(t, 2, "device-2-key-abcdefgh", models.RoleDevice, &group),
+		userWithRole(t, 3, "device-3-key-abcdefgh", models.RoleDevice, &other),
+	)
+	token := sessionToken(t, sessions, 1)
+	r := gin.New()
+	r.Use(middleware.SessionAuthMiddleware(sessions, users))
+	r.GET("/users/:id", middleware.RequireUserInScope("id"), func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true})
+	})
+
+	for path, want := range map[string]int{"/users/2": http.StatusOK, "/users/3": http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("%s: status=%d want %d", path, w.Code, want)
+		}
+	}
+}
+
+func TestSessionAuthMiddleware_deviceRoleForbidden(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users, sessions, repo := newSessionEnv(t, userWithRole(t, 1, "staff-key-abcdefgh", models.RoleDispatcher, nil))
+	token := sessionToken(t, sessions, 1)
+
+	// Сотрудника перевели в роль device после входа; устаревший флаг is_admin не должен открывать доступ.
+	u := repo.users[1]
+	u.Role = models.RoleDevice
+	u.IsAdmin = true
+	repo.users[1] = u
+
+	r := gin.New()
+	r.GET("/admin", middleware.SessionAuthMiddleware(sessions, users), func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true})
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status=%d", w.Code)
+	}
+}
+
+func TestUserAuthMiddleware_acceptsSessionOrAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const deviceKey = "device-key-abcdefgh"
+	users, sessions, _ := newSessionEnv(t,
+		userWithRole(t, 1, "admin-key-abcdefgh", models.RoleSuperAdmin, nil),
+		userWithRole(t, 2, deviceKey, models.RoleDevice, nil),
+	)
+	r := gin.New()
+	r.GET("/me", middleware.UserAuthMiddleware(sessions, users), func(c *gin.Context) {
+		u, _ := c.Get("user")
+		c.JSON(200, u)
+	})
+
+	for header, want := range map[[2]string]int{
+		{"Authorization", "Bearer " + sessionToken(t, sessions, 1)}: 1,
+		{"X-API-Key", deviceKey}:                                    2,
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set(header[0], header[1])
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status=%d body=%s", header[0], w.Code, w.Body.String())
+		}
+		var got models.User
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != want {
+			t.Fatalf("%s: got user %d, want %d", header[0], got.ID, want)
+		}
+	}
+}
+
+func TestRateLimit_perKeyWithRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const deviceKey = "device-key-abcdefgh"
+	users, sessions, _ := newSessionEnv(t, userWithRole(t, 2, deviceKey, models.RoleDevice, nil))
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(),
+		map[string]ratelimit.Limit{ratelimit.ClassIngest: ratelimit.PerMinute(1, 2)}, ratelimit.DefaultLockout)
+	r := gin.New()
+	r.POST("/location", middleware.UserAuthMiddleware(sessions, users), middleware.RateLimit(limiter, ratelimit.ClassIngest),
+		func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	send := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/location", nil)
+		req.Header.Set("X-API-Key", deviceKey)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for i := 0; i < 2; i++ {
+		if w := send(); w.Code != http.StatusCreated {
+			t.Fatalf("request %d: status=%d body=%s", i+1, w.Code, w.Body.String())
+		}
+	}
+	w := send()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status=%d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got == "" || got == "0" {
+		t.Fatalf("Retry-After=%q", got)
+	}
+	var body struct {
+		Code       string `json:"code"`
+		RetryAfter int    `json:"retry_after"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != "rate_limited" || body.RetryAfter < 1 {
+		t.Fatalf("body=%s", w.Body.String())
+	}
+}
+
+func TestAuthGuard_lockoutSparesVerifiedKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const deviceKey = "device-key-abcdefgh"
+	users, sessions, _ := newSessionEnv(t, userWithRole(t, 2, deviceKey, models.RoleDevice, nil))
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), nil,
+		ratelimit.Lockout{Free: 2, Base: time.Minute, Max: time.Hour, Window: time.Hour})
+	r := gin.New()
+	r.GET("/device/poll", middleware.AuthGuard(limiter), middleware.UserAuthMiddleware(sessions, users),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/device/poll", nil)
+		req.Header.Set("X-API-Key", key)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Устройство за тем же NAT успешно работало до перебора
+	if w := send(deviceKey); w.Code != http.StatusOK {
+		t.Fatalf("device: status=%d", w.Code)
+	}
+	for i := 0; i < 3; i++ {
+		if w := send(fmt.Sprintf("guess-%08d", i)); w.Code != http.StatusUnauthorized {
+			t.Fatalf("guess %d: status=%d", i, w.Code)
+		}
+	}
+	w := send("guess-next")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked IP: status=%d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Fatalf("Retry-After=%q, want 60", got)
+	}
+	if w := send(deviceKey); w.Code != http.StatusOK {
+		t.Fatalf("verified device blocked by IP lockout: status=%d", w.Code)
+	}
+}
+
+type fakeAuditRepo struct {
+	entries []models.AuditEntry
+}
+
+func (f *fakeAuditRepo) CreateAuditEntry(entry *models.AuditEntry) error {
+	f.entries = append(f.entries, *entry)
+	return nil
+}
+
+func (f *fakeAuditRepo) ListAuditEntries(map[string]interface{}, *time.Time, *time.Time, int, int) ([]models.AuditEntry, int64, error) {
+	return f.entries, int64(len(f.entries)), nil
+}
+
+func TestAudit_recordsMutationsWithoutSecrets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users, sessions, _ := newSessionEnv(t,
+		userWithRole(t, 1, "admin-key-abcdefgh", models.RoleFleetAdmin, nil),
+		userWithRole(t, 2, "viewer-key-abcdefgh", models.RoleViewer, nil),
+	)
+	repo := &fakeAuditRepo{}
+	r := gin.New()
+	api := r.Group("/api")
+	api.Use(middleware.SessionAuthMiddleware(sessions, users), middleware.Audit(service.NewAuditService(repo)))
+	api.GET("/admin/users/:id/device/desired-config", func(c *gin.Context) { c.Status(http.StatusOK) })
+	api.PUT("/admin/users/:id/device/desired-config", middleware.RequirePermission(models.PermDevicesConfig), func(c *gin.Context) {
+		var body map[string]interface{}
+		if err := c.ShouldBindJSON(&body); err != nil || body["admin_pin"] != "4321" {
+			t.Errorf("handler got altered body: %v %v", body, err)
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	api.POST("/admin/alert-rules", func(c *gin.Context) { c.JSON(http.StatusCreated, gin.H{"id": 17}) })
+	api.POST("/auth/totp/enable", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	send := func(method, path string, userID int, body string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+sessionToken(t, sessions, userID))
+		r.ServeHTTP(w, req)
+	}
+	send(http.MethodGet, "/api/admin/users/5/device/desired-config", 1, "")
+	send(http.MethodPut, "/api/admin/users/5/device/desired-config?dry_run=1", 1, `{"admin_pin":"4321","poll_interval_seconds":60}`)
+	send(http.MethodPut, "/api/admin/users/5/device/desired-config", 2, `{"admin_pin":"0000"}`)
+	send(http.MethodPost, "/api/admin/alert-rules", 1, `{"name":"offline","code":"device_offline"}`)
+	send(http.MethodPost, "/api/auth/totp/enable", 1, `{"code":"492039"}`)
+
+	if len(repo.entries) != 4 {
+		t.Fatalf("want 4 entries (GET is not audited), got %d", len(repo.entries))
+	}
+	put := repo.entries[0]
+	if put.Action != "PUT /api/admin/users/:id/device/desired-config" || put.TargetType != "user" || put.TargetID != "5" ||
+		put.ActorID == nil || *put.ActorID != 1 || put.Result != models.AuditResultSuccess || put.SessionID == "" {
+		t.Fatalf("unexpected entry: %+v", put)
+	}
+	params := string(put.Params)
+	if strings.Contains(params, "4321") || !strings.Contains(params, `"poll_interval_seconds":60`) || !strings.Contains(params, `"dry_run":"1"`) {
+		t.Fatalf("params=%s", params)
+	}
+
+	denied := repo.entries[1]
+	if denied.Result != models.AuditResultDenied || denied.Status != http.StatusForbidden || denied.Error == "" ||
+		strings.Contains(string(denied.Params), "0000") {
+		t.Fatalf("denied entry: %+v params=%s", denied, denied.Params)
+	}
+
+	created := repo.entries[2]
+	if created.TargetType != "alert_rule" || created.TargetID != "17" || !strings.Contains(string(created.Params), "device_offline") {
+		t.Fatalf("created entry target=%s:%s params=%s", created.TargetType, created.TargetID, created.Params)
+	}
+
+	if totp := string(repo.entries[3].Params); strings.Contains(totp, "492039") {
+		t.Fatalf("TOTP code leaked: %s", totp)
+	}
+}
+
+func TestRequestID_propagatesToContextAndResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestID())
+	var seen string
+	r.GET("/x", func(c *gin.Context) {
+		seen = logging.RequestID(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	})
+
+	for incoming, keep := range map[string]bool{
+		"":                      false,
+		"req-42.a_b":            true,
+		"bad id\nwith newline":  false,
+		strings.Repeat("a", 65): false,
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/x", nil)
+		if incoming != "" {
+			req.Header.Set(middleware.RequestIDHeader, incoming)
+		}
+		r.ServeHTTP(w, req)
+		got := w.Header().Get(middleware.RequestIDHeader)
+		if got == "" || got != seen {
+			t.Fatalf("%q: header %q, context %q", incoming, got, seen)
+		}
+		if keep != (got == incoming) {
+			t.Fatalf("%q: got id %q", incoming, got)
+		}
+	}
+}
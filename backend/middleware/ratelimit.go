@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"locator/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthGuard ставится перед аутентификацией: отклоняет запросы с IP, заблокированного
+// после серии неудачных входов, и учитывает исход аутентификации: ответ 401 —
+// неудача. Учётные данные, недавно прошедшие проверку, блокировку IP обходят,
+// чтобы устройства за общим NAT не страдали из-за соседа. При limiter == nil
+// ничего не делает.
+func AuthGuard(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		ip := c.ClientIP()
+		credential := requestCredential(c)
+		if wait := limiter.Locked(ip, credential); wait > 0 {
+			slog.WarnContext(c.Request.Context(), "IP заблокирован после неудачных попыток входа", "ip", ip,
+				"wait", wait.Round(time.Second), "method", c.Request.Method, "path", c.FullPath())
+			abortTooManyRequests(c, wait, "Слишком много неудачных попыток входа, повторите позже")
+			return
+		}
+
+		c.Next()
+
+		switch status := c.Writer.Status(); {
+		case status == http.StatusUnauthorized:
+			if lock := limiter.AuthFailed(ip); lock > 0 {
+				slog.WarnContext(c.Request.Context(), "IP заблокирован после неудачной аутентификации", "ip", ip, "lock", lock)
+			}
+		case status < http.StatusBadRequest:
+			limiter.Trust(credential)
+		}
+	}
+}
+
+// RateLimit ограничивает частоту запросов класса class. Ставится после
+// аутентификации: лимит считается по API-ключу или сессии, без них — по IP.
+// При limiter == nil ничего не делает.
+func RateLimit(limiter *ratelimit.Limiter, class string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		identity := rateLimitIdentity(c)
+		if ok, retryAfter := limiter.Allow(class, identity); !ok {
+			slog.WarnContext(c.Request.Context(), "Превышен лимит запросов", "class", class, "identity", identity,
+				"method", c.Request.Method, "path", c.FullPath())
+			abortTooManyRequests(c, retryAfter, "Слишком много запросов, повторите позже")
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitIdentity — ключ корзины: API-ключ, сессия или адрес клиента.
+func rateLimitIdentity(c *gin.Context) string {
+	if id := c.GetInt("api_key_id"); id > 0 {
+		return fmt.Sprintf("key:%d", id)
+	}
+	if id := c.GetString("session_id"); id != "" {
+		return "session:" + id
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// requestCredential — предъявленные учётные данные: токен сессии или API-ключ.
+func requestCredential(c *gin.Context) string {
+	if token := bearerToken(c); token != "" {
+		return token
+	}
+	return c.GetHeader("X-API-Key")
+}
+
+// abortTooManyRequests отвечает 429 с заголовком Retry-After (целые секунды, не меньше 1).
+func abortTooManyRequests(c *gin.Context, wait time.Duration, msg string) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": msg, "code": "rate_limited", "retry_after": seconds})
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"locator/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader — заголовок с ID запроса: принимается от клиента или прокси и
+// возвращается в ответе.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID присваивает запросу ID (из заголовка X-Request-ID, если он похож на ID,
+// иначе новый) и кладёт его в контекст запроса — записи slog.*Context из
+// обработчиков и сервисов получат атрибут request_id.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID — до 64 символов из [A-Za-z0-9._-]: чужой заголовок не должен
+// ломать журнал.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLog пишет по записи на запрос: метод, шаблон маршрута, код, длительность,
+// пользователь. 5xx — уровень error, остальное — info (попадает под сэмплирование).
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("duration_ms", time.Since(started).Milliseconds()),
+			slog.String("ip", c.ClientIP()),
+		}
+		if user := contextUser(c); user != nil {
+			attrs = append(attrs, slog.Int("user_id", user.ID))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "HTTP-запрос", attrs...)
+	}
+}
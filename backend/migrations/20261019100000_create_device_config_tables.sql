@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS device_config_profiles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    location_interval_seconds BIGINT,
+    poll_interval_seconds BIGINT,
+    health_report_interval_seconds BIGINT,
+    tracking_paused BOOLEAN,
+    hidden_from_launcher BOOLEAN,
+    api_base_url VARCHAR(255),
+    admin_pin VARCHAR(12),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS device_desired_configs (
+    user_id INTEGER PRIMARY KEY,
+    profile_id INTEGER,
+    location_interval_seconds BIGINT,
+    poll_interval_seconds BIGINT,
+    health_report_interval_seconds BIGINT,
+    tracking_paused BOOLEAN,
+    hidden_from_launcher BOOLEAN,
+    api_base_url VARCHAR(255),
+    admin_pin VARCHAR(12),
+    last_pushed_hash VARCHAR(64),
+    last_pushed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_desired_configs_profile_id ON device_desired_configs (profile_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS device_desired_configs;
+DROP TABLE IF EXISTS device_config_profiles;
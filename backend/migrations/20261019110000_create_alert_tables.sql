@@ -0,0 +1,51 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(40) NOT NULL,
+    severity VARCHAR(20) NOT NULL DEFAULT 'warning',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    user_id INTEGER,
+    threshold_minutes BIGINT,
+    issue VARCHAR(100),
+    battery_percent DOUBLE PRECISION,
+    min_app_version VARCHAR(50),
+    work_start_hour BIGINT,
+    work_end_hour BIGINT,
+    work_days_only BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_type ON alert_rules (type);
+CREATE INDEX IF NOT EXISTS idx_alert_rules_user_id ON alert_rules (user_id);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id SERIAL PRIMARY KEY,
+    rule_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    type VARCHAR(40) NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    dedup_key VARCHAR(100) NOT NULL,
+    message TEXT,
+    first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    acknowledged_by INTEGER,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    resolved_by INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_rule_id ON alerts (rule_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_user_id ON alerts (user_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_status ON alerts (status);
+CREATE INDEX IF NOT EXISTS idx_alerts_dedup_key ON alerts (dedup_key);
+CREATE INDEX IF NOT EXISTS idx_alerts_resolved_at ON alerts (resolved_at);
+-- Не более одного активного алерта на правило + пользователя.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_active_dedup ON alerts (dedup_key) WHERE status <> 'resolved';
+
+-- +goose Down
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS alert_rules;
@@ -0,0 +1,50 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS app_releases (
+    id SERIAL PRIMARY KEY,
+    channel VARCHAR(64) NOT NULL,
+    version_name VARCHAR(50) NOT NULL,
+    version_code BIGINT NOT NULL,
+    package_name VARCHAR(255),
+    filename VARCHAR(255) NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    changelog TEXT,
+    force BOOLEAN NOT NULL DEFAULT false,
+    rollout_percent BIGINT NOT NULL DEFAULT 100,
+    status VARCHAR(20) NOT NULL,
+    pause_reason TEXT,
+    paused_at TIMESTAMP WITH TIME ZONE,
+    max_failure_percent BIGINT NOT NULL DEFAULT 20,
+    min_failure_samples BIGINT NOT NULL DEFAULT 5,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_releases_channel ON app_releases (channel);
+CREATE INDEX IF NOT EXISTS idx_app_releases_status ON app_releases (status);
+-- Одна сборка (version_code) на канал.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_app_releases_channel_version ON app_releases (channel, version_code);
+
+CREATE TABLE IF NOT EXISTS app_release_channels (
+    name VARCHAR(64) PRIMARY KEY,
+    min_version VARCHAR(50),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS app_release_members (
+    user_id INTEGER PRIMARY KEY,
+    channel VARCHAR(64) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_release_members_channel ON app_release_members (channel);
+
+-- Статистика ack по релизу: payload->>'release_id' команд app_update.
+CREATE INDEX IF NOT EXISTS idx_device_commands_app_update_release
+    ON device_commands ((payload->>'release_id'))
+    WHERE type = 'app_update';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_device_commands_app_update_release;
+DROP TABLE IF EXISTS app_release_members;
+DROP TABLE IF EXISTS app_release_channels;
+DROP TABLE IF EXISTS app_releases;
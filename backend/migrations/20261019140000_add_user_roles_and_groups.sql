@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS groups (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_name ON groups (name);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS group_id INTEGER REFERENCES groups (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_group_id ON users (group_id);
+
+-- Существующие администраторы получают полный доступ, остальные — роль устройства.
+UPDATE users SET role = 'super_admin' WHERE role = '' AND is_admin = true;
+UPDATE users SET role = 'device' WHERE role = '';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_group_id;
+ALTER TABLE users DROP COLUMN IF EXISTS group_id;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+DROP TABLE IF EXISTS groups;
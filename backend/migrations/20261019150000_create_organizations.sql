@@ -0,0 +1,67 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_name ON organizations (name);
+
+-- Организация-владелец инсталляции: все существующие данные переходят в неё.
+INSERT INTO organizations (id, name) VALUES (1, 'default') ON CONFLICT (id) DO NOTHING;
+SELECT setval(pg_get_serial_sequence('organizations', 'id'), GREATEST((SELECT MAX(id) FROM organizations), 1));
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id);
+CREATE INDEX IF NOT EXISTS idx_users_organization_id ON users (organization_id);
+
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id);
+DROP INDEX IF EXISTS idx_groups_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_org_name ON groups (organization_id, name);
+
+ALTER TABLE checkpoints ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id);
+CREATE INDEX IF NOT EXISTS idx_checkpoints_organization_id ON checkpoints (organization_id);
+
+ALTER TABLE device_commands ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_device_commands_organization_id ON device_commands (organization_id);
+
+ALTER TABLE alert_rules ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id);
+CREATE INDEX IF NOT EXISTS idx_alert_rules_organization_id ON alert_rules (organization_id);
+
+ALTER TABLE app_releases ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id);
+CREATE INDEX IF NOT EXISTS idx_app_releases_organization_id ON app_releases (organization_id);
+DROP INDEX IF EXISTS idx_app_releases_channel_version;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_app_releases_channel_version ON app_releases (organization_id, channel, version_code);
+
+-- Настройки каналов — свои у каждой организации.
+ALTER TABLE app_release_channels ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id);
+ALTER TABLE app_release_channels DROP CONSTRAINT IF EXISTS app_release_channels_pkey;
+ALTER TABLE app_release_channels ADD PRIMARY KEY (organization_id, name);
+
+-- +goose Down
+ALTER TABLE app_release_channels DROP CONSTRAINT IF EXISTS app_release_channels_pkey;
+DELETE FROM app_release_channels WHERE organization_id <> 1;
+ALTER TABLE app_release_channels DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE app_release_channels ADD PRIMARY KEY (name);
+
+DROP INDEX IF EXISTS idx_app_releases_channel_version;
+DROP INDEX IF EXISTS idx_app_releases_organization_id;
+ALTER TABLE app_releases DROP COLUMN IF EXISTS organization_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_app_releases_channel_version ON app_releases (channel, version_code);
+
+DROP INDEX IF EXISTS idx_alert_rules_organization_id;
+ALTER TABLE alert_rules DROP COLUMN IF EXISTS organization_id;
+
+DROP INDEX IF EXISTS idx_device_commands_organization_id;
+ALTER TABLE device_commands DROP COLUMN IF EXISTS organization_id;
+
+DROP INDEX IF EXISTS idx_checkpoints_organization_id;
+ALTER TABLE checkpoints DROP COLUMN IF EXISTS organization_id;
+
+DROP INDEX IF EXISTS idx_groups_org_name;
+ALTER TABLE groups DROP COLUMN IF EXISTS organization_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_name ON groups (name);
+
+DROP INDEX IF EXISTS idx_users_organization_id;
+ALTER TABLE users DROP COLUMN IF EXISTS organization_id;
+
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    label VARCHAR(100) NOT NULL DEFAULT '',
+    fingerprint VARCHAR(16) NOT NULL DEFAULT '',
+    key_hash VARCHAR(100) NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    replaced_by_id INTEGER,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    last_used_ip VARCHAR(64) NOT NULL DEFAULT '',
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_fingerprint ON api_keys (fingerprint);
+CREATE INDEX IF NOT EXISTS idx_api_keys_replaced_by_id ON api_keys (replaced_by_id);
+
+-- Единственный ключ пользователя становится основным (QR). Отпечаток неизвестен —
+-- он заполнится при первой успешной проверке ключа.
+INSERT INTO api_keys (user_id, label, key_hash, is_primary, created_at)
+SELECT id, 'QR', api_key, TRUE, created_at FROM users WHERE api_key IS NOT NULL AND api_key <> '';
+
+ALTER TABLE users DROP COLUMN IF EXISTS api_key;
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN IF NOT EXISTS api_key TEXT;
+UPDATE users SET api_key = k.key_hash
+FROM (
+    SELECT DISTINCT ON (user_id) user_id, key_hash FROM api_keys
+    WHERE revoked_at IS NULL ORDER BY user_id, is_primary DESC, id DESC
+) k
+WHERE users.id = k.user_id;
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS tracking_schedules (
+    user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    organization_id INTEGER NOT NULL DEFAULT 1,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'Europe/Minsk',
+    windows JSONB,
+    holidays JSONB,
+    outside_action VARCHAR(10) NOT NULL DEFAULT 'drop',
+    consent_required BOOLEAN NOT NULL DEFAULT FALSE,
+    consent_given_at TIMESTAMP WITH TIME ZONE,
+    consent_by VARCHAR(100) NOT NULL DEFAULT '',
+    consent_recorded_by INTEGER,
+    consent_revoked_at TIMESTAMP WITH TIME ZONE,
+    last_pushed_paused BOOLEAN,
+    last_pushed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tracking_schedules_organization_id ON tracking_schedules (organization_id);
+
+-- Точки вне окна отслеживания с огрублёнными координатами (outside_action = mask).
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS masked BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE locations DROP COLUMN IF EXISTS masked;
+DROP TABLE IF EXISTS tracking_schedules;
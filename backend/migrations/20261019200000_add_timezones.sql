@@ -0,0 +1,15 @@
+-- +goose Up
+-- Часовые пояса: локальное время в запросах, «сутки», окна отслеживания и тихие часы
+-- считаются в поясе пользователя, а если он не задан — в поясе его организации.
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'Europe/Minsk';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '';
+
+-- График отслеживания без явного пояса следует поясу пользователя.
+ALTER TABLE tracking_schedules ALTER COLUMN timezone SET DEFAULT '';
+UPDATE tracking_schedules SET timezone = '' WHERE timezone = 'Europe/Minsk';
+
+-- +goose Down
+UPDATE tracking_schedules SET timezone = 'Europe/Minsk' WHERE timezone = '';
+ALTER TABLE tracking_schedules ALTER COLUMN timezone SET DEFAULT 'Europe/Minsk';
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
+ALTER TABLE organizations DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,42 @@
+-- +goose Up
+-- Сроки хранения точек по организациям (без записи — значения RETENTION_* из окружения).
+CREATE TABLE IF NOT EXISTS retention_policies (
+    organization_id INTEGER PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    raw_days INTEGER NOT NULL DEFAULT 0,
+    downsample_minutes INTEGER NOT NULL DEFAULT 5,
+    purge_days INTEGER NOT NULL DEFAULT 0,
+    archive BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Проходы прореживания/удаления и их прогресс.
+CREATE TABLE IF NOT EXISTS retention_runs (
+    id BIGSERIAL PRIMARY KEY,
+    organization_id INTEGER,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(10) NOT NULL DEFAULT 'running',
+    users_total INTEGER NOT NULL DEFAULT 0,
+    users_done INTEGER NOT NULL DEFAULT 0,
+    scanned BIGINT NOT NULL DEFAULT 0,
+    downsampled BIGINT NOT NULL DEFAULT 0,
+    purged BIGINT NOT NULL DEFAULT 0,
+    archived BIGINT NOT NULL DEFAULT 0,
+    archive_files TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Точка уже прорежена: повторный проход её не пересматривает.
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS downsampled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Выборка точек пользователя по времени фиксации (прореживание и удаление).
+CREATE INDEX IF NOT EXISTS idx_locations_user_effective_at
+    ON locations (user_id, (COALESCE(captured_at, created_at)), id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_locations_user_effective_at;
+ALTER TABLE locations DROP COLUMN IF EXISTS downsampled;
+DROP TABLE IF EXISTS retention_runs;
+DROP TABLE IF EXISTS retention_policies;
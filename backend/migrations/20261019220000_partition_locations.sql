@@ -0,0 +1,121 @@
+-- +goose Up
+-- locations секционируется по месяцам времени фиксации COALESCE(captured_at, created_at):
+-- выборки по времени затрагивают только нужные секции, а срок хранения сводится к
+-- удалению старых секций. Первичного ключа нет — у секционированной таблицы он обязан
+-- включать ключ секционирования, а выражение в ключ не входит; уникальность id даёт
+-- последовательность. Точки вне созданных секций попадают в locations_default.
+-- Время хранится с поясом (UTC): сервер пишет UTC, поэтому старые значения без пояса
+-- переносятся как UTC.
+SET LOCAL TimeZone = 'UTC';
+
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS request_id VARCHAR(36);
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS source VARCHAR(20);
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS captured_at TIMESTAMP WITHOUT TIME ZONE;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS masked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS downsampled BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE locations RENAME TO locations_legacy;
+ALTER SEQUENCE locations_id_seq OWNED BY NONE;
+
+CREATE TABLE locations (
+    id BIGINT NOT NULL DEFAULT nextval('locations_id_seq'),
+    user_id INTEGER NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    request_id VARCHAR(36),
+    source VARCHAR(20),
+    captured_at TIMESTAMP WITH TIME ZONE,
+    masked BOOLEAN NOT NULL DEFAULT FALSE,
+    downsampled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_locations_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) PARTITION BY RANGE ((COALESCE(captured_at, created_at)));
+
+CREATE TABLE locations_default PARTITION OF locations DEFAULT;
+
+-- Месячные секции locations_pYYYYMM с from_month по to_month включительно. Точки
+-- нового месяца, уже попавшие в locations_default, переносятся в его секцию.
+-- Возвращает число созданных секций.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION locations_ensure_partitions(from_month DATE, to_month DATE) RETURNS INTEGER AS $$
+DECLARE
+    m DATE := date_trunc('month', from_month)::date;
+    lo TIMESTAMPTZ;
+    hi TIMESTAMPTZ;
+    part TEXT;
+    created INTEGER := 0;
+BEGIN
+    WHILE m <= to_month LOOP
+        part := 'locations_p' || to_char(m, 'YYYYMM');
+        lo := m::timestamp AT TIME ZONE 'UTC';
+        hi := (m + INTERVAL '1 month')::timestamp AT TIME ZONE 'UTC';
+        IF to_regclass(part) IS NULL THEN
+            EXECUTE format('CREATE TABLE %I (LIKE locations INCLUDING DEFAULTS)', part);
+            EXECUTE format(
+                'WITH moved AS (DELETE FROM locations_default WHERE COALESCE(captured_at, created_at) >= %L AND COALESCE(captured_at, created_at) < %L RETURNING *) '
+                    || 'INSERT INTO %I SELECT * FROM moved',
+                lo, hi, part);
+            EXECUTE format('ALTER TABLE locations ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)', part, lo, hi);
+            created := created + 1;
+        END IF;
+        m := (m + INTERVAL '1 month')::date;
+    END LOOP;
+    RETURN created;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Секции от самой старой точки (не глубже пяти лет — остальное в locations_default)
+-- до трёх месяцев вперёд.
+SELECT locations_ensure_partitions(
+    GREATEST(
+        COALESCE((SELECT min(COALESCE(captured_at, created_at)) FROM locations_legacy), CURRENT_TIMESTAMP),
+        CURRENT_TIMESTAMP - INTERVAL '5 years'
+    )::date,
+    (CURRENT_TIMESTAMP + INTERVAL '3 months')::date
+);
+
+INSERT INTO locations (id, user_id, latitude, longitude, request_id, source, captured_at, masked, downsampled, created_at, updated_at)
+SELECT id, user_id, latitude, longitude, request_id, source, captured_at, masked, downsampled,
+       COALESCE(created_at, updated_at, CURRENT_TIMESTAMP), updated_at
+FROM locations_legacy;
+
+DROP TABLE locations_legacy;
+ALTER SEQUENCE locations_id_seq OWNED BY locations.id;
+
+CREATE INDEX idx_locations_id ON locations (id);
+CREATE INDEX idx_locations_user_effective_at ON locations (user_id, (COALESCE(captured_at, created_at)), id);
+CREATE INDEX idx_locations_effective_at ON locations ((COALESCE(captured_at, created_at)));
+CREATE INDEX idx_locations_request_id ON locations (request_id);
+
+-- +goose Down
+SET LOCAL TimeZone = 'UTC';
+
+ALTER TABLE locations RENAME TO locations_partitioned;
+ALTER SEQUENCE locations_id_seq OWNED BY NONE;
+
+CREATE TABLE locations (
+    id BIGINT PRIMARY KEY DEFAULT nextval('locations_id_seq'),
+    user_id INTEGER NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    request_id VARCHAR(36),
+    source VARCHAR(20),
+    captured_at TIMESTAMP WITH TIME ZONE,
+    masked BOOLEAN NOT NULL DEFAULT FALSE,
+    downsampled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_location_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+INSERT INTO locations SELECT * FROM locations_partitioned;
+
+DROP TABLE locations_partitioned CASCADE;
+DROP FUNCTION IF EXISTS locations_ensure_partitions(DATE, DATE);
+ALTER SEQUENCE locations_id_seq OWNED BY locations.id;
+
+CREATE INDEX idx_locations_user_captured_at ON locations (user_id, captured_at);
+CREATE INDEX idx_locations_user_effective_at ON locations (user_id, (COALESCE(captured_at, created_at)), id);
+CREATE INDEX idx_locations_request_id ON locations (request_id);
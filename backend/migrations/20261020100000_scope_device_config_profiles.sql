@@ -0,0 +1,25 @@
+-- +goose Up
+-- Профили и desired-конфигурации принадлежат организации: профили существующей
+-- инсталляции — организации по умолчанию, desired — организации пользователя.
+ALTER TABLE device_config_profiles ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id);
+ALTER TABLE device_config_profiles DROP CONSTRAINT IF EXISTS device_config_profiles_name_key;
+DROP INDEX IF EXISTS idx_device_config_profiles_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_config_profiles_org_name ON device_config_profiles (organization_id, name);
+
+ALTER TABLE device_desired_configs ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1;
+UPDATE device_desired_configs d SET organization_id = u.organization_id FROM users u WHERE u.id = d.user_id;
+CREATE INDEX IF NOT EXISTS idx_device_desired_configs_organization_id ON device_desired_configs (organization_id);
+
+-- Профиль чужой организации не наследуется.
+UPDATE device_desired_configs d SET profile_id = NULL
+FROM device_config_profiles p
+WHERE p.id = d.profile_id AND p.organization_id <> d.organization_id;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_device_desired_configs_organization_id;
+ALTER TABLE device_desired_configs DROP COLUMN IF EXISTS organization_id;
+
+DROP INDEX IF EXISTS idx_device_config_profiles_org_name;
+DELETE FROM device_config_profiles WHERE organization_id <> 1;
+ALTER TABLE device_config_profiles DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE device_config_profiles ADD CONSTRAINT device_config_profiles_name_key UNIQUE (name);
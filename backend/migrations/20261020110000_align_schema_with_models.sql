@@ -0,0 +1,19 @@
+-- +goose Up
+-- Схему ведут только миграции (AutoMigrate при старте больше не выполняется): сводим
+-- к одному виду базы, поднятые миграциями, и базы, которые успел изменить AutoMigrate.
+ALTER TABLE users ALTER COLUMN name TYPE TEXT;
+ALTER TABLE users ALTER COLUMN qr_code TYPE TEXT;
+ALTER TABLE users ALTER COLUMN qr_code DROP NOT NULL;
+ALTER TABLE checkpoints ALTER COLUMN name TYPE TEXT;
+
+-- Общий список отчётов (без фильтра по пользователю) сортируется по времени создания.
+CREATE INDEX IF NOT EXISTS idx_device_reports_created_at ON device_reports (created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_device_reports_created_at;
+
+ALTER TABLE checkpoints ALTER COLUMN name TYPE VARCHAR(255);
+UPDATE users SET qr_code = '' WHERE qr_code IS NULL;
+ALTER TABLE users ALTER COLUMN qr_code SET NOT NULL;
+ALTER TABLE users ALTER COLUMN qr_code TYPE VARCHAR(255);
+ALTER TABLE users ALTER COLUMN name TYPE VARCHAR(255);
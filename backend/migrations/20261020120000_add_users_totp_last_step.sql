@@ -0,0 +1,6 @@
+-- +goose Up
+-- Последний принятый шаг TOTP: код того же или более раннего шага повторно не принимается.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
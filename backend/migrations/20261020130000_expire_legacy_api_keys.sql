@@ -0,0 +1,10 @@
+-- +goose Up
+-- Ключи, перенесённые из users.api_key, не имеют отпечатка и проверяются перебором
+-- bcrypt. Неиспользованным даётся 30 дней: ключ, прошедший проверку за это время,
+-- получает отпечаток и срок снимается; остальные истекают — нужен новый QR.
+UPDATE api_keys SET expires_at = CURRENT_TIMESTAMP + INTERVAL '30 days'
+WHERE fingerprint = '' AND revoked_at IS NULL AND expires_at IS NULL AND replaced_by_id IS NULL;
+
+-- +goose Down
+UPDATE api_keys SET expires_at = NULL
+WHERE fingerprint = '' AND revoked_at IS NULL AND replaced_by_id IS NULL;
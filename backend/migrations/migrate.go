@@ -0,0 +1,349 @@
+// Package migrations — версионные SQL-миграции схемы БД, встроенные в бинарник.
+//
+// Файлы `<версия>_<имя>.sql` в формате goose: разделы `-- +goose Up` и `-- +goose Down`,
+// `-- +goose NO TRANSACTION` для операций вне транзакции (CREATE INDEX CONCURRENTLY).
+// Применённые версии хранятся в schema_migrations; при первом запуске на базе, которую
+// вёл goose, история переносится из goose_db_version. Параллельные запуски (несколько
+// реплик, cmd/migrate во время старта) упорядочиваются advisory-блокировкой.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockKey — ключ pg_advisory_lock на время миграций.
+const lockKey int64 = 0x6c6f6361746f72
+
+var ErrNoMigration = errors.New("no migration to roll back")
+
+// Migration — один файл миграции.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// NoTx — выполнять вне транзакции (`-- +goose NO TRANSACTION`).
+	NoTx bool
+}
+
+// Status — миграция и время её применения (nil — не применена).
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load читает встроенные миграции по возрастанию версии.
+func Load() ([]Migration, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Migration, 0, len(names))
+	seen := make(map[int64]string, len(names))
+	for _, name := range names {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		m, err := parse(name, string(content))
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[m.Version]; ok {
+			return nil, fmt.Errorf("%s: версия %d уже занята %s", name, m.Version, prev)
+		}
+		seen[m.Version] = name
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// parse разбирает файл `<версия>_<имя>.sql` в формате goose.
+func parse(filename, content string) (Migration, error) {
+	base, _, ok := strings.Cut(filename, "_")
+	version, err := strconv.ParseInt(base, 10, 64)
+	if !ok || err != nil || version <= 0 {
+		return Migration{}, fmt.Errorf("%s: имя файла должно начинаться с версии: <версия>_<имя>.sql", filename)
+	}
+	name := strings.TrimPrefix(filename, base+"_")
+	for strings.HasSuffix(name, ".sql") {
+		name = strings.TrimSuffix(name, ".sql")
+	}
+
+	m := Migration{Version: version, Name: name}
+	var up, down strings.Builder
+	var section *strings.Builder
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if directive, ok := strings.CutPrefix(trimmed, "-- +goose "); ok {
+			switch strings.ToUpper(strings.TrimSpace(directive)) {
+			case "UP":
+				section = &up
+				continue
+			case "DOWN":
+				section = &down
+				continue
+			case "NO TRANSACTION":
+				m.NoTx = true
+				continue
+			}
+		}
+		if section != nil {
+			section.WriteString(line)
+		}
+	}
+	m.Up, m.Down = strings.TrimSpace(up.String()), strings.TrimSpace(down.String())
+	if m.Up == "" {
+		return Migration{}, fmt.Errorf("%s: нет раздела -- +goose Up", filename)
+	}
+	return m, nil
+}
+
+// Runner применяет и откатывает миграции.
+type Runner struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+// New — Runner со встроенными миграциями.
+func New(db *sql.DB) (*Runner, error) {
+	list, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{DB: db, Migrations: list}, nil
+}
+
+// Up применяет все неприменённые миграции по порядку и возвращает их число.
+func (r *Runner) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := r.locked(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.Migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			started := time.Now()
+			if err := apply(ctx, conn, m, m.Up, func(exec execer) error {
+				_, err := exec.ExecContext(ctx,
+					"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)", m.Version, m.Name, time.Now().UTC())
+				return err
+			}); err != nil {
+				return fmt.Errorf("миграция %d_%s: %w", m.Version, m.Name, err)
+			}
+			slog.Info("Миграция применена", "version", m.Version, "name", m.Name, "duration", time.Since(started).Round(time.Millisecond))
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает последнюю применённую миграцию.
+func (r *Runner) Down(ctx context.Context) (*Migration, error) {
+	var rolledBack *Migration
+	err := r.locked(ctx, func(conn *sql.Conn) error {
+		var version int64
+		err := conn.QueryRowContext(ctx, "SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1").Scan(&version)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoMigration
+		}
+		if err != nil {
+			return err
+		}
+		idx := sort.Search(len(r.Migrations), func(i int) bool { return r.Migrations[i].Version >= version })
+		if idx == len(r.Migrations) || r.Migrations[idx].Version != version {
+			return fmt.Errorf("применённой версии %d нет среди файлов миграций", version)
+		}
+		m := r.Migrations[idx]
+		if err := apply(ctx, conn, m, m.Down, func(exec execer) error {
+			_, err := exec.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", m.Version)
+			return err
+		}); err != nil {
+			return fmt.Errorf("откат %d_%s: %w", m.Version, m.Name, err)
+		}
+		slog.Info("Миграция откачена", "version", m.Version, "name", m.Name)
+		rolledBack = &m
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status — все миграции с отметкой о применении.
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	var out []Status
+	err := r.locked(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.Migrations {
+			s := Status{Migration: m}
+			if at, ok := done[m.Version]; ok {
+				s.AppliedAt = &at
+			}
+			out = append(out, s)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Pending — неприменённые миграции. В отличие от Status не берёт блокировку и не
+// создаёт schema_migrations: подходит для проверки готовности, пока другая реплика
+// применяет миграции.
+func (r *Runner) Pending(ctx context.Context) ([]Migration, error) {
+	var table sql.NullString
+	if err := r.DB.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations')::text").Scan(&table); err != nil {
+		return nil, err
+	}
+	done := map[int64]time.Time{}
+	if table.Valid {
+		var err error
+		if done, err = appliedVersions(ctx, r.DB); err != nil {
+			return nil, err
+		}
+	}
+	var pending []Migration
+	for _, m := range r.Migrations {
+		if _, ok := done[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// locked выполняет fn на выделенном соединении под advisory-блокировкой, предварительно
+// создав schema_migrations.
+func (r *Runner) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := r.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("блокировка миграций: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			slog.Error("Не удалось снять блокировку миграций", "error", err)
+		}
+	}()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL DEFAULT '',
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return err
+	}
+
+	// База, которую раньше вёл goose: переносим его историю один раз.
+	var count int
+	if err := conn.QueryRowContext(ctx, "SELECT count(*) FROM schema_migrations").Scan(&count); err != nil || count > 0 {
+		return err
+	}
+	var gooseTable sql.NullString
+	if err := conn.QueryRowContext(ctx, "SELECT to_regclass('goose_db_version')::text").Scan(&gooseTable); err != nil || !gooseTable.Valid {
+		return err
+	}
+	res, err := conn.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version, applied_at)
+		SELECT version_id, tstamp FROM (
+			SELECT DISTINCT ON (version_id) version_id, is_applied, tstamp
+			FROM goose_db_version
+			WHERE version_id > 0
+			ORDER BY version_id, id DESC
+		) latest
+		WHERE is_applied`)
+	if err != nil {
+		return fmt.Errorf("перенос истории goose: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		slog.Info("Перенесена история goose", "versions", n)
+	}
+	return nil
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func appliedVersions(ctx context.Context, conn querier) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	done := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		done[version] = at
+	}
+	return done, rows.Err()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// apply выполняет SQL миграции и запись в schema_migrations — в одной транзакции,
+// если миграция не помечена NO TRANSACTION.
+func apply(ctx context.Context, conn *sql.Conn, m Migration, script string, record func(execer) error) error {
+	if m.NoTx {
+		if script != "" {
+			if _, err := conn.ExecContext(ctx, script); err != nil {
+				return err
+			}
+		}
+		return record(conn)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if script != "" {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,111 @@
+//go:build integration
+
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// openScratchSchema connects to the test database (same DB_* variables as the
+// integration harness) with search_path set to a fresh schema that is dropped after
+// the test, so migrations run from scratch without touching harness tables.
+func openScratchSchema(t *testing.T) *sql.DB {
+	t.Helper()
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		envOr("DB_HOST", "127.0.0.1"), envOr("DB_PORT", "5433"), envOr("DB_USER", "locator_user"),
+		envOr("DB_PASSWORD", "change_me"), envOr("DB_NAME", "locator_db_test"), envOr("DB_SSLMODE", "disable"))
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Skipf("postgres unavailable (%v)", err)
+	}
+	adminDB, err := admin.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := adminDB.Ping(); err != nil {
+		t.Skipf("postgres unavailable (%v)", err)
+	}
+	if err := admin.Exec(fmt.Sprintf("CREATE SCHEMA %q", schema)).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(fmt.Sprintf("DROP SCHEMA %q CASCADE", schema))
+		adminDB.Close()
+	})
+
+	scoped, err := gorm.Open(postgres.Open(dsn+" search_path="+schema), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := scoped.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRunner_upDownAgainstPostgres(t *testing.T) {
+	db := openScratchSchema(t)
+	ctx := context.Background()
+	r, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := r.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(r.Migrations) {
+		t.Fatalf("applied %d of %d migrations", applied, len(r.Migrations))
+	}
+	var kind string
+	if err := db.QueryRowContext(ctx, "SELECT relkind::text FROM pg_class WHERE oid = to_regclass('locations')").Scan(&kind); err != nil {
+		t.Fatal(err)
+	}
+	if kind != "p" {
+		t.Fatalf("locations relkind=%q, want partitioned", kind)
+	}
+	if again, err := r.Up(ctx); err != nil || again != 0 {
+		t.Fatalf("second up: applied=%d err=%v", again, err)
+	}
+
+	last := r.Migrations[len(r.Migrations)-1]
+	rolledBack, err := r.Down(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rolledBack.Version != last.Version {
+		t.Fatalf("rolled back %d, want the last migration %d", rolledBack.Version, last.Version)
+	}
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Version != last.Version {
+		t.Fatalf("pending after down: %+v", pending)
+	}
+
+	if applied, err := r.Up(ctx); err != nil || applied != 1 {
+		t.Fatalf("re-apply after down: applied=%d err=%v", applied, err)
+	}
+}
@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad_embeddedMigrationsOrderedAndParsed(t *testing.T) {
+	list, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for i, m := range list {
+		if i > 0 && m.Version <= list[i-1].Version {
+			t.Fatalf("migrations not ordered: %d after %d", m.Version, list[i-1].Version)
+		}
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("%d_%s: empty up or down section", m.Version, m.Name)
+		}
+		if strings.HasSuffix(m.Name, ".sql") {
+			t.Errorf("%d: name %q keeps extension", m.Version, m.Name)
+		}
+	}
+
+	var partition *Migration
+	for i := range list {
+		if list[i].Name == "partition_locations" {
+			partition = &list[i]
+		}
+	}
+	if partition == nil || !strings.Contains(partition.Up, "PARTITION BY RANGE ((COALESCE(captured_at, created_at)))") {
+		t.Fatal("partition_locations migration not found or does not partition locations")
+	}
+	// Тело функции между StatementBegin/End остаётся в разделе целиком.
+	if !strings.Contains(partition.Up, "$$ LANGUAGE plpgsql;") || strings.Contains(partition.Up, "DROP FUNCTION") {
+		t.Fatal("up section of partition_locations parsed incorrectly")
+	}
+}
+
+func TestParse_directives(t *testing.T) {
+	m, err := parse("20260101000000_add_index.sql", `-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY idx_a ON a (b);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY idx_a;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != 20260101000000 || m.Name != "add_index" || !m.NoTx {
+		t.Fatalf("parsed %+v", m)
+	}
+	if m.Up != "CREATE INDEX CONCURRENTLY idx_a ON a (b);" || m.Down != "DROP INDEX CONCURRENTLY idx_a;" {
+		t.Fatalf("up=%q down=%q", m.Up, m.Down)
+	}
+
+	for name, content := range map[string]string{
+		"create_table.sql":         "-- +goose Up\nSELECT 1;",
+		"20260101000000_empty.sql": "-- +goose Down\nSELECT 1;",
+	} {
+		if _, err := parse(name, content); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoad_duplicateVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20260101000000_a.sql": {Data: []byte("-- +goose Up\nSELECT 1;")},
+		"20260101000000_b.sql": {Data: []byte("-- +goose Up\nSELECT 2;")},
+	}
+	if _, err := load(fsys); err == nil {
+		t.Fatal("duplicate version must be rejected")
+	}
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+const (
+	AlertRuleTypeNoLocation             = "no_location"
+	AlertRuleTypeReportIssue            = "report_issue"
+	AlertRuleTypeBatteryLow             = "battery_low"
+	AlertRuleTypeAppVersionBelow        = "app_version_below"
+	AlertRuleTypeLocationRequestExpired = "location_request_expired"
+)
+
+const (
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+const (
+	AlertStatusOpen         = "open"
+	AlertStatusAcknowledged = "acknowledged"
+	AlertStatusResolved     = "resolved"
+)
+
+// AlertRule — правило алертинга. UserID = nil — правило для всех устройств (не админов)
+// своей организации. Параметры, не относящиеся к типу правила, игнорируются.
+type AlertRule struct {
+	ID             int    `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrganizationID int    `gorm:"not null;default:1;index" json:"organization_id"`
+	Name           string `gorm:"size:100;not null" json:"name"`
+	Type           string `gorm:"size:40;not null;index" json:"type"`
+	Severity       string `gorm:"size:20;not null;default:warning" json:"severity"`
+	Enabled        bool   `gorm:"not null;default:true" json:"enabled"`
+	UserID         *int   `gorm:"index" json:"user_id,omitempty"`
+
+	// no_location, location_request_expired: окно в минутах.
+	ThresholdMinutes *int `json:"threshold_minutes,omitempty"`
+	// report_issue: код проблемы из отчёта (background_stopped, post_stale, …).
+	Issue string `gorm:"size:100" json:"issue,omitempty"`
+	// battery_low: порог заряда, %.
+	BatteryPercent *float64 `json:"battery_percent,omitempty"`
+	// app_version_below: минимальная допустимая версия приложения.
+	MinAppVersion string `gorm:"size:50" json:"min_app_version,omitempty"`
+
+	// Рабочее время (в поясе организации): правило проверяется только в [start, end) часов.
+	WorkStartHour *int `json:"work_start_hour,omitempty"`
+	WorkEndHour   *int `json:"work_end_hour,omitempty"`
+	WorkDaysOnly  bool `gorm:"not null;default:false" json:"work_days_only"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Alert — сработавший алерт. Пока алерт не resolved, повторные срабатывания
+// с тем же DedupKey (правило + пользователь) только продлевают LastSeenAt.
+type Alert struct {
+	ID             int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	RuleID         int        `gorm:"not null;index" json:"rule_id"`
+	UserID         int        `gorm:"not null;index" json:"user_id"`
+	Type           string     `gorm:"size:40;not null" json:"type"`
+	Severity       string     `gorm:"size:20;not null" json:"severity"`
+	Status         string     `gorm:"size:20;not null;index" json:"status"`
+	DedupKey       string     `gorm:"size:100;not null;index" json:"dedup_key"`
+	Message        string     `gorm:"type:text" json:"message"`
+	FirstSeenAt    time.Time  `gorm:"not null" json:"first_seen_at"`
+	LastSeenAt     time.Time  `gorm:"not null" json:"last_seen_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *int       `json:"acknowledged_by,omitempty"`
+	ResolvedAt     *time.Time `gorm:"index" json:"resolved_at,omitempty"`
+	// ResolvedBy = nil при автоматическом закрытии (условие ушло).
+	ResolvedBy *int      `json:"resolved_by,omitempty"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// IsActive — алерт ещё не закрыт (open или acknowledged).
+func (a *Alert) IsActive() bool {
+	return a.Status != AlertStatusResolved
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// APIKey — API-ключ пользователя (устройства). Хранится только bcrypt-хеш;
+// Fingerprint — начало SHA-256 ключа: по нему ключ ищется без перебора всех
+// хешей и опознаётся в списке, не раскрывая сам ключ.
+// Primary — ключ, зашитый в QR-код пользователя. При перегенерации QR прежний
+// основной ключ получает ReplacedByID и действует, пока устройство не воспользуется
+// новым ключом или не наступит ExpiresAt (период передачи).
+type APIKey struct {
+	ID           int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       int        `gorm:"not null;index" json:"user_id"`
+	Label        string     `gorm:"size:100;not null;default:''" json:"label"`
+	Fingerprint  string     `gorm:"size:16;not null;default:'';index" json:"fingerprint"`
+	KeyHash      string     `gorm:"size:100;not null" json:"-"`
+	Primary      bool       `gorm:"column:is_primary;not null;default:false" json:"primary"`
+	ReplacedByID *int       `gorm:"index" json:"replaced_by_id,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP   string     `gorm:"size:64;not null;default:''" json:"last_used_ip"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Active сообщает, принимается ли ключ в момент now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
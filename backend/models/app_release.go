@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+const (
+	AppReleaseChannelStable = "stable"
+	AppReleaseChannelBeta   = "beta"
+	// AppReleaseChannelGroupPrefix — канал группы устройств: "group:<имя>".
+	AppReleaseChannelGroupPrefix = "group:"
+)
+
+const (
+	AppReleaseStatusActive   = "active"
+	AppReleaseStatusPaused   = "paused"
+	AppReleaseStatusArchived = "archived"
+)
+
+// AppRelease — опубликованная сборка APK в канале обновлений (история версий).
+// RolloutPercent — доля устройств канала, получающих релиз (0–100).
+// При доле ошибок app_update выше MaxFailurePercent (не раньше MinFailureSamples
+// завершённых команд) релиз автоматически ставится на паузу.
+type AppRelease struct {
+	ID                int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrganizationID    int        `gorm:"not null;default:1;index;uniqueIndex:idx_app_releases_channel_version" json:"organization_id"`
+	Channel           string     `gorm:"size:64;not null;index;uniqueIndex:idx_app_releases_channel_version" json:"channel"`
+	VersionName       string     `gorm:"size:50;not null" json:"version_name"`
+	VersionCode       int64      `gorm:"not null;uniqueIndex:idx_app_releases_channel_version" json:"version_code"`
+	PackageName       string     `gorm:"size:255" json:"package_name,omitempty"`
+	Filename          string     `gorm:"size:255;not null" json:"filename"`
+	SHA256            string     `gorm:"size:64;not null" json:"sha256"`
+	Changelog         string     `gorm:"type:text" json:"changelog,omitempty"`
+	Force             bool       `gorm:"not null;default:false" json:"force"`
+	RolloutPercent    int        `gorm:"not null" json:"rollout_percent"`
+	Status            string     `gorm:"size:20;not null;index" json:"status"`
+	PauseReason       string     `gorm:"type:text" json:"pause_reason,omitempty"`
+	PausedAt          *time.Time `json:"paused_at,omitempty"`
+	MaxFailurePercent int        `gorm:"not null" json:"max_failure_percent"`
+	MinFailureSamples int        `gorm:"not null" json:"min_failure_samples"`
+	CreatedAt         time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// AppReleaseChannel — настройки канала организации; MinVersion — минимальная
+// поддерживаемая версия (устройства ниже неё получают app_update принудительно).
+type AppReleaseChannel struct {
+	OrganizationID int       `gorm:"primaryKey;autoIncrement:false" json:"organization_id"`
+	Name           string    `gorm:"primaryKey;size:64" json:"name"`
+	MinVersion     string    `gorm:"size:50" json:"min_version,omitempty"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// AppReleaseMember — канал обновлений пользователя; без записи — stable.
+type AppReleaseMember struct {
+	UserID    int       `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Channel   string    `gorm:"size:64;not null;index" json:"channel"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Результат действия в журнале аудита.
+const (
+	AuditResultSuccess = "success"
+	AuditResultDenied  = "denied"
+	AuditResultError   = "error"
+)
+
+// AuditEntry — запись журнала аудита административных действий. Журнал только
+// дополняется: изменение и удаление записей запрещены триггером в БД.
+// Action — метод и шаблон маршрута ("PUT /api/admin/users/:id/access"),
+// Target — объект действия, Params — параметры запроса (path, query, body)
+// с заменёнными секретами (пароли, ключи, PIN, токены).
+type AuditEntry struct {
+	ID             int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	At             time.Time      `gorm:"not null;index" json:"at"`
+	OrganizationID int            `gorm:"not null;index" json:"organization_id"`
+	ActorID        *int           `gorm:"index" json:"actor_id,omitempty"`
+	ActorName      string         `gorm:"size:100;not null;default:''" json:"actor_name"`
+	ActorRole      string         `gorm:"size:20;not null;default:''" json:"actor_role"`
+	SessionID      string         `gorm:"size:36;not null;default:''" json:"session_id,omitempty"`
+	Action         string         `gorm:"size:150;not null;index" json:"action"`
+	TargetType     string         `gorm:"size:50;not null;default:''" json:"target_type,omitempty"`
+	TargetID       string         `gorm:"size:64;not null;default:'';index" json:"target_id,omitempty"`
+	Params         datatypes.JSON `gorm:"type:jsonb" json:"params,omitempty"`
+	Status         int            `gorm:"not null" json:"status"`
+	Result         string         `gorm:"size:16;not null" json:"result"`
+	Error          string         `gorm:"size:500;not null;default:''" json:"error,omitempty"`
+	IP             string         `gorm:"size:64;not null;default:''" json:"ip"`
+}
+
+// TableName — журнал хранится в таблице audit_log.
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
@@ -20,6 +20,9 @@ type Checkpoint struct {
 	// Radius — радиус зоны (в метрах), в пределах которого считается, что пользователь находится на чекпоинте.
 	Radius float64 `gorm:"not null" json:"radius"`
 
+	// OrganizationID — организация-владелец; визиты фиксируются только для её пользователей.
+	OrganizationID int `gorm:"not null;default:1;index" json:"organization_id"`
+
 	// CreatedAt — время создания записи.
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 
@@ -22,15 +22,17 @@ const (
 )
 
 // DeviceCommand — очередь удалённых команд для устройства.
+// OrganizationID — организация пользователя; заполняется DAO при создании.
 type DeviceCommand struct {
-	ID          string         `gorm:"primaryKey;size:36" json:"id"`
-	UserID      int            `gorm:"not null;index:idx_device_cmd_user_status" json:"user_id"`
-	Type        string         `gorm:"not null;size:50" json:"type"`
-	Payload     datatypes.JSON `gorm:"type:jsonb" json:"payload,omitempty"`
-	Status      string         `gorm:"not null;size:20;index:idx_device_cmd_user_status" json:"status"`
-	AckStatus   string         `gorm:"size:50" json:"ack_status,omitempty"`
-	AckMessage  string         `gorm:"type:text" json:"ack_message,omitempty"`
-	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	DeliveredAt *time.Time     `json:"delivered_at,omitempty"`
-	AckedAt     *time.Time     `json:"acked_at,omitempty"`
+	ID             string         `gorm:"primaryKey;size:36" json:"id"`
+	UserID         int            `gorm:"not null;index:idx_device_cmd_user_status" json:"user_id"`
+	OrganizationID int            `gorm:"not null;default:1;index" json:"organization_id"`
+	Type           string         `gorm:"not null;size:50" json:"type"`
+	Payload        datatypes.JSON `gorm:"type:jsonb" json:"payload,omitempty"`
+	Status         string         `gorm:"not null;size:20;index:idx_device_cmd_user_status" json:"status"`
+	AckStatus      string         `gorm:"size:50" json:"ack_status,omitempty"`
+	AckMessage     string         `gorm:"type:text" json:"ack_message,omitempty"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	AckedAt        *time.Time     `json:"acked_at,omitempty"`
 }
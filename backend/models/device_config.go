@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// DeviceConfigFields — управляемые поля конфигурации телефона; nil — не задано (наследуется из профиля).
+type DeviceConfigFields struct {
+	LocationIntervalSeconds     *int64  `json:"location_interval_seconds,omitempty"`
+	PollIntervalSeconds         *int64  `json:"poll_interval_seconds,omitempty"`
+	HealthReportIntervalSeconds *int64  `json:"health_report_interval_seconds,omitempty"`
+	TrackingPaused              *bool   `json:"tracking_paused,omitempty"`
+	HiddenFromLauncher          *bool   `json:"hidden_from_launcher,omitempty"`
+	APIBaseURL                  *string `gorm:"size:255" json:"api_base_url,omitempty"`
+	AdminPin                    *string `gorm:"size:12" json:"admin_pin,omitempty"`
+}
+
+// DeviceConfigProfile — групповой профиль конфигурации, от которого наследуются пользователи.
+// Профиль принадлежит организации: имя уникально в её пределах.
+type DeviceConfigProfile struct {
+	ID                 int    `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrganizationID     int    `gorm:"not null;default:1;uniqueIndex:idx_device_config_profiles_org_name" json:"organization_id"`
+	Name               string `gorm:"not null;size:100;uniqueIndex:idx_device_config_profiles_org_name" json:"name"`
+	DeviceConfigFields `gorm:"embedded"`
+	CreatedAt          time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// DeviceDesiredConfig — желаемая конфигурация телефона пользователя (поверх профиля).
+// OrganizationID — организация пользователя; профиль берётся только из неё.
+type DeviceDesiredConfig struct {
+	UserID             int  `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	OrganizationID     int  `gorm:"not null;default:1;index" json:"organization_id"`
+	ProfileID          *int `gorm:"index" json:"profile_id,omitempty"`
+	DeviceConfigFields `gorm:"embedded"`
+	// LastPushedHash/LastPushedAt — последний автоматический config_update по дрейфу (антиспам).
+	LastPushedHash string     `gorm:"size:64" json:"-"`
+	LastPushedAt   *time.Time `json:"last_pushed_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/datatypes"
@@ -16,3 +17,145 @@ type DeviceReport struct {
 	Platform   string         `gorm:"size:20" json:"platform,omitempty"`
 	CreatedAt  time.Time      `gorm:"autoCreateTime;index" json:"created_at"`
 }
+
+// DeviceReportIssues — коды проблем из отчёта. Нестроковые элементы массива
+// (старые сборки присылали объекты) пропускаются, а не ломают разбор.
+type DeviceReportIssues []string
+
+func (issues *DeviceReportIssues) UnmarshalJSON(data []byte) error {
+	var generic []interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	out := make(DeviceReportIssues, 0, len(generic))
+	for _, item := range generic {
+		switch v := item.(type) {
+		case string:
+			out = append(out, v)
+		case map[string]interface{}:
+			if code, ok := v["code"].(string); ok && code != "" {
+				out = append(out, code)
+			}
+		}
+	}
+	*issues = out
+	return nil
+}
+
+// ParseDeviceReportIssues разбирает колонку issues (jsonb) в список кодов.
+func ParseDeviceReportIssues(raw []byte) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var issues DeviceReportIssues
+	if err := json.Unmarshal(raw, &issues); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// DeviceReportBattery — блок battery отчёта.
+type DeviceReportBattery struct {
+	LevelPercent  *float64 `json:"level_percent,omitempty"`
+	Charging      *bool    `json:"charging,omitempty"`
+	PowerSaveMode *bool    `json:"power_save_mode,omitempty"`
+}
+
+// DeviceReportLocation — состояние геолокации и фоновой службы.
+type DeviceReportLocation struct {
+	Permission               string  `json:"permission,omitempty"` // always | while_in_use | denied
+	SystemEnabled            *bool   `json:"system_enabled,omitempty"`
+	ForegroundServiceRunning *bool   `json:"foreground_service_running,omitempty"`
+	LastPostAt               *string `json:"last_post_at,omitempty"`
+}
+
+// DeviceReportStorage — свободное место (офлайн-очередь и OTA).
+type DeviceReportStorage struct {
+	FreeBytes  *int64 `json:"free_bytes,omitempty"`
+	TotalBytes *int64 `json:"total_bytes,omitempty"`
+}
+
+// DeviceReportNetwork — тип сети на момент отчёта (wifi, cellular, none).
+type DeviceReportNetwork struct {
+	Type string `json:"type,omitempty"`
+}
+
+// DeviceReportPayload — типизированная схема отчёта телефона.
+// Известные поля разбираются в структуру, всё остальное сохраняется в Extra
+// и возвращается при сериализации без потерь.
+type DeviceReportPayload struct {
+	AppVersion           string                `json:"app_version,omitempty"`
+	Platform             string                `json:"platform,omitempty"`
+	Issues               DeviceReportIssues    `json:"issues,omitempty"`
+	Battery              *DeviceReportBattery  `json:"battery,omitempty"`
+	Location             *DeviceReportLocation `json:"location,omitempty"`
+	BackgroundRestricted *bool                 `json:"background_restricted,omitempty"`
+	Storage              *DeviceReportStorage  `json:"storage,omitempty"`
+	Network              *DeviceReportNetwork  `json:"network,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// deviceReportKnownKeys — ключи верхнего уровня, разбираемые в поля DeviceReportPayload.
+var deviceReportKnownKeys = map[string]struct{}{
+	"app_version": {}, "platform": {}, "issues": {}, "battery": {},
+	"location": {}, "background_restricted": {}, "storage": {}, "network": {},
+}
+
+type deviceReportPayloadAlias DeviceReportPayload
+
+func (p *DeviceReportPayload) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var known deviceReportPayloadAlias
+	if err := json.Unmarshal(data, &known); err != nil {
+		return err
+	}
+	*p = DeviceReportPayload(known)
+	for k, v := range raw {
+		if _, ok := deviceReportKnownKeys[k]; ok {
+			continue
+		}
+		if p.Extra == nil {
+			p.Extra = make(map[string]json.RawMessage)
+		}
+		p.Extra[k] = v
+	}
+	return nil
+}
+
+func (p DeviceReportPayload) MarshalJSON() ([]byte, error) {
+	knownJSON, err := json.Marshal(deviceReportPayloadAlias(p))
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Extra) == 0 {
+		return knownJSON, nil
+	}
+	out := make(map[string]json.RawMessage, len(p.Extra)+len(deviceReportKnownKeys))
+	for k, v := range p.Extra {
+		out[k] = v
+	}
+	var known map[string]json.RawMessage
+	if err := json.Unmarshal(knownJSON, &known); err != nil {
+		return nil, err
+	}
+	for k, v := range known {
+		out[k] = v
+	}
+	return json.Marshal(out)
+}
+
+// Payload разбирает сохранённый JSON отчёта в типизированную схему.
+func (r *DeviceReport) Payload() (*DeviceReportPayload, error) {
+	var p DeviceReportPayload
+	if len(r.Report) == 0 {
+		return &p, nil
+	}
+	if err := json.Unmarshal(r.Report, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDeviceReportPayload_preservesUnknownFields(t *testing.T) {
+	raw := `{"app_version":"1.4.0","battery":{"level_percent":42,"charging":false},` +
+		`"network":{"type":"wifi"},"issues":["post_stale",{"code":"background_stopped"},7],` +
+		`"poll":{"last_poll_status":204},"custom_flag":true}`
+
+	var p DeviceReportPayload
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.AppVersion != "1.4.0" || p.Battery == nil || *p.Battery.LevelPercent != 42 || p.Network.Type != "wifi" {
+		t.Fatalf("known fields not parsed: %+v", p)
+	}
+	if len(p.Issues) != 2 || p.Issues[1] != "background_stopped" {
+		t.Fatalf("issues=%v", p.Issues)
+	}
+	if _, ok := p.Extra["poll"]; !ok {
+		t.Fatal("unknown field poll lost")
+	}
+
+	out, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back map[string]interface{}
+	if err := json.Unmarshal(out, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back["custom_flag"] != true || back["app_version"] != "1.4.0" {
+		t.Fatalf("round trip lost fields: %s", out)
+	}
+}
+
+func TestParseDeviceReportIssues_empty(t *testing.T) {
+	for _, raw := range []string{"", "null"} {
+		issues, err := ParseDeviceReportIssues([]byte(raw))
+		if err != nil || issues != nil {
+			t.Fatalf("%q: issues=%v err=%v", raw, issues, err)
+		}
+	}
+}
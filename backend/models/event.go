@@ -11,4 +11,8 @@ type LocationEvent struct {
 	Longitude    float64   `json:"longitude"`
 	OccurredAt   time.Time `json:"occurred_at"`
 	Source       string    `json:"source,omitempty"`
+	// OrganizationID — организация пользователя: сверка только с её чекпоинтами (0 — DefaultOrganizationID).
+	OrganizationID int `json:"organization_id,omitempty"`
+	// LocationID — сохранённая точка, породившая событие (0 — событие не из PostLocation).
+	LocationID int `json:"location_id,omitempty"`
 }
@@ -25,6 +25,14 @@ type Location struct {
 	// CapturedAt — момент фиксации GPS на устройстве (офлайн-очередь); если пусто — created_at.
 	CapturedAt *time.Time `json:"captured_at,omitempty"`
 
+	// Masked — точка пришла вне окна отслеживания (см. TrackingSchedule): координаты
+	// огрублены, в трек, визиты и отчёты она не попадает — только подтверждает связь.
+	Masked bool `gorm:"not null;default:false" json:"masked,omitempty"`
+
+	// Downsampled — точка осталась после прореживания по сроку хранения (RetentionPolicy):
+	// соседние точки трека могли быть удалены.
+	Downsampled bool `gorm:"not null;default:false" json:"downsampled,omitempty"`
+
 	// CreatedAt — время приёма записи сервером.
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 
@@ -96,3 +104,16 @@ func (loc *Location) NormalizeIngressCapturedAt() {
 	t := loc.CreatedAt.UTC()
 	loc.CapturedAt = &t
 }
+
+// In переводит времена записи в пояс tz: в JSON они уходят со смещением этого пояса.
+func (loc *Location) In(tz *time.Location) {
+	if loc == nil || tz == nil {
+		return
+	}
+	if loc.CapturedAt != nil {
+		t := loc.CapturedAt.In(tz)
+		loc.CapturedAt = &t
+	}
+	loc.CreatedAt = loc.CreatedAt.In(tz)
+	loc.UpdatedAt = loc.UpdatedAt.In(tz)
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	NotificationChannelEmail    = "email"
+	NotificationChannelTelegram = "telegram"
+	NotificationChannelWebhook  = "webhook"
+)
+
+const (
+	NotificationEventAlertOpened   = "alert.opened"
+	NotificationEventAlertResolved = "alert.resolved"
+	NotificationEventVisitStarted  = "visit.started"
+	NotificationEventVisitEnded    = "visit.ended"
+	NotificationEventTest          = "test"
+)
+
+const (
+	NotificationDeliverySent        = "sent"
+	NotificationDeliveryFailed      = "failed"
+	NotificationDeliveryQuietHours  = "quiet_hours"
+	NotificationDeliveryRateLimited = "rate_limited"
+)
+
+// NotificationSubscription — куда и о чём уведомлять пользователя.
+// Подписка админа получает события по всем устройствам (или только SubjectUserID),
+// подписка обычного пользователя — только события о нём самом.
+type NotificationSubscription struct {
+	ID      int    `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID  int    `gorm:"not null;index" json:"user_id"`
+	Channel string `gorm:"size:20;not null" json:"channel"`
+	// Target: email-адрес, chat_id Telegram или URL вебхука.
+	Target string `gorm:"size:500;not null" json:"target"`
+	// Events — список событий (alert.opened, visit.started, …); пусто — все.
+	Events        datatypes.JSON `gorm:"type:jsonb" json:"events,omitempty"`
+	SubjectUserID *int           `json:"subject_user_id,omitempty"`
+	// MinSeverity — warning или critical; пусто — любые (включая события визитов).
+	MinSeverity string `gorm:"size:20" json:"min_severity,omitempty"`
+	Locale      string `gorm:"size:5;not null;default:ru" json:"locale"`
+	// Тихие часы (в поясе владельца подписки) [start, end); critical-алерты доставляются всегда.
+	QuietStartHour *int      `json:"quiet_start_hour,omitempty"`
+	QuietEndHour   *int      `json:"quiet_end_hour,omitempty"`
+	Enabled        bool      `gorm:"not null;default:true" json:"enabled"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// NotificationDelivery — журнал попыток доставки (в т.ч. подавленных тихими часами и лимитом).
+type NotificationDelivery struct {
+	ID             int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubscriptionID int       `gorm:"not null;index" json:"subscription_id"`
+	UserID         int       `gorm:"not null;index" json:"user_id"`
+	Channel        string    `gorm:"size:20;not null" json:"channel"`
+	Event          string    `gorm:"size:40;not null" json:"event"`
+	SubjectUserID  int       `json:"subject_user_id"`
+	Status         string    `gorm:"size:20;not null" json:"status"`
+	Error          string    `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt      time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
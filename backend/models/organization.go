@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// DefaultOrganizationID — организация-владелец инсталляции (создаётся миграцией).
+// Записи без явной организации относятся к ней; её super_admin управляет остальными организациями.
+const DefaultOrganizationID = 1
+
+// DefaultTimezone — часовой пояс организации, если он не задан (IANA).
+const DefaultTimezone = "Europe/Minsk"
+
+// Organization — клиентская организация (тенант). Пользователи, группы, чекпоинты,
+// релизы, правила алертов и команды устройств принадлежат ровно одной организации.
+// Timezone — пояс по умолчанию для пользователей организации (IANA, например Asia/Almaty).
+type Organization struct {
+	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Timezone  string    `gorm:"size:64;not null;default:'Europe/Minsk'" json:"timezone"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
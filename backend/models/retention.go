@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// RetentionPolicy — сроки хранения точек организации; без записи действуют значения
+// по умолчанию из окружения (RETENTION_*).
+//
+// Точки моложе RawDays суток хранятся как есть. Более старые прореживаются: на
+// стоянках остаются прибытие, отъезд и точка у центра стоянки, в движении — не
+// чаще одной в DownsampleMinutes минут. Старше PurgeDays суток точки удаляются,
+// а при Archive — сначала выгружаются в gzip-файл. Ноль в RawDays или PurgeDays
+// выключает соответствующий шаг. Визиты хранятся отдельно и не затрагиваются.
+type RetentionPolicy struct {
+	OrganizationID    int       `gorm:"primaryKey;autoIncrement:false" json:"organization_id"`
+	Enabled           bool      `gorm:"not null;default:true" json:"enabled"`
+	RawDays           int       `gorm:"not null;default:0" json:"raw_days"`
+	DownsampleMinutes int       `gorm:"not null;default:5" json:"downsample_minutes"`
+	PurgeDays         int       `gorm:"not null;default:0" json:"purge_days"`
+	Archive           bool      `gorm:"not null;default:false" json:"archive"`
+	UpdatedAt         time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Состояние прохода хранения.
+const (
+	RetentionRunRunning = "running"
+	RetentionRunDone    = "done"
+	RetentionRunFailed  = "failed"
+)
+
+// RetentionRun — проход хранения (фоновый или запущенный вручную) и его прогресс.
+// При DryRun точки только подсчитываются: Downsampled, Purged и Archived — сколько
+// было бы удалено и выгружено. ArchiveFiles — созданные файлы через перевод строки.
+type RetentionRun struct {
+	ID             int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrganizationID *int       `json:"organization_id,omitempty"`
+	DryRun         bool       `gorm:"not null;default:false" json:"dry_run"`
+	Status         string     `gorm:"size:10;not null;default:running" json:"status"`
+	UsersTotal     int        `gorm:"not null;default:0" json:"users_total"`
+	UsersDone      int        `gorm:"not null;default:0" json:"users_done"`
+	Scanned        int64      `gorm:"not null;default:0" json:"scanned"`
+	Downsampled    int64      `gorm:"not null;default:0" json:"downsampled"`
+	Purged         int64      `gorm:"not null;default:0" json:"purged"`
+	Archived       int64      `gorm:"not null;default:0" json:"archived"`
+	ArchiveFiles   string     `gorm:"type:text;not null;default:''" json:"archive_files,omitempty"`
+	Error          string     `gorm:"type:text;not null;default:''" json:"error,omitempty"`
+	StartedAt      time.Time  `gorm:"not null" json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
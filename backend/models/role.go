@@ -0,0 +1,129 @@
+package models
+
+// Роли пользователей. device — отслеживаемое устройство (доступ только к своим
+// данным через /api/device, /api/location), остальные — сотрудники с доступом
+// к административным маршрутам в пределах прав роли.
+const (
+	RoleDevice     = "device"
+	RoleViewer     = "viewer"
+	RoleDispatcher = "dispatcher"
+	RoleFleetAdmin = "fleet_admin"
+	RoleSuperAdmin = "super_admin"
+)
+
+// Permission — право на группу маршрутов; проверяется middleware.RequirePermission.
+type Permission string
+
+const (
+	// PermTrackingRead — чтение треков, визитов, алертов, статусов и отчётов устройств.
+	PermTrackingRead Permission = "tracking.read"
+	// PermDevicesCommand — wake, включение геолокации, запрос координат, произвольные команды.
+	PermDevicesCommand Permission = "devices.command"
+	// PermDevicesConfig — желаемая конфигурация устройств и профили.
+	PermDevicesConfig Permission = "devices.config"
+	// PermCheckpointsWrite — создание и изменение чекпоинтов.
+	PermCheckpointsWrite Permission = "checkpoints.write"
+	// PermAlertsAck — подтверждение и закрытие алертов.
+	PermAlertsAck Permission = "alerts.ack"
+	// PermAlertsManage — правила алертинга.
+	PermAlertsManage Permission = "alerts.manage"
+	// PermUsersManage — создание пользователей, выдача ключей/QR и назначение ролей.
+	PermUsersManage Permission = "users.manage"
+	// PermReleasesManage — релизы приложения и OTA-обновления.
+	PermReleasesManage Permission = "releases.manage"
+	// PermAuditRead — просмотр и выгрузка журнала аудита.
+	PermAuditRead Permission = "audit.read"
+	// PermSystem — служебные операции (backfill, публикация событий).
+	PermSystem Permission = "system"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleDevice: nil,
+	RoleViewer: {PermTrackingRead},
+	RoleDispatcher: {
+		PermTrackingRead, PermDevicesCommand, PermAlertsAck, PermCheckpointsWrite,
+	},
+	RoleFleetAdmin: {
+		PermTrackingRead, PermDevicesCommand, PermAlertsAck, PermCheckpointsWrite,
+		PermDevicesConfig, PermAlertsManage, PermUsersManage, PermReleasesManage,
+		PermAuditRead,
+	},
+	RoleSuperAdmin: {
+		PermTrackingRead, PermDevicesCommand, PermAlertsAck, PermCheckpointsWrite,
+		PermDevicesConfig, PermAlertsManage, PermUsersManage, PermReleasesManage,
+		PermAuditRead, PermSystem,
+	},
+}
+
+// roleRanks — старшинство ролей: нельзя выдать роль выше собственной.
+var roleRanks = map[string]int{
+	RoleDevice:     0,
+	RoleViewer:     1,
+	RoleDispatcher: 2,
+	RoleFleetAdmin: 3,
+	RoleSuperAdmin: 4,
+}
+
+// IsValidRole сообщает, известна ли роль.
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleRank — старшинство роли (-1 для неизвестной).
+func RoleRank(role string) int {
+	rank, ok := roleRanks[role]
+	if !ok {
+		return -1
+	}
+	return rank
+}
+
+// RolePermissions — права роли.
+func RolePermissions(role string) []Permission {
+	return rolePermissions[role]
+}
+
+// EffectiveRole — роль пользователя; для записей без роли (до миграции) —
+// super_admin при IsAdmin, иначе device.
+func (u *User) EffectiveRole() string {
+	if u.Role != "" {
+		return u.Role
+	}
+	if u.IsAdmin {
+		return RoleSuperAdmin
+	}
+	return RoleDevice
+}
+
+// IsStaff — пользователь-сотрудник (любая роль кроме device).
+func (u *User) IsStaff() bool {
+	return u.EffectiveRole() != RoleDevice
+}
+
+// HasPermission проверяет право по роли пользователя.
+func (u *User) HasPermission(perm Permission) bool {
+	for _, p := range rolePermissions[u.EffectiveRole()] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// LimitedToGroup — доступ ограничен пользователями своей группы.
+// super_admin видит всех независимо от группы.
+func (u *User) LimitedToGroup() bool {
+	return u.GroupID != nil && u.EffectiveRole() != RoleSuperAdmin
+}
+
+// IsPlatformAdmin — super_admin организации-владельца: управляет организациями.
+func (u *User) IsPlatformAdmin() bool {
+	return u.EffectiveRole() == RoleSuperAdmin && u.OrganizationID == DefaultOrganizationID
+}
+
+// SetRole назначает роль и синхронизирует IsAdmin (признак сотрудника).
+func (u *User) SetRole(role string) {
+	u.Role = role
+	u.IsAdmin = role != RoleDevice
+}
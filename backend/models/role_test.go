@@ -0,0 +1,65 @@
+package models
+
+import "testing"
+
+func TestEffectiveRole_legacyIsAdmin(t *testing.T) {
+	admin := User{IsAdmin: true}
+	if admin.EffectiveRole() != RoleSuperAdmin || !admin.HasPermission(PermSystem) {
+		t.Fatalf("legacy admin role=%s", admin.EffectiveRole())
+	}
+	device := User{}
+	if device.EffectiveRole() != RoleDevice || device.IsStaff() || device.HasPermission(PermTrackingRead) {
+		t.Fatalf("legacy device role=%s", device.EffectiveRole())
+	}
+}
+
+func TestHasPermission_byRole(t *testing.T) {
+	cases := []struct {
+		role    string
+		perm    Permission
+		allowed bool
+	}{
+		{RoleViewer, PermTrackingRead, true},
+		{RoleViewer, PermDevicesCommand, false},
+		{RoleDispatcher, PermDevicesCommand, true},
+		{RoleDispatcher, PermAlertsAck, true},
+		{RoleDispatcher, PermUsersManage, false},
+		{RoleFleetAdmin, PermReleasesManage, true},
+		{RoleFleetAdmin, PermSystem, false},
+		{RoleFleetAdmin, PermAuditRead, true},
+		{RoleDispatcher, PermAuditRead, false},
+		{RoleSuperAdmin, PermSystem, true},
+		{"unknown", PermTrackingRead, false},
+	}
+	for _, tc := range cases {
+		u := User{Role: tc.role, IsAdmin: true}
+		if got := u.HasPermission(tc.perm); got != tc.allowed {
+			t.Errorf("%s/%s: got %v want %v", tc.role, tc.perm, got, tc.allowed)
+		}
+	}
+}
+
+func TestLimitedToGroup(t *testing.T) {
+	group := 3
+	if !(&User{Role: RoleFleetAdmin, GroupID: &group}).LimitedToGroup() {
+		t.Fatal("fleet_admin with group must be limited")
+	}
+	if (&User{Role: RoleSuperAdmin, GroupID: &group}).LimitedToGroup() {
+		t.Fatal("super_admin must not be limited")
+	}
+	if (&User{Role: RoleViewer}).LimitedToGroup() {
+		t.Fatal("staff without group must not be limited")
+	}
+}
+
+func TestSetRole_syncsIsAdmin(t *testing.T) {
+	var u User
+	u.SetRole(RoleDispatcher)
+	if !u.IsAdmin {
+		t.Fatal("staff role must set IsAdmin")
+	}
+	u.SetRole(RoleDevice)
+	if u.IsAdmin {
+		t.Fatal("device role must clear IsAdmin")
+	}
+}
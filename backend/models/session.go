@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// UserSession — сессия входа сотрудника в веб-интерфейс. Access-токен подписан
+// сервером и ссылается на сессию по ID; refresh-токен хранится только хешем и
+// меняется при каждом обновлении. Отозванная или истёкшая сессия не принимается.
+type UserSession struct {
+	ID               string     `gorm:"primaryKey;size:36" json:"id"`
+	UserID           int        `gorm:"not null;index" json:"user_id"`
+	RefreshTokenHash string     `gorm:"size:64;not null" json:"-"`
+	UserAgent        string     `gorm:"size:255;not null;default:''" json:"user_agent"`
+	IP               string     `gorm:"size:64;not null;default:''" json:"ip"`
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt       time.Time  `gorm:"not null" json:"last_used_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Active сообщает, можно ли ещё пользоваться сессией в момент now.
+func (s *UserSession) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// LoginLink — одноразовая ссылка для входа, выданная администратором.
+// Хранится только хеш токена; после использования UsedAt заполняется.
+type LoginLink struct {
+	ID        int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    int        `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	CreatedBy int        `gorm:"not null" json:"created_by"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
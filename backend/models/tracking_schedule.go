@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Что делать с точкой, пришедшей вне окна отслеживания.
+const (
+	// TrackingOutsideDrop — точка не сохраняется.
+	TrackingOutsideDrop = "drop"
+	// TrackingOutsideMask — сохраняется только факт связи: координаты огрублены
+	// (~1 км), точка не попадает в трек, визиты и отчёты.
+	TrackingOutsideMask = "mask"
+)
+
+// TrackingWindow — интервал, когда отслеживание разрешено: дни недели
+// (1 — понедельник … 7 — воскресенье) и время [Start, End) в формате "HH:MM".
+// End "24:00" — до конца суток; End <= Start — окно через полночь.
+type TrackingWindow struct {
+	Days  []int  `json:"days"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// TrackingSchedule — график приватности пользователя: вне окон Windows, в дни
+// Holidays ("2006-01-02") и без согласия (если оно требуется) отслеживание
+// приостановлено — телефон получает tracking_paused, сервер отбрасывает или
+// маскирует точки, визиты и участки вне чекпоинтов на это время не строятся.
+// Пустой Windows — окна не ограничены (действуют только Holidays и согласие).
+type TrackingSchedule struct {
+	UserID         int  `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	OrganizationID int  `gorm:"not null;default:1;index" json:"organization_id"`
+	Enabled        bool `gorm:"not null;default:true" json:"enabled"`
+	// Timezone — IANA-зона, в которой заданы окна и праздники; пусто — пояс пользователя.
+	Timezone      string         `gorm:"size:64;not null;default:''" json:"timezone"`
+	Windows       datatypes.JSON `gorm:"type:jsonb" json:"windows,omitempty"`
+	Holidays      datatypes.JSON `gorm:"type:jsonb" json:"holidays,omitempty"`
+	OutsideAction string         `gorm:"size:10;not null;default:drop" json:"outside_action"`
+
+	// Согласие на отслеживание: при ConsentRequired без действующего согласия
+	// отслеживание приостановлено всегда. ConsentBy — кто дал согласие
+	// (сотрудник, родитель), ConsentRecordedBy — кто из сотрудников его внёс.
+	ConsentRequired   bool       `gorm:"not null;default:false" json:"consent_required"`
+	ConsentGivenAt    *time.Time `json:"consent_given_at,omitempty"`
+	ConsentBy         string     `gorm:"size:100;not null;default:''" json:"consent_by,omitempty"`
+	ConsentRecordedBy *int       `json:"consent_recorded_by,omitempty"`
+	ConsentRevokedAt  *time.Time `json:"consent_revoked_at,omitempty"`
+
+	// LastPushedPaused/LastPushedAt — последнее состояние, отправленное телефону config_update.
+	LastPushedPaused *bool      `json:"last_pushed_paused,omitempty"`
+	LastPushedAt     *time.Time `json:"last_pushed_at,omitempty"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// HasConsent — согласие дано и не отозвано (или не требуется).
+func (s *TrackingSchedule) HasConsent() bool {
+	if !s.ConsentRequired {
+		return true
+	}
+	return s.ConsentGivenAt != nil && s.ConsentRevokedAt == nil
+}
@@ -4,12 +4,37 @@ import (
 	"time"
 )
 
+// User — пользователь: отслеживаемое устройство или сотрудник.
+// Role — роль (см. role.go); IsAdmin сохраняется для совместимости и означает
+// «сотрудник» (role != device). OrganizationID — тенант: сотрудник никогда не видит
+// пользователей другой организации. GroupID ограничивает доступ сотрудника
+// пользователями своей группы. Username/PasswordHash — учётные данные сотрудника
+// для входа в веб-интерфейс (устройства входят только по API-ключу, см. APIKey).
+// Timezone — часовой пояс пользователя (IANA); пусто — пояс организации.
+// TOTPLastStep — последний принятый шаг TOTP; только для чтения, пишется UserDAO.UseTOTPStep.
 type User struct {
-	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name      string    `gorm:"not null" json:"name"`
-	ApiKey    string    `gorm:"unique;not null" json:"-"`
-	IsAdmin   bool      `gorm:"default:false" json:"is_admin"`
-	QRCode    string    `gorm:"type:text" json:"qr_code,omitempty"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID             int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name           string    `gorm:"not null" json:"name"`
+	IsAdmin        bool      `gorm:"default:false" json:"is_admin"`
+	Role           string    `gorm:"size:20;not null;default:''" json:"role"`
+	OrganizationID int       `gorm:"not null;default:1;index" json:"organization_id"`
+	GroupID        *int      `gorm:"index" json:"group_id,omitempty"`
+	Timezone       string    `gorm:"size:64;not null;default:''" json:"timezone,omitempty"`
+	QRCode         string    `gorm:"type:text" json:"qr_code,omitempty"`
+	Username       *string   `gorm:"size:100;uniqueIndex" json:"username,omitempty"`
+	PasswordHash   string    `gorm:"size:100;not null;default:''" json:"-"`
+	TOTPSecret     string    `gorm:"column:totp_secret;size:64;not null;default:''" json:"-"`
+	TOTPEnabled    bool      `gorm:"column:totp_enabled;not null;default:false" json:"totp_enabled"`
+	TOTPLastStep   int64     `gorm:"column:totp_last_step;->;not null;default:0" json:"-"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Group — группа пользователей внутри организации (подразделение, бригада).
+// Сотрудник с GroupID видит и управляет только пользователями своей группы.
+type Group struct {
+	ID             int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrganizationID int       `gorm:"not null;default:1;uniqueIndex:idx_groups_org_name" json:"organization_id"`
+	Name           string    `gorm:"size:100;not null;uniqueIndex:idx_groups_org_name" json:"name"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
 }
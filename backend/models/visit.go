@@ -12,3 +12,15 @@ type Visit struct {
 	Duration     int        `json:"duration"`                // Длительность визита в секундах (вычисляется при завершении визита).
 	Kind         string     `gorm:"-" json:"kind,omitempty"` // "checkpoint" (по умолчанию) или "outside".
 }
+
+// In переводит времена визита в пояс tz: в JSON они уходят со смещением этого пояса.
+func (v *Visit) In(tz *time.Location) {
+	if v == nil || tz == nil {
+		return
+	}
+	v.StartAt = v.StartAt.In(tz)
+	if v.EndAt != nil {
+		t := v.EndAt.In(tz)
+		v.EndAt = &t
+	}
+}
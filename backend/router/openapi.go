@@ -0,0 +1,681 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"locator/controllers"
+	"locator/internal/openapi"
+	"locator/models"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:generate go run ../cmd/openapi -dir ..
+
+// Спецификация OpenAPI собирается из таблицы apiOperations и типов запросов и ответов
+// контроллеров. Каждый маршрут InitRoutes обязан быть в таблице (TestSpec_coversRoutes);
+// снимок api/openapi.json и клиент client/ обновляются go generate ./router.
+
+// access — чем авторизуется вызывающий.
+type access int
+
+const (
+	public          access = iota
+	deviceOrSession        // API-ключ устройства (X-API-Key) или сессия сотрудника
+	sessionOnly            // только сессия сотрудника (Authorization: Bearer)
+)
+
+type param struct {
+	Name        string
+	Type        string // integer, string, boolean
+	Description string
+}
+
+type reply struct {
+	Status      int
+	Body        any    // nil — без тела (или файл при ContentType)
+	ContentType string // "" — application/json
+	Description string
+}
+
+type apiOperation struct {
+	Method, Path string
+	ID           string
+	Tag          string
+	Summary      string
+	Description  string
+	Auth         access
+	// PathStrings — параметры пути строкового типа (остальные — integer).
+	PathStrings []string
+	Query       []param
+	Body        any
+	// Form — multipart/form-data вместо (или вместе с) JSON-телом.
+	Form    *openapi.Schema
+	Replies []reply
+	// Errors — коды ошибок обработчика; 401, 429 и 500 для маршрутов с авторизацией
+	// и 403 для маршрутов сотрудников добавляются сами.
+	Errors []int
+}
+
+func ok(body any) []reply       { return []reply{{Status: http.StatusOK, Body: body}} }
+func created(body any) []reply  { return []reply{{Status: http.StatusCreated, Body: body}} }
+func accepted(body any) []reply { return []reply{{Status: http.StatusAccepted, Body: body}} }
+
+func q(name, typ, description string) param {
+	return param{Name: name, Type: typ, Description: description}
+}
+
+var (
+	qFrom = q("from", "string", "начало интервала: RFC3339 или YYYY-MM-DDTHH:mm в поясе пользователя")
+	qTo   = q("to", "string", "конец интервала, как from")
+	qTZ   = q("tz", "string", "IANA-пояс для from/to и времени в ответе")
+)
+
+// RateLimitsResponse — GET /api/admin/rate-limits: включены ли лимиты и счётчики отказов.
+type RateLimitsResponse struct {
+	Enabled  bool             `json:"enabled"`
+	Counters map[string]int64 `json:"counters"`
+}
+
+var apiTags = []openapi.Tag{
+	{Name: "health", Description: "Пробы процесса для docker и балансировщика"},
+	{Name: "auth", Description: "Вход сотрудников, сессии, пароль и TOTP"},
+	{Name: "users", Description: "Пользователи, QR-коды и API-ключи"},
+	{Name: "access", Description: "Роли, группы и организации"},
+	{Name: "locations", Description: "Точки, on-demand запросы и привязка к дорогам"},
+	{Name: "device", Description: "Мобильный коннектор: опрос, отчёты, подтверждение команд"},
+	{Name: "device-admin", Description: "Команды, диагностика и конфигурация устройств"},
+	{Name: "tracking", Description: "Окна отслеживания и согласие"},
+	{Name: "alerts", Description: "Алерты и правила алертинга"},
+	{Name: "notifications", Description: "Подписки на уведомления"},
+	{Name: "releases", Description: "OTA-релизы, каналы и раскатка"},
+	{Name: "checkpoints", Description: "Чекпоинты и визиты"},
+	{Name: "retention", Description: "Сроки хранения точек"},
+	{Name: "audit", Description: "Журнал аудита"},
+	{Name: "system", Description: "Служебные маршруты платформы"},
+}
+
+var apiOperations = []apiOperation{
+	// Пробы и спецификация.
+	{Method: "GET", Path: "/healthz", ID: "getHealthz", Tag: "health", Summary: "Процесс жив (прежнее имя /livez)",
+		Replies: ok(controllers.StatusResponse{})},
+	{Method: "GET", Path: "/livez", ID: "getLivez", Tag: "health", Summary: "Процесс жив",
+		Replies: ok(controllers.StatusResponse{})},
+	{Method: "GET", Path: "/readyz", ID: "getReadyz", Tag: "health", Summary: "Готовность: БД, брокер, миграции",
+		Replies: []reply{
+			{Status: http.StatusOK, Body: controllers.ReadinessResponse{}},
+			{Status: http.StatusServiceUnavailable, Body: controllers.ReadinessResponse{}, Description: "Проверка не прошла или идёт остановка"},
+		}},
+	{Method: "GET", Path: "/api/openapi.json", ID: "getOpenAPI", Tag: "system", Summary: "Эта спецификация",
+		Replies: ok(map[string]any{})},
+	{Method: "GET", Path: "/api/app/release/latest", ID: "getLatestRelease", Tag: "releases",
+		Summary:     "Манифест последнего релиза для OTA",
+		Description: "Самый новый релиз канала организации на 100% раскатки; без релизов в БД — manifest.json.",
+		Query: []param{
+			q("channel", "string", "канал (по умолчанию stable)"),
+			q("organization_id", "integer", "организация (по умолчанию 1)"),
+		},
+		Replies: ok(map[string]any{}), Errors: []int{400, 404, 500}},
+
+	// Вход.
+	{Method: "POST", Path: "/api/auth/login", ID: "login", Tag: "auth", Summary: "Вход по логину и паролю",
+		Body: controllers.LoginRequest{}, Replies: ok(service.SessionTokens{}), Errors: []int{400, 401, 403, 429, 500}},
+	{Method: "POST", Path: "/api/auth/login-link", ID: "loginWithLink", Tag: "auth", Summary: "Вход по одноразовой ссылке",
+		Body: controllers.LoginLinkRequest{}, Replies: ok(service.SessionTokens{}), Errors: []int{400, 401, 403, 429, 500}},
+	{Method: "POST", Path: "/api/auth/refresh", ID: "refreshSession", Tag: "auth", Summary: "Обновление access-токена",
+		Description: "Старый refresh-токен перестаёт действовать.",
+		Body:        controllers.RefreshRequest{}, Replies: ok(service.SessionTokens{}), Errors: []int{400, 401, 429, 500}},
+
+	// Устройство (API-ключ) и общие маршруты.
+	{Method: "POST", Path: "/api/location", ID: "postLocation", Tag: "locations", Auth: deviceOrSession,
+		Summary:     "Новая точка",
+		Description: "Отброшенная фильтром качества точка — 200 с skipped и reason. 503 с Retry-After — окно отслеживания не проверено, точку нужно отправить повторно.",
+		Body:        controllers.LocationUploadRequest{}, Replies: ok(controllers.LocationUploadResponse{}), Errors: []int{400, 403, 503}},
+	{Method: "POST", Path: "/api/device/report", ID: "postDeviceReport", Tag: "device", Auth: deviceOrSession,
+		Summary: "Диагностический отчёт телефона",
+		Body:    models.DeviceReportPayload{}, Replies: created(controllers.DeviceReportResponse{}), Errors: []int{400}},
+	{Method: "POST", Path: "/api/device/command/ack", ID: "ackDeviceCommand", Tag: "device", Auth: deviceOrSession,
+		Summary: "Подтверждение команды",
+		Body:    controllers.CommandAckRequest{}, Replies: ok(controllers.CommandAckResponse{}), Errors: []int{400, 403, 404}},
+	{Method: "GET", Path: "/api/users/me", ID: "getCurrentUser", Tag: "users", Auth: deviceOrSession,
+		Summary: "Текущий пользователь", Replies: ok(controllers.CurrentUserResponse{})},
+	{Method: "GET", Path: "/api/location/single", ID: "getLocationSingle", Tag: "locations", Auth: deviceOrSession,
+		Summary: "Последняя точка пользователя (прежнее имя /location/current)",
+		Query:   locationQuery, Replies: locationReplies, Errors: []int{400, 403}},
+	{Method: "GET", Path: "/api/location/current", ID: "getLocationCurrent", Tag: "locations", Auth: deviceOrSession,
+		Summary: "Последняя точка пользователя",
+		Query:   locationQuery, Replies: locationReplies, Errors: []int{400, 403}},
+	{Method: "GET", Path: "/api/device/poll", ID: "pollDevice", Tag: "device", Auth: deviceOrSession,
+		Summary: "Опрос команд устройством",
+		Query:   []param{q("json", "boolean", "вместо 204 — 200 {\"pending\": false}")},
+		Replies: []reply{
+			{Status: http.StatusOK, Body: controllers.DevicePollResponse{}},
+			{Status: http.StatusNoContent, Description: "Команд нет"},
+		}},
+	{Method: "GET", Path: "/api/location/request", ID: "pollLocationRequest", Tag: "device", Auth: deviceOrSession,
+		Summary: "Legacy-опрос on-demand запроса",
+		Query:   []param{q("json", "boolean", "вместо 204 — 200 {\"pending\": false}")},
+		Replies: []reply{
+			{Status: http.StatusOK, Body: controllers.LocationRequestPollResponse{}},
+			{Status: http.StatusNoContent, Description: "Запросов нет"},
+		}},
+	{Method: "GET", Path: "/api/notifications/subscriptions", ID: "listNotificationSubscriptions", Tag: "notifications", Auth: deviceOrSession,
+		Summary: "Подписки на уведомления",
+		Query:   []param{q("user_id", "integer", "чужие подписки (только администратор)")},
+		Replies: ok([]models.NotificationSubscription{}), Errors: []int{403}},
+	{Method: "POST", Path: "/api/notifications/subscriptions", ID: "createNotificationSubscription", Tag: "notifications", Auth: deviceOrSession,
+		Summary: "Новая подписка",
+		Body:    service.NotificationSubscriptionInput{}, Replies: created(models.NotificationSubscription{}), Errors: []int{400, 403}},
+	{Method: "PUT", Path: "/api/notifications/subscriptions/:id", ID: "updateNotificationSubscription", Tag: "notifications", Auth: deviceOrSession,
+		Summary: "Изменение подписки",
+		Body:    service.NotificationSubscriptionInput{}, Replies: ok(models.NotificationSubscription{}), Errors: []int{400, 403, 404}},
+	{Method: "DELETE", Path: "/api/notifications/subscriptions/:id", ID: "deleteNotificationSubscription", Tag: "notifications", Auth: deviceOrSession,
+		Summary: "Удаление подписки", Replies: ok(controllers.OKResponse{}), Errors: []int{400, 403, 404}},
+	{Method: "POST", Path: "/api/notifications/subscriptions/:id/test", ID: "testNotificationSubscription", Tag: "notifications", Auth: deviceOrSession,
+		Summary: "Тестовое сообщение в канал подписки",
+		Replies: []reply{
+			{Status: http.StatusOK, Body: models.NotificationDelivery{}},
+			{Status: http.StatusBadGateway, Body: models.NotificationDelivery{}, Description: "Канал отклонил сообщение"},
+		},
+		Errors: []int{400, 403, 404}},
+	{Method: "GET", Path: "/api/notifications/deliveries", ID: "listNotificationDeliveries", Tag: "notifications", Auth: deviceOrSession,
+		Summary: "Последние попытки доставки", Replies: ok([]models.NotificationDelivery{}), Errors: []int{403}},
+
+	// Своя сессия.
+	{Method: "POST", Path: "/api/auth/logout", ID: "logout", Tag: "auth", Auth: sessionOnly,
+		Summary: "Выход: закрыть текущую сессию", Replies: ok(controllers.StatusResponse{})},
+	{Method: "GET", Path: "/api/auth/sessions", ID: "listSessions", Tag: "auth", Auth: sessionOnly,
+		Summary: "Действующие сессии", Replies: ok([]controllers.SessionInfo{})},
+	{Method: "DELETE", Path: "/api/auth/sessions/:id", ID: "deleteSession", Tag: "auth", Auth: sessionOnly, PathStrings: []string{"id"},
+		Summary: "Выход на другом устройстве", Replies: ok(controllers.StatusResponse{}), Errors: []int{404}},
+	{Method: "PUT", Path: "/api/auth/password", ID: "changePassword", Tag: "auth", Auth: sessionOnly,
+		Summary: "Смена пароля; остальные сессии закрываются",
+		Body:    controllers.PasswordChangeRequest{}, Replies: ok(controllers.StatusResponse{}), Errors: []int{400}},
+	{Method: "POST", Path: "/api/auth/totp/setup", ID: "setupTOTP", Tag: "auth", Auth: sessionOnly,
+		Summary: "Новый секрет TOTP (включается через /totp/enable)", Replies: ok(service.TOTPSetup{}), Errors: []int{409}},
+	{Method: "POST", Path: "/api/auth/totp/enable", ID: "enableTOTP", Tag: "auth", Auth: sessionOnly,
+		Summary: "Включить второй фактор",
+		Body:    controllers.TOTPCodeRequest{}, Replies: ok(controllers.StatusResponse{}), Errors: []int{400, 409}},
+	{Method: "POST", Path: "/api/auth/totp/disable", ID: "disableTOTP", Tag: "auth", Auth: sessionOnly,
+		Summary: "Выключить второй фактор",
+		Body:    controllers.TOTPCodeRequest{}, Replies: ok(controllers.StatusResponse{}), Errors: []int{400, 409}},
+
+	// Точки.
+	{Method: "GET", Path: "/api/location/match-route", ID: "getMatchedRoute", Tag: "locations", Auth: sessionOnly,
+		Summary: "Трек, привязанный к дорогам (OSRM match)",
+		Query: []param{
+			q("user_id", "integer", "пользователь (обязательно)"), qFrom, qTo, qTZ,
+		},
+		Replies: ok(controllers.MatchedRouteResponse{}), Errors: []int{400, 502, 503}},
+	{Method: "GET", Path: "/api/location/", ID: "listLocations", Tag: "locations", Auth: sessionOnly,
+		Summary: "Точки (по умолчанию — значимые)",
+		Query: []param{
+			qFrom, qTo, qTZ, q("raw", "boolean", "все точки без фильтра значимых"),
+		},
+		Replies: ok([]models.Location{}), Errors: []int{400}},
+	{Method: "POST", Path: "/api/location/request", ID: "requestLocation", Tag: "locations", Auth: sessionOnly,
+		Summary: "On-demand запрос точки с телефона",
+		Body:    controllers.OnDemandLocationRequest{}, Replies: accepted(controllers.LocationRequestQueuedResponse{}), Errors: []int{400}},
+	{Method: "GET", Path: "/api/location/request/:request_id", ID: "getLocationRequest", Tag: "locations", Auth: sessionOnly,
+		PathStrings: []string{"request_id"},
+		Summary:     "Состояние on-demand запроса", Replies: ok(controllers.LocationRequestStatusResponse{}), Errors: []int{400, 404}},
+
+	// Администрирование устройств.
+	{Method: "GET", Path: "/api/admin/devices/status", ID: "getDevicesStatus", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Сводка GPS и health по всем пользователям", Replies: ok(controllers.DevicesStatusResponse{}), Errors: []int{503}},
+	{Method: "POST", Path: "/api/admin/users/:id/wake", ID: "wakeDevice", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Пробудить трекинг на телефоне", Replies: accepted(controllers.DeviceWakeResponse{}), Errors: []int{400}},
+	{Method: "POST", Path: "/api/admin/users/:id/enable-location", ID: "enableDeviceLocation", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Включить разрешения и геолокацию (Device Owner)", Replies: accepted(controllers.DeviceWakeResponse{}), Errors: []int{400}},
+	{Method: "POST", Path: "/api/admin/users/:id/commands", ID: "sendDeviceCommand", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Команда устройству",
+		Body:    controllers.UserCommandRequest{}, Replies: accepted(controllers.DeviceCommandResponse{}), Errors: []int{400}},
+	{Method: "GET", Path: "/api/admin/users/:id/commands/:command_id", ID: "getDeviceCommand", Tag: "device-admin", Auth: sessionOnly,
+		PathStrings: []string{"command_id"},
+		Summary:     "Статус команды и ack устройства", Replies: ok(models.DeviceCommand{}), Errors: []int{400, 404}},
+	{Method: "POST", Path: "/api/admin/users/:id/device/config", ID: "sendDeviceConfig", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Валидированный config_update",
+		Body:    service.DeviceConfigUpdateInput{}, Replies: accepted(controllers.DeviceCommandResponse{}), Errors: []int{400}},
+	{Method: "GET", Path: "/api/admin/users/:id/device/desired-config", ID: "getDesiredConfig", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Желаемая конфигурация устройства", Replies: ok(controllers.DesiredConfigResponse{}), Errors: []int{400, 404}},
+	{Method: "PUT", Path: "/api/admin/users/:id/device/desired-config", ID: "setDesiredConfig", Tag: "device-admin", Auth: sessionOnly,
+		Summary:     "Заменить желаемую конфигурацию",
+		Description: "Дрейф устраняется при следующем отчёте телефона.",
+		Body:        controllers.DeviceDesiredConfigRequest{}, Replies: ok(controllers.DesiredConfigResponse{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/admin/users/:id/device/config-drift", ID: "getConfigDrift", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Дрейф конфигурации устройства", Replies: ok(service.DeviceConfigDriftStatus{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/admin/users/:id/tracking-schedule", ID: "getTrackingSchedule", Tag: "tracking", Auth: sessionOnly,
+		Summary: "График отслеживания и текущее состояние", Replies: ok(controllers.TrackingScheduleResponse{}), Errors: []int{400, 404}},
+	{Method: "PUT", Path: "/api/admin/users/:id/tracking-schedule", ID: "setTrackingSchedule", Tag: "tracking", Auth: sessionOnly,
+		Summary: "Заменить график отслеживания",
+		Body:    service.TrackingScheduleInput{}, Replies: ok(controllers.TrackingScheduleResponse{}), Errors: []int{400, 404}},
+	{Method: "DELETE", Path: "/api/admin/users/:id/tracking-schedule", ID: "deleteTrackingSchedule", Tag: "tracking", Auth: sessionOnly,
+		Summary: "Снять ограничения отслеживания", Replies: ok(controllers.StatusResponse{}), Errors: []int{400, 404}},
+	{Method: "POST", Path: "/api/admin/users/:id/tracking-consent", ID: "recordTrackingConsent", Tag: "tracking", Auth: sessionOnly,
+		Summary: "Согласие на отслеживание получено или отозвано",
+		Body:    controllers.TrackingConsentRequest{}, Replies: ok(controllers.TrackingScheduleResponse{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/admin/devices/config-drift", ID: "listConfigDrift", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Дрейф конфигурации по всем устройствам", Replies: ok(controllers.ConfigDriftSummaryResponse{})},
+	{Method: "GET", Path: "/api/admin/device-config/profiles", ID: "listDeviceConfigProfiles", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Профили конфигурации", Replies: ok([]models.DeviceConfigProfile{})},
+	{Method: "POST", Path: "/api/admin/device-config/profiles", ID: "createDeviceConfigProfile", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Новый профиль конфигурации",
+		Body:    controllers.DeviceConfigProfileRequest{}, Replies: created(models.DeviceConfigProfile{}), Errors: []int{400}},
+	{Method: "PUT", Path: "/api/admin/device-config/profiles/:id", ID: "updateDeviceConfigProfile", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Изменение профиля конфигурации",
+		Body:    controllers.DeviceConfigProfileRequest{}, Replies: ok(models.DeviceConfigProfile{}), Errors: []int{400, 404}},
+
+	// Алертинг.
+	{Method: "GET", Path: "/api/admin/alert-rules", ID: "listAlertRules", Tag: "alerts", Auth: sessionOnly,
+		Summary: "Правила алертинга", Replies: ok([]models.AlertRule{})},
+	{Method: "POST", Path: "/api/admin/alert-rules", ID: "createAlertRule", Tag: "alerts", Auth: sessionOnly,
+		Summary: "Новое правило", Body: models.AlertRule{}, Replies: created(models.AlertRule{}), Errors: []int{400}},
+	{Method: "PUT", Path: "/api/admin/alert-rules/:id", ID: "updateAlertRule", Tag: "alerts", Auth: sessionOnly,
+		Summary: "Изменение правила", Body: models.AlertRule{}, Replies: ok(models.AlertRule{}), Errors: []int{400, 404}},
+	{Method: "DELETE", Path: "/api/admin/alert-rules/:id", ID: "deleteAlertRule", Tag: "alerts", Auth: sessionOnly,
+		Summary: "Удаление правила", Replies: ok(controllers.OKResponse{}), Errors: []int{400, 404}},
+	{Method: "POST", Path: "/api/admin/alerts/evaluate", ID: "evaluateAlerts", Tag: "alerts", Auth: sessionOnly,
+		Summary: "Внеочередная проверка правил", Replies: ok(service.AlertEvaluation{})},
+
+	// Пользователи и доступ.
+	{Method: "POST", Path: "/api/admin/users/:id/regenerate-qr", ID: "regenerateUserQR", Tag: "users", Auth: sessionOnly,
+		Summary:     "Новый API-ключ и QR-код",
+		Description: "По умолчанию ключ отправляется на телефон командой config_update.",
+		Body:        controllers.RegenerateQRRequest{}, Replies: ok(controllers.RegenerateQRResponse{}), Errors: []int{400, 404}},
+	{Method: "PUT", Path: "/api/admin/users/:id/access", ID: "setUserAccess", Tag: "access", Auth: sessionOnly,
+		Summary: "Роль и группа пользователя",
+		Body:    controllers.UserAccessRequest{}, Replies: ok(models.User{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/admin/users/:id/api-keys", ID: "listUserAPIKeys", Tag: "users", Auth: sessionOnly,
+		Summary: "API-ключи пользователя без секретов", Replies: ok([]models.APIKey{}), Errors: []int{400, 404}},
+	{Method: "POST", Path: "/api/admin/users/:id/api-keys", ID: "createUserAPIKey", Tag: "users", Auth: sessionOnly,
+		Summary: "Дополнительный API-ключ (показывается один раз)",
+		Body:    service.APIKeyInput{}, Replies: created(controllers.APIKeyCreatedResponse{}), Errors: []int{400, 404}},
+	{Method: "DELETE", Path: "/api/admin/users/:id/api-keys/:key_id", ID: "revokeUserAPIKey", Tag: "users", Auth: sessionOnly,
+		Summary: "Отзыв API-ключа", Replies: ok(models.APIKey{}), Errors: []int{400, 404}},
+	{Method: "PUT", Path: "/api/admin/users/:id/credentials", ID: "setUserCredentials", Tag: "auth", Auth: sessionOnly,
+		Summary: "Логин и пароль сотрудника",
+		Body:    service.CredentialsInput{}, Replies: ok(models.User{}), Errors: []int{400, 404, 409}},
+	{Method: "POST", Path: "/api/admin/users/:id/login-link", ID: "createUserLoginLink", Tag: "auth", Auth: sessionOnly,
+		Summary: "Одноразовая ссылка входа", Replies: created(controllers.LoginLinkResponse{}), Errors: []int{400, 404}},
+	{Method: "POST", Path: "/api/admin/users/:id/sessions/revoke", ID: "revokeUserSessions", Tag: "auth", Auth: sessionOnly,
+		Summary: "Закрыть все сессии сотрудника", Replies: ok(controllers.StatusResponse{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/admin/groups", ID: "listGroups", Tag: "access", Auth: sessionOnly,
+		Summary: "Группы", Replies: ok([]models.Group{})},
+	{Method: "POST", Path: "/api/admin/groups", ID: "createGroup", Tag: "access", Auth: sessionOnly,
+		Summary: "Новая группа", Body: controllers.GroupRequest{}, Replies: created(models.Group{}), Errors: []int{400}},
+	{Method: "GET", Path: "/api/admin/groups/:id/members", ID: "listGroupMembers", Tag: "access", Auth: sessionOnly,
+		Summary: "Состав группы", Replies: ok([]models.User{}), Errors: []int{400, 404}},
+	{Method: "POST", Path: "/api/admin/groups/:id/members", ID: "addGroupMember", Tag: "access", Auth: sessionOnly,
+		Summary: "Перевести пользователя в группу",
+		Body:    controllers.GroupMemberRequest{}, Replies: ok(models.User{}), Errors: []int{400, 404}},
+	{Method: "DELETE", Path: "/api/admin/groups/:id/members/:user_id", ID: "removeGroupMember", Tag: "access", Auth: sessionOnly,
+		Summary: "Убрать пользователя из группы", Replies: ok(models.User{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/admin/organizations", ID: "listOrganizations", Tag: "access", Auth: sessionOnly,
+		Summary: "Организации (администратор платформы)", Replies: ok([]models.Organization{})},
+	{Method: "POST", Path: "/api/admin/organizations", ID: "createOrganization", Tag: "access", Auth: sessionOnly,
+		Summary: "Организация и её первый super_admin",
+		Body:    controllers.OrganizationRequest{}, Replies: created(controllers.OrganizationCreatedResponse{}), Errors: []int{400}},
+	{Method: "PUT", Path: "/api/admin/organizations/:id/timezone", ID: "setOrganizationTimezone", Tag: "access", Auth: sessionOnly,
+		Summary: "Часовой пояс организации",
+		Body:    controllers.OrganizationTimezoneRequest{}, Replies: ok(models.Organization{}), Errors: []int{400, 404}},
+
+	// Релизы.
+	{Method: "POST", Path: "/api/admin/releases/publish-update/:user_id", ID: "publishAppUpdate", Tag: "releases", Auth: sessionOnly,
+		Summary: "Команда app_update на устройство",
+		Query:   []param{q("release_id", "integer", "релиз из БД (по умолчанию — manifest.json)")},
+		Replies: accepted(controllers.DeviceCommandResponse{}), Errors: []int{400, 404, 503}},
+	{Method: "POST", Path: "/api/admin/releases/sync-manifest", ID: "syncReleaseManifest", Tag: "releases", Auth: sessionOnly,
+		Summary: "Пересчитать manifest.json из APK",
+		Body:    controllers.SyncManifestRequest{}, Replies: ok(controllers.SyncManifestResponse{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/admin/releases", ID: "listReleases", Tag: "releases", Auth: sessionOnly,
+		Summary: "История релизов", Query: []param{q("channel", "string", "только этот канал")},
+		Replies: ok([]models.AppRelease{}), Errors: []int{503}},
+	{Method: "POST", Path: "/api/admin/releases", ID: "createRelease", Tag: "releases", Auth: sessionOnly,
+		Summary:     "Новый релиз",
+		Description: "multipart/form-data: поля формы (до файла или в query) и файл apk; JSON — APK, уже лежащий в static/releases.",
+		Body:        service.AppReleaseInput{}, Form: releaseUploadForm(),
+		Replies: created(models.AppRelease{}), Errors: []int{400, 409, 503}},
+	{Method: "PUT", Path: "/api/admin/releases/:id", ID: "updateRelease", Tag: "releases", Auth: sessionOnly,
+		Summary: "Раскатка, порог автопаузы, force, changelog",
+		Body:    service.AppReleaseUpdateInput{}, Replies: ok(models.AppRelease{}), Errors: []int{400, 404, 503}},
+	{Method: "POST", Path: "/api/admin/releases/:id/pause", ID: "pauseRelease", Tag: "releases", Auth: sessionOnly,
+		Summary: "Пауза раскатки", Body: controllers.ReleaseStatusRequest{}, Replies: ok(models.AppRelease{}), Errors: []int{400, 404, 503}},
+	{Method: "POST", Path: "/api/admin/releases/:id/resume", ID: "resumeRelease", Tag: "releases", Auth: sessionOnly,
+		Summary: "Возобновить раскатку", Body: controllers.ReleaseStatusRequest{}, Replies: ok(models.AppRelease{}), Errors: []int{400, 404, 503}},
+	{Method: "POST", Path: "/api/admin/releases/:id/archive", ID: "archiveRelease", Tag: "releases", Auth: sessionOnly,
+		Summary: "Архивировать релиз", Body: controllers.ReleaseStatusRequest{}, Replies: ok(models.AppRelease{}), Errors: []int{400, 404, 503}},
+	{Method: "GET", Path: "/api/admin/releases/:id/stats", ID: "getReleaseStats", Tag: "releases", Auth: sessionOnly,
+		Summary: "Итоги app_update по релизу", Replies: ok(service.AppReleaseStats{}), Errors: []int{400, 404, 503}},
+	{Method: "GET", Path: "/api/admin/release-channels", ID: "listReleaseChannels", Tag: "releases", Auth: sessionOnly,
+		Summary: "Каналы релизов", Replies: ok([]models.AppReleaseChannel{}), Errors: []int{503}},
+	{Method: "GET", Path: "/api/admin/release-channels/members", ID: "listReleaseChannelMembers", Tag: "releases", Auth: sessionOnly,
+		Summary: "Пользователи не-stable каналов", Replies: ok([]models.AppReleaseMember{}), Errors: []int{503}},
+	{Method: "PUT", Path: "/api/admin/release-channels/:name", ID: "setReleaseChannel", Tag: "releases", Auth: sessionOnly,
+		PathStrings: []string{"name"},
+		Summary:     "Минимальная версия канала",
+		Body:        controllers.ReleaseChannelRequest{}, Replies: ok(models.AppReleaseChannel{}), Errors: []int{400, 503}},
+	{Method: "PUT", Path: "/api/admin/users/:id/release-channel", ID: "setUserReleaseChannel", Tag: "releases", Auth: sessionOnly,
+		Summary: "Канал релизов пользователя",
+		Body:    controllers.UserReleaseChannelRequest{}, Replies: ok(controllers.UserReleaseChannelResponse{}), Errors: []int{400, 404, 503}},
+
+	// Обслуживание данных.
+	{Method: "POST", Path: "/api/admin/locations/backfill-captured-at", ID: "backfillCapturedAt", Tag: "system", Auth: sessionOnly,
+		Summary: "Восстановить captured_at старых точек",
+		Query: []param{
+			q("user_id", "integer", "только этот пользователь"),
+			q("interval_seconds", "integer", "шаг между точками очереди (по умолчанию 300)"),
+			q("burst_gap_seconds", "integer", "разрыв, начинающий новую пачку (по умолчанию 30)"),
+			q("dry_run", "boolean", "только подсчёт"),
+		},
+		Replies: ok(service.BackfillCapturedAtResult{})},
+	{Method: "GET", Path: "/api/admin/retention/policy", ID: "getRetentionPolicy", Tag: "retention", Auth: sessionOnly,
+		Summary: "Политика хранения организации", Query: []param{retentionOrgQuery},
+		Replies: ok(controllers.RetentionPolicyResponse{}), Errors: []int{400}},
+	{Method: "PUT", Path: "/api/admin/retention/policy", ID: "setRetentionPolicy", Tag: "retention", Auth: sessionOnly,
+		Summary: "Изменение политики хранения", Query: []param{retentionOrgQuery},
+		Body: service.RetentionPolicyInput{}, Replies: ok(controllers.RetentionPolicyResponse{}), Errors: []int{400}},
+	{Method: "DELETE", Path: "/api/admin/retention/policy", ID: "deleteRetentionPolicy", Tag: "retention", Auth: sessionOnly,
+		Summary: "Вернуть политику по умолчанию", Query: []param{retentionOrgQuery},
+		Replies: []reply{{Status: http.StatusNoContent, Description: "Политика удалена"}}, Errors: []int{400}},
+	{Method: "GET", Path: "/api/admin/retention/runs", ID: "listRetentionRuns", Tag: "retention", Auth: sessionOnly,
+		Summary: "Последние проходы хранения", Replies: ok(controllers.RetentionRunsResponse{})},
+	{Method: "POST", Path: "/api/admin/retention/runs", ID: "startRetentionRun", Tag: "retention", Auth: sessionOnly,
+		Summary: "Запустить проход хранения в фоне",
+		Body:    controllers.RetentionRunRequest{}, Replies: accepted(models.RetentionRun{}), Errors: []int{400, 409}},
+	{Method: "GET", Path: "/api/admin/retention/runs/:id", ID: "getRetentionRun", Tag: "retention", Auth: sessionOnly,
+		Summary: "Состояние прохода хранения", Replies: ok(models.RetentionRun{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/admin/audit", ID: "listAudit", Tag: "audit", Auth: sessionOnly,
+		Summary: "Журнал аудита", Query: append(auditQuery, q("limit", "integer", ""), q("offset", "integer", "")),
+		Replies: ok(controllers.AuditListResponse{}), Errors: []int{400}},
+	{Method: "GET", Path: "/api/admin/audit/export", ID: "exportAudit", Tag: "audit", Auth: sessionOnly,
+		Summary: "Выгрузка журнала аудита файлом",
+		Query:   append(auditQuery, q("format", "string", "csv (по умолчанию) или jsonl")),
+		Replies: []reply{
+			{Status: http.StatusOK, ContentType: "text/csv", Description: "CSV или JSON Lines (application/x-ndjson)"},
+		},
+		Errors: []int{400}},
+	{Method: "GET", Path: "/api/admin/rate-limits", ID: "getRateLimits", Tag: "system", Auth: sessionOnly,
+		Summary: "Лимиты частоты: счётчики отказов", Replies: ok(RateLimitsResponse{})},
+
+	// Алерты.
+	{Method: "GET", Path: "/api/alerts", ID: "listAlerts", Tag: "alerts", Auth: sessionOnly,
+		Summary: "Алерты",
+		Query: []param{
+			q("status", "string", ""), q("type", "string", ""), q("user_id", "integer", ""), q("rule_id", "integer", ""),
+			q("limit", "integer", ""), q("offset", "integer", ""),
+		},
+		Replies: ok(controllers.AlertListResponse{}), Errors: []int{400}},
+	{Method: "POST", Path: "/api/alerts/:id/ack", ID: "acknowledgeAlert", Tag: "alerts", Auth: sessionOnly,
+		Summary: "Алерт принят в работу", Replies: ok(models.Alert{}), Errors: []int{400, 404, 409}},
+	{Method: "POST", Path: "/api/alerts/:id/resolve", ID: "resolveAlert", Tag: "alerts", Auth: sessionOnly,
+		Summary: "Закрыть алерт", Replies: ok(models.Alert{}), Errors: []int{400, 404, 409}},
+
+	// Чекпоинты, визиты, события.
+	{Method: "GET", Path: "/api/checkpoint/", ID: "listCheckpoints", Tag: "checkpoints", Auth: sessionOnly,
+		Summary: "Чекпоинты организации", Replies: ok([]models.Checkpoint{})},
+	{Method: "POST", Path: "/api/checkpoint/", ID: "createCheckpoint", Tag: "checkpoints", Auth: sessionOnly,
+		Summary: "Новый чекпоинт", Body: controllers.CheckpointRequest{}, Replies: ok(models.Checkpoint{}), Errors: []int{400}},
+	{Method: "PUT", Path: "/api/checkpoint/:id", ID: "updateCheckpoint", Tag: "checkpoints", Auth: sessionOnly,
+		Summary: "Изменение чекпоинта", Body: controllers.CheckpointRequest{}, Replies: ok(models.Checkpoint{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/checkpoint/check", ID: "checkUserInCheckpoint", Tag: "checkpoints", Auth: sessionOnly,
+		Summary: "Проверить последнюю точку пользователя в чекпоинте (асинхронно)",
+		Query: []param{
+			q("user_id", "integer", "пользователь (обязательно)"),
+			q("checkpoint_id", "integer", "чекпоинт (обязательно)"),
+		},
+		Replies: ok(controllers.MessageResponse{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/visits/", ID: "listVisits", Tag: "checkpoints", Auth: sessionOnly,
+		Summary: "Визиты по фильтрам",
+		Query: []param{
+			q("id", "integer", ""), q("user_id", "integer", ""), q("checkpoint_id", "integer", ""),
+			q("active", "boolean", "только открытые"), qFrom, qTo, qTZ,
+			q("include_outside", "boolean", "вместе с визитами вне окна отслеживания"),
+		},
+		Replies: ok([]models.Visit{}), Errors: []int{400}},
+	{Method: "POST", Path: "/api/event/publish", ID: "publishEvent", Tag: "system", Auth: sessionOnly,
+		Summary: "Событие точки в очередь визитов",
+		Body:    models.LocationEvent{}, Replies: ok(controllers.MessageResponse{}), Errors: []int{400}},
+
+	// Пользователи.
+	{Method: "POST", Path: "/api/users/", ID: "createUser", Tag: "users", Auth: sessionOnly,
+		Summary: "Новый пользователь с API-ключом и QR-кодом",
+		Body:    controllers.CreateUserRequest{}, Replies: ok(models.User{}), Errors: []int{400, 404}},
+	{Method: "PUT", Path: "/api/users/:id", ID: "updateUser", Tag: "users", Auth: sessionOnly,
+		Summary: "Имя и часовой пояс пользователя",
+		Body:    controllers.UpdateUserRequest{}, Replies: ok(models.User{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/users/:id", ID: "getUser", Tag: "users", Auth: sessionOnly,
+		Summary: "Пользователь", Replies: ok(models.User{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/users/", ID: "listUsers", Tag: "users", Auth: sessionOnly,
+		Summary: "Пользователи организации", Replies: ok([]models.User{})},
+	{Method: "GET", Path: "/api/users/qr-code", ID: "getOwnQRCode", Tag: "users", Auth: sessionOnly,
+		Summary: "Свой QR-код", Replies: ok(controllers.QRCodeResponse{})},
+	{Method: "GET", Path: "/api/users/qr-code-file", ID: "getOwnQRCodeFile", Tag: "users", Auth: sessionOnly,
+		Summary: "Свой QR-код картинкой", Replies: qrPNG, Errors: []int{404}},
+	{Method: "GET", Path: "/api/users/:id/qr-code", ID: "getUserQRCode", Tag: "users", Auth: sessionOnly,
+		Summary: "QR-код пользователя", Replies: ok(controllers.QRCodeResponse{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/users/:id/qr-code-file", ID: "getUserQRCodeFile", Tag: "users", Auth: sessionOnly,
+		Summary: "QR-код пользователя картинкой", Replies: qrPNG, Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/users/:id/health", ID: "getUserHealth", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Последний отчёт устройства и проблемы", Replies: ok(controllers.UserHealthResponse{}), Errors: []int{400, 404}},
+	{Method: "GET", Path: "/api/users/:id/reports", ID: "listUserReports", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "История отчётов (по умолчанию — 7 суток)",
+		Query:   []param{qFrom, qTo, qTZ, q("limit", "integer", ""), q("offset", "integer", "")},
+		Replies: ok(service.DeviceReportPage{}), Errors: []int{400}},
+	{Method: "GET", Path: "/api/users/:id/reports/issues-timeline", ID: "getUserIssueTimeline", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Когда появлялись и уходили проблемы устройства",
+		Query:   []param{qFrom, qTo, qTZ},
+		Replies: ok(controllers.IssueTimelineResponse{}), Errors: []int{400}},
+	{Method: "GET", Path: "/api/users/:id/reports/trends", ID: "getUserReportTrends", Tag: "device-admin", Auth: sessionOnly,
+		Summary: "Батарея, сеть и фон по времени",
+		Query:   []param{qFrom, qTo, qTZ},
+		Replies: ok(controllers.ReportTrendsResponse{}), Errors: []int{400}},
+}
+
+var (
+	locationQuery = []param{
+		q("user_id", "integer", "пользователь (по умолчанию — текущий)"),
+		q("max_age_seconds", "integer", "точка старше — 404 с location"),
+	}
+	locationReplies = []reply{
+		{Status: http.StatusOK, Body: controllers.LocationSnapshot{}},
+		{Status: http.StatusNotFound, Body: controllers.StaleLocationResponse{}, Description: "Точек нет или последняя старше max_age_seconds"},
+	}
+	retentionOrgQuery = q("organization_id", "integer", "организация (по умолчанию — своя)")
+	auditQuery        = []param{
+		q("organization_id", "integer", ""), q("actor_id", "integer", ""),
+		q("action", "string", "начало имени действия"), q("target_type", "string", ""), q("target_id", "string", ""),
+		q("result", "string", ""), q("from", "string", "RFC3339"), q("to", "string", "RFC3339"),
+	}
+	qrPNG = []reply{{Status: http.StatusOK, ContentType: "image/png", Description: "PNG с QR-кодом"}}
+)
+
+// releaseUploadForm — поля multipart-загрузки APK (POST /api/admin/releases).
+func releaseUploadForm() *openapi.Schema {
+	return &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"apk":                 {Type: "string", Format: "binary"},
+			"channel":             {Type: "string"},
+			"changelog":           {Type: "string"},
+			"force":               {Type: "boolean"},
+			"rollout_percent":     {Type: "integer"},
+			"max_failure_percent": {Type: "integer"},
+			"min_failure_samples": {Type: "integer"},
+		},
+		Required: []string{"apk"},
+	}
+}
+
+var errorDescriptions = map[int]string{
+	http.StatusBadRequest:          "Некорректный запрос",
+	http.StatusUnauthorized:        "Нужна авторизация или она недействительна",
+	http.StatusForbidden:           "Недостаточно прав",
+	http.StatusNotFound:            "Не найдено",
+	http.StatusConflict:            "Конфликт с текущим состоянием",
+	http.StatusTooManyRequests:     "Превышен лимит запросов (Retry-After)",
+	http.StatusInternalServerError: "Внутренняя ошибка",
+	http.StatusBadGateway:          "Ошибка внешнего сервиса",
+	http.StatusServiceUnavailable:  "Сервис не настроен или недоступен",
+}
+
+var pathParamPattern = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// openAPIPath — путь gin (/users/:id) в виде OpenAPI (/users/{id}).
+func openAPIPath(path string) string {
+	return pathParamPattern.ReplaceAllString(path, "{$1}")
+}
+
+// Spec собирает документ OpenAPI из apiOperations.
+func Spec() *openapi.Document {
+	schemas := openapi.NewSchemas()
+	errorSchema := schemas.For(controllers.ErrorResponse{})
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "Locator API",
+			Description: "API сервера трекинга: приём точек и отчётов с телефонов, администрирование, визиты, релизы.",
+			Version:     "1.0",
+		},
+		Tags:  apiTags,
+		Paths: make(map[string]*openapi.PathItem),
+	}
+
+	for _, op := range apiOperations {
+		o := &openapi.Operation{
+			OperationID: op.ID,
+			Summary:     op.Summary,
+			Description: op.Description,
+			Tags:        []string{op.Tag},
+			Responses:   make(map[string]*openapi.Response),
+		}
+		switch op.Auth {
+		case public:
+			o.Security = []openapi.SecurityRequirement{}
+		case deviceOrSession:
+			o.Security = []openapi.SecurityRequirement{{"apiKey": {}}, {"session": {}}}
+		case sessionOnly:
+			o.Security = []openapi.SecurityRequirement{{"session": {}}}
+		}
+
+		for _, m := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+			typ := "integer"
+			for _, s := range op.PathStrings {
+				if s == m[1] {
+					typ = "string"
+				}
+			}
+			o.Parameters = append(o.Parameters, openapi.Parameter{
+				Name: m[1], In: "path", Required: true, Schema: &openapi.Schema{Type: typ},
+			})
+		}
+		for _, p := range op.Query {
+			o.Parameters = append(o.Parameters, openapi.Parameter{
+				Name: p.Name, In: "query", Description: p.Description, Schema: &openapi.Schema{Type: p.Type},
+			})
+		}
+
+		if op.Body != nil || op.Form != nil {
+			o.RequestBody = &openapi.RequestBody{Required: true, Content: make(map[string]openapi.MediaType)}
+			if op.Body != nil {
+				o.RequestBody.Content["application/json"] = openapi.MediaType{Schema: schemas.For(op.Body)}
+			}
+			if op.Form != nil {
+				o.RequestBody.Content["multipart/form-data"] = openapi.MediaType{Schema: op.Form}
+			}
+		}
+
+		for _, r := range op.Replies {
+			resp := &openapi.Response{Description: r.Description}
+			switch {
+			case r.ContentType != "":
+				resp.Content = map[string]openapi.MediaType{
+					r.ContentType: {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+				}
+			case r.Body != nil:
+				resp.Content = map[string]openapi.MediaType{"application/json": {Schema: schemas.For(r.Body)}}
+			}
+			if resp.Description == "" {
+				resp.Description = http.StatusText(r.Status)
+			}
+			o.Responses[strconv.Itoa(r.Status)] = resp
+		}
+		for _, status := range operationErrors(op) {
+			key := strconv.Itoa(status)
+			if _, ok := o.Responses[key]; ok {
+				continue
+			}
+			o.Responses[key] = &openapi.Response{
+				Description: errorDescriptions[status],
+				Content:     map[string]openapi.MediaType{"application/json": {Schema: errorSchema}},
+			}
+		}
+
+		path := openAPIPath(op.Path)
+		item := doc.Paths[path]
+		if item == nil {
+			item = &openapi.PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(op.Method)] = o
+	}
+
+	doc.Components = openapi.Components{
+		Schemas: schemas.Components(),
+		SecuritySchemes: map[string]*openapi.SecurityScheme{
+			"apiKey":  {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "API-ключ пользователя (телефон, скрипты)"},
+			"session": {Type: "http", Scheme: "bearer", Description: "access-токен сессии сотрудника (POST /api/auth/login)"},
+		},
+	}
+	return doc
+}
+
+// operationErrors — коды ошибок маршрута вместе с общими для его авторизации.
+func operationErrors(op apiOperation) []int {
+	set := make(map[int]bool)
+	for _, status := range op.Errors {
+		set[status] = true
+	}
+	if op.Auth != public {
+		set[http.StatusUnauthorized] = true
+		set[http.StatusTooManyRequests] = true
+		set[http.StatusInternalServerError] = true
+	}
+	if op.Auth == sessionOnly {
+		set[http.StatusForbidden] = true
+	}
+	out := make([]int, 0, len(set))
+	for status := range set {
+		out = append(out, status)
+	}
+	sort.Ints(out)
+	return out
+}
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+)
+
+// SpecJSON — документ в том виде, в каком он отдаётся и хранится в api/openapi.json.
+func SpecJSON() []byte {
+	specOnce.Do(func() {
+		data, err := json.MarshalIndent(Spec(), "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		specJSON = append(data, '\n')
+	})
+	return specJSON
+}
+
+// serveOpenAPI — GET /api/openapi.json (без авторизации).
+func serveOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", SpecJSON())
+}
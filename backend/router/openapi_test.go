@@ -0,0 +1,148 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"locator/client"
+	"locator/controllers"
+	"locator/internal/openapi/clientgen"
+	"locator/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func testEngine(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	// Обработчики только регистрируются, поэтому зависимости не нужны.
+	return InitRoutes(
+		&controllers.LocationController{}, &controllers.LocationRequestController{}, &controllers.DeviceController{},
+		&controllers.DeviceConfigController{}, &controllers.AlertController{}, &controllers.NotificationController{},
+		&controllers.AppReleaseController{}, &controllers.CheckpointController{}, &controllers.VisitController{},
+		&controllers.EventController{}, &controllers.UserController{}, &controllers.AuthController{},
+		&controllers.AuditController{}, &controllers.TrackingScheduleController{}, &controllers.RetentionController{},
+		&controllers.HealthController{}, &service.UserService{}, &service.SessionService{}, &service.AuditService{}, nil,
+	)
+}
+
+func TestSpec_coversRoutes(t *testing.T) {
+	documented := make(map[string]bool)
+	for _, op := range apiOperations {
+		key := op.Method + " " + op.Path
+		if documented[key] {
+			t.Errorf("%s описан дважды", key)
+		}
+		documented[key] = true
+	}
+
+	registered := make(map[string]bool)
+	for _, r := range testEngine(t).Routes() {
+		if strings.HasPrefix(r.Path, "/static/") {
+			continue
+		}
+		key := r.Method + " " + r.Path
+		registered[key] = true
+		if !documented[key] {
+			t.Errorf("маршрут %s не описан в apiOperations", key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			t.Errorf("%s описан, но не зарегистрирован", key)
+		}
+	}
+}
+
+// Снимок спецификации и клиент в репозитории должны совпадать с тем, что собирается
+// из кода; расхождение — забытый go generate ./router.
+func TestSpec_generatedFilesUpToDate(t *testing.T) {
+	spec, err := os.ReadFile("../api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(spec, SpecJSON()) {
+		t.Error("api/openapi.json устарел: выполните go generate ./router")
+	}
+
+	want, err := clientgen.Generate(Spec(), "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("../client/client_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("client/client_gen.go устарел: выполните go generate ./router")
+	}
+}
+
+func TestSpec_operationIDsUnique(t *testing.T) {
+	seen := make(map[string]string)
+	for _, op := range apiOperations {
+		if prev, dup := seen[op.ID]; dup {
+			t.Errorf("operationId %s у %s и %s %s", op.ID, prev, op.Method, op.Path)
+		}
+		seen[op.ID] = op.Method + " " + op.Path
+	}
+}
+
+func TestSpec_refsResolve(t *testing.T) {
+	doc := Spec()
+	var missing []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := doc.Components.Schemas[name]; !ok {
+					missing = append(missing, ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	var raw any
+	if err := json.Unmarshal(SpecJSON(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	walk(raw)
+	if len(missing) > 0 {
+		t.Fatalf("ссылки без схемы: %v", missing)
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	srv := httptest.NewServer(testEngine(t))
+	defer srv.Close()
+	api := client.New(srv.URL)
+
+	doc, err := api.GetOpenAPI(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, _ := doc["paths"].(map[string]any)
+	if doc["openapi"] != "3.0.3" || paths["/api/users/{id}"] == nil {
+		t.Fatalf("unexpected document: openapi=%v, paths=%d", doc["openapi"], len(paths))
+	}
+
+	// Ошибка авторизации разбирается в APIError.
+	_, err = api.GetCurrentUser(context.Background())
+	if apiErr, ok := err.(*client.APIError); !ok || apiErr.Status != http.StatusUnauthorized || apiErr.Message == "" {
+		t.Fatalf("users/me без ключа: %v", err)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"strings"
 
 	"locator/controllers"
+	"locator/internal/ratelimit"
 	"locator/middleware"
+	"locator/models"
 	"locator/service"
 
 	"github.com/gin-gonic/gin"
@@ -14,18 +16,32 @@ func InitRoutes(
 	locationController *controllers.LocationController,
 	locationRequestController *controllers.LocationRequestController,
 	deviceController *controllers.DeviceController,
+	deviceConfigController *controllers.DeviceConfigController,
+	alertController *controllers.AlertController,
+	notificationController *controllers.NotificationController,
 	appReleaseController *controllers.AppReleaseController,
 	checkpointController *controllers.CheckpointController,
 	visitController *controllers.VisitController,
 	eventController *controllers.EventController,
 	userController *controllers.UserController,
+	authController *controllers.AuthController,
+	auditController *controllers.AuditController,
+	trackingScheduleController *controllers.TrackingScheduleController,
+	retentionController *controllers.RetentionController,
+	healthController *controllers.HealthController,
 	userService *service.UserService,
+	sessionService *service.SessionService,
+	auditService *service.AuditService,
+	limiter *ratelimit.Limiter,
 ) *gin.Engine {
-	router := gin.Default()
+	// Вместо текстового журнала gin.Default — JSON-запись на запрос с request_id.
+	router := gin.New()
+	router.Use(gin.Recovery(), middleware.RequestID(), middleware.AccessLog(), middleware.Metrics())
 
-	router.GET("/healthz", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// /healthz — прежнее имя /livez для существующих проверок.
+	router.GET("/healthz", healthController.Livez)
+	router.GET("/livez", healthController.Livez)
+	router.GET("/readyz", healthController.Readyz)
 
 	router.Use(func(c *gin.Context) {
 		if strings.HasPrefix(c.Request.URL.Path, "/static/qrcode/") {
@@ -40,86 +56,211 @@ func InitRoutes(
 
 	// Базовый маршрут для API, без middleware
 	apiGroup := router.Group("/api")
+	apiGroup.GET("/openapi.json", serveOpenAPI)
 	apiGroup.GET("/app/release/latest", appReleaseController.GetLatestRelease)
 
-	// Маршруты, доступные всем авторизованным пользователям
+	// Лимиты частоты: authGuard блокирует IP после серии неудачных входов,
+	// limit(класс) ограничивает запросы по ключу/сессии (см. internal/ratelimit).
+	authGuard := middleware.AuthGuard(limiter)
+	limit := func(class string) gin.HandlerFunc { return middleware.RateLimit(limiter, class) }
+
+	// Вход сотрудников в веб-интерфейс (без авторизации — выдают сессию)
+	loginGroup := apiGroup.Group("/auth")
+	loginGroup.Use(authGuard, limit(ratelimit.ClassAuth))
+	{
+		loginGroup.POST("/login", authController.PostLogin)
+		loginGroup.POST("/login-link", authController.PostLoginLink)
+		loginGroup.POST("/refresh", authController.PostRefresh)
+	}
+
+	// Маршруты, доступные всем авторизованным пользователям:
+	// устройству по API-ключу, сотруднику по сессии
 	basicAuthGroup := apiGroup.Group("")
-	basicAuthGroup.Use(middleware.BasicAuthMiddleware(userService))
+	basicAuthGroup.Use(authGuard, middleware.UserAuthMiddleware(sessionService, userService))
 	{
+		// Приём данных с устройства — отдельный, более щедрый лимит
+		ingestGroup := basicAuthGroup.Group("")
+		ingestGroup.Use(limit(ratelimit.ClassIngest))
+		{
+			ingestGroup.POST("/location", locationController.PostLocation)
+			ingestGroup.POST("/device/report", deviceController.PostDeviceReport)
+			ingestGroup.POST("/device/command/ack", deviceController.PostCommandAck)
+		}
+
+		basicAuthGroup.Use(limit(ratelimit.ClassDevice))
+
 		// Информация о текущем пользователе
 		basicAuthGroup.GET("/users/me", userController.GetCurrentUser)
 
-		// Разрешаем всем пользователям создавать и получать локации
-		basicAuthGroup.POST("/location", locationController.PostLocation)
+		// Разрешаем всем пользователям получать локации
 		basicAuthGroup.GET("/location/single", locationController.GetLocation)
 		basicAuthGroup.GET("/location/current", locationController.GetLocation)
 
 		// Мобильный коннектор и legacy poll location request
 		basicAuthGroup.GET("/device/poll", deviceController.PollDevice)
-		basicAuthGroup.POST("/device/report", deviceController.PostDeviceReport)
-		basicAuthGroup.POST("/device/command/ack", deviceController.PostCommandAck)
 		basicAuthGroup.GET("/location/request", locationRequestController.PollLocationRequest)
+
+		// Подписки на уведомления (свои; админ — любые)
+		basicAuthGroup.GET("/notifications/subscriptions", notificationController.GetSubscriptions)
+		basicAuthGroup.POST("/notifications/subscriptions", notificationController.PostSubscription)
+		basicAuthGroup.PUT("/notifications/subscriptions/:id", notificationController.PutSubscription)
+		basicAuthGroup.DELETE("/notifications/subscriptions/:id", notificationController.DeleteSubscription)
+		basicAuthGroup.POST("/notifications/subscriptions/:id/test", notificationController.PostTestSubscription)
+		basicAuthGroup.GET("/notifications/deliveries", notificationController.GetDeliveries)
 	}
 
-	// Остальные маршруты API требуют полной аутентификации с проверкой на админа
+	// Остальные маршруты API доступны только сотрудникам (роль не device) с сессией
+	// входа; API-ключи устройств здесь не принимаются.
+	// Право на каждый маршрут объявлено здесь через can(...); inScope ограничивает
+	// сотрудника с группой пользователями своей группы (по :id или :user_id).
+	// Все изменяющие запросы (и отказы в них) пишутся в журнал аудита; auditRead —
+	// чтение секретов (QR-код с API-ключом), которое тоже пишется в журнал.
+	can := middleware.RequirePermission
+	inScope := middleware.RequireUserInScope("id")
+	auditRead := middleware.AuditAccess(auditService)
 	protectedApiGroup := apiGroup.Group("")
-	protectedApiGroup.Use(middleware.APIKeyAuthMiddleware(userService))
+	protectedApiGroup.Use(
+		authGuard,
+		middleware.SessionAuthMiddleware(sessionService, userService),
+		limit(ratelimit.ClassAdmin),
+		middleware.Audit(auditService),
+	)
 	{
+		// Своя сессия: выход, список входов, пароль и второй фактор.
+		authGroup := protectedApiGroup.Group("/auth")
+		{
+			authGroup.POST("/logout", authController.PostLogout)
+			authGroup.GET("/sessions", authController.GetSessions)
+			authGroup.DELETE("/sessions/:id", authController.DeleteSession)
+			authGroup.PUT("/password", authController.PutPassword)
+			authGroup.POST("/totp/setup", authController.PostTOTPSetup)
+			authGroup.POST("/totp/enable", authController.PostTOTPEnable)
+			authGroup.POST("/totp/disable", authController.PostTOTPDisable)
+		}
+
 		locationGroup := protectedApiGroup.Group("/location")
 		{
-			locationGroup.GET("/match-route", locationController.GetMatchedRoute)
-			locationGroup.GET("/", locationController.GetLocations)
-			locationGroup.POST("/request", locationRequestController.PostLocationRequest)
-			locationGroup.GET("/request/:request_id", locationRequestController.GetLocationRequestStatus)
+			locationGroup.GET("/match-route", can(models.PermTrackingRead), locationController.GetMatchedRoute)
+			locationGroup.GET("/", can(models.PermTrackingRead), locationController.GetLocations)
+			locationGroup.POST("/request", can(models.PermDevicesCommand), locationRequestController.PostLocationRequest)
+			locationGroup.GET("/request/:request_id", can(models.PermTrackingRead), locationRequestController.GetLocationRequestStatus)
 		}
 
 		adminGroup := protectedApiGroup.Group("/admin")
 		{
-			adminGroup.GET("/devices/status", deviceController.GetAdminDevicesStatus)
-			adminGroup.POST("/users/:id/wake", deviceController.PostAdminWakeDevice)
-			adminGroup.POST("/users/:id/enable-location", deviceController.PostAdminEnableLocation)
-			adminGroup.POST("/users/:id/commands", deviceController.PostAdminUserCommand)
-			adminGroup.POST("/users/:id/device/config", deviceController.PostAdminUserDeviceConfig)
-			adminGroup.POST("/users/:id/regenerate-qr", userController.PostRegenerateUserQR)
-			adminGroup.POST("/releases/publish-update/:user_id", deviceController.PostPublishAppUpdate)
-			adminGroup.POST("/releases/sync-manifest", appReleaseController.PostSyncReleaseManifest)
-			adminGroup.POST("/locations/backfill-captured-at", locationController.PostBackfillCapturedAt)
+			adminGroup.GET("/devices/status", can(models.PermTrackingRead), deviceController.GetAdminDevicesStatus)
+			adminGroup.POST("/users/:id/wake", can(models.PermDevicesCommand), inScope, deviceController.PostAdminWakeDevice)
+			adminGroup.POST("/users/:id/enable-location", can(models.PermDevicesCommand), inScope, deviceController.PostAdminEnableLocation)
+			adminGroup.POST("/users/:id/commands", can(models.PermDevicesCommand), inScope, deviceController.PostAdminUserCommand)
+			adminGroup.GET("/users/:id/commands/:command_id", can(models.PermDevicesCommand), inScope, deviceController.GetAdminUserCommand)
+			adminGroup.POST("/users/:id/device/config", can(models.PermDevicesConfig), inScope, deviceController.PostAdminUserDeviceConfig)
+			adminGroup.GET("/users/:id/device/desired-config", can(models.PermDevicesConfig), inScope, deviceConfigController.GetUserDesiredConfig)
+			adminGroup.PUT("/users/:id/device/desired-config", can(models.PermDevicesConfig), inScope, deviceConfigController.PutUserDesiredConfig)
+			adminGroup.GET("/users/:id/device/config-drift", can(models.PermDevicesConfig), inScope, deviceConfigController.GetUserConfigDrift)
+			adminGroup.GET("/users/:id/tracking-schedule", can(models.PermDevicesConfig), inScope, trackingScheduleController.GetUserTrackingSchedule)
+			adminGroup.PUT("/users/:id/tracking-schedule", can(models.PermDevicesConfig), inScope, trackingScheduleController.PutUserTrackingSchedule)
+			adminGroup.DELETE("/users/:id/tracking-schedule", can(models.PermDevicesConfig), inScope, trackingScheduleController.DeleteUserTrackingSchedule)
+			adminGroup.POST("/users/:id/tracking-consent", can(models.PermDevicesConfig), inScope, trackingScheduleController.PostUserTrackingConsent)
+			adminGroup.GET("/devices/config-drift", can(models.PermDevicesConfig), deviceConfigController.GetDevicesConfigDrift)
+			adminGroup.GET("/device-config/profiles", can(models.PermDevicesConfig), deviceConfigController.GetProfiles)
+			adminGroup.POST("/device-config/profiles", can(models.PermDevicesConfig), deviceConfigController.PostProfile)
+			adminGroup.PUT("/device-config/profiles/:id", can(models.PermDevicesConfig), deviceConfigController.PutProfile)
+			adminGroup.GET("/alert-rules", can(models.PermAlertsManage), alertController.GetRules)
+			adminGroup.POST("/alert-rules", can(models.PermAlertsManage), alertController.PostRule)
+			adminGroup.PUT("/alert-rules/:id", can(models.PermAlertsManage), alertController.PutRule)
+			adminGroup.DELETE("/alert-rules/:id", can(models.PermAlertsManage), alertController.DeleteRule)
+			adminGroup.POST("/alerts/evaluate", can(models.PermAlertsManage), alertController.PostEvaluate)
+			adminGroup.POST("/users/:id/regenerate-qr", can(models.PermUsersManage), inScope, userController.PostRegenerateUserQR)
+			adminGroup.PUT("/users/:id/access", can(models.PermUsersManage), inScope, userController.PutUserAccess)
+			adminGroup.GET("/users/:id/api-keys", can(models.PermUsersManage), inScope, userController.GetUserAPIKeys)
+			adminGroup.POST("/users/:id/api-keys", can(models.PermUsersManage), inScope, userController.PostUserAPIKey)
+			adminGroup.DELETE("/users/:id/api-keys/:key_id", can(models.PermUsersManage), inScope, userController.DeleteUserAPIKey)
+			adminGroup.PUT("/users/:id/credentials", can(models.PermUsersManage), inScope, authController.PutUserCredentials)
+			adminGroup.POST("/users/:id/login-link", can(models.PermUsersManage), inScope, authController.PostUserLoginLink)
+			adminGroup.POST("/users/:id/sessions/revoke", can(models.PermUsersManage), inScope, authController.PostRevokeUserSessions)
+			adminGroup.GET("/groups", can(models.PermUsersManage), userController.GetGroups)
+			adminGroup.POST("/groups", can(models.PermUsersManage), userController.PostGroup)
+			adminGroup.GET("/groups/:id/members", can(models.PermUsersManage), userController.GetGroupMembers)
+			adminGroup.POST("/groups/:id/members", can(models.PermUsersManage), userController.PostGroupMember)
+			adminGroup.DELETE("/groups/:id/members/:user_id", can(models.PermUsersManage), userController.DeleteGroupMember)
+			// Организации — только super_admin организации по умолчанию (проверяется сервисом).
+			adminGroup.GET("/organizations", can(models.PermSystem), userController.GetOrganizations)
+			adminGroup.POST("/organizations", can(models.PermSystem), userController.PostOrganization)
+			// Пояс организации — её администратору, любой — администратору платформы (проверяется сервисом).
+			adminGroup.PUT("/organizations/:id/timezone", can(models.PermUsersManage), userController.PutOrganizationTimezone)
+			adminGroup.POST("/releases/publish-update/:user_id", can(models.PermReleasesManage), middleware.RequireUserInScope("user_id"), deviceController.PostPublishAppUpdate)
+			adminGroup.POST("/releases/sync-manifest", can(models.PermReleasesManage), appReleaseController.PostSyncReleaseManifest)
+			adminGroup.GET("/releases", can(models.PermReleasesManage), appReleaseController.GetReleases)
+			adminGroup.POST("/releases", can(models.PermReleasesManage), appReleaseController.PostRelease)
+			adminGroup.PUT("/releases/:id", can(models.PermReleasesManage), appReleaseController.PutRelease)
+			adminGroup.POST("/releases/:id/pause", can(models.PermReleasesManage), appReleaseController.PostPauseRelease)
+			adminGroup.POST("/releases/:id/resume", can(models.PermReleasesManage), appReleaseController.PostResumeRelease)
+			adminGroup.POST("/releases/:id/archive", can(models.PermReleasesManage), appReleaseController.PostArchiveRelease)
+			adminGroup.GET("/releases/:id/stats", can(models.PermReleasesManage), appReleaseController.GetReleaseStats)
+			adminGroup.GET("/release-channels", can(models.PermReleasesManage), appReleaseController.GetReleaseChannels)
+			adminGroup.GET("/release-channels/members", can(models.PermReleasesManage), appReleaseController.GetReleaseChannelMembers)
+			adminGroup.PUT("/release-channels/:name", can(models.PermReleasesManage), appReleaseController.PutReleaseChannel)
+			adminGroup.PUT("/users/:id/release-channel", can(models.PermReleasesManage), inScope, appReleaseController.PutUserReleaseChannel)
+			adminGroup.POST("/locations/backfill-captured-at", can(models.PermSystem), locationController.PostBackfillCapturedAt)
+			// Политика хранения — своя организация (проверяется сервисом), проходы — только платформа.
+			adminGroup.GET("/retention/policy", can(models.PermUsersManage), retentionController.GetPolicy)
+			adminGroup.PUT("/retention/policy", can(models.PermUsersManage), retentionController.PutPolicy)
+			adminGroup.DELETE("/retention/policy", can(models.PermUsersManage), retentionController.DeletePolicy)
+			adminGroup.GET("/retention/runs", can(models.PermSystem), retentionController.GetRuns)
+			adminGroup.POST("/retention/runs", can(models.PermSystem), retentionController.PostRun)
+			adminGroup.GET("/retention/runs/:id", can(models.PermSystem), retentionController.GetRun)
+			adminGroup.GET("/audit", can(models.PermAuditRead), auditController.GetAudit)
+			adminGroup.GET("/audit/export", can(models.PermAuditRead), auditController.GetAuditExport)
+			adminGroup.GET("/rate-limits", can(models.PermSystem), func(c *gin.Context) {
+				c.JSON(200, RateLimitsResponse{Enabled: limiter != nil, Counters: ratelimit.Snapshot()})
+			})
+		}
+
+		// Алерты по устройствам и трекингу.
+		alertGroup := protectedApiGroup.Group("/alerts")
+		{
+			alertGroup.GET("", can(models.PermTrackingRead), alertController.GetAlerts)
+			alertGroup.POST("/:id/ack", can(models.PermAlertsAck), alertController.PostAcknowledge)
+			alertGroup.POST("/:id/resolve", can(models.PermAlertsAck), alertController.PostResolve)
 		}
 
 		// Группа маршрутов для работы с чекпоинтами.
 		checkpointGroup := protectedApiGroup.Group("/checkpoint")
 		{
-			checkpointGroup.GET("/", checkpointController.GetCheckpoints)
-			checkpointGroup.POST("/", checkpointController.PostCheckpoint)
-			checkpointGroup.PUT("/:id", checkpointController.UpdateCheckpoint)
-			checkpointGroup.GET("/check", checkpointController.CheckUserInCheckpoint)
+			checkpointGroup.GET("/", can(models.PermTrackingRead), checkpointController.GetCheckpoints)
+			checkpointGroup.POST("/", can(models.PermCheckpointsWrite), checkpointController.PostCheckpoint)
+			checkpointGroup.PUT("/:id", can(models.PermCheckpointsWrite), checkpointController.UpdateCheckpoint)
+			checkpointGroup.GET("/check", can(models.PermTrackingRead), checkpointController.CheckUserInCheckpoint)
 		}
 
 		// Группа маршрутов для работы с визитами.
 		visitGroup := protectedApiGroup.Group("/visits")
 		{
 			// Эндпоинт для получения визитов с фильтром.
-			visitGroup.GET("/", visitController.GetVisitsByFilters)
+			visitGroup.GET("/", can(models.PermTrackingRead), visitController.GetVisitsByFilters)
 		}
 
 		// Группа маршрутов для публикации событий (например, в RabbitMQ).
 		eventGroup := protectedApiGroup.Group("/event")
 		{
-			eventGroup.POST("/publish", eventController.PublishEvent)
+			eventGroup.POST("/publish", can(models.PermSystem), eventController.PublishEvent)
 		}
 
 		// Группа маршрутов для работы с пользователями.
 		userGroup := protectedApiGroup.Group("/users")
 		{
-			userGroup.POST("/", userController.CreateUser)
-			userGroup.PUT("/:id", userController.UpdateUser)
-			userGroup.GET("/:id", userController.GetUser)
-			userGroup.GET("/", userController.GetAllUsers)
-			userGroup.GET("/qr-code", userController.GetQRCode)
-			userGroup.GET("/qr-code-file", userController.GetQRCodeFile)
-			userGroup.GET("/:id/qr-code", userController.GetUserQRCode)
-			userGroup.GET("/:id/qr-code-file", userController.GetUserQRCodeFile)
-			userGroup.GET("/:id/health", deviceController.GetUserHealth)
+			userGroup.POST("/", can(models.PermUsersManage), userController.CreateUser)
+			userGroup.PUT("/:id", can(models.PermUsersManage), inScope, userController.UpdateUser)
+			userGroup.GET("/:id", can(models.PermTrackingRead), inScope, userController.GetUser)
+			userGroup.GET("/", can(models.PermTrackingRead), userController.GetAllUsers)
+			userGroup.GET("/qr-code", auditRead, userController.GetQRCode)
+			userGroup.GET("/qr-code-file", auditRead, userController.GetQRCodeFile)
+			userGroup.GET("/:id/qr-code", auditRead, can(models.PermUsersManage), inScope, userController.GetUserQRCode)
+			userGroup.GET("/:id/qr-code-file", auditRead, can(models.PermUsersManage), inScope, userController.GetUserQRCodeFile)
+			userGroup.GET("/:id/health", can(models.PermTrackingRead), inScope, deviceController.GetUserHealth)
+			userGroup.GET("/:id/reports", can(models.PermTrackingRead), inScope, deviceController.GetUserReports)
+			userGroup.GET("/:id/reports/issues-timeline", can(models.PermTrackingRead), inScope, deviceController.GetUserIssueTimeline)
+			userGroup.GET("/:id/reports/trends", can(models.PermTrackingRead), inScope, deviceController.GetUserReportTrends)
 		}
 	}
 
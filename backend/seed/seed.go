@@ -2,23 +2,38 @@ package seed
 
 import (
 	"errors"
-	"log"
-	"os"
+	"log/slog"
 
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"locator/config"
 	"locator/dao"
 	"locator/models"
 	"locator/service"
 )
 
+// DefaultOrganization создаёт организацию по умолчанию (ID 1), к которой относятся
+// все данные, созданные до появления организаций.
+func DefaultOrganization(db *gorm.DB) {
+	org := models.Organization{ID: models.DefaultOrganizationID, Name: "default"}
+	if err := db.Where("id = ?", org.ID).FirstOrCreate(&org).Error; err != nil {
+		slog.Error("Ошибка создания организации по умолчанию", "error", err)
+		return
+	}
+	// ID задан явно — сдвигаем последовательность, иначе следующая организация получит ID 1.
+	if err := db.Exec("SELECT setval(pg_get_serial_sequence('organizations', 'id'), GREATEST((SELECT MAX(id) FROM organizations), 1))").Error; err != nil {
+		slog.Error("Ошибка обновления последовательности организаций", "error", err)
+	}
+}
+
 // DefaultAdmin DefaultAdminSeed создаёт дефолтного администратора (если его нет в базе)
 // с использованием UserService, что обеспечивает генерацию QR кода и корректное хэширование API ключа.
-func DefaultAdmin(db *gorm.DB) {
-	// Читаем данные из переменных окружения
-	defaultName := os.Getenv("DEFAULT_ADMIN_NAME")
-	defaultAPIKey := os.Getenv("DEFAULT_ADMIN_API_KEY")
+// baseURL попадает в QR-код администратора.
+func DefaultAdmin(db *gorm.DB, cfg config.AdminConfig, baseURL string) {
+	defaultName := cfg.Name
+	defaultAPIKey := cfg.APIKey
 	if defaultName == "" || defaultAPIKey == "" {
-		log.Println("Данные дефолтного администратора (DEFAULT_ADMIN_NAME или DEFAULT_ADMIN_API_KEY) не заданы в переменных окружения")
+		slog.Warn("Данные дефолтного администратора (DEFAULT_ADMIN_NAME или DEFAULT_ADMIN_API_KEY) не заданы в переменных окружения")
 		return
 	}
 
@@ -26,27 +41,61 @@ func DefaultAdmin(db *gorm.DB) {
 	var admin models.User
 	err := db.Where("name = ? AND is_admin = ?", defaultName, true).First(&admin).Error
 	if err == nil {
-		log.Println("Дефолтный администратор уже существует")
+		slog.Info("Дефолтный администратор уже существует", "user_id", admin.ID)
+		defaultAdminCredentials(db, &admin, cfg)
 		return
 	}
 
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
-		log.Printf("Ошибка при поиске дефолтного администратора: %v", err)
+		slog.Error("Ошибка поиска дефолтного администратора", "error", err)
 		return
 	}
 
 	// Инициализируем DAO и создаём экземпляр UserService
 	userDAO := dao.NewUserDAO(db)
-	userService := service.NewUserService(userDAO)
+	userService := service.NewUserService(userDAO, dao.NewAPIKeyDAO(db))
+	userService.BaseURL = baseURL
 
 	// Создаём администратора через UserService с явным указанием API ключа
-	user, plainKey, err := userService.CreateUser(defaultName, true, defaultAPIKey)
+	user, _, err := userService.CreateUser(defaultName, true, defaultAPIKey)
 	if err != nil {
-		log.Printf("Ошибка создания дефолтного администратора: %v", err)
+		slog.Error("Ошибка создания дефолтного администратора", "error", err)
 		return
 	}
 
-	// Поле QRCode в модели обновится внутри UserService с публичной ссылкой на QR картинку,
-	// а в логах мы получаем также plaintext API ключ (он доступен только при создании).
-	log.Printf("Дефолтный администратор успешно создан: %s (ID: %d). Plain API ключ: %s", user.Name, user.ID, plainKey)
+	// Ключ в журнал не пишется: он и так известен из DEFAULT_ADMIN_API_KEY.
+	slog.Info("Дефолтный администратор создан", "user_id", user.ID, "name", user.Name)
+	defaultAdminCredentials(db, user, cfg)
+}
+
+// defaultAdminCredentials задаёт дефолтному администратору логин и пароль для входа
+// в веб-интерфейс (DEFAULT_ADMIN_USERNAME, по умолчанию — DEFAULT_ADMIN_NAME, и
+// DEFAULT_ADMIN_PASSWORD). Уже заданный пароль не перезаписывается — его меняют в интерфейсе.
+func defaultAdminCredentials(db *gorm.DB, admin *models.User, cfg config.AdminConfig) {
+	password := cfg.Password
+	if password == "" {
+		if admin.PasswordHash == "" {
+			slog.Warn("DEFAULT_ADMIN_PASSWORD не задан: вход администратора в веб-интерфейс по паролю недоступен")
+		}
+		return
+	}
+	if admin.PasswordHash != "" {
+		return
+	}
+	username := cfg.Username
+	if username == "" {
+		username = admin.Name
+	}
+	username = service.NormalizeUsername(username)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		slog.Error("Ошибка хеширования пароля дефолтного администратора", "error", err)
+		return
+	}
+	if err := db.Model(&models.User{}).Where("id = ?", admin.ID).
+		Updates(map[string]interface{}{"username": username, "password_hash": string(hash)}).Error; err != nil {
+		slog.Error("Ошибка сохранения учётных данных дефолтного администратора", "error", err)
+		return
+	}
+	slog.Info("Дефолтному администратору задан логин для входа", "username", username)
 }
@@ -0,0 +1,646 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"locator/dao"
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAlertNotFound        = errors.New("alert not found")
+	ErrAlertAlreadyResolved = errors.New("alert already resolved")
+	ErrAlertRuleNotFound    = errors.New("alert rule not found")
+	ErrAlertRuleInvalid     = errors.New("invalid alert rule")
+)
+
+const (
+	alertDefaultNoLocationMinutes     = 30
+	alertDefaultRequestExpiredMinutes = 60
+	alertDefaultBatteryPercent        = 15
+	alertDefaultEvaluationInterval    = time.Minute
+	alertListDefaultLimit             = 100
+	alertListMaxLimit                 = 500
+)
+
+// alertLocationRequestSource — просроченные on-demand запросы (LocationRequestDAO).
+type alertLocationRequestSource interface {
+	ExpirePendingOlderThan(cutoff time.Time) error
+	GetExpiredSince(since time.Time) ([]models.LocationRequest, error)
+}
+
+// alertTrackingPauseSource — пользователи на паузе по графику приватности (TrackingScheduleService).
+type alertTrackingPauseSource interface {
+	PausedUsers(now time.Time) (map[int]bool, error)
+}
+
+// AlertEvaluation — итог одного прохода движка.
+type AlertEvaluation struct {
+	Opened   int `json:"opened"`
+	Updated  int `json:"updated"`
+	Resolved int `json:"resolved"`
+}
+
+// alertFiring — сработавшее условие правила для конкретного пользователя.
+type alertFiring struct {
+	Rule    *models.AlertRule
+	UserID  int
+	Message string
+}
+
+// alertSnapshot — состояние устройств на момент проверки (несколько пакетных SQL).
+type alertSnapshot struct {
+	UserIDs []int
+	// UserOrganization — организация пользователя: правило без user_id проверяется
+	// только для пользователей своей организации.
+	UserOrganization map[int]int
+	LocationAge      map[int]int64
+	Reports          map[int]dao.LatestDeviceReportRow
+	ExpiredRequests  map[int][]time.Time
+	// TrackingPaused — отслеживание приостановлено графиком: отсутствие координат не алерт.
+	TrackingPaused map[int]bool
+}
+
+// AlertService — движок алертов: периодически и по событиям проверяет правила,
+// дедуплицирует срабатывания и автоматически закрывает алерты, когда условие ушло.
+type AlertService struct {
+	DAO       alertRepository
+	Users     userRepository
+	Locations locationAgeRepository
+	Reports   latestReportRepository
+	Requests  alertLocationRequestSource
+	// Notifier — доставка alert.opened / alert.resolved (nil — без уведомлений).
+	Notifier eventNotifier
+	// Privacy — графики приватности (nil — паузы отслеживания не учитываются).
+	Privacy alertTrackingPauseSource
+	// Timezones — пояс организации для рабочего окна правил (nil — location).
+	Timezones organizationTimezoneSource
+	// Clock — текущее время (nil — системное).
+	Clock Clock
+
+	location *time.Location
+	mu       sync.Mutex
+	trigger  chan struct{}
+}
+
+func NewAlertService(
+	dao alertRepository,
+	users userRepository,
+	locations locationAgeRepository,
+	reports latestReportRepository,
+	requests alertLocationRequestSource,
+) *AlertService {
+	return &AlertService{
+		DAO:       dao,
+		Users:     users,
+		Locations: locations,
+		Reports:   reports,
+		Requests:  requests,
+		location:  defaultTimezone(),
+		trigger:   make(chan struct{}, 1),
+	}
+}
+
+// Run — фоновый цикл: проверка раз в interval и дополнительно по Trigger().
+func (svc *AlertService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = alertDefaultEvaluationInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-svc.trigger:
+		}
+		if _, err := svc.Evaluate(); err != nil {
+			slog.Error("Ошибка проверки правил алертов", "error", err)
+		}
+	}
+}
+
+// Trigger просит фоновый цикл выполнить внеочередную проверку (не блокирует).
+func (svc *AlertService) Trigger() {
+	if svc == nil {
+		return
+	}
+	select {
+	case svc.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Evaluate проверяет все включённые правила на текущий момент (Clock) и синхронизирует алерты.
+func (svc *AlertService) Evaluate() (*AlertEvaluation, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	now := clockNow(svc.Clock)
+
+	rules, err := svc.DAO.GetAllRules()
+	if err != nil {
+		return nil, err
+	}
+	snap, err := svc.loadSnapshot(rules, now)
+	if err != nil {
+		return nil, err
+	}
+	firings, evaluated := evaluateAlertRules(rules, snap, now, svc.organizationLocations())
+
+	active, err := svc.DAO.GetActiveAlerts()
+	if err != nil {
+		return nil, err
+	}
+	activeByKey := make(map[string]*models.Alert, len(active))
+	for i := range active {
+		activeByKey[active[i].DedupKey] = &active[i]
+	}
+	enabledRules := make(map[int]bool, len(rules))
+	for _, rule := range rules {
+		enabledRules[rule.ID] = rule.Enabled
+	}
+
+	result := &AlertEvaluation{}
+	firingKeys := make(map[string]struct{}, len(firings))
+	for _, f := range firings {
+		key := alertDedupKey(f.Rule.ID, f.UserID)
+		firingKeys[key] = struct{}{}
+		if existing, ok := activeByKey[key]; ok {
+			existing.LastSeenAt = now
+			existing.Message = f.Message
+			existing.Severity = f.Rule.Severity
+			if err := svc.DAO.UpdateAlert(existing); err != nil {
+				return nil, err
+			}
+			result.Updated++
+			continue
+		}
+		alert := &models.Alert{
+			RuleID:      f.Rule.ID,
+			UserID:      f.UserID,
+			Type:        f.Rule.Type,
+			Severity:    f.Rule.Severity,
+			Status:      models.AlertStatusOpen,
+			DedupKey:    key,
+			Message:     f.Message,
+			FirstSeenAt: now,
+			LastSeenAt:  now,
+		}
+		if err := svc.DAO.CreateAlert(alert); err != nil {
+			return nil, err
+		}
+		svc.notify(models.NotificationEventAlertOpened, alert, now)
+		result.Opened++
+	}
+
+	for key, alert := range activeByKey {
+		if _, still := firingKeys[key]; still {
+			continue
+		}
+		// Вне рабочего окна правило не проверялось — состояние алерта не трогаем.
+		if enabledRules[alert.RuleID] && !evaluated[alert.RuleID] {
+			continue
+		}
+		resolvedAt := now
+		alert.Status = models.AlertStatusResolved
+		alert.ResolvedAt = &resolvedAt
+		alert.ResolvedBy = nil
+		if err := svc.DAO.UpdateAlert(alert); err != nil {
+			return nil, err
+		}
+		svc.notify(models.NotificationEventAlertResolved, alert, now)
+		result.Resolved++
+	}
+
+	if result.Opened > 0 || result.Resolved > 0 {
+		slog.Info("Алерты проверены", "opened", result.Opened, "resolved", result.Resolved, "updated", result.Updated)
+	}
+	return result, nil
+}
+
+func (svc *AlertService) loadSnapshot(rules []models.AlertRule, now time.Time) (*alertSnapshot, error) {
+	snap := &alertSnapshot{
+		UserOrganization: make(map[int]int),
+		LocationAge:      make(map[int]int64),
+		Reports:          make(map[int]dao.LatestDeviceReportRow),
+		ExpiredRequests:  make(map[int][]time.Time),
+	}
+
+	users, err := svc.Users.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if !u.IsAdmin {
+			snap.UserIDs = append(snap.UserIDs, u.ID)
+			snap.UserOrganization[u.ID] = organizationOrDefault(u.OrganizationID)
+		}
+	}
+
+	if svc.Locations != nil {
+		ages, err := svc.Locations.GetLatestAgePerUser(0)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range ages {
+			snap.LocationAge[row.UserID] = row.AgeSeconds
+		}
+	}
+
+	if svc.Reports != nil {
+		reports, err := svc.Reports.GetLatestPerUser(0)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range reports {
+			snap.Reports[row.UserID] = row
+		}
+	}
+
+	if svc.Privacy != nil {
+		paused, err := svc.Privacy.PausedUsers(now)
+		if err != nil {
+			return nil, err
+		}
+		snap.TrackingPaused = paused
+	}
+
+	lookback := 0
+	for _, rule := range rules {
+		if rule.Enabled && rule.Type == models.AlertRuleTypeLocationRequestExpired {
+			if m := ruleMinutes(&rule, alertDefaultRequestExpiredMinutes); m > lookback {
+				lookback = m
+			}
+		}
+	}
+	if lookback > 0 && svc.Requests != nil {
+		// Запросы истекают лениво (при poll) — телефон, который не опрашивает сервер,
+		// иначе держал бы их в pending вечно.
+		if err := svc.Requests.ExpirePendingOlderThan(now.Add(-locationRequestPendingTTL)); err != nil {
+			return nil, err
+		}
+		expired, err := svc.Requests.GetExpiredSince(now.Add(-time.Duration(lookback) * time.Minute))
+		if err != nil {
+			return nil, err
+		}
+		for _, req := range expired {
+			snap.ExpiredRequests[req.UserID] = append(snap.ExpiredRequests[req.UserID], req.CreatedAt)
+		}
+	}
+	return snap, nil
+}
+
+// organizationLocations — пояс организации правила; в пределах одной проверки
+// каждая организация читается один раз.
+func (svc *AlertService) organizationLocations() func(organizationID int) *time.Location {
+	cache := make(map[int]*time.Location)
+	return func(organizationID int) *time.Location {
+		if svc.Timezones == nil {
+			return svc.location
+		}
+		loc, ok := cache[organizationID]
+		if !ok {
+			loc = svc.Timezones.OrganizationLocation(organizationID)
+			cache[organizationID] = loc
+		}
+		return loc
+	}
+}
+
+// evaluateAlertRules — чистая функция: какие правила сработали для каких пользователей.
+// evaluated — правила, проверенные в этот раз (включены и в своём рабочем окне).
+// locationOf — пояс организации, в котором задано рабочее окно правила.
+func evaluateAlertRules(
+	rules []models.AlertRule,
+	snap *alertSnapshot,
+	now time.Time,
+	locationOf func(organizationID int) *time.Location,
+) ([]alertFiring, map[int]bool) {
+	var firings []alertFiring
+	evaluated := make(map[int]bool, len(rules))
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Enabled || !alertRuleActiveAt(rule, now, locationOf(rule.OrganizationID)) {
+			continue
+		}
+		evaluated[rule.ID] = true
+
+		userIDs := snap.UserIDs
+		if rule.UserID != nil {
+			userIDs = []int{*rule.UserID}
+		}
+		for _, userID := range userIDs {
+			if rule.UserID == nil && snap.UserOrganization != nil &&
+				snap.UserOrganization[userID] != organizationOrDefault(rule.OrganizationID) {
+				continue
+			}
+			if msg, ok := checkAlertRule(rule, userID, snap, now); ok {
+				firings = append(firings, alertFiring{Rule: rule, UserID: userID, Message: msg})
+			}
+		}
+	}
+	return firings, evaluated
+}
+
+func checkAlertRule(rule *models.AlertRule, userID int, snap *alertSnapshot, now time.Time) (string, bool) {
+	report, hasReport := snap.Reports[userID]
+
+	switch rule.Type {
+	case models.AlertRuleTypeNoLocation:
+		if snap.TrackingPaused[userID] {
+			return "", false
+		}
+		minutes := ruleMinutes(rule, alertDefaultNoLocationMinutes)
+		age, ok := snap.LocationAge[userID]
+		if !ok {
+			return "Нет ни одной GPS-точки", true
+		}
+		if age > int64(minutes)*60 {
+			return fmt.Sprintf("Нет координат %d мин (порог %d мин)", age/60, minutes), true
+		}
+
+	case models.AlertRuleTypeReportIssue:
+		if !hasReport || rule.Issue == "" {
+			return "", false
+		}
+		issues, _ := models.ParseDeviceReportIssues(report.Issues)
+		for _, issue := range issues {
+			if issue == rule.Issue {
+				return fmt.Sprintf("В отчёте устройства проблема %s", rule.Issue), true
+			}
+		}
+
+	case models.AlertRuleTypeBatteryLow:
+		if !hasReport || report.BatteryPercent == nil {
+			return "", false
+		}
+		threshold := float64(alertDefaultBatteryPercent)
+		if rule.BatteryPercent != nil {
+			threshold = *rule.BatteryPercent
+		}
+		if *report.BatteryPercent < threshold {
+			return fmt.Sprintf("Заряд батареи %.0f%% (порог %.0f%%)", *report.BatteryPercent, threshold), true
+		}
+
+	case models.AlertRuleTypeAppVersionBelow:
+		if !hasReport || report.AppVersion == "" || rule.MinAppVersion == "" {
+			return "", false
+		}
+		if compareAppVersions(report.AppVersion, rule.MinAppVersion) < 0 {
+			return fmt.Sprintf("Версия приложения %s ниже минимальной %s", report.AppVersion, rule.MinAppVersion), true
+		}
+
+	case models.AlertRuleTypeLocationRequestExpired:
+		since := now.Add(-time.Duration(ruleMinutes(rule, alertDefaultRequestExpiredMinutes)) * time.Minute)
+		count := 0
+		for _, at := range snap.ExpiredRequests[userID] {
+			if !at.Before(since) {
+				count++
+			}
+		}
+		if count > 0 {
+			return fmt.Sprintf("Запросов координат без ответа: %d", count), true
+		}
+	}
+	return "", false
+}
+
+// alertRuleActiveAt — попадает ли now в рабочее окно правила (в поясе организации loc).
+// Окно через полночь (start > end) поддерживается.
+func alertRuleActiveAt(rule *models.AlertRule, now time.Time, loc *time.Location) bool {
+	local := now.In(loc)
+	if rule.WorkDaysOnly && (local.Weekday() == time.Saturday || local.Weekday() == time.Sunday) {
+		return false
+	}
+	if rule.WorkStartHour == nil || rule.WorkEndHour == nil {
+		return true
+	}
+	start, end, hour := *rule.WorkStartHour, *rule.WorkEndHour, local.Hour()
+	if start == end {
+		return true
+	}
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+func ruleMinutes(rule *models.AlertRule, def int) int {
+	if rule.ThresholdMinutes != nil && *rule.ThresholdMinutes > 0 {
+		return *rule.ThresholdMinutes
+	}
+	return def
+}
+
+func alertDedupKey(ruleID, userID int) string {
+	return fmt.Sprintf("rule:%d:user:%d", ruleID, userID)
+}
+
+// ListAlerts — история алертов с фильтрами (status, user_id, rule_id, type).
+// Значение []int фильтрует по списку (user_id IN ...).
+func (svc *AlertService) ListAlerts(filters map[string]interface{}, limit, offset int) ([]models.Alert, int64, error) {
+	if limit <= 0 {
+		limit = alertListDefaultLimit
+	}
+	if limit > alertListMaxLimit {
+		limit = alertListMaxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return svc.DAO.ListAlerts(filters, limit, offset)
+}
+
+// Acknowledge — админ взял алерт в работу; алерт остаётся активным до устранения причины.
+func (svc *AlertService) Acknowledge(id, byUserID int) (*models.Alert, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	alert, err := svc.getAlert(id)
+	if err != nil {
+		return nil, err
+	}
+	if !alert.IsActive() {
+		return nil, ErrAlertAlreadyResolved
+	}
+	if alert.Status == models.AlertStatusAcknowledged {
+		return alert, nil
+	}
+	now := clockNow(svc.Clock)
+	alert.Status = models.AlertStatusAcknowledged
+	alert.AcknowledgedAt = &now
+	alert.AcknowledgedBy = &byUserID
+	if err := svc.DAO.UpdateAlert(alert); err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+// Resolve — ручное закрытие. Если условие сохраняется, следующая проверка откроет новый алерт.
+func (svc *AlertService) Resolve(id, byUserID int) (*models.Alert, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	alert, err := svc.getAlert(id)
+	if err != nil {
+		return nil, err
+	}
+	if !alert.IsActive() {
+		return nil, ErrAlertAlreadyResolved
+	}
+	now := clockNow(svc.Clock)
+	alert.Status = models.AlertStatusResolved
+	alert.ResolvedAt = &now
+	alert.ResolvedBy = &byUserID
+	if err := svc.DAO.UpdateAlert(alert); err != nil {
+		return nil, err
+	}
+	svc.notify(models.NotificationEventAlertResolved, alert, now)
+	return alert, nil
+}
+
+func (svc *AlertService) notify(event string, alert *models.Alert, at time.Time) {
+	if svc.Notifier == nil {
+		return
+	}
+	svc.Notifier.Notify(Notification{
+		Event:    event,
+		Severity: alert.Severity,
+		UserID:   alert.UserID,
+		Params: map[string]string{
+			"Message": alert.Message,
+			"AlertID": strconv.Itoa(alert.ID),
+			"Type":    alert.Type,
+		},
+		At: at,
+	})
+}
+
+// GetAlert возвращает алерт по ID.
+func (svc *AlertService) GetAlert(id int) (*models.Alert, error) {
+	return svc.getAlert(id)
+}
+
+func (svc *AlertService) getAlert(id int) (*models.Alert, error) {
+	alert, err := svc.DAO.GetAlertByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAlertNotFound
+	}
+	return alert, err
+}
+
+// ListRules возвращает правила алертинга организации.
+func (svc *AlertService) ListRules(organizationID int) ([]models.AlertRule, error) {
+	return svc.DAO.GetRulesByOrganization(organizationOrDefault(organizationID))
+}
+
+// CreateRule валидирует и сохраняет новое правило организации.
+func (svc *AlertService) CreateRule(organizationID int, rule *models.AlertRule) error {
+	rule.ID = 0
+	rule.OrganizationID = organizationOrDefault(organizationID)
+	if err := svc.normalizeAlertRule(rule); err != nil {
+		return err
+	}
+	return svc.DAO.CreateRule(rule)
+}
+
+// UpdateRule полностью заменяет правило организации с указанным ID.
+func (svc *AlertService) UpdateRule(organizationID, id int, rule *models.AlertRule) error {
+	existing, err := svc.getRule(organizationID, id)
+	if err != nil {
+		return err
+	}
+	rule.ID = existing.ID
+	rule.OrganizationID = existing.OrganizationID
+	rule.CreatedAt = existing.CreatedAt
+	if err := svc.normalizeAlertRule(rule); err != nil {
+		return err
+	}
+	return svc.DAO.UpdateRule(rule)
+}
+
+// getRule — правило организации; правило другой организации не находится.
+func (svc *AlertService) getRule(organizationID, id int) (*models.AlertRule, error) {
+	rule, err := svc.DAO.GetRuleByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) ||
+		(err == nil && organizationOrDefault(rule.OrganizationID) != organizationOrDefault(organizationID)) {
+		return nil, ErrAlertRuleNotFound
+	}
+	return rule, err
+}
+
+// DeleteRule удаляет правило; его активные алерты закроются при следующей проверке.
+func (svc *AlertService) DeleteRule(organizationID, id int) error {
+	if _, err := svc.getRule(organizationID, id); err != nil {
+		return err
+	}
+	if err := svc.DAO.DeleteRule(id); err != nil {
+		return err
+	}
+	svc.Trigger()
+	return nil
+}
+
+// normalizeAlertRule проверяет правило; user_id должен принадлежать организации правила.
+func (svc *AlertService) normalizeAlertRule(rule *models.AlertRule) error {
+	if rule.UserID != nil && svc.Users != nil {
+		u, err := svc.Users.GetByID(*rule.UserID)
+		if err != nil || organizationOrDefault(u.OrganizationID) != rule.OrganizationID {
+			return fmt.Errorf("%w: пользователь %d не найден", ErrAlertRuleInvalid, *rule.UserID)
+		}
+	}
+	return normalizeAlertRule(rule)
+}
+
+func normalizeAlertRule(rule *models.AlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("%w: укажите name", ErrAlertRuleInvalid)
+	}
+	switch rule.Severity {
+	case "":
+		rule.Severity = models.AlertSeverityWarning
+	case models.AlertSeverityWarning, models.AlertSeverityCritical:
+	default:
+		return fmt.Errorf("%w: severity должен быть warning или critical", ErrAlertRuleInvalid)
+	}
+
+	switch rule.Type {
+	case models.AlertRuleTypeNoLocation, models.AlertRuleTypeLocationRequestExpired:
+	case models.AlertRuleTypeReportIssue:
+		if rule.Issue == "" {
+			return fmt.Errorf("%w: для report_issue укажите issue", ErrAlertRuleInvalid)
+		}
+	case models.AlertRuleTypeBatteryLow:
+		if rule.BatteryPercent != nil && (*rule.BatteryPercent <= 0 || *rule.BatteryPercent > 100) {
+			return fmt.Errorf("%w: battery_percent в диапазоне 1–100", ErrAlertRuleInvalid)
+		}
+	case models.AlertRuleTypeAppVersionBelow:
+		if len(appVersionParts(rule.MinAppVersion)) == 0 {
+			return fmt.Errorf("%w: для app_version_below укажите min_app_version", ErrAlertRuleInvalid)
+		}
+	default:
+		return fmt.Errorf("%w: неизвестный type %q", ErrAlertRuleInvalid, rule.Type)
+	}
+
+	if rule.ThresholdMinutes != nil && *rule.ThresholdMinutes <= 0 {
+		return fmt.Errorf("%w: threshold_minutes должен быть > 0", ErrAlertRuleInvalid)
+	}
+	if (rule.WorkStartHour == nil) != (rule.WorkEndHour == nil) {
+		return fmt.Errorf("%w: укажите work_start_hour и work_end_hour вместе", ErrAlertRuleInvalid)
+	}
+	if rule.WorkStartHour != nil && (*rule.WorkStartHour < 0 || *rule.WorkStartHour > 23 || *rule.WorkEndHour < 0 || *rule.WorkEndHour > 24) {
+		return fmt.Errorf("%w: часы рабочего окна 0–24", ErrAlertRuleInvalid)
+	}
+	return nil
+}
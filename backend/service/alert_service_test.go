@@ -0,0 +1,323 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"locator/dao"
+	"locator/internal/testutil"
+	"locator/models"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type fakeAlertRepo struct {
+	rules  []models.AlertRule
+	alerts []models.Alert
+}
+
+func (f *fakeAlertRepo) CreateRule(rule *models.AlertRule) error {
+	rule.ID = len(f.rules) + 1
+	f.rules = append(f.rules, *rule)
+	return nil
+}
+
+func (f *fakeAlertRepo) UpdateRule(rule *models.AlertRule) error {
+	for i := range f.rules {
+		if f.rules[i].ID == rule.ID {
+			f.rules[i] = *rule
+		}
+	}
+	return nil
+}
+
+func (f *fakeAlertRepo) DeleteRule(id int) error {
+	out := f.rules[:0]
+	for _, r := range f.rules {
+		if r.ID != id {
+			out = append(out, r)
+		}
+	}
+	f.rules = out
+	return nil
+}
+
+func (f *fakeAlertRepo) GetRuleByID(id int) (*models.AlertRule, error) {
+	for i := range f.rules {
+		if f.rules[i].ID == id {
+			r := f.rules[i]
+			return &r, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeAlertRepo) GetAllRules() ([]models.AlertRule, error) {
+	return append([]models.AlertRule(nil), f.rules...), nil
+}
+
+func (f *fakeAlertRepo) GetRulesByOrganization(organizationID int) ([]models.AlertRule, error) {
+	var out []models.AlertRule
+	for _, r := range f.rules {
+		if organizationOrDefault(r.OrganizationID) == organizationID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAlertRepo) CreateAlert(alert *models.Alert) error {
+	alert.ID = len(f.alerts) + 1
+	f.alerts = append(f.alerts, *alert)
+	return nil
+}
+
+func (f *fakeAlertRepo) UpdateAlert(alert *models.Alert) error {
+	for i := range f.alerts {
+		if f.alerts[i].ID == alert.ID {
+			f.alerts[i] = *alert
+		}
+	}
+	return nil
+}
+
+func (f *fakeAlertRepo) GetAlertByID(id int) (*models.Alert, error) {
+	for i := range f.alerts {
+		if f.alerts[i].ID == id {
+			a := f.alerts[i]
+			return &a, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeAlertRepo) GetActiveAlerts() ([]models.Alert, error) {
+	var out []models.Alert
+	for _, a := range f.alerts {
+		if a.IsActive() {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAlertRepo) ListAlerts(filters map[string]interface{}, limit, offset int) ([]models.Alert, int64, error) {
+	return f.alerts, int64(len(f.alerts)), nil
+}
+
+type fakeAlertSources struct {
+	ages    []dao.LatestLocationAge
+	reports []dao.LatestDeviceReportRow
+	expired []models.LocationRequest
+}
+
+func (f *fakeAlertSources) GetLatestAgePerUser(organizationID int) ([]dao.LatestLocationAge, error) {
+	return f.ages, nil
+}
+
+func (f *fakeAlertSources) GetLatestPerUser(organizationID int) ([]dao.LatestDeviceReportRow, error) {
+	return f.reports, nil
+}
+
+func (f *fakeAlertSources) ExpirePendingOlderThan(cutoff time.Time) error { return nil }
+
+func (f *fakeAlertSources) GetExpiredSince(since time.Time) ([]models.LocationRequest, error) {
+	return f.expired, nil
+}
+
+func newTestAlertService(repo *fakeAlertRepo, src *fakeAlertSources) *AlertService {
+	users := newFakeUserRepo(
+		models.User{ID: 1, Name: "admin", IsAdmin: true},
+		models.User{ID: 2, Name: "phone-a"},
+		models.User{ID: 3, Name: "phone-b"},
+	)
+	svc := NewAlertService(repo, users, src, src, src)
+	svc.location = time.UTC
+	return svc
+}
+
+func intPtr(v int) *int { return &v }
+
+// evaluateAt — Evaluate с часами сервиса, остановленными на now.
+func evaluateAt(svc *AlertService, now time.Time) (*AlertEvaluation, error) {
+	svc.Clock = testutil.NewClock(now)
+	return svc.Evaluate()
+}
+
+func TestAlertEvaluate_ruleLimitedToOrganization(t *testing.T) {
+	repo := &fakeAlertRepo{}
+	src := &fakeAlertSources{ages: []dao.LatestLocationAge{
+		{UserID: 2, AgeSeconds: 3600},
+		{UserID: 4, AgeSeconds: 3600},
+	}}
+	svc := newTestAlertService(repo, src)
+	users := svc.Users.(*fakeUserRepo)
+	_ = users.Create(&models.User{ID: 4, Name: "phone-c", OrganizationID: 2})
+	src.ages = append(src.ages, dao.LatestLocationAge{UserID: 3, AgeSeconds: 60})
+
+	if err := svc.CreateRule(2, &models.AlertRule{Name: "GPS", Type: models.AlertRuleTypeNoLocation, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CreateRule(2, &models.AlertRule{Name: "чужой", Type: models.AlertRuleTypeNoLocation, UserID: intPtr(2)}); !errors.Is(err, ErrAlertRuleInvalid) {
+		t.Fatalf("rule for a user of another organisation must be rejected, got %v", err)
+	}
+
+	res, err := evaluateAt(svc, time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Opened != 1 || repo.alerts[0].UserID != 4 {
+		t.Fatalf("rule of organisation 2 must only fire for its users: %+v %+v", res, repo.alerts)
+	}
+	if rules, _ := svc.ListRules(models.DefaultOrganizationID); len(rules) != 0 {
+		t.Fatalf("rules of another organisation leaked: %+v", rules)
+	}
+	if err := svc.DeleteRule(models.DefaultOrganizationID, 1); !errors.Is(err, ErrAlertRuleNotFound) {
+		t.Fatalf("foreign rule must not be deletable, got %v", err)
+	}
+}
+
+func TestAlertEvaluate_dedupAndAutoResolve(t *testing.T) {
+	repo := &fakeAlertRepo{}
+	src := &fakeAlertSources{ages: []dao.LatestLocationAge{
+		{UserID: 2, AgeSeconds: 3600},
+		{UserID: 3, AgeSeconds: 60},
+	}}
+	svc := newTestAlertService(repo, src)
+	if err := svc.CreateRule(0, &models.AlertRule{Name: "GPS", Type: models.AlertRuleTypeNoLocation, Enabled: true, ThresholdMinutes: intPtr(30)}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	res, err := evaluateAt(svc, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Opened != 1 || len(repo.alerts) != 1 || repo.alerts[0].UserID != 2 {
+		t.Fatalf("want one alert for user 2: %+v %+v", res, repo.alerts)
+	}
+
+	res, _ = evaluateAt(svc, now.Add(time.Minute))
+	if res.Opened != 0 || res.Updated != 1 || len(repo.alerts) != 1 {
+		t.Fatalf("repeat firing must be deduplicated: %+v", res)
+	}
+	if !repo.alerts[0].LastSeenAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("last_seen_at not bumped: %v", repo.alerts[0].LastSeenAt)
+	}
+
+	src.ages[0].AgeSeconds = 10
+	res, _ = evaluateAt(svc, now.Add(2*time.Minute))
+	if res.Resolved != 1 || repo.alerts[0].Status != models.AlertStatusResolved || repo.alerts[0].ResolvedBy != nil {
+		t.Fatalf("alert must auto-resolve: %+v %+v", res, repo.alerts[0])
+	}
+}
+
+func TestAlertEvaluate_outsideWorkingHoursKeepsState(t *testing.T) {
+	repo := &fakeAlertRepo{}
+	src := &fakeAlertSources{ages: []dao.LatestLocationAge{{UserID: 2, AgeSeconds: 7200}}}
+	svc := newTestAlertService(repo, src)
+	_ = svc.CreateRule(0, &models.AlertRule{
+		Name: "GPS днём", Type: models.AlertRuleTypeNoLocation, Enabled: true, UserID: intPtr(2),
+		WorkStartHour: intPtr(9), WorkEndHour: intPtr(18),
+	})
+
+	if _, err := evaluateAt(svc, time.Date(2026, 10, 19, 17, 30, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.alerts) != 1 {
+		t.Fatalf("want alert during working hours, got %d", len(repo.alerts))
+	}
+
+	src.ages[0].AgeSeconds = 0
+	res, _ := evaluateAt(svc, time.Date(2026, 10, 19, 22, 0, 0, 0, time.UTC))
+	if res.Resolved != 0 || !repo.alerts[0].IsActive() {
+		t.Fatalf("rule outside its window must not touch alerts: %+v", res)
+	}
+}
+
+func TestAlertEvaluate_reportRules(t *testing.T) {
+	battery := 8.0
+	repo := &fakeAlertRepo{}
+	src := &fakeAlertSources{
+		reports: []dao.LatestDeviceReportRow{
+			{UserID: 2, AppVersion: "1.9.3", Issues: datatypes.JSON(`["background_stopped"]`), BatteryPercent: &battery},
+			{UserID: 3, AppVersion: "1.10.0", Issues: datatypes.JSON(`[]`)},
+		},
+		expired: []models.LocationRequest{{UserID: 3, CreatedAt: time.Date(2026, 10, 19, 9, 50, 0, 0, time.UTC)}},
+	}
+	svc := newTestAlertService(repo, src)
+	_ = svc.CreateRule(0, &models.AlertRule{Name: "фон", Type: models.AlertRuleTypeReportIssue, Issue: "background_stopped", Enabled: true})
+	_ = svc.CreateRule(0, &models.AlertRule{Name: "батарея", Type: models.AlertRuleTypeBatteryLow, Enabled: true})
+	_ = svc.CreateRule(0, &models.AlertRule{Name: "версия", Type: models.AlertRuleTypeAppVersionBelow, MinAppVersion: "1.10", Enabled: true})
+	_ = svc.CreateRule(0, &models.AlertRule{Name: "запрос", Type: models.AlertRuleTypeLocationRequestExpired, Enabled: true})
+
+	res, err := evaluateAt(svc, time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Opened != 4 {
+		t.Fatalf("want 4 alerts, got %+v: %+v", res, repo.alerts)
+	}
+	for _, a := range repo.alerts {
+		wantUser := 2
+		if a.Type == models.AlertRuleTypeLocationRequestExpired {
+			wantUser = 3
+		}
+		if a.UserID != wantUser {
+			t.Fatalf("%s fired for user %d", a.Type, a.UserID)
+		}
+	}
+}
+
+func TestAlertAcknowledgeAndResolve(t *testing.T) {
+	repo := &fakeAlertRepo{alerts: []models.Alert{{ID: 1, Status: models.AlertStatusOpen}}}
+	svc := newTestAlertService(repo, &fakeAlertSources{})
+
+	alert, err := svc.Acknowledge(1, 9)
+	if err != nil || alert.Status != models.AlertStatusAcknowledged || *alert.AcknowledgedBy != 9 {
+		t.Fatalf("ack: %+v %v", alert, err)
+	}
+	if _, err := svc.Resolve(1, 9); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Acknowledge(1, 9); err != ErrAlertAlreadyResolved {
+		t.Fatalf("want ErrAlertAlreadyResolved, got %v", err)
+	}
+	if _, err := svc.Resolve(42, 9); err != ErrAlertNotFound {
+		t.Fatalf("want ErrAlertNotFound, got %v", err)
+	}
+}
+
+func TestCreateAlertRule_validation(t *testing.T) {
+	svc := newTestAlertService(&fakeAlertRepo{}, &fakeAlertSources{})
+	cases := []models.AlertRule{
+		{Name: "x", Type: "unknown"},
+		{Name: "x", Type: models.AlertRuleTypeReportIssue},
+		{Name: "x", Type: models.AlertRuleTypeAppVersionBelow},
+		{Name: "x", Type: models.AlertRuleTypeNoLocation, WorkStartHour: intPtr(9)},
+		{Type: models.AlertRuleTypeNoLocation},
+	}
+	for i := range cases {
+		if err := svc.CreateRule(0, &cases[i]); err == nil {
+			t.Fatalf("case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestCompareAppVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.9.3", "1.10", -1},
+		{"1.10.0", "1.10", 0},
+		{"v2.0.1-debug", "2.0.0", 1},
+	}
+	for _, c := range cases {
+		if got := compareAppVersions(c.a, c.b); got != c.want {
+			t.Fatalf("compare(%q,%q)=%d want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"locator/models"
+)
+
+var (
+	ErrAPIKeyNotFound     = errors.New("API-ключ не найден")
+	ErrAPIKeyLabelTooLong = errors.New("название ключа длиннее 100 символов")
+	ErrAPIKeyExpired      = errors.New("срок действия ключа уже истёк")
+)
+
+const (
+	// DefaultAPIKeyRotationGrace — сколько прежний ключ действует после перегенерации
+	// QR, если устройство так и не воспользовалось новым ключом.
+	DefaultAPIKeyRotationGrace = 72 * time.Hour
+	// apiKeyTouchInterval — last_used_at/IP пишутся не чаще, чем раз в интервал.
+	apiKeyTouchInterval  = time.Minute
+	maxAPIKeyLabelLength = 100
+	primaryAPIKeyLabel   = "QR"
+	// apiKeyPrefix — префикс ключей, выпущенных с отпечатком: для них перебор
+	// ключей без отпечатка не нужен.
+	apiKeyPrefix = "lk_"
+	// legacyAPIKeyScanLimit — сколько ключей без отпечатка сравнивается bcrypt за
+	// один запрос: стоимость неверного ключа не растёт с числом старых ключей.
+	legacyAPIKeyScanLimit = 20
+)
+
+// APIKeyInput — параметры дополнительного ключа (скрипты, интеграции).
+type APIKeyInput struct {
+	Label     string     `json:"label"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// apiKeyFingerprint — первые 8 байт SHA-256 ключа в hex; по нему ищутся кандидаты
+// для сравнения с bcrypt-хешами.
+func apiKeyFingerprint(plainKey string) string {
+	sum := sha256.Sum256([]byte(plainKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// issueAPIKey сохраняет ключ plainKey (или новый случайный) пользователю userID.
+func (svc *UserService) issueAPIKey(userID int, label string, primary bool, expiresAt *time.Time, plainKey string) (*models.APIKey, string, error) {
+	if plainKey == "" {
+		var err error
+		if plainKey, err = generateSecureAPIKey(); err != nil {
+			return nil, "", err
+		}
+	}
+	hashedKey, err := bcrypt.GenerateFromPassword([]byte(plainKey), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, "", err
+	}
+	key := &models.APIKey{
+		UserID:      userID,
+		Label:       label,
+		Fingerprint: apiKeyFingerprint(plainKey),
+		KeyHash:     string(hashedKey),
+		Primary:     primary,
+		ExpiresAt:   expiresAt,
+	}
+	if err := svc.Keys.CreateAPIKey(key); err != nil {
+		return nil, "", err
+	}
+	return key, plainKey, nil
+}
+
+// rotatePrimaryAPIKey выпускает новый основной ключ. Прежние основные ключи
+// остаются действительными до первого запроса с новым ключом, но не дольше
+// KeyRotationGrace — так устройство не теряет связь, пока не получит config_update.
+func (svc *UserService) rotatePrimaryAPIKey(userID int, plainKey string) (*models.APIKey, string, error) {
+	keys, err := svc.Keys.GetAPIKeysByUser(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	key, plainKey, err := svc.issueAPIKey(userID, primaryAPIKeyLabel, true, nil, plainKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := clockNow(svc.Clock)
+	graceUntil := now.Add(svc.rotationGrace())
+	for i := range keys {
+		old := &keys[i]
+		if !old.Primary || !old.Active(now) {
+			continue
+		}
+		old.Primary = false
+		old.ReplacedByID = &key.ID
+		if old.ExpiresAt == nil || old.ExpiresAt.After(graceUntil) {
+			old.ExpiresAt = &graceUntil
+		}
+		if err := svc.Keys.UpdateAPIKey(old); err != nil {
+			return nil, "", err
+		}
+	}
+	return key, plainKey, nil
+}
+
+func (svc *UserService) rotationGrace() time.Duration {
+	if svc.KeyRotationGrace > 0 {
+		return svc.KeyRotationGrace
+	}
+	return DefaultAPIKeyRotationGrace
+}
+
+// CreateAPIKey выпускает дополнительный ключ пользователю; ключ показывается один раз.
+func (svc *UserService) CreateAPIKey(userID int, in APIKeyInput) (*models.APIKey, string, error) {
+	if _, err := svc.DAO.GetByID(userID); err != nil {
+		return nil, "", err
+	}
+	label := strings.TrimSpace(in.Label)
+	if len([]rune(label)) > maxAPIKeyLabelLength {
+		return nil, "", ErrAPIKeyLabelTooLong
+	}
+	if in.ExpiresAt != nil && !in.ExpiresAt.After(clockNow(svc.Clock)) {
+		return nil, "", ErrAPIKeyExpired
+	}
+	key, plainKey, err := svc.issueAPIKey(userID, label, false, in.ExpiresAt, "")
+	if err != nil {
+		slog.Error("Ошибка выпуска API-ключа", "user_id", userID, "error", err)
+		return nil, "", err
+	}
+	slog.Info("API-ключ выпущен", "user_id", userID, "key_id", key.ID, "label", key.Label)
+	return key, plainKey, nil
+}
+
+// ListAPIKeys — все ключи пользователя, включая отозванные и истёкшие.
+func (svc *UserService) ListAPIKeys(userID int) ([]models.APIKey, error) {
+	return svc.Keys.GetAPIKeysByUser(userID)
+}
+
+// RevokeAPIKey немедленно отзывает ключ keyID пользователя userID.
+func (svc *UserService) RevokeAPIKey(userID, keyID int) (*models.APIKey, error) {
+	key, err := svc.Keys.GetAPIKeyByID(keyID)
+	if err != nil || key.UserID != userID {
+		return nil, ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		now := clockNow(svc.Clock)
+		key.RevokedAt = &now
+		if err := svc.Keys.UpdateAPIKey(key); err != nil {
+			return nil, err
+		}
+		slog.Info("API-ключ отозван", "user_id", userID, "key_id", keyID)
+	}
+	return key, nil
+}
+
+// authenticateAPIKey ищет действующий ключ plainKey; при первом использовании
+// нового основного ключа отзывает ключи, которые он заменил.
+func (svc *UserService) authenticateAPIKey(plainKey, ip string) (*models.APIKey, error) {
+	fingerprint := apiKeyFingerprint(plainKey)
+	candidates, err := svc.Keys.GetAPIKeyCandidates(fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка доступа к базе данных")
+	}
+
+	now := clockNow(svc.Clock)
+	// Ключи без отпечатка (перенесённые из users.api_key) перебираются, только если
+	// по отпечатку ничего не нашлось и ключ не нового формата, и не больше лимита.
+	if len(candidates) == 0 && !strings.HasPrefix(plainKey, apiKeyPrefix) {
+		legacy, err := svc.Keys.GetLegacyAPIKeys(now, legacyAPIKeyScanLimit)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка доступа к базе данных")
+		}
+		if len(legacy) == legacyAPIKeyScanLimit {
+			slog.Warn("Ключей без отпечатка больше лимита перебора, перевыпустите QR", "limit", legacyAPIKeyScanLimit)
+		}
+		candidates = legacy
+	}
+
+	for i := range candidates {
+		key := &candidates[i]
+		if !key.Active(now) {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword([]byte(key.KeyHash), []byte(plainKey)) != nil {
+			continue
+		}
+		firstUse := key.LastUsedAt == nil
+		if firstUse || key.Fingerprint == "" || key.LastUsedIP != ip || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+			if key.Fingerprint == "" && key.ReplacedByID == nil {
+				// Перенесённый ключ успел до срока миграции: с отпечатком срок не нужен.
+				key.ExpiresAt = nil
+			}
+			key.Fingerprint = fingerprint
+			key.LastUsedAt = &now
+			key.LastUsedIP = ip
+			if err := svc.Keys.UpdateAPIKey(key); err != nil {
+				slog.Error("Не удалось обновить last_used_at API-ключа", "key_id", key.ID, "error", err)
+			}
+		}
+		if firstUse {
+			if err := svc.Keys.RevokeReplacedAPIKeys(key.ID, now); err != nil {
+				slog.Error("Не удалось отозвать заменённые API-ключи", "key_id", key.ID, "error", err)
+			}
+		}
+		return key, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"locator/internal/testutil"
+	"locator/models"
+)
+
+type fakeAPIKeyRepo struct {
+	keys        map[int]models.APIKey
+	nextID      int
+	legacyScans int
+}
+
+func newFakeAPIKeyRepo(keys ...models.APIKey) *fakeAPIKeyRepo {
+	f := &fakeAPIKeyRepo{keys: make(map[int]models.APIKey), nextID: 1}
+	for _, k := range keys {
+		f.keys[k.ID] = k
+		if k.ID >= f.nextID {
+			f.nextID = k.ID + 1
+		}
+	}
+	return f
+}
+
+func (f *fakeAPIKeyRepo) CreateAPIKey(key *models.APIKey) error {
+	key.ID = f.nextID
+	f.nextID++
+	f.keys[key.ID] = *key
+	return nil
+}
+
+func (f *fakeAPIKeyRepo) UpdateAPIKey(key *models.APIKey) error {
+	f.keys[key.ID] = *key
+	return nil
+}
+
+func (f *fakeAPIKeyRepo) GetAPIKeyByID(id int) (*models.APIKey, error) {
+	k, ok := f.keys[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &k, nil
+}
+
+func (f *fakeAPIKeyRepo) GetAPIKeysByUser(userID int) ([]models.APIKey, error) {
+	var out []models.APIKey
+	for _, k := range f.keys {
+		if k.UserID == userID {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAPIKeyRepo) GetAPIKeyCandidates(fingerprint string) ([]models.APIKey, error) {
+	var out []models.APIKey
+	for _, k := range f.keys {
+		if k.RevokedAt == nil && k.Fingerprint == fingerprint {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAPIKeyRepo) GetLegacyAPIKeys(now time.Time, limit int) ([]models.APIKey, error) {
+	f.legacyScans++
+	var out []models.APIKey
+	for _, k := range f.keys {
+		if k.RevokedAt == nil && k.Fingerprint == "" && k.Active(now) && len(out) < limit {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAPIKeyRepo) RevokeReplacedAPIKeys(replacedByID int, at time.Time) error {
+	for id, k := range f.keys {
+		if k.ReplacedByID != nil && *k.ReplacedByID == replacedByID && k.RevokedAt == nil {
+			k.RevokedAt = &at
+			f.keys[id] = k
+		}
+	}
+	return nil
+}
+
+// newTestKeyService — сервис пользователей с управляемыми часами; QR пишутся во временный каталог.
+func newTestKeyService(t *testing.T) (*UserService, *fakeAPIKeyRepo, *testutil.Clock) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	keys := newFakeAPIKeyRepo()
+	clock := testutil.NewClock(time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC))
+	svc := NewUserService(newFakeUserRepo(), keys)
+	svc.Clock = clock
+	return svc, keys, clock
+}
+
+func TestRegenerateUserQR_oldKeyValidUntilNewKeyUsed(t *testing.T) {
+	svc, keys, _ := newTestKeyService(t)
+	user, oldKey, err := svc.CreateUser("phone", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, newKey, err := svc.RegenerateUserQR(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newKey == oldKey {
+		t.Fatal("expected a fresh key")
+	}
+	// Пока телефон не получил config_update, он продолжает ходить со старым ключом.
+	if _, err := svc.AuthenticateUser(oldKey, "10.0.0.1"); err != nil {
+		t.Fatalf("old key during handover: %v", err)
+	}
+	if _, err := svc.AuthenticateUser(newKey, "10.0.0.2"); err != nil {
+		t.Fatalf("new key: %v", err)
+	}
+	if _, err := svc.AuthenticateUser(oldKey); err == nil {
+		t.Fatal("old key must be revoked after the new key was used")
+	}
+
+	list, _ := svc.ListAPIKeys(user.ID)
+	primary := 0
+	for _, k := range list {
+		if k.Primary {
+			primary++
+			if k.LastUsedIP != "10.0.0.2" || k.LastUsedAt == nil {
+				t.Fatalf("last use not recorded: %+v", k)
+			}
+		}
+	}
+	if primary != 1 || len(keys.keys) != 2 {
+		t.Fatalf("keys: %+v", list)
+	}
+}
+
+func TestRegenerateUserQR_oldKeyExpiresAfterGrace(t *testing.T) {
+	svc, _, clock := newTestKeyService(t)
+	svc.KeyRotationGrace = time.Hour
+	user, oldKey, err := svc.CreateUser("phone", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, newKey, err := svc.RegenerateUserQR(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Hour)
+	if _, err := svc.AuthenticateUser(oldKey); err == nil {
+		t.Fatal("old key must expire after the grace period")
+	}
+	if _, err := svc.AuthenticateUser(newKey); err != nil {
+		t.Fatalf("new key: %v", err)
+	}
+}
+
+func TestAPIKey_legacyKeysScannedOnlyForOldFormat(t *testing.T) {
+	svc, keys, clock := newTestKeyService(t)
+	user, qrKey, err := svc.CreateUser("phone", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(qrKey, apiKeyPrefix) {
+		t.Fatalf("new key without prefix: %s", qrKey)
+	}
+	// Ключи, перенесённые из users.api_key: без отпечатка, со сроком из миграции.
+	deadline := clock.Now().Add(30 * 24 * time.Hour)
+	legacy := func(id int, plain string) {
+		hash, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys.keys[id] = models.APIKey{ID: id, UserID: user.ID, KeyHash: string(hash), ExpiresAt: &deadline}
+	}
+	legacy(100, "legacy-used")
+	legacy(101, "legacy-unused")
+
+	if _, err := svc.AuthenticateUser(apiKeyPrefix + "garbage"); err == nil || keys.legacyScans != 0 {
+		t.Fatalf("new-format key: err=%v legacy scans=%d", err, keys.legacyScans)
+	}
+	if _, err := svc.AuthenticateUser("garbage"); err == nil || keys.legacyScans != 1 {
+		t.Fatalf("old-format key: err=%v legacy scans=%d", err, keys.legacyScans)
+	}
+
+	if _, err := svc.AuthenticateUser("legacy-used"); err != nil {
+		t.Fatalf("legacy key before deadline: %v", err)
+	}
+	if used := keys.keys[100]; used.Fingerprint == "" || used.ExpiresAt != nil {
+		t.Fatalf("legacy key must get a fingerprint and lose the deadline: %+v", used)
+	}
+
+	clock.Set(deadline)
+	if _, err := svc.AuthenticateUser("legacy-unused"); err == nil {
+		t.Fatal("unused legacy key accepted after the deadline")
+	}
+	if _, err := svc.AuthenticateUser("legacy-used"); err != nil {
+		t.Fatalf("backfilled legacy key after the deadline: %v", err)
+	}
+}
+
+func TestAPIKey_extraKeyExpiryAndRevocation(t *testing.T) {
+	svc, _, clock := newTestKeyService(t)
+	user, qrKey, err := svc.CreateUser("phone", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	past := clock.Now().Add(-time.Minute)
+	if _, _, err := svc.CreateAPIKey(user.ID, APIKeyInput{Label: "old", ExpiresAt: &past}); !errors.Is(err, ErrAPIKeyExpired) {
+		t.Fatalf("expiry in the past: %v", err)
+	}
+	expires := clock.Now().Add(24 * time.Hour)
+	key, plain, err := svc.CreateAPIKey(user.ID, APIKeyInput{Label: " script ", ExpiresAt: &expires})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Label != "script" || key.Primary {
+		t.Fatalf("unexpected key %+v", key)
+	}
+	if _, err := svc.AuthenticateUser(plain); err != nil {
+		t.Fatalf("extra key: %v", err)
+	}
+
+	clock.Set(expires)
+	if _, err := svc.AuthenticateUser(plain); err == nil {
+		t.Fatal("expired key accepted")
+	}
+
+	list, _ := svc.ListAPIKeys(user.ID)
+	var qrID int
+	for _, k := range list {
+		if k.Primary {
+			qrID = k.ID
+		}
+	}
+	if _, err := svc.RevokeAPIKey(user.ID+1, qrID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("foreign key: %v", err)
+	}
+	if _, err := svc.RevokeAPIKey(user.ID, qrID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AuthenticateUser(qrKey); err == nil {
+		t.Fatal("revoked key accepted")
+	}
+}
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// Проверка подписи APK по схемам v2/v3 (APK Signing Block перед central directory):
+// подпись signed data ключом подписанта, совпадение ключа с сертификатом и дайджест
+// содержимого файла. Схема v1 (только JAR-подпись) не поддерживается.
+
+var ErrAPKNotSigned = errors.New("apk has no v2/v3 signature")
+
+const (
+	apkSigBlockMagic    = "APK Sig Block 42"
+	apkSigSchemeV2ID    = 0x7109871a
+	apkSigSchemeV3ID    = 0xf05368c0
+	apkEOCDSignature    = 0x06054b50
+	apkEOCDMinSize      = 22
+	apkDigestChunkBytes = 1 << 20
+)
+
+type apkSigAlgorithm struct {
+	hash crypto.Hash
+	pss  bool
+}
+
+// apkSigAlgorithms — поддерживаемые алгоритмы подписи (DSA и verity не поддерживаются).
+var apkSigAlgorithms = map[uint32]apkSigAlgorithm{
+	0x0101: {hash: crypto.SHA256, pss: true},
+	0x0102: {hash: crypto.SHA512, pss: true},
+	0x0103: {hash: crypto.SHA256},
+	0x0104: {hash: crypto.SHA512},
+	0x0201: {hash: crypto.SHA256},
+	0x0202: {hash: crypto.SHA512},
+}
+
+// APKCertFingerprint — SHA-256 сертификата подписи в hex (как apksigner --print-certs).
+func APKCertFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeCertFingerprint убирает двоеточия и пробелы, приводит hex к нижнему регистру.
+func NormalizeCertFingerprint(fp string) string {
+	fp = strings.ToLower(strings.TrimSpace(fp))
+	return strings.NewReplacer(":", "", " ", "").Replace(fp)
+}
+
+// VerifyAPKSignature проверяет подпись APK (v3, иначе v2) и возвращает сертификаты подписантов.
+func VerifyAPKSignature(path string) ([]*x509.Certificate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open apk: %w", err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return verifyAPKSignature(f, stat.Size())
+}
+
+func verifyAPKSignature(r io.ReaderAt, size int64) ([]*x509.Certificate, error) {
+	eocdOffset, eocd, err := findAPKEOCD(r, size)
+	if err != nil {
+		return nil, err
+	}
+	cdOffset := int64(binary.LittleEndian.Uint32(eocd[16:20]))
+	cdSize := int64(binary.LittleEndian.Uint32(eocd[12:16]))
+	if cdOffset+cdSize != eocdOffset {
+		return nil, fmt.Errorf("apk: central directory не примыкает к EOCD")
+	}
+
+	blockStart, pairs, err := readAPKSigningBlock(r, cdOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme, ok := pairs[apkSigSchemeV3ID]
+	v3 := ok
+	if !ok {
+		if scheme, ok = pairs[apkSigSchemeV2ID]; !ok {
+			return nil, ErrAPKNotSigned
+		}
+	}
+
+	signers, err := apkLengthPrefixedSequence(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("apk signers: %w", err)
+	}
+	if len(signers) == 0 {
+		return nil, ErrAPKNotSigned
+	}
+
+	sections := apkDigestSections{r: r, blockStart: blockStart, cdOffset: cdOffset, eocdOffset: eocdOffset, eocd: eocd}
+	digests := make(map[uint32][]byte)
+	certs := make([]*x509.Certificate, 0, len(signers))
+	for i, signer := range signers {
+		cert, err := verifyAPKSigner(signer, v3, sections, digests)
+		if err != nil {
+			return nil, fmt.Errorf("apk signer %d: %w", i, err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+func findAPKEOCD(r io.ReaderAt, size int64) (int64, []byte, error) {
+	if size < apkEOCDMinSize {
+		return 0, nil, fmt.Errorf("apk: файл слишком мал")
+	}
+	tail := int64(apkEOCDMinSize + 0xffff)
+	if tail > size {
+		tail = size
+	}
+	buf := make([]byte, tail)
+	if _, err := r.ReadAt(buf, size-tail); err != nil && err != io.EOF {
+		return 0, nil, err
+	}
+	for i := len(buf) - apkEOCDMinSize; i >= 0; i-- {
+		if binary.LittleEndian.Uint32(buf[i:]) != apkEOCDSignature {
+			continue
+		}
+		commentLen := int(binary.LittleEndian.Uint16(buf[i+20:]))
+		if i+apkEOCDMinSize+commentLen == len(buf) {
+			eocd := append([]byte(nil), buf[i:]...)
+			return size - tail + int64(i), eocd, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("apk: не найден конец central directory")
+}
+
+// readAPKSigningBlock возвращает начало APK Signing Block и его пары ID → значение.
+func readAPKSigningBlock(r io.ReaderAt, cdOffset int64) (int64, map[uint32][]byte, error) {
+	if cdOffset < 32 {
+		return 0, nil, ErrAPKNotSigned
+	}
+	footer := make([]byte, 24)
+	if _, err := r.ReadAt(footer, cdOffset-24); err != nil {
+		return 0, nil, err
+	}
+	if string(footer[8:]) != apkSigBlockMagic {
+		return 0, nil, ErrAPKNotSigned
+	}
+	blockSize := int64(binary.LittleEndian.Uint64(footer[:8]))
+	blockStart := cdOffset - blockSize - 8
+	if blockSize < 24 || blockStart < 0 {
+		return 0, nil, fmt.Errorf("apk: некорректный размер signing block")
+	}
+	block := make([]byte, blockSize+8)
+	if _, err := r.ReadAt(block, blockStart); err != nil {
+		return 0, nil, err
+	}
+	if int64(binary.LittleEndian.Uint64(block[:8])) != blockSize {
+		return 0, nil, fmt.Errorf("apk: размеры signing block не совпадают")
+	}
+
+	pairs := make(map[uint32][]byte)
+	data := block[8 : len(block)-24]
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return 0, nil, fmt.Errorf("apk: обрезанная пара signing block")
+		}
+		n := binary.LittleEndian.Uint64(data[:8])
+		data = data[8:]
+		if n < 4 || n > uint64(len(data)) {
+			return 0, nil, fmt.Errorf("apk: некорректная длина пары signing block")
+		}
+		pairs[binary.LittleEndian.Uint32(data[:4])] = data[4:n]
+		data = data[n:]
+	}
+	return blockStart, pairs, nil
+}
+
+func verifyAPKSigner(signer []byte, v3 bool, sections apkDigestSections, digests map[uint32][]byte) (*x509.Certificate, error) {
+	signedData, rest, err := apkLengthPrefixed(signer)
+	if err != nil {
+		return nil, err
+	}
+	if v3 {
+		if len(rest) < 8 {
+			return nil, fmt.Errorf("обрезанный v3 signer")
+		}
+		rest = rest[8:] // minSdkVersion, maxSdkVersion
+	}
+	signaturesRaw, rest, err := apkLengthPrefixed(rest)
+	if err != nil {
+		return nil, err
+	}
+	publicKeyRaw, _, err := apkLengthPrefixed(rest)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(publicKeyRaw)
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+
+	// Самый сильный поддерживаемый алгоритм (SHA-512 предпочтительнее SHA-256).
+	signatures, err := apkLengthPrefixedItems(signaturesRaw)
+	if err != nil {
+		return nil, err
+	}
+	var algID uint32
+	var signature []byte
+	for _, s := range signatures {
+		if len(s) < 4 {
+			return nil, fmt.Errorf("обрезанная подпись")
+		}
+		id := binary.LittleEndian.Uint32(s[:4])
+		alg, ok := apkSigAlgorithms[id]
+		if !ok || (signature != nil && apkSigAlgorithms[algID].hash >= alg.hash) {
+			continue
+		}
+		sig, _, err := apkLengthPrefixed(s[4:])
+		if err != nil {
+			return nil, err
+		}
+		algID, signature = id, sig
+	}
+	if signature == nil {
+		return nil, fmt.Errorf("нет подписи с поддерживаемым алгоритмом")
+	}
+	alg := apkSigAlgorithms[algID]
+	if err := verifyAPKSignedData(publicKey, alg, signedData, signature); err != nil {
+		return nil, err
+	}
+
+	digestsRaw, rest, err := apkLengthPrefixed(signedData)
+	if err != nil {
+		return nil, err
+	}
+	certsRaw, _, err := apkLengthPrefixed(rest)
+	if err != nil {
+		return nil, err
+	}
+	certList, err := apkLengthPrefixedItems(certsRaw)
+	if err != nil || len(certList) == 0 {
+		return nil, fmt.Errorf("нет сертификата подписанта")
+	}
+	cert, err := x509.ParseCertificate(certList[0])
+	if err != nil {
+		return nil, fmt.Errorf("certificate: %w", err)
+	}
+	if !bytes.Equal(cert.RawSubjectPublicKeyInfo, publicKeyRaw) {
+		return nil, fmt.Errorf("ключ подписи не совпадает с сертификатом")
+	}
+
+	digestList, err := apkLengthPrefixedItems(digestsRaw)
+	if err != nil {
+		return nil, err
+	}
+	var expected []byte
+	for _, d := range digestList {
+		if len(d) < 4 || binary.LittleEndian.Uint32(d[:4]) != algID {
+			continue
+		}
+		if expected, _, err = apkLengthPrefixed(d[4:]); err != nil {
+			return nil, err
+		}
+	}
+	if expected == nil {
+		return nil, fmt.Errorf("нет дайджеста содержимого для алгоритма 0x%04x", algID)
+	}
+	actual, ok := digests[algID]
+	if !ok {
+		if actual, err = sections.digest(alg.hash); err != nil {
+			return nil, err
+		}
+		digests[algID] = actual
+	}
+	if !bytes.Equal(actual, expected) {
+		return nil, fmt.Errorf("дайджест содержимого APK не совпадает с подписью")
+	}
+	return cert, nil
+}
+
+func verifyAPKSignedData(publicKey interface{}, alg apkSigAlgorithm, signedData, signature []byte) error {
+	h := alg.hash.New()
+	h.Write(signedData)
+	sum := h.Sum(nil)
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if alg.pss {
+			return rsa.VerifyPSS(key, alg.hash, sum, signature, &rsa.PSSOptions{SaltLength: alg.hash.Size()})
+		}
+		return rsa.VerifyPKCS1v15(key, alg.hash, sum, signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, sum, signature) {
+			return fmt.Errorf("подпись ECDSA недействительна")
+		}
+		return nil
+	default:
+		return fmt.Errorf("неподдерживаемый тип ключа %T", publicKey)
+	}
+}
+
+// apkDigestSections — три защищаемых подписью участка файла: содержимое ZIP до
+// signing block, central directory и EOCD (смещение CD заменено на начало блока).
+type apkDigestSections struct {
+	r          io.ReaderAt
+	blockStart int64
+	cdOffset   int64
+	eocdOffset int64
+	eocd       []byte
+}
+
+func (s apkDigestSections) digest(algo crypto.Hash) ([]byte, error) {
+	eocd := append([]byte(nil), s.eocd...)
+	binary.LittleEndian.PutUint32(eocd[16:20], uint32(s.blockStart))
+	readers := []io.ReaderAt{
+		io.NewSectionReader(s.r, 0, s.blockStart),
+		io.NewSectionReader(s.r, s.cdOffset, s.eocdOffset-s.cdOffset),
+		bytes.NewReader(eocd),
+	}
+	sizes := []int64{s.blockStart, s.eocdOffset - s.cdOffset, int64(len(eocd))}
+	return apkChunkedDigest(algo, readers, sizes)
+}
+
+func apkChunkedDigest(algo crypto.Hash, sections []io.ReaderAt, sizes []int64) ([]byte, error) {
+	newHash := sha256.New
+	if algo == crypto.SHA512 {
+		newHash = sha512.New
+	}
+	var chunkDigests bytes.Buffer
+	var chunks uint32
+	buf := make([]byte, apkDigestChunkBytes)
+	prefix := make([]byte, 5)
+	for i, section := range sections {
+		for off := int64(0); off < sizes[i]; off += apkDigestChunkBytes {
+			n := sizes[i] - off
+			if n > apkDigestChunkBytes {
+				n = apkDigestChunkBytes
+			}
+			if _, err := section.ReadAt(buf[:n], off); err != nil && err != io.EOF {
+				return nil, err
+			}
+			var h hash.Hash = newHash()
+			prefix[0] = 0xa5
+			binary.LittleEndian.PutUint32(prefix[1:], uint32(n))
+			h.Write(prefix)
+			h.Write(buf[:n])
+			chunkDigests.Write(h.Sum(nil))
+			chunks++
+		}
+	}
+	h := newHash()
+	prefix[0] = 0x5a
+	binary.LittleEndian.PutUint32(prefix[1:], chunks)
+	h.Write(prefix)
+	h.Write(chunkDigests.Bytes())
+	return h.Sum(nil), nil
+}
+
+func apkLengthPrefixed(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("apk: обрезанная запись подписи")
+	}
+	n := binary.LittleEndian.Uint32(data[:4])
+	if uint64(n) > uint64(len(data)-4) {
+		return nil, nil, fmt.Errorf("apk: некорректная длина записи подписи")
+	}
+	return data[4 : 4+n], data[4+n:], nil
+}
+
+// apkLengthPrefixedSequence — последовательность с общим префиксом длины.
+func apkLengthPrefixedSequence(data []byte) ([][]byte, error) {
+	seq, _, err := apkLengthPrefixed(data)
+	if err != nil {
+		return nil, err
+	}
+	return apkLengthPrefixedItems(seq)
+}
+
+// apkLengthPrefixedItems — подряд идущие записи с префиксами длины (uint32 LE).
+func apkLengthPrefixedItems(seq []byte) ([][]byte, error) {
+	var items [][]byte
+	var err error
+	for len(seq) > 0 {
+		var item []byte
+		if item, seq, err = apkLengthPrefixed(seq); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func apkTestLP(parts ...[]byte) []byte {
+	var body []byte
+	for _, p := range parts {
+		body = append(body, p...)
+	}
+	out := binary.LittleEndian.AppendUint32(nil, uint32(len(body)))
+	return append(out, body...)
+}
+
+func apkTestU32(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+
+func apkTestU64(v uint64) []byte { return binary.LittleEndian.AppendUint64(nil, v) }
+
+func newAPKTestSigner(t *testing.T) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "locator test"},
+		NotBefore:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2056, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+func testUnsignedAPK(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{
+		"AndroidManifest.xml": "manifest",
+		"classes.dex":         string(bytes.Repeat([]byte("dex"), 1000)),
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// signTestAPK вставляет APK Signing Block (схема v2, ECDSA SHA-256) перед central directory.
+func signTestAPK(t *testing.T, apk []byte, key *ecdsa.PrivateKey, cert *x509.Certificate) []byte {
+	t.Helper()
+	eocdOffset, eocd, err := findAPKEOCD(bytes.NewReader(apk), int64(len(apk)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdOffset := int64(binary.LittleEndian.Uint32(eocd[16:20]))
+	sections := apkDigestSections{
+		r: bytes.NewReader(apk), blockStart: cdOffset, cdOffset: cdOffset, eocdOffset: eocdOffset, eocd: eocd,
+	}
+	digest, err := sections.digest(crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const alg = 0x0201
+	signedData := append(apkTestLP(apkTestLP(apkTestU32(alg), apkTestLP(digest))), apkTestLP(apkTestLP(cert.Raw))...)
+	signedData = append(signedData, apkTestLP()...)
+	sum := sha256.Sum256(signedData)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := apkTestLP(signedData)
+	signer = append(signer, apkTestLP(apkTestLP(apkTestU32(alg), apkTestLP(sig)))...)
+	signer = append(signer, apkTestLP(cert.RawSubjectPublicKeyInfo)...)
+	value := apkTestLP(apkTestLP(signer))
+
+	pair := append(apkTestU64(uint64(4+len(value))), apkTestU32(apkSigSchemeV2ID)...)
+	pair = append(pair, value...)
+	blockSize := uint64(len(pair) + 8 + len(apkSigBlockMagic))
+	block := append(apkTestU64(blockSize), pair...)
+	block = append(block, apkTestU64(blockSize)...)
+	block = append(block, apkSigBlockMagic...)
+
+	newEOCD := append([]byte(nil), eocd...)
+	binary.LittleEndian.PutUint32(newEOCD[16:20], uint32(cdOffset)+uint32(len(block)))
+	out := append([]byte(nil), apk[:cdOffset]...)
+	out = append(out, block...)
+	out = append(out, apk[cdOffset:eocdOffset]...)
+	return append(out, newEOCD...)
+}
+
+func TestVerifyAPKSignature_valid(t *testing.T) {
+	key, cert := newAPKTestSigner(t)
+	signed := signTestAPK(t, testUnsignedAPK(t), key, cert)
+
+	path := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(path, signed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	certs, err := VerifyAPKSignature(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 1 || APKCertFingerprint(certs[0]) != APKCertFingerprint(cert) {
+		t.Fatalf("unexpected signer certs %v", certs)
+	}
+}
+
+func TestVerifyAPKSignature_rejectsTamperedAndUnsigned(t *testing.T) {
+	key, cert := newAPKTestSigner(t)
+	unsigned := testUnsignedAPK(t)
+
+	if _, err := verifyAPKSignature(bytes.NewReader(unsigned), int64(len(unsigned))); !errors.Is(err, ErrAPKNotSigned) {
+		t.Fatalf("expected ErrAPKNotSigned, got %v", err)
+	}
+
+	signed := signTestAPK(t, unsigned, key, cert)
+	tampered := append([]byte(nil), signed...)
+	tampered[40] ^= 0xff // данные первой записи ZIP
+	if _, err := verifyAPKSignature(bytes.NewReader(tampered), int64(len(tampered))); err == nil {
+		t.Fatal("tampered APK must fail verification")
+	}
+}
+
+func TestNormalizeCertFingerprint(t *testing.T) {
+	if got := NormalizeCertFingerprint(" AB:cd:01 "); got != "abcd01" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -2,15 +2,30 @@ package service
 
 import (
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"locator/models"
+
+	"gorm.io/gorm"
 )
 
 // WriteReleaseManifest обновляет manifest.json по метаданным из APK.
-func WriteReleaseManifest(apkPath, manifestPath, baseURL, changelog string) (map[string]interface{}, error) {
+// force — обязательная установка на телефоне (без возможности отложить).
+func WriteReleaseManifest(apkPath, manifestPath, baseURL, changelog string, force bool) (map[string]interface{}, error) {
 	meta, err := ReadAPKMeta(apkPath)
 	if err != nil {
 		return nil, err
@@ -32,7 +47,7 @@ func WriteReleaseManifest(apkPath, manifestPath, baseURL, changelog string) (map
 		"package_name": meta.PackageName,
 		"filename":     filename,
 		"sha256":       sum,
-		"force":        true,
+		"force":        force,
 		"changelog":    changelog,
 		"url":          baseURL + "/static/releases/" + filename,
 	}
@@ -55,3 +70,713 @@ func sha256File(path string) (string, error) {
 	h := sha256.Sum256(data)
 	return hex.EncodeToString(h[:]), nil
 }
+
+var (
+	ErrAppReleaseNotFound = errors.New("app release not found")
+	ErrAppReleaseInvalid  = errors.New("invalid app release")
+	ErrAppReleaseExists   = errors.New("app release file already exists")
+	// ErrAppReleaseVersionNotNewer — version_code не больше уже опубликованного в канале.
+	ErrAppReleaseVersionNotNewer = errors.New("app release version code is not newer than current release")
+)
+
+// defaultMaxAPKBytes — лимит размера загружаемого APK по умолчанию.
+const defaultMaxAPKBytes = 200 << 20
+
+// appUpdateRetryAfter — повтор app_update того же релиза (истёк, не установлен ниже минимума)
+// не чаще этого интервала.
+const appUpdateRetryAfter = 6 * time.Hour
+
+const (
+	defaultAppReleaseMaxFailurePercent = 20
+	defaultAppReleaseMinFailureSamples = 5
+)
+
+var appReleaseChannelRe = regexp.MustCompile(`^(stable|beta|group:[a-z0-9_-]{1,50})$`)
+
+// AppReleaseService — релизы APK по каналам, поэтапная раскатка и минимальная версия.
+type AppReleaseService struct {
+	DAO         appReleaseRepository
+	CommandLog  appUpdateCommandRepository
+	Commands    deviceCommandEnqueuer
+	Reports     deviceReportRepository
+	ReleasesDir string
+	BaseURL     string
+
+	// ExpectedPackage — package name приложения; APK другого приложения отклоняется.
+	ExpectedPackage string
+	// CertFingerprints — SHA-256 доверенных сертификатов подписи. Пусто — APK не
+	// публикуется, если не включён AllowUnpinnedCert (только для разработки).
+	CertFingerprints  []string
+	AllowUnpinnedCert bool
+	// MaxAPKBytes — лимит размера загружаемого APK (0 — 200 МБ).
+	MaxAPKBytes int64
+
+	mu sync.Mutex // проверка version_code и регистрация релиза — атомарно
+}
+
+func NewAppReleaseService(
+	dao appReleaseRepository,
+	commandLog appUpdateCommandRepository,
+	commands deviceCommandEnqueuer,
+	reports deviceReportRepository,
+	releasesDir, baseURL string,
+) *AppReleaseService {
+	return &AppReleaseService{
+		DAO:         dao,
+		CommandLog:  commandLog,
+		Commands:    commands,
+		Reports:     reports,
+		ReleasesDir: releasesDir,
+		BaseURL:     baseURL,
+	}
+}
+
+// AppReleaseInput — создание релиза из APK, уже загруженного в ReleasesDir.
+type AppReleaseInput struct {
+	// OrganizationID — организация релиза (задаётся по текущему пользователю, 0 — организация по умолчанию).
+	OrganizationID    int    `json:"-"`
+	Filename          string `json:"filename"`
+	Channel           string `json:"channel"`
+	Changelog         string `json:"changelog"`
+	Force             bool   `json:"force"`
+	RolloutPercent    *int   `json:"rollout_percent"`
+	MaxFailurePercent *int   `json:"max_failure_percent"`
+	MinFailureSamples *int   `json:"min_failure_samples"`
+}
+
+// AppReleaseUpdateInput — частичное изменение релиза (nil — не менять).
+type AppReleaseUpdateInput struct {
+	Changelog         *string `json:"changelog"`
+	Force             *bool   `json:"force"`
+	RolloutPercent    *int    `json:"rollout_percent"`
+	MaxFailurePercent *int    `json:"max_failure_percent"`
+	MinFailureSamples *int    `json:"min_failure_samples"`
+}
+
+// AppReleaseStats — итоги команд app_update по релизу.
+type AppReleaseStats struct {
+	Release        models.AppRelease `json:"release"`
+	Completed      int64             `json:"completed"`
+	Failed         int64             `json:"failed"`
+	FailurePercent float64           `json:"failure_percent"`
+}
+
+// NormalizeAppReleaseChannel приводит имя канала к виду stable, beta или group:<имя>.
+func NormalizeAppReleaseChannel(channel string) (string, error) {
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	if channel == "" {
+		return models.AppReleaseChannelStable, nil
+	}
+	if !appReleaseChannelRe.MatchString(channel) {
+		return "", fmt.Errorf("%w: канал %q (stable, beta или group:<имя>)", ErrAppReleaseInvalid, channel)
+	}
+	return channel, nil
+}
+
+func validateAppReleasePercent(name string, v int) error {
+	if v < 0 || v > 100 {
+		return fmt.Errorf("%w: %s должен быть от 0 до 100", ErrAppReleaseInvalid, name)
+	}
+	return nil
+}
+
+// appReleaseBucket — стабильная корзина 0–99 устройства для релиза: при росте
+// rollout_percent уже получившие релиз устройства остаются в раскатке.
+func appReleaseBucket(releaseID, userID int) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d:%d", releaseID, userID)
+	return int(h.Sum32() % 100)
+}
+
+func appReleaseInRollout(r *models.AppRelease, userID int) bool {
+	return appReleaseBucket(r.ID, userID) < r.RolloutPercent
+}
+
+// CreateRelease регистрирует APK, уже лежащий в ReleasesDir (скрипты публикации),
+// с теми же проверками, что и загрузка через UploadRelease.
+func (svc *AppReleaseService) CreateRelease(input AppReleaseInput) (*models.AppRelease, error) {
+	filename := filepath.Base(strings.TrimSpace(input.Filename))
+	if filename == "" || filename == "." || !strings.HasSuffix(strings.ToLower(filename), ".apk") {
+		return nil, fmt.Errorf("%w: укажите filename APK", ErrAppReleaseInvalid)
+	}
+	apkPath := filepath.Join(svc.ReleasesDir, filename)
+	sum, err := sha256File(apkPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: файл %s не найден", ErrAppReleaseInvalid, filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	release, err := svc.prepareRelease(apkPath, sum, input)
+	if err != nil {
+		return nil, err
+	}
+	release.Filename = filename
+	if err := svc.saveRelease(release); err != nil {
+		return nil, err
+	}
+	return release, nil
+}
+
+// UploadRelease пишет APK из потока во временный файл в ReleasesDir, проверяет его и
+// только затем переименовывает в locator-<версия>-<build>.apk и регистрирует релиз.
+// При любой ошибке файл удаляется — непроверенный APK не попадает к устройствам.
+func (svc *AppReleaseService) UploadRelease(src io.Reader, input AppReleaseInput) (*models.AppRelease, error) {
+	if err := os.MkdirAll(svc.ReleasesDir, 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(svc.ReleasesDir, ".upload-*.apk.tmp")
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	limit := svc.MaxAPKBytes
+	if limit <= 0 {
+		limit = defaultMaxAPKBytes
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(src, limit+1))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("запись APK: %w", err)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("%w: пустой файл", ErrAppReleaseInvalid)
+	}
+	if n > limit {
+		return nil, fmt.Errorf("%w: APK больше %d МБ", ErrAppReleaseInvalid, limit>>20)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	release, err := svc.prepareRelease(tmpPath, sum, input)
+	if err != nil {
+		return nil, err
+	}
+
+	release.Filename = appReleaseFilename(release)
+	finalPath := filepath.Join(svc.ReleasesDir, release.Filename)
+	created := true
+	if existingSum, err := sha256File(finalPath); err == nil {
+		if existingSum != sum {
+			return nil, fmt.Errorf("%w: файл %s уже существует с другим содержимым", ErrAppReleaseExists, release.Filename)
+		}
+		created = false
+	} else if err := os.Rename(tmpPath, finalPath); err != nil {
+		return nil, err
+	}
+	if err := svc.saveRelease(release); err != nil {
+		if created {
+			_ = os.Remove(finalPath)
+		}
+		return nil, err
+	}
+	return release, nil
+}
+
+// prepareRelease проверяет APK (manifest, подпись, package, сертификат) и собирает релиз.
+func (svc *AppReleaseService) prepareRelease(apkPath, sum string, input AppReleaseInput) (*models.AppRelease, error) {
+	meta, err := ReadAPKMeta(apkPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAppReleaseInvalid, err)
+	}
+	certs, err := VerifyAPKSignature(apkPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: подпись APK: %v", ErrAppReleaseInvalid, err)
+	}
+	if err := svc.checkAPK(meta, certs); err != nil {
+		return nil, err
+	}
+	return svc.buildRelease(meta, sum, input)
+}
+
+// checkAPK — package name приложения и закреплённые отпечатки сертификатов подписи.
+func (svc *AppReleaseService) checkAPK(meta *APKMeta, certs []*x509.Certificate) error {
+	if svc.ExpectedPackage != "" && meta.PackageName != svc.ExpectedPackage {
+		return fmt.Errorf("%w: package %q, ожидается %q", ErrAppReleaseInvalid, meta.PackageName, svc.ExpectedPackage)
+	}
+	if len(svc.CertFingerprints) == 0 {
+		if svc.AllowUnpinnedCert {
+			return nil
+		}
+		return fmt.Errorf("%w: не задан ни один доверенный сертификат подписи (RELEASE_CERT_SHA256)", ErrAppReleaseInvalid)
+	}
+	pinned := make(map[string]bool, len(svc.CertFingerprints))
+	for _, fp := range svc.CertFingerprints {
+		pinned[NormalizeCertFingerprint(fp)] = true
+	}
+	for _, cert := range certs {
+		if fp := APKCertFingerprint(cert); !pinned[fp] {
+			return fmt.Errorf("%w: сертификат подписи %s не входит в доверенные", ErrAppReleaseInvalid, fp)
+		}
+	}
+	return nil
+}
+
+// buildRelease собирает релиз канала; version_code должен быть больше всех релизов канала.
+func (svc *AppReleaseService) buildRelease(meta *APKMeta, sum string, input AppReleaseInput) (*models.AppRelease, error) {
+	channel, err := NormalizeAppReleaseChannel(input.Channel)
+	if err != nil {
+		return nil, err
+	}
+	release := &models.AppRelease{
+		OrganizationID:    organizationOrDefault(input.OrganizationID),
+		Channel:           channel,
+		VersionName:       meta.VersionName,
+		VersionCode:       int64(meta.VersionCode),
+		PackageName:       meta.PackageName,
+		SHA256:            sum,
+		Changelog:         strings.TrimSpace(input.Changelog),
+		Force:             input.Force,
+		RolloutPercent:    100,
+		Status:            models.AppReleaseStatusActive,
+		MaxFailurePercent: defaultAppReleaseMaxFailurePercent,
+		MinFailureSamples: defaultAppReleaseMinFailureSamples,
+	}
+	if release.Changelog == "" {
+		release.Changelog = fmt.Sprintf("Release %s (build %d)", meta.VersionName, meta.VersionCode)
+	}
+	if err := applyAppReleaseUpdate(release, AppReleaseUpdateInput{
+		RolloutPercent:    input.RolloutPercent,
+		MaxFailurePercent: input.MaxFailurePercent,
+		MinFailureSamples: input.MinFailureSamples,
+	}); err != nil {
+		return nil, err
+	}
+
+	existing, err := svc.DAO.ListReleases(release.OrganizationID, channel)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range existing {
+		if r.VersionCode >= release.VersionCode {
+			return nil, fmt.Errorf("%w: version_code %d, в канале %s уже есть %s (build %d)",
+				ErrAppReleaseVersionNotNewer, release.VersionCode, channel, r.VersionName, r.VersionCode)
+		}
+	}
+	return release, nil
+}
+
+func (svc *AppReleaseService) saveRelease(release *models.AppRelease) error {
+	if err := svc.DAO.CreateRelease(release); err != nil {
+		return err
+	}
+	slog.Info("Релиз опубликован", "release_id", release.ID, "version", release.VersionName,
+		"version_code", release.VersionCode, "channel", release.Channel, "rollout_percent", release.RolloutPercent)
+	return nil
+}
+
+// appReleaseFilename — locator-<версия>-<build>.apk; для организаций кроме
+// организации по умолчанию имя дополняется её ID (каталог релизов общий).
+func appReleaseFilename(r *models.AppRelease) string {
+	if r.OrganizationID != models.DefaultOrganizationID {
+		return fmt.Sprintf("locator-org%d-%s-%d.apk", r.OrganizationID, appReleaseFileSafe(r.VersionName), r.VersionCode)
+	}
+	return fmt.Sprintf("locator-%s-%d.apk", appReleaseFileSafe(r.VersionName), r.VersionCode)
+}
+
+// appReleaseFileSafe — версия для имени файла: только буквы, цифры, точка, дефис и _.
+func appReleaseFileSafe(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, v)
+}
+
+func applyAppReleaseUpdate(r *models.AppRelease, input AppReleaseUpdateInput) error {
+	if input.RolloutPercent != nil {
+		if err := validateAppReleasePercent("rollout_percent", *input.RolloutPercent); err != nil {
+			return err
+		}
+		r.RolloutPercent = *input.RolloutPercent
+	}
+	if input.MaxFailurePercent != nil {
+		if err := validateAppReleasePercent("max_failure_percent", *input.MaxFailurePercent); err != nil {
+			return err
+		}
+		r.MaxFailurePercent = *input.MaxFailurePercent
+	}
+	if input.MinFailureSamples != nil {
+		if *input.MinFailureSamples < 1 {
+			return fmt.Errorf("%w: min_failure_samples должен быть не меньше 1", ErrAppReleaseInvalid)
+		}
+		r.MinFailureSamples = *input.MinFailureSamples
+	}
+	if input.Force != nil {
+		r.Force = *input.Force
+	}
+	if input.Changelog != nil {
+		r.Changelog = strings.TrimSpace(*input.Changelog)
+	}
+	return nil
+}
+
+// GetRelease возвращает релиз организации по ID; релиз другой организации не находится.
+func (svc *AppReleaseService) GetRelease(organizationID, id int) (*models.AppRelease, error) {
+	r, err := svc.DAO.GetReleaseByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && organizationOrDefault(r.OrganizationID) != organizationOrDefault(organizationID)) {
+		return nil, ErrAppReleaseNotFound
+	}
+	return r, err
+}
+
+// ListReleases — история релизов канала организации (пустой channel — все каналы).
+func (svc *AppReleaseService) ListReleases(organizationID int, channel string) ([]models.AppRelease, error) {
+	if channel != "" {
+		var err error
+		if channel, err = NormalizeAppReleaseChannel(channel); err != nil {
+			return nil, err
+		}
+	}
+	return svc.DAO.ListReleases(organizationOrDefault(organizationID), channel)
+}
+
+// UpdateRelease меняет долю раскатки, порог автопаузы, force или changelog.
+func (svc *AppReleaseService) UpdateRelease(organizationID, id int, input AppReleaseUpdateInput) (*models.AppRelease, error) {
+	r, err := svc.GetRelease(organizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyAppReleaseUpdate(r, input); err != nil {
+		return nil, err
+	}
+	if err := svc.DAO.UpdateRelease(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetReleaseStatus ставит релиз на паузу, возобновляет или архивирует его.
+func (svc *AppReleaseService) SetReleaseStatus(organizationID, id int, status, reason string, now time.Time) (*models.AppRelease, error) {
+	r, err := svc.GetRelease(organizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case models.AppReleaseStatusPaused:
+		r.PauseReason = strings.TrimSpace(reason)
+		r.PausedAt = &now
+	case models.AppReleaseStatusActive, models.AppReleaseStatusArchived:
+		r.PauseReason = ""
+		r.PausedAt = nil
+	default:
+		return nil, fmt.Errorf("%w: статус %q", ErrAppReleaseInvalid, status)
+	}
+	r.Status = status
+	if err := svc.DAO.UpdateRelease(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReleaseStats — доля неуспешных app_update по релизу.
+func (svc *AppReleaseService) ReleaseStats(organizationID, id int) (*AppReleaseStats, error) {
+	r, err := svc.GetRelease(organizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	total, failed, err := svc.CommandLog.CountAppUpdateResults(id)
+	if err != nil {
+		return nil, err
+	}
+	stats := &AppReleaseStats{Release: *r, Completed: total, Failed: failed}
+	if total > 0 {
+		stats.FailurePercent = math.Round(float64(failed)*1000/float64(total)) / 10
+	}
+	return stats, nil
+}
+
+// ListChannels — настройки каналов организации; stable и beta возвращаются всегда.
+func (svc *AppReleaseService) ListChannels(organizationID int) ([]models.AppReleaseChannel, error) {
+	organizationID = organizationOrDefault(organizationID)
+	channels, err := svc.DAO.GetAllChannels(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(channels))
+	for _, ch := range channels {
+		seen[ch.Name] = true
+	}
+	for _, name := range []string{models.AppReleaseChannelBeta, models.AppReleaseChannelStable} {
+		if !seen[name] {
+			channels = append([]models.AppReleaseChannel{{OrganizationID: organizationID, Name: name}}, channels...)
+		}
+	}
+	return channels, nil
+}
+
+// SetChannelMinVersion задаёт минимальную поддерживаемую версию канала ("" — снять).
+func (svc *AppReleaseService) SetChannelMinVersion(organizationID int, channel, minVersion string) (*models.AppReleaseChannel, error) {
+	name, err := NormalizeAppReleaseChannel(channel)
+	if err != nil {
+		return nil, err
+	}
+	minVersion = strings.TrimSpace(minVersion)
+	if minVersion != "" && len(appVersionParts(minVersion)) == 0 {
+		return nil, fmt.Errorf("%w: min_version %q", ErrAppReleaseInvalid, minVersion)
+	}
+	ch := &models.AppReleaseChannel{OrganizationID: organizationOrDefault(organizationID), Name: name, MinVersion: minVersion}
+	if err := svc.DAO.SaveChannel(ch); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// UserChannel — канал обновлений пользователя (stable, если не назначен).
+func (svc *AppReleaseService) UserChannel(userID int) (string, error) {
+	m, err := svc.DAO.GetMember(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.AppReleaseChannelStable, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return m.Channel, nil
+}
+
+// ListMembers — пользователи организации с назначенным (не stable) каналом.
+func (svc *AppReleaseService) ListMembers(organizationID int) ([]models.AppReleaseMember, error) {
+	return svc.DAO.GetAllMembers(organizationOrDefault(organizationID))
+}
+
+// SetUserChannel назначает пользователю канал; stable удаляет назначение.
+func (svc *AppReleaseService) SetUserChannel(userID int, channel string) (string, error) {
+	name, err := NormalizeAppReleaseChannel(channel)
+	if err != nil {
+		return "", err
+	}
+	if name == models.AppReleaseChannelStable {
+		return name, svc.DAO.DeleteMember(userID)
+	}
+	return name, svc.DAO.SaveMember(&models.AppReleaseMember{UserID: userID, Channel: name})
+}
+
+// userReleaseChannels — канал пользователя и stable (beta и группы получают и стабильные сборки).
+func userReleaseChannels(channel string) []string {
+	if channel == models.AppReleaseChannelStable {
+		return []string{channel}
+	}
+	return []string{channel, models.AppReleaseChannelStable}
+}
+
+// minSupportedVersion — наибольшая из минимальных версий каналов пользователя.
+func (svc *AppReleaseService) minSupportedVersion(organizationID int, channels []string) (string, error) {
+	minVersion := ""
+	for _, name := range channels {
+		ch, err := svc.DAO.GetChannel(organizationID, name)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if ch.MinVersion != "" && (minVersion == "" || compareAppVersions(ch.MinVersion, minVersion) > 0) {
+			minVersion = ch.MinVersion
+		}
+	}
+	return minVersion, nil
+}
+
+// selectAppRelease выбирает релиз для устройства версии current.
+// releases — активные релизы, новые версии первыми. Сначала — самый новый релиз,
+// в раскатку которого попадает устройство; если устройство ниже minVersion,
+// а такого релиза нет или он сам ниже минимума, — самый ранний релиз не ниже
+// минимума вне зависимости от раскатки. forced — устройство ниже минимума.
+func selectAppRelease(releases []models.AppRelease, userID int, current, minVersion string) (target *models.AppRelease, forced bool) {
+	for i := range releases {
+		r := &releases[i]
+		if compareAppVersions(r.VersionName, current) <= 0 {
+			break
+		}
+		if appReleaseInRollout(r, userID) {
+			target = r
+			break
+		}
+	}
+
+	below := minVersion != "" && compareAppVersions(current, minVersion) < 0
+	if below && (target == nil || compareAppVersions(target.VersionName, minVersion) < 0) {
+		target = nil
+		for i := len(releases) - 1; i >= 0; i-- {
+			if compareAppVersions(releases[i].VersionName, minVersion) >= 0 {
+				target = &releases[i]
+				break
+			}
+		}
+	}
+	return target, below && target != nil
+}
+
+// CheckDevice вызывается при опросе устройства: если для него есть более новый релиз
+// (по раскатке его канала) или версия ниже минимальной, ставит app_update в очередь.
+// Релизы и минимальные версии берутся из организации пользователя.
+// Повторно тот же релиз не отправляется, пока прошлая команда в работе.
+func (svc *AppReleaseService) CheckDevice(userID, organizationID int, now time.Time) (*models.DeviceCommand, error) {
+	if svc == nil || svc.Commands == nil {
+		return nil, nil
+	}
+	report, err := svc.Reports.GetLatestByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	current := strings.TrimSpace(report.AppVersion)
+	if len(appVersionParts(current)) == 0 {
+		return nil, nil
+	}
+
+	channel, err := svc.UserChannel(userID)
+	if err != nil {
+		return nil, err
+	}
+	organizationID = organizationOrDefault(organizationID)
+	channels := userReleaseChannels(channel)
+	releases, err := svc.DAO.GetActiveReleases(organizationID, channels)
+	if err != nil || len(releases) == 0 {
+		return nil, err
+	}
+	minVersion, err := svc.minSupportedVersion(organizationID, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	target, forced := selectAppRelease(releases, userID, current, minVersion)
+	if target == nil {
+		return nil, nil
+	}
+
+	last, err := svc.CommandLog.GetLatestByType(userID, models.DeviceCommandTypeAppUpdate)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if last != nil && commandReleaseID(last) == target.ID {
+		switch last.Status {
+		case models.DeviceCommandStatusPending, models.DeviceCommandStatusDelivered:
+			return nil, nil
+		case models.DeviceCommandStatusAcked, models.DeviceCommandStatusFailed:
+			if !forced {
+				return nil, nil
+			}
+		}
+		if now.Sub(last.CreatedAt) < appUpdateRetryAfter {
+			return nil, nil
+		}
+	}
+
+	cmd, err := svc.Commands.EnqueueCommand(userID, models.DeviceCommandTypeAppUpdate, svc.AppUpdatePayload(target, forced))
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Поставлен app_update", "user_id", userID, "release_id", target.ID, "version", target.VersionName,
+		"channel", target.Channel, "forced", forced)
+	return cmd, nil
+}
+
+// AppUpdatePayload — payload команды app_update для релиза (формат manifest.json).
+func (svc *AppReleaseService) AppUpdatePayload(r *models.AppRelease, forced bool) map[string]interface{} {
+	return map[string]interface{}{
+		"url":          svc.BaseURL + "/static/releases/" + r.Filename,
+		"version":      r.VersionName,
+		"version_code": r.VersionCode,
+		"sha256":       r.SHA256,
+		"force":        r.Force || forced,
+		"changelog":    r.Changelog,
+		"filename":     r.Filename,
+		"release_id":   r.ID,
+		"channel":      r.Channel,
+	}
+}
+
+// LatestManifest — manifest самого нового активного релиза канала организации на 100%
+// раскатки (для GET /api/app/release/latest); nil, если такого нет.
+func (svc *AppReleaseService) LatestManifest(organizationID int, channel string) (map[string]interface{}, error) {
+	releases, err := svc.DAO.GetActiveReleases(organizationOrDefault(organizationID), []string{channel})
+	if err != nil {
+		return nil, err
+	}
+	for i := range releases {
+		r := &releases[i]
+		if r.RolloutPercent < 100 {
+			continue
+		}
+		return map[string]interface{}{
+			"version_name": r.VersionName,
+			"version_code": r.VersionCode,
+			"package_name": r.PackageName,
+			"filename":     r.Filename,
+			"sha256":       r.SHA256,
+			"force":        r.Force,
+			"changelog":    r.Changelog,
+			"url":          svc.BaseURL + "/static/releases/" + r.Filename,
+			"release_id":   r.ID,
+			"channel":      r.Channel,
+		}, nil
+	}
+	return nil, nil
+}
+
+// OnAppUpdateResult вызывается после финального ack команды app_update: при превышении
+// порога ошибок активный релиз ставится на паузу.
+func (svc *AppReleaseService) OnAppUpdateResult(cmd *models.DeviceCommand, now time.Time) {
+	releaseID := commandReleaseID(cmd)
+	if releaseID == 0 {
+		return
+	}
+	r, err := svc.DAO.GetReleaseByID(releaseID)
+	if err != nil || r.Status != models.AppReleaseStatusActive {
+		return
+	}
+	total, failed, err := svc.CommandLog.CountAppUpdateResults(releaseID)
+	if err != nil {
+		slog.Error("Ошибка статистики релиза", "release_id", releaseID, "error", err)
+		return
+	}
+	if !appReleaseFailureExceeded(r, total, failed) {
+		return
+	}
+	reason := fmt.Sprintf("Автопауза: %d из %d обновлений завершились ошибкой (порог %d%%)",
+		failed, total, r.MaxFailurePercent)
+	if _, err := svc.SetReleaseStatus(r.OrganizationID, r.ID, models.AppReleaseStatusPaused, reason, now); err != nil {
+		slog.Error("Не удалось приостановить релиз", "release_id", r.ID, "error", err)
+		return
+	}
+	slog.Warn("Релиз приостановлен", "release_id", r.ID, "version", r.VersionName, "reason", reason)
+}
+
+func appReleaseFailureExceeded(r *models.AppRelease, total, failed int64) bool {
+	if total == 0 || total < int64(r.MinFailureSamples) {
+		return false
+	}
+	return failed*100 > int64(r.MaxFailurePercent)*total
+}
+
+// commandReleaseID — release_id из payload app_update (0 — команда не из релиза).
+func commandReleaseID(cmd *models.DeviceCommand) int {
+	payload, err := CommandPayloadMap(cmd)
+	if err != nil {
+		return 0
+	}
+	switch v := payload["release_id"].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
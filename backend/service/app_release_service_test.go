@@ -0,0 +1,447 @@
+package service
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"locator/models"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type fakeAppReleaseRepo struct {
+	releases map[int]*models.AppRelease
+	channels map[string]models.AppReleaseChannel // ключ — "<организация>/<канал>"
+	members  map[int]string
+	nextID   int
+}
+
+func newFakeAppReleaseRepo(releases ...models.AppRelease) *fakeAppReleaseRepo {
+	f := &fakeAppReleaseRepo{
+		releases: map[int]*models.AppRelease{},
+		channels: map[string]models.AppReleaseChannel{},
+		members:  map[int]string{},
+	}
+	for i := range releases {
+		r := releases[i]
+		_ = f.CreateRelease(&r)
+	}
+	return f
+}
+
+func (f *fakeAppReleaseRepo) CreateRelease(r *models.AppRelease) error {
+	if r.ID == 0 {
+		f.nextID++
+		r.ID = f.nextID
+	}
+	cp := *r
+	f.releases[r.ID] = &cp
+	return nil
+}
+
+func (f *fakeAppReleaseRepo) UpdateRelease(r *models.AppRelease) error {
+	cp := *r
+	f.releases[r.ID] = &cp
+	return nil
+}
+
+func (f *fakeAppReleaseRepo) GetReleaseByID(id int) (*models.AppRelease, error) {
+	r, ok := f.releases[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	cp := *r
+	return &cp, nil
+}
+
+func (f *fakeAppReleaseRepo) sorted(match func(*models.AppRelease) bool) []models.AppRelease {
+	var out []models.AppRelease
+	for _, r := range f.releases {
+		if match(r) {
+			out = append(out, *r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].VersionCode > out[j].VersionCode })
+	return out
+}
+
+func (f *fakeAppReleaseRepo) ListReleases(organizationID int, channel string) ([]models.AppRelease, error) {
+	return f.sorted(func(r *models.AppRelease) bool {
+		return organizationOrDefault(r.OrganizationID) == organizationID && (channel == "" || r.Channel == channel)
+	}), nil
+}
+
+func (f *fakeAppReleaseRepo) GetActiveReleases(organizationID int, channels []string) ([]models.AppRelease, error) {
+	return f.sorted(func(r *models.AppRelease) bool {
+		if r.Status != models.AppReleaseStatusActive || organizationOrDefault(r.OrganizationID) != organizationID {
+			return false
+		}
+		for _, ch := range channels {
+			if r.Channel == ch {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func fakeChannelKey(organizationID int, name string) string {
+	return fmt.Sprintf("%d/%s", organizationID, name)
+}
+
+func (f *fakeAppReleaseRepo) GetChannel(organizationID int, name string) (*models.AppReleaseChannel, error) {
+	ch, ok := f.channels[fakeChannelKey(organizationID, name)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &ch, nil
+}
+
+func (f *fakeAppReleaseRepo) GetAllChannels(organizationID int) ([]models.AppReleaseChannel, error) {
+	var out []models.AppReleaseChannel
+	for _, ch := range f.channels {
+		if ch.OrganizationID == organizationID {
+			out = append(out, ch)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAppReleaseRepo) SaveChannel(ch *models.AppReleaseChannel) error {
+	f.channels[fakeChannelKey(ch.OrganizationID, ch.Name)] = *ch
+	return nil
+}
+
+func (f *fakeAppReleaseRepo) GetMember(userID int) (*models.AppReleaseMember, error) {
+	ch, ok := f.members[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.AppReleaseMember{UserID: userID, Channel: ch}, nil
+}
+
+func (f *fakeAppReleaseRepo) GetAllMembers(organizationID int) ([]models.AppReleaseMember, error) {
+	var out []models.AppReleaseMember
+	for id, ch := range f.members {
+		out = append(out, models.AppReleaseMember{UserID: id, Channel: ch})
+	}
+	return out, nil
+}
+
+func (f *fakeAppReleaseRepo) SaveMember(m *models.AppReleaseMember) error {
+	f.members[m.UserID] = m.Channel
+	return nil
+}
+
+func (f *fakeAppReleaseRepo) DeleteMember(userID int) error {
+	delete(f.members, userID)
+	return nil
+}
+
+type fakeAppUpdateLog struct {
+	latest map[int]*models.DeviceCommand
+	total  int64
+	failed int64
+}
+
+func (f *fakeAppUpdateLog) GetLatestByType(userID int, cmdType string) (*models.DeviceCommand, error) {
+	if cmd, ok := f.latest[userID]; ok {
+		return cmd, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeAppUpdateLog) CountAppUpdateResults(releaseID int) (int64, int64, error) {
+	return f.total, f.failed, nil
+}
+
+func appUpdateCommand(releaseID int, status string, createdAt time.Time) *models.DeviceCommand {
+	payload, _ := json.Marshal(map[string]interface{}{"release_id": releaseID})
+	return &models.DeviceCommand{
+		Type:      models.DeviceCommandTypeAppUpdate,
+		Payload:   datatypes.JSON(payload),
+		Status:    status,
+		CreatedAt: createdAt,
+	}
+}
+
+func newTestAppReleaseService(repo *fakeAppReleaseRepo, cmdLog *fakeAppUpdateLog, reports ...models.DeviceReport) (*AppReleaseService, *fakeCommandEnqueuer) {
+	commands := &fakeCommandEnqueuer{}
+	svc := NewAppReleaseService(repo, cmdLog, commands, &fakeDeviceReportRepo{reports: reports}, "", "https://locator.example")
+	return svc, commands
+}
+
+func activeRelease(channel, version string, code int64, percent int) models.AppRelease {
+	return models.AppRelease{
+		Channel:           channel,
+		VersionName:       version,
+		VersionCode:       code,
+		Filename:          "locator-" + version + ".apk",
+		SHA256:            "abc",
+		RolloutPercent:    percent,
+		Status:            models.AppReleaseStatusActive,
+		MaxFailurePercent: 20,
+		MinFailureSamples: 5,
+	}
+}
+
+func TestAppReleaseBucket_stableAndSpread(t *testing.T) {
+	if appReleaseBucket(7, 42) != appReleaseBucket(7, 42) {
+		t.Fatal("bucket must be deterministic")
+	}
+	in := 0
+	r := &models.AppRelease{ID: 3, RolloutPercent: 30}
+	for userID := 1; userID <= 1000; userID++ {
+		if appReleaseInRollout(r, userID) {
+			in++
+		}
+	}
+	if in < 220 || in > 380 {
+		t.Fatalf("30%% rollout covered %d of 1000 devices", in)
+	}
+}
+
+func TestSelectAppRelease(t *testing.T) {
+	releases := []models.AppRelease{
+		{ID: 3, VersionName: "1.6.0", RolloutPercent: 0},
+		{ID: 2, VersionName: "1.5.0", RolloutPercent: 100},
+		{ID: 1, VersionName: "1.4.0", RolloutPercent: 100},
+	}
+
+	target, forced := selectAppRelease(releases, 1, "1.4.0", "")
+	if target == nil || target.ID != 2 || forced {
+		t.Fatalf("expected newest release in rollout (1.5.0), got %+v forced=%v", target, forced)
+	}
+	if target, _ := selectAppRelease(releases, 1, "1.6.0", ""); target != nil {
+		t.Fatalf("up-to-date device must not get an update, got %+v", target)
+	}
+
+	// Ниже минимума: раскатка 0% не мешает принудительному обновлению.
+	target, forced = selectAppRelease(releases, 1, "1.3.0", "1.6.0")
+	if target == nil || target.ID != 3 || !forced {
+		t.Fatalf("expected forced 1.6.0, got %+v forced=%v", target, forced)
+	}
+	if target, _ := selectAppRelease(releases, 1, "1.3.0", "2.0.0"); target != nil {
+		t.Fatalf("no release satisfies minimum, got %+v", target)
+	}
+}
+
+func TestAppReleaseCheckDevice_rolloutAndDedup(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	repo := newFakeAppReleaseRepo(activeRelease(models.AppReleaseChannelStable, "1.5.0", 150, 100))
+	cmdLog := &fakeAppUpdateLog{latest: map[int]*models.DeviceCommand{}}
+	svc, commands := newTestAppReleaseService(repo, cmdLog, models.DeviceReport{UserID: 5, AppVersion: "1.4.2"})
+
+	cmd, err := svc.CheckDevice(5, 0, now)
+	if err != nil || cmd == nil {
+		t.Fatalf("expected app_update, got %v %v", cmd, err)
+	}
+	payload := commands.payloads[0]
+	if payload["release_id"] != 1 || payload["version"] != "1.5.0" || payload["force"] != false ||
+		payload["url"] != "https://locator.example/static/releases/locator-1.5.0.apk" {
+		t.Fatalf("unexpected payload %v", payload)
+	}
+
+	cmdLog.latest[5] = appUpdateCommand(1, models.DeviceCommandStatusDelivered, now)
+	if cmd, _ := svc.CheckDevice(5, 0, now); cmd != nil {
+		t.Fatal("in-flight app_update must not be duplicated")
+	}
+	cmdLog.latest[5] = appUpdateCommand(1, models.DeviceCommandStatusFailed, now.Add(-24*time.Hour))
+	if cmd, _ := svc.CheckDevice(5, 0, now); cmd != nil {
+		t.Fatal("failed rollout update must not be retried")
+	}
+
+	// Пауза релиза останавливает раскатку.
+	if _, err := svc.SetReleaseStatus(0, 1, models.AppReleaseStatusPaused, "", now); err != nil {
+		t.Fatal(err)
+	}
+	delete(cmdLog.latest, 5)
+	if cmd, _ := svc.CheckDevice(5, 0, now); cmd != nil {
+		t.Fatal("paused release must not be offered")
+	}
+}
+
+func TestAppReleaseCheckDevice_organizationIsolation(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	other := activeRelease(models.AppReleaseChannelStable, "2.0.0", 200, 100)
+	other.OrganizationID = 2
+	repo := newFakeAppReleaseRepo(activeRelease(models.AppReleaseChannelStable, "1.5.0", 150, 100), other)
+	cmdLog := &fakeAppUpdateLog{latest: map[int]*models.DeviceCommand{}}
+	svc, commands := newTestAppReleaseService(repo, cmdLog, models.DeviceReport{UserID: 5, AppVersion: "1.4.2"})
+
+	if _, err := svc.CheckDevice(5, models.DefaultOrganizationID, now); err != nil || len(commands.payloads) != 1 ||
+		commands.payloads[0]["version"] != "1.5.0" {
+		t.Fatalf("device must get its own organisation's release: %v %v", commands.payloads, err)
+	}
+	if _, err := svc.GetRelease(models.DefaultOrganizationID, 2); !errors.Is(err, ErrAppReleaseNotFound) {
+		t.Fatalf("release of another organisation must not be found, got %v", err)
+	}
+	if list, _ := svc.ListReleases(2, ""); len(list) != 1 || list[0].VersionName != "2.0.0" {
+		t.Fatalf("unexpected releases of organisation 2: %+v", list)
+	}
+	if appReleaseFilename(&other) != "locator-org2-2.0.0-200.apk" {
+		t.Fatalf("unexpected filename %s", appReleaseFilename(&other))
+	}
+}
+
+func TestAppReleaseCheckDevice_betaAndMinimum(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	repo := newFakeAppReleaseRepo(
+		activeRelease(models.AppReleaseChannelStable, "1.5.0", 150, 0),
+		activeRelease(models.AppReleaseChannelBeta, "1.6.0-beta", 160, 100),
+	)
+	cmdLog := &fakeAppUpdateLog{latest: map[int]*models.DeviceCommand{}}
+	svc, commands := newTestAppReleaseService(repo, cmdLog,
+		models.DeviceReport{UserID: 1, AppVersion: "1.4.0"},
+		models.DeviceReport{UserID: 2, AppVersion: "1.4.0"},
+	)
+	if _, err := svc.SetUserChannel(1, "Beta"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.CheckDevice(1, 0, now); err != nil || len(commands.payloads) != 1 || commands.payloads[0]["version"] != "1.6.0-beta" {
+		t.Fatalf("beta user must get beta build, got %v (%v)", commands.payloads, err)
+	}
+	if cmd, _ := svc.CheckDevice(2, 0, now); cmd != nil {
+		t.Fatal("stable user outside 0% rollout must not get an update")
+	}
+
+	if _, err := svc.SetChannelMinVersion(0, models.AppReleaseChannelStable, "1.5.0"); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := svc.CheckDevice(2, 0, now)
+	if err != nil || cmd == nil {
+		t.Fatalf("device below minimum must get app_update, got %v %v", cmd, err)
+	}
+	last := commands.payloads[len(commands.payloads)-1]
+	if last["version"] != "1.5.0" || last["force"] != true {
+		t.Fatalf("expected forced 1.5.0, got %v", last)
+	}
+
+	// Ниже минимума — повтор после appUpdateRetryAfter, даже если прошлый ack был ok.
+	cmdLog.latest[2] = appUpdateCommand(1, models.DeviceCommandStatusAcked, now.Add(-time.Hour))
+	if cmd, _ := svc.CheckDevice(2, 0, now); cmd != nil {
+		t.Fatal("retry must wait appUpdateRetryAfter")
+	}
+	cmdLog.latest[2] = appUpdateCommand(1, models.DeviceCommandStatusAcked, now.Add(-appUpdateRetryAfter-time.Minute))
+	if cmd, _ := svc.CheckDevice(2, 0, now); cmd == nil {
+		t.Fatal("device still below minimum must be retried")
+	}
+}
+
+func TestAppReleaseOnAppUpdateResult_autoPause(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	repo := newFakeAppReleaseRepo(activeRelease(models.AppReleaseChannelStable, "1.5.0", 150, 50))
+	cmdLog := &fakeAppUpdateLog{total: 4, failed: 4}
+	svc, _ := newTestAppReleaseService(repo, cmdLog)
+	cmd := appUpdateCommand(1, models.DeviceCommandStatusFailed, now)
+
+	svc.OnAppUpdateResult(cmd, now)
+	if repo.releases[1].Status != models.AppReleaseStatusActive {
+		t.Fatal("must not pause before min_failure_samples")
+	}
+
+	cmdLog.total, cmdLog.failed = 10, 2
+	svc.OnAppUpdateResult(cmd, now)
+	if repo.releases[1].Status != models.AppReleaseStatusActive {
+		t.Fatal("20% failures is within threshold")
+	}
+
+	cmdLog.failed = 3
+	svc.OnAppUpdateResult(cmd, now)
+	r := repo.releases[1]
+	if r.Status != models.AppReleaseStatusPaused || r.PauseReason == "" || r.PausedAt == nil {
+		t.Fatalf("expected auto-pause, got %+v", r)
+	}
+}
+
+func TestNormalizeAppReleaseChannel(t *testing.T) {
+	for in, want := range map[string]string{"": "stable", " BETA ": "beta", "group:Warehouse": "group:warehouse"} {
+		got, err := NormalizeAppReleaseChannel(in)
+		if err != nil || got != want {
+			t.Fatalf("%q: got %q, %v", in, got, err)
+		}
+	}
+	for _, bad := range []string{"nightly", "group:", "group:a b"} {
+		if _, err := NormalizeAppReleaseChannel(bad); err == nil {
+			t.Fatalf("%q must be rejected", bad)
+		}
+	}
+}
+
+func TestAppReleaseCheckAPK_packageAndPinnedCert(t *testing.T) {
+	_, cert := newAPKTestSigner(t)
+	_, foreign := newAPKTestSigner(t)
+	svc, _ := newTestAppReleaseService(newFakeAppReleaseRepo(), &fakeAppUpdateLog{})
+	svc.ExpectedPackage = "com.example.lctr_app"
+	svc.CertFingerprints = []string{APKCertFingerprint(cert)}
+
+	meta := &APKMeta{PackageName: "com.example.lctr_app", VersionName: "1.5.0", VersionCode: 15}
+	if err := svc.checkAPK(meta, []*x509.Certificate{cert}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.checkAPK(meta, []*x509.Certificate{foreign}); !errors.Is(err, ErrAppReleaseInvalid) {
+		t.Fatalf("foreign certificate must be rejected, got %v", err)
+	}
+	other := &APKMeta{PackageName: "com.evil.app", VersionName: "1.5.0", VersionCode: 15}
+	if err := svc.checkAPK(other, []*x509.Certificate{cert}); !errors.Is(err, ErrAppReleaseInvalid) {
+		t.Fatalf("foreign package must be rejected, got %v", err)
+	}
+
+	// Без закреплённых отпечатков публикация закрыта, пока не включено исключение для разработки.
+	svc.CertFingerprints = nil
+	if err := svc.checkAPK(meta, []*x509.Certificate{foreign}); !errors.Is(err, ErrAppReleaseInvalid) {
+		t.Fatalf("unpinned release must be rejected, got %v", err)
+	}
+	svc.AllowUnpinnedCert = true
+	if err := svc.checkAPK(meta, []*x509.Certificate{foreign}); err != nil {
+		t.Fatalf("dev override must accept any certificate, got %v", err)
+	}
+}
+
+func TestAppReleaseBuildRelease_versionMustIncrease(t *testing.T) {
+	repo := newFakeAppReleaseRepo(activeRelease(models.AppReleaseChannelStable, "1.5.0", 150, 100))
+	svc, _ := newTestAppReleaseService(repo, &fakeAppUpdateLog{})
+
+	for _, code := range []uint32{149, 150} {
+		_, err := svc.buildRelease(&APKMeta{VersionName: "1.5.0", VersionCode: code}, "sum", AppReleaseInput{})
+		if !errors.Is(err, ErrAppReleaseVersionNotNewer) {
+			t.Fatalf("version_code %d: expected ErrAppReleaseVersionNotNewer, got %v", code, err)
+		}
+	}
+	// Другой канал — своя последовательность версий.
+	r, err := svc.buildRelease(&APKMeta{VersionName: "1.5.0", VersionCode: 150}, "sum", AppReleaseInput{Channel: "beta"})
+	if err != nil || r.Channel != "beta" || r.RolloutPercent != 100 {
+		t.Fatalf("unexpected %+v %v", r, err)
+	}
+	percent := 10
+	r, err = svc.buildRelease(&APKMeta{VersionName: "1.6.0", VersionCode: 160}, "sum", AppReleaseInput{RolloutPercent: &percent})
+	if err != nil || r.VersionCode != 160 || r.RolloutPercent != 10 || r.Status != models.AppReleaseStatusActive {
+		t.Fatalf("unexpected %+v %v", r, err)
+	}
+}
+
+func TestAppReleaseUploadRelease_rejectedFileIsRemoved(t *testing.T) {
+	svc, _ := newTestAppReleaseService(newFakeAppReleaseRepo(), &fakeAppUpdateLog{})
+	svc.ReleasesDir = t.TempDir()
+
+	if _, err := svc.UploadRelease(strings.NewReader("not an apk"), AppReleaseInput{}); !errors.Is(err, ErrAppReleaseInvalid) {
+		t.Fatalf("expected ErrAppReleaseInvalid, got %v", err)
+	}
+	svc.MaxAPKBytes = 4
+	if _, err := svc.UploadRelease(strings.NewReader("too large"), AppReleaseInput{}); !errors.Is(err, ErrAppReleaseInvalid) {
+		t.Fatalf("expected size limit error, got %v", err)
+	}
+	entries, _ := os.ReadDir(svc.ReleasesDir)
+	if len(entries) != 0 {
+		t.Fatalf("rejected uploads must not leave files, got %d", len(entries))
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareAppVersions сравнивает версии вида 1.4.10 (суффиксы -debug, +build
+// и нечисловые хвосты сегментов игнорируются). Возвращает -1, 0 или 1.
+func compareAppVersions(a, b string) int {
+	pa, pb := appVersionParts(a), appVersionParts(b)
+	for len(pa) < len(pb) {
+		pa = append(pa, 0)
+	}
+	for len(pb) < len(pa) {
+		pb = append(pb, 0)
+	}
+	for i := range pa {
+		switch {
+		case pa[i] < pb[i]:
+			return -1
+		case pa[i] > pb[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func appVersionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil
+	}
+	segments := strings.Split(v, ".")
+	parts := make([]int, 0, len(segments))
+	for _, seg := range segments {
+		end := 0
+		for end < len(seg) && seg[end] >= '0' && seg[end] <= '9' {
+			end++
+		}
+		n, _ := strconv.Atoi(seg[:end])
+		parts = append(parts, n)
+	}
+	return parts
+}
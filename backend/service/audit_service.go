@@ -0,0 +1,229 @@
+package service
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"locator/models"
+)
+
+const (
+	auditListDefaultLimit = 100
+	auditListMaxLimit     = 500
+	// AuditExportMaxRows — предел выгрузки журнала за один запрос.
+	AuditExportMaxRows  = 100000
+	auditExportPageSize = 1000
+	maxAuditErrorLength = 500
+	// RedactedValue — чем заменяются секреты в параметрах запроса.
+	RedactedValue = "***"
+)
+
+// AuditService — журнал аудита административных действий (кто, что, над чем,
+// с какими параметрами и с каким результатом). Записи только добавляются.
+type AuditService struct {
+	DAO auditRepository
+	// Clock — текущее время (nil — системное).
+	Clock Clock
+}
+
+func NewAuditService(dao auditRepository) *AuditService {
+	return &AuditService{DAO: dao}
+}
+
+// AuditFilter — фильтры просмотра и выгрузки журнала; нулевые поля не фильтруют.
+// Action — начало строки действия ("POST /api/admin/users"), From/To — период [From, To).
+type AuditFilter struct {
+	OrganizationID int
+	ActorID        int
+	Action         string
+	TargetType     string
+	TargetID       string
+	Result         string
+	From           *time.Time
+	To             *time.Time
+}
+
+// Record сохраняет запись журнала. Ошибка записи логируется и возвращается, но
+// само действие уже выполнено — отменять его вызывающий не должен.
+func (svc *AuditService) Record(entry *models.AuditEntry) error {
+	if entry.At.IsZero() {
+		entry.At = clockNow(svc.Clock)
+	}
+	entry.OrganizationID = organizationOrDefault(entry.OrganizationID)
+	if r := []rune(entry.Error); len(r) > maxAuditErrorLength {
+		entry.Error = string(r[:maxAuditErrorLength])
+	}
+	if err := svc.DAO.CreateAuditEntry(entry); err != nil {
+		slog.Error("Не удалось записать в журнал аудита", "action", entry.Action, "actor_id", entry.ActorID,
+			"target_type", entry.TargetType, "target_id", entry.TargetID, "status", entry.Status, "error", err)
+		return err
+	}
+	return nil
+}
+
+// List — страница журнала, видимая сотруднику actor: своя организация (администратор
+// платформы — любая), при ограничении группой — только действия сотрудников группы.
+func (svc *AuditService) List(actor *models.User, scope *UserScope, filter AuditFilter, limit, offset int) ([]models.AuditEntry, int64, error) {
+	filters, err := auditFilters(actor, scope, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 {
+		limit = auditListDefaultLimit
+	}
+	if limit > auditListMaxLimit {
+		limit = auditListMaxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return svc.DAO.ListAuditEntries(filters, filter.From, filter.To, limit, offset)
+}
+
+// Export передаёт в emit записи журнала (новые первыми, не больше AuditExportMaxRows)
+// с теми же правилами видимости, что и List. Конец периода фиксируется в момент
+// вызова, чтобы новые записи не сдвигали страницы выгрузки.
+func (svc *AuditService) Export(actor *models.User, scope *UserScope, filter AuditFilter, emit func(*models.AuditEntry) error) error {
+	filters, err := auditFilters(actor, scope, filter)
+	if err != nil {
+		return err
+	}
+	if filter.To == nil {
+		to := clockNow(svc.Clock)
+		filter.To = &to
+	}
+	for offset := 0; offset < AuditExportMaxRows; offset += auditExportPageSize {
+		page, _, err := svc.DAO.ListAuditEntries(filters, filter.From, filter.To, auditExportPageSize, offset)
+		if err != nil {
+			return err
+		}
+		for i := range page {
+			if err := emit(&page[i]); err != nil {
+				return err
+			}
+		}
+		if len(page) < auditExportPageSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+// auditFilters переводит фильтр в условия DAO с учётом видимости для actor.
+func auditFilters(actor *models.User, scope *UserScope, filter AuditFilter) (map[string]interface{}, error) {
+	filters := make(map[string]interface{})
+	switch {
+	case actor.IsPlatformAdmin():
+		if filter.OrganizationID > 0 {
+			filters["organization_id"] = filter.OrganizationID
+		}
+	case filter.OrganizationID > 0 && filter.OrganizationID != organizationOrDefault(actor.OrganizationID):
+		return nil, ErrAccessDenied
+	default:
+		filters["organization_id"] = organizationOrDefault(actor.OrganizationID)
+	}
+
+	if filter.ActorID > 0 {
+		if !scope.Allows(filter.ActorID) {
+			return nil, ErrAccessDenied
+		}
+		filters["actor_id"] = filter.ActorID
+	} else if !scope.All() {
+		filters["actor_id"] = scope.UserIDs()
+	}
+	if filter.Action != "" {
+		filters["action"] = filter.Action
+	}
+	if filter.TargetType != "" {
+		filters["target_type"] = filter.TargetType
+	}
+	if filter.TargetID != "" {
+		filters["target_id"] = filter.TargetID
+	}
+	if filter.Result != "" {
+		filters["result"] = filter.Result
+	}
+	return filters, nil
+}
+
+// RedactSecrets возвращает копию v (результат json.Unmarshal в interface{}),
+// в которой значения секретных полей на любой глубине заменены на RedactedValue.
+func RedactSecrets(v interface{}) interface{} {
+	return redactSecrets(v, IsSecretParam)
+}
+
+// RedactRouteSecrets — RedactSecrets для тела запроса к маршруту route (шаблон gin):
+// на маршрутах входа и 2FA скрывается и одноразовый код.
+func RedactRouteSecrets(route string, v interface{}) interface{} {
+	return redactSecrets(v, func(key string) bool { return IsRouteSecretParam(route, key) })
+}
+
+func redactSecrets(v interface{}, isSecret func(key string) bool) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			if isSecret(key) {
+				out[key] = RedactedValue
+				continue
+			}
+			out[key] = redactSecrets(item, isSecret)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = redactSecrets(item, isSecret)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// secretParamParts — фрагменты имён параметров, значения которых не пишутся в журнал.
+var secretParamParts = []string{"password", "secret", "token", "api_key", "apikey", "totp", "private_key", "authorization"}
+
+// authSecretParams — поля с одноразовым кодом (TOTP) на маршрутах authRoutePrefix.
+// В остальных маршрутах code — код ошибки, оповещения или команды, его скрывать незачем.
+var authSecretParams = []string{"code", "otp"}
+
+// authRoutePrefix — вход, ссылка входа и управление вторым фактором.
+const authRoutePrefix = "/api/auth/"
+
+// IsSecretParam сообщает, что параметр key содержит секрет (пароль, ключ, PIN, токен).
+func IsSecretParam(key string) bool {
+	k := secretParamKey(key)
+	if k == "pin" || strings.HasSuffix(k, "_pin") {
+		return true
+	}
+	for _, part := range secretParamParts {
+		if strings.Contains(k, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRouteSecretParam — IsSecretParam для параметра маршрута route (шаблон gin);
+// на маршрутах входа и 2FA секретны также code, otp и *_code, *_otp.
+func IsRouteSecretParam(route, key string) bool {
+	if IsSecretParam(key) {
+		return true
+	}
+	if !strings.HasPrefix(route, authRoutePrefix) {
+		return false
+	}
+	k := secretParamKey(key)
+	for _, name := range authSecretParams {
+		if k == name || strings.HasSuffix(k, "_"+name) {
+			return true
+		}
+	}
+	return false
+}
+
+func secretParamKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "-", "_")
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"locator/internal/testutil"
+	"locator/models"
+)
+
+type fakeAuditRepo struct {
+	entries []models.AuditEntry
+	// last — условия последнего запроса ListAuditEntries.
+	last     map[string]interface{}
+	lastTo   *time.Time
+	listHits int
+}
+
+func (f *fakeAuditRepo) CreateAuditEntry(entry *models.AuditEntry) error {
+	entry.ID = int64(len(f.entries) + 1)
+	f.entries = append(f.entries, *entry)
+	return nil
+}
+
+func (f *fakeAuditRepo) ListAuditEntries(filters map[string]interface{}, from, to *time.Time, limit, offset int) ([]models.AuditEntry, int64, error) {
+	f.last, f.lastTo = filters, to
+	f.listHits++
+	if offset >= len(f.entries) {
+		return nil, int64(len(f.entries)), nil
+	}
+	end := offset + limit
+	if end > len(f.entries) {
+		end = len(f.entries)
+	}
+	return f.entries[offset:end], int64(len(f.entries)), nil
+}
+
+func TestAuditList_scopedToOrganizationAndGroup(t *testing.T) {
+	repo := &fakeAuditRepo{}
+	svc := NewAuditService(repo)
+
+	platform := &models.User{ID: 1, Role: models.RoleSuperAdmin, OrganizationID: models.DefaultOrganizationID}
+	if _, _, err := svc.List(platform, nil, AuditFilter{OrganizationID: 7}, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(repo.last, map[string]interface{}{"organization_id": 7}) {
+		t.Fatalf("platform admin filters=%v", repo.last)
+	}
+
+	tenantAdmin := &models.User{ID: 20, Role: models.RoleFleetAdmin, OrganizationID: 7}
+	if _, _, err := svc.List(tenantAdmin, nil, AuditFilter{Action: "PUT "}, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(repo.last, map[string]interface{}{"organization_id": 7, "action": "PUT "}) {
+		t.Fatalf("tenant admin filters=%v", repo.last)
+	}
+	if _, _, err := svc.List(tenantAdmin, nil, AuditFilter{OrganizationID: 8}, 0, 0); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("other organization: err=%v, want ErrAccessDenied", err)
+	}
+
+	groupScope := NewUserScope(20, 21)
+	if _, _, err := svc.List(tenantAdmin, groupScope, AuditFilter{}, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if ids, ok := repo.last["actor_id"].([]int); !ok || len(ids) != 2 {
+		t.Fatalf("group-limited admin must see only group actors: %v", repo.last)
+	}
+	if _, _, err := svc.List(tenantAdmin, groupScope, AuditFilter{ActorID: 99}, 0, 0); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("actor outside group: err=%v, want ErrAccessDenied", err)
+	}
+}
+
+func TestAuditExport_pagesAndFreezesPeriodEnd(t *testing.T) {
+	repo := &fakeAuditRepo{}
+	svc := NewAuditService(repo)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	svc.Clock = testutil.NewClock(now)
+	for i := 0; i < auditExportPageSize+5; i++ {
+		if err := svc.Record(&models.AuditEntry{Action: "POST /api/users/", Status: 201, Result: models.AuditResultSuccess}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	admin := &models.User{ID: 1, Role: models.RoleSuperAdmin, OrganizationID: models.DefaultOrganizationID}
+	var got int
+	err := svc.Export(admin, nil, AuditFilter{}, func(*models.AuditEntry) error {
+		got++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != auditExportPageSize+5 || repo.listHits != 2 {
+		t.Fatalf("exported %d entries in %d pages", got, repo.listHits)
+	}
+	if repo.lastTo == nil || !repo.lastTo.Equal(now) {
+		t.Fatalf("period end not frozen: %v", repo.lastTo)
+	}
+	if repo.entries[0].OrganizationID != models.DefaultOrganizationID || !repo.entries[0].At.Equal(now) {
+		t.Fatalf("Record defaults not applied: %+v", repo.entries[0])
+	}
+}
+
+func TestRedactSecrets_nested(t *testing.T) {
+	in := map[string]interface{}{
+		"name": "Телефон 1",
+		"payload": map[string]interface{}{
+			"api_key":   "k-123",
+			"admin_pin": "1234",
+			"interval":  60.0,
+		},
+		"new_password":  "p@ss",
+		"refresh_token": "r",
+		"commands":      []interface{}{map[string]interface{}{"type": "wake", "X-API-Key": "k"}},
+	}
+	got := RedactSecrets(in).(map[string]interface{})
+
+	payload := got["payload"].(map[string]interface{})
+	if payload["api_key"] != RedactedValue || payload["admin_pin"] != RedactedValue || payload["interval"] != 60.0 {
+		t.Fatalf("payload=%v", payload)
+	}
+	if got["new_password"] != RedactedValue || got["refresh_token"] != RedactedValue || got["name"] != "Телефон 1" {
+		t.Fatalf("top level=%v", got)
+	}
+	cmd := got["commands"].([]interface{})[0].(map[string]interface{})
+	if cmd["X-API-Key"] != RedactedValue || cmd["type"] != "wake" {
+		t.Fatalf("array item=%v", cmd)
+	}
+	if in["payload"].(map[string]interface{})["api_key"] != "k-123" {
+		t.Fatal("input must not be modified")
+	}
+	for _, key := range []string{"pin", "spin_count", "shipping"} {
+		if want := key == "pin"; IsSecretParam(key) != want {
+			t.Errorf("IsSecretParam(%q)=%v, want %v", key, !want, want)
+		}
+	}
+}
+
+func TestRedactRouteSecrets_authCodes(t *testing.T) {
+	in := map[string]interface{}{"code": "123456", "otp": "654321", "totp_code": "111111", "reason": "lost phone"}
+	got := RedactRouteSecrets("/api/auth/totp/disable", in).(map[string]interface{})
+	if got["code"] != RedactedValue || got["otp"] != RedactedValue || got["totp_code"] != RedactedValue || got["reason"] != "lost phone" {
+		t.Fatalf("auth route=%v", got)
+	}
+	// Вне маршрутов входа code — обычный код (тип оповещения, ошибки).
+	other := RedactRouteSecrets("/api/admin/alert-rules", map[string]interface{}{"code": "offline"}).(map[string]interface{})
+	if other["code"] != "offline" {
+		t.Fatalf("admin route=%v", other)
+	}
+}
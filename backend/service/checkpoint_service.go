@@ -2,14 +2,15 @@ package service
 
 import (
 	"locator/models"
-	"log"
+	"log/slog"
 	"math"
-	"time"
 )
 
 // CheckpointService отвечает за бизнес-логику, связанную с операциями над чекпоинтами.
 type CheckpointService struct {
 	DAO checkpointRepository
+	// Clock — текущее время (nil — системное).
+	Clock Clock
 }
 
 // NewCheckpointService создаёт новый экземпляр CheckpointService.
@@ -17,72 +18,67 @@ func NewCheckpointService(dao checkpointRepository) *CheckpointService {
 	return &CheckpointService{DAO: dao}
 }
 
-// CreateCheckpoint создаёт новый чекпоинт с заданными параметрами.
-func (svc *CheckpointService) CreateCheckpoint(name string, lat, lon, radius float64) (*models.Checkpoint, error) {
-	log.Printf("[CreateCheckpoint] Создание чекпоинта: Name=%s, Latitude=%.6f, Longitude=%.6f, Radius=%.2f м",
-		name, lat, lon, radius)
+// CreateCheckpoint создаёт новый чекпоинт организации с заданными параметрами.
+func (svc *CheckpointService) CreateCheckpoint(organizationID int, name string, lat, lon, radius float64) (*models.Checkpoint, error) {
+	now := clockNow(svc.Clock)
 	cp := &models.Checkpoint{
-		Name:      name,
-		Latitude:  lat,
-		Longitude: lon,
-		Radius:    radius,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		OrganizationID: organizationOrDefault(organizationID),
+		Name:           name,
+		Latitude:       lat,
+		Longitude:      lon,
+		Radius:         radius,
+		CreatedAt:      now,
+		UpdatedAt:      now,
 	}
 	if err := svc.DAO.Create(cp); err != nil {
-		log.Printf("[CreateCheckpoint] Ошибка при создании чекпоинта (Name=%s): %v", name, err)
+		slog.Error("Ошибка создания чекпоинта", "name", name, "error", err)
 		return nil, err
 	}
-	log.Printf("[CreateCheckpoint] Чекпоинт успешно создан: ID=%d, Name=%s, Latitude=%.6f, Longitude=%.6f, Radius=%.2f м",
-		cp.ID, cp.Name, cp.Latitude, cp.Longitude, cp.Radius)
+	slog.Info("Чекпоинт создан", "checkpoint_id", cp.ID, "name", cp.Name,
+		"lat", cp.Latitude, "lon", cp.Longitude, "radius_m", cp.Radius)
 	return cp, nil
 }
 
-// GetCheckpoints возвращает все чекпоинты.
-func (svc *CheckpointService) GetCheckpoints() ([]models.Checkpoint, error) {
-	log.Println("[GetCheckpoints] Запрос на получение всех чекпоинтов")
-	checkpoints, err := svc.DAO.GetAll()
+// GetCheckpoints возвращает все чекпоинты организации.
+func (svc *CheckpointService) GetCheckpoints(organizationID int) ([]models.Checkpoint, error) {
+	checkpoints, err := svc.DAO.GetAll(organizationOrDefault(organizationID))
 	if err != nil {
-		log.Printf("[GetCheckpoints] Ошибка получения чекпоинтов: %v", err)
+		slog.Error("Ошибка получения чекпоинтов", "organization_id", organizationID, "error", err)
 		return nil, err
 	}
-	log.Printf("[GetCheckpoints] Найдено %d чекпоинтов", len(checkpoints))
 	return checkpoints, nil
 }
 
-// UpdateCheckpoint обновляет чекпоинт с заданным ID новыми параметрами.
-func (svc *CheckpointService) UpdateCheckpoint(id int, name string, lat, lon, radius float64) (*models.Checkpoint, error) {
-	cp, err := svc.GetCheckpointByID(id)
+// UpdateCheckpoint обновляет чекпоинт организации с заданным ID новыми параметрами.
+func (svc *CheckpointService) UpdateCheckpoint(organizationID, id int, name string, lat, lon, radius float64) (*models.Checkpoint, error) {
+	cp, err := svc.GetCheckpointByID(organizationID, id)
 	if err != nil {
-		log.Printf("[UpdateCheckpoint] Не удалось найти чекпоинт с ID=%d: %v", id, err)
 		return nil, err
 	}
 	cp.Name = name
 	cp.Latitude = lat
 	cp.Longitude = lon
 	cp.Radius = radius
-	cp.UpdatedAt = time.Now()
+	cp.UpdatedAt = clockNow(svc.Clock)
 
 	// Обновляем данные в БД через метод Update из DAO.
 	if err := svc.DAO.Update(cp); err != nil {
-		log.Printf("[UpdateCheckpoint] Ошибка обновления чекпоинта с ID=%d: %v", id, err)
+		slog.Error("Ошибка обновления чекпоинта", "checkpoint_id", id, "error", err)
 		return nil, err
 	}
-	log.Printf("[UpdateCheckpoint] Чекпоинт обновлён: ID=%d, Name=%s, Latitude=%.6f, Longitude=%.6f, Radius=%.2f",
-		cp.ID, cp.Name, cp.Latitude, cp.Longitude, cp.Radius)
+	slog.Info("Чекпоинт обновлён", "checkpoint_id", cp.ID, "name", cp.Name,
+		"lat", cp.Latitude, "lon", cp.Longitude, "radius_m", cp.Radius)
 	return cp, nil
 }
 
-// GetCheckpointByID возвращает чекпоинт по его ID.
-func (svc *CheckpointService) GetCheckpointByID(id int) (*models.Checkpoint, error) {
-	log.Printf("[GetCheckpointByID] Запрос на получение чекпоинта с ID: %d", id)
-	cp, err := svc.DAO.GetByID(id)
+// GetCheckpointByID возвращает чекпоинт организации по его ID;
+// чекпоинт другой организации не находится.
+func (svc *CheckpointService) GetCheckpointByID(organizationID, id int) (*models.Checkpoint, error) {
+	cp, err := svc.DAO.GetByID(organizationOrDefault(organizationID), id)
 	if err != nil {
-		log.Printf("[GetCheckpointByID] Ошибка получения чекпоинта с ID=%d: %v", id, err)
+		slog.Debug("Чекпоинт не получен", "checkpoint_id", id, "error", err)
 		return nil, err
 	}
-	log.Printf("[GetCheckpointByID] Чекпоинт найден: ID=%d, Name=%s, Latitude=%.6f, Longitude=%.6f, Radius=%.2f м",
-		cp.ID, cp.Name, cp.Latitude, cp.Longitude, cp.Radius)
 	return cp, nil
 }
 
@@ -95,14 +91,9 @@ func (svc *CheckpointService) DistanceToCheckpoint(lat, lon float64, checkpoint
 // Расстояние вычисляется с использованием формулы Хаверсина.
 func (svc *CheckpointService) IsLocationInCheckpoint(loc *models.Location, checkpoint *models.Checkpoint) bool {
 	distance := haversineDistance(loc.Latitude, loc.Longitude, checkpoint.Latitude, checkpoint.Longitude)
-	log.Printf("[IsLocationInCheckpoint] Проверка попадания локации для Чекпоинта ID=%d: Локация (%.6f, %.6f), Чекпоинт (%.6f, %.6f); Вычисленная дистанция: %.2f м, Радиус: %.2f м",
-		checkpoint.ID, loc.Latitude, loc.Longitude, checkpoint.Latitude, checkpoint.Longitude, distance, checkpoint.Radius)
 	inZone := distance <= checkpoint.Radius
-	if inZone {
-		log.Printf("[IsLocationInCheckpoint] Локация находится внутри зоны Чекпоинта ID=%d", checkpoint.ID)
-	} else {
-		log.Printf("[IsLocationInCheckpoint] Локация находится вне зоны Чекпоинта ID=%d", checkpoint.ID)
-	}
+	slog.Debug("Проверка попадания в чекпоинт", "checkpoint_id", checkpoint.ID, "location_id", loc.ID,
+		"distance_m", distance, "radius_m", checkpoint.Radius, "in_zone", inZone)
 	return inZone
 }
 
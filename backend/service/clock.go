@@ -0,0 +1,23 @@
+package service
+
+import "time"
+
+// Clock — источник текущего времени сервисов. Поле Clock сервиса может быть nil —
+// тогда используется системное время; тесты подставляют управляемые часы
+// (testutil.Clock), чтобы сроки жизни команд, grace и графики проверялись без sleep.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock — системное время.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// clockNow — показания часов c; без часов — системное время.
+func clockNow(c Clock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
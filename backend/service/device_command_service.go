@@ -3,7 +3,7 @@ package service
 import (
 	"encoding/json"
 	"errors"
-	"locator/dao"
+	"locator/internal/metrics"
 	"locator/models"
 	"strings"
 	"time"
@@ -29,28 +29,41 @@ var allowedDeviceCommandTypes = map[string]struct{}{
 	models.DeviceCommandTypeAppUpdate:       {},
 }
 
+// appUpdateResultObserver получает финальные ack команд app_update (AppReleaseService — автопауза релиза).
+type appUpdateResultObserver interface {
+	OnAppUpdateResult(cmd *models.DeviceCommand, now time.Time)
+}
+
 // DeviceCommandService — очередь команд для мобильного коннектора.
 type DeviceCommandService struct {
-	DAO              *dao.DeviceCommandDAO
+	DAO              deviceCommandRepository
 	LocationRequests *LocationRequestService
+	AppUpdates       appUpdateResultObserver
+	// Clock — текущее время: сроки жизни команд и отметки доставки (nil — системное).
+	Clock Clock
 }
 
-func NewDeviceCommandService(dao *dao.DeviceCommandDAO, locationRequests *LocationRequestService) *DeviceCommandService {
+func NewDeviceCommandService(dao deviceCommandRepository, locationRequests *LocationRequestService) *DeviceCommandService {
 	return &DeviceCommandService{DAO: dao, LocationRequests: locationRequests}
 }
 
 func (svc *DeviceCommandService) expireStale() error {
-	now := time.Now()
-	if err := svc.DAO.ExpirePendingOlderThanExceptType(
+	now := clockNow(svc.Clock)
+	expired, err := svc.DAO.ExpirePendingOlderThanExceptType(
 		now.Add(-deviceCommandPendingTTL),
 		models.DeviceCommandTypeAppUpdate,
-	); err != nil {
+	)
+	if err != nil {
 		return err
 	}
-	return svc.DAO.ExpirePendingOlderThanType(
+	expiredUpdates, err := svc.DAO.ExpirePendingOlderThanType(
 		now.Add(-deviceCommandAppUpdateTTL),
 		models.DeviceCommandTypeAppUpdate,
 	)
+	for _, cmdType := range append(expired, expiredUpdates...) {
+		metrics.DeviceCommands.Inc(cmdType, models.DeviceCommandStatusExpired)
+	}
+	return err
 }
 
 // EnqueueCommand ставит команду в очередь для пользователя.
@@ -82,15 +95,17 @@ func (svc *DeviceCommandService) EnqueueCommand(userID int, cmdType string, payl
 	}
 
 	cmd := &models.DeviceCommand{
-		ID:      id,
-		UserID:  userID,
-		Type:    cmdType,
-		Payload: datatypes.JSON(payloadBytes),
-		Status:  models.DeviceCommandStatusPending,
+		ID:        id,
+		UserID:    userID,
+		Type:      cmdType,
+		Payload:   datatypes.JSON(payloadBytes),
+		Status:    models.DeviceCommandStatusPending,
+		CreatedAt: clockNow(svc.Clock),
 	}
 	if err := svc.DAO.Create(cmd); err != nil {
 		return nil, err
 	}
+	metrics.DeviceCommands.Inc(cmd.Type, models.DeviceCommandStatusPending)
 	return cmd, nil
 }
 
@@ -106,12 +121,13 @@ func (svc *DeviceCommandService) Poll(userID int) (*models.DeviceCommand, error)
 		return nil, err
 	}
 
-	now := time.Now()
+	now := clockNow(svc.Clock)
 	if err := svc.DAO.MarkDelivered(cmd.ID, now); err != nil {
 		return nil, err
 	}
 	cmd.Status = models.DeviceCommandStatusDelivered
 	cmd.DeliveredAt = &now
+	metrics.DeviceCommands.Inc(cmd.Type, models.DeviceCommandStatusDelivered)
 	return cmd, nil
 }
 
@@ -122,6 +138,21 @@ var appUpdateProgressAck = map[string]struct{}{
 	"installing": {},
 }
 
+// GetCommand возвращает команду пользователя userID: статус доставки и ack.
+func (svc *DeviceCommandService) GetCommand(userID int, commandID string) (*models.DeviceCommand, error) {
+	cmd, err := svc.DAO.GetByID(commandID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeviceCommandNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cmd.UserID != userID {
+		return nil, ErrDeviceCommandNotFound
+	}
+	return cmd, nil
+}
+
 // Ack подтверждает выполнение или ошибку команды на устройстве.
 func (svc *DeviceCommandService) Ack(commandID string, userID int, status, message string) error {
 	cmd, err := svc.DAO.GetByID(commandID)
@@ -135,15 +166,17 @@ func (svc *DeviceCommandService) Ack(commandID string, userID int, status, messa
 		return ErrDeviceCommandWrongUser
 	}
 
-	now := time.Now()
+	now := clockNow(svc.Clock)
 	status = strings.TrimSpace(strings.ToLower(status))
 	success := status == "ok" || status == "success"
+	final := true
 	if success {
 		if err := svc.DAO.MarkAcked(commandID, status, message, now); err != nil {
 			return err
 		}
 	} else if cmd.Type == models.DeviceCommandTypeAppUpdate {
 		if _, progress := appUpdateProgressAck[status]; progress {
+			final = false
 			if err := svc.DAO.MarkProgress(commandID, status, message, now); err != nil {
 				return err
 			}
@@ -153,6 +186,18 @@ func (svc *DeviceCommandService) Ack(commandID string, userID int, status, messa
 	} else if err := svc.DAO.MarkFailed(commandID, status, message, now); err != nil {
 		return err
 	}
+	switch {
+	case success:
+		metrics.DeviceCommands.Inc(cmd.Type, models.DeviceCommandStatusAcked)
+	case final:
+		metrics.DeviceCommands.Inc(cmd.Type, models.DeviceCommandStatusFailed)
+	default:
+		metrics.DeviceCommands.Inc(cmd.Type, "progress")
+	}
+
+	if final && cmd.Type == models.DeviceCommandTypeAppUpdate && svc.AppUpdates != nil {
+		svc.AppUpdates.OnAppUpdateResult(cmd, now)
+	}
 
 	if success && cmd.Type == models.DeviceCommandTypeLocationRequest && svc.LocationRequests != nil {
 		requestID := commandID
@@ -3,6 +3,11 @@ package service
 import (
 	"errors"
 	"testing"
+	"time"
+
+	"locator/internal/testutil"
+	"locator/internal/testutil/memrepo"
+	"locator/models"
 )
 
 func TestEnqueueCommand_invalidType(t *testing.T) {
@@ -26,3 +31,82 @@ func TestEnqueueCommand_allowedTypes(t *testing.T) {
 		}
 	}
 }
+
+// newTestCommandService — очередь команд и запросов координат в памяти с управляемыми часами.
+func newTestCommandService(t *testing.T) (*DeviceCommandService, *memrepo.Store, *testutil.Clock) {
+	t.Helper()
+	clock := testutil.NewClock(testutil.FixedUTC(2026, 7, 1, 9, 0, 0))
+	store := memrepo.New(clock)
+	if err := store.Users.Create(&models.User{ID: 1, Name: "phone"}); err != nil {
+		t.Fatal(err)
+	}
+	requests := NewLocationRequestService(store.LocationRequests)
+	requests.Clock = clock
+	svc := NewDeviceCommandService(store.DeviceCommands, requests)
+	svc.Clock = clock
+	return svc, store, clock
+}
+
+func TestDeviceCommandPoll_expiresAfterTTL(t *testing.T) {
+	svc, _, clock := newTestCommandService(t)
+	health, err := svc.EnqueueCommand(1, models.DeviceCommandTypeHealthCheck, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update, err := svc.EnqueueCommand(1, models.DeviceCommandTypeAppUpdate, map[string]interface{}{"release_id": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(deviceCommandPendingTTL + time.Second)
+	got, err := svc.Poll(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.ID != update.ID {
+		t.Fatalf("poll after 15m: got %+v, want app_update (48h TTL)", got)
+	}
+	if cmd, _ := svc.GetCommand(1, health.ID); cmd.Status != models.DeviceCommandStatusExpired {
+		t.Fatalf("health_check status=%s, want expired", cmd.Status)
+	}
+
+	// Доставленная, но не подтверждённая команда истекает по своему TTL.
+	clock.Advance(deviceCommandAppUpdateTTL)
+	if got, err := svc.Poll(1); err != nil || got != nil {
+		t.Fatalf("poll after 48h: got %+v err=%v", got, err)
+	}
+	if cmd, _ := svc.GetCommand(1, update.ID); cmd.Status != models.DeviceCommandStatusExpired {
+		t.Fatalf("app_update status=%s, want expired", cmd.Status)
+	}
+}
+
+func TestDeviceCommandAck_completesLocationRequest(t *testing.T) {
+	svc, store, clock := newTestCommandService(t)
+	cmd, err := svc.EnqueueCommand(1, models.DeviceCommandTypeLocationRequest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := CommandPayloadMap(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestID, _ := payload["request_id"].(string)
+
+	clock.Advance(time.Minute)
+	polled, err := svc.Poll(1)
+	if err != nil || polled == nil || polled.DeliveredAt == nil || !polled.DeliveredAt.Equal(clock.Now()) {
+		t.Fatalf("poll: %+v err=%v", polled, err)
+	}
+	clock.Advance(time.Minute)
+	if err := svc.Ack(cmd.ID, 1, "ok", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := store.LocationRequests.GetByID(requestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Status != models.LocationRequestStatusCompleted || req.CompletedAt == nil || !req.CompletedAt.Equal(clock.Now()) {
+		t.Fatalf("request %+v, want completed at %v", req, clock.Now())
+	}
+}
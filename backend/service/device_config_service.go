@@ -0,0 +1,491 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"locator/dao"
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDeviceConfigProfileNotFound = errors.New("device config profile not found")
+	ErrDeviceDesiredConfigNotFound = errors.New("desired device config not found")
+)
+
+// deviceConfigDriftRepushAfter — не повторяем тот же config_update по дрейфу чаще этого интервала.
+const deviceConfigDriftRepushAfter = 30 * time.Minute
+
+// reportedConfigReader — reported config из отчётов устройств.
+type reportedConfigReader interface {
+	GetLatestConfigPerUser(organizationID int) ([]dao.LatestReportedConfigRow, error)
+	GetLatestConfigByUserID(userID int) (*dao.LatestReportedConfigRow, error)
+}
+
+// deviceCommandEnqueuer — постановка команды в очередь устройства (DeviceCommandService).
+type deviceCommandEnqueuer interface {
+	EnqueueCommand(userID int, cmdType string, payload map[string]interface{}) (*models.DeviceCommand, error)
+}
+
+// trackingPauseState — пауза отслеживания по графику приватности (TrackingScheduleService).
+type trackingPauseState interface {
+	TrackingPausedAt(userID int, at time.Time) (paused, managed bool, err error)
+}
+
+// DeviceConfigFieldDrift — расхождение одного поля desired/reported.
+type DeviceConfigFieldDrift struct {
+	Desired  interface{} `json:"desired"`
+	Reported interface{} `json:"reported"`
+}
+
+// DeviceConfigDriftStatus — дрейф конфигурации одного устройства.
+type DeviceConfigDriftStatus struct {
+	UserID       int                               `json:"user_id"`
+	ProfileID    *int                              `json:"profile_id,omitempty"`
+	Desired      models.DeviceConfigFields         `json:"desired"`
+	Reported     bool                              `json:"reported"`
+	ReportedAt   *time.Time                        `json:"reported_at,omitempty"`
+	InSync       bool                              `json:"in_sync"`
+	Drift        map[string]DeviceConfigFieldDrift `json:"drift"`
+	LastPushedAt *time.Time                        `json:"last_pushed_at,omitempty"`
+}
+
+// DeviceConfigService — desired-state конфигурация телефонов и автоматическое устранение дрейфа.
+type DeviceConfigService struct {
+	DAO      deviceConfigRepository
+	Reports  reportedConfigReader
+	Commands deviceCommandEnqueuer
+	// Schedules — при графике приватности tracking_paused задаёт он, а не профиль
+	// (иначе устранение дрейфа возобновляло бы отслеживание в нерабочее время).
+	Schedules trackingPauseState
+	// Clock — текущее время (nil — системное).
+	Clock Clock
+}
+
+func NewDeviceConfigService(dao deviceConfigRepository, reports reportedConfigReader, commands deviceCommandEnqueuer) *DeviceConfigService {
+	return &DeviceConfigService{DAO: dao, Reports: reports, Commands: commands}
+}
+
+// ListProfiles возвращает групповые профили организации.
+func (svc *DeviceConfigService) ListProfiles(organizationID int) ([]models.DeviceConfigProfile, error) {
+	return svc.DAO.GetAllProfiles(organizationOrDefault(organizationID))
+}
+
+// CreateProfile создаёт групповой профиль конфигурации организации.
+func (svc *DeviceConfigService) CreateProfile(organizationID int, name string, fields models.DeviceConfigFields) (*models.DeviceConfigProfile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrDeviceConfigUpdateInvalid
+	}
+	normalized, err := normalizeDeviceConfigFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	p := &models.DeviceConfigProfile{
+		OrganizationID:     organizationOrDefault(organizationID),
+		Name:               name,
+		DeviceConfigFields: normalized,
+	}
+	if err := svc.DAO.CreateProfile(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// UpdateProfile перезаписывает поля профиля; изменения применяются при следующем отчёте устройств.
+func (svc *DeviceConfigService) UpdateProfile(organizationID, id int, name string, fields models.DeviceConfigFields) (*models.DeviceConfigProfile, error) {
+	p, err := svc.getProfile(organizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := normalizeDeviceConfigFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	if name = strings.TrimSpace(name); name != "" {
+		p.Name = name
+	}
+	p.DeviceConfigFields = normalized
+	if err := svc.DAO.UpdateProfile(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// getProfile — профиль организации; профиль другой организации не находится.
+func (svc *DeviceConfigService) getProfile(organizationID, id int) (*models.DeviceConfigProfile, error) {
+	p, err := svc.DAO.GetProfileByID(organizationOrDefault(organizationID), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeviceConfigProfileNotFound
+	}
+	return p, err
+}
+
+// GetDesired возвращает сохранённую запись и итоговую конфигурацию с учётом профиля.
+func (svc *DeviceConfigService) GetDesired(userID int) (*models.DeviceDesiredConfig, models.DeviceConfigFields, error) {
+	cfg, err := svc.DAO.GetDesired(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.DeviceConfigFields{}, ErrDeviceDesiredConfigNotFound
+	}
+	if err != nil {
+		return nil, models.DeviceConfigFields{}, err
+	}
+	effective, err := svc.effectiveFields(cfg)
+	if err != nil {
+		return nil, models.DeviceConfigFields{}, err
+	}
+	if err := svc.applyTrackingSchedule(userID, &effective); err != nil {
+		return nil, models.DeviceConfigFields{}, err
+	}
+	return cfg, effective, nil
+}
+
+// applyTrackingSchedule подставляет tracking_paused из графика приватности пользователя.
+func (svc *DeviceConfigService) applyTrackingSchedule(userID int, fields *models.DeviceConfigFields) error {
+	if svc.Schedules == nil {
+		return nil
+	}
+	paused, managed, err := svc.Schedules.TrackingPausedAt(userID, clockNow(svc.Clock))
+	if err != nil || !managed {
+		return err
+	}
+	fields.TrackingPaused = &paused
+	return nil
+}
+
+// SetDesired задаёт желаемую конфигурацию пользователя организации organizationID
+// (profileID — наследуемый профиль той же организации, nil — без профиля).
+// Пользователь должен входить в область видимости сотрудника scope.
+func (svc *DeviceConfigService) SetDesired(
+	organizationID int,
+	scope *UserScope,
+	userID int,
+	profileID *int,
+	fields models.DeviceConfigFields,
+) (*models.DeviceDesiredConfig, error) {
+	if !scope.Allows(userID) {
+		return nil, ErrAccessDenied
+	}
+	normalized, err := normalizeDeviceConfigFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	if profileID != nil {
+		if _, err := svc.getProfile(organizationID, *profileID); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := svc.DAO.GetDesired(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		cfg = &models.DeviceDesiredConfig{UserID: userID}
+	} else if err != nil {
+		return nil, err
+	}
+	cfg.OrganizationID = organizationOrDefault(organizationID)
+	cfg.ProfileID = profileID
+	cfg.DeviceConfigFields = normalized
+	// Новая цель — сбрасываем антиспам, чтобы дрейф ушёл на устройство с ближайшим отчётом.
+	cfg.LastPushedHash = ""
+	cfg.LastPushedAt = nil
+	if err := svc.DAO.SaveDesired(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// effectiveFields накладывает поля пользователя поверх профиля его организации.
+func (svc *DeviceConfigService) effectiveFields(cfg *models.DeviceDesiredConfig) (models.DeviceConfigFields, error) {
+	var base models.DeviceConfigFields
+	if cfg.ProfileID != nil {
+		p, err := svc.getProfile(cfg.OrganizationID, *cfg.ProfileID)
+		if err != nil && !errors.Is(err, ErrDeviceConfigProfileNotFound) {
+			return models.DeviceConfigFields{}, err
+		}
+		if p != nil {
+			base = p.DeviceConfigFields
+		}
+	}
+	return mergeDeviceConfigFields(base, cfg.DeviceConfigFields), nil
+}
+
+// DriftForUser сравнивает desired с config последнего отчёта устройства.
+func (svc *DeviceConfigService) DriftForUser(userID int) (*DeviceConfigDriftStatus, error) {
+	cfg, effective, err := svc.GetDesired(userID)
+	if err != nil {
+		return nil, err
+	}
+	var row *dao.LatestReportedConfigRow
+	if svc.Reports != nil {
+		row, err = svc.Reports.GetLatestConfigByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buildDriftStatus(cfg, effective, row), nil
+}
+
+// DriftForAll — дрейф по всем устройствам организации с заданной desired-конфигурацией.
+func (svc *DeviceConfigService) DriftForAll(organizationID int) ([]DeviceConfigDriftStatus, error) {
+	organizationID = organizationOrDefault(organizationID)
+	items, err := svc.DAO.GetAllDesired(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	reported := make(map[int]*dao.LatestReportedConfigRow)
+	if svc.Reports != nil && len(items) > 0 {
+		rows, err := svc.Reports.GetLatestConfigPerUser(organizationID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			reported[rows[i].UserID] = &rows[i]
+		}
+	}
+
+	profiles := make(map[int]*models.DeviceConfigProfile)
+	out := make([]DeviceConfigDriftStatus, 0, len(items))
+	for i := range items {
+		cfg := &items[i]
+		var base models.DeviceConfigFields
+		if cfg.ProfileID != nil {
+			p, ok := profiles[*cfg.ProfileID]
+			if !ok {
+				p, err = svc.getProfile(cfg.OrganizationID, *cfg.ProfileID)
+				if err != nil && !errors.Is(err, ErrDeviceConfigProfileNotFound) {
+					return nil, err
+				}
+				profiles[*cfg.ProfileID] = p
+			}
+			if p != nil {
+				base = p.DeviceConfigFields
+			}
+		}
+		effective := mergeDeviceConfigFields(base, cfg.DeviceConfigFields)
+		if err := svc.applyTrackingSchedule(cfg.UserID, &effective); err != nil {
+			return nil, err
+		}
+		out = append(out, *buildDriftStatus(cfg, effective, reported[cfg.UserID]))
+	}
+	return out, nil
+}
+
+// ReconcileReported вызывается на POST /api/device/report: при дрейфе ставит config_update
+// только с расходящимися полями. nil без ошибки — конфигурация совпадает или desired не задана.
+func (svc *DeviceConfigService) ReconcileReported(userID int, reported map[string]interface{}) (*models.DeviceCommand, error) {
+	if reported == nil || svc.Commands == nil {
+		return nil, nil
+	}
+	cfg, effective, err := svc.GetDesired(userID)
+	if errors.Is(err, ErrDeviceDesiredConfigNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, input := computeDeviceConfigDrift(effective, reported)
+	payload, err := BuildConfigUpdatePayload(userID, input)
+	if errors.Is(err, ErrDeviceConfigUpdateEmpty) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hash := configPayloadHash(payload)
+	now := clockNow(svc.Clock).UTC()
+	if cfg.LastPushedHash == hash && cfg.LastPushedAt != nil && now.Sub(*cfg.LastPushedAt) < deviceConfigDriftRepushAfter {
+		return nil, nil
+	}
+
+	cmd, err := svc.Commands.EnqueueCommand(userID, models.DeviceCommandTypeConfigUpdate, payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.DAO.MarkPushed(userID, hash, now); err != nil {
+		slog.Error("Не удалось сохранить отметку отправки конфигурации", "user_id", userID, "error", err)
+	}
+	slog.Info("Дрейф конфигурации, отправлен config_update", "user_id", userID, "command_id", cmd.ID, "fields", len(payload))
+	return cmd, nil
+}
+
+func buildDriftStatus(
+	cfg *models.DeviceDesiredConfig,
+	effective models.DeviceConfigFields,
+	row *dao.LatestReportedConfigRow,
+) *DeviceConfigDriftStatus {
+	status := &DeviceConfigDriftStatus{
+		UserID:       cfg.UserID,
+		ProfileID:    cfg.ProfileID,
+		Desired:      MaskDeviceConfigFields(effective),
+		LastPushedAt: cfg.LastPushedAt,
+		Drift:        map[string]DeviceConfigFieldDrift{},
+	}
+	if row == nil || len(row.Config) == 0 {
+		return status
+	}
+	var reported map[string]interface{}
+	if err := json.Unmarshal(row.Config, &reported); err != nil || reported == nil {
+		return status
+	}
+	at := row.CreatedAt.UTC()
+	status.Reported = true
+	status.ReportedAt = &at
+	status.Drift, _ = computeDeviceConfigDrift(effective, reported)
+	status.InSync = len(status.Drift) == 0
+	return status
+}
+
+// computeDeviceConfigDrift сравнивает desired с reported config телефона.
+// Отсутствующее в reported поле считается расхождением (телефон его не подтвердил).
+// PIN не передаётся телефоном открыто: сравнивается admin_pin_sha256.
+func computeDeviceConfigDrift(
+	desired models.DeviceConfigFields,
+	reported map[string]interface{},
+) (map[string]DeviceConfigFieldDrift, DeviceConfigUpdateInput) {
+	drift := make(map[string]DeviceConfigFieldDrift)
+	var input DeviceConfigUpdateInput
+
+	checkInt := func(key string, want *int64, set func(*int64)) {
+		if want == nil {
+			return
+		}
+		got, ok := reportedInt(reported[key])
+		if ok && got == *want {
+			return
+		}
+		drift[key] = DeviceConfigFieldDrift{Desired: *want, Reported: reported[key]}
+		set(want)
+	}
+	checkBool := func(key string, want *bool, set func(*bool)) {
+		if want == nil {
+			return
+		}
+		got, ok := reported[key].(bool)
+		if ok && got == *want {
+			return
+		}
+		drift[key] = DeviceConfigFieldDrift{Desired: *want, Reported: reported[key]}
+		set(want)
+	}
+
+	checkInt("location_interval_seconds", desired.LocationIntervalSeconds, func(v *int64) { input.LocationIntervalSeconds = v })
+	checkInt("poll_interval_seconds", desired.PollIntervalSeconds, func(v *int64) { input.PollIntervalSeconds = v })
+	checkInt("health_report_interval_seconds", desired.HealthReportIntervalSeconds, func(v *int64) { input.HealthReportIntervalSeconds = v })
+	checkBool("tracking_paused", desired.TrackingPaused, func(v *bool) { input.TrackingPaused = v })
+	checkBool("hidden_from_launcher", desired.HiddenFromLauncher, func(v *bool) { input.HiddenFromLauncher = v })
+
+	if desired.APIBaseURL != nil {
+		got, _ := reported["api_base_url"].(string)
+		if strings.TrimRight(strings.TrimSpace(got), "/") != *desired.APIBaseURL {
+			drift["api_base_url"] = DeviceConfigFieldDrift{Desired: *desired.APIBaseURL, Reported: reported["api_base_url"]}
+			input.APIBaseURL = desired.APIBaseURL
+		}
+	}
+	if desired.AdminPin != nil {
+		got, _ := reported["admin_pin_sha256"].(string)
+		if !strings.EqualFold(strings.TrimSpace(got), adminPinSHA256(*desired.AdminPin)) {
+			var rep interface{}
+			if got != "" {
+				rep = "***"
+			}
+			drift["admin_pin"] = DeviceConfigFieldDrift{Desired: "***", Reported: rep}
+			input.AdminPin = desired.AdminPin
+		}
+	}
+	return drift, input
+}
+
+func reportedInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), float64(int64(n)) == n
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// normalizeDeviceConfigFields проверяет поля теми же правилами, что и config_update.
+func normalizeDeviceConfigFields(f models.DeviceConfigFields) (models.DeviceConfigFields, error) {
+	_, err := BuildConfigUpdatePayload(0, DeviceConfigUpdateInput{
+		APIBaseURL:                  f.APIBaseURL,
+		TrackingPaused:              f.TrackingPaused,
+		LocationIntervalSeconds:     f.LocationIntervalSeconds,
+		PollIntervalSeconds:         f.PollIntervalSeconds,
+		HealthReportIntervalSeconds: f.HealthReportIntervalSeconds,
+		AdminPin:                    f.AdminPin,
+		HiddenFromLauncher:          f.HiddenFromLauncher,
+	})
+	if err != nil && !errors.Is(err, ErrDeviceConfigUpdateEmpty) {
+		return models.DeviceConfigFields{}, err
+	}
+	if f.APIBaseURL != nil {
+		url := strings.TrimSpace(strings.TrimRight(*f.APIBaseURL, "/"))
+		f.APIBaseURL = &url
+	}
+	if f.AdminPin != nil {
+		pin := strings.TrimSpace(*f.AdminPin)
+		f.AdminPin = &pin
+	}
+	return f, nil
+}
+
+func mergeDeviceConfigFields(base, override models.DeviceConfigFields) models.DeviceConfigFields {
+	out := base
+	if override.LocationIntervalSeconds != nil {
+		out.LocationIntervalSeconds = override.LocationIntervalSeconds
+	}
+	if override.PollIntervalSeconds != nil {
+		out.PollIntervalSeconds = override.PollIntervalSeconds
+	}
+	if override.HealthReportIntervalSeconds != nil {
+		out.HealthReportIntervalSeconds = override.HealthReportIntervalSeconds
+	}
+	if override.TrackingPaused != nil {
+		out.TrackingPaused = override.TrackingPaused
+	}
+	if override.HiddenFromLauncher != nil {
+		out.HiddenFromLauncher = override.HiddenFromLauncher
+	}
+	if override.APIBaseURL != nil {
+		out.APIBaseURL = override.APIBaseURL
+	}
+	if override.AdminPin != nil {
+		out.AdminPin = override.AdminPin
+	}
+	return out
+}
+
+// MaskDeviceConfigFields скрывает PIN в ответах админки: его задают, но не читают обратно.
+func MaskDeviceConfigFields(f models.DeviceConfigFields) models.DeviceConfigFields {
+	if f.AdminPin != nil {
+		masked := "***"
+		f.AdminPin = &masked
+	}
+	return f
+}
+
+func adminPinSHA256(pin string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(pin)))
+	return hex.EncodeToString(sum[:])
+}
+
+func configPayloadHash(payload map[string]interface{}) string {
+	data, _ := json.Marshal(payload)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
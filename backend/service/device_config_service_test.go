@@ -0,0 +1,181 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"locator/internal/testutil/memrepo"
+	"locator/models"
+)
+
+func newDeviceConfigRepo() *memrepo.DeviceConfigs {
+	return memrepo.New(nil).DeviceConfigs
+}
+
+type fakeCommandEnqueuer struct {
+	commands []models.DeviceCommand
+	payloads []map[string]interface{}
+}
+
+func (f *fakeCommandEnqueuer) EnqueueCommand(userID int, cmdType string, payload map[string]interface{}) (*models.DeviceCommand, error) {
+	cmd := models.DeviceCommand{ID: "cmd", UserID: userID, Type: cmdType, Status: models.DeviceCommandStatusPending}
+	f.commands = append(f.commands, cmd)
+	f.payloads = append(f.payloads, payload)
+	return &cmd, nil
+}
+
+func TestDeviceConfig_profileInheritance(t *testing.T) {
+	repo := newDeviceConfigRepo()
+	svc := NewDeviceConfigService(repo, nil, nil)
+
+	interval := int64(300)
+	paused := false
+	profile, err := svc.CreateProfile(1, "Школьники", models.DeviceConfigFields{
+		LocationIntervalSeconds: &interval,
+		TrackingPaused:          &paused,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	override := int64(60)
+	if _, err := svc.SetDesired(1, nil, 7, &profile.ID, models.DeviceConfigFields{LocationIntervalSeconds: &override}); err != nil {
+		t.Fatal(err)
+	}
+	_, effective, err := svc.GetDesired(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if effective.LocationIntervalSeconds == nil || *effective.LocationIntervalSeconds != 60 {
+		t.Fatalf("override not applied: %v", effective.LocationIntervalSeconds)
+	}
+	if effective.TrackingPaused == nil || *effective.TrackingPaused {
+		t.Fatalf("profile field not inherited: %v", effective.TrackingPaused)
+	}
+}
+
+func TestDeviceConfig_setDesiredRejectsInvalid(t *testing.T) {
+	svc := NewDeviceConfigService(newDeviceConfigRepo(), nil, nil)
+	tooShort := int64(5)
+	if _, err := svc.SetDesired(1, nil, 1, nil, models.DeviceConfigFields{LocationIntervalSeconds: &tooShort}); err != ErrDeviceConfigUpdateInvalid {
+		t.Fatalf("expected invalid, got %v", err)
+	}
+	missing := 42
+	if _, err := svc.SetDesired(1, nil, 1, &missing, models.DeviceConfigFields{}); err != ErrDeviceConfigProfileNotFound {
+		t.Fatalf("expected profile not found, got %v", err)
+	}
+}
+
+func TestDeviceConfig_profilesScopedByOrganization(t *testing.T) {
+	svc := NewDeviceConfigService(newDeviceConfigRepo(), nil, nil)
+	pin := "4321"
+	foreign, err := svc.CreateProfile(2, "Чужой", models.DeviceConfigFields{AdminPin: &pin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, err := svc.CreateProfile(1, "Свой", models.DeviceConfigFields{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := svc.ListProfiles(1)
+	if err != nil || len(profiles) != 1 || profiles[0].ID != own.ID {
+		t.Fatalf("organisation 1 must see only its profile, got %+v %v", profiles, err)
+	}
+	if _, err := svc.UpdateProfile(1, foreign.ID, "Захват", models.DeviceConfigFields{}); !errors.Is(err, ErrDeviceConfigProfileNotFound) {
+		t.Fatalf("foreign profile must not be editable, got %v", err)
+	}
+	if _, err := svc.SetDesired(1, nil, 7, &foreign.ID, models.DeviceConfigFields{}); !errors.Is(err, ErrDeviceConfigProfileNotFound) {
+		t.Fatalf("foreign profile must not be assignable, got %v", err)
+	}
+	if _, err := svc.SetDesired(1, NewUserScope(5), 7, &own.ID, models.DeviceConfigFields{}); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("user outside the scope must be rejected, got %v", err)
+	}
+	if items, err := svc.DriftForAll(2); err != nil || len(items) != 0 {
+		t.Fatalf("organisation 2 must not see desired configs of organisation 1, got %+v %v", items, err)
+	}
+}
+
+func TestComputeDeviceConfigDrift_onlyDriftedFields(t *testing.T) {
+	interval := int64(120)
+	poll := int64(15)
+	hidden := true
+	url := "http://example.test:8080"
+	pin := "1234"
+	desired := models.DeviceConfigFields{
+		LocationIntervalSeconds: &interval,
+		PollIntervalSeconds:     &poll,
+		HiddenFromLauncher:      &hidden,
+		APIBaseURL:              &url,
+		AdminPin:                &pin,
+	}
+	reported := map[string]interface{}{
+		"location_interval_seconds": float64(120),
+		"poll_interval_seconds":     float64(30),
+		"hidden_from_launcher":      true,
+		"api_base_url":              "http://example.test:8080/",
+		"admin_pin_sha256":          adminPinSHA256("0000"),
+	}
+
+	drift, input := computeDeviceConfigDrift(desired, reported)
+	if len(drift) != 2 {
+		t.Fatalf("want 2 drifted fields, got %v", drift)
+	}
+	if _, ok := drift["poll_interval_seconds"]; !ok {
+		t.Fatal("poll_interval_seconds should drift")
+	}
+	if d, ok := drift["admin_pin"]; !ok || d.Desired != "***" {
+		t.Fatalf("admin_pin drift must be masked: %+v", d)
+	}
+	if input.LocationIntervalSeconds != nil || input.HiddenFromLauncher != nil || input.APIBaseURL != nil {
+		t.Fatalf("in-sync fields must not be pushed: %+v", input)
+	}
+	if input.PollIntervalSeconds == nil || input.AdminPin == nil {
+		t.Fatalf("drifted fields must be pushed: %+v", input)
+	}
+}
+
+func TestReconcileReported_enqueuesOnceForSameDrift(t *testing.T) {
+	repo := newDeviceConfigRepo()
+	cmds := &fakeCommandEnqueuer{}
+	svc := NewDeviceConfigService(repo, nil, cmds)
+
+	paused := true
+	if _, err := svc.SetDesired(1, nil, 3, nil, models.DeviceConfigFields{TrackingPaused: &paused}); err != nil {
+		t.Fatal(err)
+	}
+
+	reported := map[string]interface{}{"tracking_paused": false}
+	cmd, err := svc.ReconcileReported(3, reported)
+	if err != nil || cmd == nil {
+		t.Fatalf("expected config_update, got cmd=%v err=%v", cmd, err)
+	}
+	if cmds.commands[0].Type != models.DeviceCommandTypeConfigUpdate {
+		t.Fatalf("type=%s", cmds.commands[0].Type)
+	}
+	if len(cmds.payloads[0]) != 1 || cmds.payloads[0]["tracking_paused"] != true {
+		t.Fatalf("payload=%v", cmds.payloads[0])
+	}
+
+	again, err := svc.ReconcileReported(3, reported)
+	if err != nil || again != nil {
+		t.Fatalf("same drift must not be re-pushed immediately: cmd=%v err=%v", again, err)
+	}
+
+	inSync, err := svc.ReconcileReported(3, map[string]interface{}{"tracking_paused": true})
+	if err != nil || inSync != nil {
+		t.Fatalf("in-sync config must not enqueue: cmd=%v err=%v", inSync, err)
+	}
+	if len(cmds.commands) != 1 {
+		t.Fatalf("enqueued %d commands", len(cmds.commands))
+	}
+}
+
+func TestReconcileReported_noDesiredConfig(t *testing.T) {
+	cmds := &fakeCommandEnqueuer{}
+	svc := NewDeviceConfigService(newDeviceConfigRepo(), nil, cmds)
+	cmd, err := svc.ReconcileReported(9, map[string]interface{}{"tracking_paused": true})
+	if err != nil || cmd != nil || len(cmds.commands) != 0 {
+		t.Fatalf("cmd=%v err=%v", cmd, err)
+	}
+}
@@ -3,44 +3,110 @@ package service
 import (
 	"encoding/json"
 	"errors"
-	"locator/dao"
+	"fmt"
 	"locator/models"
+	"sort"
+	"time"
 
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
-var ErrDeviceReportNotFound = errors.New("device report not found")
+var (
+	ErrDeviceReportNotFound     = errors.New("device report not found")
+	ErrDeviceReportRangeInvalid = errors.New("invalid device report range")
+	// ErrDeviceReportInvalid — известное поле отчёта не того типа (например, battery строкой).
+	ErrDeviceReportInvalid = errors.New("invalid device report")
+)
+
+const (
+	deviceReportDefaultRange = 7 * 24 * time.Hour
+	deviceReportDefaultLimit = 50
+	deviceReportMaxLimit     = 500
+)
 
 // DeviceReportService — диагностические отчёты с устройств.
 type DeviceReportService struct {
-	DAO *dao.DeviceReportDAO
+	DAO deviceReportRepository
+	// Clock — текущее время (nil — системное).
+	Clock Clock
 }
 
-func NewDeviceReportService(dao *dao.DeviceReportDAO) *DeviceReportService {
+func NewDeviceReportService(dao deviceReportRepository) *DeviceReportService {
 	return &DeviceReportService{DAO: dao}
 }
 
-// SaveReport сохраняет отчёт с устройства.
+// DeviceReportItem — отчёт в истории: служебные поля + типизированное тело.
+type DeviceReportItem struct {
+	ID         int                         `json:"id"`
+	CreatedAt  time.Time                   `json:"created_at"`
+	AppVersion string                      `json:"app_version,omitempty"`
+	Platform   string                      `json:"platform,omitempty"`
+	Issues     []string                    `json:"issues"`
+	Report     *models.DeviceReportPayload `json:"report"`
+}
+
+// DeviceReportPage — страница истории отчётов.
+type DeviceReportPage struct {
+	From   time.Time          `json:"from"`
+	To     time.Time          `json:"to"`
+	Total  int64              `json:"total"`
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+	Items  []DeviceReportItem `json:"items"`
+}
+
+// DeviceIssueEpisode — непрерывный период, пока проблема присутствовала в отчётах.
+// ResolvedAt — время первого отчёта без этой проблемы; nil, если проблема ещё есть.
+type DeviceIssueEpisode struct {
+	Issue       string     `json:"issue"`
+	FirstSeenAt time.Time  `json:"first_seen_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+	Ongoing     bool       `json:"ongoing"`
+	Reports     int        `json:"reports"`
+}
+
+// DeviceReportTrendPoint — ключевые метрики одного отчёта для графиков.
+type DeviceReportTrendPoint struct {
+	At                       time.Time `json:"at"`
+	BatteryPercent           *float64  `json:"battery_percent,omitempty"`
+	Charging                 *bool     `json:"charging,omitempty"`
+	PowerSaveMode            *bool     `json:"power_save_mode,omitempty"`
+	BackgroundRestricted     *bool     `json:"background_restricted,omitempty"`
+	ForegroundServiceRunning *bool     `json:"foreground_service_running,omitempty"`
+	LocationPermission       string    `json:"location_permission,omitempty"`
+	NetworkType              string    `json:"network_type,omitempty"`
+	StorageFreeBytes         *int64    `json:"storage_free_bytes,omitempty"`
+	AppVersion               string    `json:"app_version,omitempty"`
+	IssueCount               int       `json:"issue_count"`
+}
+
+// SaveReport сохраняет отчёт с устройства. Тело хранится целиком (включая
+// неизвестные поля); app_version, platform и issues дублируются в колонки.
 func (svc *DeviceReportService) SaveReport(userID int, body map[string]interface{}) (*models.DeviceReport, error) {
 	reportJSON, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
 
-	report := &models.DeviceReport{
-		UserID: userID,
-		Report: datatypes.JSON(reportJSON),
+	var payload models.DeviceReportPayload
+	if err := json.Unmarshal(reportJSON, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeviceReportInvalid, err)
 	}
 
-	if v, ok := body["app_version"].(string); ok {
-		report.AppVersion = v
-	}
-	if v, ok := body["platform"].(string); ok {
-		report.Platform = v
+	report := &models.DeviceReport{
+		UserID:     userID,
+		Report:     datatypes.JSON(reportJSON),
+		AppVersion: payload.AppVersion,
+		Platform:   payload.Platform,
 	}
-	if issuesRaw, ok := body["issues"]; ok {
-		issuesBytes, err := json.Marshal(issuesRaw)
+	if _, ok := body["issues"]; ok {
+		issues := payload.Issues
+		if issues == nil {
+			issues = models.DeviceReportIssues{}
+		}
+		issuesBytes, err := json.Marshal(issues)
 		if err != nil {
 			return nil, err
 		}
@@ -65,6 +131,200 @@ func (svc *DeviceReportService) GetLatestByUserID(userID int) (*models.DeviceRep
 	return report, nil
 }
 
+// ListReports — история отчётов за интервал с пагинацией (новые первыми).
+// Пустые from/to — последние 7 суток; локальные границы и времена ответа — в поясе loc.
+func (svc *DeviceReportService) ListReports(userID int, fromStr, toStr string, loc *time.Location, limit, offset int) (*DeviceReportPage, error) {
+	from, to, err := parseDeviceReportRange(fromStr, toStr, loc, clockNow(svc.Clock))
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = deviceReportDefaultLimit
+	}
+	if limit > deviceReportMaxLimit {
+		limit = deviceReportMaxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	reports, total, err := svc.DAO.ListByUserBetween(userID, from, to, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &DeviceReportPage{
+		From:   inLocation(from, loc),
+		To:     inLocation(to, loc),
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+		Items:  make([]DeviceReportItem, 0, len(reports)),
+	}
+	for i := range reports {
+		payload, err := reports[i].Payload()
+		if err != nil {
+			payload = &models.DeviceReportPayload{}
+		}
+		issues, _ := IssuesSlice(&reports[i])
+		if issues == nil {
+			issues = []string{}
+		}
+		page.Items = append(page.Items, DeviceReportItem{
+			ID:         reports[i].ID,
+			CreatedAt:  inLocation(reports[i].CreatedAt, loc),
+			AppVersion: reports[i].AppVersion,
+			Platform:   reports[i].Platform,
+			Issues:     issues,
+			Report:     payload,
+		})
+	}
+	return page, nil
+}
+
+// IssueTimeline — эпизоды проблем за интервал: когда появились и когда ушли (пояс loc — как у ListReports).
+func (svc *DeviceReportService) IssueTimeline(userID int, fromStr, toStr string, loc *time.Location) ([]DeviceIssueEpisode, error) {
+	from, to, err := parseDeviceReportRange(fromStr, toStr, loc, clockNow(svc.Clock))
+	if err != nil {
+		return nil, err
+	}
+	reports, err := svc.DAO.GetByUserBetweenAsc(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	episodes := BuildIssueTimeline(reports)
+	for i := range episodes {
+		episodes[i].FirstSeenAt = inLocation(episodes[i].FirstSeenAt, loc)
+		episodes[i].LastSeenAt = inLocation(episodes[i].LastSeenAt, loc)
+		if episodes[i].ResolvedAt != nil {
+			resolved := inLocation(*episodes[i].ResolvedAt, loc)
+			episodes[i].ResolvedAt = &resolved
+		}
+	}
+	return episodes, nil
+}
+
+// Trends — ряд метрик (батарея, сеть, фон, место) по каждому отчёту за интервал (пояс loc — как у ListReports).
+func (svc *DeviceReportService) Trends(userID int, fromStr, toStr string, loc *time.Location) ([]DeviceReportTrendPoint, error) {
+	from, to, err := parseDeviceReportRange(fromStr, toStr, loc, clockNow(svc.Clock))
+	if err != nil {
+		return nil, err
+	}
+	reports, err := svc.DAO.GetByUserBetweenAsc(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]DeviceReportTrendPoint, 0, len(reports))
+	for i := range reports {
+		point := reportTrendPoint(&reports[i])
+		point.At = inLocation(point.At, loc)
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// BuildIssueTimeline сворачивает хронологический ряд отчётов в эпизоды проблем.
+// Эпизод закрывается первым отчётом, где проблемы уже нет; отчёт без ключа issues
+// о проблемах ничего не сообщает и эпизоды не закрывает.
+func BuildIssueTimeline(reports []models.DeviceReport) []DeviceIssueEpisode {
+	episodes := make([]DeviceIssueEpisode, 0)
+	open := make(map[string]int) // issue → индекс открытого эпизода
+
+	for i := range reports {
+		if !reportsIssues(&reports[i]) {
+			continue
+		}
+		at := reports[i].CreatedAt
+		issues, _ := IssuesSlice(&reports[i])
+		present := make(map[string]struct{}, len(issues))
+		for _, issue := range issues {
+			if _, dup := present[issue]; dup {
+				continue
+			}
+			present[issue] = struct{}{}
+			if idx, ok := open[issue]; ok {
+				episodes[idx].LastSeenAt = at
+				episodes[idx].Reports++
+				continue
+			}
+			open[issue] = len(episodes)
+			episodes = append(episodes, DeviceIssueEpisode{
+				Issue:       issue,
+				FirstSeenAt: at,
+				LastSeenAt:  at,
+				Ongoing:     true,
+				Reports:     1,
+			})
+		}
+		for issue, idx := range open {
+			if _, still := present[issue]; still {
+				continue
+			}
+			resolved := at
+			episodes[idx].ResolvedAt = &resolved
+			episodes[idx].Ongoing = false
+			delete(open, issue)
+		}
+	}
+
+	sort.SliceStable(episodes, func(i, j int) bool {
+		return episodes[i].FirstSeenAt.Before(episodes[j].FirstSeenAt)
+	})
+	return episodes
+}
+
+// reportsIssues — в отчёте был ключ issues (SaveReport пишет колонку только тогда).
+func reportsIssues(report *models.DeviceReport) bool {
+	return len(report.Issues) > 0 && string(report.Issues) != "null"
+}
+
+func reportTrendPoint(report *models.DeviceReport) DeviceReportTrendPoint {
+	issues, _ := IssuesSlice(report)
+	point := DeviceReportTrendPoint{
+		At:         report.CreatedAt,
+		AppVersion: report.AppVersion,
+		IssueCount: len(issues),
+	}
+	payload, err := report.Payload()
+	if err != nil {
+		return point
+	}
+	if payload.Battery != nil {
+		point.BatteryPercent = payload.Battery.LevelPercent
+		point.Charging = payload.Battery.Charging
+		point.PowerSaveMode = payload.Battery.PowerSaveMode
+	}
+	if payload.Location != nil {
+		point.ForegroundServiceRunning = payload.Location.ForegroundServiceRunning
+		point.LocationPermission = payload.Location.Permission
+	}
+	if payload.Network != nil {
+		point.NetworkType = payload.Network.Type
+	}
+	if payload.Storage != nil {
+		point.StorageFreeBytes = payload.Storage.FreeBytes
+	}
+	point.BackgroundRestricted = payload.BackgroundRestricted
+	return point
+}
+
+// parseDeviceReportRange — from/to как у визитов (RFC3339 или локальное время в loc);
+// без границ — последние 7 суток до now.
+func parseDeviceReportRange(fromStr, toStr string, loc *time.Location, now time.Time) (time.Time, time.Time, error) {
+	if fromStr == "" && toStr == "" {
+		to := now.UTC()
+		return to.Add(-deviceReportDefaultRange), to, nil
+	}
+	if fromStr == "" || toStr == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: укажите from и to вместе", ErrDeviceReportRangeInvalid)
+	}
+	from, to, err := parseLocalRange(fromStr, toStr, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %v", ErrDeviceReportRangeInvalid, err)
+	}
+	return from, to, nil
+}
+
 // ReportAsMap разбирает JSON отчёта в map.
 func ReportAsMap(report *models.DeviceReport) (map[string]interface{}, error) {
 	if report == nil || len(report.Report) == 0 {
@@ -82,24 +342,8 @@ func ReportAsMap(report *models.DeviceReport) (map[string]interface{}, error) {
 
 // IssuesSlice возвращает список проблем из отчёта.
 func IssuesSlice(report *models.DeviceReport) ([]string, error) {
-	if report == nil || len(report.Issues) == 0 {
+	if report == nil {
 		return nil, nil
 	}
-
-	var issues []string
-	if err := json.Unmarshal(report.Issues, &issues); err == nil {
-		return issues, nil
-	}
-
-	var generic []interface{}
-	if err := json.Unmarshal(report.Issues, &generic); err != nil {
-		return nil, err
-	}
-	out := make([]string, 0, len(generic))
-	for _, item := range generic {
-		if s, ok := item.(string); ok {
-			out = append(out, s)
-		}
-	}
-	return out, nil
+	return models.ParseDeviceReportIssues(report.Issues)
 }
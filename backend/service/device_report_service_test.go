@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"locator/models"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type fakeDeviceReportRepo struct {
+	reports []models.DeviceReport
+	limit   int
+	offset  int
+}
+
+func (f *fakeDeviceReportRepo) Create(report *models.DeviceReport) error {
+	report.ID = len(f.reports) + 1
+	f.reports = append(f.reports, *report)
+	return nil
+}
+
+func (f *fakeDeviceReportRepo) GetLatestByUserID(userID int) (*models.DeviceReport, error) {
+	for i := len(f.reports) - 1; i >= 0; i-- {
+		if f.reports[i].UserID == userID {
+			return &f.reports[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeDeviceReportRepo) ListByUserBetween(userID int, from, to time.Time, limit, offset int) ([]models.DeviceReport, int64, error) {
+	f.limit, f.offset = limit, offset
+	return f.reports, int64(len(f.reports)), nil
+}
+
+func (f *fakeDeviceReportRepo) GetByUserBetweenAsc(userID int, from, to time.Time) ([]models.DeviceReport, error) {
+	return f.reports, nil
+}
+
+func reportWithIssues(at time.Time, issues string) models.DeviceReport {
+	return models.DeviceReport{UserID: 1, CreatedAt: at, Issues: datatypes.JSON(issues)}
+}
+
+func TestBuildIssueTimeline_eveningEpisodes(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	reports := []models.DeviceReport{
+		reportWithIssues(day.Add(17*time.Hour), `[]`),
+		reportWithIssues(day.Add(19*time.Hour), `["background_stopped"]`),
+		reportWithIssues(day.Add(20*time.Hour), `["background_stopped","post_stale"]`),
+		// Отчёт без ключа issues эпизоды не закрывает.
+		{UserID: 1, CreatedAt: day.Add(25 * time.Hour)},
+		reportWithIssues(day.Add(31*time.Hour), `[]`),
+		reportWithIssues(day.Add(43*time.Hour), `["background_stopped"]`),
+	}
+
+	episodes := BuildIssueTimeline(reports)
+	if len(episodes) != 3 {
+		t.Fatalf("want 3 episodes, got %+v", episodes)
+	}
+	first := episodes[0]
+	if first.Issue != "background_stopped" || first.Reports != 2 || first.Ongoing {
+		t.Fatalf("first episode: %+v", first)
+	}
+	if first.ResolvedAt == nil || !first.ResolvedAt.Equal(day.Add(31*time.Hour)) {
+		t.Fatalf("resolved_at=%v", first.ResolvedAt)
+	}
+	if episodes[1].Issue != "post_stale" || !episodes[1].LastSeenAt.Equal(day.Add(20*time.Hour)) {
+		t.Fatalf("second episode: %+v", episodes[1])
+	}
+	last := episodes[2]
+	if !last.Ongoing || last.ResolvedAt != nil || !last.FirstSeenAt.Equal(day.Add(43*time.Hour)) {
+		t.Fatalf("last episode must be ongoing: %+v", last)
+	}
+}
+
+func TestSaveReport_normalizesIssuesAndKeepsBody(t *testing.T) {
+	repo := &fakeDeviceReportRepo{}
+	svc := NewDeviceReportService(repo)
+
+	report, err := svc.SaveReport(5, map[string]interface{}{
+		"app_version": "2.0.1",
+		"platform":    "android",
+		"issues":      []interface{}{"post_failed", map[string]interface{}{"code": "poll_stale"}},
+		"vendor_blob": map[string]interface{}{"x": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.AppVersion != "2.0.1" || report.Platform != "android" {
+		t.Fatalf("columns: %+v", report)
+	}
+	issues, _ := IssuesSlice(report)
+	if len(issues) != 2 || issues[1] != "poll_stale" {
+		t.Fatalf("issues=%v", issues)
+	}
+	body, _ := ReportAsMap(report)
+	if _, ok := body["vendor_blob"]; !ok {
+		t.Fatal("unknown field dropped from stored report")
+	}
+
+	if _, err := svc.SaveReport(5, map[string]interface{}{"battery": "full"}); !errors.Is(err, ErrDeviceReportInvalid) {
+		t.Fatalf("malformed known field: %v", err)
+	}
+	if len(repo.reports) != 1 {
+		t.Fatalf("malformed report stored: %d", len(repo.reports))
+	}
+	bare, err := svc.SaveReport(5, map[string]interface{}{"app_version": "2.0.1"})
+	if err != nil || bare.Issues != nil {
+		t.Fatalf("report without issues: issues=%s err=%v", bare.Issues, err)
+	}
+}
+
+func TestListReports_clampsPagination(t *testing.T) {
+	repo := &fakeDeviceReportRepo{}
+	svc := NewDeviceReportService(repo)
+
+	if _, err := svc.ListReports(1, "", "", nil, 10000, -3); err != nil {
+		t.Fatal(err)
+	}
+	if repo.limit != deviceReportMaxLimit || repo.offset != 0 {
+		t.Fatalf("limit=%d offset=%d", repo.limit, repo.offset)
+	}
+	if _, err := svc.ListReports(1, "2026-10-01T00:00", "", nil, 0, 0); err == nil {
+		t.Fatal("from without to must be rejected")
+	}
+}
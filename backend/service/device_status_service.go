@@ -1,8 +1,7 @@
 package service
 
 import (
-	"encoding/json"
-	"locator/dao"
+	"locator/models"
 	"time"
 )
 
@@ -21,20 +20,21 @@ type UserDeviceStatusSummary struct {
 
 // DeviceStatusService — пакетная сводка GPS и health для админки.
 type DeviceStatusService struct {
-	LocationDAO *dao.LocationDAO
-	ReportDAO   *dao.DeviceReportDAO
+	LocationDAO locationAgeRepository
+	ReportDAO   latestReportRepository
 }
 
-func NewDeviceStatusService(locationDAO *dao.LocationDAO, reportDAO *dao.DeviceReportDAO) *DeviceStatusService {
+func NewDeviceStatusService(locationDAO locationAgeRepository, reportDAO latestReportRepository) *DeviceStatusService {
 	return &DeviceStatusService{LocationDAO: locationDAO, ReportDAO: reportDAO}
 }
 
 // AllUsersSummary — один запрос к БД вместо N×2 HTTP из админки.
-func (svc *DeviceStatusService) AllUsersSummary() (map[int]UserDeviceStatusSummary, error) {
+// В сводку попадают только пользователи организации organizationID.
+func (svc *DeviceStatusService) AllUsersSummary(organizationID int) (map[int]UserDeviceStatusSummary, error) {
 	out := make(map[int]UserDeviceStatusSummary)
 
 	if svc.LocationDAO != nil {
-		ages, err := svc.LocationDAO.GetLatestAgePerUser()
+		ages, err := svc.LocationDAO.GetLatestAgePerUser(organizationOrDefault(organizationID))
 		if err != nil {
 			return nil, err
 		}
@@ -56,12 +56,12 @@ func (svc *DeviceStatusService) AllUsersSummary() (map[int]UserDeviceStatusSumma
 		return out, nil
 	}
 
-	reports, err := svc.ReportDAO.GetLatestPerUser()
+	reports, err := svc.ReportDAO.GetLatestPerUser(organizationOrDefault(organizationID))
 	if err != nil {
 		return nil, err
 	}
 	for _, row := range reports {
-		issues, _ := parseIssuesJSON(row.Issues)
+		issues, _ := models.ParseDeviceReportIssues(row.Issues)
 		healthy := len(issues) == 0
 		ts := row.CreatedAt.UTC().Format(time.RFC3339)
 		prev := out[row.UserID]
@@ -78,24 +78,3 @@ func (svc *DeviceStatusService) AllUsersSummary() (map[int]UserDeviceStatusSumma
 
 	return out, nil
 }
-
-func parseIssuesJSON(raw []byte) ([]string, error) {
-	if len(raw) == 0 {
-		return nil, nil
-	}
-	var issues []string
-	if err := json.Unmarshal(raw, &issues); err == nil {
-		return issues, nil
-	}
-	var generic []interface{}
-	if err := json.Unmarshal(raw, &generic); err != nil {
-		return nil, err
-	}
-	out := make([]string, 0, len(generic))
-	for _, item := range generic {
-		if s, ok := item.(string); ok {
-			out = append(out, s)
-		}
-	}
-	return out, nil
-}
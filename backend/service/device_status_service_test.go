@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"locator/internal/testutil"
+	"locator/internal/testutil/memrepo"
+	"locator/models"
+)
+
+func TestDeviceStatusAllUsersSummary(t *testing.T) {
+	now := testutil.FixedUTC(2026, 7, 1, 12, 0, 0)
+	store := memrepo.New(testutil.NewClock(now))
+	for _, u := range []models.User{
+		{ID: 1, Name: "online", OrganizationID: 1},
+		{ID: 2, Name: "stale", OrganizationID: 1},
+		{ID: 3, Name: "other org", OrganizationID: 2},
+	} {
+		if err := store.Users.Create(&u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, loc := range []models.Location{
+		testutil.Location(0, 1, 53.9, 27.5, now.Add(-time.Minute)),
+		testutil.Location(0, 2, 53.9, 27.5, now.Add(-time.Hour)),
+		testutil.Location(0, 3, 53.9, 27.5, now),
+	} {
+		if err := store.Locations.Create(&loc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report := &models.DeviceReport{
+		UserID: 2, AppVersion: "1.4.0", Platform: "android",
+		Report: []byte(`{"battery":{"level_percent":12}}`), Issues: []byte(`["battery_low"]`),
+		CreatedAt: now.Add(-10 * time.Minute),
+	}
+	if err := store.DeviceReports.Create(report); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewDeviceStatusService(store.Locations, store.DeviceReports)
+	summary, err := svc.AllUsersSummary(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary) != 2 {
+		t.Fatalf("summary covers %d users, want 2 (own organization only)", len(summary))
+	}
+	if s := summary[1]; s.GPS != "online" || *s.AgeSeconds != 60 || s.Healthy != nil {
+		t.Fatalf("user 1: %+v", s)
+	}
+	s := summary[2]
+	if s.GPS != "stale" || *s.AgeSeconds != 3600 || s.Healthy == nil || *s.Healthy || s.AppVersion != "1.4.0" {
+		t.Fatalf("user 2: %+v", s)
+	}
+	if len(s.Issues) != 1 || s.Issues[0] != "battery_low" {
+		t.Fatalf("user 2 issues: %v", s.Issues)
+	}
+}
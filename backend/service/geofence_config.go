@@ -1,70 +1,77 @@
 package service
 
-import (
-	"os"
-	"strconv"
-	"strings"
-)
+import "sync/atomic"
 
-const (
-	defaultGeofenceExitBufferMeters  = 40.0
-	defaultGeofenceExitGraceSeconds  = 90
-	defaultGeofenceEnterGraceSeconds = 30
-	defaultGeofenceMinVisitSeconds   = 60
-	defaultGeofenceFarExitMeters     = 300.0
-	defaultGeofenceStaleGapSeconds   = 600
-)
+// Thresholds — пороги геозон, качества точек и привязки к дорогам. Читаются на каждом
+// событии, поэтому SetThresholds меняет их без перезапуска (SIGHUP).
+type Thresholds struct {
+	GeofenceExitBufferMeters  float64
+	GeofenceExitGraceSeconds  int
+	GeofenceEnterGraceSeconds int
+	GeofenceMinVisitSeconds   int
+	GeofenceFarExitMeters     float64
+	GeofenceStaleGapSeconds   int
+	MaxPeriodicAccuracyM      float64
+	MaxOnDemandAccuracyM      float64
+	// RouteMatchChunkSize — точек в одном запросе к OSRM match.
+	RouteMatchChunkSize int
+	// RouteMatchRadiusMeters — радиус поиска дороги на точку; 0 — не передаётся.
+	RouteMatchRadiusMeters int
+}
 
-func geofenceExitBufferMeters() float64 {
-	s := strings.TrimSpace(os.Getenv("GEOFENCE_EXIT_BUFFER_METERS"))
-	if s == "" {
-		return defaultGeofenceExitBufferMeters
-	}
-	v, err := strconv.ParseFloat(s, 64)
-	if err != nil || v < 0 {
-		return defaultGeofenceExitBufferMeters
+// DefaultThresholds — пороги до первого SetThresholds.
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		GeofenceExitBufferMeters:  40,
+		GeofenceExitGraceSeconds:  90,
+		GeofenceEnterGraceSeconds: 30,
+		GeofenceMinVisitSeconds:   60,
+		GeofenceFarExitMeters:     300,
+		GeofenceStaleGapSeconds:   600,
+		MaxPeriodicAccuracyM:      150,
+		MaxOnDemandAccuracyM:      200,
+		RouteMatchChunkSize:       80,
 	}
-	return v
+}
+
+var thresholds atomic.Pointer[Thresholds]
+
+func init() {
+	SetThresholds(DefaultThresholds())
+}
+
+// SetThresholds заменяет пороги для всех последующих событий.
+func SetThresholds(t Thresholds) {
+	thresholds.Store(&t)
+}
+
+// CurrentThresholds — действующие пороги.
+func CurrentThresholds() Thresholds {
+	return *thresholds.Load()
+}
+
+func geofenceExitBufferMeters() float64 {
+	return thresholds.Load().GeofenceExitBufferMeters
 }
 
 func geofenceExitGraceSeconds() int {
-	return geofenceSecondsEnv("GEOFENCE_EXIT_GRACE_SECONDS", defaultGeofenceExitGraceSeconds)
+	return thresholds.Load().GeofenceExitGraceSeconds
 }
 
 func geofenceEnterGraceSeconds() int {
-	return geofenceSecondsEnv("GEOFENCE_ENTER_GRACE_SECONDS", defaultGeofenceEnterGraceSeconds)
+	return thresholds.Load().GeofenceEnterGraceSeconds
 }
 
 func geofenceMinVisitSeconds() int {
-	return geofenceSecondsEnv("GEOFENCE_MIN_VISIT_SECONDS", defaultGeofenceMinVisitSeconds)
+	return thresholds.Load().GeofenceMinVisitSeconds
 }
 
 func geofenceFarExitMeters() float64 {
-	s := strings.TrimSpace(os.Getenv("GEOFENCE_FAR_EXIT_METERS"))
-	if s == "" {
-		return defaultGeofenceFarExitMeters
-	}
-	v, err := strconv.ParseFloat(s, 64)
-	if err != nil || v < 0 {
-		return defaultGeofenceFarExitMeters
-	}
-	return v
+	return thresholds.Load().GeofenceFarExitMeters
 }
 
 func geofenceStaleGapSeconds() int {
-	return geofenceSecondsEnv("GEOFENCE_STALE_GAP_SECONDS", defaultGeofenceStaleGapSeconds)
-}
-
-func geofenceSecondsEnv(key string, fallback int) int {
-	s := strings.TrimSpace(os.Getenv(key))
-	if s == "" {
-		return fallback
-	}
-	v, err := strconv.Atoi(s)
-	if err != nil || v < 0 {
-		return fallback
-	}
-	return v
+	return thresholds.Load().GeofenceStaleGapSeconds
 }
 
 // geofenceInside определяет, считается ли точка внутри зоны с учётом гистерезиса.
@@ -3,7 +3,7 @@ package service
 import (
 	"fmt"
 	"locator/models"
-	"log"
+	"log/slog"
 	"sort"
 	"time"
 )
@@ -123,7 +123,7 @@ func (svc *LocationService) backfillCapturedAtForUser(
 
 	for _, item := range pending {
 		if dryRun {
-			log.Printf("[BackfillCapturedAt] dry-run id=%d captured_at=%s", item.id, item.at.Format(time.RFC3339))
+			slog.Info("Восстановление captured_at (dry-run)", "location_id", item.id, "captured_at", item.at.Format(time.RFC3339))
 			updated++
 			continue
 		}
@@ -135,16 +135,16 @@ func (svc *LocationService) backfillCapturedAtForUser(
 	return updated, bursts, nil
 }
 
-// ResolveCapturedAt — captured_at из строки или timestamp (unix ms) с телефона.
-func (svc *LocationService) ResolveCapturedAt(capturedAtStr string, timestampMs int64) (*time.Time, error) {
+// ResolveCapturedAt — captured_at из строки или timestamp (unix ms) с телефона пользователя userID.
+func (svc *LocationService) ResolveCapturedAt(userID int, capturedAtStr string, timestampMs int64) (*time.Time, error) {
 	if capturedAtStr != "" {
-		return svc.ParseCapturedAt(capturedAtStr)
+		return svc.ParseCapturedAt(userID, capturedAtStr)
 	}
 	if timestampMs <= 0 {
 		return nil, nil
 	}
 	t := time.UnixMilli(timestampMs).UTC()
-	now := time.Now().UTC()
+	now := clockNow(svc.Clock).UTC()
 	if t.After(now.Add(capturedAtMaxFutureSkew)) {
 		return nil, fmt.Errorf("timestamp не может быть в будущем")
 	}
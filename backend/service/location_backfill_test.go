@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"locator/internal/testutil"
 	"locator/models"
 )
 
@@ -58,9 +59,9 @@ func TestResolveCapturedAt_timestampMs(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	svc := &LocationService{minskLocation: loc}
+	svc := &LocationService{location: loc, Clock: testutil.NewClock(time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC))}
 	ms := time.Date(2026, 7, 1, 8, 30, 0, 0, loc).UnixMilli()
-	got, err := svc.ResolveCapturedAt("", ms)
+	got, err := svc.ResolveCapturedAt(1, "", ms)
 	if err != nil {
 		t.Fatal(err)
 	}
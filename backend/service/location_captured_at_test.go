@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"locator/internal/testutil"
 	"locator/models"
 )
 
@@ -12,8 +13,8 @@ func TestParseCapturedAt_rfc3339(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	svc := &LocationService{minskLocation: loc}
-	got, err := svc.ParseCapturedAt("2026-07-01T08:30:00+03:00")
+	svc := &LocationService{location: loc, Clock: testutil.NewClock(time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC))}
+	got, err := svc.ParseCapturedAt(1, "2026-07-01T08:30:00+03:00")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -30,8 +31,8 @@ func TestParseCapturedAt_minskLocal(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	svc := &LocationService{minskLocation: loc}
-	got, err := svc.ParseCapturedAt("2026-07-01T08:30")
+	svc := &LocationService{location: loc, Clock: testutil.NewClock(time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC))}
+	got, err := svc.ParseCapturedAt(1, "2026-07-01T08:30")
 	if err != nil {
 		t.Fatal(err)
 	}
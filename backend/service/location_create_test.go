@@ -1,10 +1,13 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"sort"
 	"testing"
 	"time"
 
+	"locator/internal/metrics"
 	"locator/internal/testutil"
 	"locator/models"
 )
@@ -94,6 +97,19 @@ func (f *fakeLocationRepo) GetLocationsBetween(from, to time.Time) ([]models.Loc
 	return out, nil
 }
 
+// GetLocationsByUserBetween — как LocationDAO: без маскированных, по времени фиксации.
+func (f *fakeLocationRepo) GetLocationsByUserBetween(userID int, from, to time.Time) ([]models.Location, error) {
+	var out []models.Location
+	for _, loc := range f.byUser[userID] {
+		at := loc.EffectiveAt()
+		if !loc.Masked && !at.Before(from) && !at.After(to) {
+			out = append(out, loc)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].EffectiveAt().Before(out[j].EffectiveAt()) })
+	return out, nil
+}
+
 func (f *fakeLocationRepo) ListUserIDsWithoutCapturedAt() ([]int, error) {
 	return nil, nil
 }
@@ -108,7 +124,7 @@ func (f *fakeLocationRepo) UpdateCapturedAt(id int, capturedAt time.Time) error
 
 func newTestLocationService(repo *fakeLocationRepo) *LocationService {
 	loc, _ := time.LoadLocation("Europe/Minsk")
-	return &LocationService{DAO: repo, minskLocation: loc}
+	return &LocationService{DAO: repo, location: loc}
 }
 
 func TestCreateLocation_periodicAlwaysPersists(t *testing.T) {
@@ -119,7 +135,7 @@ func TestCreateLocation_periodicAlwaysPersists(t *testing.T) {
 
 	// Far jump would be outlier for non-periodic, but periodic must persist.
 	got, reason, err := svc.CreateLocation(
-		1, 53.92684, 27.69516, "", models.LocationSourcePeriodic, nil, nil,
+		context.Background(), 1, 53.92684, 27.69516, "", models.LocationSourcePeriodic, nil, nil,
 	)
 	if err != nil {
 		t.Fatal(err)
@@ -140,8 +156,9 @@ func TestCreateLocation_skipsGpsOutlier(t *testing.T) {
 
 	jumpAt := homeAt.Add(2 * time.Second) // within batch window → jump > 250m is outlier
 	acc := 10.0
+	skipped := metrics.LocationsIngested.Value(models.LocationSourceOnDemand, "skipped", "gps_outlier")
 	got, reason, err := svc.CreateLocation(
-		1, 53.92684, 27.69516, "", models.LocationSourceOnDemand, &jumpAt, &acc,
+		context.Background(), 1, 53.92684, 27.69516, "", models.LocationSourceOnDemand, &jumpAt, &acc,
 	)
 	if err != nil {
 		t.Fatal(err)
@@ -149,6 +166,9 @@ func TestCreateLocation_skipsGpsOutlier(t *testing.T) {
 	if got != nil || reason != "gps_outlier" {
 		t.Fatalf("got=%v reason=%q", got, reason)
 	}
+	if v := metrics.LocationsIngested.Value(models.LocationSourceOnDemand, "skipped", "gps_outlier"); v != skipped+1 {
+		t.Fatalf("skipped counter %v, want %v", v, skipped+1)
+	}
 }
 
 func TestCreateLocation_persistsNearbyPoint(t *testing.T) {
@@ -159,7 +179,7 @@ func TestCreateLocation_persistsNearbyPoint(t *testing.T) {
 
 	acc := 15.0
 	got, reason, err := svc.CreateLocation(
-		1, 53.9005, 27.5005, "", models.LocationSourceOnDemand, nil, &acc,
+		context.Background(), 1, 53.9005, 27.5005, "", models.LocationSourceOnDemand, nil, &acc,
 	)
 	if err != nil {
 		t.Fatal(err)
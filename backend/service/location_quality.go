@@ -1,38 +1,20 @@
 package service
 
 import (
-	"os"
-	"strconv"
-	"strings"
-
 	"locator/models"
 )
 
 const (
-	defaultMaxPeriodicAccuracyM = 150.0
-	defaultMaxOnDemandAccuracyM = 200.0
 	defaultStationaryPoorAccM     = 50.0
 	defaultStationaryRadiusM      = 25.0
 )
 
 func maxPeriodicAccuracyM() float64 {
-	return envFloat("LOCATION_MAX_PERIODIC_ACCURACY_M", defaultMaxPeriodicAccuracyM)
+	return thresholds.Load().MaxPeriodicAccuracyM
 }
 
 func maxOnDemandAccuracyM() float64 {
-	return envFloat("LOCATION_MAX_ON_DEMAND_ACCURACY_M", defaultMaxOnDemandAccuracyM)
-}
-
-func envFloat(key string, fallback float64) float64 {
-	s := strings.TrimSpace(os.Getenv(key))
-	if s == "" {
-		return fallback
-	}
-	v, err := strconv.ParseFloat(s, 64)
-	if err != nil || v <= 0 {
-		return fallback
-	}
-	return v
+	return thresholds.Load().MaxOnDemandAccuracyM
 }
 
 // ShouldSkipPoorLocation отбрасывает periodic/on_demand с плохой точностью и «залипшие» координаты.
@@ -2,7 +2,6 @@ package service
 
 import (
 	"errors"
-	"locator/dao"
 	"locator/models"
 	"time"
 
@@ -20,15 +19,17 @@ const locationRequestPendingTTL = 15 * time.Minute
 
 // LocationRequestService — on-demand запросы координат с устройства.
 type LocationRequestService struct {
-	DAO *dao.LocationRequestDAO
+	DAO locationRequestRepository
+	// Clock — текущее время: срок ожидания запроса и отметка выполнения (nil — системное).
+	Clock Clock
 }
 
-func NewLocationRequestService(dao *dao.LocationRequestDAO) *LocationRequestService {
+func NewLocationRequestService(dao locationRequestRepository) *LocationRequestService {
 	return &LocationRequestService{DAO: dao}
 }
 
 func (svc *LocationRequestService) expireStale() error {
-	cutoff := time.Now().Add(-locationRequestPendingTTL)
+	cutoff := clockNow(svc.Clock).Add(-locationRequestPendingTTL)
 	return svc.DAO.ExpirePendingOlderThan(cutoff)
 }
 
@@ -42,9 +43,10 @@ func (svc *LocationRequestService) CreatePending(userID int) (*models.LocationRe
 	}
 
 	req := &models.LocationRequest{
-		ID:     id,
-		UserID: userID,
-		Status: models.LocationRequestStatusPending,
+		ID:        id,
+		UserID:    userID,
+		Status:    models.LocationRequestStatusPending,
+		CreatedAt: clockNow(svc.Clock),
 	}
 	if err := svc.DAO.Create(req); err != nil {
 		return nil, err
@@ -90,6 +92,6 @@ func (svc *LocationRequestService) Complete(requestID string, userID int) error
 	if req.Status != models.LocationRequestStatusPending {
 		return ErrLocationRequestNotPending
 	}
-	now := time.Now()
+	now := clockNow(svc.Clock)
 	return svc.DAO.UpdateStatus(requestID, models.LocationRequestStatusCompleted, &now)
 }
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"locator/internal/testutil"
+	"locator/internal/testutil/memrepo"
+	"locator/models"
+)
+
+func TestLocationRequestPollPending_expiresAfterTTL(t *testing.T) {
+	clock := testutil.NewClock(testutil.FixedUTC(2026, 7, 1, 9, 0, 0))
+	store := memrepo.New(clock)
+	svc := NewLocationRequestService(store.LocationRequests)
+	svc.Clock = clock
+
+	first, err := svc.CreatePending(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.CreatePending(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req, _ := svc.GetByID(first.ID); req.Status != models.LocationRequestStatusExpired {
+		t.Fatalf("previous request status=%s, want expired", req.Status)
+	}
+
+	clock.Advance(locationRequestPendingTTL)
+	if req, err := svc.PollPending(1); err != nil || req == nil || req.ID != second.ID {
+		t.Fatalf("at TTL: got %+v err=%v", req, err)
+	}
+	clock.Advance(time.Second)
+	if req, err := svc.PollPending(1); err != nil || req != nil {
+		t.Fatalf("after TTL: got %+v err=%v", req, err)
+	}
+	if err := svc.Complete(second.ID, 1); !errors.Is(err, ErrLocationRequestNotPending) {
+		t.Fatalf("complete expired: err=%v", err)
+	}
+}
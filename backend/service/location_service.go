@@ -1,48 +1,68 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"locator/internal/metrics"
 	"locator/models"
-	"log"
+	"log/slog"
 	"math"
+	"os"
 	"sort"
 	"strings"
 	"time"
 )
 
+// trackingPrivacyPolicy — можно ли принять точку, снятую в момент at (TrackingScheduleService).
+type trackingPrivacyPolicy interface {
+	CheckPoint(userID int, at time.Time) (allowed bool, action string, err error)
+}
+
 // LocationService отвечает за бизнес-логику, связанную с операциями над местоположениями.
 type LocationService struct {
-	DAO           locationRepository
-	minskLocation *time.Location // Временная зона Минска (UTC+3)
+	DAO      locationRepository
+	location *time.Location // Пояс по умолчанию (models.DefaultTimezone)
+	// Privacy — окна отслеживания пользователя (nil — точки принимаются всегда).
+	Privacy trackingPrivacyPolicy
+	// Timezones — пояс пользователя для captured_at без смещения (nil — пояс по умолчанию).
+	Timezones userTimezoneSource
+	// Clock — текущее время (nil — системное).
+	Clock Clock
 }
 
-// NewLocationService создаёт новый экземпляр сервиса и загружает временную зону для Минска.
+// NewLocationService создаёт новый экземпляр сервиса и загружает пояс по умолчанию.
 func NewLocationService(dao locationRepository) *LocationService {
-	loc, err := time.LoadLocation("Europe/Minsk")
+	loc, err := LoadTimezone("")
 	if err != nil {
-		log.Fatalf("Ошибка загрузки временной зоны Europe/Minsk: %v", err)
+		slog.Error("Ошибка загрузки временной зоны", "timezone", models.DefaultTimezone, "error", err)
+		os.Exit(1)
 	}
 	return &LocationService{
-		DAO:           dao,
-		minskLocation: loc,
+		DAO:      dao,
+		location: loc,
 	}
 }
 
-// toMinskTime конвертирует время в форматированную строку по Минску.
-func (svc *LocationService) toMinskTime(t time.Time) string {
-	return t.In(svc.minskLocation).Format("2006-01-02 15:04:05")
+// formatLogTime — время для журнала с явным смещением (RFC3339).
+func formatLogTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// userLocation — пояс, в котором толкуется локальное время пользователя userID.
+func (svc *LocationService) userLocation(userID int) *time.Location {
+	if svc.Timezones != nil {
+		return svc.Timezones.UserLocation(userID)
+	}
+	return orDefaultTimezone(svc.location)
 }
 
 // GetLocation получает данные о местоположении для заданного пользователя.
 func (svc *LocationService) GetLocation(userID int) (*models.Location, error) {
-	log.Printf("[GetLocation] Запрос на получение местоположения для userID=%d", userID)
 	location, err := svc.DAO.GetByUserID(userID)
 	if err != nil {
-		log.Printf("[GetLocation] Ошибка при получении местоположения для userID=%d: %v", userID, err)
+		slog.Debug("Местоположение не получено", "user_id", userID, "error", err)
 		return nil, err
 	}
-	log.Printf("[GetLocation] Запись о местоположении получена для userID=%d: Latitude=%.6f, Longitude=%.6f, CreatedAt=%s",
-		userID, location.Latitude, location.Longitude, svc.toMinskTime(location.CreatedAt))
 	return location, nil
 }
 
@@ -53,31 +73,83 @@ func (svc *LocationService) UserExists(userID int) (bool, error) {
 
 // CreateLocation создаёт новую запись о местоположении без обновления существующей.
 // capturedAt — момент фиксации на устройстве (офлайн-очередь); nil — только created_at сервера.
-// skipReason непустой — точка отброшена (выброс, плохая точность, устаревший fix).
+// skipReason непустой — точка отброшена (выброс, плохая точность, устаревший fix,
+// вне окна отслеживания). Маскированная точка (Masked) сохраняется без проверок трека.
 func (svc *LocationService) CreateLocation(
-	userID int, lat, lon float64, requestID, source string, capturedAt *time.Time, accuracy *float64,
+	ctx context.Context, userID int, lat, lon float64, requestID, source string, capturedAt *time.Time, accuracy *float64,
 ) (*models.Location, string, error) {
-	log.Printf("[CreateLocation] Создание записи: userID=%d, lat=%.6f, lon=%.6f, source=%s, captured_at=%v, accuracy=%v",
-		userID, lat, lon, source, capturedAt, accuracy)
+	location, skipReason, err := svc.createLocation(ctx, userID, lat, lon, requestID, source, capturedAt, accuracy)
+	sourceLabel := source
+	if source != models.LocationSourcePeriodic && source != models.LocationSourceOnDemand {
+		sourceLabel = "other"
+	}
+	switch {
+	case err != nil:
+		metrics.LocationsIngested.Inc(sourceLabel, "error", "")
+	case skipReason != "":
+		metrics.LocationsIngested.Inc(sourceLabel, "skipped", skipReason)
+	case location.Masked:
+		metrics.LocationsIngested.Inc(sourceLabel, "masked", "")
+	default:
+		metrics.LocationsIngested.Inc(sourceLabel, "saved", "")
+	}
+	return location, skipReason, err
+}
+
+func (svc *LocationService) createLocation(
+	ctx context.Context, userID int, lat, lon float64, requestID, source string, capturedAt *time.Time, accuracy *float64,
+) (*models.Location, string, error) {
+	logger := slog.With("user_id", userID, "lat", lat, "lon", lon, "source", source)
+	logger.DebugContext(ctx, "Приём точки", "captured_at", capturedAt, "accuracy", accuracy)
 	newLocation := models.NewLocation(userID, lat, lon)
+	newLocation.CreatedAt = clockNow(svc.Clock)
+	newLocation.UpdatedAt = newLocation.CreatedAt
 	newLocation.RequestID = requestID
 	newLocation.Source = source
 	if capturedAt != nil {
 		t := capturedAt.UTC()
 		newLocation.CapturedAt = &t
 	}
-	newLocation.NormalizeIngressCapturedAt()
-
-	effectiveAt := newLocation.EffectiveAt()
 	isPeriodic := requestID == "" && source == models.LocationSourcePeriodic
+	// Устаревший captured_at подменяется только у точек, проходящих фильтр выбросов.
+	// Periodic из офлайн-очереди сохраняет время фиксации — иначе визит закрывается в
+	// момент сброса очереди, а не ухода; порядок трека держит TrackSortAt.
+	staleFix := newLocation.HasStaleCapturedAt()
+	if !isPeriodic {
+		newLocation.NormalizeIngressCapturedAt()
+	}
+	effectiveAt := newLocation.EffectiveAt()
+
+	// Окно отслеживания проверяется по времени фиксации: точка из офлайн-очереди,
+	// снятая в нерабочее время, не сохраняется и позже.
+	if svc.Privacy != nil {
+		allowed, action, err := svc.Privacy.CheckPoint(userID, effectiveAt)
+		if err != nil {
+			// Без проверки точку нельзя ни сохранить как есть, ни отбросить навсегда.
+			logger.ErrorContext(ctx, "Ошибка проверки окна отслеживания, точка отклонена", "error", err)
+			return nil, "", fmt.Errorf("%w: %v", ErrTrackingCheckFailed, err)
+		}
+		if !allowed {
+			if action != models.TrackingOutsideMask {
+				logger.InfoContext(ctx, "Точка пропущена", "reason", trackingPrivacyReason)
+				return nil, trackingPrivacyReason, nil
+			}
+			maskLocation(newLocation)
+			if err := svc.DAO.Create(newLocation); err != nil {
+				logger.ErrorContext(ctx, "Ошибка сохранения точки", "error", err)
+				return nil, "", err
+			}
+			logger.InfoContext(ctx, "Точка вне окна отслеживания сохранена маскированной", "location_id", newLocation.ID)
+			return newLocation, "", nil
+		}
+	}
 
 	// On-demand с request_id сохраняем, если это явный ответ на запрос; но отбрасываем
 	// устаревший GPS-fix после офлайна, который телепортирует трек.
 	if requestID != "" {
 		prev, _ := svc.DAO.GetPreviousByEffectiveTime(userID, newLocation.CreatedAt.UTC())
-		if prev != nil && newLocation.HasStaleCapturedAt() && IsTrackOutlierFromPrev(*prev, *newLocation) {
-			log.Printf("[CreateLocation] Пропуск устаревшего on_demand для userID=%d: %.6f,%.6f",
-				userID, lat, lon)
+		if prev != nil && staleFix && IsTrackOutlierFromPrev(*prev, *newLocation) {
+			logger.InfoContext(ctx, "Точка пропущена", "reason", "stale_gps_outlier", "request", requestID)
 			return nil, "stale_gps_outlier", nil
 		}
 	}
@@ -86,7 +158,7 @@ func (svc *LocationService) CreateLocation(
 	if !isPeriodic && requestID == "" {
 		prev, _ := svc.DAO.GetPreviousByEffectiveTime(userID, effectiveAt)
 		if skip, reason := ShouldSkipPoorLocation(source, accuracy, prev, lat, lon); skip {
-			log.Printf("[CreateLocation] Пропуск %s для userID=%d: %.6f,%.6f", reason, userID, lat, lon)
+			logger.InfoContext(ctx, "Точка пропущена", "reason", reason)
 			return nil, reason, nil
 		}
 		baseline := svc.outlierBaseline(userID, effectiveAt)
@@ -95,25 +167,24 @@ func (svc *LocationService) CreateLocation(
 				baseline.Latitude, baseline.Longitude,
 				newLocation.Latitude, newLocation.Longitude,
 			) > trackBatchMaxJumpM {
-				log.Printf("[CreateLocation] Пропуск выброса GPS для userID=%d: %.6f,%.6f",
-					userID, lat, lon)
+				logger.InfoContext(ctx, "Точка пропущена", "reason", "gps_outlier")
 				return nil, "gps_outlier", nil
 			}
-			log.Printf("[CreateLocation] Точка userID=%d принята как возврат к надёжной позиции", userID)
+			logger.DebugContext(ctx, "Точка принята как возврат к надёжной позиции")
 		} else if baseline != nil && IsTrackOutlierFromPrev(*baseline, *newLocation) {
-			log.Printf("[CreateLocation] Пропуск выброса GPS для userID=%d: %.6f,%.6f (база %.6f,%.6f)",
-				userID, lat, lon, baseline.Latitude, baseline.Longitude)
+			logger.InfoContext(ctx, "Точка пропущена", "reason", "gps_outlier",
+				"baseline_lat", baseline.Latitude, "baseline_lon", baseline.Longitude)
 			return nil, "gps_outlier", nil
 		}
 	}
 
 	if err := svc.DAO.Create(newLocation); err != nil {
-		log.Printf("[CreateLocation] Ошибка при создании записи для userID=%d: %v", userID, err)
+		logger.ErrorContext(ctx, "Ошибка сохранения точки", "error", err)
 		return nil, "", err
 	}
 
-	log.Printf("[CreateLocation] Запись создана userID=%d: effective=%s, received=%s",
-		userID, svc.toMinskTime(effectiveAt), svc.toMinskTime(newLocation.CreatedAt))
+	logger.InfoContext(ctx, "Точка сохранена", "location_id", newLocation.ID,
+		"effective_at", formatLogTime(effectiveAt), "received_at", formatLogTime(newLocation.CreatedAt))
 	return newLocation, "", nil
 }
 
@@ -122,18 +193,18 @@ const (
 	capturedAtMaxAge        = 90 * 24 * time.Hour
 )
 
-// ParseCapturedAt парсит RFC3339 или локальное время Минска (как в query периода).
-func (svc *LocationService) ParseCapturedAt(s string) (*time.Time, error) {
+// ParseCapturedAt парсит RFC3339 или локальное время пользователя userID (как в query периода).
+func (svc *LocationService) ParseCapturedAt(userID int, s string) (*time.Time, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		return nil, nil
 	}
-	t, err := svc.parseLocationQueryTime(s)
+	t, err := parseLocalQueryTime(s, svc.userLocation(userID))
 	if err != nil {
 		return nil, fmt.Errorf("captured_at: %w", err)
 	}
 	t = t.UTC()
-	now := time.Now().UTC()
+	now := clockNow(svc.Clock).UTC()
 	if t.After(now.Add(capturedAtMaxFutureSkew)) {
 		return nil, fmt.Errorf("captured_at не может быть в будущем")
 	}
@@ -170,65 +241,31 @@ func (svc *LocationService) GetLocations() ([]models.Location, error) {
 
 // GetLocationsWithoutCache возвращает значимые локации без использования кэширования.
 func (svc *LocationService) GetLocationsWithoutCache() ([]models.Location, error) {
-	log.Printf("[GetLocations] Запрос на получение значимых локаций без кэширования")
-
 	// Получаем все локации из БД.
 	allLocations, err := svc.DAO.GetAll()
 	if err != nil {
-		log.Printf("[GetLocations] Ошибка при получении записей: %v", err)
+		slog.Error("Ошибка получения локаций", "error", err)
 		return nil, err
 	}
 
-	log.Printf("[GetLocations] Получено %d записей из БД, начинаем фильтрацию", len(allLocations))
-
 	// Фильтруем и возвращаем только значимые точки.
 	significantLocations := svc.filterSignificantLocations(allLocations)
-	log.Printf("[GetLocations] Отфильтровано %d значимых локаций из %d общих",
-		len(significantLocations), len(allLocations))
+	slog.Debug("Отфильтрованы значимые локации", "significant", len(significantLocations), "total", len(allLocations))
 	return significantLocations, nil
 }
 
-// parseLocationQueryTime парсит строку из query (RFC3339 или локальное время Минска YYYY-MM-DDTHH:mm).
-func (svc *LocationService) parseLocationQueryTime(s string) (time.Time, error) {
-	if strings.ContainsAny(s, "Z+") {
-		return time.Parse(time.RFC3339, s)
+// parseLocationRange парсит пару границ интервала (локальное время в loc или RFC3339).
+func (svc *LocationService) parseLocationRange(fromStr, toStr string, loc *time.Location) (time.Time, time.Time, error) {
+	if loc == nil {
+		loc = svc.location
 	}
-	return time.ParseInLocation("2006-01-02T15:04", s, svc.minskLocation)
-}
-
-// parseLocationRange парсит пару границ интервала (Europe/Minsk или RFC3339).
-func (svc *LocationService) parseLocationRange(fromStr, toStr string) (time.Time, time.Time, error) {
-	from, err := svc.parseLocationQueryTime(fromStr)
-	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %v", err)
-	}
-	to, err := svc.parseLocationQueryTime(toStr)
-	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %v", err)
-	}
-	to = svc.normalizeLocationRangeEnd(to, toStr)
-	if from.After(to) {
-		return time.Time{}, time.Time{}, fmt.Errorf("начало интервала не может быть позже окончания")
-	}
-	return from, to, nil
-}
-
-// normalizeLocationRangeEnd включает всю минуту, если в строке задано только HH:mm.
-func (svc *LocationService) normalizeLocationRangeEnd(to time.Time, toStr string) time.Time {
-	if !strings.Contains(toStr, "T") {
-		return to
-	}
-	timePart := strings.SplitN(toStr, "T", 2)[1]
-	if strings.Count(timePart, ":") == 1 {
-		return to.Add(59*time.Second + 999*time.Millisecond)
-	}
-	return to
+	return parseLocalRange(fromStr, toStr, loc)
 }
 
 // locationRangeForDB переводит границы интервала в UTC для сравнения с created_at в БД
 // (TIMESTAMP WITHOUT TIME ZONE, фактически UTC с сервера приложения).
-func (svc *LocationService) locationRangeForDB(fromStr, toStr string) (time.Time, time.Time, error) {
-	from, to, err := svc.parseLocationRange(fromStr, toStr)
+func (svc *LocationService) locationRangeForDB(fromStr, toStr string, loc *time.Location) (time.Time, time.Time, error) {
+	from, to, err := svc.parseLocationRange(fromStr, toStr, loc)
 	if err != nil {
 		return time.Time{}, time.Time{}, err
 	}
@@ -253,8 +290,9 @@ func (svc *LocationService) GetLocationsRaw() ([]models.Location, error) {
 }
 
 // GetLocationsBetweenRaw возвращает локации за период без фильтрации, отсортированные по времени.
-func (svc *LocationService) GetLocationsBetweenRaw(fromStr, toStr string) ([]models.Location, error) {
-	from, to, err := svc.locationRangeForDB(fromStr, toStr)
+// Локальные границы толкуются в поясе loc, времена в ответе — в нём же.
+func (svc *LocationService) GetLocationsBetweenRaw(fromStr, toStr string, loc *time.Location) ([]models.Location, error) {
+	from, to, err := svc.locationRangeForDB(fromStr, toStr, loc)
 	if err != nil {
 		return nil, err
 	}
@@ -263,12 +301,13 @@ func (svc *LocationService) GetLocationsBetweenRaw(fromStr, toStr string) ([]mod
 		return nil, err
 	}
 	sortLocationsByEffectiveAt(all)
+	localizeLocations(all, loc)
 	return all, nil
 }
 
-// GetLocationsBetween возвращает значимые локации за указанный период.
-func (svc *LocationService) GetLocationsBetween(fromStr, toStr string) ([]models.Location, error) {
-	from, to, err := svc.locationRangeForDB(fromStr, toStr)
+// GetLocationsBetween возвращает значимые локации за указанный период (пояс loc — как у Raw).
+func (svc *LocationService) GetLocationsBetween(fromStr, toStr string, loc *time.Location) ([]models.Location, error) {
+	from, to, err := svc.locationRangeForDB(fromStr, toStr, loc)
 	if err != nil {
 		return nil, err
 	}
@@ -277,7 +316,16 @@ func (svc *LocationService) GetLocationsBetween(fromStr, toStr string) ([]models
 	if err != nil {
 		return nil, err
 	}
-	return svc.filterSignificantLocations(all), nil
+	significant := svc.filterSignificantLocations(all)
+	localizeLocations(significant, loc)
+	return significant, nil
+}
+
+// localizeLocations переводит времена точек в пояс loc (nil — без изменений).
+func localizeLocations(locations []models.Location, loc *time.Location) {
+	for i := range locations {
+		locations[i].In(loc)
+	}
 }
 
 // filterSignificantLocations фильтрует только значимые локации из всех.
@@ -314,8 +362,7 @@ func (svc *LocationService) filterSignificantLocations(allLocations []models.Loc
 			representativePoints := svc.getRepresentativePoints(locations)
 			significantLocations = append(significantLocations, representativePoints...)
 
-			log.Printf("[filterSignificantLocations] Пользователь %d не имеет кластеров, добавлено %d репрезентативных точек",
-				userID, len(representativePoints))
+			slog.Debug("Нет кластеров, добавлены репрезентативные точки", "user_id", userID, "points", len(representativePoints))
 		}
 	}
 
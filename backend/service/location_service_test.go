@@ -22,8 +22,8 @@ func TestParseLocationRange_minskWallClock(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	svc := &LocationService{minskLocation: loc}
-	from, to, err := svc.parseLocationRange("2026-04-11T10:00", "2026-04-11T11:00")
+	svc := &LocationService{location: loc}
+	from, to, err := svc.parseLocationRange("2026-04-11T10:00", "2026-04-11T11:00", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -37,8 +37,8 @@ func TestLocationRangeForDB_minskEveningInUTC(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	svc := &LocationService{minskLocation: loc}
-	from, to, err := svc.locationRangeForDB("2026-05-15T22:00", "2026-05-15T23:59")
+	svc := &LocationService{location: loc}
+	from, to, err := svc.locationRangeForDB("2026-05-15T22:00", "2026-05-15T23:59", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -60,7 +60,7 @@ func TestFilterSignificantLocations_clusterWithin100m(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	svc := &LocationService{minskLocation: loc}
+	svc := &LocationService{location: loc}
 	base := time.Date(2026, 4, 11, 10, 0, 0, 0, loc)
 	locs := []models.Location{
 		{UserID: 1, Latitude: 53.9, Longitude: 27.57, CreatedAt: base},
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"locator/models"
+)
+
+// NotificationMessage — отрендеренное уведомление, готовое к отправке в канал.
+type NotificationMessage struct {
+	Event         string            `json:"event"`
+	Severity      string            `json:"severity,omitempty"`
+	SubjectUserID int               `json:"user_id"`
+	Subject       string            `json:"subject"`
+	Text          string            `json:"text"`
+	Params        map[string]string `json:"params,omitempty"`
+	At            time.Time         `json:"at"`
+}
+
+// NotificationChannel — способ доставки (email, Telegram, вебхук).
+type NotificationChannel interface {
+	Name() string
+	Send(ctx context.Context, target string, msg NotificationMessage) error
+}
+
+// SMTPChannel — email через SMTP (без TLS-обёртки; STARTTLS, если сервер предлагает).
+type SMTPChannel struct {
+	Addr     string // host:port
+	From     string
+	Username string
+	Password string
+}
+
+func (ch *SMTPChannel) Name() string { return models.NotificationChannelEmail }
+
+func (ch *SMTPChannel) Send(ctx context.Context, target string, msg NotificationMessage) error {
+	var auth smtp.Auth
+	if ch.Username != "" {
+		host := ch.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", ch.Username, ch.Password, host)
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", ch.From)
+	fmt.Fprintf(&body, "To: %s\r\n", target)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	body.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Text, "\n", "\r\n"))
+	body.WriteString("\r\n")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(ch.Addr, auth, ch.From, []string{target}, body.Bytes())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TelegramChannel — Bot API sendMessage; target — chat_id.
+type TelegramChannel struct {
+	BaseURL string // по умолчанию https://api.telegram.org
+	Token   string
+	HTTP    *http.Client
+}
+
+func (ch *TelegramChannel) Name() string { return models.NotificationChannelTelegram }
+
+func (ch *TelegramChannel) Send(ctx context.Context, target string, msg NotificationMessage) error {
+	base := strings.TrimRight(ch.BaseURL, "/")
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	payload := map[string]interface{}{
+		"chat_id":                  target,
+		"text":                     msg.Subject + "\n\n" + msg.Text,
+		"disable_web_page_preview": true,
+	}
+	var resp struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	status, err := postNotificationJSON(ctx, ch.HTTP, base+"/bot"+ch.Token+"/sendMessage", payload, &resp)
+	if err != nil {
+		if ch.Token != "" {
+			return errors.New(strings.ReplaceAll(err.Error(), ch.Token, "***"))
+		}
+		return err
+	}
+	if status != http.StatusOK || !resp.OK {
+		return fmt.Errorf("telegram: HTTP %d: %s", status, resp.Description)
+	}
+	return nil
+}
+
+// errWebhookPrivateAddress — вебхук указывает во внутреннюю сеть (защита от SSRF).
+var errWebhookPrivateAddress = errors.New("адрес вебхука во внутренней сети")
+
+// WebhookChannel — произвольный JSON POST на URL подписки.
+// Без AllowPrivateNetworks адреса loopback, частных и link-local сетей отклоняются
+// и при создании подписки (CheckTarget), и при каждом соединении — в том числе
+// после редиректа или смены DNS-записи.
+type WebhookChannel struct {
+	HTTP                 *http.Client
+	AllowPrivateNetworks bool
+}
+
+func (ch *WebhookChannel) Name() string { return models.NotificationChannelWebhook }
+
+// CheckTarget разрешает имя хоста URL и отклоняет внутренние адреса.
+func (ch *WebhookChannel) CheckTarget(ctx context.Context, target string) error {
+	if ch.AllowPrivateNetworks {
+		return nil
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("webhook: некорректный URL")
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook: не удалось разрешить %s: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return errWebhookPrivateAddress
+		}
+	}
+	return nil
+}
+
+func (ch *WebhookChannel) Send(ctx context.Context, target string, msg NotificationMessage) error {
+	client := ch.HTTP
+	if !ch.AllowPrivateNetworks {
+		if err := ch.CheckTarget(ctx, target); err != nil {
+			return err
+		}
+		if client == nil {
+			client = publicOnlyHTTPClient()
+		}
+	}
+	status, err := postNotificationJSON(ctx, client, target, msg, nil)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("webhook: HTTP %d", status)
+	}
+	return nil
+}
+
+// publicOnlyHTTPClient — клиент, который соединяется только с публичными адресами:
+// проверка в Dialer.Control видит уже разрешённый IP. Прокси из окружения не
+// используется — иначе проверялся бы адрес прокси.
+func publicOnlyHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return errWebhookPrivateAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     30 * time.Second,
+		},
+	}
+}
+
+// carrierGradeNAT — 100.64.0.0/10 (RFC 6598), не покрыт net.IP.IsPrivate.
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP — адрес маршрутизируется в интернет: не loopback, не частная,
+// не link-local (в т.ч. 169.254.169.254 метаданных облака) и не multicast.
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if ip4[0] == 0 || carrierGradeNAT.Contains(ip4) {
+			return false
+		}
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+func postNotificationJSON(ctx context.Context, client *http.Client, target string, payload interface{}, out interface{}) (int, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, errors.New("некорректный URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, redactURLError(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if out != nil && len(data) > 0 {
+		_ = json.Unmarshal(data, out)
+	}
+	return resp.StatusCode, nil
+}
+
+// redactURLError убирает из ошибки транспорта полный URL: в пути может быть
+// секрет (токен бота Telegram), а текст ошибки пишется в лог и в журнал доставки,
+// который видят пользователи. Остаются схема и хост.
+func redactURLError(err error) error {
+	var uerr *url.Error
+	if !errors.As(err, &uerr) {
+		return err
+	}
+	where := "URL"
+	if u, perr := url.Parse(uerr.URL); perr == nil && u.Host != "" {
+		where = u.Scheme + "://" + u.Host
+	}
+	return fmt.Errorf("%s %s: %w", uerr.Op, where, uerr.Err)
+}
@@ -0,0 +1,529 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"locator/models"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotificationSubscriptionNotFound = errors.New("notification subscription not found")
+	ErrNotificationSubscriptionInvalid  = errors.New("invalid notification subscription")
+	ErrNotificationForbidden            = errors.New("notification subscription belongs to another user")
+)
+
+const (
+	notificationDefaultRatePerHour = 20
+	notificationSendTimeout        = 15 * time.Second
+	notificationDeliveriesLimit    = 200
+)
+
+// Notification — событие для доставки людям (алерт, визит).
+type Notification struct {
+	Event    string
+	Severity string // warning, critical; пусто — информационное событие
+	UserID   int    // о каком устройстве/пользователе событие
+	Params   map[string]string
+	At       time.Time
+}
+
+// eventNotifier — приёмник событий для AlertService и VisitEventProcessor.
+type eventNotifier interface {
+	Notify(n Notification)
+}
+
+// NotificationService — подписки и доставка уведомлений по каналам
+// с тихими часами и ограничением частоты на подписку.
+type NotificationService struct {
+	DAO              notificationRepository
+	Users            userRepository
+	RateLimitPerHour int
+
+	// Timezones — пояс владельца подписки для тихих часов и времени в тексте (nil — location).
+	Timezones userTimezoneSource
+	// Clock — текущее время (nil — системное).
+	Clock Clock
+
+	channels map[string]NotificationChannel
+	location *time.Location
+
+	mu   sync.Mutex
+	sent map[int][]time.Time // subscription → время недавних отправок
+}
+
+func NewNotificationService(dao notificationRepository, users userRepository, channels ...NotificationChannel) *NotificationService {
+	svc := &NotificationService{
+		DAO:              dao,
+		Users:            users,
+		RateLimitPerHour: notificationDefaultRatePerHour,
+		channels:         make(map[string]NotificationChannel),
+		location:         defaultTimezone(),
+		sent:             make(map[int][]time.Time),
+	}
+	for _, ch := range channels {
+		if ch != nil {
+			svc.channels[ch.Name()] = ch
+		}
+	}
+	return svc
+}
+
+// Notify ставит событие на асинхронную доставку (не блокирует вызывающего).
+func (svc *NotificationService) Notify(n Notification) {
+	if svc == nil {
+		return
+	}
+	if n.At.IsZero() {
+		n.At = clockNow(svc.Clock)
+	}
+	go func() {
+		if _, err := svc.Dispatch(context.Background(), n); err != nil {
+			slog.Error("Ошибка доставки уведомления", "event", n.Event, "user_id", n.UserID, "error", err)
+		}
+	}()
+}
+
+// Dispatch синхронно доставляет событие всем подходящим подпискам и пишет журнал.
+func (svc *NotificationService) Dispatch(ctx context.Context, n Notification) ([]models.NotificationDelivery, error) {
+	subs, err := svc.DAO.GetEnabledSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	users, err := svc.Users.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	var deliveries []models.NotificationDelivery
+	for i := range subs {
+		sub := &subs[i]
+		owner, ok := byID[sub.UserID]
+		if !ok || !subscriptionMatches(sub, n) {
+			continue
+		}
+		if subject, known := byID[n.UserID]; !notificationVisible(&owner, &subject, known) {
+			continue
+		}
+		d := svc.deliver(ctx, sub, n, byID[n.UserID].Name, false)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// SendTest отправляет тестовое сообщение в подписку (без тихих часов и лимита).
+func (svc *NotificationService) SendTest(ctx context.Context, subID int, actor *models.User, scope *UserScope) (*models.NotificationDelivery, error) {
+	sub, err := svc.getOwnedSubscription(subID, actor, scope)
+	if err != nil {
+		return nil, err
+	}
+	n := Notification{Event: models.NotificationEventTest, UserID: sub.UserID, At: clockNow(svc.Clock)}
+	d := svc.deliver(ctx, sub, n, actor.Name, true)
+	return &d, nil
+}
+
+func (svc *NotificationService) deliver(ctx context.Context, sub *models.NotificationSubscription, n Notification, userName string, force bool) models.NotificationDelivery {
+	d := models.NotificationDelivery{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		Channel:        sub.Channel,
+		Event:          n.Event,
+		SubjectUserID:  n.UserID,
+	}
+
+	loc := svc.subscriberLocation(sub)
+	switch {
+	case !force && n.Severity != models.AlertSeverityCritical && inQuietHours(sub, n.At, loc):
+		d.Status = models.NotificationDeliveryQuietHours
+	case !force && !svc.allowSend(sub.ID, n.At):
+		d.Status = models.NotificationDeliveryRateLimited
+	default:
+		if err := svc.send(ctx, sub, n, userName, loc); err != nil {
+			d.Status = models.NotificationDeliveryFailed
+			d.Error = err.Error()
+			slog.Warn("Уведомление не доставлено", "subscription_id", sub.ID, "channel", sub.Channel, "error", err)
+		} else {
+			d.Status = models.NotificationDeliverySent
+		}
+	}
+
+	if err := svc.DAO.CreateDelivery(&d); err != nil {
+		slog.Error("Не удалось записать журнал доставки", "subscription_id", sub.ID, "error", err)
+	}
+	return d
+}
+
+// subscriberLocation — пояс владельца подписки.
+func (svc *NotificationService) subscriberLocation(sub *models.NotificationSubscription) *time.Location {
+	if svc.Timezones == nil {
+		return svc.location
+	}
+	return svc.Timezones.UserLocation(sub.UserID)
+}
+
+func (svc *NotificationService) send(
+	ctx context.Context, sub *models.NotificationSubscription, n Notification, userName string, loc *time.Location,
+) error {
+	ch, ok := svc.channels[sub.Channel]
+	if !ok {
+		return fmt.Errorf("канал %s не настроен на сервере", sub.Channel)
+	}
+
+	data := map[string]string{
+		"UserID":   strconv.Itoa(n.UserID),
+		"UserName": userName,
+		"Severity": n.Severity,
+		"Time":     n.At.In(loc).Format("02.01.2006 15:04 -07:00"),
+	}
+	if data["UserName"] == "" {
+		data["UserName"] = "#" + data["UserID"]
+	}
+	for k, v := range n.Params {
+		data[k] = v
+	}
+	subject, text, err := renderNotification(n.Event, sub.Locale, data)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notificationSendTimeout)
+	defer cancel()
+	return ch.Send(ctx, sub.Target, NotificationMessage{
+		Event:         n.Event,
+		Severity:      n.Severity,
+		SubjectUserID: n.UserID,
+		Subject:       subject,
+		Text:          text,
+		Params:        n.Params,
+		At:            n.At.UTC(),
+	})
+}
+
+// allowSend — скользящее окно в 1 час на подписку.
+func (svc *NotificationService) allowSend(subID int, now time.Time) bool {
+	limit := svc.RateLimitPerHour
+	if limit <= 0 {
+		return true
+	}
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	cutoff := now.Add(-time.Hour)
+	recent := svc.sent[subID][:0]
+	for _, at := range svc.sent[subID] {
+		if at.After(cutoff) {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= limit {
+		svc.sent[subID] = recent
+		return false
+	}
+	svc.sent[subID] = append(recent, now)
+	return true
+}
+
+// notificationVisible — владелец подписки получает события о себе, а о других
+// пользователях — только с правом tracking.read и в пределах своей организации
+// и группы (как UserScope). Событие о неизвестном пользователе — только ему самому.
+func notificationVisible(owner, subject *models.User, known bool) bool {
+	if subject.ID == owner.ID {
+		return true
+	}
+	if !known || !owner.HasPermission(models.PermTrackingRead) {
+		return false
+	}
+	if organizationOrDefault(subject.OrganizationID) != organizationOrDefault(owner.OrganizationID) {
+		return false
+	}
+	return !owner.LimitedToGroup() || (subject.GroupID != nil && *subject.GroupID == *owner.GroupID)
+}
+
+// subscriptionMatches — событие подходит подписке по типу, важности и объекту.
+func subscriptionMatches(sub *models.NotificationSubscription, n Notification) bool {
+	if sub.SubjectUserID != nil && *sub.SubjectUserID != n.UserID {
+		return false
+	}
+	if events := subscriptionEvents(sub); len(events) > 0 {
+		found := false
+		for _, e := range events {
+			if e == n.Event {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	switch sub.MinSeverity {
+	case models.AlertSeverityWarning:
+		return n.Severity == models.AlertSeverityWarning || n.Severity == models.AlertSeverityCritical
+	case models.AlertSeverityCritical:
+		return n.Severity == models.AlertSeverityCritical
+	}
+	return true
+}
+
+func subscriptionEvents(sub *models.NotificationSubscription) []string {
+	if len(sub.Events) == 0 {
+		return nil
+	}
+	var events []string
+	_ = json.Unmarshal(sub.Events, &events)
+	return events
+}
+
+// inQuietHours — [start, end) в поясе владельца подписки loc; окно через полночь поддерживается.
+func inQuietHours(sub *models.NotificationSubscription, at time.Time, loc *time.Location) bool {
+	if sub.QuietStartHour == nil || sub.QuietEndHour == nil {
+		return false
+	}
+	start, end, hour := *sub.QuietStartHour, *sub.QuietEndHour, at.In(loc).Hour()
+	if start == end {
+		return false
+	}
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// NotificationSubscriptionInput — тело создания/изменения подписки.
+type NotificationSubscriptionInput struct {
+	UserID         *int     `json:"user_id"`
+	Channel        string   `json:"channel"`
+	Target         string   `json:"target"`
+	Events         []string `json:"events"`
+	SubjectUserID  *int     `json:"subject_user_id"`
+	MinSeverity    string   `json:"min_severity"`
+	Locale         string   `json:"locale"`
+	QuietStartHour *int     `json:"quiet_start_hour"`
+	QuietEndHour   *int     `json:"quiet_end_hour"`
+	Enabled        *bool    `json:"enabled"`
+}
+
+// canManageNotificationsOf — свои подписки доступны всегда, чужие — сотруднику
+// с правом alerts.manage в пределах его области видимости.
+func canManageNotificationsOf(actor *models.User, scope *UserScope, userID int) bool {
+	return userID == actor.ID || (actor.HasPermission(models.PermAlertsManage) && scope.Allows(userID))
+}
+
+// ListSubscriptions — свои подписки; с правом alerts.manage userID = 0 — подписки
+// всех пользователей области видимости, иначе — указанного пользователя.
+func (svc *NotificationService) ListSubscriptions(actor *models.User, scope *UserScope, userID int) ([]models.NotificationSubscription, error) {
+	if !actor.HasPermission(models.PermAlertsManage) {
+		userID = actor.ID
+	}
+	if userID != 0 && !canManageNotificationsOf(actor, scope, userID) {
+		return nil, ErrNotificationForbidden
+	}
+	subs, err := svc.DAO.GetSubscriptionsByUser(userID)
+	if err != nil || userID != 0 {
+		return subs, err
+	}
+	visible := make([]models.NotificationSubscription, 0, len(subs))
+	for _, sub := range subs {
+		if scope.Allows(sub.UserID) {
+			visible = append(visible, sub)
+		}
+	}
+	return visible, nil
+}
+
+// CreateSubscription — пользователь создаёт подписку себе; с правом alerts.manage
+// можно указать user_id из своей области видимости.
+func (svc *NotificationService) CreateSubscription(actor *models.User, scope *UserScope, in NotificationSubscriptionInput) (*models.NotificationSubscription, error) {
+	sub := &models.NotificationSubscription{UserID: actor.ID, Enabled: true}
+	if in.UserID != nil && *in.UserID != actor.ID {
+		if !canManageNotificationsOf(actor, scope, *in.UserID) {
+			return nil, ErrNotificationForbidden
+		}
+		sub.UserID = *in.UserID
+	}
+	if err := checkSubjectAccess(actor, scope, sub, in); err != nil {
+		return nil, err
+	}
+	if err := applySubscriptionInput(sub, in); err != nil {
+		return nil, err
+	}
+	if err := svc.checkTarget(sub); err != nil {
+		return nil, err
+	}
+	if err := svc.DAO.CreateSubscription(sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// UpdateSubscription полностью заменяет настройки подписки.
+func (svc *NotificationService) UpdateSubscription(id int, actor *models.User, scope *UserScope, in NotificationSubscriptionInput) (*models.NotificationSubscription, error) {
+	sub, err := svc.getOwnedSubscription(id, actor, scope)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSubjectAccess(actor, scope, sub, in); err != nil {
+		return nil, err
+	}
+	if err := applySubscriptionInput(sub, in); err != nil {
+		return nil, err
+	}
+	if err := svc.checkTarget(sub); err != nil {
+		return nil, err
+	}
+	if err := svc.DAO.UpdateSubscription(sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (svc *NotificationService) DeleteSubscription(id int, actor *models.User, scope *UserScope) error {
+	if _, err := svc.getOwnedSubscription(id, actor, scope); err != nil {
+		return err
+	}
+	return svc.DAO.DeleteSubscription(id)
+}
+
+// ListDeliveries — журнал доставки: свой; с правом alerts.manage — по всем
+// подписчикам области видимости.
+func (svc *NotificationService) ListDeliveries(actor *models.User, scope *UserScope) ([]models.NotificationDelivery, error) {
+	if !actor.HasPermission(models.PermAlertsManage) {
+		return svc.DAO.GetDeliveries(actor.ID, notificationDeliveriesLimit)
+	}
+	deliveries, err := svc.DAO.GetDeliveries(0, notificationDeliveriesLimit)
+	if err != nil {
+		return nil, err
+	}
+	visible := make([]models.NotificationDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		if scope.Allows(d.UserID) {
+			visible = append(visible, d)
+		}
+	}
+	return visible, nil
+}
+
+func (svc *NotificationService) getOwnedSubscription(id int, actor *models.User, scope *UserScope) (*models.NotificationSubscription, error) {
+	sub, err := svc.DAO.GetSubscriptionByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotificationSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !canManageNotificationsOf(actor, scope, sub.UserID) {
+		return nil, ErrNotificationForbidden
+	}
+	return sub, nil
+}
+
+// checkSubjectAccess — подписка на события о другом пользователе (subject_user_id)
+// требует права tracking.read на него.
+func checkSubjectAccess(actor *models.User, scope *UserScope, sub *models.NotificationSubscription, in NotificationSubscriptionInput) error {
+	if in.SubjectUserID == nil || *in.SubjectUserID == sub.UserID || *in.SubjectUserID == actor.ID {
+		return nil
+	}
+	if !actor.HasPermission(models.PermTrackingRead) || !scope.Allows(*in.SubjectUserID) {
+		return ErrNotificationForbidden
+	}
+	return nil
+}
+
+// notificationTargetChecker — канал, проверяющий адрес подписки при сохранении
+// (вебхук: адрес не должен вести во внутреннюю сеть).
+type notificationTargetChecker interface {
+	CheckTarget(ctx context.Context, target string) error
+}
+
+func (svc *NotificationService) checkTarget(sub *models.NotificationSubscription) error {
+	checker, ok := svc.channels[sub.Channel].(notificationTargetChecker)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notificationSendTimeout)
+	defer cancel()
+	if err := checker.CheckTarget(ctx, sub.Target); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotificationSubscriptionInvalid, err)
+	}
+	return nil
+}
+
+func applySubscriptionInput(sub *models.NotificationSubscription, in NotificationSubscriptionInput) error {
+	target := strings.TrimSpace(in.Target)
+	switch in.Channel {
+	case models.NotificationChannelEmail:
+		if !strings.Contains(target, "@") || strings.ContainsAny(target, " \r\n") {
+			return fmt.Errorf("%w: некорректный email", ErrNotificationSubscriptionInvalid)
+		}
+	case models.NotificationChannelTelegram:
+		if target == "" {
+			return fmt.Errorf("%w: укажите chat_id", ErrNotificationSubscriptionInvalid)
+		}
+	case models.NotificationChannelWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: URL вебхука должен быть http(s)", ErrNotificationSubscriptionInvalid)
+		}
+	default:
+		return fmt.Errorf("%w: channel — email, telegram или webhook", ErrNotificationSubscriptionInvalid)
+	}
+
+	for _, e := range in.Events {
+		if _, ok := notificationTemplates[e]; !ok || e == models.NotificationEventTest {
+			return fmt.Errorf("%w: неизвестное событие %q", ErrNotificationSubscriptionInvalid, e)
+		}
+	}
+	switch in.MinSeverity {
+	case "", models.AlertSeverityWarning, models.AlertSeverityCritical:
+	default:
+		return fmt.Errorf("%w: min_severity — warning или critical", ErrNotificationSubscriptionInvalid)
+	}
+	locale := in.Locale
+	if locale == "" {
+		locale = "ru"
+	}
+	if locale != "ru" && locale != "en" {
+		return fmt.Errorf("%w: locale — ru или en", ErrNotificationSubscriptionInvalid)
+	}
+	if (in.QuietStartHour == nil) != (in.QuietEndHour == nil) {
+		return fmt.Errorf("%w: укажите quiet_start_hour и quiet_end_hour вместе", ErrNotificationSubscriptionInvalid)
+	}
+	if in.QuietStartHour != nil && (*in.QuietStartHour < 0 || *in.QuietStartHour > 23 || *in.QuietEndHour < 0 || *in.QuietEndHour > 24) {
+		return fmt.Errorf("%w: тихие часы 0–24", ErrNotificationSubscriptionInvalid)
+	}
+
+	sub.Channel = in.Channel
+	sub.Target = target
+	sub.Events = nil
+	if len(in.Events) > 0 {
+		raw, err := json.Marshal(in.Events)
+		if err != nil {
+			return err
+		}
+		sub.Events = datatypes.JSON(raw)
+	}
+	sub.SubjectUserID = in.SubjectUserID
+	sub.MinSeverity = in.MinSeverity
+	sub.Locale = locale
+	sub.QuietStartHour = in.QuietStartHour
+	sub.QuietEndHour = in.QuietEndHour
+	if in.Enabled != nil {
+		sub.Enabled = *in.Enabled
+	}
+	return nil
+}
@@ -0,0 +1,452 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+type fakeNotificationRepo struct {
+	subs       []models.NotificationSubscription
+	deliveries []models.NotificationDelivery
+}
+
+func (f *fakeNotificationRepo) CreateSubscription(sub *models.NotificationSubscription) error {
+	sub.ID = len(f.subs) + 1
+	f.subs = append(f.subs, *sub)
+	return nil
+}
+
+func (f *fakeNotificationRepo) UpdateSubscription(sub *models.NotificationSubscription) error {
+	for i := range f.subs {
+		if f.subs[i].ID == sub.ID {
+			f.subs[i] = *sub
+		}
+	}
+	return nil
+}
+
+func (f *fakeNotificationRepo) DeleteSubscription(id int) error { return nil }
+
+func (f *fakeNotificationRepo) GetSubscriptionByID(id int) (*models.NotificationSubscription, error) {
+	for i := range f.subs {
+		if f.subs[i].ID == id {
+			s := f.subs[i]
+			return &s, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeNotificationRepo) GetSubscriptionsByUser(userID int) ([]models.NotificationSubscription, error) {
+	var out []models.NotificationSubscription
+	for _, s := range f.subs {
+		if userID == 0 || s.UserID == userID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeNotificationRepo) GetEnabledSubscriptions() ([]models.NotificationSubscription, error) {
+	var out []models.NotificationSubscription
+	for _, s := range f.subs {
+		if s.Enabled {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeNotificationRepo) CreateDelivery(d *models.NotificationDelivery) error {
+	f.deliveries = append(f.deliveries, *d)
+	return nil
+}
+
+func (f *fakeNotificationRepo) GetDeliveries(userID, limit int) ([]models.NotificationDelivery, error) {
+	return f.deliveries, nil
+}
+
+type recordingChannel struct {
+	name string
+	msgs []NotificationMessage
+}
+
+func (c *recordingChannel) Name() string { return c.name }
+
+func (c *recordingChannel) Send(ctx context.Context, target string, msg NotificationMessage) error {
+	c.msgs = append(c.msgs, msg)
+	return nil
+}
+
+func newTestNotificationService(repo *fakeNotificationRepo, channels ...NotificationChannel) *NotificationService {
+	users := newFakeUserRepo(
+		models.User{ID: 1, Name: "admin", IsAdmin: true},
+		models.User{ID: 2, Name: "Курьер"},
+		models.User{ID: 3, Name: "other"},
+	)
+	svc := NewNotificationService(repo, users, channels...)
+	svc.location = time.UTC
+	return svc
+}
+
+func TestNotificationDispatch_scopesAndSeverity(t *testing.T) {
+	repo := &fakeNotificationRepo{}
+	ch := &recordingChannel{name: models.NotificationChannelWebhook}
+	svc := newTestNotificationService(repo, ch)
+	admin := &models.User{ID: 1, IsAdmin: true}
+	user := &models.User{ID: 3}
+
+	if _, err := svc.CreateSubscription(admin, nil, NotificationSubscriptionInput{
+		Channel: models.NotificationChannelWebhook, Target: "http://hooks.test/a", MinSeverity: models.AlertSeverityCritical,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateSubscription(user, nil, NotificationSubscriptionInput{
+		Channel: models.NotificationChannelWebhook, Target: "http://hooks.test/u", Locale: "en",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	out, err := svc.Dispatch(context.Background(), Notification{
+		Event: models.NotificationEventAlertOpened, Severity: models.AlertSeverityWarning, UserID: 2,
+		Params: map[string]string{"Message": "Нет координат 40 мин"}, At: at,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 {
+		t.Fatalf("warning about user 2 must reach nobody: %+v", out)
+	}
+
+	out, _ = svc.Dispatch(context.Background(), Notification{
+		Event: models.NotificationEventAlertOpened, Severity: models.AlertSeverityCritical, UserID: 2,
+		Params: map[string]string{"Message": "Нет координат 40 мин"}, At: at,
+	})
+	if len(out) != 1 || out[0].SubscriptionID != 1 || out[0].Status != models.NotificationDeliverySent {
+		t.Fatalf("critical must reach admin only: %+v", out)
+	}
+	if !strings.Contains(ch.msgs[0].Subject, "Курьер") || !strings.Contains(ch.msgs[0].Text, "Нет координат 40 мин") {
+		t.Fatalf("ru template: %+v", ch.msgs[0])
+	}
+
+	out, _ = svc.Dispatch(context.Background(), Notification{
+		Event: models.NotificationEventVisitStarted, UserID: 3, Params: map[string]string{"Checkpoint": "Office"}, At: at,
+	})
+	if len(out) != 1 || out[0].SubscriptionID != 2 {
+		t.Fatalf("user must get own visit event: %+v", out)
+	}
+	if got := ch.msgs[len(ch.msgs)-1].Subject; got != "Visit started: other — Office" {
+		t.Fatalf("en template subject=%q", got)
+	}
+}
+
+func TestNotificationDispatch_quietHoursAndRateLimit(t *testing.T) {
+	repo := &fakeNotificationRepo{}
+	ch := &recordingChannel{name: models.NotificationChannelWebhook}
+	svc := newTestNotificationService(repo, ch)
+	svc.RateLimitPerHour = 2
+	quietStart, quietEnd := 22, 7
+	if _, err := svc.CreateSubscription(&models.User{ID: 1, IsAdmin: true}, nil, NotificationSubscriptionInput{
+		Channel: models.NotificationChannelWebhook, Target: "https://hooks.test/x",
+		QuietStartHour: &quietStart, QuietEndHour: &quietEnd,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	night := time.Date(2026, 10, 19, 23, 30, 0, 0, time.UTC)
+	out, _ := svc.Dispatch(context.Background(), Notification{Event: models.NotificationEventAlertOpened, Severity: models.AlertSeverityWarning, UserID: 2, At: night})
+	if out[0].Status != models.NotificationDeliveryQuietHours {
+		t.Fatalf("warning at night: %s", out[0].Status)
+	}
+	out, _ = svc.Dispatch(context.Background(), Notification{Event: models.NotificationEventAlertOpened, Severity: models.AlertSeverityCritical, UserID: 2, At: night})
+	if out[0].Status != models.NotificationDeliverySent {
+		t.Fatalf("critical must bypass quiet hours: %s", out[0].Status)
+	}
+
+	day := time.Date(2026, 10, 20, 10, 0, 0, 0, time.UTC)
+	var statuses []string
+	for i := 0; i < 3; i++ {
+		out, _ = svc.Dispatch(context.Background(), Notification{Event: models.NotificationEventAlertResolved, UserID: 2, At: day.Add(time.Duration(i) * time.Minute)})
+		statuses = append(statuses, out[0].Status)
+	}
+	if statuses[2] != models.NotificationDeliveryRateLimited || statuses[1] != models.NotificationDeliverySent {
+		t.Fatalf("rate limit statuses=%v", statuses)
+	}
+	out, _ = svc.Dispatch(context.Background(), Notification{Event: models.NotificationEventAlertResolved, UserID: 2, At: day.Add(2 * time.Hour)})
+	if out[0].Status != models.NotificationDeliverySent {
+		t.Fatalf("window must slide: %s", out[0].Status)
+	}
+}
+
+func TestCreateSubscription_validationAndOwnership(t *testing.T) {
+	svc := newTestNotificationService(&fakeNotificationRepo{})
+	user := &models.User{ID: 3}
+	other := 2
+	cases := []NotificationSubscriptionInput{
+		{Channel: "sms", Target: "123"},
+		{Channel: models.NotificationChannelEmail, Target: "not-an-email"},
+		{Channel: models.NotificationChannelWebhook, Target: "ftp://x"},
+		{Channel: models.NotificationChannelTelegram, Target: "1", Events: []string{"nope"}},
+		{Channel: models.NotificationChannelTelegram, Target: "1", Locale: "de"},
+	}
+	for i, in := range cases {
+		if _, err := svc.CreateSubscription(user, nil, in); err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+	}
+	if _, err := svc.CreateSubscription(user, nil, NotificationSubscriptionInput{
+		UserID: &other, Channel: models.NotificationChannelTelegram, Target: "1",
+	}); err != ErrNotificationForbidden {
+		t.Fatalf("user must not subscribe others: %v", err)
+	}
+}
+
+func TestNotificationAccess_permissionsAndGroupScope(t *testing.T) {
+	repo := &fakeNotificationRepo{}
+	ch := &recordingChannel{name: models.NotificationChannelTelegram}
+	users := newFakeUserRepo(
+		models.User{ID: 1, Role: models.RoleViewer},
+		models.User{ID: 2, Role: models.RoleDevice, GroupID: intPtr(7)},
+		models.User{ID: 3, Role: models.RoleDevice, GroupID: intPtr(8)},
+		models.User{ID: 4, Role: models.RoleFleetAdmin, GroupID: intPtr(7)},
+	)
+	svc := NewNotificationService(repo, users, ch)
+	svc.location = time.UTC
+	viewer, manager := users.users[1], users.users[4]
+	managerScope := NewUserScope(2, 4)
+
+	device := 2
+	if _, err := svc.CreateSubscription(&viewer, NewUserScope(1, 2, 3), NotificationSubscriptionInput{
+		UserID: &device, Channel: models.NotificationChannelTelegram, Target: "1",
+	}); !errors.Is(err, ErrNotificationForbidden) {
+		t.Fatalf("viewer must not manage subscriptions of others, got %v", err)
+	}
+	outside := 3
+	if _, err := svc.CreateSubscription(&manager, managerScope, NotificationSubscriptionInput{
+		UserID: &outside, Channel: models.NotificationChannelTelegram, Target: "1",
+	}); !errors.Is(err, ErrNotificationForbidden) {
+		t.Fatalf("manager must not manage users outside the group, got %v", err)
+	}
+	if _, err := svc.CreateSubscription(&manager, managerScope, NotificationSubscriptionInput{
+		SubjectUserID: &outside, Channel: models.NotificationChannelTelegram, Target: "1",
+	}); !errors.Is(err, ErrNotificationForbidden) {
+		t.Fatalf("manager must not watch users outside the group, got %v", err)
+	}
+	sub, err := svc.CreateSubscription(&manager, managerScope, NotificationSubscriptionInput{
+		Channel: models.NotificationChannelTelegram, Target: "1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subs, err := svc.ListSubscriptions(&viewer, NewUserScope(1, 2, 3, 4), 4); err != nil || len(subs) != 0 {
+		t.Fatalf("viewer must see only own subscriptions, got %+v %v", subs, err)
+	}
+	if err := svc.DeleteSubscription(sub.ID, &viewer, NewUserScope(1, 2, 3, 4)); !errors.Is(err, ErrNotificationForbidden) {
+		t.Fatalf("viewer must not delete subscriptions of others, got %v", err)
+	}
+
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	for subject, want := range map[int]int{2: 1, 3: 0} {
+		out, err := svc.Dispatch(context.Background(), Notification{Event: models.NotificationEventAlertOpened, UserID: subject, At: at})
+		if err != nil || len(out) != want {
+			t.Fatalf("event about user %d: want %d deliveries, got %+v %v", subject, want, out, err)
+		}
+	}
+}
+
+func TestWebhookChannel_postsJSON(t *testing.T) {
+	var got NotificationMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("content-type=%q", r.Header.Get("Content-Type"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	ch := &WebhookChannel{HTTP: srv.Client(), AllowPrivateNetworks: true}
+	if err := ch.Send(context.Background(), srv.URL, NotificationMessage{Event: "alert.opened", SubjectUserID: 7, Text: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Event != "alert.opened" || got.SubjectUserID != 7 {
+		t.Fatalf("payload=%+v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := ch.Send(context.Background(), failing.URL, NotificationMessage{}); err == nil {
+		t.Fatal("5xx must be an error")
+	}
+}
+
+func TestWebhookChannel_rejectsPrivateNetworks(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hit = true }))
+	defer srv.Close()
+
+	ch := &WebhookChannel{}
+	for _, target := range []string{srv.URL, "http://10.0.0.5/hook", "http://169.254.169.254/latest/meta-data", "http://[::1]:8080/"} {
+		if err := ch.Send(context.Background(), target, NotificationMessage{}); err == nil {
+			t.Errorf("%s: private address must be rejected", target)
+		}
+	}
+	if hit {
+		t.Fatal("request reached a loopback server")
+	}
+
+	svc := newTestNotificationService(&fakeNotificationRepo{}, ch)
+	_, err := svc.CreateSubscription(&models.User{ID: 1, IsAdmin: true}, nil, NotificationSubscriptionInput{
+		Channel: models.NotificationChannelWebhook, Target: "http://127.0.0.1:9000/hook",
+	})
+	if !errors.Is(err, ErrNotificationSubscriptionInvalid) {
+		t.Fatalf("loopback webhook must be rejected on creation, got %v", err)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"8.8.8.8": true, "2001:4860:4860::8888": true,
+		"127.0.0.1": false, "10.1.2.3": false, "172.16.0.1": false, "192.168.1.1": false,
+		"169.254.169.254": false, "100.64.0.1": false, "0.0.0.0": false, "::1": false,
+		"fe80::1": false, "fd00::1": false, "::ffff:127.0.0.1": false,
+	} {
+		if got := isPublicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("isPublicIP(%s)=%v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestTelegramChannel_transportErrorHidesToken(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "http://" + ln.Addr().String()
+	_ = ln.Close() // соединение будет отклонено
+
+	ch := &TelegramChannel{BaseURL: base, Token: "123:secret-token"}
+	err = ch.Send(context.Background(), "42", NotificationMessage{})
+	if err == nil {
+		t.Fatal("expected transport error")
+	}
+	if strings.Contains(err.Error(), "secret-token") || strings.Contains(err.Error(), "/bot") {
+		t.Fatalf("token leaked into error: %v", err)
+	}
+}
+
+func TestTelegramChannel_sendMessage(t *testing.T) {
+	var path string
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["chat_id"] == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"ok":false,"description":"chat not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	ch := &TelegramChannel{BaseURL: srv.URL, Token: "123:abc", HTTP: srv.Client()}
+	if err := ch.Send(context.Background(), "42", NotificationMessage{Subject: "S", Text: "T"}); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/bot123:abc/sendMessage" || body["chat_id"] != "42" || body["text"] != "S\n\nT" {
+		t.Fatalf("path=%s body=%v", path, body)
+	}
+	if err := ch.Send(context.Background(), "bad", NotificationMessage{}); err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Fatalf("want telegram error, got %v", err)
+	}
+}
+
+// fakeSMTPServer — минимальный SMTP-приёмник: принимает одно письмо и отдаёт его DATA.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	data := make(chan string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		write := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		write("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				write("250 fake")
+			case strings.HasPrefix(cmd, "DATA"):
+				write("354 go ahead")
+				var msg strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					msg.WriteString(l)
+				}
+				data <- msg.String()
+				write("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				write("221 bye")
+				return
+			default:
+				write("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), data
+}
+
+func TestSMTPChannel_sendsMail(t *testing.T) {
+	addr, data := fakeSMTPServer(t)
+	ch := &SMTPChannel{Addr: addr, From: "locator@example.test"}
+	err := ch.Send(context.Background(), "ops@example.test", NotificationMessage{Subject: "Проблема", Text: "строка 1\nстрока 2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-data:
+		if !strings.Contains(msg, "To: ops@example.test") || !strings.Contains(msg, "строка 1\r\nстрока 2") {
+			t.Fatalf("message=%q", msg)
+		}
+		if !strings.Contains(msg, "Subject: =?utf-8?q?") {
+			t.Fatalf("subject must be MIME-encoded: %q", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"locator/models"
+)
+
+type notificationTemplate struct {
+	Subject string
+	Body    string
+}
+
+// notificationTemplates — шаблоны по событию и языку (ru, en).
+// Данные: UserID, UserName, Severity, Time (в поясе владельца подписки, со смещением) и параметры события
+// (Message — для алертов; Checkpoint, Duration — для визитов).
+var notificationTemplates = map[string]map[string]notificationTemplate{
+	models.NotificationEventAlertOpened: {
+		"ru": {
+			Subject: "[{{.Severity}}] Проблема с устройством: {{.UserName}}",
+			Body:    "Устройство {{.UserName}} (ID {{.UserID}}): {{.Message}}\nВремя: {{.Time}}",
+		},
+		"en": {
+			Subject: "[{{.Severity}}] Device problem: {{.UserName}}",
+			Body:    "Device {{.UserName}} (ID {{.UserID}}): {{.Message}}\nTime: {{.Time}}",
+		},
+	},
+	models.NotificationEventAlertResolved: {
+		"ru": {
+			Subject: "Проблема устранена: {{.UserName}}",
+			Body:    "Устройство {{.UserName}} (ID {{.UserID}}): алерт закрыт.\n{{.Message}}\nВремя: {{.Time}}",
+		},
+		"en": {
+			Subject: "Resolved: {{.UserName}}",
+			Body:    "Device {{.UserName}} (ID {{.UserID}}): alert resolved.\n{{.Message}}\nTime: {{.Time}}",
+		},
+	},
+	models.NotificationEventVisitStarted: {
+		"ru": {
+			Subject: "Визит начат: {{.UserName}} — {{.Checkpoint}}",
+			Body:    "{{.UserName}}: начало визита в «{{.Checkpoint}}» в {{.Time}}",
+		},
+		"en": {
+			Subject: "Visit started: {{.UserName}} — {{.Checkpoint}}",
+			Body:    "{{.UserName}}: visit to \"{{.Checkpoint}}\" started at {{.Time}}",
+		},
+	},
+	models.NotificationEventVisitEnded: {
+		"ru": {
+			Subject: "Визит завершён: {{.UserName}} — {{.Checkpoint}}",
+			Body:    "{{.UserName}}: визит в «{{.Checkpoint}}» завершён в {{.Time}}, длительность {{.Duration}}",
+		},
+		"en": {
+			Subject: "Visit ended: {{.UserName}} — {{.Checkpoint}}",
+			Body:    "{{.UserName}}: visit to \"{{.Checkpoint}}\" ended at {{.Time}}, duration {{.Duration}}",
+		},
+	},
+	models.NotificationEventTest: {
+		"ru": {
+			Subject: "Тестовое уведомление Locator",
+			Body:    "Подписка настроена верно. Время: {{.Time}}",
+		},
+		"en": {
+			Subject: "Locator test notification",
+			Body:    "Your subscription works. Time: {{.Time}}",
+		},
+	},
+}
+
+// renderNotification подставляет данные в шаблон события; неизвестный язык — ru.
+func renderNotification(event, locale string, data map[string]string) (string, string, error) {
+	byLocale, ok := notificationTemplates[event]
+	if !ok {
+		return "", "", fmt.Errorf("нет шаблона для события %q", event)
+	}
+	tpl, ok := byLocale[locale]
+	if !ok {
+		tpl = byLocale["ru"]
+	}
+	subject, err := executeNotificationTemplate(tpl.Subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := executeNotificationTemplate(tpl.Body, data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+func executeNotificationTemplate(text string, data map[string]string) (string, error) {
+	t, err := template.New("n").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
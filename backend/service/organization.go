@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"locator/models"
+)
+
+var (
+	ErrOrganizationNameEmpty = errors.New("не указано имя организации")
+	ErrOrganizationNotFound  = errors.New("организация не найдена")
+)
+
+// organizationOrDefault — ID организации; 0 (данные до появления организаций,
+// внутренние вызовы) означает организацию по умолчанию.
+func organizationOrDefault(organizationID int) int {
+	if organizationID == 0 {
+		return models.DefaultOrganizationID
+	}
+	return organizationID
+}
+
+// ListOrganizations — все организации; доступно только администратору платформы.
+func (svc *UserService) ListOrganizations(actor *models.User) ([]models.Organization, error) {
+	if !actor.IsPlatformAdmin() {
+		return nil, ErrAccessDenied
+	}
+	if svc.Organizations == nil {
+		return []models.Organization{}, nil
+	}
+	return svc.Organizations.GetAllOrganizations()
+}
+
+// CreateOrganization создаёт организацию и её первого super_admin с именем adminName.
+// timezone — IANA-пояс организации ("" — models.DefaultTimezone).
+// Возвращает организацию, администратора и его API-ключ.
+func (svc *UserService) CreateOrganization(actor *models.User, name, adminName, timezone string) (*models.Organization, *models.User, string, error) {
+	if !actor.IsPlatformAdmin() {
+		return nil, nil, "", ErrAccessDenied
+	}
+	name = strings.TrimSpace(name)
+	adminName = strings.TrimSpace(adminName)
+	if name == "" {
+		return nil, nil, "", ErrOrganizationNameEmpty
+	}
+	timezone, err := ValidateTimezone(timezone)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if timezone == "" {
+		timezone = models.DefaultTimezone
+	}
+	if svc.Organizations == nil {
+		return nil, nil, "", errors.New("хранилище организаций не настроено")
+	}
+	if adminName == "" {
+		adminName = name + " admin"
+	}
+	org := &models.Organization{Name: name, Timezone: timezone}
+	if err := svc.Organizations.CreateOrganization(org); err != nil {
+		return nil, nil, "", err
+	}
+	admin, apiKey, err := svc.createUser(org.ID, adminName, models.RoleSuperAdmin, nil, "")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	slog.Info("Организация создана", "organization_id", org.ID, "name", org.Name, "admin_id", admin.ID, "actor_id", actor.ID)
+	return org, admin, apiKey, nil
+}
+
+// SetOrganizationTimezone меняет пояс организации: своей — её администратору,
+// любой — администратору платформы.
+func (svc *UserService) SetOrganizationTimezone(actor *models.User, organizationID int, timezone string) (*models.Organization, error) {
+	if !actor.IsPlatformAdmin() && actor.OrganizationID != organizationID {
+		return nil, ErrAccessDenied
+	}
+	timezone, err := ValidateTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+	if timezone == "" {
+		timezone = models.DefaultTimezone
+	}
+	if svc.Organizations == nil {
+		return nil, errors.New("хранилище организаций не настроено")
+	}
+	org, err := svc.Organizations.GetOrganizationByID(organizationID)
+	if err != nil {
+		return nil, ErrOrganizationNotFound
+	}
+	org.Timezone = timezone
+	if err := svc.Organizations.UpdateOrganization(org); err != nil {
+		return nil, err
+	}
+	slog.Info("Пояс организации изменён", "organization_id", org.ID, "timezone", org.Timezone, "actor_id", actor.ID)
+	return org, nil
+}
@@ -5,49 +5,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"locator/internal/metrics"
 	"locator/models"
 )
 
-// osrmMatchChunkSize — сколько точек в одном GET к /match/v1/… Публичный router.project-osrm.org
-// принимает не больше ~10 координат (иначе TooBig); задайте ROUTING_MATCH_CHUNK_SIZE=10.
-func osrmMatchChunkSize() int {
-	const def = 80
-	s := strings.TrimSpace(os.Getenv("ROUTING_MATCH_CHUNK_SIZE"))
-	if s == "" {
-		return def
-	}
-	n, err := strconv.Atoi(s)
-	if err != nil || n < 2 {
-		return def
-	}
-	if n > 100 {
-		return 100
-	}
-	return n
-}
-
-// osrmMatchRadiusMeters — опциональный радиус поиска (метры) на каждую точку в match; уменьшает NoMatch
-// на «рваном» GPS. Публичный OSRM: попробуйте 15–25 (50 может дать TooBig). Пусто — без radiuses.
-func osrmMatchRadiusMeters() (ok bool, meters int) {
-	s := strings.TrimSpace(os.Getenv("ROUTING_MATCH_RADIUS"))
-	if s == "" {
-		return false, 0
-	}
-	n, err := strconv.Atoi(s)
-	if err != nil || n < 1 {
-		return false, 0
-	}
-	if n > 50 {
-		n = 50
-	}
-	return true, n
-}
-
 // OSRMMatchResponse минимальная структура ответа /match/v1/... с geometries=geojson.
 type osrmMatchResponse struct {
 	Matchings []struct {
@@ -100,7 +65,7 @@ func MatchLocationsToRoads(client *http.Client, baseURL string, locs []models.Lo
 }
 
 func matchLocationSegment(client *http.Client, baseURL string, locs []models.Location) ([][]float64, error) {
-	chunkSz := osrmMatchChunkSize()
+	chunkSz := thresholds.Load().RouteMatchChunkSize
 	var merged [][]float64
 	for start := 0; start < len(locs); {
 		end := start + chunkSz
@@ -129,6 +94,16 @@ func matchLocationSegment(client *http.Client, baseURL string, locs []models.Loc
 }
 
 func osrmMatchChunk(client *http.Client, baseURL string, chunk []models.Location) ([][]float64, error) {
+	started := time.Now()
+	out, err := requestOSRMMatch(client, baseURL, chunk)
+	metrics.OSRMDuration.Observe(time.Since(started).Seconds(), "match")
+	if err != nil {
+		metrics.OSRMErrors.Inc("match")
+	}
+	return out, err
+}
+
+func requestOSRMMatch(client *http.Client, baseURL string, chunk []models.Location) ([][]float64, error) {
 	var b strings.Builder
 	for i, loc := range chunk {
 		if i > 0 {
@@ -138,7 +113,8 @@ func osrmMatchChunk(client *http.Client, baseURL string, chunk []models.Location
 	}
 	u := fmt.Sprintf("%s/match/v1/driving/%s?overview=full&geometries=geojson&steps=false",
 		baseURL, b.String())
-	if useR, r := osrmMatchRadiusMeters(); useR {
+	// Радиус уменьшает NoMatch на «рваном» GPS; публичному OSRM больше 25 м не давать (TooBig).
+	if r := thresholds.Load().RouteMatchRadiusMeters; r > 0 {
 		var rad strings.Builder
 		for i := 0; i < len(chunk); i++ {
 			if i > 0 {
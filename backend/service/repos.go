@@ -3,17 +3,64 @@ package service
 import (
 	"time"
 
+	"locator/dao"
 	"locator/models"
 )
 
 // Narrow repository interfaces so unit tests can inject in-memory fakes
-// while production keeps using concrete *dao.*DAO (implicit satisfaction).
+// (local fakes or internal/testutil/memrepo) while production keeps using
+// concrete *dao.*DAO (implicit satisfaction).
 
 type userRepository interface {
 	Create(user *models.User) error
 	Update(user *models.User) error
 	GetByID(id int) (*models.User, error)
+	GetByUsername(username string) (*models.User, error)
+	UseTOTPStep(userID int, step int64) (bool, error)
 	GetAll() ([]models.User, error)
+	GetAllByOrganization(organizationID int) ([]models.User, error)
+	GetIDsByOrganization(organizationID int, groupID *int) ([]int, error)
+}
+
+type apiKeyRepository interface {
+	CreateAPIKey(key *models.APIKey) error
+	UpdateAPIKey(key *models.APIKey) error
+	GetAPIKeyByID(id int) (*models.APIKey, error)
+	GetAPIKeysByUser(userID int) ([]models.APIKey, error)
+	GetAPIKeyCandidates(fingerprint string) ([]models.APIKey, error)
+	GetLegacyAPIKeys(now time.Time, limit int) ([]models.APIKey, error)
+	RevokeReplacedAPIKeys(replacedByID int, at time.Time) error
+}
+
+type auditRepository interface {
+	CreateAuditEntry(entry *models.AuditEntry) error
+	ListAuditEntries(filters map[string]interface{}, from, to *time.Time, limit, offset int) ([]models.AuditEntry, int64, error)
+}
+
+type groupRepository interface {
+	CreateGroup(group *models.Group) error
+	GetGroupByID(id int) (*models.Group, error)
+	GetAllGroups(organizationID int) ([]models.Group, error)
+}
+
+type organizationRepository interface {
+	CreateOrganization(org *models.Organization) error
+	GetOrganizationByID(id int) (*models.Organization, error)
+	GetAllOrganizations() ([]models.Organization, error)
+	UpdateOrganization(org *models.Organization) error
+}
+
+type sessionRepository interface {
+	CreateSession(session *models.UserSession) error
+	UpdateSession(session *models.UserSession) error
+	GetSessionByID(id string) (*models.UserSession, error)
+	GetSessionsByUser(userID int) ([]models.UserSession, error)
+	RotateRefreshToken(session *models.UserSession, previousHash string) (bool, error)
+	RevokeSession(id string, at time.Time) error
+	RevokeUserSessions(userID int, at time.Time) error
+	CreateLoginLink(link *models.LoginLink) error
+	ConsumeLoginLink(id int, at time.Time) (bool, error)
+	GetLoginLinkByHash(tokenHash string) (*models.LoginLink, error)
 }
 
 type locationRepository interface {
@@ -28,17 +75,155 @@ type locationRepository interface {
 	UpdateCapturedAt(id int, capturedAt time.Time) error
 }
 
+// locationHistoryRepository — история точек пользователя за период (визиты, участки пути).
+type locationHistoryRepository interface {
+	GetLocationsByUserBetween(userID int, from, to time.Time) ([]models.Location, error)
+}
+
+// locationAgeRepository — возраст последней точки по пользователям (organizationID 0 — все организации).
+type locationAgeRepository interface {
+	GetLatestAgePerUser(organizationID int) ([]dao.LatestLocationAge, error)
+}
+
+// retentionLocationRepository — выборка и удаление точек для прореживания по сроку хранения.
+type retentionLocationRepository interface {
+	GetByUserID(userID int) (*models.Location, error)
+	GetForDownsampling(userID int, from, to, afterAt time.Time, afterID, limit int) ([]models.Location, error)
+	GetOlderThan(userID int, before time.Time, afterID, limit int) ([]models.Location, error)
+	DeleteByIDs(ids []int) (int64, error)
+	MarkDownsampled(ids []int) error
+}
+
+// locationPartitionRepository — обслуживание месячных секций locations.
+type locationPartitionRepository interface {
+	EnsurePartitions(from, to time.Time) (int, error)
+	ExpiredPartitions(before time.Time) ([]string, error)
+	DetachPartition(name string) error
+	LatestInPartition(name string) ([]int, error)
+	PartitionLocations(name string, afterID, limit int) ([]models.Location, error)
+	DropPartition(name string, keep []int) error
+}
+
+type retentionRepository interface {
+	GetPolicy(organizationID int) (*models.RetentionPolicy, error)
+	SavePolicy(p *models.RetentionPolicy) error
+	DeletePolicy(organizationID int) error
+	CreateRun(run *models.RetentionRun) error
+	UpdateRun(run *models.RetentionRun) error
+	GetRun(id int64) (*models.RetentionRun, error)
+	ListRuns(limit int) ([]models.RetentionRun, error)
+}
+
 type visitRepository interface {
 	Create(visit *models.Visit) error
 	Update(visit *models.Visit) error
 	Delete(id int64) error
 	GetActiveVisit(userID int, checkpointID int) (*models.Visit, error)
-	GetVisits(filters map[string]interface{}, activeOnly bool, rangeFrom, rangeTo *time.Time) ([]models.Visit, error)
+	GetVisits(organizationID int, filters map[string]interface{}, activeOnly bool, rangeFrom, rangeTo *time.Time) ([]models.Visit, error)
+	InUserLock(userID int, fn func(tx dao.VisitStore) error) error
 }
 
 type checkpointRepository interface {
 	Create(cp *models.Checkpoint) error
 	Update(cp *models.Checkpoint) error
-	GetByID(id int) (*models.Checkpoint, error)
-	GetAll() ([]models.Checkpoint, error)
+	GetByID(organizationID, id int) (*models.Checkpoint, error)
+	GetAll(organizationID int) ([]models.Checkpoint, error)
+}
+
+type deviceConfigRepository interface {
+	CreateProfile(p *models.DeviceConfigProfile) error
+	UpdateProfile(p *models.DeviceConfigProfile) error
+	GetProfileByID(organizationID, id int) (*models.DeviceConfigProfile, error)
+	GetAllProfiles(organizationID int) ([]models.DeviceConfigProfile, error)
+	GetDesired(userID int) (*models.DeviceDesiredConfig, error)
+	SaveDesired(cfg *models.DeviceDesiredConfig) error
+	GetAllDesired(organizationID int) ([]models.DeviceDesiredConfig, error)
+	MarkPushed(userID int, hash string, at time.Time) error
+}
+
+type trackingScheduleRepository interface {
+	GetSchedule(userID int) (*models.TrackingSchedule, error)
+	SaveSchedule(s *models.TrackingSchedule) error
+	DeleteSchedule(userID int) error
+	GetEnabledSchedules() ([]models.TrackingSchedule, error)
+	MarkPushed(userID int, paused bool, at time.Time) error
+}
+
+type deviceReportRepository interface {
+	Create(report *models.DeviceReport) error
+	GetLatestByUserID(userID int) (*models.DeviceReport, error)
+	ListByUserBetween(userID int, from, to time.Time, limit, offset int) ([]models.DeviceReport, int64, error)
+	GetByUserBetweenAsc(userID int, from, to time.Time) ([]models.DeviceReport, error)
+}
+
+// latestReportRepository — последний отчёт по пользователям (organizationID 0 — все организации).
+type latestReportRepository interface {
+	GetLatestPerUser(organizationID int) ([]dao.LatestDeviceReportRow, error)
+}
+
+type deviceCommandRepository interface {
+	Create(cmd *models.DeviceCommand) error
+	GetByID(id string) (*models.DeviceCommand, error)
+	GetNextPending(userID int) (*models.DeviceCommand, error)
+	MarkDelivered(id string, at time.Time) error
+	MarkAcked(id, ackStatus, ackMessage string, at time.Time) error
+	MarkFailed(id, ackStatus, ackMessage string, at time.Time) error
+	MarkProgress(id, ackStatus, ackMessage string, at time.Time) error
+	ExpirePendingOlderThanExceptType(cutoff time.Time, exceptType string) ([]string, error)
+	ExpirePendingOlderThanType(cutoff time.Time, cmdType string) ([]string, error)
+	CancelPendingForUser(userID int, cmdType, excludeID string) error
+}
+
+type locationRequestRepository interface {
+	Create(req *models.LocationRequest) error
+	GetByID(id string) (*models.LocationRequest, error)
+	GetPendingByUserID(userID int) (*models.LocationRequest, error)
+	UpdateStatus(id, status string, completedAt *time.Time) error
+	ExpirePendingOlderThan(cutoff time.Time) error
+	CancelPendingForUser(userID int, excludeID string) error
+}
+
+type alertRepository interface {
+	CreateRule(rule *models.AlertRule) error
+	UpdateRule(rule *models.AlertRule) error
+	DeleteRule(id int) error
+	GetRuleByID(id int) (*models.AlertRule, error)
+	GetAllRules() ([]models.AlertRule, error)
+	GetRulesByOrganization(organizationID int) ([]models.AlertRule, error)
+	CreateAlert(alert *models.Alert) error
+	UpdateAlert(alert *models.Alert) error
+	GetAlertByID(id int) (*models.Alert, error)
+	GetActiveAlerts() ([]models.Alert, error)
+	ListAlerts(filters map[string]interface{}, limit, offset int) ([]models.Alert, int64, error)
+}
+
+type notificationRepository interface {
+	CreateSubscription(sub *models.NotificationSubscription) error
+	UpdateSubscription(sub *models.NotificationSubscription) error
+	DeleteSubscription(id int) error
+	GetSubscriptionByID(id int) (*models.NotificationSubscription, error)
+	GetSubscriptionsByUser(userID int) ([]models.NotificationSubscription, error)
+	GetEnabledSubscriptions() ([]models.NotificationSubscription, error)
+	CreateDelivery(d *models.NotificationDelivery) error
+	GetDeliveries(userID, limit int) ([]models.NotificationDelivery, error)
+}
+
+type appReleaseRepository interface {
+	CreateRelease(release *models.AppRelease) error
+	UpdateRelease(release *models.AppRelease) error
+	GetReleaseByID(id int) (*models.AppRelease, error)
+	ListReleases(organizationID int, channel string) ([]models.AppRelease, error)
+	GetActiveReleases(organizationID int, channels []string) ([]models.AppRelease, error)
+	GetChannel(organizationID int, name string) (*models.AppReleaseChannel, error)
+	GetAllChannels(organizationID int) ([]models.AppReleaseChannel, error)
+	SaveChannel(ch *models.AppReleaseChannel) error
+	GetMember(userID int) (*models.AppReleaseMember, error)
+	GetAllMembers(organizationID int) ([]models.AppReleaseMember, error)
+	SaveMember(m *models.AppReleaseMember) error
+	DeleteMember(userID int) error
+}
+
+type appUpdateCommandRepository interface {
+	GetLatestByType(userID int, cmdType string) (*models.DeviceCommand, error)
+	CountAppUpdateResults(releaseID int) (total, failed int64, err error)
 }
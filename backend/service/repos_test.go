@@ -0,0 +1,33 @@
+package service
+
+import "locator/internal/testutil/memrepo"
+
+// memrepo реализует те же интерфейсы, что и *dao.*DAO.
+var (
+	_ userRepository              = (*memrepo.Users)(nil)
+	_ apiKeyRepository            = (*memrepo.APIKeys)(nil)
+	_ auditRepository             = (*memrepo.Audit)(nil)
+	_ groupRepository             = (*memrepo.Groups)(nil)
+	_ organizationRepository      = (*memrepo.Organizations)(nil)
+	_ sessionRepository           = (*memrepo.Sessions)(nil)
+	_ locationRepository          = (*memrepo.Locations)(nil)
+	_ locationHistoryRepository   = (*memrepo.Locations)(nil)
+	_ locationAgeRepository       = (*memrepo.Locations)(nil)
+	_ retentionLocationRepository = (*memrepo.Locations)(nil)
+	_ locationPartitionRepository = (*memrepo.Locations)(nil)
+	_ retentionRepository         = (*memrepo.Retention)(nil)
+	_ visitRepository             = (*memrepo.Visits)(nil)
+	_ checkpointRepository        = (*memrepo.Checkpoints)(nil)
+	_ deviceConfigRepository      = (*memrepo.DeviceConfigs)(nil)
+	_ trackingScheduleRepository  = (*memrepo.TrackingSchedules)(nil)
+	_ deviceReportRepository      = (*memrepo.DeviceReports)(nil)
+	_ latestReportRepository      = (*memrepo.DeviceReports)(nil)
+	_ reportedConfigReader        = (*memrepo.DeviceReports)(nil)
+	_ deviceCommandRepository     = (*memrepo.DeviceCommands)(nil)
+	_ appUpdateCommandRepository  = (*memrepo.DeviceCommands)(nil)
+	_ locationRequestRepository   = (*memrepo.LocationRequests)(nil)
+	_ alertLocationRequestSource  = (*memrepo.LocationRequests)(nil)
+	_ alertRepository             = (*memrepo.Alerts)(nil)
+	_ notificationRepository      = (*memrepo.Notifications)(nil)
+	_ appReleaseRepository        = (*memrepo.AppReleases)(nil)
+)
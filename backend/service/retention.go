@@ -0,0 +1,147 @@
+package service
+
+import (
+	"time"
+
+	"locator/models"
+)
+
+// Стоянка при прореживании — как у «значимых» точек: не меньше трёх точек в радиусе
+// 100 м от центра за 15 минут и больше, без разрывов длиннее часа.
+const (
+	retentionStayRadius      = 100.0
+	retentionStayMinDuration = 15 * time.Minute
+	retentionStayMinPoints   = 3
+	retentionStayMaxGap      = time.Hour
+)
+
+// downsampleLocations делит точки одного пользователя (по возрастанию времени) на
+// оставляемые и удаляемые. На стоянке остаются прибытие, отъезд и ближайшая к центру
+// стоянки точка, в движении — не чаще одной за step. Первая и последняя точка
+// выборки, ответы на on-demand запросы и точки по обе стороны входа в чекпоинт и
+// выхода из него (границы участков вне чекпоинтов) сохраняются всегда; маскированные
+// точки (только факт связи) — не чаще одной за step. step <= 0 — прореживание выключено.
+func downsampleLocations(points []models.Location, step time.Duration, checkpoints []models.Checkpoint) (keep, drop []int) {
+	if len(points) == 0 {
+		return nil, nil
+	}
+	if step <= 0 {
+		for _, p := range points {
+			keep = append(keep, p.ID)
+		}
+		return keep, nil
+	}
+
+	kept := make(map[int]bool, len(points))
+	kept[points[0].ID] = true
+	kept[points[len(points)-1].ID] = true
+
+	var tracked []models.Location
+	var lastMasked time.Time
+	for _, p := range points {
+		switch {
+		case p.RequestID != "":
+			kept[p.ID] = true
+		case p.Masked:
+			if lastMasked.IsZero() || p.EffectiveAt().Sub(lastMasked) >= step {
+				kept[p.ID] = true
+				lastMasked = p.EffectiveAt()
+			}
+		}
+		if !p.Masked {
+			tracked = append(tracked, p)
+		}
+	}
+
+	if len(checkpoints) > 0 {
+		for i := 1; i < len(tracked); i++ {
+			prev, cur := tracked[i-1], tracked[i]
+			if isInsideAnyCheckpoint(prev.Latitude, prev.Longitude, checkpoints) !=
+				isInsideAnyCheckpoint(cur.Latitude, cur.Longitude, checkpoints) {
+				kept[prev.ID] = true
+				kept[cur.ID] = true
+			}
+		}
+	}
+
+	var lastKept time.Time
+	keepMoving := func(p models.Location) {
+		if lastKept.IsZero() || p.EffectiveAt().Sub(lastKept) >= step {
+			kept[p.ID] = true
+			lastKept = p.EffectiveAt()
+		}
+	}
+	for _, cluster := range splitRetentionClusters(tracked) {
+		if !isRetentionStay(cluster) {
+			for _, p := range cluster {
+				keepMoving(p)
+			}
+			continue
+		}
+		first, last := cluster[0], cluster[len(cluster)-1]
+		kept[first.ID] = true
+		kept[last.ID] = true
+		kept[nearestToCenter(cluster).ID] = true
+		lastKept = last.EffectiveAt()
+	}
+
+	for _, p := range points {
+		if kept[p.ID] {
+			keep = append(keep, p.ID)
+		} else {
+			drop = append(drop, p.ID)
+		}
+	}
+	return keep, drop
+}
+
+// splitRetentionClusters — подряд идущие точки в радиусе стоянки от центра группы.
+func splitRetentionClusters(points []models.Location) [][]models.Location {
+	var clusters [][]models.Location
+	var current []models.Location
+	var sumLat, sumLon float64
+	for _, p := range points {
+		if len(current) > 0 {
+			n := float64(len(current))
+			last := current[len(current)-1]
+			far := haversineDistance(sumLat/n, sumLon/n, p.Latitude, p.Longitude) > retentionStayRadius
+			if far || p.EffectiveAt().Sub(last.EffectiveAt()) > retentionStayMaxGap {
+				clusters = append(clusters, current)
+				current, sumLat, sumLon = nil, 0, 0
+			}
+		}
+		current = append(current, p)
+		sumLat += p.Latitude
+		sumLon += p.Longitude
+	}
+	if len(current) > 0 {
+		clusters = append(clusters, current)
+	}
+	return clusters
+}
+
+func isRetentionStay(cluster []models.Location) bool {
+	if len(cluster) < retentionStayMinPoints {
+		return false
+	}
+	return cluster[len(cluster)-1].EffectiveAt().Sub(cluster[0].EffectiveAt()) >= retentionStayMinDuration
+}
+
+// nearestToCenter — точка стоянки, ближайшая к её центру (координаты не усредняются,
+// чтобы сохранённая точка оставалась реальным замером).
+func nearestToCenter(cluster []models.Location) models.Location {
+	var sumLat, sumLon float64
+	for _, p := range cluster {
+		sumLat += p.Latitude
+		sumLon += p.Longitude
+	}
+	n := float64(len(cluster))
+	best, bestDist := cluster[0], -1.0
+	for _, p := range cluster {
+		d := haversineDistance(sumLat/n, sumLon/n, p.Latitude, p.Longitude)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = p, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,672 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRetentionRunning       = errors.New("retention run already in progress")
+	ErrRetentionRunNotFound   = errors.New("retention run not found")
+	ErrRetentionPolicyInvalid = errors.New("invalid retention policy")
+)
+
+const (
+	retentionDefaultInterval = 24 * time.Hour
+	// retentionBatchSize — точек пользователя за один запрос к БД.
+	retentionBatchSize = 5000
+	retentionRunsLimit = 20
+	// retentionPartitionsAhead — на сколько месяцев вперёд заводятся секции locations.
+	retentionPartitionsAhead = 3
+)
+
+// retentionCheckpointSource — чекпоинты организации (CheckpointService): точки на
+// границах чекпоинтов не прореживаются.
+type retentionCheckpointSource interface {
+	GetCheckpoints(organizationID int) ([]models.Checkpoint, error)
+}
+
+// RetentionPolicyInput — политика хранения, заданная с админки.
+type RetentionPolicyInput struct {
+	Enabled           *bool `json:"enabled"`
+	RawDays           int   `json:"raw_days"`
+	DownsampleMinutes int   `json:"downsample_minutes"`
+	PurgeDays         int   `json:"purge_days"`
+	Archive           bool  `json:"archive"`
+}
+
+// RetentionService — сроки хранения точек: прореживание старых точек, удаление и
+// архивирование самых старых. Визиты хранятся отдельно и не затрагиваются; последняя
+// точка пользователя (текущее положение) не удаляется никогда.
+type RetentionService struct {
+	DAO       retentionRepository
+	Locations retentionLocationRepository
+	Users     userRepository
+	// Organizations — список организаций для прохода (nil — только организация по умолчанию).
+	Organizations organizationRepository
+	// Checkpoints — границы чекпоинтов сохраняются при прореживании (nil — не учитываются).
+	Checkpoints retentionCheckpointSource
+	// Defaults — политика организаций без своей записи (RETENTION_* из окружения).
+	Defaults models.RetentionPolicy
+	// ArchiveDir — каталог gzip-архивов удалённых точек.
+	ArchiveDir string
+	// Partitions — месячные секции locations: фоновый проход заводит будущие, а секции
+	// за сроком хранения всех организаций удаляет целиком (nil — таблица не секционирована).
+	Partitions locationPartitionRepository
+	// Clock — текущее время (nil — системное).
+	Clock Clock
+
+	running atomic.Bool
+}
+
+func NewRetentionService(dao retentionRepository, locations retentionLocationRepository, users userRepository) *RetentionService {
+	return &RetentionService{
+		DAO:        dao,
+		Locations:  locations,
+		Users:      users,
+		Defaults:   models.RetentionPolicy{Enabled: true, DownsampleMinutes: 5},
+		ArchiveDir: "archive/locations",
+	}
+}
+
+// Policy — действующая политика организации; custom — задана ли своя запись.
+func (svc *RetentionService) Policy(organizationID int) (policy *models.RetentionPolicy, custom bool, err error) {
+	organizationID = organizationOrDefault(organizationID)
+	p, err := svc.DAO.GetPolicy(organizationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		def := svc.Defaults
+		def.OrganizationID = organizationID
+		return &def, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return p, true, nil
+}
+
+// ViewPolicy — Policy с проверкой доступа: своя организация или администратор платформы.
+func (svc *RetentionService) ViewPolicy(actor *models.User, organizationID int) (*models.RetentionPolicy, bool, error) {
+	if !actor.IsPlatformAdmin() && organizationOrDefault(actor.OrganizationID) != organizationOrDefault(organizationID) {
+		return nil, false, ErrAccessDenied
+	}
+	return svc.Policy(organizationID)
+}
+
+// SavePolicy задаёт политику организации: своей — её администратору, любой —
+// администратору платформы.
+func (svc *RetentionService) SavePolicy(actor *models.User, organizationID int, in RetentionPolicyInput) (*models.RetentionPolicy, error) {
+	organizationID = organizationOrDefault(organizationID)
+	if !actor.IsPlatformAdmin() && organizationOrDefault(actor.OrganizationID) != organizationID {
+		return nil, ErrAccessDenied
+	}
+	if in.RawDays < 0 || in.PurgeDays < 0 || in.DownsampleMinutes < 0 {
+		return nil, fmt.Errorf("%w: сроки не могут быть отрицательными", ErrRetentionPolicyInvalid)
+	}
+	if in.RawDays > 0 && in.DownsampleMinutes == 0 {
+		return nil, fmt.Errorf("%w: укажите downsample_minutes", ErrRetentionPolicyInvalid)
+	}
+	if in.PurgeDays > 0 && in.PurgeDays <= in.RawDays {
+		return nil, fmt.Errorf("%w: purge_days должен быть больше raw_days", ErrRetentionPolicyInvalid)
+	}
+	p := &models.RetentionPolicy{
+		OrganizationID:    organizationID,
+		Enabled:           in.Enabled == nil || *in.Enabled,
+		RawDays:           in.RawDays,
+		DownsampleMinutes: in.DownsampleMinutes,
+		PurgeDays:         in.PurgeDays,
+		Archive:           in.Archive,
+	}
+	if err := svc.DAO.SavePolicy(p); err != nil {
+		return nil, err
+	}
+	slog.Info("Политика хранения изменена", "organization_id", p.OrganizationID, "raw_days", p.RawDays,
+		"downsample_minutes", p.DownsampleMinutes, "purge_days", p.PurgeDays, "archive", p.Archive,
+		"enabled", p.Enabled, "actor_id", actor.ID)
+	return p, nil
+}
+
+// DeletePolicy возвращает организации политику по умолчанию.
+func (svc *RetentionService) DeletePolicy(actor *models.User, organizationID int) error {
+	organizationID = organizationOrDefault(organizationID)
+	if !actor.IsPlatformAdmin() && organizationOrDefault(actor.OrganizationID) != organizationID {
+		return ErrAccessDenied
+	}
+	return svc.DAO.DeletePolicy(organizationID)
+}
+
+func (svc *RetentionService) GetRun(id int64) (*models.RetentionRun, error) {
+	run, err := svc.DAO.GetRun(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRetentionRunNotFound
+	}
+	return run, err
+}
+
+// ListRuns — последние проходы, новые первыми.
+func (svc *RetentionService) ListRuns() ([]models.RetentionRun, error) {
+	return svc.DAO.ListRuns(retentionRunsLimit)
+}
+
+// Run — фоновый проход раз в interval по всем организациям.
+func (svc *RetentionService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = retentionDefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		svc.ensurePartitions()
+		if _, err := svc.Execute(ctx, nil, false); err != nil && !errors.Is(err, ErrRetentionRunning) {
+			slog.Error("Ошибка прохода хранения", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Start запускает проход в фоне и сразу возвращает его запись (прогресс — GetRun).
+// organizationID nil — все организации; dryRun — только подсчёт.
+func (svc *RetentionService) Start(organizationID *int, dryRun bool) (*models.RetentionRun, error) {
+	run, err := svc.begin(organizationID, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := *run
+	go func() {
+		if err := svc.execute(context.Background(), run); err != nil {
+			slog.Error("Проход хранения завершился ошибкой", "run_id", run.ID, "error", err)
+		}
+	}()
+	return &snapshot, nil
+}
+
+// Execute выполняет проход синхронно и возвращает его итог.
+func (svc *RetentionService) Execute(ctx context.Context, organizationID *int, dryRun bool) (*models.RetentionRun, error) {
+	run, err := svc.begin(organizationID, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	err = svc.execute(ctx, run)
+	return run, err
+}
+
+// begin занимает единственный слот прохода и создаёт его запись.
+func (svc *RetentionService) begin(organizationID *int, dryRun bool) (*models.RetentionRun, error) {
+	if !svc.running.CompareAndSwap(false, true) {
+		return nil, ErrRetentionRunning
+	}
+	run := &models.RetentionRun{
+		OrganizationID: organizationID,
+		DryRun:         dryRun,
+		Status:         models.RetentionRunRunning,
+		StartedAt:      clockNow(svc.Clock),
+	}
+	if err := svc.DAO.CreateRun(run); err != nil {
+		svc.running.Store(false)
+		return nil, err
+	}
+	return run, nil
+}
+
+// retentionJob — пользователи одной организации и её политика.
+type retentionJob struct {
+	policy      models.RetentionPolicy
+	users       []models.User
+	checkpoints []models.Checkpoint
+}
+
+func (svc *RetentionService) execute(ctx context.Context, run *models.RetentionRun) (err error) {
+	defer svc.running.Store(false)
+	defer func() {
+		finished := clockNow(svc.Clock)
+		run.FinishedAt = &finished
+		run.Status = models.RetentionRunDone
+		if err != nil {
+			run.Status = models.RetentionRunFailed
+			run.Error = err.Error()
+		}
+		if uerr := svc.DAO.UpdateRun(run); uerr != nil {
+			slog.Error("Не удалось сохранить итог прохода хранения", "run_id", run.ID, "error", uerr)
+		}
+		slog.Info("Проход хранения завершён", "run_id", run.ID, "dry_run", run.DryRun, "status", run.Status,
+			"users_done", run.UsersDone, "users_total", run.UsersTotal, "scanned", run.Scanned,
+			"downsampled", run.Downsampled, "purged", run.Purged, "archived", run.Archived)
+	}()
+
+	jobs, err := svc.jobs(run.OrganizationID)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		run.UsersTotal += len(job.users)
+	}
+	if err := svc.DAO.UpdateRun(run); err != nil {
+		return err
+	}
+
+	now := clockNow(svc.Clock)
+	if svc.Partitions != nil && !run.DryRun && run.OrganizationID == nil {
+		// Секции общие для всех организаций: удалять их целиком можно только за самым
+		// длинным сроком хранения. Пробный проход считает те же точки построчно.
+		before, ok, err := svc.partitionHorizon(now)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := svc.dropExpiredPartitions(ctx, run, jobs, now, before); err != nil {
+				return err
+			}
+		}
+	}
+	for _, job := range jobs {
+		if err := svc.processOrganization(ctx, run, job, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// partitionHorizon — граница, до которой точки удаляются во всех организациях: самый
+// длинный PurgeDays. ok=false — есть организация, чьи точки хранятся бессрочно.
+func (svc *RetentionService) partitionHorizon(now time.Time) (before time.Time, ok bool, err error) {
+	orgIDs, err := svc.organizationIDs(nil)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	purgeDays := 0
+	for _, id := range orgIDs {
+		policy, _, err := svc.Policy(id)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if !policy.Enabled || policy.PurgeDays <= 0 {
+			return time.Time{}, false, nil
+		}
+		purgeDays = max(purgeDays, policy.PurgeDays)
+	}
+	return now.AddDate(0, 0, -purgeDays), purgeDays > 0, nil
+}
+
+// dropExpiredPartitions удаляет месячные секции locations, закончившиеся до before:
+// секция отсоединяется, её точки архивируются по политике организации пользователя,
+// последняя точка пользователя возвращается в таблицу, секция удаляется. Точки
+// граничного месяца и locations_default удаляет построчный проход. Секция, оставшаяся
+// отсоединённой после ошибки, обрабатывается следующим проходом.
+func (svc *RetentionService) dropExpiredPartitions(ctx context.Context, run *models.RetentionRun, jobs []retentionJob, now, before time.Time) error {
+	names, err := svc.Partitions.ExpiredPartitions(before)
+	if err != nil || len(names) == 0 {
+		return err
+	}
+	policies := make(map[int]models.RetentionPolicy)
+	for _, job := range jobs {
+		for _, user := range job.users {
+			policies[user.ID] = job.policy
+		}
+	}
+	archives := make(map[int]*retentionArchive)
+	defer func() {
+		for _, archive := range archives {
+			if err := archive.close(); err != nil {
+				slog.Error("Ошибка закрытия архива", "path", archive.path, "error", err)
+			}
+		}
+	}()
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := svc.Partitions.DetachPartition(name); err != nil {
+			return fmt.Errorf("секция %s: %w", name, err)
+		}
+		keep, err := svc.Partitions.LatestInPartition(name)
+		if err != nil {
+			return fmt.Errorf("секция %s: %w", name, err)
+		}
+		kept := make(map[int]bool, len(keep))
+		for _, id := range keep {
+			kept[id] = true
+		}
+
+		var purged int64
+		afterID := 0
+		for {
+			batch, err := svc.Partitions.PartitionLocations(name, afterID, retentionBatchSize)
+			if err != nil {
+				return fmt.Errorf("секция %s: %w", name, err)
+			}
+			if len(batch) == 0 {
+				break
+			}
+			afterID = batch[len(batch)-1].ID
+			run.Scanned += int64(len(batch))
+
+			byOrganization := make(map[int][]models.Location)
+			for _, p := range batch {
+				if kept[p.ID] {
+					continue
+				}
+				purged++
+				policy, ok := policies[p.UserID]
+				if !ok {
+					// Пользователь появился после начала прохода — точки сохраняются в архив.
+					policy = models.RetentionPolicy{OrganizationID: models.DefaultOrganizationID, Archive: true}
+				}
+				if policy.Archive {
+					byOrganization[policy.OrganizationID] = append(byOrganization[policy.OrganizationID], p)
+				}
+			}
+			for organizationID, points := range byOrganization {
+				archive := archives[organizationID]
+				if archive == nil {
+					if archive, err = openRetentionArchive(svc.ArchiveDir, organizationID, run.ID, now); err != nil {
+						return err
+					}
+					archives[organizationID] = archive
+					addArchiveFile(run, archive.path)
+				}
+				if err := archive.write(points); err != nil {
+					return err
+				}
+				run.Archived += int64(len(points))
+			}
+		}
+
+		if err := svc.Partitions.DropPartition(name, keep); err != nil {
+			return fmt.Errorf("секция %s: %w", name, err)
+		}
+		run.Purged += purged
+		if err := svc.DAO.UpdateRun(run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensurePartitions заводит секции locations на ближайшие месяцы, чтобы новые точки
+// не копились в locations_default.
+func (svc *RetentionService) ensurePartitions() {
+	if svc.Partitions == nil {
+		return
+	}
+	now := clockNow(svc.Clock)
+	created, err := svc.Partitions.EnsurePartitions(now, now.AddDate(0, retentionPartitionsAhead, 0))
+	if err != nil {
+		slog.Error("Ошибка создания секций locations", "error", err)
+		return
+	}
+	if created > 0 {
+		slog.Info("Созданы секции locations", "created", created)
+	}
+}
+
+// organizationIDs — организации прохода: одна заданная или все.
+func (svc *RetentionService) organizationIDs(organizationID *int) ([]int, error) {
+	switch {
+	case organizationID != nil:
+		return []int{organizationOrDefault(*organizationID)}, nil
+	case svc.Organizations != nil:
+		orgs, err := svc.Organizations.GetAllOrganizations()
+		if err != nil {
+			return nil, err
+		}
+		orgIDs := make([]int, 0, len(orgs))
+		for _, org := range orgs {
+			orgIDs = append(orgIDs, org.ID)
+		}
+		return orgIDs, nil
+	default:
+		return []int{models.DefaultOrganizationID}, nil
+	}
+}
+
+// jobs — организации прохода, для которых политика что-то делает.
+func (svc *RetentionService) jobs(organizationID *int) ([]retentionJob, error) {
+	orgIDs, err := svc.organizationIDs(organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []retentionJob
+	for _, id := range orgIDs {
+		policy, _, err := svc.Policy(id)
+		if err != nil {
+			return nil, err
+		}
+		if !policy.Enabled || (policy.RawDays == 0 && policy.PurgeDays == 0) {
+			continue
+		}
+		users, err := svc.Users.GetAllByOrganization(id)
+		if err != nil {
+			return nil, err
+		}
+		job := retentionJob{policy: *policy, users: users}
+		if svc.Checkpoints != nil && policy.RawDays > 0 {
+			if job.checkpoints, err = svc.Checkpoints.GetCheckpoints(id); err != nil {
+				return nil, err
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (svc *RetentionService) processOrganization(ctx context.Context, run *models.RetentionRun, job retentionJob, now time.Time) error {
+	var archive *retentionArchive
+	defer func() {
+		if archive != nil {
+			if err := archive.close(); err != nil {
+				slog.Error("Ошибка закрытия архива", "path", archive.path, "error", err)
+			}
+		}
+	}()
+	openArchive := func() (*retentionArchive, error) {
+		if archive == nil {
+			a, err := openRetentionArchive(svc.ArchiveDir, job.policy.OrganizationID, run.ID, now)
+			if err != nil {
+				return nil, err
+			}
+			archive = a
+			addArchiveFile(run, a.path)
+		}
+		return archive, nil
+	}
+
+	for _, user := range job.users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := svc.processUser(run, job, user.ID, now, openArchive); err != nil {
+			return fmt.Errorf("пользователь ID=%d: %w", user.ID, err)
+		}
+		run.UsersDone++
+		if err := svc.DAO.UpdateRun(run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processUser удаляет (и архивирует) точки старше PurgeDays, затем прореживает точки
+// старше RawDays. Последняя точка пользователя не трогается.
+func (svc *RetentionService) processUser(
+	run *models.RetentionRun,
+	job retentionJob,
+	userID int,
+	now time.Time,
+	openArchive func() (*retentionArchive, error),
+) error {
+	policy := job.policy
+	protected := 0
+	if latest, err := svc.Locations.GetByUserID(userID); err == nil {
+		protected = latest.ID
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	var purgeBefore time.Time
+	if policy.PurgeDays > 0 {
+		purgeBefore = now.AddDate(0, 0, -policy.PurgeDays)
+		afterID := 0
+		for {
+			batch, err := svc.Locations.GetOlderThan(userID, purgeBefore, afterID, retentionBatchSize)
+			if err != nil {
+				return err
+			}
+			if len(batch) == 0 {
+				break
+			}
+			afterID = batch[len(batch)-1].ID
+			run.Scanned += int64(len(batch))
+
+			victims := make([]models.Location, 0, len(batch))
+			for _, p := range batch {
+				if p.ID != protected {
+					victims = append(victims, p)
+				}
+			}
+			if run.DryRun {
+				run.Purged += int64(len(victims))
+				if policy.Archive {
+					run.Archived += int64(len(victims))
+				}
+				continue
+			}
+			if policy.Archive && len(victims) > 0 {
+				archive, err := openArchive()
+				if err != nil {
+					return err
+				}
+				if err := archive.write(victims); err != nil {
+					return err
+				}
+				run.Archived += int64(len(victims))
+			}
+			deleted, err := svc.Locations.DeleteByIDs(locationIDs(victims))
+			if err != nil {
+				return err
+			}
+			run.Purged += deleted
+		}
+	}
+
+	if policy.RawDays <= 0 || policy.DownsampleMinutes <= 0 {
+		return nil
+	}
+	step := time.Duration(policy.DownsampleMinutes) * time.Minute
+	rawFrom := now.AddDate(0, 0, -policy.RawDays)
+	var afterAt time.Time
+	afterID := 0
+	for {
+		batch, err := svc.Locations.GetForDownsampling(userID, purgeBefore, rawFrom, afterAt, afterID, retentionBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		last := batch[len(batch)-1]
+		afterAt, afterID = last.EffectiveAt(), last.ID
+		run.Scanned += int64(len(batch))
+
+		keep, drop := downsampleLocations(batch, step, job.checkpoints)
+		drop = withoutID(drop, protected)
+		run.Downsampled += int64(len(drop))
+		if run.DryRun {
+			continue
+		}
+		if _, err := svc.Locations.DeleteByIDs(drop); err != nil {
+			return err
+		}
+		if err := svc.Locations.MarkDownsampled(keep); err != nil {
+			return err
+		}
+	}
+}
+
+// addArchiveFile добавляет архив в список файлов прохода (по строке на файл).
+func addArchiveFile(run *models.RetentionRun, path string) {
+	if slices.Contains(strings.Split(run.ArchiveFiles, "\n"), path) {
+		return
+	}
+	run.ArchiveFiles = strings.TrimPrefix(run.ArchiveFiles+"\n"+path, "\n")
+}
+
+func locationIDs(points []models.Location) []int {
+	ids := make([]int, len(points))
+	for i, p := range points {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func withoutID(ids []int, id int) []int {
+	out := ids[:0]
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// retentionArchive — удалённые точки организации за проход: gzip, по записи JSON на строку.
+type retentionArchive struct {
+	path string
+	file *os.File
+	gz   *gzip.Writer
+	enc  *json.Encoder
+}
+
+func openRetentionArchive(dir string, organizationID int, runID int64, now time.Time) (*retentionArchive, error) {
+	orgDir := filepath.Join(dir, fmt.Sprintf("org-%d", organizationID))
+	if err := os.MkdirAll(orgDir, 0o750); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(orgDir, fmt.Sprintf("locations-%s-run%d.jsonl.gz", now.UTC().Format("20060102"), runID))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	return &retentionArchive{path: path, file: file, gz: gz, enc: json.NewEncoder(gz)}, nil
+}
+
+// write дописывает точки и сбрасывает их на диск: удалять можно только после этого.
+func (a *retentionArchive) write(points []models.Location) error {
+	for i := range points {
+		if err := a.enc.Encode(&points[i]); err != nil {
+			return err
+		}
+	}
+	if err := a.gz.Flush(); err != nil {
+		return err
+	}
+	return a.file.Sync()
+}
+
+func (a *retentionArchive) close() error {
+	if err := a.gz.Close(); err != nil {
+		a.file.Close()
+		return err
+	}
+	return a.file.Close()
+}
@@ -0,0 +1,488 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"locator/internal/testutil"
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+type fakeRetentionRepo struct {
+	policies map[int]models.RetentionPolicy
+	runs     []models.RetentionRun
+}
+
+func newFakeRetentionRepo() *fakeRetentionRepo {
+	return &fakeRetentionRepo{policies: make(map[int]models.RetentionPolicy)}
+}
+
+func (f *fakeRetentionRepo) GetPolicy(organizationID int) (*models.RetentionPolicy, error) {
+	p, ok := f.policies[organizationID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &p, nil
+}
+
+func (f *fakeRetentionRepo) SavePolicy(p *models.RetentionPolicy) error {
+	f.policies[p.OrganizationID] = *p
+	return nil
+}
+
+func (f *fakeRetentionRepo) DeletePolicy(organizationID int) error {
+	delete(f.policies, organizationID)
+	return nil
+}
+
+func (f *fakeRetentionRepo) CreateRun(run *models.RetentionRun) error {
+	run.ID = int64(len(f.runs) + 1)
+	f.runs = append(f.runs, *run)
+	return nil
+}
+
+func (f *fakeRetentionRepo) UpdateRun(run *models.RetentionRun) error {
+	f.runs[run.ID-1] = *run
+	return nil
+}
+
+func (f *fakeRetentionRepo) GetRun(id int64) (*models.RetentionRun, error) {
+	if id <= 0 || int(id) > len(f.runs) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	run := f.runs[id-1]
+	return &run, nil
+}
+
+func (f *fakeRetentionRepo) ListRuns(limit int) ([]models.RetentionRun, error) {
+	return f.runs, nil
+}
+
+// fakeRetentionLocations — точки в памяти с той же семантикой выборок, что у LocationDAO.
+type fakeRetentionLocations struct {
+	points []models.Location
+}
+
+func (f *fakeRetentionLocations) GetByUserID(userID int) (*models.Location, error) {
+	var latest *models.Location
+	for i := range f.points {
+		if f.points[i].UserID == userID && (latest == nil || f.points[i].ID > latest.ID) {
+			latest = &f.points[i]
+		}
+	}
+	if latest == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return latest, nil
+}
+
+func (f *fakeRetentionLocations) GetForDownsampling(userID int, from, to, afterAt time.Time, afterID, limit int) ([]models.Location, error) {
+	var out []models.Location
+	for _, p := range f.points {
+		at := p.EffectiveAt()
+		after := at.After(afterAt) || (at.Equal(afterAt) && p.ID > afterID)
+		if p.UserID == userID && !p.Downsampled && !at.Before(from) && at.Before(to) && after {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].EffectiveAt().Before(out[j].EffectiveAt()) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (f *fakeRetentionLocations) GetOlderThan(userID int, before time.Time, afterID, limit int) ([]models.Location, error) {
+	var out []models.Location
+	for _, p := range f.points {
+		if p.UserID == userID && p.EffectiveAt().Before(before) && p.ID > afterID {
+			out = append(out, p)
+		}
+	}
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (f *fakeRetentionLocations) DeleteByIDs(ids []int) (int64, error) {
+	drop := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := f.points[:0]
+	for _, p := range f.points {
+		if !drop[p.ID] {
+			kept = append(kept, p)
+		}
+	}
+	deleted := int64(len(f.points) - len(kept))
+	f.points = kept
+	return deleted, nil
+}
+
+func (f *fakeRetentionLocations) MarkDownsampled(ids []int) error {
+	mark := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		mark[id] = true
+	}
+	for i := range f.points {
+		if mark[f.points[i].ID] {
+			f.points[i].Downsampled = true
+		}
+	}
+	return nil
+}
+
+// retentionTrack — час стоянки (точка в минуту), затем час движения на восток
+// (точка в минуту, ~150 м за минуту).
+func retentionTrack(userID int, start time.Time) []models.Location {
+	var points []models.Location
+	for i := 0; i < 60; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		points = append(points, models.Location{
+			ID: len(points) + 1, UserID: userID, Latitude: 53.9 + float64(i%3)*0.0001, Longitude: 27.56, CapturedAt: &at,
+		})
+	}
+	for i := 1; i <= 60; i++ {
+		at := start.Add(time.Duration(59+i) * time.Minute)
+		points = append(points, models.Location{
+			ID: len(points) + 1, UserID: userID, Latitude: 53.9, Longitude: 27.56 + float64(i)*0.0023, CapturedAt: &at,
+		})
+	}
+	return points
+}
+
+func TestDownsampleLocations_keepsStayAndThinsTrip(t *testing.T) {
+	start := time.Date(2026, 1, 10, 8, 0, 0, 0, time.UTC)
+	points := retentionTrack(1, start)
+	points[70].RequestID = "req-1"
+
+	keep, drop := downsampleLocations(points, 5*time.Minute, nil)
+	if len(keep)+len(drop) != len(points) {
+		t.Fatalf("keep=%d drop=%d, total %d", len(keep), len(drop), len(points))
+	}
+	kept := make(map[int]bool)
+	for _, id := range keep {
+		kept[id] = true
+	}
+	for _, id := range []int{1, 60, 71, 120} {
+		if !kept[id] {
+			t.Errorf("point %d must be kept (stay arrival/departure, on-demand, last)", id)
+		}
+	}
+	stay := 0
+	for id := 1; id <= 60; id++ {
+		if kept[id] {
+			stay++
+		}
+	}
+	if stay > 3 {
+		t.Errorf("stay kept %d points, want arrival, departure and centroid", stay)
+	}
+	// Час движения с шагом 5 минут — около 12 точек (плюс on-demand).
+	if trip := len(keep) - stay; trip < 11 || trip > 15 {
+		t.Errorf("trip kept %d points, want ~12", trip)
+	}
+
+	if keep, drop := downsampleLocations(points, 0, nil); len(keep) != len(points) || len(drop) != 0 {
+		t.Fatalf("step 0 must keep everything: keep=%d drop=%d", len(keep), len(drop))
+	}
+}
+
+func TestDownsampleLocations_keepsCheckpointBoundaries(t *testing.T) {
+	start := time.Date(2026, 1, 10, 8, 0, 0, 0, time.UTC)
+	points := retentionTrack(1, start)
+	// Чекпоинт вокруг 20-й минуты поездки: вход и выход — точки по обе стороны границы.
+	cp := models.Checkpoint{Latitude: 53.9, Longitude: 27.56 + 20*0.0023, Radius: 200}
+
+	keep, _ := downsampleLocations(points, 30*time.Minute, []models.Checkpoint{cp})
+	kept := make(map[int]bool)
+	for _, id := range keep {
+		kept[id] = true
+	}
+	var boundary []int
+	for i := 1; i < len(points); i++ {
+		a := isInsideAnyCheckpoint(points[i-1].Latitude, points[i-1].Longitude, []models.Checkpoint{cp})
+		b := isInsideAnyCheckpoint(points[i].Latitude, points[i].Longitude, []models.Checkpoint{cp})
+		if a != b {
+			boundary = append(boundary, points[i-1].ID, points[i].ID)
+		}
+	}
+	if len(boundary) == 0 {
+		t.Fatal("test track never crosses the checkpoint")
+	}
+	for _, id := range boundary {
+		if !kept[id] {
+			t.Errorf("boundary point %d dropped", id)
+		}
+	}
+}
+
+func TestRetentionExecute_dryRunThenPurgeWithArchive(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	old := retentionTrack(1, now.AddDate(0, 0, -400))
+	recent := retentionTrack(1, now.AddDate(0, 0, -60))
+	for i := range recent {
+		recent[i].ID += len(old)
+	}
+	locations := &fakeRetentionLocations{points: append(append([]models.Location{}, old...), recent...)}
+	repo := newFakeRetentionRepo()
+	repo.policies[models.DefaultOrganizationID] = models.RetentionPolicy{
+		OrganizationID: models.DefaultOrganizationID, Enabled: true, RawDays: 30, DownsampleMinutes: 5, PurgeDays: 365, Archive: true,
+	}
+	svc := NewRetentionService(repo, locations, newFakeUserRepo(models.User{ID: 1, OrganizationID: models.DefaultOrganizationID}))
+	svc.ArchiveDir = t.TempDir()
+	svc.Clock = testutil.NewClock(now)
+
+	dry, err := svc.Execute(context.Background(), nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.Status != models.RetentionRunDone || dry.Purged != int64(len(old)) || dry.Downsampled == 0 || dry.UsersDone != 1 {
+		t.Fatalf("dry run: %+v", dry)
+	}
+	if len(locations.points) != len(old)+len(recent) || dry.ArchiveFiles != "" {
+		t.Fatalf("dry run changed data: %d points, files %q", len(locations.points), dry.ArchiveFiles)
+	}
+
+	run, err := svc.Execute(context.Background(), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Purged != dry.Purged || run.Archived != dry.Purged || run.Downsampled != dry.Downsampled {
+		t.Fatalf("run %+v differs from dry run %+v", run, dry)
+	}
+	if want := len(recent) - int(run.Downsampled); len(locations.points) != want {
+		t.Fatalf("%d points left, want %d", len(locations.points), want)
+	}
+	for _, p := range locations.points {
+		if !p.Downsampled && p.ID != len(old)+len(recent) {
+			t.Fatalf("kept point %d not marked downsampled", p.ID)
+		}
+	}
+
+	f, err := os.Open(run.ArchiveFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(gz)
+	archived := 0
+	for {
+		var p models.Location
+		if err := dec.Decode(&p); err != nil {
+			break
+		}
+		archived++
+	}
+	if archived != len(old) || !strings.Contains(run.ArchiveFiles, "org-1") {
+		t.Fatalf("archive %s holds %d points, want %d", run.ArchiveFiles, archived, len(old))
+	}
+
+	// Повторный проход ничего не находит: прореженные точки не пересматриваются.
+	again, err := svc.Execute(context.Background(), nil, false)
+	if err != nil || again.Downsampled != 0 || again.Purged != 0 {
+		t.Fatalf("second run: %+v, %v", again, err)
+	}
+}
+
+func TestRetentionExecute_keepsLatestPoint(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	at := now.AddDate(-2, 0, 0)
+	locations := &fakeRetentionLocations{points: []models.Location{{ID: 1, UserID: 1, CapturedAt: &at}}}
+	repo := newFakeRetentionRepo()
+	svc := NewRetentionService(repo, locations, newFakeUserRepo(models.User{ID: 1, OrganizationID: models.DefaultOrganizationID}))
+	svc.Defaults.PurgeDays = 90
+	svc.Clock = testutil.NewClock(now)
+
+	if _, err := svc.Execute(context.Background(), nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(locations.points) != 1 {
+		t.Fatal("the only (current) point of a user must survive purge")
+	}
+}
+
+func TestRetentionPolicy_accessAndValidation(t *testing.T) {
+	repo := newFakeRetentionRepo()
+	svc := NewRetentionService(repo, &fakeRetentionLocations{}, newFakeUserRepo())
+
+	owner := &models.User{ID: 1, Role: models.RoleFleetAdmin, OrganizationID: 2}
+	if _, err := svc.SavePolicy(owner, 3, RetentionPolicyInput{RawDays: 30, DownsampleMinutes: 5}); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("foreign organization: err=%v, want ErrAccessDenied", err)
+	}
+	if _, err := svc.SavePolicy(owner, 2, RetentionPolicyInput{RawDays: 30, DownsampleMinutes: 5, PurgeDays: 10}); !errors.Is(err, ErrRetentionPolicyInvalid) {
+		t.Fatalf("purge before raw: err=%v", err)
+	}
+	if _, err := svc.SavePolicy(owner, 2, RetentionPolicyInput{RawDays: 30, DownsampleMinutes: 5, PurgeDays: 365}); err != nil {
+		t.Fatal(err)
+	}
+	if p, custom, err := svc.Policy(2); err != nil || !custom || p.PurgeDays != 365 || !p.Enabled {
+		t.Fatalf("policy=%+v custom=%v err=%v", p, custom, err)
+	}
+	if err := svc.DeletePolicy(owner, 2); err != nil {
+		t.Fatal(err)
+	}
+	if p, custom, _ := svc.Policy(2); custom || p.RawDays != 0 || p.OrganizationID != 2 {
+		t.Fatalf("after delete: policy=%+v custom=%v", p, custom)
+	}
+
+	svc.running.Store(true)
+	if _, err := svc.Start(nil, true); !errors.Is(err, ErrRetentionRunning) {
+		t.Fatalf("concurrent run: err=%v", err)
+	}
+}
+
+// fakeLocationPartitions — месячные секции в памяти: имя → точки.
+type fakeLocationPartitions struct {
+	parts    map[string][]models.Location
+	detached []string
+	dropped  map[string][]int
+	ensured  [][2]time.Time
+}
+
+func (f *fakeLocationPartitions) EnsurePartitions(from, to time.Time) (int, error) {
+	f.ensured = append(f.ensured, [2]time.Time{from, to})
+	return 0, nil
+}
+
+func (f *fakeLocationPartitions) ExpiredPartitions(before time.Time) ([]string, error) {
+	var names []string
+	for name := range f.parts {
+		month, err := time.Parse("200601", strings.TrimPrefix(name, "locations_p"))
+		if err == nil && !month.AddDate(0, 1, 0).After(before) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (f *fakeLocationPartitions) DetachPartition(name string) error {
+	f.detached = append(f.detached, name)
+	return nil
+}
+
+func (f *fakeLocationPartitions) LatestInPartition(name string) ([]int, error) {
+	latest := make(map[int]models.Location)
+	for _, p := range f.parts[name] {
+		cur, ok := latest[p.UserID]
+		newer := !ok || p.EffectiveAt().After(cur.EffectiveAt()) || (p.EffectiveAt().Equal(cur.EffectiveAt()) && p.ID > cur.ID)
+		if !p.Masked && newer {
+			latest[p.UserID] = p
+		}
+	}
+	var ids []int
+	for _, p := range latest {
+		ids = append(ids, p.ID)
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+func (f *fakeLocationPartitions) PartitionLocations(name string, afterID, limit int) ([]models.Location, error) {
+	var out []models.Location
+	for _, p := range f.parts[name] {
+		if p.ID > afterID {
+			out = append(out, p)
+		}
+	}
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (f *fakeLocationPartitions) DropPartition(name string, keep []int) error {
+	if f.dropped == nil {
+		f.dropped = make(map[string][]int)
+	}
+	f.dropped[name] = keep
+	delete(f.parts, name)
+	return nil
+}
+
+func TestRetentionExecute_dropsPartitionsPastLongestHorizon(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	old := time.Date(2025, 8, 10, 12, 0, 0, 0, time.UTC) // до now-400 дней (2025-09-14)
+	repo := newFakeRetentionRepo()
+	repo.policies[2] = models.RetentionPolicy{OrganizationID: 2, Enabled: true, PurgeDays: 400, Archive: true}
+	orgs := &fakeOrganizationRepo{orgs: []models.Organization{{ID: models.DefaultOrganizationID}, {ID: 2}}}
+	partitions := &fakeLocationPartitions{parts: map[string][]models.Location{
+		"locations_p202508": {
+			{ID: 1, UserID: 1, CapturedAt: &old},
+			{ID: 2, UserID: 1, CapturedAt: &old},
+			{ID: 3, UserID: 2, CapturedAt: &old},
+			{ID: 4, UserID: 2, CapturedAt: ptrTime(old.Add(time.Hour))},
+		},
+		"locations_p202509": {{ID: 5, UserID: 1, CapturedAt: ptrTime(old.AddDate(0, 1, 0))}},
+	}}
+	users := newFakeUserRepo(
+		models.User{ID: 1, OrganizationID: models.DefaultOrganizationID},
+		models.User{ID: 2, OrganizationID: 2},
+	)
+	svc := NewRetentionService(repo, &fakeRetentionLocations{}, users)
+	svc.Organizations = orgs
+	svc.Partitions = partitions
+	svc.ArchiveDir = t.TempDir()
+	svc.Defaults.PurgeDays = 90
+	svc.Clock = testutil.NewClock(now)
+
+	if _, err := svc.Execute(context.Background(), nil, true); err != nil {
+		t.Fatal(err)
+	}
+	one := 2
+	if _, err := svc.Execute(context.Background(), &one, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions.detached) != 0 {
+		t.Fatal("dry and single-organization runs must not touch shared partitions")
+	}
+
+	// Организация без срока удаления — секции не трогаются.
+	repo.policies[3] = models.RetentionPolicy{OrganizationID: 3, Enabled: true, RawDays: 30, DownsampleMinutes: 5}
+	orgs.orgs = append(orgs.orgs, models.Organization{ID: 3})
+	if _, err := svc.Execute(context.Background(), nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions.detached) != 0 {
+		t.Fatal("partitions must be kept while some organization keeps points forever")
+	}
+
+	orgs.orgs = orgs.orgs[:2]
+	run, err := svc.Execute(context.Background(), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions.detached) != 1 || partitions.detached[0] != "locations_p202508" {
+		t.Fatalf("detached %v, want only the month before the longest horizon (400 days)", partitions.detached)
+	}
+	if keep := partitions.dropped["locations_p202508"]; len(keep) != 2 || keep[0] != 2 || keep[1] != 4 {
+		t.Fatalf("kept %v, want the latest point of each user", keep)
+	}
+	if run.Purged != 2 || run.Archived != 1 || run.ArchiveFiles == "" {
+		t.Fatalf("purged=%d archived=%d files=%q, want 2 purged and only organization 2 archived", run.Purged, run.Archived, run.ArchiveFiles)
+	}
+	if _, ok := partitions.parts["locations_p202509"]; !ok {
+		t.Fatal("partition inside the horizon must stay")
+	}
+
+	svc.ensurePartitions()
+	if len(partitions.ensured) != 1 || !partitions.ensured[0][1].Equal(now.AddDate(0, 3, 0)) {
+		t.Fatalf("ensured %v", partitions.ensured)
+	}
+}
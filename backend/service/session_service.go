@@ -0,0 +1,663 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"locator/models"
+)
+
+var (
+	ErrInvalidCredentials    = errors.New("неверный логин или пароль")
+	ErrTOTPRequired          = errors.New("требуется код двухфакторной аутентификации")
+	ErrInvalidTOTP           = errors.New("неверный код двухфакторной аутентификации")
+	ErrTOTPAlreadyEnabled    = errors.New("двухфакторная аутентификация уже включена")
+	ErrTOTPNotEnabled        = errors.New("двухфакторная аутентификация не включена")
+	ErrSessionInvalid        = errors.New("сессия недействительна или истекла")
+	ErrSessionNotFound       = errors.New("сессия не найдена")
+	ErrLoginLinkInvalid      = errors.New("ссылка для входа недействительна или истекла")
+	ErrUsernameEmpty         = errors.New("не указан логин")
+	ErrUsernameTaken         = errors.New("логин уже занят")
+	ErrPasswordTooShort      = errors.New("пароль слишком короткий")
+	ErrCredentialsNotAllowed = errors.New("вход по паролю доступен только сотрудникам")
+)
+
+const (
+	defaultAccessTTL    = 15 * time.Minute
+	defaultRefreshTTL   = 30 * 24 * time.Hour
+	defaultLoginLinkTTL = 24 * time.Hour
+	minPasswordLength   = 8
+	maxUsernameLength   = 100
+	// sessionTouchInterval — как часто обновлять last_used_at (не на каждый запрос).
+	sessionTouchInterval = time.Minute
+)
+
+// SessionService — вход сотрудников в веб-интерфейс по логину и паролю (или
+// одноразовой ссылке) с необязательным TOTP. Выдаёт короткоживущий подписанный
+// access-токен и refresh-токен; каждая проверка access-токена сверяется с сессией
+// в БД, поэтому выход и отзыв действуют сразу. API-ключи устройств здесь не участвуют.
+type SessionService struct {
+	Users    userRepository
+	Sessions sessionRepository
+	// TOTPIssuer — имя сервиса в приложении-аутентификаторе.
+	TOTPIssuer   string
+	AccessTTL    time.Duration
+	RefreshTTL   time.Duration
+	LoginLinkTTL time.Duration
+	// Clock — текущее время (nil — системное).
+	Clock Clock
+
+	secret []byte
+}
+
+// NewSessionService создаёт сервис сессий; secret подписывает access-токены.
+func NewSessionService(users userRepository, sessions sessionRepository, secret []byte) *SessionService {
+	return &SessionService{
+		Users:        users,
+		Sessions:     sessions,
+		TOTPIssuer:   "Locator",
+		AccessTTL:    defaultAccessTTL,
+		RefreshTTL:   defaultRefreshTTL,
+		LoginLinkTTL: defaultLoginLinkTTL,
+		secret:       secret,
+	}
+}
+
+// SessionMeta — сведения о клиенте, сохраняемые в сессии.
+type SessionMeta struct {
+	UserAgent string
+	IP        string
+}
+
+// SessionTokens — результат входа или обновления сессии.
+type SessionTokens struct {
+	AccessToken  string       `json:"access_token"`
+	RefreshToken string       `json:"refresh_token"`
+	TokenType    string       `json:"token_type"`
+	ExpiresIn    int          `json:"expires_in"`
+	SessionID    string       `json:"session_id"`
+	User         *models.User `json:"user"`
+}
+
+// accessClaims — содержимое access-токена.
+type accessClaims struct {
+	UserID    int    `json:"uid"`
+	SessionID string `json:"sid"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// NormalizeUsername — логины сравниваются без учёта регистра и пробелов по краям.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Login проверяет логин, пароль и (если включён) TOTP-код и открывает сессию.
+func (svc *SessionService) Login(username, password, totpCode string, meta SessionMeta) (*SessionTokens, error) {
+	user, err := svc.Users.GetByUsername(NormalizeUsername(username))
+	if err != nil || user.PasswordHash == "" {
+		// Сравнение с фиктивным хешем выравнивает время ответа для несуществующих логинов.
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		slog.Warn("Неверный пароль", "user_id", user.ID)
+		return nil, ErrInvalidCredentials
+	}
+	if !user.IsStaff() {
+		return nil, ErrCredentialsNotAllowed
+	}
+	if err := svc.checkTOTP(user, totpCode); err != nil {
+		return nil, err
+	}
+	return svc.openSession(user, meta)
+}
+
+// LoginWithLink входит по одноразовой ссылке. Если у сотрудника включён TOTP,
+// без кода ссылка не расходуется — её можно повторить вместе с кодом.
+func (svc *SessionService) LoginWithLink(token, totpCode string, meta SessionMeta) (*SessionTokens, error) {
+	if token == "" {
+		return nil, ErrLoginLinkInvalid
+	}
+	link, err := svc.Sessions.GetLoginLinkByHash(hashToken(token))
+	now := clockNow(svc.Clock)
+	if err != nil || link.UsedAt != nil || !now.Before(link.ExpiresAt) {
+		return nil, ErrLoginLinkInvalid
+	}
+	user, err := svc.Users.GetByID(link.UserID)
+	if err != nil || !user.IsStaff() {
+		return nil, ErrLoginLinkInvalid
+	}
+	if err := svc.checkTOTP(user, totpCode); err != nil {
+		return nil, err
+	}
+	// Ссылка расходуется условным UPDATE: из двух параллельных входов проходит один.
+	consumed, err := svc.Sessions.ConsumeLoginLink(link.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrLoginLinkInvalid
+	}
+	slog.Info("Вход по ссылке", "user_id", user.ID, "name", user.Name)
+	return svc.openSession(user, meta)
+}
+
+func (svc *SessionService) checkTOTP(user *models.User, code string) error {
+	if !user.TOTPEnabled {
+		return nil
+	}
+	if strings.TrimSpace(code) == "" {
+		return ErrTOTPRequired
+	}
+	return svc.useTOTP(user, code)
+}
+
+// useTOTP проверяет код и расходует его шаг: перехваченный код нельзя повторить,
+// пока он ещё попадает в окно ±totpSkew.
+func (svc *SessionService) useTOTP(user *models.User, code string) error {
+	step, ok := validateTOTP(user.TOTPSecret, code, clockNow(svc.Clock))
+	if !ok {
+		slog.Warn("Неверный TOTP-код", "user_id", user.ID)
+		return ErrInvalidTOTP
+	}
+	used, err := svc.Users.UseTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		slog.Warn("Повторно использован TOTP-код", "user_id", user.ID)
+		return ErrInvalidTOTP
+	}
+	return nil
+}
+
+func (svc *SessionService) openSession(user *models.User, meta SessionMeta) (*SessionTokens, error) {
+	now := clockNow(svc.Clock)
+	session := &models.UserSession{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		UserAgent:  truncate(meta.UserAgent, 255),
+		IP:         truncate(meta.IP, 64),
+		ExpiresAt:  now.Add(svc.RefreshTTL),
+		LastUsedAt: now,
+	}
+	refresh, err := newRefreshToken(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	session.RefreshTokenHash = hashToken(refresh)
+	if err := svc.Sessions.CreateSession(session); err != nil {
+		slog.Error("Ошибка создания сессии", "user_id", user.ID, "error", err)
+		return nil, err
+	}
+	slog.Info("Сессия открыта", "user_id", user.ID, "name", user.Name, "session_id", session.ID)
+	return svc.issueTokens(user, session, refresh)
+}
+
+func (svc *SessionService) issueTokens(user *models.User, session *models.UserSession, refresh string) (*SessionTokens, error) {
+	access, err := svc.signAccessToken(accessClaims{
+		UserID:    user.ID,
+		SessionID: session.ID,
+		ExpiresAt: clockNow(svc.Clock).Add(svc.AccessTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SessionTokens{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(svc.AccessTTL / time.Second),
+		SessionID:    session.ID,
+		User:         user,
+	}, nil
+}
+
+// Refresh выдаёт новую пару токенов и заменяет refresh-токен. Повторное
+// предъявление уже заменённого refresh-токена означает его утечку — сессия отзывается.
+func (svc *SessionService) Refresh(refreshToken string, meta SessionMeta) (*SessionTokens, error) {
+	sessionID, _, ok := strings.Cut(refreshToken, ".")
+	if !ok || sessionID == "" {
+		return nil, ErrSessionInvalid
+	}
+	session, err := svc.Sessions.GetSessionByID(sessionID)
+	now := clockNow(svc.Clock)
+	if err != nil || !session.Active(now) {
+		return nil, ErrSessionInvalid
+	}
+	previousHash := hashToken(refreshToken)
+	if subtle.ConstantTimeCompare([]byte(session.RefreshTokenHash), []byte(previousHash)) != 1 {
+		return nil, svc.refreshReused(session, now)
+	}
+	user, err := svc.Users.GetByID(session.UserID)
+	if err != nil || !user.IsStaff() {
+		return nil, ErrSessionInvalid
+	}
+
+	refresh, err := newRefreshToken(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	session.RefreshTokenHash = hashToken(refresh)
+	session.ExpiresAt = now.Add(svc.RefreshTTL)
+	session.LastUsedAt = now
+	if meta.UserAgent != "" {
+		session.UserAgent = truncate(meta.UserAgent, 255)
+	}
+	if meta.IP != "" {
+		session.IP = truncate(meta.IP, 64)
+	}
+	// Замена — условный UPDATE: из двух запросов с одним токеном проходит один,
+	// второй считается повторным использованием.
+	rotated, err := svc.Sessions.RotateRefreshToken(session, previousHash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, svc.refreshReused(session, now)
+	}
+	return svc.issueTokens(user, session, refresh)
+}
+
+// refreshReused отзывает сессию, чей refresh-токен предъявлен повторно.
+func (svc *SessionService) refreshReused(session *models.UserSession, now time.Time) error {
+	slog.Warn("Повторное использование refresh-токена, сессия отозвана", "user_id", session.UserID, "session_id", session.ID)
+	if err := svc.Sessions.RevokeSession(session.ID, now); err != nil {
+		slog.Error("Не удалось отозвать сессию", "session_id", session.ID, "error", err)
+	}
+	return ErrSessionInvalid
+}
+
+// Authenticate проверяет access-токен и возвращает сотрудника и его сессию.
+func (svc *SessionService) Authenticate(accessToken string) (*models.User, *models.UserSession, error) {
+	claims, err := svc.verifyAccessToken(accessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := clockNow(svc.Clock)
+	session, err := svc.Sessions.GetSessionByID(claims.SessionID)
+	if err != nil || session.UserID != claims.UserID || !session.Active(now) {
+		return nil, nil, ErrSessionInvalid
+	}
+	user, err := svc.Users.GetByID(claims.UserID)
+	if err != nil {
+		return nil, nil, ErrSessionInvalid
+	}
+	if now.Sub(session.LastUsedAt) >= sessionTouchInterval {
+		session.LastUsedAt = now
+		if err := svc.Sessions.UpdateSession(session); err != nil {
+			slog.Error("Ошибка обновления last_used_at сессии", "session_id", session.ID, "error", err)
+		}
+	}
+	return user, session, nil
+}
+
+// Logout закрывает сессию.
+func (svc *SessionService) Logout(sessionID string) error {
+	session, err := svc.Sessions.GetSessionByID(sessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	return svc.revoke(session)
+}
+
+// ListSessions — действующие сессии пользователя, новые первыми.
+func (svc *SessionService) ListSessions(userID int) ([]models.UserSession, error) {
+	sessions, err := svc.Sessions.GetSessionsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := clockNow(svc.Clock)
+	active := make([]models.UserSession, 0, len(sessions))
+	for _, s := range sessions {
+		if s.Active(now) {
+			active = append(active, s)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool { return active[i].CreatedAt.After(active[j].CreatedAt) })
+	return active, nil
+}
+
+// RevokeSession отзывает одну из сессий пользователя (например, забытый браузер).
+func (svc *SessionService) RevokeSession(userID int, sessionID string) error {
+	session, err := svc.Sessions.GetSessionByID(sessionID)
+	if err != nil || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	return svc.revoke(session)
+}
+
+func (svc *SessionService) revoke(session *models.UserSession) error {
+	if session.RevokedAt != nil {
+		return nil
+	}
+	now := clockNow(svc.Clock)
+	session.RevokedAt = &now
+	if err := svc.Sessions.UpdateSession(session); err != nil {
+		return err
+	}
+	slog.Info("Сессия отозвана", "user_id", session.UserID, "session_id", session.ID)
+	return nil
+}
+
+// RevokeUserSessions отзывает все сессии сотрудника от имени actor.
+func (svc *SessionService) RevokeUserSessions(actor *models.User, scope *UserScope, userID int) error {
+	target, err := svc.Users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := svc.checkTarget(actor, scope, target); err != nil {
+		return err
+	}
+	slog.Info("Все сессии отозваны", "user_id", userID, "actor_id", actor.ID)
+	return svc.Sessions.RevokeUserSessions(userID, clockNow(svc.Clock))
+}
+
+// checkTarget — actor управляет target (см. CheckUserManagement) в своей организации.
+// Администратор платформы может выдать вход первому администратору новой организации.
+func (svc *SessionService) checkTarget(actor *models.User, scope *UserScope, target *models.User) error {
+	if organizationOrDefault(target.OrganizationID) != organizationOrDefault(actor.OrganizationID) &&
+		!actor.IsPlatformAdmin() {
+		return ErrAccessDenied
+	}
+	return CheckUserManagement(actor, scope, target)
+}
+
+// CredentialsInput — изменения учётных данных сотрудника; пустые поля не меняются.
+type CredentialsInput struct {
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	ResetTOTP bool   `json:"reset_totp"`
+}
+
+// SetCredentials задаёт сотруднику логин и/или пароль от имени actor; ResetTOTP
+// отключает второй фактор (потерянный телефон). Смена пароля и сброс TOTP
+// закрывают все сессии сотрудника.
+func (svc *SessionService) SetCredentials(actor *models.User, scope *UserScope, userID int, in CredentialsInput) (*models.User, error) {
+	target, err := svc.Users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.checkTarget(actor, scope, target); err != nil {
+		return nil, err
+	}
+	if !target.IsStaff() {
+		return nil, ErrCredentialsNotAllowed
+	}
+
+	if in.Username != "" {
+		username := NormalizeUsername(in.Username)
+		if username == "" || len(username) > maxUsernameLength {
+			return nil, ErrUsernameEmpty
+		}
+		if existing, err := svc.Users.GetByUsername(username); err == nil && existing.ID != target.ID {
+			return nil, ErrUsernameTaken
+		}
+		target.Username = &username
+	}
+	if target.Username == nil || *target.Username == "" {
+		return nil, ErrUsernameEmpty
+	}
+	revoke := in.ResetTOTP && target.TOTPEnabled
+	if in.Password != "" {
+		if err := setPassword(target, in.Password); err != nil {
+			return nil, err
+		}
+		revoke = true
+	}
+	if in.ResetTOTP {
+		target.TOTPEnabled = false
+		target.TOTPSecret = ""
+	}
+	if err := svc.Users.Update(target); err != nil {
+		return nil, err
+	}
+	if revoke {
+		if err := svc.Sessions.RevokeUserSessions(target.ID, clockNow(svc.Clock)); err != nil {
+			return nil, err
+		}
+	}
+	slog.Info("Учётные данные обновлены", "user_id", target.ID, "username", *target.Username, "actor_id", actor.ID)
+	return target, nil
+}
+
+// ChangePassword меняет пароль сотрудника по текущему; остальные его сессии закрываются.
+func (svc *SessionService) ChangePassword(userID int, currentSessionID, current, next string) error {
+	user, err := svc.Users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash != "" && bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)) != nil {
+		return ErrInvalidCredentials
+	}
+	if err := setPassword(user, next); err != nil {
+		return err
+	}
+	if err := svc.Users.Update(user); err != nil {
+		return err
+	}
+	sessions, err := svc.Sessions.GetSessionsByUser(userID)
+	if err != nil {
+		return err
+	}
+	for i := range sessions {
+		if sessions[i].ID != currentSessionID {
+			if err := svc.revoke(&sessions[i]); err != nil {
+				return err
+			}
+		}
+	}
+	slog.Info("Пароль изменён", "user_id", userID)
+	return nil
+}
+
+func setPassword(user *models.User, password string) error {
+	if len([]rune(password)) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hash)
+	return nil
+}
+
+// CreateLoginLink выдаёт одноразовый токен входа для сотрудника userID.
+// Токен возвращается только здесь; в БД хранится его хеш.
+func (svc *SessionService) CreateLoginLink(actor *models.User, scope *UserScope, userID int) (string, *models.LoginLink, error) {
+	target, err := svc.Users.GetByID(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := svc.checkTarget(actor, scope, target); err != nil {
+		return "", nil, err
+	}
+	if !target.IsStaff() {
+		return "", nil, ErrCredentialsNotAllowed
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+	link := &models.LoginLink{
+		UserID:    target.ID,
+		TokenHash: hashToken(token),
+		CreatedBy: actor.ID,
+		ExpiresAt: clockNow(svc.Clock).Add(svc.LoginLinkTTL),
+	}
+	if err := svc.Sessions.CreateLoginLink(link); err != nil {
+		return "", nil, err
+	}
+	slog.Info("Ссылка для входа выпущена", "user_id", target.ID, "actor_id", actor.ID, "expires_at", link.ExpiresAt)
+	return token, link, nil
+}
+
+// TOTPSetup — секрет и otpauth-ссылка для приложения-аутентификатора.
+type TOTPSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// SetupTOTP генерирует новый секрет; второй фактор включается только после
+// подтверждения кодом в EnableTOTP.
+func (svc *SessionService) SetupTOTP(userID int) (*TOTPSetup, error) {
+	user, err := svc.Users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	if err := svc.Users.Update(user); err != nil {
+		return nil, err
+	}
+	account := user.Name
+	if user.Username != nil {
+		account = *user.Username
+	}
+	return &TOTPSetup{Secret: secret, URI: totpURI(svc.TOTPIssuer, account, secret)}, nil
+}
+
+// EnableTOTP включает второй фактор, если код совпадает с секретом из SetupTOTP.
+func (svc *SessionService) EnableTOTP(userID int, code string) error {
+	user, err := svc.Users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabled {
+		return ErrTOTPAlreadyEnabled
+	}
+	if err := svc.useTOTP(user, code); err != nil {
+		return err
+	}
+	user.TOTPEnabled = true
+	if err := svc.Users.Update(user); err != nil {
+		return err
+	}
+	slog.Info("TOTP включён", "user_id", userID)
+	return nil
+}
+
+// DisableTOTP отключает второй фактор по действующему коду.
+func (svc *SessionService) DisableTOTP(userID int, code string) error {
+	user, err := svc.Users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+	if err := svc.useTOTP(user, code); err != nil {
+		return err
+	}
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	if err := svc.Users.Update(user); err != nil {
+		return err
+	}
+	slog.Info("TOTP отключён", "user_id", userID)
+	return nil
+}
+
+// signAccessToken — base64url(JSON claims) + "." + base64url(HMAC-SHA256).
+func (svc *SessionService) signAccessToken(claims accessClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(svc.sign(body)), nil
+}
+
+func (svc *SessionService) verifyAccessToken(token string) (*accessClaims, error) {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrSessionInvalid
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, svc.sign(body)) {
+		return nil, ErrSessionInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrSessionInvalid
+	}
+	var claims accessClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.SessionID == "" {
+		return nil, ErrSessionInvalid
+	}
+	if clockNow(svc.Clock).Unix() >= claims.ExpiresAt {
+		return nil, ErrSessionInvalid
+	}
+	return &claims, nil
+}
+
+func (svc *SessionService) sign(body string) []byte {
+	mac := hmac.New(sha256.New, svc.secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+// newRefreshToken — "<session id>.<случайная часть>": ID позволяет найти сессию без перебора.
+func newRefreshToken(sessionID string) (string, error) {
+	random, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	return sessionID + "." + random, nil
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashToken — SHA-256 токена: refresh-токены и ссылки входа случайны и длинны,
+// медленный хеш для них не нужен.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// dummyPasswordHash — bcrypt-хеш для сравнения при неизвестном логине
+// (считается при первом обращении, а не при старте).
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("locator-dummy-password"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
@@ -0,0 +1,445 @@
+package service
+
+import (
+	"encoding/base32"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"locator/internal/testutil"
+	"locator/models"
+)
+
+type fakeSessionRepo struct {
+	sessions map[string]models.UserSession
+	links    map[string]models.LoginLink
+}
+
+func newFakeSessionRepo() *fakeSessionRepo {
+	return &fakeSessionRepo{sessions: map[string]models.UserSession{}, links: map[string]models.LoginLink{}}
+}
+
+func (f *fakeSessionRepo) CreateSession(s *models.UserSession) error {
+	f.sessions[s.ID] = *s
+	return nil
+}
+
+func (f *fakeSessionRepo) UpdateSession(s *models.UserSession) error {
+	f.sessions[s.ID] = *s
+	return nil
+}
+
+func (f *fakeSessionRepo) GetSessionByID(id string) (*models.UserSession, error) {
+	s, ok := f.sessions[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &s, nil
+}
+
+func (f *fakeSessionRepo) GetSessionsByUser(userID int) ([]models.UserSession, error) {
+	var out []models.UserSession
+	for _, s := range f.sessions {
+		if s.UserID == userID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeSessionRepo) RotateRefreshToken(session *models.UserSession, previousHash string) (bool, error) {
+	s, ok := f.sessions[session.ID]
+	if !ok || s.RefreshTokenHash != previousHash || s.RevokedAt != nil {
+		return false, nil
+	}
+	f.sessions[session.ID] = *session
+	return true, nil
+}
+
+func (f *fakeSessionRepo) RevokeSession(id string, at time.Time) error {
+	if s, ok := f.sessions[id]; ok && s.RevokedAt == nil {
+		s.RevokedAt = &at
+		f.sessions[id] = s
+	}
+	return nil
+}
+
+func (f *fakeSessionRepo) RevokeUserSessions(userID int, at time.Time) error {
+	for id, s := range f.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt = &at
+			f.sessions[id] = s
+		}
+	}
+	return nil
+}
+
+func (f *fakeSessionRepo) CreateLoginLink(link *models.LoginLink) error {
+	link.ID = len(f.links) + 1
+	f.links[link.TokenHash] = *link
+	return nil
+}
+
+func (f *fakeSessionRepo) ConsumeLoginLink(id int, at time.Time) (bool, error) {
+	for hash, link := range f.links {
+		if link.ID == id && link.UsedAt == nil && link.ExpiresAt.After(at) {
+			link.UsedAt = &at
+			f.links[hash] = link
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeSessionRepo) GetLoginLinkByHash(tokenHash string) (*models.LoginLink, error) {
+	link, ok := f.links[tokenHash]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &link, nil
+}
+
+// racingLoginLinks расходует ссылку сразу после чтения — как параллельный вход.
+type racingLoginLinks struct {
+	*fakeSessionRepo
+	clock *testutil.Clock
+}
+
+func (r racingLoginLinks) GetLoginLinkByHash(tokenHash string) (*models.LoginLink, error) {
+	link, err := r.fakeSessionRepo.GetLoginLinkByHash(tokenHash)
+	if err == nil {
+		_, _ = r.ConsumeLoginLink(link.ID, r.clock.Now())
+	}
+	return link, err
+}
+
+const testOperatorPassword = "correct-horse-1"
+
+func operator(t *testing.T, id int, username, role string) models.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testOperatorPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := models.User{ID: id, Name: username, OrganizationID: models.DefaultOrganizationID, Username: &username, PasswordHash: string(hash)}
+	u.SetRole(role)
+	return u
+}
+
+// newTestSessionService — сервис с управляемыми часами.
+func newTestSessionService(users *fakeUserRepo) (*SessionService, *fakeSessionRepo, *testutil.Clock) {
+	repo := newFakeSessionRepo()
+	svc := NewSessionService(users, repo, []byte("test-secret"))
+	clock := testutil.NewClock(time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC))
+	svc.Clock = clock
+	return svc, repo, clock
+}
+
+func TestTOTPCode_rfc6238Vector(t *testing.T) {
+	// RFC 6238, приложение B: SHA1, T=59 → 94287082 (последние 6 цифр).
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	got, err := totpCode(secret, 59/30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Fatalf("got %s", got)
+	}
+	if step, ok := validateTOTP(secret, "287082", time.Unix(59+30, 0)); !ok || step != 1 {
+		t.Fatalf("previous step must be accepted: step=%d ok=%v", step, ok)
+	}
+	if _, ok := validateTOTP(secret, "287082", time.Unix(59+90, 0)); ok {
+		t.Fatal("code three steps old must be rejected")
+	}
+}
+
+func TestSessionLogin_passwordAndDeviceRole(t *testing.T) {
+	device := operator(t, 2, "phone", models.RoleDevice)
+	users := newFakeUserRepo(operator(t, 1, "alice", models.RoleDispatcher), device)
+	svc, _, _ := newTestSessionService(users)
+
+	if _, err := svc.Login("alice", "wrong-password", "", SessionMeta{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong password: %v", err)
+	}
+	if _, err := svc.Login("nobody", testOperatorPassword, "", SessionMeta{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("unknown user: %v", err)
+	}
+	if _, err := svc.Login("phone", testOperatorPassword, "", SessionMeta{}); !errors.Is(err, ErrCredentialsNotAllowed) {
+		t.Fatalf("device role: %v", err)
+	}
+
+	tokens, err := svc.Login("  Alice ", testOperatorPassword, "", SessionMeta{UserAgent: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, session, err := svc.Authenticate(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 1 || session.UserAgent != "test" {
+		t.Fatalf("user=%d session=%+v", user.ID, session)
+	}
+}
+
+func TestSessionAuthenticate_accessTokenExpires(t *testing.T) {
+	users := newFakeUserRepo(operator(t, 1, "alice", models.RoleViewer))
+	svc, _, clock := newTestSessionService(users)
+	tokens, err := svc.Login("alice", testOperatorPassword, "", SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(svc.AccessTTL)
+	if _, _, err := svc.Authenticate(tokens.AccessToken); !errors.Is(err, ErrSessionInvalid) {
+		t.Fatalf("expired access token: %v", err)
+	}
+
+	refreshed, err := svc.Refresh(tokens.RefreshToken, SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.Authenticate(refreshed.AccessToken); err != nil {
+		t.Fatalf("refreshed token: %v", err)
+	}
+}
+
+func TestSessionRefresh_rotationAndReuseRevokes(t *testing.T) {
+	users := newFakeUserRepo(operator(t, 1, "alice", models.RoleViewer))
+	svc, repo, _ := newTestSessionService(users)
+	tokens, err := svc.Login("alice", testOperatorPassword, "", SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := svc.Refresh(tokens.RefreshToken, SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.RefreshToken == tokens.RefreshToken || next.SessionID != tokens.SessionID {
+		t.Fatalf("refresh token must rotate within the same session: %+v", next)
+	}
+
+	// Старый refresh-токен предъявлен повторно — сессия отзывается целиком.
+	if _, err := svc.Refresh(tokens.RefreshToken, SessionMeta{}); !errors.Is(err, ErrSessionInvalid) {
+		t.Fatalf("reuse: %v", err)
+	}
+	if repo.sessions[tokens.SessionID].RevokedAt == nil {
+		t.Fatal("session must be revoked after refresh token reuse")
+	}
+	if _, err := svc.Refresh(next.RefreshToken, SessionMeta{}); !errors.Is(err, ErrSessionInvalid) {
+		t.Fatalf("current token after reuse: %v", err)
+	}
+	if _, _, err := svc.Authenticate(next.AccessToken); !errors.Is(err, ErrSessionInvalid) {
+		t.Fatalf("access token after reuse: %v", err)
+	}
+}
+
+// interleavedSessionReads выполняет during сразу после первого чтения сессии —
+// как параллельный запрос, успевший между чтением и записью.
+type interleavedSessionReads struct {
+	*fakeSessionRepo
+	during func()
+}
+
+func (r *interleavedSessionReads) GetSessionByID(id string) (*models.UserSession, error) {
+	session, err := r.fakeSessionRepo.GetSessionByID(id)
+	if during := r.during; during != nil {
+		r.during = nil
+		during()
+	}
+	return session, err
+}
+
+func TestSessionRefresh_concurrentSameTokenOneWins(t *testing.T) {
+	users := newFakeUserRepo(operator(t, 1, "alice", models.RoleViewer))
+	svc, repo, _ := newTestSessionService(users)
+	tokens, err := svc.Login("alice", testOperatorPassword, "", SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var innerErr error
+	svc.Sessions = &interleavedSessionReads{fakeSessionRepo: repo, during: func() {
+		_, innerErr = svc.Refresh(tokens.RefreshToken, SessionMeta{})
+	}}
+	_, outerErr := svc.Refresh(tokens.RefreshToken, SessionMeta{})
+
+	if innerErr != nil {
+		t.Fatalf("first refresh: %v", innerErr)
+	}
+	if !errors.Is(outerErr, ErrSessionInvalid) {
+		t.Fatalf("second refresh with the same token must fail, got %v", outerErr)
+	}
+	if repo.sessions[tokens.SessionID].RevokedAt == nil {
+		t.Fatal("session must be revoked when the same refresh token is used twice")
+	}
+}
+
+func TestSessionLogin_totpRequired(t *testing.T) {
+	users := newFakeUserRepo(operator(t, 1, "alice", models.RoleFleetAdmin))
+	svc, _, clock := newTestSessionService(users)
+
+	setup, err := svc.SetupTOTP(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := totpCode(setup.Secret, uint64(clock.Now().Unix())/30)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	if err := svc.EnableTOTP(1, wrong); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("enable with wrong code: %v", err)
+	}
+	if err := svc.EnableTOTP(1, code); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.Login("alice", testOperatorPassword, "", SessionMeta{}); !errors.Is(err, ErrTOTPRequired) {
+		t.Fatalf("missing code: %v", err)
+	}
+	if _, err := svc.Login("alice", testOperatorPassword, "12345", SessionMeta{}); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("bad code: %v", err)
+	}
+	// Код, которым включали TOTP, повторно не принимается даже в пределах окна.
+	if _, err := svc.Login("alice", testOperatorPassword, code, SessionMeta{}); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("replayed enable code: %v", err)
+	}
+	clock.Advance(totpPeriod)
+	code, _ = totpCode(setup.Secret, uint64(clock.Now().Unix())/30)
+	if _, err := svc.Login("alice", testOperatorPassword, code, SessionMeta{}); err != nil {
+		t.Fatalf("valid code: %v", err)
+	}
+	if _, err := svc.Login("alice", testOperatorPassword, code, SessionMeta{}); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("replayed login code: %v", err)
+	}
+	// Код предыдущего шага ещё в окне, но старше принятого — тоже повтор.
+	previous, _ := totpCode(setup.Secret, uint64(clock.Now().Unix())/30-1)
+	if _, err := svc.Login("alice", testOperatorPassword, previous, SessionMeta{}); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("older step: %v", err)
+	}
+}
+
+func TestLoginLink_singleUseAndScoped(t *testing.T) {
+	admin := operator(t, 1, "admin", models.RoleSuperAdmin)
+	fleet := operator(t, 4, "fleet", models.RoleFleetAdmin)
+	other := operator(t, 3, "stranger", models.RoleSuperAdmin)
+	other.OrganizationID = 2
+	users := newFakeUserRepo(admin, operator(t, 2, "bob", models.RoleDispatcher), other, fleet)
+	svc, links, clock := newTestSessionService(users)
+	scope := NewUserScope(1, 2)
+
+	if _, _, err := svc.CreateLoginLink(&fleet, AllUsersScope(), 3); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("other organization: %v", err)
+	}
+	// Первый администратор новой организации получает вход от администратора платформы.
+	if _, _, err := svc.CreateLoginLink(&admin, AllUsersScope(), 3); err != nil {
+		t.Fatalf("platform admin: %v", err)
+	}
+
+	token, _, err := svc.CreateLoginLink(&admin, scope, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := svc.LoginWithLink(token, "", SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.User.ID != 2 {
+		t.Fatalf("logged in as %d", tokens.User.ID)
+	}
+	if _, err := svc.LoginWithLink(token, "", SessionMeta{}); !errors.Is(err, ErrLoginLinkInvalid) {
+		t.Fatalf("second use: %v", err)
+	}
+
+	// Параллельный вход израсходовал ссылку между чтением и UPDATE: второй вход не проходит.
+	raced, _, err := svc.CreateLoginLink(&admin, scope, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.Sessions = racingLoginLinks{links, clock}
+	if _, err := svc.LoginWithLink(raced, "", SessionMeta{}); !errors.Is(err, ErrLoginLinkInvalid) {
+		t.Fatalf("raced link: %v", err)
+	}
+	svc.Sessions = links
+
+	expired, _, err := svc.CreateLoginLink(&admin, scope, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(svc.LoginLinkTTL)
+	if _, err := svc.LoginWithLink(expired, "", SessionMeta{}); !errors.Is(err, ErrLoginLinkInvalid) {
+		t.Fatalf("expired link: %v", err)
+	}
+}
+
+func TestSetCredentials_revokesSessionsAndChecksAccess(t *testing.T) {
+	admin := operator(t, 1, "admin", models.RoleFleetAdmin)
+	superAdmin := operator(t, 3, "root", models.RoleSuperAdmin)
+	device := models.User{ID: 4, Name: "phone", OrganizationID: models.DefaultOrganizationID}
+	device.SetRole(models.RoleDevice)
+	users := newFakeUserRepo(admin, operator(t, 2, "bob", models.RoleDispatcher), superAdmin, device)
+	svc, _, _ := newTestSessionService(users)
+	scope := AllUsersScope()
+
+	if _, err := svc.SetCredentials(&admin, scope, 3, CredentialsInput{Password: "new-password-1"}); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("senior role: %v", err)
+	}
+	if _, err := svc.SetCredentials(&admin, scope, 4, CredentialsInput{Username: "phone", Password: "new-password-1"}); !errors.Is(err, ErrCredentialsNotAllowed) {
+		t.Fatalf("device: %v", err)
+	}
+	if _, err := svc.SetCredentials(&admin, scope, 2, CredentialsInput{Username: "Admin"}); !errors.Is(err, ErrUsernameTaken) {
+		t.Fatalf("taken username: %v", err)
+	}
+	if _, err := svc.SetCredentials(&admin, scope, 2, CredentialsInput{Password: "short"}); !errors.Is(err, ErrPasswordTooShort) {
+		t.Fatalf("short password: %v", err)
+	}
+
+	tokens, err := svc.Login("bob", testOperatorPassword, "", SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.SetCredentials(&admin, scope, 2, CredentialsInput{Password: "new-password-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.Authenticate(tokens.AccessToken); !errors.Is(err, ErrSessionInvalid) {
+		t.Fatalf("session after password reset: %v", err)
+	}
+	if _, err := svc.Login("bob", "new-password-1", "", SessionMeta{}); err != nil {
+		t.Fatalf("login with new password: %v", err)
+	}
+}
+
+func TestChangePassword_keepsCurrentSession(t *testing.T) {
+	users := newFakeUserRepo(operator(t, 1, "alice", models.RoleViewer))
+	svc, _, _ := newTestSessionService(users)
+	current, err := svc.Login("alice", testOperatorPassword, "", SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := svc.Login("alice", testOperatorPassword, "", SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.ChangePassword(1, current.SessionID, "wrong", "new-password-1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong current password: %v", err)
+	}
+	if err := svc.ChangePassword(1, current.SessionID, testOperatorPassword, "new-password-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.Authenticate(current.AccessToken); err != nil {
+		t.Fatalf("current session: %v", err)
+	}
+	if _, _, err := svc.Authenticate(other.AccessToken); !errors.Is(err, ErrSessionInvalid) {
+		t.Fatalf("other session: %v", err)
+	}
+	sessions, err := svc.ListSessions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != current.SessionID {
+		t.Fatalf("sessions: %+v", sessions)
+	}
+}
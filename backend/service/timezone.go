@@ -0,0 +1,196 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"locator/models"
+)
+
+var ErrTimezoneInvalid = errors.New("invalid timezone")
+
+var timezoneCache sync.Map // имя → *time.Location
+
+// LoadTimezone — IANA-пояс по имени ("" — models.DefaultTimezone).
+func LoadTimezone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = models.DefaultTimezone
+	}
+	if loc, ok := timezoneCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	// "Local" зависит от сервера, а не от пользователя.
+	if name == "Local" {
+		return nil, fmt.Errorf("%w: %q", ErrTimezoneInvalid, name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrTimezoneInvalid, name)
+	}
+	timezoneCache.Store(name, loc)
+	return loc, nil
+}
+
+// defaultTimezone — пояс по умолчанию; без базы tzdata — UTC.
+func defaultTimezone() *time.Location {
+	loc, err := LoadTimezone("")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// orDefaultTimezone — loc или пояс по умолчанию, если loc не задан.
+func orDefaultTimezone(loc *time.Location) *time.Location {
+	if loc == nil {
+		return defaultTimezone()
+	}
+	return loc
+}
+
+// inLocation — t в поясе loc (nil — без изменений): в JSON время уходит со смещением пояса.
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// userTimezoneSource — часовой пояс пользователя (TimezoneService).
+type userTimezoneSource interface {
+	UserLocation(userID int) *time.Location
+}
+
+// organizationTimezoneSource — часовой пояс организации (TimezoneService).
+type organizationTimezoneSource interface {
+	OrganizationLocation(organizationID int) *time.Location
+}
+
+// TimezoneService определяет, в каком поясе толковать локальное время пользователя:
+// его собственный пояс, иначе пояс организации, иначе models.DefaultTimezone.
+// Нулевой указатель допустим — всегда пояс по умолчанию.
+type TimezoneService struct {
+	Users         userRepository
+	Organizations organizationRepository
+}
+
+func NewTimezoneService(users userRepository, organizations organizationRepository) *TimezoneService {
+	return &TimezoneService{Users: users, Organizations: organizations}
+}
+
+// OrganizationLocation — пояс организации; ошибки чтения — пояс по умолчанию.
+func (svc *TimezoneService) OrganizationLocation(organizationID int) *time.Location {
+	if svc == nil || svc.Organizations == nil {
+		return defaultTimezone()
+	}
+	org, err := svc.Organizations.GetOrganizationByID(organizationOrDefault(organizationID))
+	if err != nil {
+		return defaultTimezone()
+	}
+	loc, err := LoadTimezone(org.Timezone)
+	if err != nil {
+		slog.Warn("Неизвестный пояс организации", "organization_id", org.ID, "error", err)
+		return defaultTimezone()
+	}
+	return loc
+}
+
+// UserLocation — пояс пользователя userID (см. ForUser).
+func (svc *TimezoneService) UserLocation(userID int) *time.Location {
+	if svc == nil || svc.Users == nil {
+		return defaultTimezone()
+	}
+	user, err := svc.Users.GetByID(userID)
+	if err != nil {
+		return defaultTimezone()
+	}
+	return svc.ForUser(user)
+}
+
+// ForUser — пояс пользователя, а если он не задан — его организации.
+func (svc *TimezoneService) ForUser(user *models.User) *time.Location {
+	if user == nil {
+		return defaultTimezone()
+	}
+	if user.Timezone != "" {
+		if loc, err := LoadTimezone(user.Timezone); err == nil {
+			return loc
+		}
+		slog.Warn("Неизвестный пояс пользователя", "user_id", user.ID, "timezone", user.Timezone)
+	}
+	return svc.OrganizationLocation(user.OrganizationID)
+}
+
+// Resolve — пояс запроса: явный tz (query ?tz=) важнее пояса пользователя.
+func (svc *TimezoneService) Resolve(tz string, user *models.User) (*time.Location, error) {
+	if strings.TrimSpace(tz) != "" {
+		return LoadTimezone(tz)
+	}
+	return svc.ForUser(user), nil
+}
+
+// ValidateTimezone проверяет имя пояса перед сохранением ("" допустимо).
+func ValidateTimezone(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil
+	}
+	if _, err := LoadTimezone(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Локальное время без смещения в query: минуты, секунды или целые сутки.
+var localQueryLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02"}
+
+// parseLocalQueryTime — RFC3339 с любым смещением или локальное время в loc
+// (YYYY-MM-DDTHH:mm, YYYY-MM-DDTHH:mm:ss, YYYY-MM-DD).
+func parseLocalQueryTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range localQueryLayouts {
+		if t, err := time.ParseInLocation(layout, s, orDefaultTimezone(loc)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q: ожидается RFC3339 или YYYY-MM-DDTHH:mm", s)
+}
+
+// parseLocalRange — границы интервала в UTC. Конец без секунд включает всю минуту,
+// конец-дата — все сутки в поясе loc.
+func parseLocalRange(fromStr, toStr string, loc *time.Location) (time.Time, time.Time, error) {
+	from, err := parseLocalQueryTime(fromStr, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %w", err)
+	}
+	to, err := parseLocalQueryTime(toStr, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %w", err)
+	}
+	to = normalizeLocalRangeEnd(to, toStr)
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("начало интервала не может быть позже окончания")
+	}
+	return from.UTC(), to.UTC(), nil
+}
+
+func normalizeLocalRangeEnd(to time.Time, toStr string) time.Time {
+	toStr = strings.TrimSpace(toStr)
+	if !strings.Contains(toStr, "T") {
+		// Только дата: до конца этих суток (с учётом перехода на летнее время).
+		return to.AddDate(0, 0, 1).Add(-time.Millisecond)
+	}
+	timePart := strings.SplitN(toStr, "T", 2)[1]
+	if strings.Count(timePart, ":") == 1 {
+		return to.Add(59*time.Second + 999*time.Millisecond)
+	}
+	return to
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"locator/models"
+)
+
+func TestTimezoneService_userThenOrganizationThenDefault(t *testing.T) {
+	users := newFakeUserRepo(
+		models.User{ID: 1, OrganizationID: 2, Timezone: "Asia/Almaty"},
+		models.User{ID: 2, OrganizationID: 2},
+		models.User{ID: 3, OrganizationID: models.DefaultOrganizationID},
+	)
+	orgs := &fakeOrganizationRepo{orgs: []models.Organization{
+		{ID: 1, Timezone: models.DefaultTimezone},
+		{ID: 2, Timezone: "America/New_York"},
+	}}
+	svc := NewTimezoneService(users, orgs)
+
+	for userID, want := range map[int]string{1: "Asia/Almaty", 2: "America/New_York", 3: models.DefaultTimezone, 99: models.DefaultTimezone} {
+		if got := svc.UserLocation(userID).String(); got != want {
+			t.Errorf("UserLocation(%d)=%s, want %s", userID, got, want)
+		}
+	}
+
+	user, _ := users.GetByID(2)
+	loc, err := svc.Resolve("Europe/Warsaw", user)
+	if err != nil || loc.String() != "Europe/Warsaw" {
+		t.Fatalf("tz override: %v, %v", loc, err)
+	}
+	if _, err := svc.Resolve("Mars/Olympus", user); !errors.Is(err, ErrTimezoneInvalid) {
+		t.Fatalf("unknown tz: err=%v, want ErrTimezoneInvalid", err)
+	}
+	if _, err := LoadTimezone("Local"); !errors.Is(err, ErrTimezoneInvalid) {
+		t.Fatalf("server-local zone must be rejected: %v", err)
+	}
+
+	var nilSvc *TimezoneService
+	if got := nilSvc.UserLocation(1).String(); got != models.DefaultTimezone {
+		t.Fatalf("nil service: %s", got)
+	}
+}
+
+func TestParseLocalRange_offsetsAndWholeDay(t *testing.T) {
+	ny, err := LoadTimezone("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Явное отрицательное смещение важнее пояса запроса.
+	from, to, err := parseLocalRange("2026-10-19T08:00:00-05:00", "2026-10-19T09:30", ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 10, 19, 13, 0, 0, 0, time.UTC); !from.Equal(want) {
+		t.Fatalf("from=%s, want %s", from, want)
+	}
+	if want := time.Date(2026, 10, 19, 13, 30, 59, 999e6, time.UTC); !to.Equal(want) {
+		t.Fatalf("to=%s, want %s (EDT, minute included)", to, want)
+	}
+
+	// 8 марта 2026 в Нью-Йорке — переход на летнее время, в сутках 23 часа.
+	from, to, err = parseLocalRange("2026-03-08", "2026-03-08", ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 3, 8, 5, 0, 0, 0, time.UTC); !from.Equal(want) {
+		t.Fatalf("from=%s, want %s", from, want)
+	}
+	if want := time.Date(2026, 3, 9, 3, 59, 59, 999e6, time.UTC); !to.Equal(want) {
+		t.Fatalf("to=%s, want %s", to, want)
+	}
+}
+
+func TestParseCapturedAt_usesUserTimezone(t *testing.T) {
+	users := newFakeUserRepo(models.User{ID: 1, Timezone: "Asia/Tokyo"})
+	svc := &LocationService{location: defaultTimezone(), Timezones: NewTimezoneService(users, nil)}
+
+	local := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	s := local.In(mustLoadTimezone(t, "Asia/Tokyo")).Format("2006-01-02T15:04")
+	got, err := svc.ParseCapturedAt(1, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(local) {
+		t.Fatalf("captured_at=%s, want %s (Asia/Tokyo)", got, local)
+	}
+}
+
+func TestSetOrganizationTimezone_ownOrganizationOnly(t *testing.T) {
+	orgs := &fakeOrganizationRepo{orgs: []models.Organization{{ID: 2, Name: "Acme", Timezone: models.DefaultTimezone}}}
+	svc := &UserService{Organizations: orgs}
+
+	tenantAdmin := &models.User{ID: 20, Role: models.RoleSuperAdmin, OrganizationID: 3}
+	if _, err := svc.SetOrganizationTimezone(tenantAdmin, 2, "Asia/Almaty"); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("foreign organization: err=%v, want ErrAccessDenied", err)
+	}
+	owner := &models.User{ID: 21, Role: models.RoleFleetAdmin, OrganizationID: 2}
+	if _, err := svc.SetOrganizationTimezone(owner, 2, "Nowhere/City"); !errors.Is(err, ErrTimezoneInvalid) {
+		t.Fatalf("unknown tz: err=%v", err)
+	}
+	org, err := svc.SetOrganizationTimezone(owner, 2, "Asia/Almaty")
+	if err != nil || org.Timezone != "Asia/Almaty" || orgs.orgs[0].Timezone != "Asia/Almaty" {
+		t.Fatalf("org=%+v, err=%v", org, err)
+	}
+}
+
+func mustLoadTimezone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := LoadTimezone(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"locator/models"
+)
+
+// timeSpan — полуинтервал [From, To).
+type timeSpan struct {
+	From time.Time
+	To   time.Time
+}
+
+// trackingWindowSpec — окно графика в минутах от начала суток; end может быть 1440 (24:00).
+type trackingWindowSpec struct {
+	days  [8]bool // индекс 1..7, 1 — понедельник
+	start int
+	end   int
+}
+
+// trackingPlan — разобранный график: по нему считаются разрешённые интервалы.
+type trackingPlan struct {
+	loc      *time.Location
+	windows  []trackingWindowSpec
+	holidays map[string]struct{}
+	// Согласие действует в [consentFrom, consentUntil); consentRequired без
+	// consentFrom — отслеживание запрещено всегда.
+	consentRequired bool
+	consentFrom     *time.Time
+	consentUntil    *time.Time
+}
+
+// newTrackingPlan разбирает сохранённый график (его поля уже проверены при сохранении).
+// userLoc — пояс пользователя для графика без собственного пояса (nil — пояс по умолчанию).
+func newTrackingPlan(s *models.TrackingSchedule, userLoc *time.Location) (*trackingPlan, error) {
+	loc := orDefaultTimezone(userLoc)
+	if s.Timezone != "" {
+		var err error
+		if loc, err = LoadTimezone(s.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	plan := &trackingPlan{
+		loc:             loc,
+		holidays:        make(map[string]struct{}),
+		consentRequired: s.ConsentRequired,
+		consentFrom:     s.ConsentGivenAt,
+		consentUntil:    s.ConsentRevokedAt,
+	}
+
+	var windows []models.TrackingWindow
+	if len(s.Windows) > 0 {
+		if err := json.Unmarshal(s.Windows, &windows); err != nil {
+			return nil, fmt.Errorf("windows: %w", err)
+		}
+	}
+	for _, w := range windows {
+		spec, err := parseTrackingWindow(w)
+		if err != nil {
+			return nil, err
+		}
+		plan.windows = append(plan.windows, spec)
+	}
+	if len(plan.windows) == 0 {
+		all := trackingWindowSpec{start: 0, end: 24 * 60}
+		for d := 1; d <= 7; d++ {
+			all.days[d] = true
+		}
+		plan.windows = []trackingWindowSpec{all}
+	}
+
+	var holidays []string
+	if len(s.Holidays) > 0 {
+		if err := json.Unmarshal(s.Holidays, &holidays); err != nil {
+			return nil, fmt.Errorf("holidays: %w", err)
+		}
+	}
+	for _, h := range holidays {
+		plan.holidays[h] = struct{}{}
+	}
+	return plan, nil
+}
+
+func parseTrackingWindow(w models.TrackingWindow) (trackingWindowSpec, error) {
+	var spec trackingWindowSpec
+	if len(w.Days) == 0 {
+		return spec, fmt.Errorf("окно %s–%s: не указаны дни недели", w.Start, w.End)
+	}
+	for _, d := range w.Days {
+		if d < 1 || d > 7 {
+			return spec, fmt.Errorf("окно %s–%s: день недели %d вне 1..7", w.Start, w.End, d)
+		}
+		spec.days[d] = true
+	}
+	var err error
+	if spec.start, err = parseClockMinutes(w.Start, false); err != nil {
+		return spec, err
+	}
+	if spec.end, err = parseClockMinutes(w.End, true); err != nil {
+		return spec, err
+	}
+	if spec.start == spec.end {
+		return spec, fmt.Errorf("окно %s–%s: пустой интервал", w.Start, w.End)
+	}
+	return spec, nil
+}
+
+// parseClockMinutes — "HH:MM" в минуты от начала суток; "24:00" допустимо только для конца окна.
+func parseClockMinutes(s string, allowEndOfDay bool) (int, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) == 2 && len(parts[0]) == 2 && len(parts[1]) == 2 {
+		h, errH := strconv.Atoi(parts[0])
+		m, errM := strconv.Atoi(parts[1])
+		if errH == nil && errM == nil && m >= 0 && m < 60 {
+			if h >= 0 && h < 24 {
+				return h*60 + m, nil
+			}
+			if allowEndOfDay && h == 24 && m == 0 {
+				return 24 * 60, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("время %q должно быть в формате HH:MM", s)
+}
+
+// isoWeekday — 1 понедельник … 7 воскресенье.
+func isoWeekday(t time.Time) int {
+	if wd := int(t.Weekday()); wd != 0 {
+		return wd
+	}
+	return 7
+}
+
+func (p *trackingPlan) dayAt(day time.Time, minutes int) time.Time {
+	// time.Date сам переносит 24:00 на следующие сутки и учитывает переход на летнее время.
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, p.loc)
+}
+
+// allowedSpans — интервалы внутри [from, to), когда отслеживание разрешено.
+func (p *trackingPlan) allowedSpans(from, to time.Time) []timeSpan {
+	if !to.After(from) {
+		return nil
+	}
+	localFrom := from.In(p.loc)
+	// Окно через полночь, начатое накануне, тоже может попасть в период.
+	day := time.Date(localFrom.Year(), localFrom.Month(), localFrom.Day(), 0, 0, 0, 0, p.loc).AddDate(0, 0, -1)
+
+	var spans, holidays []timeSpan
+	for !day.After(to) {
+		next := day.AddDate(0, 0, 1)
+		if _, ok := p.holidays[day.Format("2006-01-02")]; ok {
+			holidays = append(holidays, timeSpan{From: day, To: next})
+		} else {
+			wd := isoWeekday(day)
+			for _, w := range p.windows {
+				if !w.days[wd] {
+					continue
+				}
+				end := p.dayAt(day, w.end)
+				if w.end <= w.start {
+					end = p.dayAt(next, w.end)
+				}
+				spans = append(spans, timeSpan{From: p.dayAt(day, w.start), To: end})
+			}
+		}
+		day = next
+	}
+
+	spans = subtractSpans(mergeSpans(spans), holidays)
+	if p.consentRequired {
+		if p.consentFrom == nil {
+			return nil
+		}
+		consent := timeSpan{From: *p.consentFrom, To: to}
+		if p.consentUntil != nil {
+			consent.To = *p.consentUntil
+		}
+		spans = intersectSpans(spans, consent)
+	}
+	return intersectSpans(spans, timeSpan{From: from, To: to})
+}
+
+// pausedSpans — дополнение allowedSpans внутри [from, to).
+func (p *trackingPlan) pausedSpans(from, to time.Time) []timeSpan {
+	return subtractSpans([]timeSpan{{From: from, To: to}}, p.allowedSpans(from, to))
+}
+
+// allowedAt — разрешено ли отслеживание в момент t.
+func (p *trackingPlan) allowedAt(t time.Time) bool {
+	return len(p.allowedSpans(t, t.Add(time.Second))) > 0
+}
+
+// trackingPlanHorizon — насколько далеко ищется ближайшая смена состояния.
+const trackingPlanHorizon = 8 * 24 * time.Hour
+
+// currentSpan — состояние в момент now и интервал, в котором оно не меняется
+// (границы — не дальше trackingPlanHorizon; нулевое время — за горизонтом).
+func (p *trackingPlan) currentSpan(now time.Time) (paused bool, since, until time.Time) {
+	from, to := now.Add(-trackingPlanHorizon), now.Add(trackingPlanHorizon)
+	spans := p.allowedSpans(from, to)
+	if !p.allowedAt(now) {
+		spans = p.pausedSpans(from, to)
+		paused = true
+	}
+	for _, s := range spans {
+		if !now.Before(s.From) && now.Before(s.To) {
+			if s.From.After(from) {
+				since = s.From
+			}
+			if s.To.Before(to) {
+				until = s.To
+			}
+			break
+		}
+	}
+	return paused, since, until
+}
+
+// mergeSpans сортирует и склеивает пересекающиеся и смежные интервалы.
+func mergeSpans(spans []timeSpan) []timeSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	sorted := append([]timeSpan(nil), spans...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].From.Before(sorted[j].From) })
+	out := []timeSpan{sorted[0]}
+	for _, s := range sorted[1:] {
+		last := &out[len(out)-1]
+		if !s.From.After(last.To) {
+			if s.To.After(last.To) {
+				last.To = s.To
+			}
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// subtractSpans — части интервалов spans, не покрытые cut.
+func subtractSpans(spans, cut []timeSpan) []timeSpan {
+	cut = mergeSpans(cut)
+	var out []timeSpan
+	for _, s := range spans {
+		rest := []timeSpan{s}
+		for _, c := range cut {
+			var next []timeSpan
+			for _, r := range rest {
+				if !c.From.Before(r.To) || !c.To.After(r.From) {
+					next = append(next, r)
+					continue
+				}
+				if c.From.After(r.From) {
+					next = append(next, timeSpan{From: r.From, To: c.From})
+				}
+				if c.To.Before(r.To) {
+					next = append(next, timeSpan{From: c.To, To: r.To})
+				}
+			}
+			rest = next
+		}
+		out = append(out, rest...)
+	}
+	return out
+}
+
+// intersectSpans — части интервалов spans внутри bound.
+func intersectSpans(spans []timeSpan, bound timeSpan) []timeSpan {
+	var out []timeSpan
+	for _, s := range spans {
+		if s.From.Before(bound.From) {
+			s.From = bound.From
+		}
+		if s.To.After(bound.To) {
+			s.To = bound.To
+		}
+		if s.To.After(s.From) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,504 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTrackingScheduleNotFound = errors.New("tracking schedule not found")
+	ErrTrackingScheduleInvalid  = errors.New("invalid tracking schedule")
+	// ErrTrackingCheckFailed — окно отслеживания не удалось проверить: точка не
+	// сохраняется, устройство повторит отправку.
+	ErrTrackingCheckFailed = errors.New("tracking window check failed")
+)
+
+const (
+	trackingScheduleDefaultInterval = time.Minute
+	// trackingPrivacyReason — причина пропуска точки вне окна отслеживания (ответ POST /api/location).
+	trackingPrivacyReason = "privacy_window"
+	// trackingMaskPrecision — знаков после запятой у координат маскированной точки (~1 км).
+	trackingMaskPrecision = 100.0
+)
+
+// trackingVisitCloser — завершение активных визитов пользователя (VisitService).
+type trackingVisitCloser interface {
+	CloseActiveVisits(organizationID, userID int, at time.Time) (int, error)
+}
+
+// TrackingScheduleInput — график, заданный с админки.
+type TrackingScheduleInput struct {
+	Enabled         *bool                   `json:"enabled"`
+	Timezone        string                  `json:"timezone"`
+	Windows         []models.TrackingWindow `json:"windows"`
+	Holidays        []string                `json:"holidays"`
+	OutsideAction   string                  `json:"outside_action"`
+	ConsentRequired bool                    `json:"consent_required"`
+}
+
+// TrackingScheduleStatus — состояние отслеживания пользователя сейчас.
+type TrackingScheduleStatus struct {
+	Paused bool `json:"paused"`
+	// Reason — consent (нет согласия) или schedule (вне окна, праздник).
+	Reason string     `json:"reason,omitempty"`
+	Since  *time.Time `json:"since,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+	// Timezone — пояс, в котором действует график (его собственный или пользователя).
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// TrackingScheduleService — окна отслеживания и согласие: пауза и возобновление
+// телефона на границах окон, фильтр точек при приёме и интервалы паузы для визитов.
+type TrackingScheduleService struct {
+	DAO      trackingScheduleRepository
+	Users    userRepository
+	Commands deviceCommandEnqueuer
+	// Visits — завершение активных визитов при уходе на паузу (nil — не завершаются).
+	Visits trackingVisitCloser
+	// Timezones — пояс пользователя для графика без собственного пояса (nil — пояс по умолчанию).
+	Timezones userTimezoneSource
+	// Clock — текущее время (nil — системное).
+	Clock Clock
+
+	mu sync.Mutex
+}
+
+func NewTrackingScheduleService(dao trackingScheduleRepository, users userRepository, commands deviceCommandEnqueuer) *TrackingScheduleService {
+	return &TrackingScheduleService{DAO: dao, Users: users, Commands: commands}
+}
+
+// Get возвращает график пользователя и состояние отслеживания на текущий момент.
+func (svc *TrackingScheduleService) Get(userID int) (*models.TrackingSchedule, *TrackingScheduleStatus, error) {
+	s, err := svc.getSchedule(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	status, err := svc.scheduleStatus(s, clockNow(svc.Clock))
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, status, nil
+}
+
+// plan разбирает график s в поясе графика, а если он не задан — в поясе пользователя.
+func (svc *TrackingScheduleService) plan(s *models.TrackingSchedule) (*trackingPlan, error) {
+	var userLoc *time.Location
+	if s.Timezone == "" && svc.Timezones != nil {
+		userLoc = svc.Timezones.UserLocation(s.UserID)
+	}
+	return newTrackingPlan(s, userLoc)
+}
+
+func (svc *TrackingScheduleService) getSchedule(userID int) (*models.TrackingSchedule, error) {
+	s, err := svc.DAO.GetSchedule(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTrackingScheduleNotFound
+	}
+	return s, err
+}
+
+// userOrganization — организация пользователя: график хранится вместе с ней.
+func (svc *TrackingScheduleService) userOrganization(userID int) (int, error) {
+	user, err := svc.Users.GetByID(userID)
+	if err != nil {
+		return 0, err
+	}
+	return organizationOrDefault(user.OrganizationID), nil
+}
+
+// Save задаёт график пользователя (согласие сохраняется) и сразу отправляет
+// телефону график и текущее состояние паузы.
+func (svc *TrackingScheduleService) Save(userID int, in TrackingScheduleInput) (*models.TrackingSchedule, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	s, err := svc.getSchedule(userID)
+	if errors.Is(err, ErrTrackingScheduleNotFound) {
+		s = &models.TrackingSchedule{UserID: userID}
+	} else if err != nil {
+		return nil, err
+	}
+	if err := applyTrackingScheduleInput(s, in); err != nil {
+		return nil, err
+	}
+	if s.OrganizationID, err = svc.userOrganization(userID); err != nil {
+		return nil, err
+	}
+	// Новый график — телефон получит его с ближайшим проходом независимо от прошлой отправки.
+	s.LastPushedPaused = nil
+	s.LastPushedAt = nil
+	if err := svc.DAO.SaveSchedule(s); err != nil {
+		return nil, err
+	}
+	if _, err := svc.sync(s, clockNow(svc.Clock), true); err != nil {
+		slog.Warn("График сохранён, но не отправлен телефону", "user_id", userID, "error", err)
+	}
+	return s, nil
+}
+
+// Delete удаляет график: ограничения снимаются, телефон возобновляет отслеживание.
+func (svc *TrackingScheduleService) Delete(userID int) error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	s, err := svc.getSchedule(userID)
+	if err != nil {
+		return err
+	}
+	if err := svc.DAO.DeleteSchedule(userID); err != nil {
+		return err
+	}
+	if svc.Commands == nil || s.LastPushedPaused == nil {
+		return nil
+	}
+	payload := map[string]interface{}{"tracking_paused": false, "tracking_schedule": nil}
+	if _, err := svc.Commands.EnqueueCommand(userID, models.DeviceCommandTypeConfigUpdate, payload); err != nil {
+		slog.Warn("Не удалось снять паузу на телефоне", "user_id", userID, "error", err)
+	}
+	return nil
+}
+
+// RecordConsent фиксирует согласие на отслеживание (given = false — отзыв).
+// by — кто дал согласие, actorID — сотрудник, внёсший отметку.
+func (svc *TrackingScheduleService) RecordConsent(userID, actorID int, given bool, by string) (*models.TrackingSchedule, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	s, err := svc.getSchedule(userID)
+	if errors.Is(err, ErrTrackingScheduleNotFound) {
+		organizationID, err := svc.userOrganization(userID)
+		if err != nil {
+			return nil, err
+		}
+		// Согласие без графика: окна не ограничены, отслеживание зависит только от согласия.
+		s = &models.TrackingSchedule{
+			UserID:          userID,
+			OrganizationID:  organizationID,
+			Enabled:         true,
+			OutsideAction:   models.TrackingOutsideDrop,
+			ConsentRequired: true,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := clockNow(svc.Clock).UTC()
+	if given {
+		by = strings.TrimSpace(by)
+		if by == "" || len([]rune(by)) > 100 {
+			return nil, ErrTrackingScheduleInvalid
+		}
+		s.ConsentGivenAt = &now
+		s.ConsentBy = by
+		s.ConsentRevokedAt = nil
+	} else {
+		if s.ConsentGivenAt == nil || s.ConsentRevokedAt != nil {
+			return s, nil
+		}
+		s.ConsentRevokedAt = &now
+	}
+	s.ConsentRecordedBy = &actorID
+	if err := svc.DAO.SaveSchedule(s); err != nil {
+		return nil, err
+	}
+	if _, err := svc.sync(s, now, false); err != nil {
+		slog.Warn("Не удалось отправить состояние отслеживания телефону", "user_id", userID, "error", err)
+	}
+	return s, nil
+}
+
+// CheckPoint — можно ли принять точку пользователя, снятую в момент at. При
+// запрете action — что с ней делать (models.TrackingOutsideDrop или Mask).
+func (svc *TrackingScheduleService) CheckPoint(userID int, at time.Time) (bool, string, error) {
+	s, plan, err := svc.enabledPlan(userID)
+	if err != nil || plan == nil || plan.allowedAt(at) {
+		return true, "", err
+	}
+	action := s.OutsideAction
+	if action != models.TrackingOutsideMask {
+		action = models.TrackingOutsideDrop
+	}
+	return false, action, nil
+}
+
+// enabledPlan — включённый график пользователя; plan = nil — графика нет или он выключен.
+func (svc *TrackingScheduleService) enabledPlan(userID int) (*models.TrackingSchedule, *trackingPlan, error) {
+	s, err := svc.getSchedule(userID)
+	if errors.Is(err, ErrTrackingScheduleNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil || !s.Enabled {
+		return s, nil, err
+	}
+	plan, err := svc.plan(s)
+	if err != nil {
+		return s, nil, err
+	}
+	return s, plan, nil
+}
+
+// PausedSpans — интервалы внутри [from, to), когда отслеживание пользователя было
+// приостановлено (пусто — графика нет или он выключен).
+func (svc *TrackingScheduleService) PausedSpans(userID int, from, to time.Time) ([]timeSpan, error) {
+	_, plan, err := svc.enabledPlan(userID)
+	if err != nil || plan == nil {
+		return nil, err
+	}
+	return plan.pausedSpans(from, to), nil
+}
+
+// TrackingPausedAt — приостановлено ли отслеживание пользователя графиком в момент at;
+// managed = false — графика нет (или он выключен), tracking_paused задаёт только конфигурация.
+func (svc *TrackingScheduleService) TrackingPausedAt(userID int, at time.Time) (paused, managed bool, err error) {
+	_, plan, err := svc.enabledPlan(userID)
+	if err != nil || plan == nil {
+		return false, false, err
+	}
+	return !plan.allowedAt(at), true, nil
+}
+
+// PausedUsers — пользователи, чьё отслеживание приостановлено в момент now (движок алертов).
+func (svc *TrackingScheduleService) PausedUsers(now time.Time) (map[int]bool, error) {
+	schedules, err := svc.DAO.GetEnabledSchedules()
+	if err != nil {
+		return nil, err
+	}
+	paused := make(map[int]bool)
+	for i := range schedules {
+		plan, err := svc.plan(&schedules[i])
+		if err != nil {
+			slog.Warn("Некорректный график отслеживания", "user_id", schedules[i].UserID, "error", err)
+			continue
+		}
+		if !plan.allowedAt(now) {
+			paused[schedules[i].UserID] = true
+		}
+	}
+	return paused, nil
+}
+
+// Run — фоновый цикл: раз в interval отправляет паузу и возобновление на границах окон.
+func (svc *TrackingScheduleService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = trackingScheduleDefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := svc.Apply(clockNow(svc.Clock)); err != nil {
+			slog.Error("Ошибка применения графиков отслеживания", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Apply сверяет состояние каждого включённого графика на момент now с последним
+// отправленным телефону и при смене ставит config_update. Возвращает число отправок.
+func (svc *TrackingScheduleService) Apply(now time.Time) (int, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	schedules, err := svc.DAO.GetEnabledSchedules()
+	if err != nil {
+		return 0, err
+	}
+	pushed := 0
+	for i := range schedules {
+		ok, err := svc.sync(&schedules[i], now, false)
+		if err != nil {
+			slog.Error("Ошибка синхронизации графика отслеживания", "user_id", schedules[i].UserID, "error", err)
+			continue
+		}
+		if ok {
+			pushed++
+		}
+	}
+	return pushed, nil
+}
+
+// sync отправляет телефону состояние паузы, если оно изменилось (withSchedule —
+// вместе с самим графиком), и при уходе на паузу завершает визиты на её границе.
+// true — телефону поставлен config_update.
+func (svc *TrackingScheduleService) sync(s *models.TrackingSchedule, now time.Time, withSchedule bool) (bool, error) {
+	paused := false
+	var since time.Time
+	var plan *trackingPlan
+	if s.Enabled {
+		var err error
+		if plan, err = svc.plan(s); err != nil {
+			return false, err
+		}
+		paused, since, _ = plan.currentSpan(now)
+	}
+	if !withSchedule && s.LastPushedPaused != nil && *s.LastPushedPaused == paused {
+		return false, nil
+	}
+
+	if paused && svc.Visits != nil {
+		if since.IsZero() {
+			since = now
+		}
+		if n, err := svc.Visits.CloseActiveVisits(s.OrganizationID, s.UserID, since); err != nil {
+			slog.Error("Не удалось завершить визиты на границе паузы", "user_id", s.UserID, "error", err)
+		} else if n > 0 {
+			slog.Info("Завершены визиты на границе паузы", "user_id", s.UserID, "visits", n)
+		}
+	}
+
+	if svc.Commands == nil {
+		return false, nil
+	}
+	payload := map[string]interface{}{"tracking_paused": paused}
+	if withSchedule {
+		payload["tracking_schedule"] = deviceTrackingSchedule(s, plan)
+	}
+	cmd, err := svc.Commands.EnqueueCommand(s.UserID, models.DeviceCommandTypeConfigUpdate, payload)
+	if err != nil {
+		return false, fmt.Errorf("config_update: %w", err)
+	}
+	at := now.UTC()
+	s.LastPushedPaused = &paused
+	s.LastPushedAt = &at
+	if err := svc.DAO.MarkPushed(s.UserID, paused, at); err != nil {
+		slog.Error("Не удалось сохранить отметку отправки графика", "user_id", s.UserID, "error", err)
+	}
+	slog.Info("Состояние отслеживания отправлено телефону", "user_id", s.UserID, "tracking_paused", paused, "command_id", cmd.ID)
+	return true, nil
+}
+
+// deviceTrackingSchedule — график для телефона: он сам соблюдает окна без связи с сервером.
+// Пояс — фактический (графика или пользователя). nil — ограничений нет.
+func deviceTrackingSchedule(s *models.TrackingSchedule, plan *trackingPlan) map[string]interface{} {
+	if !s.Enabled || plan == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"timezone":      plan.loc.String(),
+		"windows":       json.RawMessage(jsonOrEmptyArray(s.Windows)),
+		"holidays":      json.RawMessage(jsonOrEmptyArray(s.Holidays)),
+		"consent_given": s.HasConsent(),
+	}
+}
+
+func jsonOrEmptyArray(data []byte) []byte {
+	if len(data) == 0 {
+		return []byte("[]")
+	}
+	return data
+}
+
+// scheduleStatus — состояние отслеживания по графику s на момент now; границы —
+// в поясе графика.
+func (svc *TrackingScheduleService) scheduleStatus(s *models.TrackingSchedule, now time.Time) (*TrackingScheduleStatus, error) {
+	status := &TrackingScheduleStatus{}
+	if !s.Enabled {
+		return status, nil
+	}
+	plan, err := svc.plan(s)
+	if err != nil {
+		return nil, err
+	}
+	paused, since, until := plan.currentSpan(now)
+	status.Paused = paused
+	status.Timezone = plan.loc.String()
+	if !since.IsZero() {
+		since = since.In(plan.loc)
+		status.Since = &since
+	}
+	if !until.IsZero() {
+		until = until.In(plan.loc)
+		status.Until = &until
+	}
+	if paused {
+		status.Reason = "schedule"
+		if !s.HasConsent() {
+			status.Reason = "consent"
+		}
+	}
+	return status, nil
+}
+
+// applyTrackingScheduleInput проверяет ввод и переносит его в s.
+func applyTrackingScheduleInput(s *models.TrackingSchedule, in TrackingScheduleInput) error {
+	// Пустой пояс — график следует поясу пользователя.
+	tz, err := ValidateTimezone(in.Timezone)
+	if err != nil {
+		return fmt.Errorf("%w: неизвестный часовой пояс %q", ErrTrackingScheduleInvalid, strings.TrimSpace(in.Timezone))
+	}
+	for _, w := range in.Windows {
+		if _, err := parseTrackingWindow(w); err != nil {
+			return fmt.Errorf("%w: %v", ErrTrackingScheduleInvalid, err)
+		}
+	}
+	holidays := make([]string, 0, len(in.Holidays))
+	seen := make(map[string]struct{}, len(in.Holidays))
+	for _, h := range in.Holidays {
+		h = strings.TrimSpace(h)
+		if _, err := time.Parse("2006-01-02", h); err != nil {
+			return fmt.Errorf("%w: дата %q должна быть в формате YYYY-MM-DD", ErrTrackingScheduleInvalid, h)
+		}
+		if _, dup := seen[h]; !dup {
+			seen[h] = struct{}{}
+			holidays = append(holidays, h)
+		}
+	}
+	sort.Strings(holidays)
+
+	action := strings.TrimSpace(in.OutsideAction)
+	switch action {
+	case "":
+		action = models.TrackingOutsideDrop
+	case models.TrackingOutsideDrop, models.TrackingOutsideMask:
+	default:
+		return fmt.Errorf("%w: outside_action должен быть drop или mask", ErrTrackingScheduleInvalid)
+	}
+
+	windows := in.Windows
+	if windows == nil {
+		windows = []models.TrackingWindow{}
+	}
+	windowsJSON, err := json.Marshal(windows)
+	if err != nil {
+		return err
+	}
+	holidaysJSON, err := json.Marshal(holidays)
+	if err != nil {
+		return err
+	}
+
+	s.Enabled = in.Enabled == nil || *in.Enabled
+	s.Timezone = tz
+	s.Windows = windowsJSON
+	s.Holidays = holidaysJSON
+	s.OutsideAction = action
+	s.ConsentRequired = in.ConsentRequired
+	return nil
+}
+
+// maskLocation огрубляет координаты точки вне окна отслеживания.
+func maskLocation(loc *models.Location) {
+	loc.Latitude = roundCoordinate(loc.Latitude)
+	loc.Longitude = roundCoordinate(loc.Longitude)
+	loc.Masked = true
+}
+
+func roundCoordinate(v float64) float64 {
+	return math.Round(v*trackingMaskPrecision) / trackingMaskPrecision
+}
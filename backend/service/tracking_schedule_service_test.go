@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"locator/internal/testutil"
+	"locator/models"
+
+	"gorm.io/gorm"
+)
+
+type fakeTrackingScheduleRepo struct {
+	items map[int]models.TrackingSchedule
+}
+
+func newFakeTrackingScheduleRepo() *fakeTrackingScheduleRepo {
+	return &fakeTrackingScheduleRepo{items: make(map[int]models.TrackingSchedule)}
+}
+
+func (f *fakeTrackingScheduleRepo) GetSchedule(userID int) (*models.TrackingSchedule, error) {
+	s, ok := f.items[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &s, nil
+}
+
+func (f *fakeTrackingScheduleRepo) SaveSchedule(s *models.TrackingSchedule) error {
+	f.items[s.UserID] = *s
+	return nil
+}
+
+func (f *fakeTrackingScheduleRepo) DeleteSchedule(userID int) error {
+	delete(f.items, userID)
+	return nil
+}
+
+func (f *fakeTrackingScheduleRepo) GetEnabledSchedules() ([]models.TrackingSchedule, error) {
+	var out []models.TrackingSchedule
+	for _, s := range f.items {
+		if s.Enabled {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeTrackingScheduleRepo) MarkPushed(userID int, paused bool, at time.Time) error {
+	s := f.items[userID]
+	s.LastPushedPaused = &paused
+	s.LastPushedAt = &at
+	f.items[userID] = s
+	return nil
+}
+
+type fakeVisitCloser struct {
+	closedAt []time.Time
+}
+
+func (f *fakeVisitCloser) CloseActiveVisits(organizationID, userID int, at time.Time) (int, error) {
+	f.closedAt = append(f.closedAt, at)
+	return 1, nil
+}
+
+type fakeTrackingPrivacy struct {
+	allowed bool
+	action  string
+	err     error
+}
+
+func (f fakeTrackingPrivacy) CheckPoint(userID int, at time.Time) (bool, string, error) {
+	return f.allowed, f.action, f.err
+}
+
+// minskTime — местное время Europe/Minsk (UTC+3, без перехода на летнее время).
+func minskTime(t *testing.T, day, hour, min int) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Minsk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return time.Date(2026, 10, day, hour, min, 0, 0, loc)
+}
+
+func newTestTrackingSchedule(t *testing.T, in TrackingScheduleInput) *models.TrackingSchedule {
+	t.Helper()
+	s := &models.TrackingSchedule{UserID: 1}
+	if err := applyTrackingScheduleInput(s, in); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newTestTrackingScheduleService(clock *testutil.Clock) (*TrackingScheduleService, *fakeTrackingScheduleRepo, *fakeCommandEnqueuer) {
+	repo := newFakeTrackingScheduleRepo()
+	commands := &fakeCommandEnqueuer{}
+	svc := NewTrackingScheduleService(repo, newFakeUserRepo(models.User{ID: 1}), commands)
+	svc.Clock = clock
+	return svc, repo, commands
+}
+
+func TestTrackingPlan_overnightWindowAndHoliday(t *testing.T) {
+	// 19.10.2026 — понедельник; 20.10 — праздник.
+	s := newTestTrackingSchedule(t, TrackingScheduleInput{
+		Windows:  []models.TrackingWindow{{Days: []int{1, 2, 3, 4, 5}, Start: "22:00", End: "06:00"}},
+		Holidays: []string{"2026-10-20", "2026-10-20"},
+	})
+	if string(s.Holidays) != `["2026-10-20"]` {
+		t.Fatalf("holidays=%s", s.Holidays)
+	}
+	plan, err := newTrackingPlan(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		at      time.Time
+		allowed bool
+	}{
+		{"понедельник вечером", minskTime(t, 19, 23, 0), true},
+		{"утро праздника", minskTime(t, 20, 5, 0), false},
+		{"праздник днём", minskTime(t, 20, 12, 0), false},
+		{"ночь после праздника", minskTime(t, 21, 5, 0), false},
+		{"среда вечером", minskTime(t, 21, 23, 0), true},
+		{"утро четверга", minskTime(t, 22, 5, 59), true},
+		{"четверг 06:00", minskTime(t, 22, 6, 0), false},
+	}
+	for _, tc := range cases {
+		if got := plan.allowedAt(tc.at); got != tc.allowed {
+			t.Errorf("%s: allowed=%v, want %v", tc.name, got, tc.allowed)
+		}
+	}
+}
+
+func TestTrackingPlan_endOfDay(t *testing.T) {
+	s := newTestTrackingSchedule(t, TrackingScheduleInput{
+		Windows: []models.TrackingWindow{{Days: []int{1}, Start: "08:00", End: "24:00"}},
+	})
+	plan, err := newTrackingPlan(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.allowedAt(minskTime(t, 19, 23, 59)) {
+		t.Fatal("23:59 должно быть в окне")
+	}
+	if plan.allowedAt(minskTime(t, 20, 0, 0)) {
+		t.Fatal("00:00 вторника вне окна")
+	}
+
+	paused, since, until := plan.currentSpan(minskTime(t, 19, 12, 0))
+	if paused || !since.Equal(minskTime(t, 19, 8, 0)) || !until.Equal(minskTime(t, 20, 0, 0)) {
+		t.Fatalf("paused=%v since=%v until=%v", paused, since, until)
+	}
+
+	if _, err := parseClockMinutes("24:00", false); err == nil {
+		t.Fatal("24:00 допустимо только как конец окна")
+	}
+}
+
+func TestApplyTrackingScheduleInput_rejectsInvalid(t *testing.T) {
+	cases := []TrackingScheduleInput{
+		{Timezone: "Mars/Olympus"},
+		{Windows: []models.TrackingWindow{{Days: []int{8}, Start: "09:00", End: "18:00"}}},
+		{Windows: []models.TrackingWindow{{Days: []int{1}, Start: "9:00", End: "18:00"}}},
+		{Windows: []models.TrackingWindow{{Days: []int{1}, Start: "09:00", End: "09:00"}}},
+		{Holidays: []string{"20.10.2026"}},
+		{OutsideAction: "blur"},
+	}
+	for i, in := range cases {
+		if err := applyTrackingScheduleInput(&models.TrackingSchedule{}, in); err == nil {
+			t.Errorf("case %d: ожидалась ошибка", i)
+		}
+	}
+}
+
+func TestTrackingSchedule_consent(t *testing.T) {
+	now := minskTime(t, 19, 12, 0)
+	clock := testutil.NewClock(now)
+	svc, _, commands := newTestTrackingScheduleService(clock)
+
+	if _, err := svc.Save(1, TrackingScheduleInput{ConsentRequired: true}); err != nil {
+		t.Fatal(err)
+	}
+	_, status, err := svc.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Paused || status.Reason != "consent" {
+		t.Fatalf("status=%+v", status)
+	}
+	if allowed, _, _ := svc.CheckPoint(1, now); allowed {
+		t.Fatal("без согласия точка не принимается")
+	}
+
+	if _, err := svc.RecordConsent(1, 9, true, ""); err == nil {
+		t.Fatal("согласие без автора должно отклоняться")
+	}
+	if _, err := svc.RecordConsent(1, 9, true, "Иванов И. И."); err != nil {
+		t.Fatal(err)
+	}
+	if allowed, _, _ := svc.CheckPoint(1, now.Add(time.Minute)); !allowed {
+		t.Fatal("после согласия точка принимается")
+	}
+	// До согласия точка по-прежнему запрещена (офлайн-очередь).
+	if allowed, _, _ := svc.CheckPoint(1, now.Add(-time.Hour)); allowed {
+		t.Fatal("точка до согласия не принимается")
+	}
+
+	now = clock.Advance(time.Hour)
+	if _, err := svc.RecordConsent(1, 9, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	if allowed, _, _ := svc.CheckPoint(1, now.Add(time.Minute)); allowed {
+		t.Fatal("после отзыва согласия точка не принимается")
+	}
+	if allowed, _, _ := svc.CheckPoint(1, now.Add(-30*time.Minute)); !allowed {
+		t.Fatal("точка в период согласия принимается")
+	}
+
+	// Save (paused) → согласие (resume) → отзыв (pause).
+	want := []bool{true, false, true}
+	if len(commands.payloads) != len(want) {
+		t.Fatalf("payloads=%v", commands.payloads)
+	}
+	for i, p := range commands.payloads {
+		if p["tracking_paused"] != want[i] {
+			t.Errorf("push %d: tracking_paused=%v, want %v", i, p["tracking_paused"], want[i])
+		}
+	}
+}
+
+func TestTrackingSchedule_applyPushesAtBoundary(t *testing.T) {
+	now := minskTime(t, 19, 17, 59)
+	svc, repo, commands := newTestTrackingScheduleService(testutil.NewClock(now))
+	visits := &fakeVisitCloser{}
+	svc.Visits = visits
+
+	if _, err := svc.Save(1, TrackingScheduleInput{
+		Windows: []models.TrackingWindow{{Days: []int{1, 2, 3, 4, 5, 6, 7}, Start: "09:00", End: "18:00"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(commands.payloads) != 1 || commands.payloads[0]["tracking_paused"] != false ||
+		commands.payloads[0]["tracking_schedule"] == nil {
+		t.Fatalf("payloads=%v", commands.payloads)
+	}
+
+	if n, err := svc.Apply(minskTime(t, 19, 17, 59)); err != nil || n != 0 {
+		t.Fatalf("before boundary: n=%d err=%v", n, err)
+	}
+	if n, err := svc.Apply(minskTime(t, 19, 18, 1)); err != nil || n != 1 {
+		t.Fatalf("after boundary: n=%d err=%v", n, err)
+	}
+	if commands.payloads[1]["tracking_paused"] != true {
+		t.Fatalf("payload=%v", commands.payloads[1])
+	}
+	if _, ok := commands.payloads[1]["tracking_schedule"]; ok {
+		t.Fatal("на границе окна график повторно не отправляется")
+	}
+	if len(visits.closedAt) != 1 || !visits.closedAt[0].Equal(minskTime(t, 19, 18, 0)) {
+		t.Fatalf("visits closed at %v", visits.closedAt)
+	}
+	if p := repo.items[1].LastPushedPaused; p == nil || !*p {
+		t.Fatal("отметка отправки не сохранена")
+	}
+
+	if n, _ := svc.Apply(minskTime(t, 19, 18, 30)); n != 0 {
+		t.Fatalf("repeat push: n=%d", n)
+	}
+	if n, _ := svc.Apply(minskTime(t, 20, 9, 0)); n != 1 || commands.payloads[2]["tracking_paused"] != false {
+		t.Fatalf("resume: n=%d payloads=%v", n, commands.payloads)
+	}
+
+	if err := svc.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+	last := commands.payloads[len(commands.payloads)-1]
+	if last["tracking_paused"] != false || last["tracking_schedule"] != nil {
+		t.Fatalf("delete payload=%v", last)
+	}
+}
+
+func TestTrackingSchedule_checkPointAction(t *testing.T) {
+	now := minskTime(t, 19, 12, 0)
+	svc, _, _ := newTestTrackingScheduleService(testutil.NewClock(now))
+	disabled := false
+
+	if allowed, _, err := svc.CheckPoint(1, now); err != nil || !allowed {
+		t.Fatal("без графика точка принимается")
+	}
+	if _, err := svc.Save(1, TrackingScheduleInput{
+		Windows:       []models.TrackingWindow{{Days: []int{1}, Start: "09:00", End: "18:00"}},
+		OutsideAction: models.TrackingOutsideMask,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if allowed, _, _ := svc.CheckPoint(1, now); !allowed {
+		t.Fatal("точка в окне принимается")
+	}
+	allowed, action, _ := svc.CheckPoint(1, minskTime(t, 19, 20, 0))
+	if allowed || action != models.TrackingOutsideMask {
+		t.Fatalf("allowed=%v action=%q", allowed, action)
+	}
+
+	if _, err := svc.Save(1, TrackingScheduleInput{
+		Enabled: &disabled,
+		Windows: []models.TrackingWindow{{Days: []int{1}, Start: "09:00", End: "18:00"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if allowed, _, _ := svc.CheckPoint(1, minskTime(t, 19, 20, 0)); !allowed {
+		t.Fatal("выключенный график не ограничивает")
+	}
+}
+
+func TestCreateLocation_privacyWindow(t *testing.T) {
+	repo := newFakeLocationRepo()
+	svc := newTestLocationService(repo)
+
+	svc.Privacy = fakeTrackingPrivacy{action: models.TrackingOutsideDrop}
+	got, reason, err := svc.CreateLocation(context.Background(), 1, 53.90123, 27.55678, "", models.LocationSourcePeriodic, nil, nil)
+	if err != nil || got != nil || reason != trackingPrivacyReason {
+		t.Fatalf("drop: got=%v reason=%q err=%v", got, reason, err)
+	}
+
+	svc.Privacy = fakeTrackingPrivacy{action: models.TrackingOutsideMask}
+	got, reason, err = svc.CreateLocation(context.Background(), 1, 53.90123, 27.55678, "", models.LocationSourcePeriodic, nil, nil)
+	if err != nil || got == nil || reason != "" {
+		t.Fatalf("mask: got=%v reason=%q err=%v", got, reason, err)
+	}
+	if !got.Masked || got.Latitude != 53.9 || got.Longitude != 27.56 {
+		t.Fatalf("masked=%v lat=%v lon=%v", got.Masked, got.Latitude, got.Longitude)
+	}
+	if len(repo.byUser[1]) != 1 {
+		t.Fatalf("stored=%d", len(repo.byUser[1]))
+	}
+
+	// Ошибка проверки не пропускает точку без маски.
+	svc.Privacy = fakeTrackingPrivacy{allowed: true, err: errors.New("connection refused")}
+	got, _, err = svc.CreateLocation(context.Background(), 1, 53.90123, 27.55678, "", models.LocationSourcePeriodic, nil, nil)
+	if !errors.Is(err, ErrTrackingCheckFailed) || got != nil || len(repo.byUser[1]) != 1 {
+		t.Fatalf("check error: got=%v err=%v stored=%d", got, err, len(repo.byUser[1]))
+	}
+}
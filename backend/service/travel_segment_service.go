@@ -1,27 +1,34 @@
 package service
 
 import (
-	"locator/dao"
 	"locator/models"
 	"sort"
 	"time"
 )
 
+// trackingPauseSource — интервалы паузы отслеживания пользователя (TrackingScheduleService).
+type trackingPauseSource interface {
+	PausedSpans(userID int, from, to time.Time) ([]timeSpan, error)
+}
+
 // TravelSegmentService строит интервалы перемещения вне всех чекпоинтов по GPS-точкам.
 type TravelSegmentService struct {
-	LocationDAO       *dao.LocationDAO
+	LocationDAO       locationHistoryRepository
 	CheckpointService *CheckpointService
+	// Privacy — окна отслеживания: участки не строятся через время паузы (nil — без ограничений).
+	Privacy trackingPauseSource
 }
 
-func NewTravelSegmentService(locationDAO *dao.LocationDAO, checkpointService *CheckpointService) *TravelSegmentService {
+func NewTravelSegmentService(locationDAO locationHistoryRepository, checkpointService *CheckpointService) *TravelSegmentService {
 	return &TravelSegmentService{
 		LocationDAO:       locationDAO,
 		CheckpointService: checkpointService,
 	}
 }
 
-// GetOutsideSegments возвращает участки, когда пользователь не находился ни в одном чекпоинте.
-func (s *TravelSegmentService) GetOutsideSegments(userID int, from, to time.Time) ([]models.Visit, error) {
+// GetOutsideSegments возвращает участки, когда пользователь не находился ни в одном
+// чекпоинте своей организации organizationID.
+func (s *TravelSegmentService) GetOutsideSegments(organizationID, userID int, from, to time.Time) ([]models.Visit, error) {
 	locations, err := s.LocationDAO.GetLocationsByUserBetween(userID, from, to)
 	if err != nil {
 		return nil, err
@@ -30,18 +37,28 @@ func (s *TravelSegmentService) GetOutsideSegments(userID int, from, to time.Time
 		return nil, nil
 	}
 
-	checkpoints, err := s.CheckpointService.GetCheckpoints()
+	checkpoints, err := s.CheckpointService.GetCheckpoints(organizationID)
 	if err != nil {
 		return nil, err
 	}
 
-	return buildOutsideSegments(userID, locations, checkpoints, geofenceMinVisitSeconds()), nil
+	var paused []timeSpan
+	if s.Privacy != nil {
+		if paused, err = s.Privacy.PausedSpans(userID, from, to); err != nil {
+			return nil, err
+		}
+	}
+
+	return buildOutsideSegments(userID, locations, checkpoints, paused, geofenceMinVisitSeconds()), nil
 }
 
+// buildOutsideSegments — участки вне чекпоинтов; точки внутри paused пропускаются,
+// а пауза между точками обрывает участок.
 func buildOutsideSegments(
 	userID int,
 	locations []models.Location,
 	checkpoints []models.Checkpoint,
+	paused []timeSpan,
 	minDurationSec int,
 ) []models.Visit {
 	var segments []models.Visit
@@ -74,13 +91,21 @@ func buildOutsideSegments(
 	}
 
 	for _, loc := range locations {
+		t := loc.EffectiveAt().UTC()
+		if spansOverlap(paused, t, t.Add(time.Nanosecond)) {
+			flush()
+			continue
+		}
+		if segmentStart != nil && spansOverlap(paused, lastOutside, t) {
+			flush()
+		}
+
 		inside := false
 		if len(checkpoints) > 0 {
 			inside = isInsideAnyCheckpoint(loc.Latitude, loc.Longitude, checkpoints)
 		}
 
 		if !inside {
-			t := loc.EffectiveAt().UTC()
 			if segmentStart == nil {
 				segmentStart = &t
 			}
@@ -94,6 +119,16 @@ func buildOutsideSegments(
 	return segments
 }
 
+// spansOverlap — пересекается ли какой-либо из spans с [from, to).
+func spansOverlap(spans []timeSpan, from, to time.Time) bool {
+	for _, sp := range spans {
+		if sp.From.Before(to) && sp.To.After(from) {
+			return true
+		}
+	}
+	return false
+}
+
 func isInsideAnyCheckpoint(lat, lon float64, checkpoints []models.Checkpoint) bool {
 	for i := range checkpoints {
 		if haversineDistance(lat, lon, checkpoints[i].Latitude, checkpoints[i].Longitude) <= checkpoints[i].Radius {
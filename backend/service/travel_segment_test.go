@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"locator/internal/testutil"
+	"locator/internal/testutil/memrepo"
 	"locator/models"
 )
 
@@ -22,7 +24,7 @@ func TestBuildOutsideSegments(t *testing.T) {
 		{UserID: 1, Latitude: 53.91, Longitude: 27.51, CreatedAt: base.Add(3 * time.Minute)},
 	}
 
-	segments := buildOutsideSegments(1, locations, checkpoints, 60)
+	segments := buildOutsideSegments(1, locations, checkpoints, nil, 60)
 	if len(segments) != 1 {
 		t.Fatalf("expected 1 outside segment, got %d", len(segments))
 	}
@@ -40,8 +42,60 @@ func TestBuildOutsideSegments_skipsShort(t *testing.T) {
 		{UserID: 1, Latitude: 54.0, Longitude: 28.0, CreatedAt: base},
 		{UserID: 1, Latitude: 54.0, Longitude: 28.0, CreatedAt: base.Add(5 * time.Second)},
 	}
-	segments := buildOutsideSegments(1, locations, nil, 60)
+	segments := buildOutsideSegments(1, locations, nil, nil, 60)
 	if len(segments) != 0 {
 		t.Fatalf("expected no segments for short outside period, got %d", len(segments))
 	}
 }
+
+func TestBuildOutsideSegments_splitsAtTrackingPause(t *testing.T) {
+	base := time.Date(2026, 5, 15, 17, 0, 0, 0, time.UTC)
+	var locations []models.Location
+	for i := 0; i <= 120; i += 10 {
+		locations = append(locations, models.Location{UserID: 1, Latitude: 54.0, Longitude: 28.0, CreatedAt: base.Add(time.Duration(i) * time.Minute)})
+	}
+	// Пауза 18:00–18:30: точки внутри не учитываются, участок разрывается.
+	paused := []timeSpan{{From: base.Add(time.Hour), To: base.Add(90 * time.Minute)}}
+
+	segments := buildOutsideSegments(1, locations, nil, paused, 60)
+	if len(segments) != 2 {
+		t.Fatalf("expected 2 segments around the pause, got %d: %+v", len(segments), segments)
+	}
+	for _, seg := range segments {
+		if seg.StartAt.Before(paused[0].To) && seg.EndAt.After(paused[0].From) {
+			t.Fatalf("segment overlaps the pause: %+v", seg)
+		}
+	}
+}
+
+func TestGetOutsideSegments_readsUserHistory(t *testing.T) {
+	base := testutil.FixedUTC(2026, 7, 1, 9, 0, 0)
+	store := memrepo.New(testutil.NewClock(base))
+	home := testutil.Checkpoint(0, "дом", 53.9, 27.5, 100)
+	if err := store.Checkpoints.Create(&home); err != nil {
+		t.Fatal(err)
+	}
+	// Пользователь 1: дома, 10 минут вне чекпоинтов, снова дома; точки пользователя 2 не мешают.
+	for i, lat := range []float64{53.9, 53.95, 53.95, 53.95, 53.9} {
+		loc := testutil.Location(0, 1, lat, 27.5, base.Add(time.Duration(i)*5*time.Minute))
+		if err := store.Locations.Create(&loc); err != nil {
+			t.Fatal(err)
+		}
+		other := testutil.Location(0, 2, 53.9, 27.5, base.Add(time.Duration(i)*5*time.Minute))
+		if err := store.Locations.Create(&other); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := NewTravelSegmentService(store.Locations, NewCheckpointService(store.Checkpoints))
+	segments, err := svc.GetOutsideSegments(models.DefaultOrganizationID, 1, base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 {
+		t.Fatalf("expected 1 segment, got %+v", segments)
+	}
+	if !segments[0].StartAt.Equal(base.Add(5*time.Minute)) || segments[0].Duration != 10*60 {
+		t.Fatalf("unexpected segment %+v", segments[0])
+	}
+}
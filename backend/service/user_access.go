@@ -0,0 +1,330 @@
+package service
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"locator/models"
+)
+
+var (
+	ErrAccessDenied   = errors.New("недостаточно прав")
+	ErrInvalidRole    = errors.New("неизвестная роль")
+	ErrGroupNotFound  = errors.New("группа не найдена")
+	ErrGroupNameEmpty = errors.New("не указано имя группы")
+
+	ErrGroupMemberNotFound = errors.New("пользователь не состоит в группе")
+)
+
+// UserScope — множество пользователей, доступных сотруднику.
+// nil или all=true — без ограничений (внутренние вызовы и тесты); ScopeFor
+// всегда ограничивает область организацией сотрудника. Список ID загружается
+// при первой проверке: запросы, которым область не нужна, не обращаются к БД.
+type UserScope struct {
+	all  bool
+	ids  map[int]struct{}
+	load func() ([]int, error)
+	once sync.Once
+}
+
+// AllUsersScope — область видимости без ограничений.
+func AllUsersScope() *UserScope {
+	return &UserScope{all: true}
+}
+
+// NewUserScope — область видимости, ограниченная перечисленными пользователями.
+func NewUserScope(userIDs ...int) *UserScope {
+	s := &UserScope{ids: make(map[int]struct{}, len(userIDs))}
+	for _, id := range userIDs {
+		s.ids[id] = struct{}{}
+	}
+	return s
+}
+
+// All — доступны все пользователи.
+func (s *UserScope) All() bool {
+	return s == nil || s.all
+}
+
+// resolve загружает ID пользователей области; при ошибке доступны только
+// заранее известные (сам сотрудник).
+func (s *UserScope) resolve() {
+	if s.load == nil {
+		return
+	}
+	s.once.Do(func() {
+		ids, err := s.load()
+		if err != nil {
+			slog.Error("Ошибка загрузки области видимости", "error", err)
+			return
+		}
+		for _, id := range ids {
+			s.ids[id] = struct{}{}
+		}
+	})
+}
+
+// Allows сообщает, доступен ли пользователь.
+func (s *UserScope) Allows(userID int) bool {
+	if s.All() {
+		return true
+	}
+	if _, ok := s.ids[userID]; ok {
+		return true
+	}
+	s.resolve()
+	_, ok := s.ids[userID]
+	return ok
+}
+
+// UserIDs — отсортированный список доступных пользователей (nil при All).
+func (s *UserScope) UserIDs() []int {
+	if s.All() {
+		return nil
+	}
+	s.resolve()
+	ids := make([]int, 0, len(s.ids))
+	for id := range s.ids {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// ScopeFor строит область видимости сотрудника: пользователи его организации,
+// а для ограниченного группой — только его группы (включая его самого).
+// ID пользователей читаются из БД при первой проверке, а не на каждый запрос.
+func (svc *UserService) ScopeFor(user *models.User) (*UserScope, error) {
+	organizationID := organizationOrDefault(user.OrganizationID)
+	var groupID *int
+	if user.LimitedToGroup() {
+		id := *user.GroupID
+		groupID = &id
+	}
+	scope := NewUserScope(user.ID)
+	scope.load = func() ([]int, error) {
+		return svc.DAO.GetIDsByOrganization(organizationID, groupID)
+	}
+	return scope, nil
+}
+
+// CheckRoleAssignment проверяет, может ли actor назначить роль role и группу groupID
+// пользователю target (nil — создаваемый пользователь): нельзя выдать роль выше
+// собственной, менять свою роль, старших по роли и пользователей вне своей группы.
+func CheckRoleAssignment(actor *models.User, scope *UserScope, target *models.User, role string, groupID *int) error {
+	if !actor.HasPermission(models.PermUsersManage) {
+		return ErrAccessDenied
+	}
+	if !models.IsValidRole(role) {
+		return ErrInvalidRole
+	}
+	actorRank := models.RoleRank(actor.EffectiveRole())
+	if models.RoleRank(role) > actorRank {
+		return ErrAccessDenied
+	}
+	if target != nil {
+		if target.ID == actor.ID {
+			return ErrAccessDenied
+		}
+		if err := CheckUserManagement(actor, scope, target); err != nil {
+			return err
+		}
+	}
+	if actor.LimitedToGroup() && (groupID == nil || *groupID != *actor.GroupID) {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// CheckUserManagement проверяет, может ли actor управлять пользователем target
+// (имя, API-ключ, QR-код): нужно право users.manage, target должен быть в области
+// видимости actor и не старше его по роли.
+func CheckUserManagement(actor *models.User, scope *UserScope, target *models.User) error {
+	if !actor.HasPermission(models.PermUsersManage) || !scope.Allows(target.ID) {
+		return ErrAccessDenied
+	}
+	if models.RoleRank(target.EffectiveRole()) > models.RoleRank(actor.EffectiveRole()) {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// CreateUserWithAccess создаёт пользователя с ролью и группой от имени actor.
+// Для сотрудника, ограниченного группой, группа по умолчанию — его собственная.
+func (svc *UserService) CreateUserWithAccess(actor *models.User, scope *UserScope, name, role string, groupID *int) (*models.User, string, error) {
+	if groupID == nil && actor.LimitedToGroup() {
+		groupID = actor.GroupID
+	}
+	if err := CheckRoleAssignment(actor, scope, nil, role, groupID); err != nil {
+		return nil, "", err
+	}
+	if err := svc.checkGroupExists(actor, groupID); err != nil {
+		return nil, "", err
+	}
+	return svc.createUser(actor.OrganizationID, name, role, groupID, "")
+}
+
+// SetUserAccess меняет роль и группу пользователя от имени actor.
+func (svc *UserService) SetUserAccess(actor *models.User, scope *UserScope, userID int, role string, groupID *int) (*models.User, error) {
+	target, err := svc.DAO.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckRoleAssignment(actor, scope, target, role, groupID); err != nil {
+		return nil, err
+	}
+	if err := svc.checkGroupExists(actor, groupID); err != nil {
+		return nil, err
+	}
+
+	target.SetRole(role)
+	target.GroupID = groupID
+	if err := svc.DAO.Update(target); err != nil {
+		slog.Error("Ошибка обновления пользователя", "user_id", userID, "error", err)
+		return nil, err
+	}
+	slog.Info("Доступ пользователя изменён", "user_id", target.ID, "role", role, "group_id", groupID, "actor_id", actor.ID)
+	return target, nil
+}
+
+// checkGroupExists — группа существует и принадлежит организации actor.
+func (svc *UserService) checkGroupExists(actor *models.User, groupID *int) error {
+	if groupID == nil || svc.Groups == nil {
+		return nil
+	}
+	_, err := svc.getGroup(actor, *groupID)
+	return err
+}
+
+func (svc *UserService) getGroup(actor *models.User, groupID int) (*models.Group, error) {
+	group, err := svc.Groups.GetGroupByID(groupID)
+	if err != nil || organizationOrDefault(group.OrganizationID) != organizationOrDefault(actor.OrganizationID) {
+		return nil, ErrGroupNotFound
+	}
+	return group, nil
+}
+
+// ListGroups — группы, видимые actor (ограниченный группой видит только свою).
+func (svc *UserService) ListGroups(actor *models.User) ([]models.Group, error) {
+	if svc.Groups == nil {
+		return []models.Group{}, nil
+	}
+	groups, err := svc.Groups.GetAllGroups(organizationOrDefault(actor.OrganizationID))
+	if err != nil {
+		return nil, err
+	}
+	if !actor.LimitedToGroup() {
+		return groups, nil
+	}
+	out := make([]models.Group, 0, 1)
+	for _, g := range groups {
+		if g.ID == *actor.GroupID {
+			out = append(out, g)
+		}
+	}
+	return out, nil
+}
+
+// CreateGroup создаёт группу; сотрудникам, ограниченным группой, запрещено.
+func (svc *UserService) CreateGroup(actor *models.User, name string) (*models.Group, error) {
+	if !actor.HasPermission(models.PermUsersManage) || actor.LimitedToGroup() {
+		return nil, ErrAccessDenied
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrGroupNameEmpty
+	}
+	if svc.Groups == nil {
+		return nil, errors.New("хранилище групп не настроено")
+	}
+	group := &models.Group{OrganizationID: organizationOrDefault(actor.OrganizationID), Name: name}
+	if err := svc.Groups.CreateGroup(group); err != nil {
+		return nil, err
+	}
+	slog.Info("Группа создана", "group_id", group.ID, "name", group.Name)
+	return group, nil
+}
+
+// ListGroupMembers — пользователи группы; ограниченный группой видит только свою.
+func (svc *UserService) ListGroupMembers(actor *models.User, groupID int) ([]models.User, error) {
+	if svc.Groups == nil {
+		return nil, ErrGroupNotFound
+	}
+	if _, err := svc.getGroup(actor, groupID); err != nil {
+		return nil, err
+	}
+	if actor.LimitedToGroup() && *actor.GroupID != groupID {
+		return nil, ErrAccessDenied
+	}
+	users, err := svc.DAO.GetAllByOrganization(organizationOrDefault(actor.OrganizationID))
+	if err != nil {
+		return nil, err
+	}
+	members := make([]models.User, 0)
+	for _, u := range users {
+		if u.GroupID != nil && *u.GroupID == groupID {
+			members = append(members, u)
+		}
+	}
+	return members, nil
+}
+
+// AddGroupMember переводит пользователя в группу groupID; ограниченный группой
+// сотрудник может добавлять только в свою группу.
+func (svc *UserService) AddGroupMember(actor *models.User, scope *UserScope, groupID, userID int) (*models.User, error) {
+	if svc.Groups == nil {
+		return nil, ErrGroupNotFound
+	}
+	if _, err := svc.getGroup(actor, groupID); err != nil {
+		return nil, err
+	}
+	if actor.LimitedToGroup() && *actor.GroupID != groupID {
+		return nil, ErrAccessDenied
+	}
+	return svc.setUserGroup(actor, scope, userID, &groupID)
+}
+
+// RemoveGroupMember исключает пользователя из группы groupID; ограниченный группой
+// сотрудник не может выводить пользователей из своей группы.
+func (svc *UserService) RemoveGroupMember(actor *models.User, scope *UserScope, groupID, userID int) (*models.User, error) {
+	if svc.Groups == nil {
+		return nil, ErrGroupNotFound
+	}
+	if _, err := svc.getGroup(actor, groupID); err != nil {
+		return nil, err
+	}
+	if actor.LimitedToGroup() {
+		return nil, ErrAccessDenied
+	}
+	target, err := svc.DAO.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if target.GroupID == nil || *target.GroupID != groupID {
+		return nil, ErrGroupMemberNotFound
+	}
+	return svc.setUserGroup(actor, scope, userID, nil)
+}
+
+func (svc *UserService) setUserGroup(actor *models.User, scope *UserScope, userID int, groupID *int) (*models.User, error) {
+	target, err := svc.DAO.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if target.ID == actor.ID {
+		return nil, ErrAccessDenied
+	}
+	if err := CheckUserManagement(actor, scope, target); err != nil {
+		return nil, err
+	}
+	target.GroupID = groupID
+	if err := svc.DAO.Update(target); err != nil {
+		slog.Error("Ошибка обновления пользователя", "user_id", userID, "error", err)
+		return nil, err
+	}
+	slog.Info("Группа пользователя изменена", "user_id", target.ID, "group_id", groupID, "actor_id", actor.ID)
+	return target, nil
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"locator/models"
+)
+
+type fakeGroupRepo struct {
+	groups map[int]models.Group
+}
+
+func (f *fakeGroupRepo) CreateGroup(group *models.Group) error {
+	group.ID = len(f.groups) + 1
+	f.groups[group.ID] = *group
+	return nil
+}
+
+func (f *fakeGroupRepo) GetGroupByID(id int) (*models.Group, error) {
+	g, ok := f.groups[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &g, nil
+}
+
+func (f *fakeGroupRepo) GetAllGroups(organizationID int) ([]models.Group, error) {
+	var out []models.Group
+	for _, g := range f.groups {
+		if g.OrganizationID == organizationID {
+			out = append(out, g)
+		}
+	}
+	return out, nil
+}
+
+type fakeOrganizationRepo struct {
+	orgs []models.Organization
+}
+
+func (f *fakeOrganizationRepo) CreateOrganization(org *models.Organization) error {
+	org.ID = len(f.orgs) + 2 // 1 — организация по умолчанию
+	f.orgs = append(f.orgs, *org)
+	return nil
+}
+
+func (f *fakeOrganizationRepo) GetOrganizationByID(id int) (*models.Organization, error) {
+	for _, o := range f.orgs {
+		if o.ID == id {
+			return &o, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeOrganizationRepo) GetAllOrganizations() ([]models.Organization, error) {
+	return f.orgs, nil
+}
+
+func (f *fakeOrganizationRepo) UpdateOrganization(org *models.Organization) error {
+	for i := range f.orgs {
+		if f.orgs[i].ID == org.ID {
+			f.orgs[i] = *org
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func TestScopeFor_groupLimited(t *testing.T) {
+	repo := newFakeUserRepo(
+		models.User{ID: 1, Role: models.RoleFleetAdmin, GroupID: intPtr(7)},
+		models.User{ID: 2, Role: models.RoleDevice, GroupID: intPtr(7)},
+		models.User{ID: 3, Role: models.RoleDevice, GroupID: intPtr(8)},
+		models.User{ID: 4, Role: models.RoleDevice},
+		models.User{ID: 5, Role: models.RoleDevice, OrganizationID: 2},
+	)
+	svc := &UserService{DAO: repo}
+
+	actor := repo.users[1]
+	scope, err := svc.ScopeFor(&actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scope.All() || !scope.Allows(1) || !scope.Allows(2) || scope.Allows(3) || scope.Allows(4) {
+		t.Fatalf("scope=%v", scope.UserIDs())
+	}
+
+	super := models.User{ID: 9, Role: models.RoleSuperAdmin, GroupID: intPtr(7)}
+	scope, err = svc.ScopeFor(&super)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scope.Allows(2) || !scope.Allows(3) || !scope.Allows(4) {
+		t.Fatalf("super_admin must see the whole organisation, scope=%v", scope.UserIDs())
+	}
+	if scope.Allows(5) {
+		t.Fatal("super_admin must not see users of another organisation")
+	}
+
+	other := models.User{ID: 6, Role: models.RoleSuperAdmin, OrganizationID: 2}
+	scope, err = svc.ScopeFor(&other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scope.Allows(5) || scope.Allows(1) || scope.Allows(4) {
+		t.Fatalf("tenant scope leaked: %v", scope.UserIDs())
+	}
+}
+
+func TestCheckRoleAssignment(t *testing.T) {
+	fleet := &models.User{ID: 1, Role: models.RoleFleetAdmin, GroupID: intPtr(7)}
+	scope := NewUserScope(1, 2, 3)
+	device := &models.User{ID: 2, Role: models.RoleDevice, GroupID: intPtr(7)}
+	super := &models.User{ID: 3, Role: models.RoleSuperAdmin, GroupID: intPtr(7)}
+
+	cases := []struct {
+		name    string
+		actor   *models.User
+		target  *models.User
+		role    string
+		groupID *int
+		want    error
+	}{
+		{"grant dispatcher in own group", fleet, device, models.RoleDispatcher, intPtr(7), nil},
+		{"grant above own role", fleet, device, models.RoleSuperAdmin, intPtr(7), ErrAccessDenied},
+		{"move to foreign group", fleet, device, models.RoleViewer, intPtr(8), ErrAccessDenied},
+		{"drop group", fleet, device, models.RoleViewer, nil, ErrAccessDenied},
+		{"senior target", fleet, super, models.RoleViewer, intPtr(7), ErrAccessDenied},
+		{"self", fleet, fleet, models.RoleViewer, intPtr(7), ErrAccessDenied},
+		{"out of scope", fleet, &models.User{ID: 5}, models.RoleViewer, intPtr(7), ErrAccessDenied},
+		{"unknown role", fleet, device, "root", intPtr(7), ErrInvalidRole},
+		{"viewer cannot manage", &models.User{ID: 6, Role: models.RoleViewer}, device, models.RoleDevice, nil, ErrAccessDenied},
+		{"super admin anywhere", &models.User{ID: 6, Role: models.RoleSuperAdmin}, device, models.RoleSuperAdmin, nil, nil},
+	}
+	for _, tc := range cases {
+		err := CheckRoleAssignment(tc.actor, scope, tc.target, tc.role, tc.groupID)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: err=%v want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestSetUserAccess_updatesRoleAndGroup(t *testing.T) {
+	repo := newFakeUserRepo(
+		models.User{ID: 1, Role: models.RoleSuperAdmin, IsAdmin: true},
+		models.User{ID: 2, Role: models.RoleDevice},
+	)
+	svc := &UserService{DAO: repo}
+	actor := repo.users[1]
+
+	user, err := svc.SetUserAccess(&actor, AllUsersScope(), 2, models.RoleDispatcher, intPtr(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := repo.users[2]
+	if user.Role != models.RoleDispatcher || !stored.IsAdmin || stored.GroupID == nil || *stored.GroupID != 4 {
+		t.Fatalf("stored=%+v", stored)
+	}
+}
+
+func TestCreateGroup_limitedActorDenied(t *testing.T) {
+	svc := &UserService{DAO: newFakeUserRepo()}
+	actor := &models.User{ID: 1, Role: models.RoleFleetAdmin, GroupID: intPtr(7)}
+	if _, err := svc.CreateGroup(actor, "north"); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestGroupMembers_organizationAndGroupLimits(t *testing.T) {
+	repo := newFakeUserRepo(
+		models.User{ID: 1, Role: models.RoleFleetAdmin},
+		models.User{ID: 2, Role: models.RoleDevice},
+		models.User{ID: 3, Role: models.RoleDispatcher, GroupID: intPtr(7)},
+		models.User{ID: 4, Role: models.RoleDevice, OrganizationID: 2},
+	)
+	groups := &fakeGroupRepo{groups: map[int]models.Group{
+		7: {ID: 7, OrganizationID: 1, Name: "Бригада"},
+		8: {ID: 8, OrganizationID: 2, Name: "Чужая"},
+	}}
+	svc := &UserService{DAO: repo, Groups: groups}
+	admin := repo.users[1]
+	scope, _ := svc.ScopeFor(&admin)
+
+	if _, err := svc.AddGroupMember(&admin, scope, 7, 2); err != nil {
+		t.Fatal(err)
+	}
+	members, err := svc.ListGroupMembers(&admin, 7)
+	if err != nil || len(members) != 2 {
+		t.Fatalf("want users 2 and 3 in group 7, got %+v %v", members, err)
+	}
+	if _, err := svc.AddGroupMember(&admin, scope, 7, 4); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("user of another organisation must not be added, got %v", err)
+	}
+	if _, err := svc.AddGroupMember(&admin, scope, 8, 2); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("group of another organisation must not be found, got %v", err)
+	}
+	if _, err := svc.RemoveGroupMember(&admin, scope, 7, 2); err != nil {
+		t.Fatal(err)
+	}
+	if repo.users[2].GroupID != nil {
+		t.Fatal("user must leave the group")
+	}
+	if _, err := svc.RemoveGroupMember(&admin, scope, 7, 2); !errors.Is(err, ErrGroupMemberNotFound) {
+		t.Fatalf("want ErrGroupMemberNotFound, got %v", err)
+	}
+}
+
+func TestCreateOrganization_platformAdminOnly(t *testing.T) {
+	repo := newFakeUserRepo(
+		models.User{ID: 1, Role: models.RoleSuperAdmin, OrganizationID: models.DefaultOrganizationID},
+		models.User{ID: 2, Role: models.RoleSuperAdmin, OrganizationID: 2},
+	)
+	orgs := &fakeOrganizationRepo{}
+	svc := &UserService{DAO: repo, Keys: newFakeAPIKeyRepo(), Organizations: orgs}
+	t.Chdir(t.TempDir())
+
+	foreign := repo.users[2]
+	if _, _, _, err := svc.CreateOrganization(&foreign, "Acme", "", ""); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("super_admin of a tenant must not create organisations, got %v", err)
+	}
+	platform := repo.users[1]
+	org, admin, key, err := svc.CreateOrganization(&platform, " Acme ", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if org.Name != "Acme" || admin.OrganizationID != org.ID || admin.EffectiveRole() != models.RoleSuperAdmin || key == "" {
+		t.Fatalf("unexpected org=%+v admin=%+v", org, admin)
+	}
+	if admin.IsPlatformAdmin() {
+		t.Fatal("tenant admin must not be a platform admin")
+	}
+}
@@ -4,23 +4,30 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
 	"github.com/skip2/go-qrcode"
-	"golang.org/x/crypto/bcrypt"
 	"locator/models"
 )
 
 type UserService struct {
-	DAO userRepository
+	DAO           userRepository
+	Keys          apiKeyRepository
+	Groups        groupRepository
+	Organizations organizationRepository
+	// KeyRotationGrace — период передачи ключа при перегенерации QR (0 — DefaultAPIKeyRotationGrace).
+	KeyRotationGrace time.Duration
+	// BaseURL — внешний адрес API для QR-кодов и config_update ("" — DefaultAPIBaseURL).
+	BaseURL string
+	// Clock — текущее время (nil — системное).
+	Clock Clock
 }
 
 // NewUserService создаёт новый экземпляр UserService.
-func NewUserService(dao userRepository) *UserService {
-	log.Println("[UserService] Инициализация сервиса пользователей")
-	return &UserService{DAO: dao}
+func NewUserService(dao userRepository, keys apiKeyRepository) *UserService {
+	return &UserService{DAO: dao, Keys: keys}
 }
 
 // generateSecureAPIKey генерирует 32-байтовый ключ и возвращает его в виде шестнадцатеричной строки.
@@ -29,105 +36,103 @@ func generateSecureAPIKey() (string, error) {
 	if _, err := rand.Read(key); err != nil {
 		return "", err
 	}
-	return hex.EncodeToString(key), nil
+	return apiKeyPrefix + hex.EncodeToString(key), nil
 }
 
 // CreateUser Обновленный метод CreateUser
+// isAdmin=true создаёт super_admin, иначе — пользователя-устройство (в организации по умолчанию).
 func (svc *UserService) CreateUser(name string, isAdmin bool, forceAPIKey ...string) (*models.User, string, error) {
-	var plainKey string
-	var err error
-
-	// Используем forceAPIKey только если он явно передан (для сидера)
-	if len(forceAPIKey) > 0 && forceAPIKey[0] != "" {
-		plainKey = forceAPIKey[0]
-	} else {
-		// Для всех обычных пользователей генерируем новый случайный ключ
-		plainKey, err = generateSecureAPIKey()
-		if err != nil {
-			log.Printf("[UserService CreateUser] Ошибка генерации API ключа: %v", err)
-			return nil, "", err
-		}
+	role := models.RoleDevice
+	if isAdmin {
+		role = models.RoleSuperAdmin
 	}
-
-	hashedKey, err := bcrypt.GenerateFromPassword([]byte(plainKey), bcrypt.DefaultCost)
-	if err != nil {
-		log.Printf("[UserService CreateUser] Ошибка хеширования API ключа: %v", err)
-		return nil, "", err
+	var forced string
+	if len(forceAPIKey) > 0 {
+		forced = forceAPIKey[0]
 	}
+	return svc.createUser(models.DefaultOrganizationID, name, role, nil, forced)
+}
 
+func (svc *UserService) createUser(organizationID int, name, role string, groupID *int, forceAPIKey string) (*models.User, string, error) {
 	user := &models.User{
-		Name:    name,
-		ApiKey:  string(hashedKey),
-		IsAdmin: isAdmin,
+		OrganizationID: organizationOrDefault(organizationID),
+		Name:           name,
+		GroupID:        groupID,
 	}
+	user.SetRole(role)
 
 	if err := svc.DAO.Create(user); err != nil {
-		log.Printf("[UserService CreateUser] Ошибка создания пользователя: %v", err)
+		slog.Error("Ошибка создания пользователя", "name", name, "error", err)
+		return nil, "", err
+	}
+
+	// forceAPIKey используется только если он явно передан (для сидера),
+	// для всех обычных пользователей генерируется новый случайный ключ.
+	_, plainKey, err := svc.issueAPIKey(user.ID, primaryAPIKeyLabel, true, nil, forceAPIKey)
+	if err != nil {
+		slog.Error("Ошибка выпуска API-ключа", "user_id", user.ID, "error", err)
 		return nil, "", err
 	}
 
 	qrCodeURL, err := svc.writeUserQRCode(user.ID, plainKey)
 	if err != nil {
-		log.Printf("[UserService CreateUser] Ошибка генерации QR кода: %v", err)
+		slog.Error("Ошибка генерации QR-кода", "user_id", user.ID, "error", err)
 		return nil, "", err
 	}
 	user.QRCode = qrCodeURL
 
 	// Обновляем запись пользователя, сохраняя ссылку на QR‑код.
 	if err := svc.DAO.Update(user); err != nil {
-		log.Printf("[UserService CreateUser] Ошибка обновления пользователя с QR кодом: %v", err)
+		slog.Error("Ошибка сохранения QR-кода пользователя", "user_id", user.ID, "error", err)
 		return nil, "", err
 	}
 
-	log.Printf("[UserService CreateUser] Пользователь создан: ID=%d, Name=%s, IsAdmin=%t", user.ID, user.Name, user.IsAdmin)
+	slog.Info("Пользователь создан", "user_id", user.ID, "name", user.Name, "role", user.Role, "organization_id", user.OrganizationID)
 	// Возвращаем plaintext API‑ключ только при создании (в дальнейшем не показываем его)
 	return user, plainKey, nil
 }
 
-// AuthenticateUser проверяет, соответствует ли предоставленный API‑ключ хешированному значению в базе.
-// AuthenticateUser проверяет API-ключ с правильной обработкой указателей
-func (svc *UserService) AuthenticateUser(providedKey string) (*models.User, error) {
-	if providedKey == "" {
-		return nil, fmt.Errorf("API ключ не может быть пустым")
+// AuthenticateUser проверяет API-ключ: он должен совпасть с одним из действующих
+// (не отозванных и не истёкших) ключей пользователя. ip — адрес клиента для last_used_ip.
+func (svc *UserService) AuthenticateUser(providedKey string, ip ...string) (*models.User, error) {
+	var clientIP string
+	if len(ip) > 0 {
+		clientIP = ip[0]
 	}
+	user, _, err := svc.AuthenticateAPIKey(providedKey, clientIP)
+	return user, err
+}
 
-	users, err := svc.DAO.GetAll()
-	if err != nil {
-		return nil, fmt.Errorf("ошибка доступа к базе данных")
+// AuthenticateAPIKey — как AuthenticateUser, но возвращает и сам ключ (для лимитов по ключу).
+func (svc *UserService) AuthenticateAPIKey(providedKey, clientIP string) (*models.User, *models.APIKey, error) {
+	if providedKey == "" {
+		return nil, nil, fmt.Errorf("API ключ не может быть пустым")
 	}
 
-	// Вместо работы с указателями в цикле, найдем ID подходящего пользователя
-	var matchedUserID int = -1
-
-	for _, user := range users {
-		if err := bcrypt.CompareHashAndPassword([]byte(user.ApiKey), []byte(providedKey)); err == nil {
-			matchedUserID = user.ID
-			log.Printf("[AuthenticateUser] Найдено совпадение для пользователя ID=%d, Name=%s",
-				user.ID, user.Name)
-			break
-		}
+	key, err := svc.authenticateAPIKey(providedKey, clientIP)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Если нашли подходящего пользователя, загружаем его заново из базы по ID
-	if matchedUserID != -1 {
-		matchedUser, err := svc.DAO.GetByID(matchedUserID)
+	if key != nil {
+		matchedUser, err := svc.DAO.GetByID(key.UserID)
 		if err != nil {
-			return nil, fmt.Errorf("ошибка получения данных пользователя")
+			return nil, nil, fmt.Errorf("ошибка получения данных пользователя")
 		}
-		log.Printf("[AuthenticateUser] Успешная аутентификация пользователя: ID=%d, Name=%s",
-			matchedUser.ID, matchedUser.Name)
-		return matchedUser, nil
+		// Вызывается на каждый запрос устройства — только на уровне debug.
+		slog.Debug("Аутентификация по API-ключу", "user_id", matchedUser.ID, "key_id", key.ID)
+		return matchedUser, key, nil
 	}
 
-	log.Printf("[AuthenticateUser] Недействительный API ключ")
-	return nil, fmt.Errorf("недействительный API ключ")
+	slog.Warn("Недействительный API-ключ")
+	return nil, nil, fmt.Errorf("недействительный API ключ")
 }
 
 // GetUserByID возвращает пользователя по его ID.
 func (svc *UserService) GetUserByID(id int) (*models.User, error) {
 	user, err := svc.DAO.GetByID(id)
 	if err != nil {
-		log.Printf("[UserService GetUserByID] Пользователь с ID=%d не найден: %v", id, err)
+		slog.Debug("Пользователь не найден", "user_id", id, "error", err)
 		return nil, err
 	}
 	return user, nil
@@ -137,31 +142,62 @@ func (svc *UserService) GetUserByID(id int) (*models.User, error) {
 func (svc *UserService) UpdateUserName(id int, name string) (*models.User, error) {
 	user, err := svc.DAO.GetByID(id)
 	if err != nil {
-		log.Printf("[UserService UpdateUserName] Пользователь с ID=%d не найден: %v", id, err)
+		slog.Debug("Пользователь не найден", "user_id", id, "error", err)
 		return nil, err
 	}
 
 	user.Name = name
 	if err := svc.DAO.Update(user); err != nil {
-		log.Printf("[UserService UpdateUserName] Ошибка обновления пользователя ID=%d: %v", id, err)
+		slog.Error("Ошибка обновления пользователя", "user_id", id, "error", err)
+		return nil, err
+	}
+
+	slog.Info("Имя пользователя обновлено", "user_id", user.ID, "name", user.Name)
+	return user, nil
+}
+
+// UpdateUserTimezone задаёт IANA-пояс пользователя ("" — пояс его организации).
+func (svc *UserService) UpdateUserTimezone(id int, timezone string) (*models.User, error) {
+	timezone, err := ValidateTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+	user, err := svc.DAO.GetByID(id)
+	if err != nil {
+		slog.Debug("Пользователь не найден", "user_id", id, "error", err)
+		return nil, err
+	}
+
+	user.Timezone = timezone
+	if err := svc.DAO.Update(user); err != nil {
+		slog.Error("Ошибка обновления пользователя", "user_id", id, "error", err)
 		return nil, err
 	}
 
-	log.Printf("[UserService UpdateUserName] Имя обновлено: ID=%d, Name=%s", user.ID, user.Name)
+	slog.Info("Пояс пользователя обновлён", "user_id", user.ID, "timezone", user.Timezone)
 	return user, nil
 }
 
-func apiBaseURL() string {
-	apiBase := os.Getenv("BASE_URL")
-	if apiBase == "" {
-		return "http://localhost:8080"
+// DefaultAPIBaseURL — адрес API, если BaseURL не задан (локальный запуск, тесты).
+const DefaultAPIBaseURL = "http://localhost:8080"
+
+// APIBaseURL — адрес API, который получает телефон.
+func (svc *UserService) APIBaseURL() string {
+	if svc.BaseURL == "" {
+		return DefaultAPIBaseURL
 	}
-	return apiBase
+	return svc.BaseURL
+}
+
+// UserQRContent — содержимое QR-кода привязки телефона: приложение читает из него
+// user_id, api_key и api_base_url.
+func UserQRContent(userID int, plainKey, apiBase string) string {
+	return fmt.Sprintf(`{"user_id": %d, "api_key": "%s", "api_base_url": "%s"}`, userID, plainKey, apiBase)
 }
 
 func (svc *UserService) writeUserQRCode(userID int, plainKey string) (string, error) {
-	apiBase := apiBaseURL()
-	qrContent := fmt.Sprintf(`{"user_id": %d, "api_key": "%s", "api_base_url": "%s"}`, userID, plainKey, apiBase)
+	apiBase := svc.APIBaseURL()
+	qrContent := UserQRContent(userID, plainKey, apiBase)
 
 	if err := os.MkdirAll("static/qrcode", 0o755); err != nil {
 		return "", err
@@ -172,57 +208,51 @@ func (svc *UserService) writeUserQRCode(userID int, plainKey string) (string, er
 		return "", err
 	}
 
-	return fmt.Sprintf("%s/static/qrcode/%d.png?v=%d", apiBase, userID, time.Now().Unix()), nil
+	return fmt.Sprintf("%s/static/qrcode/%d.png?v=%d", apiBase, userID, clockNow(svc.Clock).Unix()), nil
 }
 
-// RegenerateUserQR создаёт новый API-ключ и перезаписывает PNG QR-кода с текущим BASE_URL.
-// Если plainKey не пустой — используется указанный ключ вместо генерации нового.
+// RegenerateUserQR выпускает новый основной API-ключ и перезаписывает PNG QR-кода
+// с текущим BASE_URL. Прежний ключ действует до первого запроса с новым ключом
+// (или до истечения KeyRotationGrace). Если plainKey не пустой — используется
+// указанный ключ вместо генерации нового.
 func (svc *UserService) RegenerateUserQR(userID int, plainKey ...string) (*models.User, string, error) {
 	user, err := svc.DAO.GetByID(userID)
 	if err != nil {
-		log.Printf("[UserService RegenerateUserQR] Пользователь с ID=%d не найден: %v", userID, err)
+		slog.Debug("Пользователь не найден", "user_id", userID, "error", err)
 		return nil, "", err
 	}
 
-	var key string
-	if len(plainKey) > 0 && plainKey[0] != "" {
-		key = plainKey[0]
-	} else {
-		key, err = generateSecureAPIKey()
-		if err != nil {
-			log.Printf("[UserService RegenerateUserQR] Ошибка генерации API ключа: %v", err)
-			return nil, "", err
-		}
+	var forced string
+	if len(plainKey) > 0 {
+		forced = plainKey[0]
 	}
-
-	hashedKey, err := bcrypt.GenerateFromPassword([]byte(key), bcrypt.DefaultCost)
+	_, key, err := svc.rotatePrimaryAPIKey(userID, forced)
 	if err != nil {
-		log.Printf("[UserService RegenerateUserQR] Ошибка хеширования API ключа: %v", err)
+		slog.Error("Ошибка выпуска API-ключа", "user_id", userID, "error", err)
 		return nil, "", err
 	}
 
 	qrCodeURL, err := svc.writeUserQRCode(userID, key)
 	if err != nil {
-		log.Printf("[UserService RegenerateUserQR] Ошибка генерации QR кода: %v", err)
+		slog.Error("Ошибка генерации QR-кода", "user_id", userID, "error", err)
 		return nil, "", err
 	}
 
-	user.ApiKey = string(hashedKey)
 	user.QRCode = qrCodeURL
 	if err := svc.DAO.Update(user); err != nil {
-		log.Printf("[UserService RegenerateUserQR] Ошибка обновления пользователя: %v", err)
+		slog.Error("Ошибка сохранения QR-кода пользователя", "user_id", userID, "error", err)
 		return nil, "", err
 	}
 
-	log.Printf("[UserService RegenerateUserQR] QR перегенерирован: ID=%d, Name=%s", user.ID, user.Name)
+	slog.Info("QR-код перегенерирован", "user_id", user.ID, "name", user.Name)
 	return user, key, nil
 }
 
-// GetAllUsers возвращает список всех пользователей.
-func (svc *UserService) GetAllUsers() ([]models.User, error) {
-	users, err := svc.DAO.GetAll()
+// GetAllUsers возвращает список всех пользователей организации.
+func (svc *UserService) GetAllUsers(organizationID int) ([]models.User, error) {
+	users, err := svc.DAO.GetAllByOrganization(organizationOrDefault(organizationID))
 	if err != nil {
-		log.Printf("[UserService GetAllUsers] Ошибка получения пользователей: %v", err)
+		slog.Error("Ошибка получения пользователей", "organization_id", organizationID, "error", err)
 		return nil, err
 	}
 	return users, nil
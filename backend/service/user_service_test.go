@@ -38,10 +38,22 @@ func (f *fakeUserRepo) Create(user *models.User) error {
 }
 
 func (f *fakeUserRepo) Update(user *models.User) error {
+	// totp_last_step только для чтения: Save его не перезаписывает.
+	user.TOTPLastStep = f.users[user.ID].TOTPLastStep
 	f.users[user.ID] = *user
 	return nil
 }
 
+func (f *fakeUserRepo) UseTOTPStep(userID int, step int64) (bool, error) {
+	u, ok := f.users[userID]
+	if !ok || u.TOTPLastStep >= step {
+		return false, nil
+	}
+	u.TOTPLastStep = step
+	f.users[userID] = u
+	return true, nil
+}
+
 func (f *fakeUserRepo) GetByID(id int) (*models.User, error) {
 	u, ok := f.users[id]
 	if !ok {
@@ -51,6 +63,16 @@ func (f *fakeUserRepo) GetByID(id int) (*models.User, error) {
 	return &cp, nil
 }
 
+func (f *fakeUserRepo) GetByUsername(username string) (*models.User, error) {
+	for _, u := range f.users {
+		if u.Username != nil && *u.Username == username {
+			cp := u
+			return &cp, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
 func (f *fakeUserRepo) GetAll() ([]models.User, error) {
 	out := make([]models.User, 0, len(f.users))
 	for _, u := range f.users {
@@ -59,13 +81,34 @@ func (f *fakeUserRepo) GetAll() ([]models.User, error) {
 	return out, nil
 }
 
+func (f *fakeUserRepo) GetAllByOrganization(organizationID int) ([]models.User, error) {
+	out := make([]models.User, 0, len(f.users))
+	for _, u := range f.users {
+		if organizationOrDefault(u.OrganizationID) == organizationID {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeUserRepo) GetIDsByOrganization(organizationID int, groupID *int) ([]int, error) {
+	users, _ := f.GetAllByOrganization(organizationID)
+	ids := make([]int, 0, len(users))
+	for _, u := range users {
+		if groupID == nil || (u.GroupID != nil && *u.GroupID == *groupID) {
+			ids = append(ids, u.ID)
+		}
+	}
+	return ids, nil
+}
+
 func TestAuthenticateUser_success(t *testing.T) {
 	const plain = "test-api-key-admin-01"
-	admin, err := testutil.UserWithAPIKey(1, "admin", plain, true)
+	admin, key, err := testutil.UserWithAPIKey(1, "admin", plain, true)
 	if err != nil {
 		t.Fatal(err)
 	}
-	svc := &UserService{DAO: newFakeUserRepo(admin)}
+	svc := &UserService{DAO: newFakeUserRepo(admin), Keys: newFakeAPIKeyRepo(key)}
 
 	got, err := svc.AuthenticateUser(plain)
 	if err != nil {
@@ -77,11 +120,11 @@ func TestAuthenticateUser_success(t *testing.T) {
 }
 
 func TestAuthenticateUser_wrongKey(t *testing.T) {
-	admin, err := testutil.UserWithAPIKey(1, "admin", "correct-key-xxxxxxxx", true)
+	admin, key, err := testutil.UserWithAPIKey(1, "admin", "correct-key-xxxxxxxx", true)
 	if err != nil {
 		t.Fatal(err)
 	}
-	svc := &UserService{DAO: newFakeUserRepo(admin)}
+	svc := &UserService{DAO: newFakeUserRepo(admin), Keys: newFakeAPIKeyRepo(key)}
 
 	_, err = svc.AuthenticateUser("wrong-key-yyyyyyyy")
 	if err == nil {
@@ -90,7 +133,7 @@ func TestAuthenticateUser_wrongKey(t *testing.T) {
 }
 
 func TestAuthenticateUser_emptyKey(t *testing.T) {
-	svc := &UserService{DAO: newFakeUserRepo()}
+	svc := &UserService{DAO: newFakeUserRepo(), Keys: newFakeAPIKeyRepo()}
 	_, err := svc.AuthenticateUser("")
 	if err == nil {
 		t.Fatal("expected error for empty key")
@@ -99,11 +142,11 @@ func TestAuthenticateUser_emptyKey(t *testing.T) {
 
 func TestAuthenticateUser_nonAdminFlagPreserved(t *testing.T) {
 	const plain = "device-user-key-aaaa"
-	user, err := testutil.UserWithAPIKey(2, "device", plain, false)
+	user, key, err := testutil.UserWithAPIKey(2, "device", plain, false)
 	if err != nil {
 		t.Fatal(err)
 	}
-	svc := &UserService{DAO: newFakeUserRepo(user)}
+	svc := &UserService{DAO: newFakeUserRepo(user), Keys: newFakeAPIKeyRepo(key)}
 
 	got, err := svc.AuthenticateUser(plain)
 	if err != nil {
@@ -1,30 +1,32 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
+	"locator/dao"
 	"locator/models"
 )
 
-// visitLocationReader — для тестов и DAO: история точек при закрытии визита.
-type visitLocationReader interface {
-	GetLocationsByUserBetween(userID int, from, to time.Time) ([]models.Location, error)
-}
-
 // VisitEventProcessor отвечает за обработку событий локации из RabbitMQ.
 type VisitEventProcessor struct {
 	CheckpointService *CheckpointService
 	VisitService      *VisitService
-	LocationDAO       visitLocationReader
-	geofenceStates    *geofenceStateStore
+	LocationDAO       locationHistoryRepository
+	// Clock — время события без OccurredAt (nil — системное).
+	Clock Clock
+	// Notifier — доставка visit.started / visit.ended (nil — без уведомлений).
+	Notifier       eventNotifier
+	geofenceStates *geofenceStateStore
 }
 
 // NewVisitEventProcessor создаёт новый экземпляр обработчика событий.
-func NewVisitEventProcessor(cs *CheckpointService, vs *VisitService, locationDAO visitLocationReader) *VisitEventProcessor {
+func NewVisitEventProcessor(cs *CheckpointService, vs *VisitService, locationDAO locationHistoryRepository) *VisitEventProcessor {
 	return &VisitEventProcessor{
 		CheckpointService: cs,
 		VisitService:      vs,
@@ -35,46 +37,88 @@ func NewVisitEventProcessor(cs *CheckpointService, vs *VisitService, locationDAO
 
 // ProcessEvent обрабатывает событие из RabbitMQ:
 // - Получает и десериализует входящее сообщение.
-// - Получает все чекпоинты для обработки.
+// - Получает все чекпоинты организации пользователя.
 // - Для каждого чекпоинта определяет, находится ли пользователь в зоне, и запускает или завершает визит.
-func (vep *VisitEventProcessor) ProcessEvent(message []byte) error {
-	log.Println("[ProcessEvent] Получение и обработка события из RabbitMQ")
+// ctx несёт ID исходного HTTP-запроса (заголовок сообщения) для журнала.
+func (vep *VisitEventProcessor) ProcessEvent(ctx context.Context, message []byte) error {
 	var event models.LocationEvent
 	if err := json.Unmarshal(message, &event); err != nil {
-		log.Printf("[ProcessEvent] Ошибка десериализации события: %v", err)
+		slog.ErrorContext(ctx, "Ошибка десериализации события", "error", err)
 		return err
 	}
-	log.Printf("[ProcessEvent] Событие успешно десериализовано: userID=%d, Latitude=%.6f, Longitude=%.6f",
-		event.UserID, event.Latitude, event.Longitude)
+	logger := slog.With("user_id", event.UserID, "location_id", event.LocationID)
+	logger.DebugContext(ctx, "Обработка события локации",
+		"lat", event.Latitude, "lon", event.Longitude, "source", event.Source)
 
-	checkpoints, err := vep.CheckpointService.GetCheckpoints()
+	checkpoints, err := vep.CheckpointService.GetCheckpoints(event.OrganizationID)
 	if err != nil {
-		log.Printf("[ProcessEvent] Ошибка получения чекпоинтов: %v", err)
+		logger.ErrorContext(ctx, "Ошибка получения чекпоинтов", "error", err)
 		return err
 	}
-	log.Printf("[ProcessEvent] Получено %d чекпоинтов для обработки", len(checkpoints))
 
-	for _, cp := range checkpoints {
-		if err := vep.processCheckpoint(cp, event); err != nil {
-			return err
+	// Визиты пользователя меняются под его блокировкой и одной транзакцией на событие
+	// (пересборка визитов берёт ту же блокировку); уведомления — после фиксации.
+	var pending pendingNotifications
+	err = vep.VisitService.DAO.InUserLock(event.UserID, func(tx dao.VisitStore) error {
+		locked := vep.withVisits(tx)
+		if vep.Notifier != nil {
+			locked.Notifier = &pending
+		}
+		for _, cp := range checkpoints {
+			if err := locked.processCheckpoint(ctx, logger.With("checkpoint_id", cp.ID), cp, event); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, n := range pending {
+		vep.Notifier.Notify(n)
 	}
-
-	log.Println("[ProcessEvent] Обработка события завершена успешно")
 	return nil
 }
 
-func (vep *VisitEventProcessor) processCheckpoint(cp models.Checkpoint, event models.LocationEvent) error {
-	log.Printf("[processCheckpoint] Проверка чекпоинта: ID=%d, Name=%s", cp.ID, cp.Name)
+// withVisits — копия обработчика, пишущая визиты в tx (внутри InUserLock).
+func (vep *VisitEventProcessor) withVisits(tx dao.VisitStore) *VisitEventProcessor {
+	visits := *vep.VisitService
+	visits.DAO = lockedVisitRepo{tx}
+	locked := *vep
+	locked.VisitService = &visits
+	return &locked
+}
+
+// lockedVisitRepo — визиты внутри InUserLock: блокировка уже взята, вложенный вызов
+// выполняет fn в той же транзакции.
+type lockedVisitRepo struct {
+	dao.VisitStore
+}
+
+func (r lockedVisitRepo) InUserLock(userID int, fn func(tx dao.VisitStore) error) error {
+	return fn(r.VisitStore)
+}
+
+// pendingNotifications копит уведомления до фиксации транзакции.
+type pendingNotifications []Notification
+
+func (p *pendingNotifications) Notify(n Notification) {
+	*p = append(*p, n)
+}
+
+func (vep *VisitEventProcessor) processCheckpoint(
+	ctx context.Context, logger *slog.Logger, cp models.Checkpoint, event models.LocationEvent,
+) error {
 
 	distance := vep.CheckpointService.DistanceToCheckpoint(event.Latitude, event.Longitude, &cp)
 	now := event.OccurredAt.UTC()
 	if now.IsZero() {
-		now = time.Now().UTC()
+		now = clockNow(vep.Clock).UTC()
 	}
 
 	activeVisit, err := vep.getActiveVisit(event.UserID, cp.ID)
 	if err != nil {
+		logger.ErrorContext(ctx, "Ошибка получения активного визита", "error", err)
 		return err
 	}
 
@@ -82,22 +126,21 @@ func (vep *VisitEventProcessor) processCheckpoint(cp models.Checkpoint, event mo
 	inside := geofenceInside(distance, cp.Radius, hasVisit)
 	state := vep.geofenceStates.get(event.UserID, cp.ID)
 
-	log.Printf("[processCheckpoint] userID=%d checkpointID=%d distance=%.1fm radius=%.1fm inside=%v hasVisit=%v",
-		event.UserID, cp.ID, distance, cp.Radius, inside, hasVisit)
+	logger.DebugContext(ctx, "Проверка чекпоинта", "distance_m", distance, "radius_m", cp.Radius,
+		"inside", inside, "has_visit", hasVisit)
 
 	if inside {
 		state.clearPendingExit()
-		return vep.handleInside(event.UserID, cp.ID, activeVisit, state, now, event.Source)
+		return vep.handleInside(ctx, logger, event.UserID, cp, activeVisit, state, now, event.Source)
 	}
 
 	state.clearPendingEnter()
-	return vep.handleOutside(event.UserID, cp, activeVisit, state, now, distance)
+	return vep.handleOutside(ctx, logger, event.UserID, cp, activeVisit, state, now, distance)
 }
 
 func (vep *VisitEventProcessor) getActiveVisit(userID, checkpointID int) (*models.Visit, error) {
 	activeVisit, err := vep.VisitService.GetActiveVisit(userID, checkpointID)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		log.Printf("[getActiveVisit] Ошибка получения активного визита для userID=%d, checkpointID=%d: %v", userID, checkpointID, err)
 		return nil, err
 	}
 	return activeVisit, nil
@@ -106,14 +149,17 @@ func (vep *VisitEventProcessor) getActiveVisit(userID, checkpointID int) (*model
 // handleInside: при отсутствии визита ждём устойчивого нахождения в зоне, затем создаём визит.
 // on_demand (пинг менеджера) — визит сразу, без ожидания grace.
 func (vep *VisitEventProcessor) handleInside(
-	userID, checkpointID int,
+	ctx context.Context,
+	logger *slog.Logger,
+	userID int,
+	cp models.Checkpoint,
 	activeVisit *models.Visit,
 	state *geofencePendingState,
 	now time.Time,
 	source string,
 ) error {
+	checkpointID := cp.ID
 	if activeVisit != nil {
-		log.Printf("[handleInside] Пользователь %d в зоне чекпоинта %d, визит уже активен", userID, checkpointID)
 		return nil
 	}
 
@@ -122,28 +168,26 @@ func (vep *VisitEventProcessor) handleInside(
 		enterGrace := geofenceEnterGraceSeconds()
 		if !state.pendingEnterElapsed(now, enterGrace) {
 			state.markPendingEnter(now)
-			log.Printf("[handleInside] Ожидание подтверждения входа userID=%d checkpointID=%d (grace=%ds)",
-				userID, checkpointID, enterGrace)
+			logger.DebugContext(ctx, "Ожидание подтверждения входа", "grace_s", enterGrace)
 			return nil
 		}
 	}
 
 	state.clearPendingEnter()
-	_, err := vep.VisitService.StartVisitAt(userID, checkpointID, now)
+	visit, err := vep.VisitService.StartVisitAt(userID, checkpointID, now)
 	if err != nil {
-		log.Printf("[handleInside] Ошибка начала визита для userID=%d, checkpointID=%d: %v", userID, checkpointID, err)
+		logger.ErrorContext(ctx, "Ошибка начала визита", "error", err)
 		return err
 	}
-	if onDemand {
-		log.Printf("[handleInside] Начат визит (on_demand) userID=%d checkpointID=%d", userID, checkpointID)
-	} else {
-		log.Printf("[handleInside] Начат визит для userID=%d checkpointID=%d после grace", userID, checkpointID)
-	}
+	logger.InfoContext(ctx, "Начат визит", "visit_id", visit.ID, "start_at", now, "on_demand", onDemand)
+	vep.notifyVisit(models.NotificationEventVisitStarted, userID, cp, now, 0)
 	return nil
 }
 
 // handleOutside: при активном визите ждём устойчивого выхода из зоны, затем завершаем.
 func (vep *VisitEventProcessor) handleOutside(
+	ctx context.Context,
+	logger *slog.Logger,
 	userID int,
 	cp models.Checkpoint,
 	activeVisit *models.Visit,
@@ -155,13 +199,11 @@ func (vep *VisitEventProcessor) handleOutside(
 		return nil
 	}
 
-	checkpointID := cp.ID
 	farOutside := geofenceFarOutside(distance, cp.Radius, true)
 	exitGrace := geofenceExitGraceSeconds()
 	if !farOutside && !state.pendingExitElapsed(now, exitGrace) {
 		state.markPendingExit(now)
-		log.Printf("[handleOutside] Ожидание подтверждения выхода userID=%d checkpointID=%d (grace=%ds)",
-			userID, checkpointID, exitGrace)
+		logger.DebugContext(ctx, "Ожидание подтверждения выхода", "visit_id", activeVisit.ID, "grace_s", exitGrace)
 		return nil
 	}
 
@@ -172,25 +214,38 @@ func (vep *VisitEventProcessor) handleOutside(
 	elapsed := int(endAt.Sub(activeVisit.StartAt.UTC()).Seconds())
 	if elapsed < minVisit {
 		if err := vep.VisitService.AbandonVisit(activeVisit); err != nil {
-			log.Printf("[handleOutside] Ошибка отмены короткого визита userID=%d checkpointID=%d: %v",
-				userID, checkpointID, err)
+			logger.ErrorContext(ctx, "Ошибка отмены короткого визита", "visit_id", activeVisit.ID, "error", err)
 			return err
 		}
-		log.Printf("[handleOutside] Короткий визит (%ds < %ds) отменён для userID=%d checkpointID=%d",
-			elapsed, minVisit, userID, checkpointID)
+		logger.InfoContext(ctx, "Короткий визит отменён", "visit_id", activeVisit.ID,
+			"elapsed_s", elapsed, "min_s", minVisit)
 		return nil
 	}
 
 	if err := vep.VisitService.EndVisitAt(activeVisit, endAt); err != nil {
-		log.Printf("[handleOutside] Ошибка завершения визита userID=%d checkpointID=%d: %v",
-			userID, checkpointID, err)
+		logger.ErrorContext(ctx, "Ошибка завершения визита", "visit_id", activeVisit.ID, "error", err)
 		return err
 	}
-	log.Printf("[handleOutside] Завершён визит для userID=%d checkpointID=%d endAt=%s farOutside=%v",
-		userID, checkpointID, endAt.UTC(), farOutside)
+	logger.InfoContext(ctx, "Завершён визит", "visit_id", activeVisit.ID,
+		"end_at", endAt.UTC(), "far_outside", farOutside)
+	vep.notifyVisit(models.NotificationEventVisitEnded, userID, cp, endAt, activeVisit.Duration)
 	return nil
 }
 
+func (vep *VisitEventProcessor) notifyVisit(event string, userID int, cp models.Checkpoint, at time.Time, durationSeconds int) {
+	if vep.Notifier == nil {
+		return
+	}
+	params := map[string]string{
+		"Checkpoint":   cp.Name,
+		"CheckpointID": strconv.Itoa(cp.ID),
+	}
+	if event == models.NotificationEventVisitEnded {
+		params["Duration"] = (time.Duration(durationSeconds) * time.Second).String()
+	}
+	vep.Notifier.Notify(Notification{Event: event, UserID: userID, Params: params, At: at})
+}
+
 // resolveVisitEndAt не растягивает визит на период без GPS: конец = последняя точка в зоне + grace.
 func (vep *VisitEventProcessor) resolveVisitEndAt(
 	userID int,
@@ -239,7 +294,7 @@ func (vep *VisitEventProcessor) resolveVisitEndAt(
 	if endAt.Before(visit.StartAt.UTC()) {
 		endAt = visit.StartAt.UTC()
 	}
-	log.Printf("[resolveVisitEndAt] userID=%d checkpointID=%d lastInside=%s gap=%s endAt=%s",
-		userID, cp.ID, lastInside, gap, endAt)
+	slog.Debug("Конец визита по последней точке в зоне", "user_id", userID, "checkpoint_id", cp.ID,
+		"last_inside", lastInside, "gap", gap.String(), "end_at", endAt)
 	return endAt
 }
@@ -1,9 +1,9 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"os"
 	"testing"
 	"time"
 
@@ -13,9 +13,18 @@ import (
 	"gorm.io/gorm"
 )
 
+// withThresholds меняет пороги на время теста.
+func withThresholds(t *testing.T, change func(*Thresholds)) {
+	t.Helper()
+	prev := CurrentThresholds()
+	next := prev
+	change(&next)
+	SetThresholds(next)
+	t.Cleanup(func() { SetThresholds(prev) })
+}
+
 func TestProcessEvent_onDemandStartsVisitInside(t *testing.T) {
-	_ = os.Setenv("GEOFENCE_ENTER_GRACE_SECONDS", "30")
-	t.Cleanup(func() { _ = os.Unsetenv("GEOFENCE_ENTER_GRACE_SECONDS") })
+	withThresholds(t, func(th *Thresholds) { th.GeofenceEnterGraceSeconds = 30 })
 
 	cp := testutil.Checkpoint(1, "office", 53.92684, 27.695144, 100)
 	visitRepo := newFakeVisitRepo()
@@ -31,7 +40,7 @@ func TestProcessEvent_onDemandStartsVisitInside(t *testing.T) {
 		Source:     models.LocationSourceOnDemand,
 	}
 	body, _ := json.Marshal(event)
-	if err := vep.ProcessEvent(body); err != nil {
+	if err := vep.ProcessEvent(context.Background(), body); err != nil {
 		t.Fatal(err)
 	}
 
@@ -45,13 +54,10 @@ func TestProcessEvent_onDemandStartsVisitInside(t *testing.T) {
 }
 
 func TestProcessEvent_exitAbandonsShortVisit(t *testing.T) {
-	_ = os.Setenv("GEOFENCE_MIN_VISIT_SECONDS", "120")
-	_ = os.Setenv("GEOFENCE_EXIT_GRACE_SECONDS", "0")
-	_ = os.Setenv("GEOFENCE_FAR_EXIT_METERS", "50")
-	t.Cleanup(func() {
-		_ = os.Unsetenv("GEOFENCE_MIN_VISIT_SECONDS")
-		_ = os.Unsetenv("GEOFENCE_EXIT_GRACE_SECONDS")
-		_ = os.Unsetenv("GEOFENCE_FAR_EXIT_METERS")
+	withThresholds(t, func(th *Thresholds) {
+		th.GeofenceMinVisitSeconds = 120
+		th.GeofenceExitGraceSeconds = 0
+		th.GeofenceFarExitMeters = 50
 	})
 
 	cp := testutil.Checkpoint(1, "office", 53.92684, 27.695144, 100)
@@ -73,7 +79,7 @@ func TestProcessEvent_exitAbandonsShortVisit(t *testing.T) {
 		Source:     models.LocationSourcePeriodic,
 	}
 	body, _ := json.Marshal(event)
-	if err := vep.ProcessEvent(body); err != nil {
+	if err := vep.ProcessEvent(context.Background(), body); err != nil {
 		t.Fatal(err)
 	}
 	_, err := visitRepo.GetActiveVisit(1, 1)
@@ -93,15 +99,21 @@ func (a *checkpointDAOAdapter) Create(cp *models.Checkpoint) error {
 	return nil
 }
 func (a *checkpointDAOAdapter) Update(cp *models.Checkpoint) error { return nil }
-func (a *checkpointDAOAdapter) GetByID(id int) (*models.Checkpoint, error) {
+func (a *checkpointDAOAdapter) GetByID(organizationID, id int) (*models.Checkpoint, error) {
 	for _, cp := range a.items {
-		if cp.ID == id {
+		if cp.ID == id && organizationOrDefault(cp.OrganizationID) == organizationID {
 			c := cp
 			return &c, nil
 		}
 	}
 	return nil, errors.New("not found")
 }
-func (a *checkpointDAOAdapter) GetAll() ([]models.Checkpoint, error) {
-	return append([]models.Checkpoint(nil), a.items...), nil
+func (a *checkpointDAOAdapter) GetAll(organizationID int) ([]models.Checkpoint, error) {
+	var out []models.Checkpoint
+	for _, cp := range a.items {
+		if organizationOrDefault(cp.OrganizationID) == organizationID {
+			out = append(out, cp)
+		}
+	}
+	return out, nil
 }
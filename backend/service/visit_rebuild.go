@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"locator/dao"
+	"locator/models"
+)
+
+// VisitRebuildResult — итог пересборки визитов пользователя. From и To — интервал
+// после расширения до границ затронутых визитов.
+type VisitRebuildResult struct {
+	UserID  int            `json:"user_id"`
+	From    time.Time      `json:"from"`
+	To      time.Time      `json:"to"`
+	DryRun  bool           `json:"dry_run"`
+	Deleted int            `json:"deleted"`
+	Points  int            `json:"points"`
+	Created int            `json:"created"`
+	Visits  []models.Visit `json:"visits"`
+}
+
+// errVisitRebuildDryRun откатывает транзакцию пробной пересборки.
+var errVisitRebuildDryRun = errors.New("пробная пересборка")
+
+// RebuildVisits заново вычисляет визиты пользователя за [from, to] по сохранённым точкам:
+// визиты, пересекающие интервал, удаляются, точки (без маскированных) проходят через
+// те же правила входа и выхода, что и события из RabbitMQ, с текущими порогами и
+// чекпоинтами. Интервал расширяется до начала и конца пересекающих его визитов, чтобы
+// не разрезать визит пополам. Уведомления не отправляются. Закрытие визитов на границе
+// паузы отслеживания не воспроизводится — маскированных точек в пересборке нет.
+//
+// Удаление и пересборка идут одной транзакцией под блокировкой визитов пользователя,
+// которую берёт и обработчик событий: при ошибке старые визиты остаются, живые события
+// пользователя ждут окончания пересборки. dryRun — то же самое с откатом транзакции.
+func (vep *VisitEventProcessor) RebuildVisits(
+	ctx context.Context, organizationID, userID int, from, to time.Time, dryRun bool,
+) (*VisitRebuildResult, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("начало интервала должно быть раньше конца")
+	}
+	from, to = from.UTC(), to.UTC()
+	logger := slog.With("user_id", userID, "dry_run", dryRun)
+
+	var result *VisitRebuildResult
+	err := vep.VisitService.DAO.InUserLock(userID, func(tx dao.VisitStore) error {
+		res, err := vep.withVisits(tx).rebuildVisits(ctx, logger, organizationID, userID, from, to)
+		if err != nil {
+			return err
+		}
+		res.DryRun = dryRun
+		result = res
+		if dryRun {
+			return errVisitRebuildDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errVisitRebuildDryRun) {
+		return nil, err
+	}
+	logger.InfoContext(ctx, "Визиты пересобраны", "from", result.From, "to", result.To,
+		"deleted", result.Deleted, "points", result.Points, "created", result.Created)
+	return result, nil
+}
+
+// rebuildVisits — тело RebuildVisits внутри транзакции (vep пишет визиты в неё).
+func (vep *VisitEventProcessor) rebuildVisits(
+	ctx context.Context, logger *slog.Logger, organizationID, userID int, from, to time.Time,
+) (*VisitRebuildResult, error) {
+	existing, err := vep.VisitService.GetVisits(organizationID, map[string]interface{}{"user_id": userID}, false, &from, &to)
+	if err != nil {
+		return nil, err
+	}
+	// Расширение интервала может захватить новые визиты — до устойчивого состояния.
+	for i := 0; i < 10; i++ {
+		widened := false
+		for _, v := range existing {
+			if v.StartAt.Before(from) {
+				from, widened = v.StartAt.UTC(), true
+			}
+			if v.EndAt != nil && v.EndAt.After(to) {
+				to, widened = v.EndAt.UTC(), true
+			}
+		}
+		if !widened {
+			break
+		}
+		if existing, err = vep.VisitService.GetVisits(organizationID, map[string]interface{}{"user_id": userID}, false, &from, &to); err != nil {
+			return nil, err
+		}
+	}
+
+	points, err := vep.LocationDAO.GetLocationsByUserBetween(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	checkpoints, err := vep.CheckpointService.GetCheckpoints(organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range existing {
+		if err := vep.VisitService.DAO.Delete(v.ID); err != nil {
+			return nil, fmt.Errorf("удаление визита %d: %w", v.ID, err)
+		}
+	}
+	recorder := &visitRebuildRecorder{visitRepository: vep.VisitService.DAO, created: make(map[int64]*models.Visit)}
+	replay := &VisitEventProcessor{
+		CheckpointService: vep.CheckpointService,
+		VisitService:      &VisitService{DAO: recorder},
+		LocationDAO:       vep.LocationDAO,
+		geofenceStates:    newGeofenceStateStore(),
+	}
+
+	for _, loc := range points {
+		event := models.LocationEvent{
+			UserID:         userID,
+			OrganizationID: organizationID,
+			Latitude:       loc.Latitude,
+			Longitude:      loc.Longitude,
+			OccurredAt:     loc.EffectiveAt(),
+			Source:         loc.Source,
+			LocationID:     loc.ID,
+		}
+		for _, cp := range checkpoints {
+			if err := replay.processCheckpoint(ctx, logger.With("checkpoint_id", cp.ID), cp, event); err != nil {
+				return nil, fmt.Errorf("пересборка прервана на точке %d, визиты не изменены: %w", loc.ID, err)
+			}
+		}
+	}
+
+	visits := recorder.visits()
+	return &VisitRebuildResult{
+		UserID:  userID,
+		From:    from,
+		To:      to,
+		Deleted: len(existing),
+		Points:  len(points),
+		Created: len(visits),
+		Visits:  visits,
+	}, nil
+}
+
+// visitRebuildRecorder запоминает визиты, созданные пересборкой (без отменённых коротких).
+type visitRebuildRecorder struct {
+	visitRepository
+	created map[int64]*models.Visit
+}
+
+func (r *visitRebuildRecorder) Create(visit *models.Visit) error {
+	if err := r.visitRepository.Create(visit); err != nil {
+		return err
+	}
+	r.created[visit.ID] = visit
+	return nil
+}
+
+func (r *visitRebuildRecorder) Update(visit *models.Visit) error {
+	if err := r.visitRepository.Update(visit); err != nil {
+		return err
+	}
+	if _, ok := r.created[visit.ID]; ok {
+		r.created[visit.ID] = visit
+	}
+	return nil
+}
+
+func (r *visitRebuildRecorder) Delete(id int64) error {
+	if err := r.visitRepository.Delete(id); err != nil {
+		return err
+	}
+	delete(r.created, id)
+	return nil
+}
+
+func (r *visitRebuildRecorder) visits() []models.Visit {
+	out := make([]models.Visit, 0, len(r.created))
+	for _, v := range r.created {
+		out = append(out, *v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartAt.Before(out[j].StartAt) })
+	return out
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"locator/internal/testutil"
+	"locator/models"
+)
+
+// rangeLocationDAO отдаёт точки в [from, to], как LocationDAO.GetLocationsByUserBetween.
+type rangeLocationDAO struct {
+	locations []models.Location
+}
+
+func (f *rangeLocationDAO) GetLocationsByUserBetween(userID int, from, to time.Time) ([]models.Location, error) {
+	var out []models.Location
+	for _, loc := range f.locations {
+		at := loc.EffectiveAt()
+		if loc.UserID == userID && !at.Before(from) && !at.After(to) {
+			out = append(out, loc)
+		}
+	}
+	return out, nil
+}
+
+func rebuildFixture(t *testing.T) (*VisitEventProcessor, *fakeVisitRepo, time.Time) {
+	t.Helper()
+	withThresholds(t, func(th *Thresholds) {
+		th.GeofenceEnterGraceSeconds = 60
+		th.GeofenceExitGraceSeconds = 0
+		th.GeofenceMinVisitSeconds = 120
+		th.GeofenceFarExitMeters = 50
+	})
+
+	cp := testutil.Checkpoint(1, "office", 53.92684, 27.695144, 100)
+	start := testutil.FixedUTC(2026, 7, 1, 10, 0, 0)
+	locations := &rangeLocationDAO{}
+	for i := 0; i <= 20; i++ {
+		locations.locations = append(locations.locations,
+			testutil.Location(i+1, 1, 53.92684, 27.695144, start.Add(time.Duration(i)*time.Minute)))
+	}
+	locations.locations = append(locations.locations,
+		testutil.Location(30, 1, 53.94, 27.72, start.Add(25*time.Minute)))
+
+	// Ложный визит, оставшийся от старых порогов.
+	visits := newFakeVisitRepo()
+	wrongEnd := start.Add(6 * time.Minute)
+	if err := visits.Create(&models.Visit{UserID: 1, CheckpointID: 1, StartAt: start.Add(5 * time.Minute), EndAt: &wrongEnd, Duration: 60}); err != nil {
+		t.Fatal(err)
+	}
+
+	cs := &CheckpointService{DAO: &checkpointDAOAdapter{items: []models.Checkpoint{cp}}}
+	return NewVisitEventProcessor(cs, &VisitService{DAO: visits}, locations), visits, start
+}
+
+func TestRebuildVisits_replacesVisitsFromStoredPoints(t *testing.T) {
+	vep, visits, start := rebuildFixture(t)
+
+	res, err := vep.RebuildVisits(context.Background(), 1, 1, start, start.Add(time.Hour), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Deleted != 1 || res.Points != 22 || res.Created != 1 {
+		t.Fatalf("result=%+v", res)
+	}
+	if len(visits.visits) != 1 {
+		t.Fatalf("visits in repo=%d", len(visits.visits))
+	}
+	got := res.Visits[0]
+	if !got.StartAt.Equal(start.Add(time.Minute)) || got.EndAt == nil || !got.EndAt.Equal(start.Add(20*time.Minute)) {
+		t.Fatalf("visit=%+v end=%v", got, got.EndAt)
+	}
+}
+
+func TestRebuildVisits_dryRunLeavesVisits(t *testing.T) {
+	vep, visits, start := rebuildFixture(t)
+
+	res, err := vep.RebuildVisits(context.Background(), 1, 1, start, start.Add(time.Hour), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.DryRun || res.Deleted != 1 || res.Created != 1 {
+		t.Fatalf("result=%+v", res)
+	}
+	for _, v := range visits.visits {
+		if !v.StartAt.Equal(start.Add(5 * time.Minute)) {
+			t.Fatalf("dry run changed visits: %+v", v)
+		}
+	}
+}
+
+func TestRebuildVisits_failureKeepsVisits(t *testing.T) {
+	vep, visits, start := rebuildFixture(t)
+	visits.createErr = errors.New("connection reset")
+
+	if _, err := vep.RebuildVisits(context.Background(), 1, 1, start, start.Add(time.Hour), false); err == nil {
+		t.Fatal("expected replay error")
+	}
+	if len(visits.visits) != 1 {
+		t.Fatalf("visits=%d, a failed rebuild must roll back the deletion", len(visits.visits))
+	}
+}
+
+func TestRebuildVisits_invalidRange(t *testing.T) {
+	vep, _, start := rebuildFixture(t)
+	if _, err := vep.RebuildVisits(context.Background(), 1, 1, start, start, false); err == nil {
+		t.Fatal("expected error for empty range")
+	}
+}
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"locator/internal/testutil"
+	"locator/models"
+)
+
+// Сценарии визитов — YAML в testdata/visit_scenarios: чекпоинты, поток точек с
+// временем фиксации и приёма, ожидаемые визиты. Каждый сценарий проходит весь путь
+// PostLocation: ParseCapturedAt → CreateLocation (фильтры точности и выбросов) →
+// событие → VisitEventProcessor, с часами сервера, подменёнными временем приёма точки.
+// Формат описан в docs/TESTING.md.
+
+const scenarioUserID = 1
+
+type visitScenario struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// KnownIssue — расхождение с реальностью, которое пока не исправлено.
+	KnownIssue  *scenarioKnownIssue  `yaml:"known_issue"`
+	Origin      time.Time            `yaml:"origin"`
+	Seed        int64                `yaml:"seed"`
+	Thresholds  map[string]float64   `yaml:"thresholds"`
+	Checkpoints []scenarioCheckpoint `yaml:"checkpoints"`
+	Stream      []scenarioPoint      `yaml:"stream"`
+	Expect      scenarioExpect       `yaml:"expect"`
+}
+
+type scenarioCheckpoint struct {
+	ID     int     `yaml:"id"`
+	Name   string  `yaml:"name"`
+	Lat    float64 `yaml:"lat"`
+	Lon    float64 `yaml:"lon"`
+	Radius float64 `yaml:"radius"`
+}
+
+// scenarioPoint — одна точка или серия (until и every) от начала сценария.
+type scenarioPoint struct {
+	At    time.Duration `yaml:"at"`
+	Until time.Duration `yaml:"until"`
+	Every time.Duration `yaml:"every"`
+	// Received — момент приёма сервером (сброс офлайн-очереди); пусто — сразу после фиксации.
+	Received *time.Duration `yaml:"received"`
+	// Near — чекпоинт, от центра которого отсчитываются North и East (метры).
+	Near  int     `yaml:"near"`
+	North float64 `yaml:"north"`
+	East  float64 `yaml:"east"`
+	Lat   float64 `yaml:"lat"`
+	Lon   float64 `yaml:"lon"`
+	// Jitter — шум GPS: случайное смещение в круге такого радиуса (метры).
+	Jitter    float64  `yaml:"jitter"`
+	Source    string   `yaml:"source"`
+	RequestID string   `yaml:"request_id"`
+	Accuracy  *float64 `yaml:"accuracy"`
+}
+
+type scenarioExpect struct {
+	Tolerance time.Duration   `yaml:"tolerance"`
+	Visits    []scenarioVisit `yaml:"visits"`
+	// Skipped — точки, отброшенные CreateLocation, по причинам; nil — не проверяется.
+	Skipped map[string]int `yaml:"skipped"`
+}
+
+// scenarioKnownIssue — открытая ошибка, из-за которой сценарий расходится с Expect.
+// Сценарий сверяется с текущим (неверным) результатом Actual: изменение поведения в
+// любую сторону роняет тест, а не проходит молча.
+type scenarioKnownIssue struct {
+	// ID — запись в разделе «Known issues» docs/TESTING.md.
+	ID     string          `yaml:"id"`
+	Reason string          `yaml:"reason"`
+	Actual []scenarioVisit `yaml:"actual"`
+}
+
+// scenarioVisit — ожидаемый визит; End == nil — визит ещё открыт в конце потока.
+type scenarioVisit struct {
+	Checkpoint int            `yaml:"checkpoint"`
+	Start      time.Duration  `yaml:"start"`
+	End        *time.Duration `yaml:"end"`
+}
+
+// scenarioFix — развёрнутая точка потока.
+type scenarioFix struct {
+	capturedAt time.Time
+	receivedAt time.Time
+	lat, lon   float64
+	source     string
+	requestID  string
+	accuracy   *float64
+}
+
+func TestVisitScenarios(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "visit_scenarios", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no visit scenarios in testdata/visit_scenarios")
+	}
+	for _, file := range files {
+		sc, err := loadVisitScenario(file)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		t.Run(sc.Name, func(t *testing.T) { runVisitScenario(t, sc) })
+	}
+}
+
+func loadVisitScenario(path string) (*visitScenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var sc visitScenario
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&sc); err != nil {
+		return nil, err
+	}
+	if sc.Name == "" {
+		sc.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if sc.Origin.IsZero() {
+		return nil, fmt.Errorf("origin is required")
+	}
+	if len(sc.Checkpoints) == 0 || len(sc.Stream) == 0 {
+		return nil, fmt.Errorf("checkpoints and stream are required")
+	}
+	if ki := sc.KnownIssue; ki != nil && (ki.ID == "" || ki.Reason == "") {
+		return nil, fmt.Errorf("known_issue needs id and reason")
+	}
+	if sc.Expect.Tolerance == 0 {
+		sc.Expect.Tolerance = 2 * time.Minute
+	}
+	return &sc, nil
+}
+
+func runVisitScenario(t *testing.T, sc *visitScenario) {
+	withThresholds(t, func(th *Thresholds) {
+		if err := applyScenarioThresholds(th, sc.Thresholds); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	checkpoints := make([]models.Checkpoint, 0, len(sc.Checkpoints))
+	for _, cp := range sc.Checkpoints {
+		checkpoints = append(checkpoints, testutil.Checkpoint(cp.ID, cp.Name, cp.Lat, cp.Lon, cp.Radius))
+	}
+	fixes, err := expandScenarioStream(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locations := newFakeLocationRepo()
+	visits := newFakeVisitRepo()
+	svc := newTestLocationService(locations)
+	vep := NewVisitEventProcessor(
+		&CheckpointService{DAO: &checkpointDAOAdapter{items: checkpoints}},
+		&VisitService{DAO: visits},
+		locations,
+	)
+
+	ctx := context.Background()
+	skipped := make(map[string]int)
+	for _, fix := range fixes {
+		received := fix.receivedAt
+		svc.Clock = testutil.NewClock(received)
+
+		// Как PostLocation: captured_at приходит строкой и проверяется по часам сервера.
+		capturedAt, err := svc.ParseCapturedAt(scenarioUserID, fix.capturedAt.Format(time.RFC3339))
+		if err != nil {
+			t.Fatalf("point at %s: %v", fix.capturedAt.Sub(sc.Origin), err)
+		}
+		loc, reason, err := svc.CreateLocation(ctx, scenarioUserID, fix.lat, fix.lon, fix.requestID, fix.source, capturedAt, fix.accuracy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reason != "" {
+			skipped[reason]++
+			continue
+		}
+		body, _ := json.Marshal(models.LocationEvent{
+			UserID:     scenarioUserID,
+			Latitude:   fix.lat,
+			Longitude:  fix.lon,
+			OccurredAt: loc.EffectiveAt(),
+			Source:     fix.source,
+			LocationID: loc.ID,
+		})
+		if err := vep.ProcessEvent(ctx, body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actual := make([]models.Visit, 0, len(visits.visits))
+	for _, v := range visits.visits {
+		actual = append(actual, *v)
+	}
+	sort.Slice(actual, func(i, j int) bool { return actual[i].StartAt.Before(actual[j].StartAt) })
+
+	problems := compareScenarioVisits(sc.Origin, sc.Expect.Visits, sc.Expect.Tolerance, actual)
+	if sc.Expect.Skipped != nil && !sameSkipCounts(sc.Expect.Skipped, skipped) {
+		problems = append(problems, fmt.Sprintf("skipped points %v, want %v", skipped, sc.Expect.Skipped))
+	}
+
+	report := fmt.Sprintf("%s\nexpected:\n%sactual:\n%s", strings.Join(problems, "\n"),
+		formatExpectedVisits(sc.Expect.Visits), formatActualVisits(sc.Origin, actual))
+	if ki := sc.KnownIssue; ki != nil {
+		if len(problems) == 0 {
+			t.Fatalf("scenario matches now; close %s and remove known_issue", ki.ID)
+		}
+		if drift := compareScenarioVisits(sc.Origin, ki.Actual, sc.Expect.Tolerance, actual); len(drift) > 0 {
+			t.Fatalf("known issue %s: output changed, update known_issue.actual\n%s\nrecorded:\n%sactual:\n%s",
+				ki.ID, strings.Join(drift, "\n"), formatExpectedVisits(ki.Actual), formatActualVisits(sc.Origin, actual))
+		}
+		t.Logf("known issue %s: %s\n%s", ki.ID, strings.TrimSpace(ki.Reason), report)
+		return
+	}
+	if len(problems) > 0 {
+		t.Fatalf("%s\n%s", strings.TrimSpace(sc.Description), report)
+	}
+}
+
+// applyScenarioThresholds применяет переопределения порогов по именам переменных окружения.
+func applyScenarioThresholds(th *Thresholds, overrides map[string]float64) error {
+	for name, v := range overrides {
+		switch name {
+		case "GEOFENCE_EXIT_BUFFER_METERS":
+			th.GeofenceExitBufferMeters = v
+		case "GEOFENCE_EXIT_GRACE_SECONDS":
+			th.GeofenceExitGraceSeconds = int(v)
+		case "GEOFENCE_ENTER_GRACE_SECONDS":
+			th.GeofenceEnterGraceSeconds = int(v)
+		case "GEOFENCE_MIN_VISIT_SECONDS":
+			th.GeofenceMinVisitSeconds = int(v)
+		case "GEOFENCE_FAR_EXIT_METERS":
+			th.GeofenceFarExitMeters = v
+		case "GEOFENCE_STALE_GAP_SECONDS":
+			th.GeofenceStaleGapSeconds = int(v)
+		case "LOCATION_MAX_PERIODIC_ACCURACY_M":
+			th.MaxPeriodicAccuracyM = v
+		case "LOCATION_MAX_ON_DEMAND_ACCURACY_M":
+			th.MaxOnDemandAccuracyM = v
+		default:
+			return fmt.Errorf("unknown threshold %s", name)
+		}
+	}
+	return nil
+}
+
+// expandScenarioStream разворачивает серии в точки в порядке приёма сервером.
+func expandScenarioStream(sc *visitScenario) ([]scenarioFix, error) {
+	centers := make(map[int]scenarioCheckpoint, len(sc.Checkpoints))
+	for _, cp := range sc.Checkpoints {
+		centers[cp.ID] = cp
+	}
+	rng := rand.New(rand.NewSource(sc.Seed))
+
+	var fixes []scenarioFix
+	for i, p := range sc.Stream {
+		lat, lon := p.Lat, p.Lon
+		if p.Near != 0 {
+			cp, ok := centers[p.Near]
+			if !ok {
+				return nil, fmt.Errorf("stream[%d]: unknown checkpoint %d", i, p.Near)
+			}
+			lat, lon = offsetMeters(cp.Lat, cp.Lon, p.North, p.East)
+		} else if lat == 0 && lon == 0 {
+			return nil, fmt.Errorf("stream[%d]: set near or lat/lon", i)
+		}
+		until := p.At
+		if p.Until != 0 {
+			if p.Every <= 0 || p.Until < p.At {
+				return nil, fmt.Errorf("stream[%d]: series needs every > 0 and until >= at", i)
+			}
+			until = p.Until
+		}
+		source := p.Source
+		if source == "" {
+			source = models.LocationSourcePeriodic
+		}
+		for at := p.At; at <= until; at += p.Every {
+			fix := scenarioFix{
+				capturedAt: sc.Origin.Add(at),
+				receivedAt: sc.Origin.Add(at),
+				lat:        lat,
+				lon:        lon,
+				source:     source,
+				requestID:  p.RequestID,
+				accuracy:   p.Accuracy,
+			}
+			if p.Received != nil && *p.Received > at {
+				fix.receivedAt = sc.Origin.Add(*p.Received)
+			}
+			if p.Jitter > 0 {
+				r := p.Jitter * math.Sqrt(rng.Float64())
+				bearing := 2 * math.Pi * rng.Float64()
+				fix.lat, fix.lon = offsetMeters(lat, lon, r*math.Cos(bearing), r*math.Sin(bearing))
+			}
+			fixes = append(fixes, fix)
+			if p.Every <= 0 {
+				break
+			}
+		}
+	}
+	sort.SliceStable(fixes, func(i, j int) bool { return fixes[i].receivedAt.Before(fixes[j].receivedAt) })
+	return fixes, nil
+}
+
+// offsetMeters сдвигает точку на north и east метров (плоское приближение, до пары км).
+func offsetMeters(lat, lon, north, east float64) (float64, float64) {
+	const metersPerDegree = 111320.0
+	return lat + north/metersPerDegree, lon + east/(metersPerDegree*math.Cos(lat*math.Pi/180))
+}
+
+func compareScenarioVisits(origin time.Time, want []scenarioVisit, tol time.Duration, actual []models.Visit) []string {
+	if len(want) != len(actual) {
+		return []string{fmt.Sprintf("visits = %d, want %d", len(actual), len(want))}
+	}
+	var problems []string
+	for i, w := range want {
+		got := actual[i]
+		if got.CheckpointID != w.Checkpoint {
+			problems = append(problems, fmt.Sprintf("visit %d: checkpoint %d, want %d", i, got.CheckpointID, w.Checkpoint))
+			continue
+		}
+		if d := got.StartAt.Sub(origin.Add(w.Start)); d.Abs() > tol {
+			problems = append(problems, fmt.Sprintf("visit %d: start off by %s (tolerance %s)", i, d, tol))
+		}
+		switch {
+		case w.End == nil && got.EndAt != nil:
+			problems = append(problems, fmt.Sprintf("visit %d: ended, want still open", i))
+		case w.End != nil && got.EndAt == nil:
+			problems = append(problems, fmt.Sprintf("visit %d: still open, want ended", i))
+		case w.End != nil:
+			if d := got.EndAt.Sub(origin.Add(*w.End)); d.Abs() > tol {
+				problems = append(problems, fmt.Sprintf("visit %d: end off by %s (tolerance %s)", i, d, tol))
+			}
+		}
+	}
+	return problems
+}
+
+func sameSkipCounts(want, got map[string]int) bool {
+	if len(want) != len(got) {
+		return false
+	}
+	for reason, n := range want {
+		if got[reason] != n {
+			return false
+		}
+	}
+	return true
+}
+
+func formatExpectedVisits(visits []scenarioVisit) string {
+	var b strings.Builder
+	for _, v := range visits {
+		end := "open"
+		if v.End != nil {
+			end = v.End.String()
+		}
+		fmt.Fprintf(&b, "  checkpoint %d: %s – %s\n", v.Checkpoint, v.Start, end)
+	}
+	return b.String()
+}
+
+func formatActualVisits(origin time.Time, visits []models.Visit) string {
+	var b strings.Builder
+	for _, v := range visits {
+		end := "open"
+		if v.EndAt != nil {
+			end = v.EndAt.Sub(origin).String()
+		}
+		fmt.Fprintf(&b, "  checkpoint %d: %s – %s\n", v.CheckpointID, v.StartAt.Sub(origin), end)
+	}
+	return b.String()
+}
@@ -2,11 +2,11 @@ package service
 
 import (
 	"fmt"
+	"locator/internal/metrics"
 	"locator/models"
-	"log"
+	"log/slog"
 	"net/url"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -14,34 +14,31 @@ import (
 type VisitService struct {
 	DAO            visitRepository
 	TravelSegments *TravelSegmentService
+	// Clock — текущее время (nil — системное).
+	Clock Clock
 }
 
 // NewVisitService создаёт новый экземпляр сервиса для работы с визитами.
 func NewVisitService(dao visitRepository, travelSegments *TravelSegmentService) *VisitService {
-	log.Printf("[NewVisitService] Инициализация сервиса визитов")
 	return &VisitService{DAO: dao, TravelSegments: travelSegments}
 }
 
 // GetActiveVisit возвращает активный визит пользователя в указанный чекпоинт.
 func (vs *VisitService) GetActiveVisit(userID int, checkpointID int) (*models.Visit, error) {
-	log.Printf("[GetActiveVisit] Запрос активного визита для userID=%d, checkpointID=%d", userID, checkpointID)
 	visit, err := vs.DAO.GetActiveVisit(userID, checkpointID)
 	if err != nil {
-		log.Printf("[GetActiveVisit] Ошибка получения активного визита для userID=%d, checkpointID=%d: %v", userID, checkpointID, err)
-	} else {
-		log.Printf("[GetActiveVisit] Получен активный визит для userID=%d, checkpointID=%d: Visit=%+v", userID, checkpointID, visit)
+		slog.Error("Ошибка получения активного визита", "user_id", userID, "checkpoint_id", checkpointID, "error", err)
 	}
 	return visit, err
 }
 
 // StartVisit начинается новый визит.
 func (vs *VisitService) StartVisit(userID int, checkpointID int) (*models.Visit, error) {
-	return vs.StartVisitAt(userID, checkpointID, time.Now().UTC())
+	return vs.StartVisitAt(userID, checkpointID, clockNow(vs.Clock).UTC())
 }
 
 // StartVisitAt начинает визит с указанным временем (в т.ч. из captured_at офлайн-точки).
 func (vs *VisitService) StartVisitAt(userID int, checkpointID int, at time.Time) (*models.Visit, error) {
-	log.Printf("[StartVisitAt] userID=%d, checkpointID=%d, at=%s", userID, checkpointID, at.UTC())
 	visit := &models.Visit{
 		UserID:       userID,
 		CheckpointID: checkpointID,
@@ -49,23 +46,21 @@ func (vs *VisitService) StartVisitAt(userID int, checkpointID int, at time.Time)
 	}
 	err := vs.DAO.Create(visit)
 	if err != nil {
-		log.Printf("[StartVisitAt] Ошибка создания визита: %v", err)
+		slog.Error("Ошибка создания визита", "user_id", userID, "checkpoint_id", checkpointID, "error", err)
 		return nil, err
 	}
-	log.Printf("[StartVisitAt] Визит начат VisitID=%d", visit.ID)
+	slog.Info("Визит начат", "visit_id", visit.ID, "user_id", userID, "checkpoint_id", checkpointID, "at", at.UTC())
+	metrics.Visits.Inc("start")
 	return visit, nil
 }
 
 // EndVisit завершает активный визит, фиксируя время окончания и вычисляя длительность.
 func (vs *VisitService) EndVisit(visit *models.Visit) error {
-	return vs.EndVisitAt(visit, time.Now().UTC())
+	return vs.EndVisitAt(visit, clockNow(vs.Clock).UTC())
 }
 
 // EndVisitAt завершает визит в указанное время (в т.ч. captured_at офлайн-точки).
 func (vs *VisitService) EndVisitAt(visit *models.Visit, at time.Time) error {
-	log.Printf("[EndVisitAt] userID=%d, checkpointID=%d, VisitID=%d, at=%s",
-		visit.UserID, visit.CheckpointID, visit.ID, at.UTC())
-
 	endUTC := at.UTC()
 	startUTC := visit.StartAt.UTC()
 	if endUTC.Before(startUTC) {
@@ -77,37 +72,60 @@ func (vs *VisitService) EndVisitAt(visit *models.Visit, at time.Time) error {
 
 	err := vs.DAO.Update(visit)
 	if err != nil {
-		log.Printf("[EndVisitAt] Ошибка: %v", err)
+		slog.Error("Ошибка завершения визита", "visit_id", visit.ID, "error", err)
 		return err
 	}
-	log.Printf("[EndVisitAt] Визит завершён, длительность=%d с", visit.Duration)
+	slog.Info("Визит завершён", "visit_id", visit.ID, "user_id", visit.UserID, "checkpoint_id", visit.CheckpointID,
+		"at", endUTC, "duration_s", visit.Duration)
+	metrics.Visits.Inc("end")
 	return nil
 }
 
+// CloseActiveVisits завершает все активные визиты пользователя в момент at
+// (начало паузы отслеживания): визит не продолжается через время без наблюдения.
+func (vs *VisitService) CloseActiveVisits(organizationID, userID int, at time.Time) (int, error) {
+	visits, err := vs.GetVisits(organizationID, map[string]interface{}{"user_id": userID}, true, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	for i := range visits {
+		if err := vs.EndVisitAt(&visits[i], at); err != nil {
+			return i, err
+		}
+	}
+	return len(visits), nil
+}
+
 // AbandonVisit удаляет активный визит без записи в историю (короткий ложный визит).
 func (vs *VisitService) AbandonVisit(visit *models.Visit) error {
-	log.Printf("[AbandonVisit] Удаление короткого визита: userID=%d, checkpointID=%d, VisitID=%d",
-		visit.UserID, visit.CheckpointID, visit.ID)
-	return vs.DAO.Delete(visit.ID)
+	slog.Info("Удаление короткого визита", "visit_id", visit.ID, "user_id", visit.UserID, "checkpoint_id", visit.CheckpointID)
+	if err := vs.DAO.Delete(visit.ID); err != nil {
+		return err
+	}
+	metrics.Visits.Inc("abandon")
+	return nil
 }
 
-// GetVisits возвращает список визитов с применением переданных фильтров.
+// GetVisits возвращает список визитов пользователей организации с применением переданных фильтров.
 func (vs *VisitService) GetVisits(
+	organizationID int,
 	filters map[string]interface{},
 	activeOnly bool,
 	rangeFrom, rangeTo *time.Time,
 ) ([]models.Visit, error) {
-	return vs.DAO.GetVisits(filters, activeOnly, rangeFrom, rangeTo)
+	return vs.DAO.GetVisits(organizationOrDefault(organizationID), filters, activeOnly, rangeFrom, rangeTo)
 }
 
-// GetVisitsByFilters анализирует query-параметры, формирует фильтры и возвращает список визитов.
-func (vs *VisitService) GetVisitsByFilters(params url.Values) ([]models.Visit, error) {
+// GetVisitsByFilters анализирует query-параметры, формирует фильтры и возвращает список
+// визитов организации organizationID. Локальные from/to толкуются в поясе loc, времена
+// визитов в ответе — в нём же (nil — пояс по умолчанию, времена в UTC).
+func (vs *VisitService) GetVisitsByFilters(organizationID int, params url.Values, loc *time.Location) ([]models.Visit, error) {
 	filters := make(map[string]interface{})
 
 	if idStr := params.Get("id"); idStr != "" {
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			log.Printf("[GetVisitsByFilters] неверный формат параметра id: %v", err)
+			slog.Debug("Неверный формат параметра визитов", "param", "id", "error", err)
 		} else {
 			filters["id"] = id
 		}
@@ -116,7 +134,7 @@ func (vs *VisitService) GetVisitsByFilters(params url.Values) ([]models.Visit, e
 	if userIDStr := params.Get("user_id"); userIDStr != "" {
 		userID, err := strconv.Atoi(userIDStr)
 		if err != nil {
-			log.Printf("[GetVisitsByFilters] неверный формат параметра user_id: %v", err)
+			slog.Debug("Неверный формат параметра визитов", "param", "user_id", "error", err)
 		} else {
 			filters["user_id"] = userID
 		}
@@ -125,7 +143,7 @@ func (vs *VisitService) GetVisitsByFilters(params url.Values) ([]models.Visit, e
 	if checkpointIDStr := params.Get("checkpoint_id"); checkpointIDStr != "" {
 		checkpointID, err := strconv.Atoi(checkpointIDStr)
 		if err != nil {
-			log.Printf("[GetVisitsByFilters] неверный формат параметра checkpoint_id: %v", err)
+			slog.Debug("Неверный формат параметра визитов", "param", "checkpoint_id", "error", err)
 		} else {
 			filters["checkpoint_id"] = checkpointID
 		}
@@ -143,7 +161,7 @@ func (vs *VisitService) GetVisitsByFilters(params url.Values) ([]models.Visit, e
 		if fromStr == "" || toStr == "" {
 			return nil, fmt.Errorf("укажите оба параметра from и to")
 		}
-		from, to, err := parseVisitQueryRange(fromStr, toStr)
+		from, to, err := parseLocalRange(fromStr, toStr, loc)
 		if err != nil {
 			return nil, err
 		}
@@ -151,14 +169,14 @@ func (vs *VisitService) GetVisitsByFilters(params url.Values) ([]models.Visit, e
 		rangeTo = &to
 	}
 
-	visits, err := vs.GetVisits(filters, activeOnly, rangeFrom, rangeTo)
+	visits, err := vs.GetVisits(organizationID, filters, activeOnly, rangeFrom, rangeTo)
 	if err != nil {
 		return nil, err
 	}
 
 	includeOutside := params.Get("include_outside") == "true" || params.Get("include_outside") == "1"
 	if !includeOutside || vs.TravelSegments == nil || activeOnly {
-		return visits, nil
+		return localizeVisits(visits, loc), nil
 	}
 
 	userID, hasUser := filters["user_id"].(int)
@@ -166,48 +184,18 @@ func (vs *VisitService) GetVisitsByFilters(params url.Values) ([]models.Visit, e
 		return nil, fmt.Errorf("для участков вне чекпоинтов укажите user_id, from и to")
 	}
 
-	outside, err := vs.TravelSegments.GetOutsideSegments(userID, *rangeFrom, *rangeTo)
+	outside, err := vs.TravelSegments.GetOutsideSegments(organizationOrDefault(organizationID), userID, *rangeFrom, *rangeTo)
 	if err != nil {
 		return nil, err
 	}
 
-	return mergeVisitsSorted(visits, outside), nil
+	return localizeVisits(mergeVisitsSorted(visits, outside), loc), nil
 }
 
-func parseVisitQueryRange(fromStr, toStr string) (time.Time, time.Time, error) {
-	loc, err := time.LoadLocation("Europe/Minsk")
-	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("часовой пояс Europe/Minsk: %w", err)
-	}
-	from, err := parseVisitQueryTime(fromStr, loc)
-	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %w", err)
-	}
-	to, err := parseVisitQueryTime(toStr, loc)
-	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %w", err)
-	}
-	to = normalizeVisitRangeEnd(to, toStr)
-	if from.After(to) {
-		return time.Time{}, time.Time{}, fmt.Errorf("начало интервала не может быть позже окончания")
-	}
-	return from.UTC(), to.UTC(), nil
-}
-
-func parseVisitQueryTime(s string, loc *time.Location) (time.Time, error) {
-	if strings.ContainsAny(s, "Z+") {
-		return time.Parse(time.RFC3339, s)
-	}
-	return time.ParseInLocation("2006-01-02T15:04", s, loc)
-}
-
-func normalizeVisitRangeEnd(to time.Time, toStr string) time.Time {
-	if !strings.Contains(toStr, "T") {
-		return to
-	}
-	timePart := strings.SplitN(toStr, "T", 2)[1]
-	if strings.Count(timePart, ":") == 1 {
-		return to.Add(59*time.Second + 999*time.Millisecond)
+// localizeVisits переводит времена визитов в пояс loc (nil — без изменений).
+func localizeVisits(visits []models.Visit, loc *time.Location) []models.Visit {
+	for i := range visits {
+		visits[i].In(loc)
 	}
-	return to
+	return visits
 }
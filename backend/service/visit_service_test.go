@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"locator/dao"
 	"locator/internal/testutil"
 	"locator/models"
 
@@ -15,6 +16,8 @@ import (
 type fakeVisitRepo struct {
 	visits map[int64]*models.Visit
 	nextID int64
+	// createErr — ошибка Create (сбой БД посреди пересборки).
+	createErr error
 }
 
 func newFakeVisitRepo() *fakeVisitRepo {
@@ -22,6 +25,9 @@ func newFakeVisitRepo() *fakeVisitRepo {
 }
 
 func (f *fakeVisitRepo) Create(visit *models.Visit) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
 	if visit.ID == 0 {
 		visit.ID = f.nextID
 		f.nextID++
@@ -42,6 +48,20 @@ func (f *fakeVisitRepo) Delete(id int64) error {
 	return nil
 }
 
+// InUserLock откатывает изменения визитов, если fn вернула ошибку.
+func (f *fakeVisitRepo) InUserLock(userID int, fn func(tx dao.VisitStore) error) error {
+	saved, nextID := make(map[int64]*models.Visit, len(f.visits)), f.nextID
+	for id, v := range f.visits {
+		cp := *v
+		saved[id] = &cp
+	}
+	if err := fn(f); err != nil {
+		f.visits, f.nextID = saved, nextID
+		return err
+	}
+	return nil
+}
+
 func (f *fakeVisitRepo) GetActiveVisit(userID int, checkpointID int) (*models.Visit, error) {
 	for _, v := range f.visits {
 		if v.UserID == userID && v.CheckpointID == checkpointID && v.EndAt == nil {
@@ -52,7 +72,7 @@ func (f *fakeVisitRepo) GetActiveVisit(userID int, checkpointID int) (*models.Vi
 	return nil, gorm.ErrRecordNotFound
 }
 
-func (f *fakeVisitRepo) GetVisits(filters map[string]interface{}, activeOnly bool, rangeFrom, rangeTo *time.Time) ([]models.Visit, error) {
+func (f *fakeVisitRepo) GetVisits(organizationID int, filters map[string]interface{}, activeOnly bool, rangeFrom, rangeTo *time.Time) ([]models.Visit, error) {
 	var out []models.Visit
 	for _, v := range f.visits {
 		if uid, ok := filters["user_id"].(int); ok && v.UserID != uid {
@@ -93,6 +113,22 @@ func TestStartVisitAt_andEndVisitAt(t *testing.T) {
 	}
 }
 
+func TestStartVisit_usesClock(t *testing.T) {
+	clock := testutil.NewClock(testutil.FixedUTC(2026, 7, 1, 10, 0, 0))
+	vs := &VisitService{DAO: newFakeVisitRepo(), Clock: clock}
+	visit, err := vs.StartVisit(1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(20 * time.Minute)
+	if err := vs.EndVisit(visit); err != nil {
+		t.Fatal(err)
+	}
+	if !visit.StartAt.Equal(testutil.FixedUTC(2026, 7, 1, 10, 0, 0)) || visit.Duration != 20*60 {
+		t.Fatalf("unexpected visit %+v", visit)
+	}
+}
+
 func TestEndVisitAt_clampsBeforeStart(t *testing.T) {
 	repo := newFakeVisitRepo()
 	vs := &VisitService{DAO: repo}
@@ -131,7 +167,7 @@ func TestAbandonVisit(t *testing.T) {
 
 func TestGetVisitsByFilters_requiresBothFromTo(t *testing.T) {
 	vs := &VisitService{DAO: newFakeVisitRepo()}
-	_, err := vs.GetVisitsByFilters(url.Values{"from": {"2026-07-01T10:00"}})
+	_, err := vs.GetVisitsByFilters(0, url.Values{"from": {"2026-07-01T10:00"}}, nil)
 	if err == nil {
 		t.Fatal("expected error when only from is set")
 	}
@@ -142,10 +178,10 @@ func TestGetVisitsByFilters_userAndActive(t *testing.T) {
 	vs := &VisitService{DAO: repo}
 	_, _ = vs.StartVisitAt(7, 1, testutil.FixedUTC(2026, 7, 1, 9, 0, 0))
 
-	got, err := vs.GetVisitsByFilters(url.Values{
+	got, err := vs.GetVisitsByFilters(0, url.Values{
 		"user_id": {"7"},
 		"active":  {"true"},
-	})
+	}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
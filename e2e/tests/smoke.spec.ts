@@ -1,6 +1,7 @@
 import { test, expect } from '@playwright/test';
 
-const apiKey = process.env.E2E_API_KEY || process.env.DEFAULT_ADMIN_API_KEY || '';
+const username = process.env.E2E_USERNAME || process.env.DEFAULT_ADMIN_USERNAME || 'admin';
+const password = process.env.E2E_PASSWORD || process.env.DEFAULT_ADMIN_PASSWORD || '';
 
 async function ensureLocatorUI(page: import('@playwright/test').Page) {
   try {
@@ -18,12 +19,13 @@ async function ensureLocatorUI(page: import('@playwright/test').Page) {
 }
 
 async function login(page: import('@playwright/test').Page) {
-  if (!apiKey) {
-    test.skip(true, 'set E2E_API_KEY or DEFAULT_ADMIN_API_KEY for e2e login');
+  if (!password) {
+    test.skip(true, 'set E2E_PASSWORD or DEFAULT_ADMIN_PASSWORD for e2e login');
   }
   await page.goto('/login');
-  await expect(page.locator('#apiKey')).toBeVisible({ timeout: 10_000 });
-  await page.locator('#apiKey').fill(apiKey);
+  await expect(page.locator('#username')).toBeVisible({ timeout: 10_000 });
+  await page.locator('#username').fill(username);
+  await page.locator('#password').fill(password);
   await page.getByRole('button', { name: /Войти/i }).click();
   await expect(page).not.toHaveURL(/\/login/, { timeout: 15_000 });
 }
@@ -58,9 +60,10 @@ test.describe('Locator admin smoke', () => {
     await expect(page.locator('main')).toBeVisible();
   });
 
-  test('login page renders without key', async ({ page }) => {
+  test('login page renders without credentials', async ({ page }) => {
     await page.goto('/login');
-    await expect(page.locator('#apiKey')).toBeVisible({ timeout: 10_000 });
+    await expect(page.locator('#username')).toBeVisible({ timeout: 10_000 });
+    await expect(page.locator('#password')).toBeVisible();
     await expect(page.getByRole('button', { name: /Войти/i })).toBeVisible();
   });
 });
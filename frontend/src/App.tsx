@@ -3,6 +3,8 @@ import { BrowserRouter as Router, Routes, Route, Link, Navigate } from 'react-ro
 import Dashboard from './pages/Dashboard';
 import Checkpoints from './pages/Checkpoints';
 import UserVisits from './pages/UserVisits';
+import Account from './pages/Account';
+import Audit from './pages/Audit';
 import Login from './components/Login';
 import UserManagement from './components/UserManagement';
 import { AuthProvider, useAuth } from './context/AuthContext';
@@ -53,9 +55,14 @@ const Navigation: React.FC = () => {
                         <Link to="/users">Пользователи</Link>
                     </li>
                 )}
+                {(user?.role === 'fleet_admin' || user?.role === 'super_admin' || (!user?.role && user?.is_admin)) && (
+                    <li>
+                        <Link to="/audit">Аудит</Link>
+                    </li>
+                )}
             </ul>
             <div className="user-controls">
-                <span className="user-info">{user?.name} ({user?.is_admin ? 'Админ' : 'Пользователь'})</span>
+                <Link to="/account" className="user-info">{user?.name} ({user?.is_admin ? 'Админ' : 'Пользователь'})</Link>
                 <button onClick={logout} className="logout-button">Выйти</button>
             </div>
         </nav>
@@ -96,6 +103,18 @@ const AppRoutes: React.FC = () => {
                 </ProtectedRoute>
             } />
 
+            <Route path="/audit" element={
+                <ProtectedRoute>
+                    <Audit />
+                </ProtectedRoute>
+            } />
+
+            <Route path="/account" element={
+                <ProtectedRoute>
+                    <Account />
+                </ProtectedRoute>
+            } />
+
             <Route path="*" element={<h1>Страница не найдена</h1>} />
         </Routes>
     );
@@ -0,0 +1,158 @@
+import React, { useCallback, useEffect, useState } from 'react';
+import { userApi } from '../services/api';
+import type { ApiKeyInfo, User } from '../types/models';
+import { formatDateTime } from '../utils/dateFormat';
+
+type Props = {
+    user: User;
+    apiKey: string;
+    onClose: () => void;
+};
+
+function keyStatus(key: ApiKeyInfo): string {
+    if (key.revoked_at) return `отозван ${formatDateTime(key.revoked_at)}`;
+    if (key.expires_at && new Date(key.expires_at).getTime() <= Date.now()) return 'истёк';
+    if (key.replaced_by_id) return `заменён, действует до ${formatDateTime(key.expires_at)}`;
+    if (key.expires_at) return `действует до ${formatDateTime(key.expires_at)}`;
+    return 'действует';
+}
+
+// API-ключи пользователя: основной (QR), ключи в периоде передачи и дополнительные
+const ApiKeysPanel: React.FC<Props> = ({ user, apiKey, onClose }) => {
+    const [keys, setKeys] = useState<ApiKeyInfo[]>([]);
+    const [error, setError] = useState<string | null>(null);
+    const [label, setLabel] = useState('');
+    const [expiresAt, setExpiresAt] = useState('');
+    const [issued, setIssued] = useState<string | null>(null);
+
+    const fetchKeys = useCallback(async () => {
+        try {
+            setKeys(await userApi.getApiKeys(user.id, apiKey));
+            setError(null);
+        } catch (err) {
+            setError(err instanceof Error ? err.message : 'Не удалось получить API-ключи');
+        }
+    }, [user.id, apiKey]);
+
+    useEffect(() => {
+        fetchKeys();
+    }, [fetchKeys]);
+
+    const handleCreate = async (e: React.FormEvent) => {
+        e.preventDefault();
+        try {
+            const result = await userApi.createApiKey(
+                user.id,
+                {
+                    label: label.trim(),
+                    ...(expiresAt ? { expires_at: new Date(expiresAt).toISOString() } : {}),
+                },
+                apiKey
+            );
+            setIssued(result.api_key);
+            setLabel('');
+            setExpiresAt('');
+            fetchKeys();
+        } catch (err) {
+            setError(err instanceof Error ? err.message : 'Не удалось выпустить API-ключ');
+        }
+    };
+
+    const handleRevoke = async (key: ApiKeyInfo) => {
+        const warning = key.primary ? '\nЭто ключ из QR-кода — телефон потеряет связь с сервером.' : '';
+        if (!window.confirm(`Отозвать ключ «${key.label || key.fingerprint}»?${warning}`)) return;
+        try {
+            await userApi.revokeApiKey(user.id, key.id, apiKey);
+            fetchKeys();
+        } catch (err) {
+            setError(err instanceof Error ? err.message : 'Не удалось отозвать API-ключ');
+        }
+    };
+
+    return (
+        <div className="qr-code-modal">
+            <div className="qr-code-container">
+                <div className="qr-code-header">
+                    <h3>API-ключи: {user.name}</h3>
+                    <button className="close-button" onClick={onClose}>
+                        ×
+                    </button>
+                </div>
+
+                {error && <div className="error-message">{error}</div>}
+                {issued && (
+                    <div className="success-message">
+                        Новый ключ (показывается один раз): <code>{issued}</code>
+                    </div>
+                )}
+
+                <table className="users-table">
+                    <thead>
+                        <tr>
+                            <th>Название</th>
+                            <th>Отпечаток</th>
+                            <th>Создан</th>
+                            <th>Последнее использование</th>
+                            <th>Статус</th>
+                            <th></th>
+                        </tr>
+                    </thead>
+                    <tbody>
+                        {keys.map((key) => (
+                            <tr key={key.id}>
+                                <td>
+                                    {key.label || '—'}
+                                    {key.primary && ' (QR)'}
+                                </td>
+                                <td>
+                                    <code>{key.fingerprint || '—'}</code>
+                                </td>
+                                <td>{formatDateTime(key.created_at)}</td>
+                                <td>
+                                    {formatDateTime(key.last_used_at)}
+                                    {key.last_used_ip && ` · ${key.last_used_ip}`}
+                                </td>
+                                <td>{keyStatus(key)}</td>
+                                <td>
+                                    {!key.revoked_at && (
+                                        <button className="device-action-button" onClick={() => handleRevoke(key)}>
+                                            Отозвать
+                                        </button>
+                                    )}
+                                </td>
+                            </tr>
+                        ))}
+                    </tbody>
+                </table>
+
+                <form onSubmit={handleCreate}>
+                    <div className="form-group">
+                        <label htmlFor="apiKeyLabel">Название дополнительного ключа</label>
+                        <input
+                            id="apiKeyLabel"
+                            type="text"
+                            maxLength={100}
+                            value={label}
+                            onChange={(e) => setLabel(e.target.value)}
+                            placeholder="например, скрипт выгрузки"
+                        />
+                    </div>
+                    <div className="form-group">
+                        <label htmlFor="apiKeyExpires">Действует до (необязательно)</label>
+                        <input
+                            id="apiKeyExpires"
+                            type="datetime-local"
+                            value={expiresAt}
+                            onChange={(e) => setExpiresAt(e.target.value)}
+                        />
+                    </div>
+                    <button type="submit" className="button">
+                        Выпустить ключ
+                    </button>
+                </form>
+            </div>
+        </div>
+    );
+};
+
+export default ApiKeysPanel;
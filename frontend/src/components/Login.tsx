@@ -1,58 +1,135 @@
 // components/Login.tsx
-import React, { useState } from 'react';
+import React, { useEffect, useRef, useState } from 'react';
 import { useAuth } from '../context/AuthContext';
+import { ApiError } from '../services/api';
+
+const needsTOTP = (error: unknown) =>
+    error instanceof ApiError && (error.code === 'totp_required' || error.code === 'totp_invalid');
 
 const Login: React.FC = () => {
-    const [apiKey, setApiKey] = useState('');
+    const [username, setUsername] = useState('');
+    const [password, setPassword] = useState('');
+    const [totpCode, setTotpCode] = useState('');
+    const [showTotp, setShowTotp] = useState(false);
     const [isLoading, setIsLoading] = useState(false);
-    const { login, error } = useAuth();
+    const { login, loginWithLink, error } = useAuth();
 
-    const handleSubmit = async (e: React.FormEvent) => {
-        e.preventDefault();
-        if (!apiKey.trim()) return;
+    // Одноразовая ссылка входа от администратора: /login?login_token=...
+    const [linkToken] = useState(
+        () => new URLSearchParams(window.location.search).get('login_token') ?? '',
+    );
+    const linkTried = useRef(false);
 
+    const submit = async (request: () => Promise<void>) => {
         setIsLoading(true);
         try {
-            await login(apiKey);
-        } catch {
-            // Ошибка уже обрабатывается в AuthContext
+            await request();
+        } catch (err) {
+            // Текст ошибки уже выставлен в AuthContext; здесь только запрашиваем код TOTP
+            if (needsTOTP(err)) {
+                setShowTotp(true);
+            }
         } finally {
             setIsLoading(false);
         }
     };
 
+    useEffect(() => {
+        if (!linkToken || linkTried.current) return;
+        linkTried.current = true;
+        // Токен ссылки одноразовый — убираем его из адресной строки
+        window.history.replaceState(null, '', window.location.pathname);
+        submit(() => loginWithLink(linkToken));
+        // eslint-disable-next-line react-hooks/exhaustive-deps
+    }, [linkToken]);
+
+    const byLink = linkToken !== '';
+    const canSubmit = byLink
+        ? totpCode.trim() !== ''
+        : username.trim() !== '' && password !== '' && (!showTotp || totpCode.trim() !== '');
+
+    const handleSubmit = async (e: React.FormEvent) => {
+        e.preventDefault();
+        if (!canSubmit) return;
+
+        const code = showTotp ? totpCode.trim() : undefined;
+        await submit(() =>
+            byLink ? loginWithLink(linkToken, code) : login(username.trim(), password, code),
+        );
+    };
+
     return (
         <div className="login-container">
             <div className="login-form">
                 <h2>Вход в систему</h2>
-                <p>Пожалуйста, введите свой API ключ для доступа к системе.</p>
+                <p>
+                    {byLink
+                        ? 'Вход по одноразовой ссылке.'
+                        : 'Введите логин и пароль администратора.'}
+                </p>
 
                 {error && <div className="error-message">{error}</div>}
 
                 <form onSubmit={handleSubmit}>
-                    <div className="form-group">
-                        <label htmlFor="apiKey">API Ключ</label>
-                        <input
-                            type="password"
-                            id="apiKey"
-                            value={apiKey}
-                            onChange={(e) => setApiKey(e.target.value)}
-                            disabled={isLoading}
-                            placeholder="Введите ваш API ключ"
-                        />
-                    </div>
-
-                    <button
-                        type="submit"
-                        className="login-button"
-                        disabled={isLoading || !apiKey.trim()}
-                    >
-                        {isLoading ? 'Вход...' : 'Войти'}
-                    </button>
+                    {!byLink && (
+                        <>
+                            <div className="form-group">
+                                <label htmlFor="username">Логин</label>
+                                <input
+                                    type="text"
+                                    id="username"
+                                    autoComplete="username"
+                                    value={username}
+                                    onChange={(e) => setUsername(e.target.value)}
+                                    disabled={isLoading}
+                                    placeholder="Введите логин"
+                                />
+                            </div>
+                            <div className="form-group">
+                                <label htmlFor="password">Пароль</label>
+                                <input
+                                    type="password"
+                                    id="password"
+                                    autoComplete="current-password"
+                                    value={password}
+                                    onChange={(e) => setPassword(e.target.value)}
+                                    disabled={isLoading}
+                                    placeholder="Введите пароль"
+                                />
+                            </div>
+                        </>
+                    )}
+
+                    {showTotp && (
+                        <div className="form-group">
+                            <label htmlFor="totpCode">Код из приложения-аутентификатора</label>
+                            <input
+                                type="text"
+                                id="totpCode"
+                                inputMode="numeric"
+                                autoComplete="one-time-code"
+                                maxLength={6}
+                                value={totpCode}
+                                onChange={(e) => setTotpCode(e.target.value)}
+                                disabled={isLoading}
+                                placeholder="000000"
+                            />
+                        </div>
+                    )}
+
+                    {(!byLink || showTotp) && (
+                        <button
+                            type="submit"
+                            className="login-button"
+                            disabled={isLoading || !canSubmit}
+                        >
+                            {isLoading ? 'Вход...' : 'Войти'}
+                        </button>
+                    )}
                 </form>
             </div>
         </div>
     );
 };
 
-export default Login;
\ No newline at end of file
+export default Login;
@@ -1,6 +1,7 @@
 // components/QRCodeDisplay.tsx
 import React, { useEffect, useState } from 'react';
 import { useAuth } from '../context/AuthContext';
+import { authHeader } from '../services/api';
 
 interface QRCodeDisplayProps {
     onClose?: () => void;
@@ -36,7 +37,7 @@ const QRCodeDisplay: React.FC<QRCodeDisplayProps> = ({ onClose, userId, userName
 
                 const response = await fetch(url, {
                     headers: {
-                        'X-API-Key': apiKey,
+                        ...authHeader(apiKey),
                         'Cache-Control': 'no-cache',
                         Pragma: 'no-cache',
                     },
@@ -0,0 +1,285 @@
+import React, { useCallback, useEffect, useState } from 'react';
+import { ApiError, trackingScheduleApi } from '../services/api';
+import type { TrackingSchedule, TrackingScheduleStatus, TrackingWindow, User } from '../types/models';
+import { formatDateTime } from '../utils/dateFormat';
+
+type Props = {
+    user: User;
+    apiKey: string;
+    onClose: () => void;
+};
+
+const WEEKDAYS = ['Пн', 'Вт', 'Ср', 'Чт', 'Пт', 'Сб', 'Вс'];
+const DEFAULT_WINDOW: TrackingWindow = { days: [1, 2, 3, 4, 5], start: '09:00', end: '18:00' };
+
+function statusText(status: TrackingScheduleStatus | null): string {
+    if (!status) return 'ограничений нет';
+    const until = status.until ? ` до ${formatDateTime(status.until)}` : '';
+    if (!status.paused) return `отслеживание идёт${until}`;
+    if (status.reason === 'consent') return 'на паузе: нет согласия';
+    return `на паузе по графику${until}`;
+}
+
+// Окна отслеживания, праздники и согласие пользователя на отслеживание
+const TrackingSchedulePanel: React.FC<Props> = ({ user, apiKey, onClose }) => {
+    const [schedule, setSchedule] = useState<TrackingSchedule | null>(null);
+    const [status, setStatus] = useState<TrackingScheduleStatus | null>(null);
+    const [error, setError] = useState<string | null>(null);
+    const [saved, setSaved] = useState(false);
+
+    const [enabled, setEnabled] = useState(true);
+    const [timezone, setTimezone] = useState('');
+    const [windows, setWindows] = useState<TrackingWindow[]>([]);
+    const [holidays, setHolidays] = useState('');
+    const [outsideAction, setOutsideAction] = useState<'drop' | 'mask'>('drop');
+    const [consentRequired, setConsentRequired] = useState(false);
+    const [consentBy, setConsentBy] = useState('');
+
+    const applyResponse = (next: { schedule: TrackingSchedule; status: TrackingScheduleStatus }) => {
+        setSchedule(next.schedule);
+        setStatus(next.status);
+        setEnabled(next.schedule.enabled);
+        setTimezone(next.schedule.timezone ?? '');
+        setWindows(next.schedule.windows ?? []);
+        setHolidays((next.schedule.holidays ?? []).join('\n'));
+        setOutsideAction(next.schedule.outside_action);
+        setConsentRequired(next.schedule.consent_required);
+    };
+
+    const fetchSchedule = useCallback(async () => {
+        try {
+            applyResponse(await trackingScheduleApi.get(user.id, apiKey));
+            setError(null);
+        } catch (err) {
+            if (err instanceof ApiError && err.status === 404) {
+                setSchedule(null);
+                setStatus(null);
+                return;
+            }
+            setError(err instanceof Error ? err.message : 'Не удалось получить график отслеживания');
+        }
+    }, [user.id, apiKey]);
+
+    useEffect(() => {
+        fetchSchedule();
+    }, [fetchSchedule]);
+
+    const updateWindow = (index: number, patch: Partial<TrackingWindow>) => {
+        setWindows((prev) => prev.map((w, i) => (i === index ? { ...w, ...patch } : w)));
+    };
+
+    const toggleDay = (index: number, day: number) => {
+        const days = windows[index].days;
+        updateWindow(index, {
+            days: days.includes(day) ? days.filter((d) => d !== day) : [...days, day].sort((a, b) => a - b),
+        });
+    };
+
+    const handleSave = async (e: React.FormEvent) => {
+        e.preventDefault();
+        setSaved(false);
+        try {
+            applyResponse(
+                await trackingScheduleApi.save(
+                    user.id,
+                    {
+                        enabled,
+                        timezone: timezone.trim(),
+                        windows,
+                        holidays: holidays
+                            .split(/[\s,]+/)
+                            .map((h) => h.trim())
+                            .filter(Boolean),
+                        outside_action: outsideAction,
+                        consent_required: consentRequired,
+                    },
+                    apiKey
+                )
+            );
+            setError(null);
+            setSaved(true);
+        } catch (err) {
+            setError(err instanceof Error ? err.message : 'Не удалось сохранить график отслеживания');
+        }
+    };
+
+    const handleDelete = async () => {
+        if (!window.confirm(`Снять все ограничения отслеживания для «${user.name}»?`)) return;
+        try {
+            await trackingScheduleApi.remove(user.id, apiKey);
+            setWindows([]);
+            setHolidays('');
+            setConsentRequired(false);
+            fetchSchedule();
+        } catch (err) {
+            setError(err instanceof Error ? err.message : 'Не удалось удалить график отслеживания');
+        }
+    };
+
+    const handleConsent = async (given: boolean) => {
+        if (!given && !window.confirm('Отозвать согласие? Отслеживание будет приостановлено.')) return;
+        try {
+            applyResponse(await trackingScheduleApi.consent(user.id, given, consentBy.trim(), apiKey));
+            setConsentBy('');
+            setError(null);
+        } catch (err) {
+            setError(err instanceof Error ? err.message : 'Не удалось сохранить согласие');
+        }
+    };
+
+    const consentActive = !!schedule?.consent_given_at && !schedule?.consent_revoked_at;
+
+    return (
+        <div className="qr-code-modal">
+            <div className="qr-code-container">
+                <div className="qr-code-header">
+                    <h3>Окна отслеживания: {user.name}</h3>
+                    <button className="close-button" onClick={onClose}>
+                        ×
+                    </button>
+                </div>
+
+                {error && <div className="error-message">{error}</div>}
+                {saved && <div className="success-message">График сохранён и отправлен на телефон</div>}
+
+                <p>
+                    Сейчас: <strong>{statusText(status)}</strong>
+                    {schedule?.last_pushed_at && ` · отправлено на телефон ${formatDateTime(schedule.last_pushed_at)}`}
+                </p>
+
+                <form onSubmit={handleSave}>
+                    <label className="form-check device-control-check">
+                        <input type="checkbox" checked={enabled} onChange={(e) => setEnabled(e.target.checked)} />{' '}
+                        График включён
+                    </label>
+                    <div className="form-group">
+                        <label htmlFor="trackingTimezone">Часовой пояс (пусто — пояс пользователя)</label>
+                        <input
+                            id="trackingTimezone"
+                            type="text"
+                            value={timezone}
+                            onChange={(e) => setTimezone(e.target.value)}
+                            placeholder={status?.timezone ?? 'как у пользователя'}
+                        />
+                    </div>
+
+                    <div className="form-group">
+                        <label>Разрешённые окна (без окон — отслеживание разрешено всегда)</label>
+                        {windows.map((w, index) => (
+                            <div key={index} className="tracking-window-row">
+                                {WEEKDAYS.map((name, i) => (
+                                    <label key={name}>
+                                        <input
+                                            type="checkbox"
+                                            checked={w.days.includes(i + 1)}
+                                            onChange={() => toggleDay(index, i + 1)}
+                                        />
+                                        {name}
+                                    </label>
+                                ))}
+                                <input
+                                    type="text"
+                                    value={w.start}
+                                    onChange={(e) => updateWindow(index, { start: e.target.value })}
+                                    title="HH:MM"
+                                />
+                                –
+                                <input
+                                    type="text"
+                                    value={w.end}
+                                    onChange={(e) => updateWindow(index, { end: e.target.value })}
+                                    title="HH:MM; 24:00 — до конца суток; раньше начала — через полночь"
+                                />
+                                <button
+                                    type="button"
+                                    className="device-action-button"
+                                    onClick={() => setWindows((prev) => prev.filter((_, i) => i !== index))}
+                                >
+                                    Удалить
+                                </button>
+                            </div>
+                        ))}
+                        <button
+                            type="button"
+                            className="device-action-button"
+                            onClick={() => setWindows((prev) => [...prev, { ...DEFAULT_WINDOW }])}
+                        >
+                            Добавить окно
+                        </button>
+                    </div>
+
+                    <div className="form-group">
+                        <label htmlFor="trackingHolidays">Праздники без отслеживания (YYYY-MM-DD, по одной в строке)</label>
+                        <textarea
+                            id="trackingHolidays"
+                            rows={3}
+                            value={holidays}
+                            onChange={(e) => setHolidays(e.target.value)}
+                        />
+                    </div>
+                    <div className="form-group">
+                        <label htmlFor="trackingOutsideAction">Точки вне окна</label>
+                        <select
+                            id="trackingOutsideAction"
+                            value={outsideAction}
+                            onChange={(e) => setOutsideAction(e.target.value as 'drop' | 'mask')}
+                        >
+                            <option value="drop">не сохранять</option>
+                            <option value="mask">сохранять с точностью ~1 км</option>
+                        </select>
+                    </div>
+                    <label className="form-check device-control-check">
+                        <input
+                            type="checkbox"
+                            checked={consentRequired}
+                            onChange={(e) => setConsentRequired(e.target.checked)}
+                        />{' '}
+                        Отслеживать только с согласия
+                    </label>
+                    <button type="submit" className="button">
+                        Сохранить график
+                    </button>{' '}
+                    {schedule && (
+                        <button type="button" className="device-action-button" onClick={handleDelete}>
+                            Снять ограничения
+                        </button>
+                    )}
+                </form>
+
+                <h4>Согласие</h4>
+                {consentActive ? (
+                    <p>
+                        Дано {formatDateTime(schedule?.consent_given_at)} ({schedule?.consent_by}){' '}
+                        <button className="device-action-button" onClick={() => handleConsent(false)}>
+                            Отозвать
+                        </button>
+                    </p>
+                ) : (
+                    <div className="form-group">
+                        {schedule?.consent_revoked_at && (
+                            <p>Отозвано {formatDateTime(schedule.consent_revoked_at)}</p>
+                        )}
+                        <label htmlFor="trackingConsentBy">Кто дал согласие</label>
+                        <input
+                            id="trackingConsentBy"
+                            type="text"
+                            maxLength={100}
+                            value={consentBy}
+                            onChange={(e) => setConsentBy(e.target.value)}
+                            placeholder="ФИО"
+                        />
+                        <button
+                            className="device-action-button"
+                            onClick={() => handleConsent(true)}
+                            disabled={!consentBy.trim()}
+                        >
+                            Согласие получено
+                        </button>
+                    </div>
+                )}
+            </div>
+        </div>
+    );
+};
+
+export default TrackingSchedulePanel;
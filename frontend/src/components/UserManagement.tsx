@@ -1,7 +1,7 @@
 // components/UserManagement.tsx
 import React, { useCallback, useEffect, useState } from 'react';
 import { useAuth } from '../context/AuthContext';
-import { deviceApi, locationApi, releaseApi, userApi } from '../services/api';
+import { authApi, deviceApi, locationApi, releaseApi, userApi } from '../services/api';
 import type { User } from '../types/models';
 import { formatDateTime } from '../utils/dateFormat';
 import {
@@ -14,6 +14,8 @@ import {
 } from '../utils/userDeviceStatus';
 import QRCodeDisplay from './QRCodeDisplay';
 import DeviceControlPanel from './DeviceControlPanel';
+import ApiKeysPanel from './ApiKeysPanel';
+import TrackingSchedulePanel from './TrackingSchedulePanel';
 
 const STATUS_POLL_MS = 45_000;
 
@@ -66,7 +68,7 @@ function reportSummary(report: Record<string, unknown>): string[] {
 }
 
 const UserManagement: React.FC = () => {
-    const { apiKey, user: currentUser, refreshUser, adoptApiKey } = useAuth();
+    const { apiKey, user: currentUser, refreshUser } = useAuth();
     const [users, setUsers] = useState<User[]>([]);
     const [loading, setLoading] = useState(true);
     const [error, setError] = useState<string | null>(null);
@@ -97,6 +99,8 @@ const UserManagement: React.FC = () => {
     const [actionNotice, setActionNotice] = useState<Record<number, string>>({});
     const [expandedReportUserId, setExpandedReportUserId] = useState<number | null>(null);
     const [devicePanelUser, setDevicePanelUser] = useState<User | null>(null);
+    const [apiKeysUser, setApiKeysUser] = useState<User | null>(null);
+    const [scheduleUser, setScheduleUser] = useState<User | null>(null);
 
     const setNotice = (userId: number, text: string, clearMs = 8000) => {
         setActionNotice((prev) => ({ ...prev, [userId]: text }));
@@ -252,7 +256,8 @@ const UserManagement: React.FC = () => {
 
         const confirmed = window.confirm(
             `Перегенерировать QR для «${user.name}»?\n\n` +
-                'Будет создан новый API-ключ. Старый перестанет работать.\n' +
+                'Будет создан новый API-ключ. Старый действует, пока телефон не перейдёт на новый ' +
+                '(или до истечения периода передачи).\n' +
                 'На телефоне нужно отсканировать новый QR (или дождаться config_update, если устройство онлайн).'
         );
         if (!confirmed) return;
@@ -272,10 +277,6 @@ const UserManagement: React.FC = () => {
                 apiKey: result.api_key,
                 pushedToDevice: Boolean(result.config_command_id),
             });
-            // Regenerating own key would orphan sessionStorage unless we adopt the new key.
-            if (currentUser?.id === user.id) {
-                await adoptApiKey(result.api_key);
-            }
             setNotice(
                 user.id,
                 result.config_command_id
@@ -292,6 +293,88 @@ const UserManagement: React.FC = () => {
         }
     };
 
+    // Часовой пояс пользователя: в нём толкуется локальное время отчётов и окон отслеживания
+    const handleSetTimezone = async (user: User) => {
+        if (!apiKey) return;
+        const timezone = window.prompt(
+            `Часовой пояс «${user.name}» (IANA, например Europe/Warsaw). Пусто — пояс организации:`,
+            user.timezone ?? ''
+        );
+        if (timezone === null) return;
+
+        setActionUserId(user.id);
+        try {
+            const updated = await userApi.setTimezone(user.id, timezone.trim(), apiKey);
+            setUsers((prev) => prev.map((u) => (u.id === user.id ? { ...u, timezone: updated.timezone } : u)));
+            setNotice(user.id, updated.timezone ? `Часовой пояс: ${updated.timezone}` : 'Часовой пояс организации');
+        } catch (err) {
+            setNotice(user.id, err instanceof Error ? err.message : 'Не удалось сохранить часовой пояс');
+        } finally {
+            setActionUserId(null);
+        }
+    };
+
+    // Логин/пароль для входа в панель (только для сотрудников, не для устройств)
+    const handleSetCredentials = async (user: User) => {
+        if (!apiKey) return;
+        const username = window.prompt(`Логин для «${user.name}»:`, user.username ?? '');
+        if (username === null) return;
+        const password = window.prompt(
+            'Новый пароль (не короче 8 символов). Оставьте пустым, чтобы не менять.\n' +
+                'При смене пароля все сессии пользователя будут завершены.'
+        );
+        if (password === null) return;
+
+        setActionUserId(user.id);
+        try {
+            const updated = await authApi.setCredentials(
+                user.id,
+                { username: username.trim(), ...(password ? { password } : {}) },
+                apiKey
+            );
+            setUsers((prev) =>
+                prev.map((u) => (u.id === user.id ? { ...u, username: updated.username } : u))
+            );
+            setNotice(user.id, 'Учётные данные сохранены');
+        } catch (err) {
+            setNotice(user.id, err instanceof Error ? err.message : 'Не удалось сохранить учётные данные');
+        } finally {
+            setActionUserId(null);
+        }
+    };
+
+    const handleCreateLoginLink = async (user: User) => {
+        if (!apiKey) return;
+        setActionUserId(user.id);
+        try {
+            const link = await authApi.createLoginLink(user.id, apiKey);
+            const url = `${window.location.origin}${link.path}`;
+            window.prompt(
+                `Одноразовая ссылка входа до ${formatDateTime(link.expires_at)}. Скопируйте и передайте сотруднику:`,
+                url
+            );
+            setNotice(user.id, 'Ссылка входа создана');
+        } catch (err) {
+            setNotice(user.id, err instanceof Error ? err.message : 'Не удалось создать ссылку для входа');
+        } finally {
+            setActionUserId(null);
+        }
+    };
+
+    const handleRevokeSessions = async (user: User) => {
+        if (!apiKey) return;
+        if (!window.confirm(`Завершить все сессии «${user.name}»?`)) return;
+        setActionUserId(user.id);
+        try {
+            await authApi.revokeUserSessions(user.id, apiKey);
+            setNotice(user.id, 'Все сессии завершены');
+        } catch (err) {
+            setNotice(user.id, err instanceof Error ? err.message : 'Не удалось завершить сессии');
+        } finally {
+            setActionUserId(null);
+        }
+    };
+
     const handleShowRegeneratedQR = () => {
         if (!regenerateResult) return;
         const user = users.find((u) => u.id === regenerateResult.userId);
@@ -511,6 +594,14 @@ const UserManagement: React.FC = () => {
                 />
             )}
 
+            {apiKeysUser && apiKey && (
+                <ApiKeysPanel user={apiKeysUser} apiKey={apiKey} onClose={() => setApiKeysUser(null)} />
+            )}
+
+            {scheduleUser && apiKey && (
+                <TrackingSchedulePanel user={scheduleUser} apiKey={apiKey} onClose={() => setScheduleUser(null)} />
+            )}
+
             {regenerateResult && (
                 <div className="qr-code-modal">
                     <div className="qr-code-container">
@@ -781,10 +872,58 @@ const UserManagement: React.FC = () => {
                                                 className="device-action-button"
                                                 onClick={() => handleRegenerateQR(user)}
                                                 disabled={busy}
-                                                title="Новый API-ключ и QR (старый перестанет работать)"
+                                                title="Новый API-ключ и QR (старый действует до перехода телефона на новый)"
                                             >
                                                 Перегенерировать QR
                                             </button>
+                                            <button
+                                                className="device-action-button"
+                                                onClick={() => setApiKeysUser(user)}
+                                                disabled={busy}
+                                                title="Ключи пользователя: использование, срок действия, отзыв"
+                                            >
+                                                Ключи
+                                            </button>
+                                            <button
+                                                className="device-action-button"
+                                                onClick={() => setScheduleUser(user)}
+                                                disabled={busy}
+                                                title="Окна отслеживания, праздники и согласие пользователя"
+                                            >
+                                                Окна
+                                            </button>
+                                            <button
+                                                className="device-action-button"
+                                                onClick={() => handleSetTimezone(user)}
+                                                disabled={busy}
+                                                title={`Часовой пояс: ${user.timezone || 'как у организации'}`}
+                                            >
+                                                Пояс
+                                            </button>
+                                            <button
+                                                className="device-action-button"
+                                                onClick={() => handleSetCredentials(user)}
+                                                disabled={busy}
+                                                title="Логин и пароль для входа в панель"
+                                            >
+                                                Вход
+                                            </button>
+                                            <button
+                                                className="device-action-button"
+                                                onClick={() => handleCreateLoginLink(user)}
+                                                disabled={busy}
+                                                title="Одноразовая ссылка для первого входа"
+                                            >
+                                                Ссылка входа
+                                            </button>
+                                            <button
+                                                className="device-action-button"
+                                                onClick={() => handleRevokeSessions(user)}
+                                                disabled={busy}
+                                                title="Завершить все сессии пользователя"
+                                            >
+                                                Выйти везде
+                                            </button>
                                             <button
                                                 className="device-action-button device-action-button--location"
                                                 onClick={() => handleEnableLocation(user.id)}
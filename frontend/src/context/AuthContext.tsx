@@ -1,15 +1,15 @@
 // context/AuthContext.tsx
-import React, { createContext, useCallback, useContext, useEffect, useMemo, useState } from 'react';
+import React, { createContext, useCallback, useContext, useEffect, useMemo, useRef, useState } from 'react';
 import type { AuthState } from '../types/models';
-import { userApi } from '../services/api';
+import { ApiError, authApi, userApi, type SessionTokens } from '../services/api';
 
 interface AuthContextType extends AuthState {
-    login: (apiKey: string) => Promise<void>;
+    login: (username: string, password: string, totpCode?: string) => Promise<void>;
+    /** One-time login link issued by an admin (/login?login_token=...). */
+    loginWithLink: (token: string, totpCode?: string) => Promise<void>;
     logout: () => void;
     /** Silent profile refresh — does not toggle global `loading` (avoids remounting pages). */
     refreshUser: () => Promise<void>;
-    /** Replace session key after QR regenerate (self) without full remount flash. */
-    adoptApiKey: (apiKey: string) => Promise<void>;
 }
 
 const AuthContext = createContext<AuthContextType | undefined>(undefined);
@@ -22,6 +22,30 @@ export const useAuth = () => {
     return context;
 };
 
+// Refresh token survives reloads of this tab; the short-lived access token lives in memory.
+const REFRESH_TOKEN_KEY = 'refreshToken';
+// Renew the access token this long before it expires.
+const REFRESH_MARGIN_MS = 60_000;
+// Retry delay when the server rate-limits a refresh (429 leaves the refresh token unused).
+const RATE_LIMITED_RETRY_MS = 15_000;
+
+const isRateLimited = (error: unknown) => error instanceof ApiError && error.status === 429;
+
+// One in-flight refresh per token: StrictMode re-runs effects, and presenting an
+// already-rotated refresh token makes the server revoke the whole session.
+let pendingRefresh: { token: string; promise: Promise<SessionTokens> } | null = null;
+const refreshOnce = (refreshToken: string) => {
+    if (pendingRefresh?.token !== refreshToken) {
+        const promise = authApi.refresh(refreshToken).catch((error) => {
+            // A rejected refresh may be retried with the same token (e.g. after 429).
+            if (pendingRefresh?.token === refreshToken) pendingRefresh = null;
+            throw error;
+        });
+        pendingRefresh = { token: refreshToken, promise };
+    }
+    return pendingRefresh.promise;
+};
+
 const expiredSession = (error: string): AuthState => ({
     isAuthenticated: false,
     user: null,
@@ -38,10 +62,19 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
         loading: true,
         error: null,
     });
+    const refreshTimer = useRef<ReturnType<typeof setTimeout> | null>(null);
 
-    const logout = useCallback(() => {
-        sessionStorage.removeItem('apiKey');
-        setState({
+    const clearRefreshTimer = () => {
+        if (refreshTimer.current) {
+            clearTimeout(refreshTimer.current);
+            refreshTimer.current = null;
+        }
+    };
+
+    const dropSession = useCallback((error: string | null) => {
+        clearRefreshTimer();
+        sessionStorage.removeItem(REFRESH_TOKEN_KEY);
+        setState(error ? expiredSession(error) : {
             isAuthenticated: false,
             user: null,
             apiKey: null,
@@ -50,6 +83,56 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
         });
     }, []);
 
+    // Stores the new token pair, loads the profile and schedules the next refresh.
+    const applyTokens = useCallback(async (tokens: SessionTokens) => {
+        sessionStorage.setItem(REFRESH_TOKEN_KEY, tokens.refresh_token);
+
+        const user = await userApi.getCurrentUser(tokens.access_token);
+        if (!user.is_admin) {
+            dropSession('Доступ запрещен: требуются права администратора');
+            throw new Error('Доступ запрещен: требуются права администратора');
+        }
+
+        setState({
+            isAuthenticated: true,
+            user,
+            apiKey: tokens.access_token,
+            loading: false,
+            error: null,
+        });
+
+        clearRefreshTimer();
+        const refreshLater = (delay: number) => {
+            refreshTimer.current = setTimeout(() => {
+                const refreshToken = sessionStorage.getItem(REFRESH_TOKEN_KEY);
+                if (!refreshToken) return;
+                refreshOnce(refreshToken)
+                    .then(applyTokensRef.current)
+                    .catch((error) => {
+                        if (isRateLimited(error)) {
+                            refreshLater(RATE_LIMITED_RETRY_MS);
+                            return;
+                        }
+                        dropSession('Сессия истекла. Пожалуйста, войдите снова.');
+                    });
+            }, delay);
+        };
+        refreshLater(Math.max(tokens.expires_in * 1000 - REFRESH_MARGIN_MS, 10_000));
+    }, [dropSession]);
+
+    // The timer callback needs the latest applyTokens without re-creating the timer chain.
+    const applyTokensRef = useRef(applyTokens);
+    useEffect(() => {
+        applyTokensRef.current = applyTokens;
+    }, [applyTokens]);
+
+    const logout = useCallback(() => {
+        if (state.apiKey) {
+            authApi.logout(state.apiKey).catch(() => undefined);
+        }
+        dropSession(null);
+    }, [state.apiKey, dropSession]);
+
     const refreshUser = useCallback(async () => {
         if (!state.apiKey) return;
 
@@ -58,10 +141,7 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
             const user = await userApi.getCurrentUser(state.apiKey);
 
             if (!user.is_admin) {
-                sessionStorage.removeItem('apiKey');
-                setState(
-                    expiredSession('Доступ запрещен: требуются права администратора'),
-                );
+                dropSession('Доступ запрещен: требуются права администратора');
                 return;
             }
 
@@ -72,59 +152,14 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
             }));
         } catch (error) {
             console.error('Ошибка обновления данных пользователя:', error);
-            sessionStorage.removeItem('apiKey');
-            setState(expiredSession('Сессия истекла. Пожалуйста, войдите снова.'));
-        }
-    }, [state.apiKey]);
-
-    const adoptApiKey = useCallback(async (newApiKey: string) => {
-        sessionStorage.setItem('apiKey', newApiKey);
-        try {
-            const user = await userApi.getCurrentUser(newApiKey);
-            if (!user.is_admin) {
-                sessionStorage.removeItem('apiKey');
-                setState(
-                    expiredSession('Доступ запрещен: требуются права администратора'),
-                );
-                return;
-            }
-            setState({
-                isAuthenticated: true,
-                user,
-                apiKey: newApiKey,
-                loading: false,
-                error: null,
-            });
-        } catch (error) {
-            console.error('Ошибка принятия нового API-ключа:', error);
-            sessionStorage.removeItem('apiKey');
-            setState(expiredSession('Сессия истекла. Пожалуйста, войдите снова.'));
-            throw error;
+            dropSession('Сессия истекла. Пожалуйста, войдите снова.');
         }
-    }, []);
+    }, [state.apiKey, dropSession]);
 
-    const login = useCallback(async (apiKey: string) => {
+    const startSession = useCallback(async (request: Promise<SessionTokens>) => {
         try {
             setState((prev) => ({ ...prev, loading: true, error: null }));
-
-            const user = await userApi.authenticate(apiKey);
-
-            if (!user.is_admin) {
-                setState(
-                    expiredSession('Доступ запрещен: требуются права администратора'),
-                );
-                throw new Error('Доступ запрещен: требуются права администратора');
-            }
-
-            sessionStorage.setItem('apiKey', apiKey);
-
-            setState({
-                isAuthenticated: true,
-                user,
-                apiKey,
-                loading: false,
-                error: null,
-            });
+            await applyTokens(await request);
         } catch (error) {
             setState(
                 expiredSession(
@@ -133,23 +168,41 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
             );
             throw error;
         }
-    }, []);
+    }, [applyTokens]);
+
+    const login = useCallback(
+        (username: string, password: string, totpCode?: string) =>
+            startSession(authApi.login(username, password, totpCode)),
+        [startSession],
+    );
+
+    const loginWithLink = useCallback(
+        (token: string, totpCode?: string) => startSession(authApi.loginWithLink(token, totpCode)),
+        [startSession],
+    );
 
     useEffect(() => {
-        const savedApiKey = sessionStorage.getItem('apiKey');
-        if (savedApiKey) {
-            login(savedApiKey).catch(() => {
-                sessionStorage.removeItem('apiKey');
-                setState(expiredSession('Сессия истекла. Пожалуйста, войдите снова.'));
-            });
+        const savedRefreshToken = sessionStorage.getItem(REFRESH_TOKEN_KEY);
+        if (savedRefreshToken) {
+            refreshOnce(savedRefreshToken)
+                .then(applyTokens)
+                .catch((error) => {
+                    if (isRateLimited(error)) {
+                        // Keep the refresh token: reloading the page later restores the session.
+                        setState(expiredSession(error.message));
+                        return;
+                    }
+                    dropSession('Сессия истекла. Пожалуйста, войдите снова.');
+                });
         } else {
             setState((prev) => ({ ...prev, loading: false }));
         }
-    }, [login]);
+        return clearRefreshTimer;
+    }, [applyTokens, dropSession]);
 
     const contextValue = useMemo(
-        () => ({ ...state, login, logout, refreshUser, adoptApiKey }),
-        [state, login, logout, refreshUser, adoptApiKey],
+        () => ({ ...state, login, loginWithLink, logout, refreshUser }),
+        [state, login, loginWithLink, logout, refreshUser],
     );
 
     return (
@@ -0,0 +1,204 @@
+import React, { useCallback, useEffect, useState } from 'react';
+import { authApi, type SessionInfo } from '../services/api';
+import { useAuth } from '../context/AuthContext';
+import { formatDateTime } from '../utils/dateFormat';
+
+// Безопасность учётной записи: пароль, двухфакторная аутентификация, активные сессии
+const Account: React.FC = () => {
+    const { apiKey, user, refreshUser } = useAuth();
+    const [sessions, setSessions] = useState<SessionInfo[]>([]);
+    const [message, setMessage] = useState<string | null>(null);
+    const [error, setError] = useState<string | null>(null);
+
+    const [currentPassword, setCurrentPassword] = useState('');
+    const [newPassword, setNewPassword] = useState('');
+
+    const [totpSetup, setTotpSetup] = useState<{ secret: string; otpauth_uri: string } | null>(null);
+    const [totpCode, setTotpCode] = useState('');
+
+    const report = (ok: string | null, err?: unknown) => {
+        setMessage(ok);
+        setError(err ? (err instanceof Error ? err.message : 'Ошибка запроса') : null);
+    };
+
+    const fetchSessions = useCallback(async () => {
+        if (!apiKey) return;
+        try {
+            setSessions(await authApi.getSessions(apiKey));
+        } catch (err) {
+            report(null, err);
+        }
+    }, [apiKey]);
+
+    useEffect(() => {
+        fetchSessions();
+    }, [fetchSessions]);
+
+    const handleChangePassword = async (e: React.FormEvent) => {
+        e.preventDefault();
+        if (!apiKey) return;
+        try {
+            await authApi.changePassword(currentPassword, newPassword, apiKey);
+            setCurrentPassword('');
+            setNewPassword('');
+            report('Пароль изменён, остальные сессии завершены');
+            fetchSessions();
+        } catch (err) {
+            report(null, err);
+        }
+    };
+
+    const handleSetupTOTP = async () => {
+        if (!apiKey) return;
+        try {
+            setTotpSetup(await authApi.setupTOTP(apiKey));
+            setTotpCode('');
+            report(null);
+        } catch (err) {
+            report(null, err);
+        }
+    };
+
+    const handleTOTP = async (e: React.FormEvent) => {
+        e.preventDefault();
+        if (!apiKey) return;
+        try {
+            if (user?.totp_enabled) {
+                await authApi.disableTOTP(totpCode.trim(), apiKey);
+                report('Двухфакторная аутентификация отключена');
+            } else {
+                await authApi.enableTOTP(totpCode.trim(), apiKey);
+                setTotpSetup(null);
+                report('Двухфакторная аутентификация включена');
+            }
+            setTotpCode('');
+            await refreshUser();
+        } catch (err) {
+            report(null, err);
+        }
+    };
+
+    const handleRevoke = async (id: string) => {
+        if (!apiKey) return;
+        try {
+            await authApi.revokeSession(id, apiKey);
+            fetchSessions();
+        } catch (err) {
+            report(null, err);
+        }
+    };
+
+    return (
+        <div className="account-page">
+            <h1>Учётная запись</h1>
+            <p>Логин: <strong>{user?.username ?? '—'}</strong></p>
+
+            {message && <div className="success-message">{message}</div>}
+            {error && <div className="error-message">{error}</div>}
+
+            <section>
+                <h2>Смена пароля</h2>
+                <form onSubmit={handleChangePassword}>
+                    <div className="form-group">
+                        <label htmlFor="currentPassword">Текущий пароль</label>
+                        <input
+                            type="password"
+                            id="currentPassword"
+                            autoComplete="current-password"
+                            value={currentPassword}
+                            onChange={(e) => setCurrentPassword(e.target.value)}
+                        />
+                    </div>
+                    <div className="form-group">
+                        <label htmlFor="newPassword">Новый пароль</label>
+                        <input
+                            type="password"
+                            id="newPassword"
+                            autoComplete="new-password"
+                            value={newPassword}
+                            onChange={(e) => setNewPassword(e.target.value)}
+                        />
+                    </div>
+                    <button type="submit" className="button" disabled={!currentPassword || newPassword.length < 8}>
+                        Сменить пароль
+                    </button>
+                </form>
+            </section>
+
+            <section>
+                <h2>Двухфакторная аутентификация</h2>
+                <p>{user?.totp_enabled ? 'Включена' : 'Выключена'}</p>
+
+                {!user?.totp_enabled && !totpSetup && (
+                    <button type="button" className="button" onClick={handleSetupTOTP}>
+                        Настроить
+                    </button>
+                )}
+
+                {totpSetup && (
+                    <p>
+                        Добавьте ключ в приложение-аутентификатор: <code>{totpSetup.secret}</code>
+                        <br />
+                        <a href={totpSetup.otpauth_uri}>Открыть в приложении</a>
+                    </p>
+                )}
+
+                {(user?.totp_enabled || totpSetup) && (
+                    <form onSubmit={handleTOTP}>
+                        <div className="form-group">
+                            <label htmlFor="accountTotpCode">Код из приложения</label>
+                            <input
+                                type="text"
+                                id="accountTotpCode"
+                                inputMode="numeric"
+                                autoComplete="one-time-code"
+                                maxLength={6}
+                                value={totpCode}
+                                onChange={(e) => setTotpCode(e.target.value)}
+                            />
+                        </div>
+                        <button type="submit" className="button" disabled={totpCode.trim().length !== 6}>
+                            {user?.totp_enabled ? 'Отключить' : 'Включить'}
+                        </button>
+                    </form>
+                )}
+            </section>
+
+            <section>
+                <h2>Активные сессии</h2>
+                <table className="sessions-table">
+                    <thead>
+                        <tr>
+                            <th>Устройство</th>
+                            <th>IP</th>
+                            <th>Вход</th>
+                            <th>Активность</th>
+                            <th></th>
+                        </tr>
+                    </thead>
+                    <tbody>
+                        {sessions.map((s) => (
+                            <tr key={s.id}>
+                                <td>{s.user_agent || '—'}</td>
+                                <td>{s.ip || '—'}</td>
+                                <td>{formatDateTime(s.created_at)}</td>
+                                <td>{formatDateTime(s.last_used_at)}</td>
+                                <td>
+                                    {s.current ? (
+                                        'Текущая'
+                                    ) : (
+                                        <button type="button" className="button" onClick={() => handleRevoke(s.id)}>
+                                            Завершить
+                                        </button>
+                                    )}
+                                </td>
+                            </tr>
+                        ))}
+                    </tbody>
+                </table>
+            </section>
+        </div>
+    );
+};
+
+export default Account;
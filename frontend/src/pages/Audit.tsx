@@ -0,0 +1,219 @@
+import React, { useCallback, useEffect, useState } from 'react';
+import type { AuditEntry, AuditFilters } from '../types/models';
+import { auditApi } from '../services/api';
+import { useAuth } from '../context/AuthContext';
+import { formatDateTime } from '../utils/dateFormat';
+import { compareMinskDateTimes, minskDayBounds, minskLocalToMs } from '../utils/locationTrack';
+
+const PAGE_SIZE = 100;
+
+const resultLabels: Record<AuditEntry['result'], string> = {
+    success: 'Успех',
+    denied: 'Отказ',
+    error: 'Ошибка',
+};
+
+const emptyFilters = () => {
+    const week = minskDayBounds(-7);
+    const today = minskDayBounds(0);
+    return {
+        actor_id: '',
+        action: '',
+        target_type: '',
+        target_id: '',
+        result: '',
+        from: week.from,
+        to: today.to,
+    };
+};
+
+// Период в фильтре задаётся по Минску, сервер ждёт RFC3339
+const toRequestFilters = (filters: ReturnType<typeof emptyFilters>): AuditFilters => ({
+    actor_id: filters.actor_id.trim(),
+    action: filters.action.trim(),
+    target_type: filters.target_type.trim(),
+    target_id: filters.target_id.trim(),
+    result: filters.result,
+    from: filters.from ? new Date(minskLocalToMs(filters.from)).toISOString() : undefined,
+    to: filters.to ? new Date(minskLocalToMs(filters.to)).toISOString() : undefined,
+});
+
+const downloadBlob = (blob: Blob, filename: string) => {
+    const url = URL.createObjectURL(blob);
+    const link = document.createElement('a');
+    link.href = url;
+    link.download = filename;
+    link.click();
+    URL.revokeObjectURL(url);
+};
+
+const Audit: React.FC = () => {
+    const { apiKey } = useAuth();
+    const [filters, setFilters] = useState(emptyFilters);
+    const [applied, setApplied] = useState<AuditFilters>(() => toRequestFilters(emptyFilters()));
+    const [entries, setEntries] = useState<AuditEntry[]>([]);
+    const [total, setTotal] = useState(0);
+    const [offset, setOffset] = useState(0);
+    const [loading, setLoading] = useState(true);
+    const [error, setError] = useState<string | null>(null);
+    const [expanded, setExpanded] = useState<number | null>(null);
+
+    const fetchEntries = useCallback(async () => {
+        if (!apiKey) return;
+        try {
+            setLoading(true);
+            setError(null);
+            const data = await auditApi.list(applied, PAGE_SIZE, offset, apiKey);
+            setEntries(data.entries);
+            setTotal(data.total);
+        } catch (err) {
+            console.error('Ошибка загрузки журнала аудита:', err);
+            setError(err instanceof Error ? err.message : 'Ошибка загрузки журнала аудита');
+        } finally {
+            setLoading(false);
+        }
+    }, [apiKey, applied, offset]);
+
+    useEffect(() => {
+        fetchEntries();
+    }, [fetchEntries]);
+
+    const handleFilterChange = (e: React.ChangeEvent<HTMLInputElement | HTMLSelectElement>) => {
+        const { name, value } = e.target;
+        setFilters(prev => ({ ...prev, [name]: value }));
+    };
+
+    const handleApply = () => {
+        if (filters.from && filters.to && compareMinskDateTimes(filters.from, filters.to) >= 0) {
+            setError('Дата начала должна быть раньше даты окончания');
+            return;
+        }
+        setOffset(0);
+        setApplied(toRequestFilters(filters));
+    };
+
+    const handleReset = () => {
+        const next = emptyFilters();
+        setFilters(next);
+        setOffset(0);
+        setApplied(toRequestFilters(next));
+    };
+
+    const handleExport = async (format: 'csv' | 'jsonl') => {
+        if (!apiKey) return;
+        try {
+            setError(null);
+            const blob = await auditApi.exportFile(applied, format, apiKey);
+            downloadBlob(blob, `audit.${format}`);
+        } catch (err) {
+            console.error('Ошибка выгрузки журнала аудита:', err);
+            setError(err instanceof Error ? err.message : 'Ошибка выгрузки журнала аудита');
+        }
+    };
+
+    return (
+        <div className="visits-page">
+            <h1>Журнал аудита</h1>
+
+            {error && <div className="error-message">{error}</div>}
+
+            <div className="filters">
+                <h3>Фильтры</h3>
+                <div className="filter-group">
+                    <label htmlFor="actor_id">ID сотрудника:</label>
+                    <input id="actor_id" name="actor_id" value={filters.actor_id} onChange={handleFilterChange} placeholder="ID сотрудника" />
+                </div>
+                <div className="filter-group">
+                    <label htmlFor="action">Действие:</label>
+                    <input id="action" name="action" value={filters.action} onChange={handleFilterChange} placeholder="PUT /api/admin/users" />
+                </div>
+                <div className="filter-group">
+                    <label htmlFor="target_type">Тип объекта:</label>
+                    <input id="target_type" name="target_type" value={filters.target_type} onChange={handleFilterChange} placeholder="user, device, checkpoint" />
+                </div>
+                <div className="filter-group">
+                    <label htmlFor="target_id">ID объекта:</label>
+                    <input id="target_id" name="target_id" value={filters.target_id} onChange={handleFilterChange} placeholder="ID объекта" />
+                </div>
+                <div className="filter-group">
+                    <label htmlFor="result">Результат:</label>
+                    <select id="result" name="result" value={filters.result} onChange={handleFilterChange}>
+                        <option value="">Все</option>
+                        <option value="success">Успех</option>
+                        <option value="denied">Отказ</option>
+                        <option value="error">Ошибка</option>
+                    </select>
+                </div>
+                <div className="filter-group">
+                    <label htmlFor="from">С (Europe/Minsk)</label>
+                    <input type="datetime-local" id="from" name="from" value={filters.from} onChange={handleFilterChange} max={filters.to || undefined} />
+                </div>
+                <div className="filter-group">
+                    <label htmlFor="to">По (Europe/Minsk)</label>
+                    <input type="datetime-local" id="to" name="to" value={filters.to} onChange={handleFilterChange} min={filters.from || undefined} />
+                </div>
+
+                <div className="filter-buttons">
+                    <button onClick={handleApply} className="filter-button">Применить фильтры</button>
+                    <button onClick={handleReset} className="reset-button">Сбросить фильтры</button>
+                    <button onClick={() => handleExport('csv')}>Выгрузить CSV</button>
+                    <button onClick={() => handleExport('jsonl')}>Выгрузить JSONL</button>
+                </div>
+            </div>
+
+            <div className="visits-list">
+                <h2>Записи ({total})</h2>
+                {loading ? (
+                    <p>Загрузка журнала...</p>
+                ) : entries.length > 0 ? (
+                    <table className="users-table">
+                        <thead>
+                        <tr>
+                            <th>Время</th>
+                            <th>Сотрудник</th>
+                            <th>Действие</th>
+                            <th>Объект</th>
+                            <th>Результат</th>
+                            <th>IP</th>
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {entries.map(entry => (
+                            <React.Fragment key={entry.id}>
+                                <tr onClick={() => setExpanded(expanded === entry.id ? null : entry.id)}>
+                                    <td>{formatDateTime(entry.at)}</td>
+                                    <td>{entry.actor_name || '—'}{entry.actor_id ? ` (#${entry.actor_id}, ${entry.actor_role})` : ''}</td>
+                                    <td>{entry.action}</td>
+                                    <td>{entry.target_type ? `${entry.target_type} ${entry.target_id ?? ''}` : '—'}</td>
+                                    <td title={entry.error || undefined}>{resultLabels[entry.result] ?? entry.result} ({entry.status})</td>
+                                    <td>{entry.ip}</td>
+                                </tr>
+                                {expanded === entry.id && (
+                                    <tr>
+                                        <td colSpan={6}>
+                                            {entry.error && <p className="error-message">{entry.error}</p>}
+                                            <pre>{JSON.stringify(entry.params ?? {}, null, 2)}</pre>
+                                        </td>
+                                    </tr>
+                                )}
+                            </React.Fragment>
+                        ))}
+                        </tbody>
+                    </table>
+                ) : (
+                    <p>Записей не найдено</p>
+                )}
+
+                {total > PAGE_SIZE && (
+                    <div className="filter-buttons">
+                        <button disabled={offset === 0} onClick={() => setOffset(Math.max(0, offset - PAGE_SIZE))}>Назад</button>
+                        <span>{offset + 1}–{Math.min(offset + PAGE_SIZE, total)} из {total}</span>
+                        <button disabled={offset + PAGE_SIZE >= total} onClick={() => setOffset(offset + PAGE_SIZE)}>Вперёд</button>
+                    </div>
+                )}
+            </div>
+        </div>
+    );
+};
+
+export default Audit;
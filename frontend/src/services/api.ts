@@ -1,5 +1,18 @@
 import axios from 'axios';
-import type {Checkpoint, Location, LocationEvent, User, Visit} from '../types/models';
+import type {
+    ApiKeyInfo,
+    AuditEntry,
+    AuditFilters,
+    Checkpoint,
+    Location,
+    LocationEvent,
+    TrackingSchedule,
+    TrackingScheduleInput,
+    TrackingScheduleStatus,
+    User,
+    Visit,
+} from '../types/models';
+import { DISPLAY_TIMEZONE } from '../utils/dateFormat';
 
 const api = axios.create({
     baseURL: '/api',
@@ -8,14 +21,16 @@ const api = axios.create({
     }
 });
 
-// Функция для добавления API ключа в конфигурацию запроса
-const withApiKey = (apiKey?: string) => {
-    if (!apiKey) return {};
+// Заголовок авторизации: access-токен сессии сотрудника.
+// API-ключи устройств административные маршруты не принимают.
+export const authHeader = (token: string) => ({ Authorization: `Bearer ${token}` });
+
+// Функция для добавления токена сессии в конфигурацию запроса
+const withAuth = (token?: string) => {
+    if (!token) return {};
 
     return {
-        headers: {
-            'X-API-Key': apiKey
-        }
+        headers: authHeader(token)
     };
 };
 
@@ -28,23 +43,24 @@ export type LocationFetchOpts = {
 export const locationApi = {
     getAll: (apiKey?: string, opts?: LocationFetchOpts) =>
         api.get<Location[]>('/location/', {
-            ...withApiKey(apiKey),
+            ...withAuth(apiKey),
             params: opts?.raw ? { raw: 'true' } : undefined
         }),
 
     getBetween: (from: string, to: string, apiKey?: string, opts?: LocationFetchOpts) =>
         api.get<Location[]>('/location/', {
-            ...withApiKey(apiKey),
+            ...withAuth(apiKey),
             params: {
                 from,
                 to,
+                tz: DISPLAY_TIMEZONE,
                 ...(opts?.raw ? { raw: 'true' } : {})
             }
         }),
 
     getByUserId: (userId: number, apiKey?: string, maxAgeSeconds?: number) =>
         api.get<Location>(`/location/single`, {
-            ...withApiKey(apiKey),
+            ...withAuth(apiKey),
             params: {
                 user_id: userId,
                 ...(maxAgeSeconds != null ? { max_age_seconds: maxAgeSeconds } : {})
@@ -54,7 +70,7 @@ export const locationApi = {
     /** Последняя позиция текущего пользователя (по API-ключу) → GET /location/single */
     getCurrent: (apiKey?: string, maxAgeSeconds?: number) =>
         api.get<Location>(`/location/single`, {
-            ...withApiKey(apiKey),
+            ...withAuth(apiKey),
             params: maxAgeSeconds != null ? { max_age_seconds: maxAgeSeconds } : undefined
         }),
 
@@ -65,30 +81,30 @@ export const locationApi = {
             longitude: number;
             request_id?: string;
             source?: 'periodic' | 'on_demand';
-            /** RFC3339 или YYYY-MM-DDTHH:mm (Europe/Minsk) — время фиксации на устройстве */
+            /** RFC3339 или YYYY-MM-DDTHH:mm (в поясе пользователя) — время фиксации на устройстве */
             captured_at?: string;
         },
         apiKey?: string
-    ) => api.post<Location | { skipped: boolean; reason: string }>('/location', location, withApiKey(apiKey)),
+    ) => api.post<Location | { skipped: boolean; reason: string }>('/location', location, withAuth(apiKey)),
 
     requestOnDemand: (userId: number, apiKey?: string) =>
         api.post<{ request_id: string; status: string; user_id: number }>(
             '/location/request',
             { user_id: userId },
-            withApiKey(apiKey)
+            withAuth(apiKey)
         ),
 
     getRequestStatus: (requestId: string, apiKey?: string) =>
         api.get<{ request_id: string; user_id: number; status: string; created_at: string; completed_at?: string }>(
             `/location/request/${requestId}`,
-            withApiKey(apiKey)
+            withAuth(apiKey)
         ),
 
     /** OSRM match; нужен ROUTING_BASE_URL на сервере. Координаты [lat, lng] */
     getMatchedRoute: (userId: number, from: string, to: string, apiKey?: string) =>
         api.get<{ coordinates: [number, number][]; segments?: [number, number][][] }>('/location/match-route', {
-            ...withApiKey(apiKey),
-            params: { user_id: userId, from, to }
+            ...withAuth(apiKey),
+            params: { user_id: userId, from, to, tz: DISPLAY_TIMEZONE }
         }),
 
     /** Backfill captured_at для старых точек (офлайн без timestamp в БД) */
@@ -101,7 +117,7 @@ export const locationApi = {
             '/admin/locations/backfill-captured-at',
             {},
             {
-                ...withApiKey(apiKey),
+                ...withAuth(apiKey),
                 params: {
                     user_id: userId,
                     ...(opts?.dryRun ? { dry_run: 'true' } : {}),
@@ -122,7 +138,7 @@ export const deviceApi = {
         api.post<{ command_id: string; type: string; status: string; user_id: number; payload?: Record<string, unknown> }>(
             `/admin/users/${userId}/commands`,
             body,
-            withApiKey(apiKey)
+            withAuth(apiKey)
         ),
 
     getUserHealth: (userId: number, apiKey?: string) =>
@@ -135,7 +151,7 @@ export const deviceApi = {
             issue_count: number;
             healthy: boolean;
             report: Record<string, unknown>;
-        }>(`/users/${userId}/health`, withApiKey(apiKey)),
+        }>(`/users/${userId}/health`, withAuth(apiKey)),
 
     pushDeviceConfig: (
         userId: number,
@@ -148,7 +164,7 @@ export const deviceApi = {
             status: string;
             user_id: number;
             payload?: Record<string, unknown>;
-        }>(`/admin/users/${userId}/device/config`, body, withApiKey(apiKey)),
+        }>(`/admin/users/${userId}/device/config`, body, withAuth(apiKey)),
 
     /** Пакетный статус всех устройств (1 запрос вместо N×2) */
     getAllDevicesStatus: (apiKey?: string) =>
@@ -165,7 +181,7 @@ export const deviceApi = {
                     issues: string[];
                 }
             >;
-        }>('/admin/devices/status', withApiKey(apiKey)),
+        }>('/admin/devices/status', withAuth(apiKey)),
 
     wakeDevice: (userId: number, apiKey?: string) =>
         api.post<{
@@ -174,7 +190,7 @@ export const deviceApi = {
             health_command_id?: string;
             location_command_id?: string;
             note?: string;
-        }>(`/admin/users/${userId}/wake`, {}, withApiKey(apiKey)),
+        }>(`/admin/users/${userId}/wake`, {}, withAuth(apiKey)),
 
     enableLocation: (userId: number, apiKey?: string) =>
         api.post<{
@@ -183,7 +199,7 @@ export const deviceApi = {
             health_command_id?: string;
             location_command_id?: string;
             note?: string;
-        }>(`/admin/users/${userId}/enable-location`, {}, withApiKey(apiKey)),
+        }>(`/admin/users/${userId}/enable-location`, {}, withAuth(apiKey)),
 };
 
 export const releaseApi = {
@@ -194,25 +210,25 @@ export const releaseApi = {
             type: string;
             user_id: number;
             payload?: Record<string, unknown>;
-        }>(`/admin/releases/publish-update/${userId}`, {}, withApiKey(apiKey)),
+        }>(`/admin/releases/publish-update/${userId}`, {}, withAuth(apiKey)),
 };
 
 // API для работы с чекпоинтами
 export const checkpointApi = {
     // Получение всех чекпоинтов с опциональным API ключом
-    getAll: (apiKey?: string) => api.get<Checkpoint[]>('/checkpoint/', withApiKey(apiKey)),
+    getAll: (apiKey?: string) => api.get<Checkpoint[]>('/checkpoint/', withAuth(apiKey)),
 
     // Создание нового чекпоинта
     create: (checkpoint: { name: string, latitude: number, longitude: number, radius: number }, apiKey?: string) =>
-        api.post<Checkpoint>('/checkpoint/', checkpoint, withApiKey(apiKey)),
+        api.post<Checkpoint>('/checkpoint/', checkpoint, withAuth(apiKey)),
 
     // Обновление существующего чекпоинта
     update: (id: number, checkpoint: { name: string, latitude: number, longitude: number, radius: number }, apiKey?: string) =>
-        api.put<Checkpoint>(`/checkpoint/${id}`, checkpoint, withApiKey(apiKey)),
+        api.put<Checkpoint>(`/checkpoint/${id}`, checkpoint, withAuth(apiKey)),
 
     // Проверка, находится ли пользователь в чекпоинте
     checkUserInCheckpoint: (userId: number, checkpointId: number, apiKey?: string) =>
-        api.get(`/checkpoint/check?user_id=${userId}&checkpoint_id=${checkpointId}`, withApiKey(apiKey))
+        api.get(`/checkpoint/check?user_id=${userId}&checkpoint_id=${checkpointId}`, withAuth(apiKey))
 };
 
 // API для работы с визитами
@@ -233,82 +249,50 @@ export const visitApi = {
         if (params.checkpoint_id) queryParams.append('checkpoint_id', params.checkpoint_id.toString());
         if (params.from) queryParams.append('from', params.from);
         if (params.to) queryParams.append('to', params.to);
+        if (params.from || params.to) queryParams.append('tz', DISPLAY_TIMEZONE);
         if (params.include_outside) queryParams.append('include_outside', 'true');
 
         const queryString = queryParams.toString();
         const url = queryString ? `/visits/?${queryString}` : '/visits/';
 
-        return api.get<Visit[]>(url, withApiKey(apiKey));
+        return api.get<Visit[]>(url, withAuth(apiKey));
     },
 
     // Получение всех визитов (для админов)
     getAll: (apiKey?: string) =>
-        api.get<Visit[]>('/visits/', withApiKey(apiKey)),
+        api.get<Visit[]>('/visits/', withAuth(apiKey)),
 
     /** Только активные визиты (end_at IS NULL) по всем пользователям */
     getActive: (apiKey?: string) =>
-        api.get<Visit[]>('/visits/?active=true', withApiKey(apiKey)),
+        api.get<Visit[]>('/visits/?active=true', withAuth(apiKey)),
 
     // Получение визита по ID
     getById: (id: number, apiKey?: string) =>
-        api.get<Visit[]>(`/visits/?id=${id}`, withApiKey(apiKey)),
+        api.get<Visit[]>(`/visits/?id=${id}`, withAuth(apiKey)),
 
     // Получение визитов пользователя
     getByUserId: (userId: number, apiKey?: string) =>
-        api.get<Visit[]>(`/visits/?user_id=${userId}`, withApiKey(apiKey)),
+        api.get<Visit[]>(`/visits/?user_id=${userId}`, withAuth(apiKey)),
 
     // Получение визитов для чекпоинта
     getByCheckpointId: (checkpointId: number, apiKey?: string) =>
-        api.get<Visit[]>(`/visits/?checkpoint_id=${checkpointId}`, withApiKey(apiKey))
+        api.get<Visit[]>(`/visits/?checkpoint_id=${checkpointId}`, withAuth(apiKey))
 };
 
 // API для работы с событиями
 export const eventApi = {
     // Публикация события
     publish: (event: LocationEvent, apiKey?: string) =>
-        api.post('/event/publish', event, withApiKey(apiKey))
+        api.post('/event/publish', event, withAuth(apiKey))
 };
 
 // API для работы с пользователями
 export const userApi = {
-    authenticate: async (apiKey: string): Promise<User> => {
-        try {
-            // Используем новый эндпоинт для получения текущего пользователя
-            const response = await fetch('/api/users/me', {
-                headers: {
-                    'X-API-Key': apiKey
-                }
-            });
-
-            if (!response.ok) {
-                throw new Error('Неверный API ключ');
-            }
-
-            const userData = await response.json();
-
-            // Преобразуем данные в формат User
-            return {
-                id: userData.id,
-                name: userData.name,
-                is_admin: userData.is_admin,
-                created_at: userData.created_at,
-                updated_at: userData.updated_at,
-                qr_code: userData.qr_code
-            };
-
-        } catch (error) {
-            console.error('Ошибка аутентификации:', error);
-            throw new Error('Ошибка аутентификации');
-        }
-    },
-
     // Метод для обновления информации о текущем пользователе
     getCurrentUser: async (apiKey: string): Promise<User> => {
         try {
             const response = await fetch('/api/users/me', {
-                headers: {
-                    'X-API-Key': apiKey
-                }
+                headers: authHeader(apiKey)
             });
 
             if (!response.ok) {
@@ -324,7 +308,10 @@ export const userApi = {
                 is_admin: userData.is_admin,
                 created_at: userData.created_at,
                 updated_at: userData.updated_at,
-                qr_code: userData.qr_code
+                qr_code: userData.qr_code,
+                username: userData.username ?? null,
+                totp_enabled: Boolean(userData.totp_enabled),
+                timezone: userData.timezone
             };
         } catch (error) {
             console.error('Ошибка получения текущего пользователя:', error);
@@ -334,9 +321,7 @@ export const userApi = {
 
     getAll: async (apiKey: string): Promise<User[]> => {
         const response = await fetch('/api/users/', {
-            headers: {
-                'X-API-Key': apiKey
-            }
+            headers: authHeader(apiKey)
         });
 
         if (!response.ok) {
@@ -348,9 +333,7 @@ export const userApi = {
 
     getById: async (id: number, apiKey: string): Promise<User> => {
         const response = await fetch(`/api/users/${id}`, {
-            headers: {
-                'X-API-Key': apiKey
-            }
+            headers: authHeader(apiKey)
         });
 
         if (!response.ok) {
@@ -365,7 +348,7 @@ export const userApi = {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
-                'X-API-Key': apiKey
+                ...authHeader(apiKey)
             },
             body: JSON.stringify({ name, is_admin: isAdmin })
         });
@@ -383,7 +366,7 @@ export const userApi = {
             method: 'PUT',
             headers: {
                 'Content-Type': 'application/json',
-                'X-API-Key': apiKey
+                ...authHeader(apiKey)
             },
             body: JSON.stringify({ name })
         });
@@ -396,6 +379,25 @@ export const userApi = {
         return response.json();
     },
 
+    /** IANA-пояс пользователя; '' — пояс организации */
+    setTimezone: async (id: number, timezone: string, apiKey: string): Promise<User> => {
+        const response = await fetch(`/api/users/${id}`, {
+            method: 'PUT',
+            headers: {
+                'Content-Type': 'application/json',
+                ...authHeader(apiKey)
+            },
+            body: JSON.stringify({ timezone })
+        });
+
+        if (!response.ok) {
+            const data = await response.json().catch(() => ({}));
+            throw new Error(data.error || 'Ошибка при изменении часового пояса');
+        }
+
+        return response.json();
+    },
+
     getQRCodeUrl: () => {
         // Здесь создаем URL с учетом текущего хоста и порта
         const baseUrl = window.location.origin;
@@ -403,7 +405,7 @@ export const userApi = {
         return `${baseUrl}/api/users/qr-code-file`;
     },
     getQRCodeData: (apiKey: string) => {
-        return api.get('/users/qr-code', withApiKey(apiKey));
+        return api.get('/users/qr-code', withAuth(apiKey));
     },
     getCurrentUserQRCodeFile: () => {
         return `/api/users/qr-code-file?t=${Date.now()}`;
@@ -416,7 +418,7 @@ export const userApi = {
 
     // Получить данные QR-кода конкретного пользователя (для админов)
     getUserQRCodeData: (userId: number, apiKey: string) => {
-        return api.get(`/users/${userId}/qr-code`, withApiKey(apiKey));
+        return api.get(`/users/${userId}/qr-code`, withAuth(apiKey));
     },
 
     regenerateQR: async (
@@ -435,7 +437,7 @@ export const userApi = {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
-                'X-API-Key': apiKey,
+                ...authHeader(apiKey),
             },
             body: JSON.stringify({
                 push_to_device: opts?.pushToDevice !== false,
@@ -449,4 +451,180 @@ export const userApi = {
 
         return response.json();
     },
-};
\ No newline at end of file
+
+    // API-ключи пользователя: основной (QR) и дополнительные
+    getApiKeys: (userId: number, apiKey: string) =>
+        authRequest<ApiKeyInfo[]>('GET', `/admin/users/${userId}/api-keys`, undefined, apiKey,
+            'Не удалось получить API-ключи'),
+
+    createApiKey: (userId: number, input: { label: string; expires_at?: string }, apiKey: string) =>
+        authRequest<{ key: ApiKeyInfo; api_key: string }>('POST', `/admin/users/${userId}/api-keys`, input, apiKey,
+            'Не удалось выпустить API-ключ'),
+
+    revokeApiKey: (userId: number, keyId: number, apiKey: string) =>
+        authRequest<ApiKeyInfo>('DELETE', `/admin/users/${userId}/api-keys/${keyId}`, undefined, apiKey,
+            'Не удалось отозвать API-ключ'),
+};
+// Ответ входа и обновления сессии (POST /api/auth/login|login-link|refresh)
+export interface SessionTokens {
+    access_token: string;
+    refresh_token: string;
+    token_type: string;
+    expires_in: number;
+    session_id: string;
+    user: User;
+}
+
+export interface SessionInfo {
+    id: string;
+    user_agent: string;
+    ip: string;
+    created_at: string;
+    last_used_at: string;
+    expires_at: string;
+    current: boolean;
+}
+
+// Ошибка API с машинно-читаемой причиной (например, totp_required)
+export class ApiError extends Error {
+    code?: string;
+    status: number;
+
+    constructor(message: string, status: number, code?: string) {
+        super(message);
+        this.status = status;
+        this.code = code;
+    }
+}
+
+const authRequest = async <T>(
+    method: string,
+    path: string,
+    body?: unknown,
+    token?: string,
+    fallback = 'Ошибка авторизации',
+): Promise<T> => {
+    const response = await fetch(`/api${path}`, {
+        method,
+        headers: {
+            'Content-Type': 'application/json',
+            ...(token ? authHeader(token) : {}),
+        },
+        body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    const data = await response.json().catch(() => ({}));
+    if (!response.ok) {
+        throw new ApiError(data.error || fallback, response.status, data.code);
+    }
+    return data as T;
+};
+
+// API входа сотрудников: сессии, пароль, второй фактор
+export const authApi = {
+    login: (username: string, password: string, totpCode?: string) =>
+        authRequest<SessionTokens>('POST', '/auth/login', { username, password, totp_code: totpCode || '' }),
+
+    loginWithLink: (token: string, totpCode?: string) =>
+        authRequest<SessionTokens>('POST', '/auth/login-link', { token, totp_code: totpCode || '' }),
+
+    refresh: (refreshToken: string) =>
+        authRequest<SessionTokens>('POST', '/auth/refresh', { refresh_token: refreshToken }),
+
+    logout: (token: string) => authRequest<{ status: string }>('POST', '/auth/logout', {}, token),
+
+    getSessions: (token: string) =>
+        authRequest<SessionInfo[]>('GET', '/auth/sessions', undefined, token, 'Не удалось получить список сессий'),
+
+    revokeSession: (id: string, token: string) =>
+        authRequest<{ status: string }>('DELETE', `/auth/sessions/${id}`, undefined, token, 'Не удалось завершить сессию'),
+
+    changePassword: (currentPassword: string, newPassword: string, token: string) =>
+        authRequest<{ status: string }>(
+            'PUT',
+            '/auth/password',
+            { current_password: currentPassword, new_password: newPassword },
+            token,
+            'Не удалось сменить пароль',
+        ),
+
+    setupTOTP: (token: string) =>
+        authRequest<{ secret: string; otpauth_uri: string }>('POST', '/auth/totp/setup', {}, token),
+
+    enableTOTP: (code: string, token: string) =>
+        authRequest<{ status: string }>('POST', '/auth/totp/enable', { code }, token),
+
+    disableTOTP: (code: string, token: string) =>
+        authRequest<{ status: string }>('POST', '/auth/totp/disable', { code }, token),
+
+    // Администратор: логин/пароль сотрудника, сброс TOTP, ссылка входа, выход везде
+    setCredentials: (
+        userId: number,
+        credentials: { username?: string; password?: string; reset_totp?: boolean },
+        token: string,
+    ) =>
+        authRequest<User>('PUT', `/admin/users/${userId}/credentials`, credentials, token,
+            'Не удалось сохранить учётные данные'),
+
+    createLoginLink: (userId: number, token: string) =>
+        authRequest<{ token: string; path: string; expires_at: string }>(
+            'POST', `/admin/users/${userId}/login-link`, {}, token, 'Не удалось создать ссылку для входа'),
+
+    revokeUserSessions: (userId: number, token: string) =>
+        authRequest<{ status: string }>('POST', `/admin/users/${userId}/sessions/revoke`, {}, token,
+            'Не удалось завершить сессии'),
+};
+
+const auditQuery = (filters: AuditFilters, extra: Record<string, string> = {}) => {
+    const params = new URLSearchParams(extra);
+    Object.entries(filters).forEach(([key, value]) => {
+        if (value) params.set(key, value);
+    });
+    return params.toString();
+};
+
+// Журнал аудита административных действий
+export const auditApi = {
+    list: (filters: AuditFilters, limit: number, offset: number, token: string) =>
+        authRequest<{ entries: AuditEntry[]; total: number }>(
+            'GET',
+            `/admin/audit?${auditQuery(filters, { limit: String(limit), offset: String(offset) })}`,
+            undefined,
+            token,
+            'Не удалось получить журнал аудита',
+        ),
+
+    // Выгрузка файлом (CSV или JSON Lines) с теми же фильтрами
+    exportFile: async (filters: AuditFilters, format: 'csv' | 'jsonl', token: string): Promise<Blob> => {
+        const response = await fetch(`/api/admin/audit/export?${auditQuery(filters, { format })}`, {
+            headers: authHeader(token),
+            cache: 'no-store',
+        });
+        if (!response.ok) {
+            const data = await response.json().catch(() => ({}));
+            throw new ApiError(data.error || 'Не удалось выгрузить журнал аудита', response.status, data.code);
+        }
+        return response.blob();
+    },
+};
+
+type TrackingScheduleResponse = { schedule: TrackingSchedule; status: TrackingScheduleStatus };
+
+// Окна отслеживания и согласие пользователя (GET отвечает 404, пока график не задан)
+export const trackingScheduleApi = {
+    get: (userId: number, token: string) =>
+        authRequest<TrackingScheduleResponse>('GET', `/admin/users/${userId}/tracking-schedule`, undefined, token,
+            'Не удалось получить график отслеживания'),
+
+    save: (userId: number, input: TrackingScheduleInput, token: string) =>
+        authRequest<TrackingScheduleResponse>('PUT', `/admin/users/${userId}/tracking-schedule`, input, token,
+            'Не удалось сохранить график отслеживания'),
+
+    remove: (userId: number, token: string) =>
+        authRequest<{ status: string }>('DELETE', `/admin/users/${userId}/tracking-schedule`, undefined, token,
+            'Не удалось удалить график отслеживания'),
+
+    // given = false — отзыв согласия
+    consent: (userId: number, given: boolean, by: string, token: string) =>
+        authRequest<TrackingScheduleResponse>('POST', `/admin/users/${userId}/tracking-consent`, { given, by }, token,
+            'Не удалось сохранить согласие'),
+};
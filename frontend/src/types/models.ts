@@ -6,6 +6,29 @@ export interface User {
     created_at: string;
     updated_at: string;
     qr_code: string;
+    /** Логин для входа в веб-интерфейс (только у сотрудников) */
+    username?: string | null;
+    totp_enabled?: boolean;
+    /** Роль: device, viewer, dispatcher, fleet_admin, super_admin */
+    role?: string;
+    /** IANA-пояс пользователя; пусто — пояс организации (в /users/me — действующий пояс) */
+    timezone?: string;
+}
+
+// API-ключ пользователя (сам ключ показывается только при выпуске)
+export interface ApiKeyInfo {
+    id: number;
+    user_id: number;
+    label: string;
+    fingerprint: string;
+    /** Ключ из QR-кода; после перегенерации QR прежний ключ получает replaced_by_id */
+    primary: boolean;
+    replaced_by_id?: number;
+    expires_at?: string;
+    last_used_at?: string;
+    last_used_ip: string;
+    revoked_at?: string;
+    created_at: string;
 }
 
 export interface Checkpoint {
@@ -31,6 +54,77 @@ export interface Location {
     age_seconds?: number;
 }
 
+// Запись журнала аудита административных действий (секреты в params заменены на ***)
+export interface AuditEntry {
+    id: number;
+    at: string;
+    organization_id: number;
+    actor_id?: number;
+    actor_name: string;
+    actor_role: string;
+    /** Метод и шаблон маршрута, например "PUT /api/admin/users/:id/access" */
+    action: string;
+    target_type?: string;
+    target_id?: string;
+    params?: Record<string, unknown>;
+    status: number;
+    result: 'success' | 'denied' | 'error';
+    error?: string;
+    ip: string;
+}
+
+export interface AuditFilters {
+    actor_id?: string;
+    action?: string;
+    target_type?: string;
+    target_id?: string;
+    result?: string;
+    /** RFC3339 */
+    from?: string;
+    to?: string;
+}
+
+// Окно отслеживания: дни недели 1 (пн) … 7 (вс), время "HH:MM"; конец раньше начала — окно через полночь
+export interface TrackingWindow {
+    days: number[];
+    start: string;
+    end: string;
+}
+
+// График приватности пользователя: когда разрешено отслеживание и согласие на него
+export interface TrackingSchedule {
+    user_id: number;
+    enabled: boolean;
+    /** IANA-пояс графика; пусто — пояс пользователя */
+    timezone: string;
+    windows?: TrackingWindow[];
+    /** YYYY-MM-DD — весь день без отслеживания */
+    holidays?: string[];
+    /** drop — точка вне окна отбрасывается, mask — сохраняется с огрублёнными координатами */
+    outside_action: 'drop' | 'mask';
+    consent_required: boolean;
+    consent_given_at?: string;
+    consent_by?: string;
+    consent_revoked_at?: string;
+    last_pushed_paused?: boolean;
+    last_pushed_at?: string;
+}
+
+export interface TrackingScheduleStatus {
+    paused: boolean;
+    /** consent — нет согласия, schedule — вне окна или праздник */
+    reason?: 'consent' | 'schedule';
+    since?: string;
+    until?: string;
+    /** Пояс, в котором действует график */
+    timezone?: string;
+}
+
+export type TrackingScheduleInput = Pick<
+    TrackingSchedule,
+    'enabled' | 'timezone' | 'windows' | 'holidays' | 'outside_action' | 'consent_required'
+>;
+
 export interface Visit {
     id: number;
     user_id: number;
@@ -53,6 +147,7 @@ export interface LocationEvent {
 export interface AuthState {
     isAuthenticated: boolean;
     user: User | null;
+    /** Access-токен текущей сессии (заголовок Authorization: Bearer) */
     apiKey: string | null;
     loading: boolean;
     error: string | null;
@@ -1,5 +1,7 @@
 const DISPLAY_LOCALE = 'ru-RU';
 const MINSK_TZ = 'Europe/Minsk';
+/** Пояс, в котором интерфейс показывает и вводит время; передаётся в API как ?tz= */
+export const DISPLAY_TIMEZONE = MINSK_TZ;
 
 function toDate(value: string | Date): Date {
     if (typeof value !== 'string') return value;
@@ -0,0 +1,19 @@
+#!/usr/bin/env bash
+# Вход администратора для скриптов: административные маршруты принимают только
+# сессию (Authorization: Bearer), API-ключ работает лишь на маршрутах устройства.
+# Использование: source scripts/admin_session.sh; TOKEN=$(locator_admin_token)
+# Переменные: BASE_URL, LOCATOR_ADMIN_USERNAME (admin), LOCATOR_ADMIN_PASSWORD,
+# LOCATOR_ADMIN_TOTP (код, если у администратора включён второй фактор).
+
+locator_admin_token() {
+  local base="${BASE_URL:-http://localhost:8080}"
+  if [[ -z "${LOCATOR_ADMIN_PASSWORD:-}" ]]; then
+    echo "LOCATOR_ADMIN_PASSWORD не задан" >&2
+    return 1
+  fi
+  local body
+  body=$(python3 -c 'import json,sys; print(json.dumps({"username": sys.argv[1], "password": sys.argv[2], "totp_code": sys.argv[3]}))' \
+    "${LOCATOR_ADMIN_USERNAME:-admin}" "$LOCATOR_ADMIN_PASSWORD" "${LOCATOR_ADMIN_TOTP:-}")
+  curl -sS -X POST "${base}/api/auth/login" -H "Content-Type: application/json" -d "$body" |
+    python3 -c 'import json,sys; d=json.load(sys.stdin); t=d.get("access_token"); print(t) if t else sys.exit("вход не выполнен: %s" % d.get("error"))'
+}
@@ -106,6 +106,8 @@ done
 echo ""
 echo "Опубликовано: $BASE_URL/static/releases/$PUBLISHED"
 echo "OTA на user 1:"
+source "$(dirname "${BASH_SOURCE[0]}")/admin_session.sh"
+ADMIN_TOKEN=$(locator_admin_token) || exit 0
 curl -sS -X POST "${BASE_URL}/api/admin/releases/publish-update/1" \
-  -H "X-API-Key: ${LOCATOR_ADMIN_API_KEY:-change_me}" \
+  -H "Authorization: Bearer ${ADMIN_TOKEN}" \
   -H "Content-Type: application/json" | python3 -m json.tool 2>/dev/null || true
@@ -3,7 +3,8 @@
 set -euo pipefail
 
 BASE_URL="${BASE_URL:-http://localhost:8080}"
-API_KEY="${API_KEY:-change_me}"
+API_KEY="${API_KEY:-change_me}"  # API-ключ устройства
+source "$(dirname "${BASH_SOURCE[0]}")/admin_session.sh"
 
 post() {
   local body="$1"
@@ -51,7 +52,7 @@ post '{
 echo
 echo "=== 5. GET raw за утро 01.07 (Минск) — сортировка по captured_at ==="
 curl -sS "${BASE_URL}/api/location/?from=2026-07-01T07:00&to=2026-07-01T08:00&raw=true" \
-  -H "X-API-Key: ${API_KEY}" | python3 -m json.tool 2>/dev/null | head -40
+  -H "Authorization: Bearer $(locator_admin_token)" | python3 -m json.tool 2>/dev/null | head -40
 
 echo
 echo "Готово."
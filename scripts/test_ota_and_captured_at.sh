@@ -3,10 +3,14 @@
 set -euo pipefail
 
 BASE_URL="${BASE_URL:-http://localhost:8080}"
-API_KEY="${API_KEY:-change_me}"
+API_KEY="${API_KEY:-change_me}"  # API-ключ устройства USER_ID
 USER_ID="${USER_ID:-1}"
 
+source "$(dirname "${BASH_SOURCE[0]}")/admin_session.sh"
+ADMIN_TOKEN=$(locator_admin_token)
+
 hdr=(-H "Content-Type: application/json" -H "X-API-Key: ${API_KEY}")
+admin_hdr=(-H "Content-Type: application/json" -H "Authorization: Bearer ${ADMIN_TOKEN}")
 
 echo "=== 1. POST с timestamp (как Android Location.getTime()) ==="
 TS=$(python3 -c "from datetime import datetime,timezone; print(int(datetime(2026,7,1,9,0,tzinfo=timezone.utc).timestamp()*1000))")
@@ -19,11 +23,11 @@ curl -sS -X POST "${BASE_URL}/api/location" "${hdr[@]}" -d "{
 echo
 
 echo "=== 2. Backfill captured_at (dry-run) user=${USER_ID} ==="
-curl -sS -X POST "${BASE_URL}/api/admin/locations/backfill-captured-at?user_id=${USER_ID}&dry_run=true" "${hdr[@]}" | python3 -m json.tool
+curl -sS -X POST "${BASE_URL}/api/admin/locations/backfill-captured-at?user_id=${USER_ID}&dry_run=true" "${admin_hdr[@]}" | python3 -m json.tool
 echo
 
 echo "=== 3. Backfill captured_at (apply) user=${USER_ID} ==="
-curl -sS -X POST "${BASE_URL}/api/admin/locations/backfill-captured-at?user_id=${USER_ID}" "${hdr[@]}" | python3 -m json.tool
+curl -sS -X POST "${BASE_URL}/api/admin/locations/backfill-captured-at?user_id=${USER_ID}" "${admin_hdr[@]}" | python3 -m json.tool
 echo
 
 echo "=== 4. Latest release manifest ==="
@@ -31,7 +35,7 @@ curl -sS "${BASE_URL}/api/app/release/latest" | python3 -m json.tool
 echo
 
 echo "=== 5. OTA app_update command → user ${USER_ID} ==="
-curl -sS -X POST "${BASE_URL}/api/admin/releases/publish-update/${USER_ID}" "${hdr[@]}" | python3 -m json.tool
+curl -sS -X POST "${BASE_URL}/api/admin/releases/publish-update/${USER_ID}" "${admin_hdr[@]}" | python3 -m json.tool
 echo
 
 echo "Готово."